@@ -0,0 +1,160 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ArtifactBucketEnvVar is the environment variable holding the name of the
+// artifact bucket.
+const ArtifactBucketEnvVar = "ARTIFACT_BUCKET"
+
+// bucketNamePattern is the naming rule for S3 general purpose buckets.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// defaultNoncurrentVersionDays is how long overwritten and deleted
+// artifacts are kept by default.
+const defaultNoncurrentVersionDays = 30
+
+// ArtifactBucketOptions creates a versioned, encrypted S3 bucket the agents
+// share for inputs, intermediate results, and outputs. Every agent gets its
+// name in ARTIFACT_BUCKET and may read, write, and delete its objects. The
+// bucket is retained when the stack is deleted unless
+// removalPolicies.artifacts is destroy.
+type ArtifactBucketOptions struct {
+	// BucketName is the bucket name.
+	// Default: generated by CloudFormation
+	BucketName string `json:"bucketName,omitempty" yaml:"bucketName,omitempty"`
+
+	// ExpirationDays deletes artifacts this many days after they were
+	// written.
+	// Default: 0 (artifacts are kept until deleted)
+	ExpirationDays int `json:"expirationDays,omitempty" yaml:"expirationDays,omitempty"`
+
+	// NoncurrentVersionDays deletes overwritten and deleted versions of
+	// artifacts after this many days.
+	// Default: 30
+	NoncurrentVersionDays int `json:"noncurrentVersionDays,omitempty" yaml:"noncurrentVersionDays,omitempty"`
+
+	// InfrequentAccessDays moves artifacts to S3 Standard-IA this many
+	// days after they were written, at least 30.
+	// Default: 0 (no transition)
+	InfrequentAccessDays int `json:"infrequentAccessDays,omitempty" yaml:"infrequentAccessDays,omitempty"`
+}
+
+// validate validates the artifact bucket options.
+func (o *ArtifactBucketOptions) validate() error {
+	switch {
+	case o.BucketName != "" && !*awscdk.Token_IsUnresolved(o.BucketName) && !bucketNamePattern.MatchString(o.BucketName):
+		return fmt.Errorf("artifactBucket.bucketName %q must be a valid S3 bucket name", o.BucketName)
+	case o.ExpirationDays < 0:
+		return fmt.Errorf("artifactBucket.expirationDays must not be negative, got %d", o.ExpirationDays)
+	case o.NoncurrentVersionDays < 0:
+		return fmt.Errorf("artifactBucket.noncurrentVersionDays must not be negative, got %d", o.NoncurrentVersionDays)
+	case o.InfrequentAccessDays != 0 && o.InfrequentAccessDays < 30:
+		return fmt.Errorf("artifactBucket.infrequentAccessDays must be at least 30, got %d", o.InfrequentAccessDays)
+	case o.InfrequentAccessDays != 0 && o.ExpirationDays != 0 && o.ExpirationDays <= o.InfrequentAccessDays:
+		return fmt.Errorf("artifactBucket.expirationDays (%d) must be after infrequentAccessDays (%d)", o.ExpirationDays, o.InfrequentAccessDays)
+	}
+	return nil
+}
+
+// artifactBucketRemovalPolicy returns the removal policy of the artifact
+// bucket: retain unless removalPolicies.artifacts says otherwise.
+func (s *AgentCoreStack) artifactBucketRemovalPolicy() string {
+	if opts := s.Options.RemovalPolicies; opts != nil && opts.Artifacts != "" {
+		return opts.Artifacts
+	}
+	return RemovalPolicyRetain
+}
+
+// createArtifactBucket creates the artifact bucket, lets the execution role
+// use its objects, and outputs its name.
+func (s *AgentCoreStack) createArtifactBucket() {
+	opts := s.Options.ArtifactBucket
+	if opts == nil {
+		return
+	}
+
+	noncurrentDays := opts.NoncurrentVersionDays
+	if noncurrentDays == 0 {
+		noncurrentDays = defaultNoncurrentVersionDays
+	}
+	rule := &awss3.LifecycleRule{
+		Id:                                  jsii.String("artifacts"),
+		AbortIncompleteMultipartUploadAfter: awscdk.Duration_Days(jsii.Number(7)),
+		NoncurrentVersionExpiration:         awscdk.Duration_Days(jsii.Number(float64(noncurrentDays))),
+	}
+	if opts.ExpirationDays > 0 {
+		rule.Expiration = awscdk.Duration_Days(jsii.Number(float64(opts.ExpirationDays)))
+	} else {
+		// Removes the delete markers left when the last version expires
+		rule.ExpiredObjectDeleteMarker = jsii.Bool(true)
+	}
+	if opts.InfrequentAccessDays > 0 {
+		rule.Transitions = &[]*awss3.Transition{{
+			StorageClass:    awss3.StorageClass_INFREQUENT_ACCESS(),
+			TransitionAfter: awscdk.Duration_Days(jsii.Number(float64(opts.InfrequentAccessDays))),
+		}}
+	}
+
+	encryption := awss3.BucketEncryption_S3_MANAGED
+	if s.KMSKey != nil {
+		encryption = awss3.BucketEncryption_KMS
+	}
+	destroy := s.artifactBucketRemovalPolicy() == RemovalPolicyDestroy
+	props := &awss3.BucketProps{
+		Encryption:        encryption,
+		EncryptionKey:     s.KMSKey,
+		BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+		EnforceSSL:        jsii.Bool(true),
+		Versioned:         jsii.Bool(true),
+		LifecycleRules:    &[]*awss3.LifecycleRule{rule},
+		RemovalPolicy:     cdkRemovalPolicy(s.artifactBucketRemovalPolicy()),
+		AutoDeleteObjects: jsii.Bool(destroy),
+	}
+	if s.KMSKey != nil {
+		// Fewer KMS requests for agents reading and writing many objects
+		props.BucketKeyEnabled = jsii.Bool(true)
+	}
+	if opts.BucketName != "" {
+		props.BucketName = jsii.String(opts.BucketName)
+	}
+	s.ArtifactBucket = awss3.NewBucket(s.Stack, jsii.String("ArtifactBucket"), props)
+
+	// Object access only; agents cannot change the bucket's configuration
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("s3:ListBucket", "s3:GetBucketLocation"),
+		Resources: &[]*string{s.ArtifactBucket.BucketArn()},
+	}))
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: jsii.Strings(
+			"s3:GetObject",
+			"s3:GetObjectVersion",
+			"s3:PutObject",
+			"s3:DeleteObject",
+			"s3:AbortMultipartUpload",
+		),
+		Resources: &[]*string{s.ArtifactBucket.ArnForObjects(jsii.String("*"))},
+	}))
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("ArtifactBucketName"), &awscdk.CfnOutputProps{
+		Value:       s.ArtifactBucket.BucketName(),
+		Description: jsii.String("Artifact bucket shared by the agents"),
+	})
+}
+
+// addArtifactEnvironment passes the artifact bucket to an agent.
+func (s *AgentCoreStack) addArtifactEnvironment(envVars map[string]string) {
+	if s.ArtifactBucket == nil {
+		return
+	}
+	envVars[ArtifactBucketEnvVar] = *s.ArtifactBucket.BucketName()
+}
@@ -0,0 +1,112 @@
+package agentcore
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// openIDConfigurationPath is the path of OpenID Connect discovery documents.
+const openIDConfigurationPath = "/.well-known/openid-configuration"
+
+// JWTAuthorizerOptions makes an agent's runtime accept requests with a JWT
+// bearer token from an OpenID Connect identity provider, e.g. Cognito or
+// Okta, instead of SigV4-signed requests, so external services can invoke
+// it without AWS credentials. Requests without a valid token are rejected.
+type JWTAuthorizerOptions struct {
+	// DiscoveryURL is the OpenID Connect discovery URL of the identity
+	// provider, ending in /.well-known/openid-configuration, e.g.
+	// https://cognito-idp.us-east-1.amazonaws.com/us-east-1_AbCdEf/.well-known/openid-configuration.
+	DiscoveryURL string `json:"discoveryUrl" yaml:"discoveryUrl"`
+
+	// AllowedAudiences are the accepted aud claims.
+	AllowedAudiences []string `json:"allowedAudiences,omitempty" yaml:"allowedAudiences,omitempty"`
+
+	// AllowedClients are the accepted client_id claims.
+	AllowedClients []string `json:"allowedClients,omitempty" yaml:"allowedClients,omitempty"`
+
+	// AllowedScopes are the accepted scopes; a token needs one of them.
+	AllowedScopes []string `json:"allowedScopes,omitempty" yaml:"allowedScopes,omitempty"`
+}
+
+// validateAuthorizer validates an agent's inbound authorization. Runtimes
+// accept either SigV4-signed requests (the default) or JWTs, so a JWT agent
+// can't be invoked by the parts of the stack that sign requests with SigV4.
+func validateAuthorizer(agent AgentConfig, o *StackOptions, config StackConfig) error {
+	if agent.Authorizer != nil && agent.Authorizer.Type == "LAMBDA" {
+		return fmt.Errorf("authorizer.type LAMBDA is not supported by agent runtimes; use jwtAuthorizer, or a gateway interceptorLambda")
+	}
+
+	opts := o.Agents[agent.Name]
+	if opts == nil || opts.JWTAuthorizer == nil {
+		return nil
+	}
+	auth := opts.JWTAuthorizer
+	if agent.Authorizer != nil && agent.Authorizer.Type == "IAM" {
+		return fmt.Errorf("jwtAuthorizer replaces authorizer.type IAM; remove one of them")
+	}
+	if u, err := url.Parse(auth.DiscoveryURL); err != nil || u.Scheme != "https" || u.Host == "" || !strings.HasSuffix(u.Path, openIDConfigurationPath) {
+		return fmt.Errorf("jwtAuthorizer.discoveryUrl %q must be an https URL ending in %s", auth.DiscoveryURL, openIDConfigurationPath)
+	}
+	if len(auth.AllowedAudiences) == 0 && len(auth.AllowedClients) == 0 {
+		return fmt.Errorf("jwtAuthorizer requires allowedAudiences or allowedClients")
+	}
+	for field, values := range map[string][]string{"allowedAudiences": auth.AllowedAudiences, "allowedClients": auth.AllowedClients, "allowedScopes": auth.AllowedScopes} {
+		for i, value := range values {
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("jwtAuthorizer.%s[%d] must not be empty", field, i)
+			}
+		}
+	}
+
+	// Invokers that sign with SigV4
+	switch {
+	case len(opts.Schedules) > 0:
+		return fmt.Errorf("jwtAuthorizer: schedules invoke the agent with SigV4, which the runtime rejects")
+	case len(opts.Triggers) > 0:
+		return fmt.Errorf("jwtAuthorizer: triggers invoke the agent with SigV4, which the runtime rejects")
+	case opts.HealthCheck != nil && !opts.HealthCheck.Skip:
+		return fmt.Errorf("jwtAuthorizer: smoke tests invoke the agent with SigV4; set healthCheck.skip")
+	case agent.Protocol == ProtocolMCP && config.Gateway != nil && config.Gateway.Enabled:
+		return fmt.Errorf("jwtAuthorizer: the gateway invokes MCP agents with its IAM role, which the runtime rejects")
+	}
+	if o.HTTPAPI != nil || o.CustomDomain != nil {
+		exposed := o.HTTPAPI == nil || len(o.HTTPAPI.Agents) == 0
+		if o.HTTPAPI != nil {
+			for _, name := range o.HTTPAPI.Agents {
+				exposed = exposed || name == agent.Name
+			}
+		}
+		if exposed {
+			return fmt.Errorf("jwtAuthorizer: the HTTP API invokes the agent with SigV4; leave it out of httpApi.agents")
+		}
+	}
+	return nil
+}
+
+// authorizerConfiguration returns the JWT authorizer of an agent's runtime,
+// or nil for SigV4.
+func (s *AgentCoreStack) authorizerConfiguration(config *AgentConfig) *awsbedrockagentcore.CfnRuntime_AuthorizerConfigurationProperty {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || opts.JWTAuthorizer == nil {
+		return nil
+	}
+
+	auth := opts.JWTAuthorizer
+	jwt := &awsbedrockagentcore.CfnRuntime_CustomJWTAuthorizerConfigurationProperty{
+		DiscoveryUrl: jsii.String(auth.DiscoveryURL),
+	}
+	if len(auth.AllowedAudiences) > 0 {
+		jwt.AllowedAudience = jsii.Strings(auth.AllowedAudiences...)
+	}
+	if len(auth.AllowedClients) > 0 {
+		jwt.AllowedClients = jsii.Strings(auth.AllowedClients...)
+	}
+	if len(auth.AllowedScopes) > 0 {
+		jwt.AllowedScopes = jsii.Strings(auth.AllowedScopes...)
+	}
+	return &awsbedrockagentcore.CfnRuntime_AuthorizerConfigurationProperty{CustomJwtAuthorizer: jwt}
+}
@@ -0,0 +1,86 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// defaultCandidateEndpoint is the name of the endpoint serving the latest
+// runtime version of a blue/green agent.
+const defaultCandidateEndpoint = "candidate"
+
+// BlueGreenOptions serves a blue/green agent's default endpoint from a
+// pinned "live" runtime version, while a candidate endpoint follows the
+// latest version. Each deploy creates a new runtime version that only the
+// candidate endpoint serves until it is promoted (deploy --promote), which
+// switches all traffic of the default endpoint to it at once.
+type BlueGreenOptions struct {
+	// CandidateEndpoint is the name of the endpoint following the latest
+	// runtime version.
+	// Pattern: [a-zA-Z][a-zA-Z0-9_]{0,47}
+	// Default: "candidate"
+	CandidateEndpoint string `json:"candidateEndpoint,omitempty" yaml:"candidateEndpoint,omitempty"`
+}
+
+// candidateEndpoint returns the name of the candidate endpoint.
+func (o *BlueGreenOptions) candidateEndpoint() string {
+	if o.CandidateEndpoint != "" {
+		return o.CandidateEndpoint
+	}
+	return defaultCandidateEndpoint
+}
+
+// validateBlueGreen validates an agent's blue/green options.
+func validateBlueGreen(agentName string, opts *AgentOptions) error {
+	if opts == nil || opts.BlueGreen == nil {
+		return nil
+	}
+	name := opts.BlueGreen.candidateEndpoint()
+	if !endpointNamePattern.MatchString(name) {
+		return fmt.Errorf("blueGreen.candidateEndpoint %q must match %s", name, endpointNamePattern)
+	}
+	if name == defaultEndpointName(agentName, opts) {
+		return fmt.Errorf("blueGreen.candidateEndpoint %q is the agent's default endpoint", name)
+	}
+	for _, endpoint := range opts.Endpoints {
+		if endpoint.Name == name {
+			return fmt.Errorf("blueGreen.candidateEndpoint %q is already used by another endpoint of the agent", name)
+		}
+	}
+	return nil
+}
+
+// createCandidateEndpoint creates the endpoint following the latest runtime
+// version of a blue/green agent.
+func (s *AgentCoreStack) createCandidateEndpoint(config *AgentConfig) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || opts.BlueGreen == nil {
+		return
+	}
+	name := opts.BlueGreen.candidateEndpoint()
+	runtime := s.Runtimes[config.Name]
+
+	endpoint := awsbedrockagentcore.NewCfnRuntimeEndpoint(s.agentScope(config.Name),
+		jsii.String(fmt.Sprintf("NamedEndpoint-%s-%s", config.Name, name)),
+		&awsbedrockagentcore.CfnRuntimeEndpointProps{
+			Name:           jsii.String(name),
+			AgentRuntimeId: runtime.AttrAgentRuntimeId(),
+			Description:    jsii.String(fmt.Sprintf("Candidate endpoint for agent %s", config.Name)),
+			Tags:           s.getTags(config),
+		},
+	)
+	if s.NamedEndpoints[config.Name] == nil {
+		s.NamedEndpoints[config.Name] = make(map[string]awsbedrockagentcore.CfnRuntimeEndpoint)
+	}
+	s.NamedEndpoints[config.Name][name] = endpoint
+
+	awscdk.NewCfnOutput(s.Stack,
+		jsii.String(fmt.Sprintf("Agent-%s-Endpoint-%s-Arn", config.Name, name)),
+		&awscdk.CfnOutputProps{
+			Value:       endpoint.AttrAgentRuntimeEndpointArn(),
+			Description: jsii.String(fmt.Sprintf("ARN of the candidate endpoint of agent %s", config.Name)),
+		})
+}
@@ -1,13 +1,16 @@
 package agentcore
 
 import (
+	"context"
+
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/constructs-go/constructs/v10"
 )
 
 // StackBuilder provides a fluent interface for building AgentCore stacks.
 type StackBuilder struct {
-	config StackConfig
+	config  StackConfig
+	options StackOptions
 }
 
 // NewStackBuilder creates a new stack builder.
@@ -39,6 +42,16 @@ func (b *StackBuilder) WithAgents(configs ...AgentConfig) *StackBuilder {
 	return b
 }
 
+// WithAgentBuilder adds agents from builders, including any CDK-specific
+// options (such as a memory store) configured on them.
+func (b *StackBuilder) WithAgentBuilder(agents ...*AgentBuilder) *StackBuilder {
+	for _, agent := range agents {
+		b.config.Agents = append(b.config.Agents, agent.config)
+		*b.options.agentOptions(agent.config.Name) = agent.options
+	}
+	return b
+}
+
 // WithSimpleAgent adds an agent with minimal configuration.
 func (b *StackBuilder) WithSimpleAgent(name, containerImage string) *StackBuilder {
 	return b.WithAgent(DefaultAgentConfig(name, containerImage))
@@ -66,6 +79,25 @@ func (b *StackBuilder) WithExistingVPC(vpcID string, subnetIDs []string) *StackB
 	return b
 }
 
+// WithExistingVPCByTags uses an existing VPC found by vpcTags, placing the
+// agents in its subnets carrying subnetTags (a single tag, e.g.
+// {"tier": "private"}; nil for the private subnets). Unlike IDs, tags can be
+// the same in every account.
+func (b *StackBuilder) WithExistingVPCByTags(vpcTags, subnetTags map[string]string) *StackBuilder {
+	b.config.VPC = &VPCConfig{}
+	opts := b.vpcOptions()
+	opts.VPCTags = vpcTags
+	opts.SubnetTags = subnetTags
+	return b
+}
+
+// WithSubnetGroup places the agents in the subnets of the existing VPC
+// (see WithExistingVPCByTags) with the given aws-cdk:subnet-name tag.
+func (b *StackBuilder) WithSubnetGroup(name string) *StackBuilder {
+	b.vpcOptions().SubnetGroupName = name
+	return b
+}
+
 // WithNewVPC creates a new VPC with the specified CIDR.
 func (b *StackBuilder) WithNewVPC(cidr string, maxAZs int) *StackBuilder {
 	b.config.VPC = &VPCConfig{
@@ -77,13 +109,81 @@ func (b *StackBuilder) WithNewVPC(cidr string, maxAZs int) *StackBuilder {
 	return b
 }
 
+// WithVPCOptions configures the topology of a created VPC.
+func (b *StackBuilder) WithVPCOptions(opts *VPCOptions) *StackBuilder {
+	b.options.VPC = opts
+	return b
+}
+
+// WithNatGateways sets the number of NAT gateways in a created VPC.
+// Use more than one (up to the AZ count) for highly available egress.
+func (b *StackBuilder) WithNatGateways(count int) *StackBuilder {
+	b.vpcOptions().NatGateways = &count
+	return b
+}
+
+// WithNatInstances uses count NAT instances of the given EC2 instance type
+// instead of managed NAT gateways, trading availability for cost.
+func (b *StackBuilder) WithNatInstances(instanceType string, count int) *StackBuilder {
+	opts := b.vpcOptions()
+	opts.NatInstanceType = instanceType
+	opts.NatGateways = &count
+	return b
+}
+
+// WithIsolatedSubnets places agents in isolated subnets with no NAT.
+// VPC endpoints are enabled so agents can still reach AWS services.
+func (b *StackBuilder) WithIsolatedSubnets() *StackBuilder {
+	if b.config.VPC == nil {
+		b.config.VPC = DefaultVPCConfig()
+	}
+	b.config.VPC.EnableVPCEndpoints = true
+	b.vpcOptions().IsolatedSubnets = true
+	return b
+}
+
+// WithSubnetCidrMask sets the CIDR mask of each subnet in a created VPC.
+func (b *StackBuilder) WithSubnetCidrMask(mask int) *StackBuilder {
+	b.vpcOptions().SubnetCidrMask = mask
+	return b
+}
+
+// vpcOptions returns the VPC options, creating them if needed.
+func (b *StackBuilder) vpcOptions() *VPCOptions {
+	if b.options.VPC == nil {
+		b.options.VPC = &VPCOptions{}
+	}
+	return b.options.VPC
+}
+
+// WithGlobalEnvVar sets an environment variable on every agent. An agent's
+// own value of the variable wins.
+func (b *StackBuilder) WithGlobalEnvVar(key, value string) *StackBuilder {
+	if b.options.GlobalEnvironment == nil {
+		b.options.GlobalEnvironment = make(map[string]string)
+	}
+	b.options.GlobalEnvironment[key] = value
+	return b
+}
+
+// WithGlobalEnvironment sets environment variables on every agent. An
+// agent's own values win.
+func (b *StackBuilder) WithGlobalEnvironment(env map[string]string) *StackBuilder {
+	for k, v := range env {
+		b.WithGlobalEnvVar(k, v)
+	}
+	return b
+}
+
 // WithSecrets configures secrets management.
 func (b *StackBuilder) WithSecrets(config *SecretsConfig) *StackBuilder {
 	b.config.Secrets = config
 	return b
 }
 
-// WithSecretValues creates secrets with the provided values.
+// WithSecretValues creates secrets with the provided values. The values
+// are written to the template in plaintext; prefer
+// WithSecretValueReference or WithGeneratedSecretValue for credentials.
 func (b *StackBuilder) WithSecretValues(values map[string]string) *StackBuilder {
 	b.config.Secrets = &SecretsConfig{
 		CreateSecrets: true,
@@ -92,6 +192,358 @@ func (b *StackBuilder) WithSecretValues(values map[string]string) *StackBuilder
 	return b
 }
 
+// WithSecretValueReference sets a key of the stack's secret to the value
+// of an existing secret, or of its jsonKey if set, e.g. one written by
+// push-secrets.
+func (b *StackBuilder) WithSecretValueReference(key, secretARN, jsonKey string) *StackBuilder {
+	b.createSecret()
+	b.options.Secrets.ValueReferences = append(b.options.Secrets.ValueReferences, SecretValueReference{Key: key, SecretARN: secretARN, JSONKey: jsonKey})
+	return b
+}
+
+// WithGeneratedSecretValue adds a random key of length characters to the
+// stack's secret; 0 uses 32.
+func (b *StackBuilder) WithGeneratedSecretValue(key string, length int) *StackBuilder {
+	b.createSecret()
+	b.options.Secrets.Generate = &GeneratedSecretValue{Key: key, Length: length}
+	return b
+}
+
+// createSecret makes the stack create its secret.
+func (b *StackBuilder) createSecret() {
+	if b.config.Secrets == nil {
+		b.config.Secrets = &SecretsConfig{}
+	}
+	b.config.Secrets.CreateSecrets = true
+	if b.options.Secrets == nil {
+		b.options.Secrets = &SecretsOptions{}
+	}
+}
+
+// WithSSMSecrets reads secrets from SecureString parameters under
+// /{prefix}/{group}/{KEY} instead of Secrets Manager. An empty prefix uses
+// the stack name; no groups uses DefaultSecretGroups.
+func (b *StackBuilder) WithSSMSecrets(prefix string, groups ...string) *StackBuilder {
+	b.options.Secrets = &SecretsOptions{
+		Backend: SecretsBackendSSM,
+		Prefix:  prefix,
+		Groups:  groups,
+	}
+	return b
+}
+
+// WithSecretsPrefix grants agents the {prefix}/{group} Secrets Manager
+// secrets written by push-secrets --prefix and passes their names in
+// SECRETS_NAME_{GROUP}. The prefix may contain the {project} and {env}
+// placeholders; environment is the {env}. No groups uses
+// DefaultSecretGroups.
+func (b *StackBuilder) WithSecretsPrefix(prefix, environment string, groups ...string) *StackBuilder {
+	if b.options.Secrets == nil {
+		b.options.Secrets = &SecretsOptions{}
+	}
+	b.options.Secrets.Prefix = prefix
+	b.options.Secrets.Environment = environment
+	b.options.Secrets.Groups = groups
+	return b
+}
+
+// WithImportedSecrets grants agents read access to the secrets whose
+// names start with prefix, e.g. "stats-agent/", and injects the ARNs of
+// names, e.g. "llm", as SECRET_ARN_{NAME}.
+func (b *StackBuilder) WithImportedSecrets(prefix string, names ...string) *StackBuilder {
+	if b.options.Secrets == nil {
+		b.options.Secrets = &SecretsOptions{}
+	}
+	b.options.Secrets.ImportByPrefix = prefix
+	b.options.Secrets.ImportSecrets = names
+	return b
+}
+
+// WithSecretReplica replicates the stack's secret to another region,
+// encrypted with kmsKeyARN there, or the region's aws/secretsmanager key if
+// empty. Call it once per region.
+func (b *StackBuilder) WithSecretReplica(region, kmsKeyARN string) *StackBuilder {
+	if b.options.Secrets == nil {
+		b.options.Secrets = &SecretsOptions{}
+	}
+	b.options.Secrets.ReplicaRegions = append(b.options.Secrets.ReplicaRegions, SecretReplica{Region: region, KMSKeyARN: kmsKeyARN})
+	return b
+}
+
+// WithSecretTags adds tags to the stack's secret, e.g.
+// {"environment": "prod"}, next to the project and managed-by tags.
+func (b *StackBuilder) WithSecretTags(tags map[string]string) *StackBuilder {
+	if b.options.Secrets == nil {
+		b.options.Secrets = &SecretsOptions{}
+	}
+	if b.options.Secrets.Tags == nil {
+		b.options.Secrets.Tags = make(map[string]string)
+	}
+	for key, value := range tags {
+		b.options.Secrets.Tags[key] = value
+	}
+	return b
+}
+
+// WithSecretReadRestriction lets only the execution role and readerARNs
+// read the stack's secret.
+func (b *StackBuilder) WithSecretReadRestriction(readerARNs ...string) *StackBuilder {
+	if b.options.Secrets == nil {
+		b.options.Secrets = &SecretsOptions{}
+	}
+	b.options.Secrets.RestrictRead = true
+	b.options.Secrets.ReaderARNs = append(b.options.Secrets.ReaderARNs, readerARNs...)
+	return b
+}
+
+// WithKMSKey encrypts stack resources with an existing customer managed key.
+func (b *StackBuilder) WithKMSKey(keyARN string) *StackBuilder {
+	b.options.KMS = &KMSOptions{KeyARN: keyARN}
+	return b
+}
+
+// WithCustomerManagedKey creates a customer managed key that encrypts stack
+// resources. An empty alias uses the stack name.
+func (b *StackBuilder) WithCustomerManagedKey(alias string) *StackBuilder {
+	b.options.KMS = &KMSOptions{Create: true, Alias: alias}
+	return b
+}
+
+// WithDashboard creates a CloudWatch dashboard named "{stackName}-agents"
+// with per-agent invocations, error rates, and latency percentiles, and
+// error and warning counts from the agent log group.
+func (b *StackBuilder) WithDashboard() *StackBuilder {
+	b.options.Dashboard = &DashboardOptions{}
+	return b
+}
+
+// WithDeploymentHistory creates a DynamoDB table the deploy tool records
+// each deployment in, keeping records for retentionDays, or forever if 0.
+func (b *StackBuilder) WithDeploymentHistory(retentionDays int) *StackBuilder {
+	b.options.DeploymentHistory = &DeploymentHistoryOptions{RetentionDays: retentionDays}
+	return b
+}
+
+// WithArtifactBucket creates a versioned, encrypted S3 bucket the agents
+// share for inputs and outputs, passed to them in ARTIFACT_BUCKET.
+// Overwritten versions expire after 30 days.
+func (b *StackBuilder) WithArtifactBucket() *StackBuilder {
+	b.options.ArtifactBucket = &ArtifactBucketOptions{}
+	return b
+}
+
+// WithArtifactBucketOptions creates the artifact bucket with the given
+// options, e.g. to expire artifacts.
+func (b *StackBuilder) WithArtifactBucketOptions(opts ArtifactBucketOptions) *StackBuilder {
+	b.options.ArtifactBucket = &opts
+	return b
+}
+
+// WithStateTable creates an on-demand DynamoDB table the agents share for
+// durable state, passed to them in STATE_TABLE. An empty name is generated;
+// a non-empty ttlAttribute expires items at the epoch seconds it holds.
+func (b *StackBuilder) WithStateTable(name, ttlAttribute string) *StackBuilder {
+	return b.WithStateTableOptions(StateTableOptions{TableName: name, TTLAttribute: ttlAttribute})
+}
+
+// WithStateTableOptions creates the state table with the given options,
+// e.g. custom keys or point-in-time recovery.
+func (b *StackBuilder) WithStateTableOptions(opts StateTableOptions) *StackBuilder {
+	b.options.StateTable = &opts
+	return b
+}
+
+// WithKnowledgeBase creates a Bedrock knowledge base backed by S3 Vectors,
+// ingesting the documents of the given bucket (empty for none). Agents
+// query it after declaring AgentBuilder.WithKnowledgeBase(name).
+func (b *StackBuilder) WithKnowledgeBase(name, dataSourceBucketARN string) *StackBuilder {
+	return b.WithKnowledgeBaseOptions(KnowledgeBaseOptions{Name: name, DataSourceBucketARN: dataSourceBucketARN})
+}
+
+// WithExistingKnowledgeBase attaches an existing Bedrock knowledge base
+// under the given name.
+func (b *StackBuilder) WithExistingKnowledgeBase(name, knowledgeBaseID string) *StackBuilder {
+	return b.WithKnowledgeBaseOptions(KnowledgeBaseOptions{Name: name, KnowledgeBaseID: knowledgeBaseID})
+}
+
+// WithKnowledgeBaseOptions adds a knowledge base with the given options,
+// e.g. an OpenSearch Serverless vector store or another embedding model.
+func (b *StackBuilder) WithKnowledgeBaseOptions(opts KnowledgeBaseOptions) *StackBuilder {
+	b.options.KnowledgeBases = append(b.options.KnowledgeBases, opts)
+	return b
+}
+
+// WithRedis creates an ElastiCache Redis replication group of the given node
+// type (e.g. "cache.t4g.small") with 0-5 read replicas in the stack's VPC.
+// Agents in VPC network mode get its URL in REDIS_URL.
+func (b *StackBuilder) WithRedis(nodeType string, replicas int) *StackBuilder {
+	return b.WithRedisOptions(RedisOptions{NodeType: nodeType, Replicas: replicas})
+}
+
+// WithServerlessRedis creates an ElastiCache Serverless Redis cache in the
+// stack's VPC instead of a node-based one.
+func (b *StackBuilder) WithServerlessRedis() *StackBuilder {
+	return b.WithRedisOptions(RedisOptions{Serverless: true})
+}
+
+// WithRedisOptions creates the cache with the given options, e.g. the
+// Valkey engine or a data limit.
+func (b *StackBuilder) WithRedisOptions(opts RedisOptions) *StackBuilder {
+	b.options.Redis = &opts
+	return b
+}
+
+// WithHTTPAPI creates an API Gateway HTTP API with a POST
+// /agents/{name}/invoke route per agent, authorized with IAM (SigV4).
+func (b *StackBuilder) WithHTTPAPI() *StackBuilder {
+	return b.WithHTTPAPIOptions(HTTPAPIOptions{})
+}
+
+// WithHTTPAPIOptions creates an HTTP API with options such as a custom
+// domain and throttling.
+func (b *StackBuilder) WithHTTPAPIOptions(opts HTTPAPIOptions) *StackBuilder {
+	b.options.HTTPAPI = &opts
+	return b
+}
+
+// WithCustomDomain serves the agents and the gateway on a domain in a Route
+// 53 hosted zone, with a DNS-validated certificate: agents at
+// https://{domainName}/agents/{name}/invoke through the HTTP API, and the
+// gateway at https://{domainName}/mcp.
+func (b *StackBuilder) WithCustomDomain(domainName, hostedZoneID string) *StackBuilder {
+	return b.WithCustomDomainOptions(CustomDomainOptions{Name: domainName, HostedZoneID: hostedZoneID})
+}
+
+// WithCustomDomainOptions serves the agents and the gateway on a custom
+// domain with options such as an existing certificate.
+func (b *StackBuilder) WithCustomDomainOptions(opts CustomDomainOptions) *StackBuilder {
+	b.options.CustomDomain = &opts
+	return b
+}
+
+// WithWAF serves the custom domain through a CloudFront distribution
+// protected by an existing web ACL with CLOUDFRONT scope. An empty ARN
+// creates a web ACL with rate limiting and the AWS managed rule groups of
+// DefaultWAFManagedRuleGroups, which requires a stack in us-east-1.
+func (b *StackBuilder) WithWAF(webACLARN string) *StackBuilder {
+	return b.WithWAFOptions(WAFOptions{WebACLARN: webACLARN})
+}
+
+// WithWAFOptions protects the custom domain with a web ACL with options
+// such as the rate limit and an IP allowlist.
+func (b *StackBuilder) WithWAFOptions(opts WAFOptions) *StackBuilder {
+	b.options.WAF = &opts
+	return b
+}
+
+// WithExportOutputs exports the agent runtime and endpoint ARNs, the
+// execution role ARN, and the gateway ARN and URL, for other stacks to
+// import with ImportAgentRuntime or Fn::ImportValue.
+func (b *StackBuilder) WithExportOutputs() *StackBuilder {
+	b.options.ExportOutputs = true
+	return b
+}
+
+// WithPartition splits the agents' resources across nested stacks with the
+// "per-agent" or "grouped" strategy, for fleets that exceed
+// CloudFormation's per-template resource limit.
+func (b *StackBuilder) WithPartition(strategy string) *StackBuilder {
+	return b.WithPartitionOptions(PartitionOptions{Strategy: strategy})
+}
+
+// WithPartitionOptions splits the agents' resources across nested stacks
+// with full options.
+func (b *StackBuilder) WithPartitionOptions(opts PartitionOptions) *StackBuilder {
+	b.options.Partition = &opts
+	return b
+}
+
+// WithSecurityChecks fails synth on findings of the security rules, such
+// as wildcard IAM permissions and security groups open to the internet.
+func (b *StackBuilder) WithSecurityChecks() *StackBuilder {
+	if b.options.SecurityChecks == nil {
+		b.options.SecurityChecks = &SecurityChecksOptions{}
+	}
+	return b
+}
+
+// WithSecurityChecksOptions configures the security checks.
+func (b *StackBuilder) WithSecurityChecksOptions(opts SecurityChecksOptions) *StackBuilder {
+	b.options.SecurityChecks = &opts
+	return b
+}
+
+// WithSecuritySuppression accepts the findings of a security rule under a
+// construct path (relative to the stack; empty for the whole stack) with
+// a documented reason.
+func (b *StackBuilder) WithSecuritySuppression(rule, path, reason string) *StackBuilder {
+	b.WithSecurityChecks()
+	b.options.SecurityChecks.Suppressions = append(b.options.SecurityChecks.Suppressions, SecuritySuppression{
+		Rule:   rule,
+		Path:   path,
+		Reason: reason,
+	})
+	return b
+}
+
+// WithPolicies makes synth fail on violations of the named built-in or
+// registered policies.
+func (b *StackBuilder) WithPolicies(names ...string) *StackBuilder {
+	b.options.Policies = append(b.options.Policies, names...)
+	return b
+}
+
+// WithResourceHook customizes the generated CloudFormation resources, e.g.
+// to tag them or override their names. Combine with OnResourceType to
+// select resources of one type.
+func (b *StackBuilder) WithResourceHook(hook ResourceHook) *StackBuilder {
+	b.options.ResourceHooks = append(b.options.ResourceHooks, hook)
+	return b
+}
+
+// WithTLSEnforcement denies non-TLS access to the stack's buckets, queues,
+// and topics and sets the minimum TLS version ("1.2" or "1.3"; empty is
+// 1.2) of its ingress. Resources that cannot comply are reported as synth
+// warnings.
+func (b *StackBuilder) WithTLSEnforcement(minimumVersion string) *StackBuilder {
+	b.options.TLS = &TLSOptions{MinimumVersion: minimumVersion}
+	return b
+}
+
+// WithSkipRegionCheck synthesizes the stack for regions where the region
+// matrix says Bedrock AgentCore is not available.
+func (b *StackBuilder) WithSkipRegionCheck() *StackBuilder {
+	b.options.SkipRegionCheck = true
+	return b
+}
+
+// WithSynthesizer configures the stack synthesizer for a customized CDK
+// bootstrap.
+func (b *StackBuilder) WithSynthesizer(opts SynthesizerOptions) *StackBuilder {
+	b.options.Synthesizer = &opts
+	return b
+}
+
+// WithBootstrapQualifier deploys with the bootstrap resources of a
+// non-default qualifier.
+func (b *StackBuilder) WithBootstrapQualifier(qualifier string) *StackBuilder {
+	if b.options.Synthesizer == nil {
+		b.options.Synthesizer = &SynthesizerOptions{}
+	}
+	b.options.Synthesizer.Qualifier = qualifier
+	return b
+}
+
+// WithPermissionsBoundary sets the permissions boundary (a managed policy
+// name or ARN) of every IAM role the stack creates.
+func (b *StackBuilder) WithPermissionsBoundary(policy string) *StackBuilder {
+	if b.options.Synthesizer == nil {
+		b.options.Synthesizer = &SynthesizerOptions{}
+	}
+	b.options.Synthesizer.PermissionsBoundary = policy
+	return b
+}
+
 // WithObservability configures observability.
 func (b *StackBuilder) WithObservability(config *ObservabilityConfig) *StackBuilder {
 	b.config.Observability = config
@@ -122,6 +574,117 @@ func (b *StackBuilder) WithLangfuse(project string, apiKeySecretARN string) *Sta
 	return b
 }
 
+// WithPhoenix configures Arize Phoenix observability. endpoint is the
+// Phoenix collector URL (e.g. https://app.phoenix.arize.com); the API key
+// secret may be empty for self-hosted Phoenix without authentication.
+func (b *StackBuilder) WithPhoenix(project, endpoint, apiKeySecretARN string) *StackBuilder {
+	b.config.Observability = &ObservabilityConfig{
+		Provider:             "phoenix",
+		Project:              project,
+		Endpoint:             endpoint,
+		APIKeySecretARN:      apiKeySecretARN,
+		EnableCloudWatchLogs: true,
+		LogRetentionDays:     30,
+	}
+	return b
+}
+
+// WithXRay sends agent traces to AWS X-Ray: agents get OpenTelemetry
+// exporter settings for an ADOT collector at http://localhost:4318, which
+// forwards them to X-Ray, and the execution role may write traces. Call it after WithOpik, WithLangfuse, WithPhoenix, or
+// WithCloudWatchOnly, which replace the observability configuration.
+func (b *StackBuilder) WithXRay() *StackBuilder {
+	if b.config.Observability == nil {
+		b.config.Observability = DefaultObservabilityConfig()
+	}
+	b.config.Observability.EnableXRay = true
+	return b
+}
+
+// WithADOTCollector enables X-Ray tracing through an ADOT collector that
+// agents reach at endpoint. An empty endpoint uses http://localhost:4318.
+func (b *StackBuilder) WithADOTCollector(endpoint string) *StackBuilder {
+	if b.options.Observability == nil {
+		b.options.Observability = &ObservabilityOptions{}
+	}
+	b.options.Observability.XRay = &XRayOptions{Collector: &ADOTCollectorOptions{Endpoint: endpoint}}
+	return b.WithXRay()
+}
+
+// WithLogDestination ships the stack's log group to an existing Firehose
+// delivery stream, Kinesis data stream, or CloudWatch Logs destination of
+// another account. Requires CloudWatch logs in the observability
+// configuration.
+func (b *StackBuilder) WithLogDestination(destinationARN string) *StackBuilder {
+	return b.WithLogDestinationOptions(&LogDestinationOptions{DestinationARN: destinationARN})
+}
+
+// WithLogDestinationOptions ships the stack's log group with the given
+// options, e.g. through a Firehose delivery stream the stack creates.
+func (b *StackBuilder) WithLogDestinationOptions(opts *LogDestinationOptions) *StackBuilder {
+	if b.options.Observability == nil {
+		b.options.Observability = &ObservabilityOptions{}
+	}
+	b.options.Observability.LogDestination = opts
+	return b
+}
+
+// WithMetricFromLogs derives the metric name in namespace from the agent
+// log group, counting the events that match pattern, e.g. "ERROR". An empty
+// namespace uses "AgentKit/{stackName}". Requires CloudWatch logs in the
+// observability configuration.
+func (b *StackBuilder) WithMetricFromLogs(name, pattern, namespace string) *StackBuilder {
+	return b.WithLogMetric(LogMetricOptions{Name: name, Pattern: pattern, Namespace: namespace})
+}
+
+// WithLogMetric derives a metric from the agent log group with the given
+// options, e.g. the value of a field of structured JSON logs.
+func (b *StackBuilder) WithLogMetric(metric LogMetricOptions) *StackBuilder {
+	if b.options.Observability == nil {
+		b.options.Observability = &ObservabilityOptions{}
+	}
+	b.options.Observability.LogMetrics = append(b.options.Observability.LogMetrics, metric)
+	return b
+}
+
+// WithUsageMetrics publishes per-agent, per-model LLM token and cost
+// metrics from the usage lines agents log, and writes a daily cost report
+// to a bucket created by the stack. Requires CloudWatch logs in the
+// observability configuration.
+func (b *StackBuilder) WithUsageMetrics() *StackBuilder {
+	return b.WithUsageMetricsOptions(&UsageMetricsOptions{})
+}
+
+// WithUsageMetricsOptions publishes LLM usage metrics with the given
+// options, e.g. model prices or an existing report bucket.
+func (b *StackBuilder) WithUsageMetricsOptions(opts *UsageMetricsOptions) *StackBuilder {
+	if b.options.Observability == nil {
+		b.options.Observability = &ObservabilityOptions{}
+	}
+	b.options.Observability.UsageMetrics = opts
+	return b
+}
+
+// WithTraceEnvironment reports environment, e.g. "prod", as the
+// deployment.environment resource attribute of agent traces.
+func (b *StackBuilder) WithTraceEnvironment(environment string) *StackBuilder {
+	if b.options.Observability == nil {
+		b.options.Observability = &ObservabilityOptions{}
+	}
+	b.options.Observability.Environment = environment
+	return b
+}
+
+// WithOpikWorkspace sends agent traces to the named Opik workspace. Call it
+// with WithOpik.
+func (b *StackBuilder) WithOpikWorkspace(workspace string) *StackBuilder {
+	if b.options.Observability == nil {
+		b.options.Observability = &ObservabilityOptions{}
+	}
+	b.options.Observability.OpikWorkspace = workspace
+	return b
+}
+
 // WithCloudWatchOnly configures CloudWatch-only observability.
 func (b *StackBuilder) WithCloudWatchOnly(retentionDays int) *StackBuilder {
 	b.config.Observability = &ObservabilityConfig{
@@ -132,6 +695,89 @@ func (b *StackBuilder) WithCloudWatchOnly(retentionDays int) *StackBuilder {
 	return b
 }
 
+// WithCrossStackGateway enables this stack's gateway and registers agent
+// runtimes from other stacks as its targets, so one "tool hub" gateway can
+// front runtimes owned by team stacks. The gateway role is granted
+// bedrock-agentcore:InvokeAgentRuntime on each remote runtime.
+func (b *StackBuilder) WithCrossStackGateway(targets ...RemoteRuntimeTarget) *StackBuilder {
+	if b.config.Gateway == nil {
+		b.config.Gateway = &GatewayConfig{}
+	}
+	b.config.Gateway.Enabled = true
+	if b.options.Gateway == nil {
+		b.options.Gateway = &GatewayOptions{}
+	}
+	b.options.Gateway.RemoteTargets = append(b.options.Gateway.RemoteTargets, targets...)
+	return b
+}
+
+// WithToolTargets enables this stack's gateway and exposes Lambda
+// functions and OpenAPI described APIs as its MCP tools.
+func (b *StackBuilder) WithToolTargets(targets ...ToolTarget) *StackBuilder {
+	if b.config.Gateway == nil {
+		b.config.Gateway = &GatewayConfig{}
+	}
+	b.config.Gateway.Enabled = true
+	if b.options.Gateway == nil {
+		b.options.Gateway = &GatewayOptions{}
+	}
+	b.options.Gateway.ToolTargets = append(b.options.Gateway.ToolTargets, targets...)
+	return b
+}
+
+// WithGatewayInterceptor enables this stack's gateway and deploys the Go
+// Lambda function in codePath (a directory or .zip holding a "bootstrap"
+// binary) as its request interceptor.
+func (b *StackBuilder) WithGatewayInterceptor(codePath string) *StackBuilder {
+	return b.WithGatewayInterceptorOptions(&InterceptorOptions{CodePath: codePath})
+}
+
+// WithGatewayInterceptorARN enables this stack's gateway and attaches an
+// existing Lambda function as its request interceptor.
+func (b *StackBuilder) WithGatewayInterceptorARN(functionARN string) *StackBuilder {
+	return b.WithGatewayInterceptorOptions(&InterceptorOptions{FunctionARN: functionARN})
+}
+
+// WithGatewayInterceptorOptions enables this stack's gateway and attaches
+// the given interceptor.
+func (b *StackBuilder) WithGatewayInterceptorOptions(opts *InterceptorOptions) *StackBuilder {
+	if b.config.Gateway == nil {
+		b.config.Gateway = &GatewayConfig{}
+	}
+	b.config.Gateway.Enabled = true
+	if b.options.Gateway == nil {
+		b.options.Gateway = &GatewayOptions{}
+	}
+	b.options.Gateway.InterceptorLambda = opts
+	return b
+}
+
+// WithConfigStore publishes agents' environment variables to "ssm" or "s3"
+// and injects only a CONFIG_URI pointer. Only agents whose environment is
+// larger than thresholdBytes are offloaded; zero offloads all of them.
+func (b *StackBuilder) WithConfigStore(storeType string, thresholdBytes int) *StackBuilder {
+	b.options.ConfigStore = &ConfigStoreOptions{
+		Type:           storeType,
+		ThresholdBytes: thresholdBytes,
+	}
+	return b
+}
+
+// WithImageValidation checks agent image URIs and verifies the images exist
+// in their registry before synth, so a typo in an image tag fails fast
+// instead of at CloudFormation deploy time.
+func (b *StackBuilder) WithImageValidation() *StackBuilder {
+	b.options.ValidateImages = true
+	return b
+}
+
+// WithRawResource adds a CloudFormation resource declared by type and
+// properties, for AgentCore resources aws-cdk-go doesn't model yet.
+func (b *StackBuilder) WithRawResource(resource RawResource) *StackBuilder {
+	b.options.RawResources = append(b.options.RawResources, resource)
+	return b
+}
+
 // WithIAM configures IAM settings.
 func (b *StackBuilder) WithIAM(config *IAMConfig) *StackBuilder {
 	b.config.IAM = config
@@ -185,25 +831,58 @@ func (b *StackBuilder) DestroyOnDelete() *StackBuilder {
 	return b.WithRemovalPolicy("destroy")
 }
 
+// WithRemovalPolicies sets the removal policies of resource classes,
+// overriding the stack-wide removal policy for them.
+func (b *StackBuilder) WithRemovalPolicies(opts RemovalPolicyOptions) *StackBuilder {
+	b.options.RemovalPolicies = &opts
+	return b
+}
+
 // Config returns the current configuration.
 func (b *StackBuilder) Config() StackConfig {
 	return b.config
 }
 
+// Options returns the current CDK-specific options.
+func (b *StackBuilder) Options() StackOptions {
+	return b.options
+}
+
 // Validate validates the current configuration.
 func (b *StackBuilder) Validate() error {
 	b.config.ApplyDefaults()
-	return b.config.Validate()
+	if err := validateTokens(b.config, b.options); err != nil {
+		return err
+	}
+	config := b.config
+	applyGlobalEnvironment(&config, b.options.GlobalEnvironment)
+	if err := validateProtocols(config.Agents); err != nil {
+		return err
+	}
+	if err := validateMemory(config.Agents); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if err := b.options.Validate(config); err != nil {
+		return err
+	}
+	if b.options.ValidateImages {
+		return validateImages(context.Background(), config)
+	}
+	return nil
 }
 
 // Build creates the AgentCore stack.
 func (b *StackBuilder) Build(scope constructs.Construct) *AgentCoreStack {
-	return NewAgentCoreStack(scope, b.config.StackName, b.config)
+	return NewAgentCoreStackWithOptions(scope, b.config.StackName, b.config, b.options)
 }
 
 // AgentBuilder provides a fluent interface for building agent configurations.
 type AgentBuilder struct {
-	config AgentConfig
+	config  AgentConfig
+	options AgentOptions
 }
 
 // NewAgentBuilder creates a new agent builder.
@@ -219,7 +898,10 @@ func (b *AgentBuilder) WithDescription(description string) *AgentBuilder {
 	return b
 }
 
-// WithMemory sets the memory allocation in MB.
+// WithMemory sets the memory allocation in MB. AgentCore runtimes have no
+// memory setting, so validation fails for any value other than 512.
+//
+// Deprecated: leave the memory unset; runtimes are sized by AgentCore.
 func (b *AgentBuilder) WithMemory(memoryMB int) *AgentBuilder {
 	b.config.MemoryMB = memoryMB
 	return b
@@ -231,6 +913,13 @@ func (b *AgentBuilder) WithTimeout(timeoutSeconds int) *AgentBuilder {
 	return b
 }
 
+// WithProtocol sets the runtime protocol (ProtocolHTTP, ProtocolMCP, or
+// ProtocolA2A).
+func (b *AgentBuilder) WithProtocol(protocol string) *AgentBuilder {
+	b.config.Protocol = protocol
+	return b
+}
+
 // WithEnvironment sets environment variables.
 func (b *AgentBuilder) WithEnvironment(env map[string]string) *AgentBuilder {
 	for k, v := range env {
@@ -251,6 +940,201 @@ func (b *AgentBuilder) WithSecrets(secretARNs ...string) *AgentBuilder {
 	return b
 }
 
+// WithSecretEnvVar sets an environment variable to a Secrets Manager secret,
+// or to one key of a JSON secret if jsonKey is not empty. An empty secretARN
+// selects the stack's secret. The value is resolved at deploy time.
+func (b *AgentBuilder) WithSecretEnvVar(name, secretARN, jsonKey string) *AgentBuilder {
+	b.options.SecretEnvironment = append(b.options.SecretEnvironment, SecretEnvVar{
+		Name:      name,
+		SecretARN: secretARN,
+		JSONKey:   jsonKey,
+	})
+	return b
+}
+
+// WithBlueGreen serves the agent's default endpoint from a promoted runtime
+// version and the latest version from a "candidate" endpoint.
+func (b *AgentBuilder) WithBlueGreen() *AgentBuilder {
+	return b.WithBlueGreenOptions(BlueGreenOptions{})
+}
+
+// WithBlueGreenOptions enables blue/green deployment with full options.
+func (b *AgentBuilder) WithBlueGreenOptions(opts BlueGreenOptions) *AgentBuilder {
+	b.options.BlueGreen = &opts
+	return b
+}
+
+// WithVersions lets the agent be rolled back to its last retain runtime
+// versions (0 for the default of 5).
+func (b *AgentBuilder) WithVersions(retain int) *AgentBuilder {
+	b.options.Versions = &VersionsOptions{Retain: retain}
+	return b
+}
+
+// WithScaling ends the agent's runtime sessions after idleSeconds without
+// requests or maxLifetimeSeconds after they started (0 for the AgentCore
+// defaults of 900 and 28800).
+func (b *AgentBuilder) WithScaling(idleSeconds, maxLifetimeSeconds int) *AgentBuilder {
+	b.options.Scaling = &ScalingOptions{IdleSessionTimeoutSeconds: idleSeconds, MaxSessionLifetimeSeconds: maxLifetimeSeconds}
+	return b
+}
+
+// WithJWTAuthorizer makes the agent's runtime accept JWT bearer tokens
+// from the OpenID Connect provider of discoveryURL with one of the
+// audiences, instead of SigV4-signed requests.
+func (b *AgentBuilder) WithJWTAuthorizer(discoveryURL string, audiences ...string) *AgentBuilder {
+	return b.WithJWTAuthorizerOptions(JWTAuthorizerOptions{DiscoveryURL: discoveryURL, AllowedAudiences: audiences})
+}
+
+// WithJWTAuthorizerOptions configures the JWT authorizer of the agent's
+// runtime with full options, e.g. allowed clients and scopes.
+func (b *AgentBuilder) WithJWTAuthorizerOptions(opts JWTAuthorizerOptions) *AgentBuilder {
+	b.options.JWTAuthorizer = &opts
+	return b
+}
+
+// DependsOn makes the agent depend on other agents of the stack: they are
+// created first, their endpoint and runtime ARNs are injected as
+// AGENT_{NAME}_ENDPOINT_ARN and AGENT_{NAME}_RUNTIME_ARN, and the agent may
+// invoke them (see CanInvoke).
+func (b *AgentBuilder) DependsOn(agents ...string) *AgentBuilder {
+	b.options.DependsOn = append(b.options.DependsOn, agents...)
+	return b
+}
+
+// CanInvoke lets the agent call other agents of the stack by granting the
+// execution role bedrock-agentcore:InvokeAgentRuntime on their runtimes.
+func (b *AgentBuilder) CanInvoke(agents ...string) *AgentBuilder {
+	b.options.CanInvoke = append(b.options.CanInvoke, agents...)
+	return b
+}
+
+// WithKnowledgeBase lets the agent query knowledge bases of the stack: their
+// IDs are injected as KNOWLEDGE_BASE_{NAME}_ID (and KNOWLEDGE_BASE_ID for a
+// single one) and the agent may call bedrock:Retrieve and
+// bedrock:RetrieveAndGenerate on them.
+func (b *AgentBuilder) WithKnowledgeBase(names ...string) *AgentBuilder {
+	b.options.KnowledgeBases = append(b.options.KnowledgeBases, names...)
+	return b
+}
+
+// WithHealthCheck sets the payload smoke tests invoke the agent with after
+// a deploy.
+func (b *AgentBuilder) WithHealthCheck(payload map[string]any) *AgentBuilder {
+	return b.WithHealthCheckOptions(HealthCheckOptions{Payload: payload})
+}
+
+// WithHealthCheckOptions configures the smoke test of the agent with full
+// options.
+func (b *AgentBuilder) WithHealthCheckOptions(opts HealthCheckOptions) *AgentBuilder {
+	b.options.HealthCheck = &opts
+	return b
+}
+
+// WithMemoryStore provisions an AgentCore Memory resource for the agent.
+// The execution role is granted access and the memory ID is injected as
+// AGENTCORE_MEMORY_ID. Add the agent with StackBuilder.WithAgentBuilder to
+// keep the name and expiry; agents added via Build get default settings.
+func (b *AgentBuilder) WithMemoryStore(name string, eventExpiryDays int) *AgentBuilder {
+	b.config.EnableMemory = true
+	b.options.MemoryStore = &MemoryStoreConfig{
+		Name:            name,
+		EventExpiryDays: eventExpiryDays,
+	}
+	return b
+}
+
+// WithNetworkMode sets the runtime network mode ("VPC" or "PUBLIC").
+func (b *AgentBuilder) WithNetworkMode(mode string) *AgentBuilder {
+	b.options.NetworkMode = mode
+	return b
+}
+
+// WithPublicNetwork runs the agent outside the VPC. Use for lightweight
+// agents that only call public APIs.
+func (b *AgentBuilder) WithPublicNetwork() *AgentBuilder {
+	return b.WithNetworkMode(NetworkModePublic)
+}
+
+// WithSubnets overrides the stack's private subnets for this agent.
+func (b *AgentBuilder) WithSubnets(subnetIDs ...string) *AgentBuilder {
+	b.options.SubnetIDs = append(b.options.SubnetIDs, subnetIDs...)
+	return b
+}
+
+// WithSecurityGroups overrides the stack security group for this agent.
+func (b *AgentBuilder) WithSecurityGroups(securityGroupIDs ...string) *AgentBuilder {
+	b.options.SecurityGroupIDs = append(b.options.SecurityGroupIDs, securityGroupIDs...)
+	return b
+}
+
+// WithEndpointName renames the agent's default runtime endpoint.
+func (b *AgentBuilder) WithEndpointName(name string) *AgentBuilder {
+	b.options.EndpointName = name
+	return b
+}
+
+// WithEndpoint adds a named runtime endpoint, pinned to runtimeVersion if
+// it is not empty.
+func (b *AgentBuilder) WithEndpoint(name, runtimeVersion string) *AgentBuilder {
+	b.options.Endpoints = append(b.options.Endpoints, EndpointOptions{
+		Name:           name,
+		RuntimeVersion: runtimeVersion,
+	})
+	return b
+}
+
+// WithContract publishes JSON Schemas of the agent's request and response
+// payloads. Empty paths leave that side of the contract open.
+func (b *AgentBuilder) WithContract(requestSchemaFile, responseSchemaFile string) *AgentBuilder {
+	b.options.Contract = &ContractOptions{
+		RequestSchemaFile:  requestSchemaFile,
+		ResponseSchemaFile: responseSchemaFile,
+	}
+	return b
+}
+
+// WithSchedule invokes the agent with payload on a schedule. The expression
+// is a cron(), rate(), or at() expression, or a bare six-field cron
+// expression such as "0 2 * * ? *" (02:00 UTC daily).
+func (b *AgentBuilder) WithSchedule(expression string, payload map[string]any) *AgentBuilder {
+	return b.WithScheduleOptions(ScheduleOptions{
+		Expression: expression,
+		Payload:    payload,
+	})
+}
+
+// WithScheduleOptions invokes the agent on a schedule with full options.
+func (b *AgentBuilder) WithScheduleOptions(opts ScheduleOptions) *AgentBuilder {
+	b.options.Schedules = append(b.options.Schedules, opts)
+	return b
+}
+
+// WithSQSTrigger invokes the agent with each message of an SQS queue. An
+// empty queueARN creates the queue.
+func (b *AgentBuilder) WithSQSTrigger(queueARN string) *AgentBuilder {
+	return b.WithTriggerOptions(TriggerOptions{Type: TriggerTypeSQS, SourceARN: queueARN})
+}
+
+// WithSNSTrigger invokes the agent with each message of an SNS topic. An
+// empty topicARN creates the topic.
+func (b *AgentBuilder) WithSNSTrigger(topicARN string) *AgentBuilder {
+	return b.WithTriggerOptions(TriggerOptions{Type: TriggerTypeSNS, SourceARN: topicARN})
+}
+
+// WithEventBridgeTrigger invokes the agent with each event on the default
+// event bus that matches eventPattern.
+func (b *AgentBuilder) WithEventBridgeTrigger(eventPattern map[string]any) *AgentBuilder {
+	return b.WithTriggerOptions(TriggerOptions{Type: TriggerTypeEventBridge, EventPattern: eventPattern})
+}
+
+// WithTriggerOptions invokes the agent from an event source with full
+// options.
+func (b *AgentBuilder) WithTriggerOptions(opts TriggerOptions) *AgentBuilder {
+	b.options.Triggers = append(b.options.Triggers, opts)
+	return b
+}
+
 // AsDefault marks this agent as the default.
 func (b *AgentBuilder) AsDefault() *AgentBuilder {
 	b.config.IsDefault = true
@@ -262,6 +1146,11 @@ func (b *AgentBuilder) Build() AgentConfig {
 	return b.config
 }
 
+// Options returns the CDK-specific agent options.
+func (b *AgentBuilder) Options() AgentOptions {
+	return b.options
+}
+
 // NewApp creates a new CDK app with common settings.
 func NewApp() awscdk.App {
 	return awscdk.NewApp(&awscdk.AppProps{
@@ -0,0 +1,137 @@
+package agentcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/plexusone/agentkit-aws-cdk/contracts"
+)
+
+// ContractOptions attaches JSON Schemas for an agent's request and response
+// payloads. The schemas are published to SSM so callers can validate
+// payloads with the contracts package. Each schema is given inline or as a
+// JSON file path relative to the CDK app directory.
+type ContractOptions struct {
+	// RequestSchema is the inline schema of request payloads.
+	RequestSchema map[string]any `json:"requestSchema,omitempty" yaml:"requestSchema,omitempty"`
+
+	// RequestSchemaFile is a JSON file holding the request schema.
+	RequestSchemaFile string `json:"requestSchemaFile,omitempty" yaml:"requestSchemaFile,omitempty"`
+
+	// ResponseSchema is the inline schema of response payloads.
+	ResponseSchema map[string]any `json:"responseSchema,omitempty" yaml:"responseSchema,omitempty"`
+
+	// ResponseSchemaFile is a JSON file holding the response schema.
+	ResponseSchemaFile string `json:"responseSchemaFile,omitempty" yaml:"responseSchemaFile,omitempty"`
+}
+
+// validate checks that the schemas load and fit in an SSM parameter.
+func (c *ContractOptions) validate() error {
+	data, err := c.document()
+	if err != nil {
+		return err
+	}
+	if len(data) > ssmAdvancedMaxBytes {
+		return fmt.Errorf("contract is %d bytes, exceeding the %d byte SSM limit", len(data), ssmAdvancedMaxBytes)
+	}
+	return nil
+}
+
+// document returns the contract as a minified contracts.Contract document.
+func (c *ContractOptions) document() ([]byte, error) {
+	request, err := loadSchema("requestSchema", c.RequestSchema, c.RequestSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+	response, err := loadSchema("responseSchema", c.ResponseSchema, c.ResponseSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil && response == nil {
+		return nil, fmt.Errorf("contract requires a request or response schema")
+	}
+
+	document := make(map[string]json.RawMessage)
+	if request != nil {
+		document["request"] = request
+	}
+	if response != nil {
+		document["response"] = response
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := contracts.Parse(data); err != nil {
+		return nil, fmt.Errorf("contract: %w", err)
+	}
+	return data, nil
+}
+
+// loadSchema returns the JSON of an inline or file schema, or nil if neither
+// is set.
+func loadSchema(field string, inline map[string]any, file string) (json.RawMessage, error) {
+	switch {
+	case inline != nil && file != "":
+		return nil, fmt.Errorf("contract.%s and contract.%sFile are mutually exclusive", field, field)
+	case file != "":
+		data, err := os.ReadFile(file) //nolint:gosec // G304: schema path comes from the stack config
+		if err != nil {
+			return nil, fmt.Errorf("reading contract.%sFile: %w", field, err)
+		}
+		var schema any
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		return json.Marshal(schema)
+	case inline != nil:
+		return json.Marshal(inline)
+	}
+	return nil, nil
+}
+
+// publishContract stores the agent's contract in an SSM parameter, grants
+// the execution role read access so orchestration agents can validate calls
+// to it, and exposes the parameter name as AGENTCORE_CONTRACT_PARAMETER and
+// as a stack output.
+func (s *AgentCoreStack) publishContract(config *AgentConfig, envVars map[string]string) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || opts.Contract == nil {
+		return
+	}
+
+	data, err := opts.Contract.document()
+	if err != nil {
+		panic(fmt.Sprintf("invalid stack options: agent %s: %v", config.Name, err))
+	}
+
+	tier := awsssm.ParameterTier_STANDARD
+	if len(data) > ssmStandardMaxBytes {
+		tier = awsssm.ParameterTier_ADVANCED
+	}
+
+	parameterName := contracts.ParameterName(s.Config.StackName, config.Name)
+	parameter := awsssm.NewStringParameter(s.agentScope(config.Name),
+		jsii.String(fmt.Sprintf("Contract-%s", config.Name)),
+		&awsssm.StringParameterProps{
+			ParameterName: jsii.String(parameterName),
+			Description:   jsii.String(fmt.Sprintf("Request/response schemas for agent %s", config.Name)),
+			StringValue:   jsii.String(string(data)),
+			Tier:          tier,
+		},
+	)
+	parameter.GrantRead(s.ExecutionRole)
+
+	envVars["AGENTCORE_CONTRACT_PARAMETER"] = parameterName
+
+	awscdk.NewCfnOutput(s.Stack,
+		jsii.String(fmt.Sprintf("Agent-%s-ContractParameter", config.Name)),
+		&awscdk.CfnOutputProps{
+			Value:       jsii.String(parameterName),
+			Description: jsii.String(fmt.Sprintf("SSM parameter with the contract of agent %s", config.Name)),
+		})
+}
@@ -0,0 +1,453 @@
+package agentcore
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/tabwriter"
+)
+
+// Cost report formats.
+const (
+	// CostReportTable renders the report as a text table.
+	CostReportTable = "table"
+
+	// CostReportJSON renders the report as a CostReport in indented JSON.
+	CostReportJSON = "json"
+)
+
+// Cost item categories.
+const (
+	// CostFixed is a cost paid every month whether or not the agents are
+	// invoked.
+	CostFixed = "fixed"
+
+	// CostUsage is a cost that depends on usage, estimated from the
+	// report's assumptions.
+	CostUsage = "usage"
+)
+
+// hoursPerMonth is the number of hours AWS bills per month.
+const hoursPerMonth = 730
+
+// List prices in us-east-1, in USD.
+const (
+	priceNATGatewayHour         = 0.045
+	priceNATGatewayGB           = 0.045
+	priceInterfaceEndpointHour  = 0.01
+	priceSecretMonth            = 0.40
+	priceKMSKeyMonth            = 1.00
+	priceDashboardMonth         = 3.00
+	priceLogsIngestGB           = 0.50
+	priceLogsStorageGBMonth     = 0.03
+	priceRuntimeVCPUHour        = 0.0895
+	priceRuntimeGBHour          = 0.00945
+	priceGatewayThousandCalls   = 0.005
+	priceMemoryThousandEvents   = 0.25
+	priceXRayMillionTraces      = 5.00
+	priceHTTPAPIMillionCalls    = 1.00
+	priceWebACLMonth            = 5.00
+	priceWAFRuleMonth           = 1.00
+	priceWAFMillionRequests     = 0.60
+	priceCloudFrontMillionHTTPS = 1.00
+	priceFirehoseIngestGB       = 0.029
+	priceOpenSearchOCUHour      = 0.24
+	priceRedisServerlessGBHour  = 0.125
+	priceValkeyServerlessGBHour = 0.084
+)
+
+// CostAssumptions are the usage the usage-based items of a CostReport are
+// estimated from. Zero fields use the defaults of DefaultCostAssumptions.
+type CostAssumptions struct {
+	// SessionHours is how many hours each agent is busy per month.
+	// Default: 100
+	SessionHours float64 `json:"sessionHours"`
+
+	// VCPUs is the number of vCPUs an agent uses while busy.
+	// Default: 1
+	VCPUs float64 `json:"vcpus"`
+
+	// LogGBPerAgent is the log volume of each agent per month.
+	// Default: 1
+	LogGBPerAgent float64 `json:"logGbPerAgent"`
+
+	// NATGBPerAgent is the traffic of each agent through the NAT gateways
+	// per month, such as calls to model providers outside AWS.
+	// Default: 5
+	NATGBPerAgent float64 `json:"natGbPerAgent"`
+
+	// Invocations is the number of invocations per month, across agents,
+	// of the gateway and the HTTP API, and the number of traces.
+	// Default: 100000
+	Invocations float64 `json:"invocations"`
+
+	// MemoryEventsPerAgent is the number of events each agent with memory
+	// stores per month.
+	// Default: 10000
+	MemoryEventsPerAgent float64 `json:"memoryEventsPerAgent"`
+}
+
+// DefaultCostAssumptions returns the default usage of cost estimates: a
+// lightly used development deployment.
+func DefaultCostAssumptions() CostAssumptions {
+	return CostAssumptions{
+		SessionHours:         100,
+		VCPUs:                1,
+		LogGBPerAgent:        1,
+		NATGBPerAgent:        5,
+		Invocations:          100000,
+		MemoryEventsPerAgent: 10000,
+	}
+}
+
+// withDefaults returns the assumptions with zero fields set to the defaults.
+func (a CostAssumptions) withDefaults() CostAssumptions {
+	defaults := DefaultCostAssumptions()
+	for _, field := range []struct {
+		value    *float64
+		fallback float64
+	}{
+		{&a.SessionHours, defaults.SessionHours},
+		{&a.VCPUs, defaults.VCPUs},
+		{&a.LogGBPerAgent, defaults.LogGBPerAgent},
+		{&a.NATGBPerAgent, defaults.NATGBPerAgent},
+		{&a.Invocations, defaults.Invocations},
+		{&a.MemoryEventsPerAgent, defaults.MemoryEventsPerAgent},
+	} {
+		if *field.value == 0 {
+			*field.value = field.fallback
+		}
+	}
+	return a
+}
+
+// CostReport is an estimate of the monthly cost of a stack, itemized into
+// fixed costs and usage-based costs.
+type CostReport struct {
+	// StackName is the name of the stack.
+	StackName string `json:"stackName"`
+
+	// Currency is the currency of the prices, USD.
+	Currency string `json:"currency"`
+
+	// PriceRegion is the region of the list prices used. Prices in other
+	// regions differ by up to about 30%.
+	PriceRegion string `json:"priceRegion"`
+
+	// Items are the line items of the estimate.
+	Items []CostItem `json:"items"`
+
+	// FixedMonthly is the sum of the fixed items.
+	FixedMonthly float64 `json:"fixedMonthly"`
+
+	// UsageMonthly is the sum of the usage-based items.
+	UsageMonthly float64 `json:"usageMonthly"`
+
+	// TotalMonthly is the estimated monthly total.
+	TotalMonthly float64 `json:"totalMonthly"`
+
+	// Assumptions are the usage the usage-based items are estimated from.
+	Assumptions CostAssumptions `json:"assumptions"`
+
+	// Notes are costs the estimate leaves out.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// CostItem is a line item of a CostReport.
+type CostItem struct {
+	// Name describes the item, e.g. "NAT gateway".
+	Name string `json:"name"`
+
+	// Category is CostFixed or CostUsage.
+	Category string `json:"category"`
+
+	// Quantity is the number of units per month.
+	Quantity float64 `json:"quantity"`
+
+	// Unit is the billing unit, e.g. "hour" or "GB".
+	Unit string `json:"unit"`
+
+	// UnitPrice is the price of one unit.
+	UnitPrice float64 `json:"unitPrice"`
+
+	// Monthly is the estimated monthly cost of the item.
+	Monthly float64 `json:"monthly"`
+
+	// Basis explains the quantity, e.g. "6 endpoints x 2 AZs x 730 hours".
+	Basis string `json:"basis"`
+}
+
+// EstimateCost estimates the monthly cost of the stack a StackConfig
+// creates.
+func EstimateCost(config StackConfig) CostReport {
+	return EstimateCostWithOptions(config, StackOptions{}, CostAssumptions{})
+}
+
+// EstimateCostWithOptions estimates the monthly cost of the stack a
+// StackConfig and CDK-specific options create, with usage-based items
+// estimated from the assumptions.
+func EstimateCostWithOptions(config StackConfig, options StackOptions, assumptions CostAssumptions) CostReport {
+	config.ApplyDefaults()
+	assumptions = assumptions.withDefaults()
+	report := CostReport{
+		StackName:   config.StackName,
+		Currency:    "USD",
+		PriceRegion: "us-east-1",
+		Assumptions: assumptions,
+	}
+	add := func(name, category string, quantity float64, unit string, unitPrice float64, basis string) {
+		report.Items = append(report.Items, CostItem{
+			Name:      name,
+			Category:  category,
+			Quantity:  quantity,
+			Unit:      unit,
+			UnitPrice: unitPrice,
+			Monthly:   roundCents(quantity * unitPrice),
+			Basis:     basis,
+		})
+	}
+	agents := float64(len(config.Agents))
+
+	// Network: only a created VPC has NAT gateways and endpoints
+	natGateways := 0
+	if costNeedsVPC(config, options) && config.VPC.VPCID == "" && config.VPC.CreateVPC {
+		azs := config.VPC.MaxAZs
+		if azs == 0 {
+			azs = 2
+		}
+		vpcOptions := options.VPC
+		if vpcOptions == nil {
+			vpcOptions = &VPCOptions{}
+		}
+
+		switch {
+		case vpcOptions.IsolatedSubnets:
+		case vpcOptions.NatInstanceType != "":
+			report.Notes = append(report.Notes, fmt.Sprintf("NAT instances (%s), billed at EC2 prices", vpcOptions.NatInstanceType))
+		default:
+			natGateways = DefaultNatGateways
+			if vpcOptions.NatGateways != nil {
+				natGateways = *vpcOptions.NatGateways
+			}
+			add("NAT gateway", CostFixed, float64(natGateways*hoursPerMonth), "hour", priceNATGatewayHour,
+				fmt.Sprintf("%d x %d hours", natGateways, hoursPerMonth))
+		}
+
+		if config.VPC.EnableVPCEndpoints {
+			endpoints := 6 // bedrock, bedrock-runtime, secretsmanager, logs, ecr.api, ecr.dkr
+			if options.Secrets.usesSSM() {
+				endpoints++
+			}
+			add("Interface VPC endpoints", CostFixed, float64(endpoints*azs*hoursPerMonth), "hour", priceInterfaceEndpointHour,
+				fmt.Sprintf("%d endpoints x %d AZs x %d hours", endpoints, azs, hoursPerMonth))
+		}
+	}
+
+	// Secrets: the stack's own secret and those the agents read
+	secrets := make(map[string]bool)
+	if createsSecret(config) {
+		secrets["stack"] = true
+	}
+	for _, agent := range config.Agents {
+		for _, arn := range agent.SecretsARNs {
+			secrets[arn] = true
+		}
+	}
+	if len(secrets) > 0 {
+		add("Secrets Manager secrets", CostFixed, float64(len(secrets)), "secret-month", priceSecretMonth,
+			fmt.Sprintf("%d secrets created by the stack or read by the agents", len(secrets)))
+	}
+
+	if options.KMS != nil && options.KMS.KeyARN == "" {
+		add("KMS key", CostFixed, 1, "key-month", priceKMSKeyMonth, "customer managed key")
+	}
+	if options.Dashboard != nil {
+		add("CloudWatch dashboard", CostFixed, 1, "dashboard-month", priceDashboardMonth, "1 dashboard")
+	}
+
+	// Serverless caches bill a minimum of stored data; node prices vary by
+	// type
+	if redis := options.Redis; redis != nil {
+		switch {
+		case !redis.Serverless:
+			report.Notes = append(report.Notes, fmt.Sprintf("ElastiCache cache (%d x %s), billed at ElastiCache node prices", 1+redis.Replicas, redis.NodeType))
+		case redis.engine() == RedisEngineValkey:
+			add("ElastiCache Serverless Valkey", CostFixed, 0.1*hoursPerMonth, "GB-hour", priceValkeyServerlessGBHour, "100 MB minimum storage")
+		default:
+			add("ElastiCache Serverless Redis", CostFixed, hoursPerMonth, "GB-hour", priceRedisServerlessGBHour, "1 GB minimum storage")
+		}
+	}
+
+	// OpenSearch Serverless bills its minimum OCUs around the clock: half
+	// an OCU each for indexing and search, doubled by standby replicas
+	for _, kb := range options.KnowledgeBases {
+		if kb.KnowledgeBaseID != "" || kb.vectorStore() != VectorStoreOpenSearchServerless {
+			continue
+		}
+		ocus := 1.0
+		if kb.StandbyReplicas {
+			ocus = 2
+		}
+		add(fmt.Sprintf("OpenSearch Serverless %s", kb.Name), CostFixed, ocus*hoursPerMonth, "OCU-hour", priceOpenSearchOCUHour,
+			fmt.Sprintf("%g minimum OCUs x %d hours", ocus, hoursPerMonth))
+	}
+
+	// Runtime compute is billed only while sessions are active
+	for _, agent := range config.Agents {
+		memoryMB := agent.MemoryMB
+		if memoryMB == 0 {
+			memoryMB = 512
+		}
+		add(fmt.Sprintf("Runtime %s: CPU", agent.Name), CostUsage, assumptions.SessionHours*assumptions.VCPUs, "vCPU-hour", priceRuntimeVCPUHour,
+			fmt.Sprintf("%g vCPU x %g session hours", assumptions.VCPUs, assumptions.SessionHours))
+		add(fmt.Sprintf("Runtime %s: memory", agent.Name), CostUsage, assumptions.SessionHours*float64(memoryMB)/1024, "GB-hour", priceRuntimeGBHour,
+			fmt.Sprintf("%d MB x %g session hours", memoryMB, assumptions.SessionHours))
+		if resolveMemoryStore(agent, options.Agents[agent.Name]) != nil {
+			add(fmt.Sprintf("Memory %s: events", agent.Name), CostUsage, assumptions.MemoryEventsPerAgent/1000, "1K events", priceMemoryThousandEvents,
+				fmt.Sprintf("%g short-term memory events", assumptions.MemoryEventsPerAgent))
+		}
+	}
+
+	// Logs are stored for the retention period; infinite retention grows
+	// without bound, so a year of logs is estimated
+	if config.Observability.EnableCloudWatchLogs {
+		logGB := agents * assumptions.LogGBPerAgent
+		retentionDays := config.Observability.LogRetentionDays
+		if retentionDays == 0 {
+			retentionDays = 30
+		}
+		retentionMonths := float64(retentionDays) / 30
+		if retentionDays < 0 || retentionDays > 365 {
+			retentionMonths = 12
+		}
+		add("CloudWatch Logs ingestion", CostUsage, logGB, "GB", priceLogsIngestGB,
+			fmt.Sprintf("%g GB per agent", assumptions.LogGBPerAgent))
+		add("CloudWatch Logs storage", CostUsage, logGB*retentionMonths, "GB-month", priceLogsStorageGBMonth,
+			fmt.Sprintf("%g GB per month kept %d days", logGB, retentionDays))
+		if options.Observability != nil && options.Observability.LogDestination != nil && options.Observability.LogDestination.Firehose != nil {
+			add("Firehose log delivery", CostUsage, logGB, "GB", priceFirehoseIngestGB,
+				fmt.Sprintf("%g GB per agent", assumptions.LogGBPerAgent))
+		}
+	}
+
+	if natGateways > 0 {
+		add("NAT gateway data processing", CostUsage, agents*assumptions.NATGBPerAgent, "GB", priceNATGatewayGB,
+			fmt.Sprintf("%g GB per agent", assumptions.NATGBPerAgent))
+	}
+	if config.Gateway != nil && config.Gateway.Enabled {
+		add("Gateway tool invocations", CostUsage, assumptions.Invocations/1000, "1K calls", priceGatewayThousandCalls,
+			fmt.Sprintf("%g invocations", assumptions.Invocations))
+	}
+	if options.HTTPAPI != nil || options.CustomDomain != nil {
+		add("HTTP API requests", CostUsage, assumptions.Invocations/1000000, "1M requests", priceHTTPAPIMillionCalls,
+			fmt.Sprintf("%g requests", assumptions.Invocations))
+	}
+	if waf := options.WAF; waf != nil {
+		if waf.WebACLARN == "" {
+			rules := len(waf.ManagedRuleGroups)
+			if rules == 0 {
+				rules = len(DefaultWAFManagedRuleGroups)
+			}
+			if waf.RateLimit >= 0 {
+				rules++
+			}
+			if len(waf.AllowedIPs) > 0 {
+				rules++
+			}
+			add("AWS WAF web ACL", CostFixed, 1, "web-ACL-month", priceWebACLMonth, "1 web ACL")
+			add("AWS WAF rules", CostFixed, float64(rules), "rule-month", priceWAFRuleMonth, fmt.Sprintf("%d rules", rules))
+		}
+		add("AWS WAF requests", CostUsage, assumptions.Invocations/1000000, "1M requests", priceWAFMillionRequests,
+			fmt.Sprintf("%g requests", assumptions.Invocations))
+		add("CloudFront HTTPS requests", CostUsage, assumptions.Invocations/1000000, "1M requests", priceCloudFrontMillionHTTPS,
+			fmt.Sprintf("%g requests; data transfer not included", assumptions.Invocations))
+	}
+	if (options.Observability != nil && options.Observability.XRay != nil) || config.Observability.EnableXRay {
+		add("X-Ray traces", CostUsage, assumptions.Invocations/1000000, "1M traces", priceXRayMillionTraces,
+			fmt.Sprintf("%g traces", assumptions.Invocations))
+	}
+
+	for _, item := range report.Items {
+		if item.Category == CostFixed {
+			report.FixedMonthly += item.Monthly
+		} else {
+			report.UsageMonthly += item.Monthly
+		}
+	}
+	report.FixedMonthly = roundCents(report.FixedMonthly)
+	report.UsageMonthly = roundCents(report.UsageMonthly)
+	report.TotalMonthly = roundCents(report.FixedMonthly + report.UsageMonthly)
+	report.Notes = append(report.Notes,
+		"Bedrock model tokens, billed per model and token",
+		"Data transfer out of AWS",
+		"Secrets Manager and KMS API calls",
+	)
+	if len(options.RawResources) > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("%d raw resources", len(options.RawResources)))
+	}
+	return report
+}
+
+// costNeedsVPC reports whether any agent attaches to the VPC, as
+// AgentCoreStack.needsVPC.
+func costNeedsVPC(config StackConfig, options StackOptions) bool {
+	for _, agent := range config.Agents {
+		opts := options.Agents[agent.Name]
+		if opts == nil || opts.NetworkMode == "" || opts.NetworkMode == NetworkModeVPC {
+			return true
+		}
+	}
+	return false
+}
+
+// Table renders the report as a text table.
+func (r *CostReport) Table() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Estimated monthly cost of %s (%s list prices, %s)\n\n", r.StackName, r.PriceRegion, r.Currency)
+
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ITEM\tCATEGORY\tQUANTITY\tUNIT PRICE\tMONTHLY\tBASIS")
+	for _, item := range r.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s %s\t$%s\t$%.2f\t%s\n",
+			item.Name, item.Category, formatQuantity(item.Quantity), item.Unit, formatPrice(item.UnitPrice), item.Monthly, item.Basis)
+	}
+	fmt.Fprintf(w, "Fixed\t\t\t\t$%.2f\t\n", r.FixedMonthly)
+	fmt.Fprintf(w, "Usage\t\t\t\t$%.2f\t\n", r.UsageMonthly)
+	fmt.Fprintf(w, "Total\t\t\t\t$%.2f\t\n", r.TotalMonthly)
+	_ = w.Flush()
+
+	a := r.Assumptions
+	sb.WriteString("\nAssumptions:\n")
+	fmt.Fprintf(&sb, "  - Each agent is busy %g hours per month with %g vCPU\n", a.SessionHours, a.VCPUs)
+	fmt.Fprintf(&sb, "  - Each agent writes %g GB of logs and sends %g GB through NAT per month\n", a.LogGBPerAgent, a.NATGBPerAgent)
+	fmt.Fprintf(&sb, "  - %g invocations (and traces) per month; %g memory events per agent with memory\n", a.Invocations, a.MemoryEventsPerAgent)
+	if len(r.Notes) > 0 {
+		sb.WriteString("\nNot included:\n")
+		for _, note := range r.Notes {
+			fmt.Fprintf(&sb, "  - %s\n", note)
+		}
+	}
+	return sb.String()
+}
+
+// roundCents rounds an amount to cents.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// formatQuantity formats a quantity without needless decimals.
+func formatQuantity(quantity float64) string {
+	if quantity == float64(int64(quantity)) {
+		return fmt.Sprintf("%d", int64(quantity))
+	}
+	return fmt.Sprintf("%.2f", quantity)
+}
+
+// formatPrice formats a unit price with up to five decimals.
+func formatPrice(price float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.5f", price), "0")
+	if strings.HasSuffix(s, ".") || strings.Index(s, ".") == len(s)-2 {
+		s = fmt.Sprintf("%.2f", price)
+	}
+	return s
+}
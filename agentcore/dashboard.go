@@ -0,0 +1,186 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// agentCoreNamespace is the CloudWatch namespace of AgentCore runtime metrics.
+const agentCoreNamespace = "AWS/Bedrock-AgentCore"
+
+// dashboardNamePattern is the naming rule for CloudWatch dashboards.
+var dashboardNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
+
+// DashboardOptions configures a CloudWatch dashboard showing per-agent
+// invocations, error rates, and latency percentiles, and log-derived error
+// and warning counts.
+type DashboardOptions struct {
+	// Name is the dashboard name.
+	// Default: "{stackName}-agents"
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// PeriodMinutes is the aggregation period of the graphs.
+	// Default: 5
+	PeriodMinutes int `json:"periodMinutes,omitempty" yaml:"periodMinutes,omitempty"`
+}
+
+// validate validates the dashboard options.
+func (o *DashboardOptions) validate() error {
+	if o.Name != "" && !dashboardNamePattern.MatchString(o.Name) {
+		return fmt.Errorf("dashboard.name %q must contain only letters, digits, '_', and '-'", o.Name)
+	}
+	if o.PeriodMinutes < 0 || o.PeriodMinutes > 1440 {
+		return fmt.Errorf("dashboard.periodMinutes must be between 1 and 1440, got %d", o.PeriodMinutes)
+	}
+	return nil
+}
+
+// createDashboard creates the agent dashboard and its URL output.
+func (s *AgentCoreStack) createDashboard() {
+	opts := s.Options.Dashboard
+	if opts == nil {
+		return
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-agents", s.Config.StackName)
+	}
+	periodMinutes := opts.PeriodMinutes
+	if periodMinutes == 0 {
+		periodMinutes = 5
+	}
+	period := awscdk.Duration_Minutes(jsii.Number(float64(periodMinutes)))
+
+	s.Dashboard = awscloudwatch.NewDashboard(s.Stack, jsii.String("Dashboard"), &awscloudwatch.DashboardProps{
+		DashboardName: jsii.String(name),
+	})
+
+	for _, agent := range s.Config.Agents {
+		s.Dashboard.AddWidgets(agentWidgets(agent.Name, period)...)
+	}
+	if widgets := s.logMetricWidgets(period); len(widgets) > 0 {
+		s.Dashboard.AddWidgets(widgets...)
+	}
+	if widget := s.logMetricWidget(period); widget != nil {
+		s.Dashboard.AddWidgets(widget)
+	}
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("DashboardUrl"), &awscdk.CfnOutputProps{
+		Value: jsii.String(fmt.Sprintf("https://%s.console.aws.amazon.com/cloudwatch/home?region=%s#dashboards:name=%s",
+			*s.Stack.Region(), *s.Stack.Region(), name)),
+		Description: jsii.String("CloudWatch dashboard URL"),
+	})
+}
+
+// agentWidgets returns the invocation, error rate, and latency graphs of an
+// agent. AgentCore metrics are found by searching for the runtime name, so
+// the graphs also cover every endpoint of the runtime.
+func agentWidgets(agentName string, period awscdk.Duration) []awscloudwatch.IWidget {
+	search := func(metric, statistic, label string) awscloudwatch.MathExpression {
+		return awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+			Expression: jsii.String(fmt.Sprintf(`SUM(SEARCH('{%s} MetricName="%s" "%s"', '%s', %d))`,
+				agentCoreNamespace, metric, agentName, statistic, int(*period.ToSeconds(nil)))),
+			Label:  jsii.String(label),
+			Period: period,
+		})
+	}
+	latency := func(statistic string) awscloudwatch.MathExpression {
+		return awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+			Expression: jsii.String(fmt.Sprintf(`MAX(SEARCH('{%s} MetricName="Latency" "%s"', '%s', %d))`,
+				agentCoreNamespace, agentName, statistic, int(*period.ToSeconds(nil)))),
+			Label:  jsii.String(statistic),
+			Period: period,
+		})
+	}
+
+	invocations := search("Invocations", "Sum", "Invocations")
+	errors := awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+		Expression: jsii.String("systemErrors + userErrors"),
+		UsingMetrics: &map[string]awscloudwatch.IMetric{
+			"systemErrors": search("SystemErrors", "Sum", "System errors"),
+			"userErrors":   search("UserErrors", "Sum", "User errors"),
+		},
+		Label:  jsii.String("Errors"),
+		Period: period,
+	})
+	errorRate := awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+		Expression: jsii.String("IF(invocations > 0, 100 * errors / invocations, 0)"),
+		UsingMetrics: &map[string]awscloudwatch.IMetric{
+			"invocations": invocations,
+			"errors":      errors,
+		},
+		Label:  jsii.String("Error rate (%)"),
+		Period: period,
+	})
+
+	return []awscloudwatch.IWidget{
+		awscloudwatch.NewGraphWidget(&awscloudwatch.GraphWidgetProps{
+			Title:  jsii.String(fmt.Sprintf("%s: invocations", agentName)),
+			Left:   &[]awscloudwatch.IMetric{invocations, search("Throttles", "Sum", "Throttles")},
+			Width:  jsii.Number(8),
+			Period: period,
+		}),
+		awscloudwatch.NewGraphWidget(&awscloudwatch.GraphWidgetProps{
+			Title:  jsii.String(fmt.Sprintf("%s: error rate", agentName)),
+			Left:   &[]awscloudwatch.IMetric{errorRate},
+			Right:  &[]awscloudwatch.IMetric{errors},
+			Width:  jsii.Number(8),
+			Period: period,
+		}),
+		awscloudwatch.NewGraphWidget(&awscloudwatch.GraphWidgetProps{
+			Title:  jsii.String(fmt.Sprintf("%s: latency (ms)", agentName)),
+			Left:   &[]awscloudwatch.IMetric{latency("p50"), latency("p90"), latency("p99")},
+			Width:  jsii.Number(8),
+			Period: period,
+		}),
+	}
+}
+
+// logMetricWidgets creates metric filters counting error and warning lines
+// in the agent log group and returns their graph. It returns nil when the
+// stack has no log group.
+func (s *AgentCoreStack) logMetricWidgets(period awscdk.Duration) []awscloudwatch.IWidget {
+	if s.LogGroup == nil {
+		return nil
+	}
+
+	namespace := fmt.Sprintf("AgentKit/%s", s.Config.StackName)
+	filters := []struct {
+		metric string
+		terms  []string
+	}{
+		{"LogErrors", []string{"ERROR", "Error", "error", "FATAL", "panic"}},
+		{"LogWarnings", []string{"WARN", "Warn", "warn", "WARNING"}},
+	}
+
+	metrics := make([]awscloudwatch.IMetric, 0, len(filters))
+	for _, f := range filters {
+		filter := awslogs.NewMetricFilter(s.Stack, jsii.String(fmt.Sprintf("MetricFilter-%s", f.metric)), &awslogs.MetricFilterProps{
+			LogGroup:        s.LogGroup,
+			FilterPattern:   awslogs.FilterPattern_AnyTerm(*jsii.Strings(f.terms...)...),
+			MetricNamespace: jsii.String(namespace),
+			MetricName:      jsii.String(f.metric),
+			MetricValue:     jsii.String("1"),
+			DefaultValue:    jsii.Number(0),
+		})
+		metrics = append(metrics, filter.Metric(&awscloudwatch.MetricOptions{
+			Statistic: jsii.String("Sum"),
+			Period:    period,
+		}))
+	}
+
+	return []awscloudwatch.IWidget{
+		awscloudwatch.NewGraphWidget(&awscloudwatch.GraphWidgetProps{
+			Title:  jsii.String("Agent log errors and warnings"),
+			Left:   &metrics,
+			Width:  jsii.Number(24),
+			Period: period,
+		}),
+	}
+}
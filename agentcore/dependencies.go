@@ -0,0 +1,186 @@
+package agentcore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// validateDependencies validates the agents' dependsOn lists: they must name
+// other agents of the stack and must not form a cycle.
+func validateDependencies(agents []AgentConfig, options map[string]*AgentOptions) error {
+	names := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		names[agent.Name] = true
+	}
+	for i, agent := range agents {
+		opts := options[agent.Name]
+		if opts == nil {
+			continue
+		}
+		for _, dependency := range opts.DependsOn {
+			switch {
+			case dependency == agent.Name:
+				return fmt.Errorf("agents[%d] (%s): dependsOn: an agent cannot depend on itself", i, agent.Name)
+			case !names[dependency]:
+				return fmt.Errorf("agents[%d] (%s): dependsOn: unknown agent %q", i, agent.Name, dependency)
+			}
+		}
+	}
+	_, err := dependencyOrder(agents, options)
+	return err
+}
+
+// dependencyOrder returns the agents ordered so that each comes after the
+// agents it depends on, keeping the config order otherwise. It returns an
+// error naming the agents of a dependency cycle.
+func dependencyOrder(agents []AgentConfig, options map[string]*AgentOptions) ([]AgentConfig, error) {
+	byName := make(map[string]AgentConfig, len(agents))
+	for _, agent := range agents {
+		byName[agent.Name] = agent
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(agents))
+	ordered := make([]AgentConfig, 0, len(agents))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+				}
+			}
+			return fmt.Errorf("agent dependency cycle: %s -> %s", strings.Join(path[start:], " -> "), name)
+		}
+		state[name] = visiting
+		path = append(path, name)
+		if opts := options[name]; opts != nil {
+			for _, dependency := range opts.DependsOn {
+				if _, ok := byName[dependency]; !ok {
+					continue // Reported by validateDependencies
+				}
+				if err := visit(dependency); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		ordered = append(ordered, byName[name])
+		return nil
+	}
+
+	for _, agent := range agents {
+		if err := visit(agent.Name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// DependencyEnvName returns the environment variable that holds the
+// endpoint ARN of an upstream agent, e.g. AGENT_RESEARCH_ENDPOINT_ARN for
+// "research".
+func DependencyEnvName(agent string) string {
+	return "AGENT_" + agentEnvSegment(agent) + "_ENDPOINT_ARN"
+}
+
+// agentEnvSegment converts an agent name to the upper-case form used in
+// environment variable names.
+func agentEnvSegment(agent string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(agent))
+}
+
+// addDependencyEnvironment points the agent at the endpoints and runtimes
+// of the agents it depends on, and makes its runtime wait for them. The
+// upstream agents are created first (see dependencyOrder).
+func (s *AgentCoreStack) addDependencyEnvironment(config *AgentConfig, envVars map[string]string) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil {
+		return
+	}
+	for _, dependency := range opts.DependsOn {
+		envVars[DependencyEnvName(dependency)] = *s.Endpoints[dependency].AttrAgentRuntimeEndpointArn()
+		envVars["AGENT_"+agentEnvSegment(dependency)+"_RUNTIME_ARN"] = *s.Runtimes[dependency].AttrAgentRuntimeArn()
+	}
+}
+
+// addDependencyOrdering makes the agent's runtime depend on the default
+// endpoints of its upstream agents, so CloudFormation creates them first
+// even when the environment variables are overridden.
+func (s *AgentCoreStack) addDependencyOrdering(config *AgentConfig) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil {
+		return
+	}
+	for _, dependency := range opts.DependsOn {
+		s.Runtimes[config.Name].Node().AddDependency(s.Endpoints[dependency])
+	}
+}
+
+// validateInvokes validates the agents' canInvoke lists: they must name
+// other agents of the stack.
+func validateInvokes(agents []AgentConfig, options map[string]*AgentOptions) error {
+	names := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		names[agent.Name] = true
+	}
+	for i, agent := range agents {
+		opts := options[agent.Name]
+		if opts == nil {
+			continue
+		}
+		for _, target := range opts.CanInvoke {
+			switch {
+			case target == agent.Name:
+				return fmt.Errorf("agents[%d] (%s): canInvoke: an agent cannot invoke itself", i, agent.Name)
+			case !names[target]:
+				return fmt.Errorf("agents[%d] (%s): canInvoke: unknown agent %q", i, agent.Name, target)
+			}
+		}
+	}
+	return nil
+}
+
+// grantAgentInvokes grants the execution role
+// bedrock-agentcore:InvokeAgentRuntime on the runtimes (and their endpoints)
+// of the agents other agents call through canInvoke or dependsOn.
+func (s *AgentCoreStack) grantAgentInvokes() {
+	targets := make(map[string]bool)
+	for _, agent := range s.Config.Agents {
+		if opts := s.Options.Agents[agent.Name]; opts != nil {
+			for _, target := range opts.CanInvoke {
+				targets[target] = true
+			}
+			for _, target := range opts.DependsOn {
+				targets[target] = true
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	var resources []*string
+	for _, target := range sortedKeys(targets) {
+		runtimeARN := s.Runtimes[target].AttrAgentRuntimeArn()
+		resources = append(resources, runtimeARN, jsii.String(fmt.Sprintf("%s/*", *runtimeARN)))
+	}
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("bedrock-agentcore:InvokeAgentRuntime"),
+		Resources: &resources,
+	}))
+}
@@ -0,0 +1,116 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2integrations"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// gatewayRoutePath is the path the gateway is served on under a custom
+// domain.
+const gatewayRoutePath = "/mcp"
+
+// CustomDomainOptions serves the agents and the gateway on a custom domain
+// in a Route 53 hosted zone, so clients use friendly URLs instead of raw AWS
+// ones:
+//
+//	https://{name}/agents/{agent}/invoke   # Agents, through the HTTP API
+//	https://{name}/mcp                     # The gateway, if enabled
+//
+// The HTTP API is created with default options if it isn't configured, and
+// the domain takes the place of httpApi.domain.
+type CustomDomainOptions struct {
+	// Name is the domain name, e.g. "agents.example.com".
+	Name string `json:"name" yaml:"name"`
+
+	// HostedZoneID is the Route 53 hosted zone the alias record and the
+	// certificate validation records are created in.
+	HostedZoneID string `json:"hostedZoneId" yaml:"hostedZoneId"`
+
+	// HostedZoneName is the name of the hosted zone, e.g. "example.com".
+	// Default: the record is created with the fully qualified domain name
+	HostedZoneName string `json:"hostedZoneName,omitempty" yaml:"hostedZoneName,omitempty"`
+
+	// CertificateARN is an ACM certificate for the domain in the stack
+	// region.
+	// Default: a DNS-validated certificate in the hosted zone
+	CertificateARN string `json:"certificateArn,omitempty" yaml:"certificateArn,omitempty"`
+}
+
+// validate validates the custom domain options.
+func (o *CustomDomainOptions) validate(httpAPI *HTTPAPIOptions) error {
+	switch {
+	case o.Name == "":
+		return fmt.Errorf("customDomain.name is required")
+	case !*awscdk.Token_IsUnresolved(o.Name) && !domainNamePattern.MatchString(o.Name):
+		return fmt.Errorf("customDomain.name %q must be a lowercase domain name", o.Name)
+	case o.HostedZoneID == "":
+		return fmt.Errorf("customDomain.hostedZoneId is required")
+	case o.CertificateARN != "" && !*awscdk.Token_IsUnresolved(o.CertificateARN) && !certificateARNPattern.MatchString(o.CertificateARN):
+		return fmt.Errorf("customDomain.certificateArn %q must be an ACM certificate ARN", o.CertificateARN)
+	case httpAPI != nil && httpAPI.Domain != nil:
+		return fmt.Errorf("customDomain serves the HTTP API; remove httpApi.domain")
+	}
+	return nil
+}
+
+// httpAPIOptions returns the options of the HTTP API, or nil if there is
+// none: Options.HTTPAPI, with the custom domain if one is configured.
+func (s *AgentCoreStack) httpAPIOptions() *HTTPAPIOptions {
+	domain := s.Options.CustomDomain
+	if domain == nil {
+		return s.Options.HTTPAPI
+	}
+
+	var opts HTTPAPIOptions
+	if s.Options.HTTPAPI != nil {
+		opts = *s.Options.HTTPAPI
+	}
+	opts.Domain = &HTTPAPIDomainOptions{
+		Name:           domain.Name,
+		CertificateARN: domain.CertificateARN,
+		HostedZoneID:   domain.HostedZoneID,
+		HostedZoneName: domain.HostedZoneName,
+	}
+	return &opts
+}
+
+// createCustomDomainRoutes adds the gateway route to the HTTP API of the
+// custom domain and outputs the URLs of the agents and the gateway.
+func (s *AgentCoreStack) createCustomDomainRoutes(agentNames []string) {
+	domain := s.Options.CustomDomain
+	if domain == nil {
+		return
+	}
+	baseURL := fmt.Sprintf("https://%s", domain.Name)
+
+	for _, name := range agentNames {
+		awscdk.NewCfnOutput(s.Stack, jsii.String(fmt.Sprintf("Agent-%s-InvokeUrl", name)), &awscdk.CfnOutputProps{
+			Value:       jsii.String(fmt.Sprintf("%s/agents/%s/invoke", baseURL, name)),
+			Description: jsii.String(fmt.Sprintf("Invoke URL of agent %s", name)),
+		})
+	}
+
+	if s.Gateway != nil {
+		// MCP clients authenticate with the gateway, not with the API
+		s.HTTPAPI.AddRoutes(&awsapigatewayv2.AddRoutesOptions{
+			Path:    jsii.String(gatewayRoutePath),
+			Methods: &[]awsapigatewayv2.HttpMethod{awsapigatewayv2.HttpMethod_ANY},
+			Integration: awsapigatewayv2integrations.NewHttpUrlIntegration(jsii.String("GatewayIntegration"),
+				s.Gateway.AttrGatewayUrl(), nil),
+			Authorizer: awsapigatewayv2.NewHttpNoneAuthorizer(),
+		})
+		awscdk.NewCfnOutput(s.Stack, jsii.String("GatewayCustomUrl"), &awscdk.CfnOutputProps{
+			Value:       jsii.String(baseURL + gatewayRoutePath),
+			Description: jsii.String("Gateway URL on the custom domain"),
+		})
+	}
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("CustomDomainUrl"), &awscdk.CfnOutputProps{
+		Value:       jsii.String(baseURL),
+		Description: jsii.String("Custom domain URL of the agents and the gateway"),
+	})
+}
@@ -0,0 +1,104 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// endpointNamePattern is the naming rule for AgentCore runtime endpoints.
+// The default "{agent}-endpoint" name predates it and is kept so existing
+// endpoints are not replaced.
+var endpointNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,47}$`)
+
+// EndpointOptions configures an additional named endpoint of an agent
+// runtime, e.g. "live" and "shadow" endpoints pinned to different runtime
+// versions.
+type EndpointOptions struct {
+	// Name is the endpoint name, used as the invocation qualifier.
+	// Pattern: [a-zA-Z][a-zA-Z0-9_]{0,47}
+	Name string `json:"name" yaml:"name"`
+
+	// Description is the endpoint description.
+	// Default: "{name} endpoint for agent {agent}"
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// RuntimeVersion pins the endpoint to a runtime version.
+	// Default: the latest version
+	RuntimeVersion string `json:"runtimeVersion,omitempty" yaml:"runtimeVersion,omitempty"`
+}
+
+// validateEndpoints validates an agent's endpoint names.
+func validateEndpoints(agentName string, opts *AgentOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.EndpointName != "" && !endpointNamePattern.MatchString(opts.EndpointName) {
+		return fmt.Errorf("endpointName %q must match %s", opts.EndpointName, endpointNamePattern)
+	}
+
+	names := map[string]bool{defaultEndpointName(agentName, opts): true}
+	for i, endpoint := range opts.Endpoints {
+		if !endpointNamePattern.MatchString(endpoint.Name) {
+			return fmt.Errorf("endpoints[%d].name %q must match %s", i, endpoint.Name, endpointNamePattern)
+		}
+		if names[endpoint.Name] {
+			return fmt.Errorf("endpoints[%d].name %q is already used by another endpoint of the agent", i, endpoint.Name)
+		}
+		names[endpoint.Name] = true
+	}
+	return nil
+}
+
+// defaultEndpointName returns the name of an agent's default endpoint.
+func defaultEndpointName(agentName string, opts *AgentOptions) string {
+	if opts != nil && opts.EndpointName != "" {
+		return opts.EndpointName
+	}
+	return fmt.Sprintf("%s-endpoint", agentName)
+}
+
+// createNamedEndpoints creates the additional endpoints of an agent runtime
+// and their outputs.
+func (s *AgentCoreStack) createNamedEndpoints(config *AgentConfig) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || len(opts.Endpoints) == 0 {
+		return
+	}
+
+	runtime := s.Runtimes[config.Name]
+	endpoints := make(map[string]awsbedrockagentcore.CfnRuntimeEndpoint, len(opts.Endpoints))
+	for _, endpointOpts := range opts.Endpoints {
+		description := endpointOpts.Description
+		if description == "" {
+			description = fmt.Sprintf("%s endpoint for agent %s", endpointOpts.Name, config.Name)
+		}
+
+		props := &awsbedrockagentcore.CfnRuntimeEndpointProps{
+			Name:           jsii.String(endpointOpts.Name),
+			AgentRuntimeId: runtime.AttrAgentRuntimeId(),
+			Description:    jsii.String(description),
+			Tags:           s.getTags(config),
+		}
+		if endpointOpts.RuntimeVersion != "" {
+			props.AgentRuntimeVersion = jsii.String(endpointOpts.RuntimeVersion)
+		}
+
+		endpoint := awsbedrockagentcore.NewCfnRuntimeEndpoint(s.agentScope(config.Name),
+			jsii.String(fmt.Sprintf("NamedEndpoint-%s-%s", config.Name, endpointOpts.Name)),
+			props,
+		)
+		endpoints[endpointOpts.Name] = endpoint
+
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("Agent-%s-Endpoint-%s-Arn", config.Name, endpointOpts.Name)),
+			&awscdk.CfnOutputProps{
+				Value:       endpoint.AttrAgentRuntimeEndpointArn(),
+				Description: jsii.String(fmt.Sprintf("ARN of the %s endpoint of agent %s", endpointOpts.Name, config.Name)),
+			})
+	}
+	s.NamedEndpoints[config.Name] = endpoints
+}
@@ -0,0 +1,174 @@
+package agentcore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3deployment"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Config store types for environment indirection.
+const (
+	ConfigStoreSSM = "ssm"
+	ConfigStoreS3  = "s3"
+)
+
+// SSM parameter size limits in bytes.
+const (
+	ssmStandardMaxBytes = 4096
+	ssmAdvancedMaxBytes = 8192
+)
+
+// ConfigStoreOptions moves agents' user-defined environment variables out of
+// the runtime definition into SSM Parameter Store or S3. The runtime receives
+// only a CONFIG_URI pointer, keeping its environment payload under service
+// limits for agents with large configurations.
+type ConfigStoreOptions struct {
+	// Type is the store type: "ssm" or "s3".
+	Type string `json:"type" yaml:"type"`
+
+	// ThresholdBytes offloads only agents whose JSON-encoded environment is
+	// larger than this. Zero offloads every agent with environment variables.
+	ThresholdBytes int `json:"thresholdBytes,omitempty" yaml:"thresholdBytes,omitempty"`
+}
+
+// validate validates the config store settings against the agents.
+func (c *ConfigStoreOptions) validate(agents []AgentConfig) error {
+	switch c.Type {
+	case ConfigStoreSSM:
+		for i, agent := range agents {
+			if size := environmentSize(agent.Environment); c.offloads(agent) && size > ssmAdvancedMaxBytes {
+				return fmt.Errorf("agents[%d] (%s): environment is %d bytes, exceeding the %d byte SSM limit (use configStore.type %s)",
+					i, agent.Name, size, ssmAdvancedMaxBytes, ConfigStoreS3)
+			}
+		}
+	case ConfigStoreS3:
+	default:
+		return fmt.Errorf("configStore.type must be one of [%s %s]", ConfigStoreSSM, ConfigStoreS3)
+	}
+	if c.ThresholdBytes < 0 {
+		return fmt.Errorf("configStore.thresholdBytes must not be negative")
+	}
+	return nil
+}
+
+// offloads reports whether the agent's environment goes to the config store.
+func (c *ConfigStoreOptions) offloads(config AgentConfig) bool {
+	return len(config.Environment) > 0 && environmentSize(config.Environment) > c.ThresholdBytes
+}
+
+// applyGlobalEnvironment merges the global environment into each agent's
+// environment, keeping the agent's own values. The agents get new maps, so
+// configs shared with the caller aren't modified.
+func applyGlobalEnvironment(config *StackConfig, global map[string]string) {
+	if len(global) == 0 {
+		return
+	}
+	agents := make([]AgentConfig, len(config.Agents))
+	for i, agent := range config.Agents {
+		env := make(map[string]string, len(global)+len(agent.Environment))
+		for k, v := range global {
+			env[k] = v
+		}
+		for k, v := range agent.Environment {
+			env[k] = v
+		}
+		agent.Environment = env
+		agents[i] = agent
+	}
+	config.Agents = agents
+}
+
+// environmentSize returns the JSON-encoded size of an environment map.
+func environmentSize(env map[string]string) int {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// offloadEnvironment publishes the agent's user-defined environment variables
+// to the config store, removes them from envVars, and injects CONFIG_URI.
+// Variables set by the stack itself stay inline.
+func (s *AgentCoreStack) offloadEnvironment(config *AgentConfig, envVars map[string]string) {
+	store := s.Options.ConfigStore
+	if store == nil || !store.offloads(*config) {
+		return
+	}
+
+	for k, v := range config.Environment {
+		if envVars[k] == v {
+			delete(envVars, k)
+		}
+	}
+
+	switch store.Type {
+	case ConfigStoreSSM:
+		envVars["CONFIG_URI"] = s.publishConfigToSSM(config)
+	case ConfigStoreS3:
+		envVars["CONFIG_URI"] = s.publishConfigToS3(config)
+	}
+}
+
+// publishConfigToSSM stores the agent environment in an SSM parameter and
+// returns its ssm:// URI.
+func (s *AgentCoreStack) publishConfigToSSM(config *AgentConfig) string {
+	tier := awsssm.ParameterTier_STANDARD
+	if environmentSize(config.Environment) > ssmStandardMaxBytes {
+		tier = awsssm.ParameterTier_ADVANCED
+	}
+
+	parameterName := fmt.Sprintf("/%s/agents/%s/config", s.Config.StackName, config.Name)
+	parameter := awsssm.NewStringParameter(s.agentScope(config.Name),
+		jsii.String(fmt.Sprintf("Config-%s", config.Name)),
+		&awsssm.StringParameterProps{
+			ParameterName: jsii.String(parameterName),
+			Description:   jsii.String(fmt.Sprintf("Environment configuration for agent %s", config.Name)),
+			StringValue:   s.Stack.ToJsonString(config.Environment, nil),
+			Tier:          tier,
+		},
+	)
+	parameter.GrantRead(s.ExecutionRole)
+
+	return "ssm://" + parameterName
+}
+
+// publishConfigToS3 stores the agent environment as a JSON object in the
+// config bucket and returns its s3:// URI.
+func (s *AgentCoreStack) publishConfigToS3(config *AgentConfig) string {
+	if s.ConfigBucket == nil {
+		encryption := awss3.BucketEncryption_S3_MANAGED
+		if s.KMSKey != nil {
+			encryption = awss3.BucketEncryption_KMS
+		}
+		s.ConfigBucket = awss3.NewBucket(s.Stack, jsii.String("ConfigBucket"), &awss3.BucketProps{
+			Encryption:        encryption,
+			EncryptionKey:     s.KMSKey,
+			BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+			EnforceSSL:        jsii.Bool(true),
+			Versioned:         jsii.Bool(true),
+			RemovalPolicy:     awscdk.RemovalPolicy_DESTROY,
+			AutoDeleteObjects: jsii.Bool(true),
+		})
+	}
+
+	prefix := fmt.Sprintf("agents/%s/", config.Name)
+	awss3deployment.NewBucketDeployment(s.Stack,
+		jsii.String(fmt.Sprintf("ConfigDeployment-%s", config.Name)),
+		&awss3deployment.BucketDeploymentProps{
+			DestinationBucket:    s.ConfigBucket,
+			DestinationKeyPrefix: jsii.String(prefix),
+			Sources: &[]awss3deployment.ISource{
+				awss3deployment.Source_JsonData(jsii.String("config.json"), config.Environment, nil),
+			},
+		},
+	)
+	s.ConfigBucket.GrantRead(s.ExecutionRole, jsii.String(prefix+"*"))
+
+	return fmt.Sprintf("s3://%s/%sconfig.json", *s.ConfigBucket.BucketName(), prefix)
+}
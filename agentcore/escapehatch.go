@@ -0,0 +1,73 @@
+package agentcore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+)
+
+// The accessors below are the escape hatch to the AgentCore L1 resources:
+// they return the CloudFormation resources of the stack so properties the
+// options don't model yet can be set with AddPropertyOverride, e.g.
+//
+//	stack.Runtime("research").AddPropertyOverride(jsii.String("LifecycleConfiguration.IdleRuntimeSessionTimeout"), jsii.Number(900))
+//
+// Unlike the maps of AgentCoreStack, they are a stable API, and they panic
+// with the known names when asked for a resource the stack doesn't have, so
+// a typo fails synth instead of silently overriding nothing.
+
+// Runtime returns the runtime resource of an agent.
+func (s *AgentCoreStack) Runtime(agent string) awsbedrockagentcore.CfnRuntime {
+	runtime, ok := s.Runtimes[agent]
+	if !ok {
+		panic(fmt.Sprintf("agentcore: no runtime for agent %q (agents: %s)", agent, strings.Join(sortedKeys(s.Runtimes), ", ")))
+	}
+	return runtime
+}
+
+// RuntimeEndpoint returns the default runtime endpoint resource of an
+// agent.
+func (s *AgentCoreStack) RuntimeEndpoint(agent string) awsbedrockagentcore.CfnRuntimeEndpoint {
+	endpoint, ok := s.Endpoints[agent]
+	if !ok {
+		panic(fmt.Sprintf("agentcore: no runtime endpoint for agent %q (agents: %s)", agent, strings.Join(sortedKeys(s.Endpoints), ", ")))
+	}
+	return endpoint
+}
+
+// NamedRuntimeEndpoint returns an additional runtime endpoint of an agent
+// (AgentOptions.Endpoints).
+func (s *AgentCoreStack) NamedRuntimeEndpoint(agent, name string) awsbedrockagentcore.CfnRuntimeEndpoint {
+	endpoint, ok := s.NamedEndpoints[agent][name]
+	if !ok {
+		panic(fmt.Sprintf("agentcore: no endpoint %q for agent %q (endpoints: %s)", name, agent, strings.Join(sortedKeys(s.NamedEndpoints[agent]), ", ")))
+	}
+	return endpoint
+}
+
+// CfnMemory returns the memory resource of an agent with a memory store.
+func (s *AgentCoreStack) CfnMemory(agent string) awsbedrockagentcore.CfnMemory {
+	memory, ok := s.Memories[agent]
+	if !ok {
+		panic(fmt.Sprintf("agentcore: no memory for agent %q (agents with memory: %s)", agent, strings.Join(sortedKeys(s.Memories), ", ")))
+	}
+	return memory
+}
+
+// CfnGateway returns the gateway resource.
+func (s *AgentCoreStack) CfnGateway() awsbedrockagentcore.CfnGateway {
+	if s.Gateway == nil {
+		panic("agentcore: the stack creates no gateway")
+	}
+	return s.Gateway
+}
+
+// CfnGatewayTarget returns a gateway target resource.
+func (s *AgentCoreStack) CfnGatewayTarget(name string) awsbedrockagentcore.CfnGatewayTarget {
+	target, ok := s.GatewayTargets[name]
+	if !ok {
+		panic(fmt.Sprintf("agentcore: no gateway target %q (targets: %s)", name, strings.Join(sortedKeys(s.GatewayTargets), ", ")))
+	}
+	return target
+}
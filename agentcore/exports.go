@@ -0,0 +1,98 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// exportNameSanitizer matches characters CloudFormation export names can't
+// contain.
+var exportNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9:-]`)
+
+// AgentExportName returns the name an agent output is exported under with
+// StackOptions.ExportOutputs, e.g. "my-agents-research-RuntimeArn".
+// Characters export names can't contain are replaced with hyphens.
+func AgentExportName(stackName, agentName, output string) string {
+	return exportNameSanitizer.ReplaceAllString(fmt.Sprintf("%s-%s-%s", stackName, agentName, output), "-")
+}
+
+// StackExportName returns the name a stack output is exported under with
+// StackOptions.ExportOutputs, e.g. "my-agents-GatewayUrl".
+func StackExportName(stackName, output string) string {
+	return exportNameSanitizer.ReplaceAllString(fmt.Sprintf("%s-%s", stackName, output), "-")
+}
+
+// agentExportName returns the export name of an agent output, or nil if
+// outputs aren't exported.
+func (s *AgentCoreStack) agentExportName(agentName, output string) *string {
+	if !s.Options.ExportOutputs {
+		return nil
+	}
+	return jsii.String(AgentExportName(s.Config.StackName, agentName, output))
+}
+
+// stackExportName returns the export name of a stack output, or nil if
+// outputs aren't exported.
+func (s *AgentCoreStack) stackExportName(output string) *string {
+	if !s.Options.ExportOutputs {
+		return nil
+	}
+	return jsii.String(StackExportName(s.Config.StackName, output))
+}
+
+// ImportedAgentRuntime is an agent runtime exported by another stack of the
+// same account and region.
+type ImportedAgentRuntime struct {
+	// RuntimeARN is the runtime ARN.
+	RuntimeARN *string
+
+	// RuntimeID is the runtime ID.
+	RuntimeID *string
+
+	// InvocationURL is the URL invoking the runtime's default endpoint.
+	InvocationURL *string
+}
+
+// ImportAgentRuntime imports the runtime ARN an agent stack exported with
+// StackOptions.ExportOutputs into a consuming stack:
+//
+//	research := agentcore.ImportAgentRuntime(stack,
+//		agentcore.AgentExportName("my-agents", "research", "RuntimeArn"))
+//	research.GrantInvoke(handler)
+//
+// The consuming stack depends on the export, which can't be removed or
+// changed while it's imported.
+func ImportAgentRuntime(scope constructs.Construct, exportName string) *ImportedAgentRuntime {
+	stack := awscdk.Stack_Of(scope)
+	runtimeARN := awscdk.Fn_ImportValue(jsii.String(exportName))
+	runtimeID := awscdk.Fn_Select(jsii.Number(1), awscdk.Fn_Split(jsii.String("runtime/"), runtimeARN, nil))
+
+	// The runtime ARN is URL-encoded in the path; build it from its parts
+	// since tokens can't be escaped at synth time
+	escapedARN := fmt.Sprintf("arn%%3A%s%%3Abedrock-agentcore%%3A%s%%3A%s%%3Aruntime%%2F%s",
+		*stack.Partition(), *stack.Region(), *stack.Account(), *runtimeID)
+	return &ImportedAgentRuntime{
+		RuntimeARN: runtimeARN,
+		RuntimeID:  runtimeID,
+		InvocationURL: jsii.String(fmt.Sprintf("https://bedrock-agentcore.%s.%s/runtimes/%s/invocations",
+			*stack.Region(), *stack.UrlSuffix(), escapedARN)),
+	}
+}
+
+// GrantInvoke grants bedrock-agentcore:InvokeAgentRuntime on the runtime
+// and its endpoints.
+func (r *ImportedAgentRuntime) GrantInvoke(grantee awsiam.IGrantable) awsiam.Grant {
+	return awsiam.Grant_AddToPrincipal(&awsiam.GrantOnPrincipalOptions{
+		Grantee: grantee,
+		Actions: jsii.Strings("bedrock-agentcore:InvokeAgentRuntime"),
+		ResourceArns: &[]*string{
+			r.RuntimeARN,
+			awscdk.Fn_Join(jsii.String(""), &[]*string{r.RuntimeARN, jsii.String("/*")}),
+		},
+	})
+}
@@ -0,0 +1,99 @@
+package agentcore
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// gatewayTargetNamePattern is the naming rule for gateway targets.
+var gatewayTargetNamePattern = regexp.MustCompile(`^[0-9a-zA-Z](?:[0-9a-zA-Z-]{0,98}[0-9a-zA-Z])?$`)
+
+// runtimeARNPattern matches an AgentCore runtime ARN and captures its region.
+var runtimeARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:bedrock-agentcore:([a-z0-9-]+):\d{12}:runtime/[a-zA-Z0-9_-]+$`)
+
+// validateRemoteTargets validates cross-stack gateway targets.
+func validateRemoteTargets(targets []RemoteRuntimeTarget) error {
+	names := make(map[string]bool)
+	for i, target := range targets {
+		if !gatewayTargetNamePattern.MatchString(target.Name) {
+			return fmt.Errorf("gateway.remoteTargets[%d]: name %q must match %s", i, target.Name, gatewayTargetNamePattern)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("gateway.remoteTargets[%d]: duplicate name %s", i, target.Name)
+		}
+		names[target.Name] = true
+
+		if !runtimeARNPattern.MatchString(target.RuntimeARN) {
+			return fmt.Errorf("gateway.remoteTargets[%d] (%s): invalid runtime ARN %q", i, target.Name, target.RuntimeARN)
+		}
+	}
+	return nil
+}
+
+// runtimeInvocationURL returns the AgentCore data plane URL for invoking a runtime.
+func runtimeInvocationURL(runtimeARN, qualifier string) string {
+	region := runtimeARNPattern.FindStringSubmatch(runtimeARN)[1]
+	if qualifier == "" {
+		qualifier = "DEFAULT"
+	}
+	return fmt.Sprintf("https://bedrock-agentcore.%s.amazonaws.com/runtimes/%s/invocations?qualifier=%s",
+		region, url.QueryEscape(runtimeARN), url.QueryEscape(qualifier))
+}
+
+// createRemoteGatewayTargets registers runtimes from other stacks as targets
+// of this stack's gateway and grants the gateway role permission to invoke them.
+func (s *AgentCoreStack) createRemoteGatewayTargets() {
+	if s.Gateway == nil || s.Options.Gateway == nil || len(s.Options.Gateway.RemoteTargets) == 0 {
+		return
+	}
+
+	runtimeARNs := make([]*string, 0, len(s.Options.Gateway.RemoteTargets))
+	for _, target := range s.Options.Gateway.RemoteTargets {
+		gatewayTarget := awsbedrockagentcore.NewCfnGatewayTarget(s.Stack,
+			jsii.String(fmt.Sprintf("GatewayTarget-%s", target.Name)),
+			&awsbedrockagentcore.CfnGatewayTargetProps{
+				Name:              jsii.String(target.Name),
+				Description:       jsii.String(fmt.Sprintf("Remote agent runtime %s", target.RuntimeARN)),
+				GatewayIdentifier: s.Gateway.AttrGatewayIdentifier(),
+				TargetConfiguration: &awsbedrockagentcore.CfnGatewayTarget_TargetConfigurationProperty{
+					Mcp: &awsbedrockagentcore.CfnGatewayTarget_McpTargetConfigurationProperty{
+						McpServer: &awsbedrockagentcore.CfnGatewayTarget_McpServerTargetConfigurationProperty{
+							Endpoint: jsii.String(runtimeInvocationURL(target.RuntimeARN, target.Qualifier)),
+						},
+					},
+				},
+				CredentialProviderConfigurations: &[]interface{}{
+					&awsbedrockagentcore.CfnGatewayTarget_CredentialProviderConfigurationProperty{
+						CredentialProviderType: jsii.String("GATEWAY_IAM_ROLE"),
+					},
+				},
+			},
+		)
+		s.GatewayTargets[target.Name] = gatewayTarget
+
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("GatewayTarget-%s-Id", target.Name)),
+			&awscdk.CfnOutputProps{
+				Value:       gatewayTarget.AttrTargetId(),
+				Description: jsii.String(fmt.Sprintf("Gateway target ID for remote runtime %s", target.Name)),
+			})
+
+		runtimeARNs = append(runtimeARNs,
+			jsii.String(target.RuntimeARN),
+			jsii.String(target.RuntimeARN+"/*"),
+		)
+	}
+
+	// The gateway invokes targets with its own role (the execution role).
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("bedrock-agentcore:InvokeAgentRuntime"),
+		Resources: &runtimeARNs,
+	}))
+}
@@ -0,0 +1,181 @@
+package agentcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+const (
+	// defaultHealthCheckTimeout is how long a health check waits for the
+	// agent's response by default.
+	defaultHealthCheckTimeout = 60 * time.Second
+
+	// maxHealthCheckBody bounds the response body read by a health check.
+	maxHealthCheckBody = 1 << 20
+)
+
+// HealthCheckOptions configures the request that smoke tests invoke the
+// agent with after a deploy (deploy --smoke-test). The check passes if the
+// agent answers with a 2xx status.
+type HealthCheckOptions struct {
+	// Payload is sent to the agent as the JSON request body.
+	// Default: {}
+	Payload map[string]any `json:"payload,omitempty" yaml:"payload,omitempty"`
+
+	// Endpoint is the runtime endpoint to invoke.
+	// Default: the candidate endpoint of blue/green agents, otherwise the
+	// agent's default endpoint
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	// ExpectContains fails the check unless the response body contains
+	// this text.
+	ExpectContains string `json:"expectContains,omitempty" yaml:"expectContains,omitempty"`
+
+	// TimeoutSeconds is how long to wait for the response.
+	// Range: 1-900
+	// Default: 60
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+
+	// Skip excludes the agent from smoke tests, e.g. for agents that
+	// can't answer without side effects.
+	Skip bool `json:"skip,omitempty" yaml:"skip,omitempty"`
+}
+
+// HealthCheckResult is the outcome of a health check.
+type HealthCheckResult struct {
+	// Agent is the agent name.
+	Agent string
+
+	// Endpoint is the runtime endpoint invoked.
+	Endpoint string
+
+	// StatusCode is the HTTP status of the response, or 0 if the request
+	// failed.
+	StatusCode int
+
+	// Duration is how long the agent took to answer.
+	Duration time.Duration
+}
+
+// validateHealthCheck validates an agent's health check.
+func validateHealthCheck(agentName string, opts *AgentOptions) error {
+	if opts == nil || opts.HealthCheck == nil {
+		return nil
+	}
+	check := opts.HealthCheck
+
+	if check.Endpoint != "" && check.Endpoint != defaultEndpointName(agentName, opts) {
+		found := opts.BlueGreen != nil && check.Endpoint == opts.BlueGreen.candidateEndpoint()
+		for _, endpoint := range opts.Endpoints {
+			if endpoint.Name == check.Endpoint {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("healthCheck.endpoint %q is not an endpoint of the agent", check.Endpoint)
+		}
+	}
+	if check.TimeoutSeconds != 0 && (check.TimeoutSeconds < 1 || check.TimeoutSeconds > 900) {
+		return fmt.Errorf("healthCheck.timeoutSeconds must be between 1 and 900, got %d", check.TimeoutSeconds)
+	}
+	if _, err := json.Marshal(check.Payload); err != nil {
+		return fmt.Errorf("healthCheck.payload: %w", err)
+	}
+	return nil
+}
+
+// RunHealthCheck invokes a deployed agent runtime with the agent's health
+// check payload and returns an error if the agent doesn't answer with a 2xx
+// status (and the expected text, if set). opts may be nil for the default
+// check.
+func RunHealthCheck(ctx context.Context, cfg aws.Config, agentName, runtimeARN string, opts *AgentOptions) (*HealthCheckResult, error) {
+	check := HealthCheckOptions{}
+	if opts != nil && opts.HealthCheck != nil {
+		check = *opts.HealthCheck
+	}
+	result := &HealthCheckResult{Agent: agentName, Endpoint: check.Endpoint}
+	if result.Endpoint == "" {
+		// A blue/green agent's new version is only served by the candidate
+		result.Endpoint = defaultEndpointName(agentName, opts)
+		if opts != nil && opts.BlueGreen != nil {
+			result.Endpoint = opts.BlueGreen.candidateEndpoint()
+		}
+	}
+
+	parts := strings.SplitN(runtimeARN, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return result, fmt.Errorf("invalid runtime ARN %q", runtimeARN)
+	}
+	region := parts[3]
+
+	payload := check.Payload
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return result, fmt.Errorf("encoding payload: %w", err)
+	}
+
+	timeout := defaultHealthCheckTimeout
+	if check.TimeoutSeconds > 0 {
+		timeout = time.Duration(check.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	invokeURL := fmt.Sprintf("https://%s/runtimes/%s/invocations?qualifier=%s",
+		awsapi.Host(region, "bedrock-agentcore"), url.QueryEscape(runtimeARN), url.QueryEscape(result.Endpoint))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, invokeURL, bytes.NewReader(body))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	// Each check runs in a new session; IDs must be at least 33 characters
+	sessionID := make([]byte, 20)
+	if _, err := rand.Read(sessionID); err != nil {
+		return result, err
+	}
+	req.Header.Set("X-Amzn-Bedrock-AgentCore-Runtime-Session-Id", "health-check-"+hex.EncodeToString(sessionID))
+
+	if err := awsapi.Sign(ctx, cfg, req, body, "bedrock-agentcore", region); err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("invoking agent: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthCheckBody))
+	result.StatusCode = resp.StatusCode
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("agent answered %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if check.ExpectContains != "" && !strings.Contains(string(respBody), check.ExpectContains) {
+		return result, fmt.Errorf("response does not contain %q", check.ExpectContains)
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Keys of the deployment history table. The deploy tool writes one item
+// per deployment of a stack, keyed by the stack name and the deployment
+// time (RFC 3339, UTC), so the newest deployments are read with a
+// descending query.
+const (
+	// HistoryPartitionKey is the partition key: the stack name.
+	HistoryPartitionKey = "StackName"
+
+	// HistorySortKey is the sort key: the deployment time.
+	HistorySortKey = "DeployedAt"
+
+	// HistoryTTLAttribute holds the expiry time of a record in epoch
+	// seconds, when DeploymentHistoryOptions.RetentionDays is set.
+	HistoryTTLAttribute = "ExpiresAt"
+)
+
+// DeploymentHistoryOptions creates a DynamoDB table the deploy tool records
+// each deployment in: who deployed, when, the git commit, config hash,
+// images, resource changes, and outcome. The table name is the
+// DeploymentHistoryTable output. The table is an audit log, so it is
+// retained when the stack is deleted unless removalPolicies.deploymentHistory
+// is destroy.
+type DeploymentHistoryOptions struct {
+	// RetentionDays expires records after this many days.
+	// Default: 0 (records are kept forever)
+	RetentionDays int `json:"retentionDays,omitempty" yaml:"retentionDays,omitempty"`
+}
+
+// validate validates the deployment history options.
+func (o *DeploymentHistoryOptions) validate() error {
+	if o.RetentionDays < 0 {
+		return fmt.Errorf("deploymentHistory.retentionDays must not be negative, got %d", o.RetentionDays)
+	}
+	return nil
+}
+
+// deploymentHistoryRemovalPolicy returns the removal policy of the history
+// table: retain unless removalPolicies.deploymentHistory says otherwise.
+func (s *AgentCoreStack) deploymentHistoryRemovalPolicy() string {
+	if opts := s.Options.RemovalPolicies; opts != nil && opts.DeploymentHistory != "" {
+		return opts.DeploymentHistory
+	}
+	return RemovalPolicyRetain
+}
+
+// createDeploymentHistory creates the deployment history table and its
+// output.
+func (s *AgentCoreStack) createDeploymentHistory() {
+	opts := s.Options.DeploymentHistory
+	if opts == nil {
+		return
+	}
+
+	encryption := awsdynamodb.TableEncryption_AWS_MANAGED
+	if s.KMSKey != nil {
+		encryption = awsdynamodb.TableEncryption_CUSTOMER_MANAGED
+	}
+	props := &awsdynamodb.TableProps{
+		PartitionKey:  &awsdynamodb.Attribute{Name: jsii.String(HistoryPartitionKey), Type: awsdynamodb.AttributeType_STRING},
+		SortKey:       &awsdynamodb.Attribute{Name: jsii.String(HistorySortKey), Type: awsdynamodb.AttributeType_STRING},
+		BillingMode:   awsdynamodb.BillingMode_PAY_PER_REQUEST,
+		Encryption:    encryption,
+		EncryptionKey: s.KMSKey,
+		PointInTimeRecoverySpecification: &awsdynamodb.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: jsii.Bool(true),
+		},
+	}
+	if opts.RetentionDays > 0 {
+		props.TimeToLiveAttribute = jsii.String(HistoryTTLAttribute)
+	}
+	s.DeploymentHistory = awsdynamodb.NewTable(s.Stack, jsii.String("DeploymentHistory"), props)
+	s.DeploymentHistory.ApplyRemovalPolicy(cdkRemovalPolicy(s.deploymentHistoryRemovalPolicy()))
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("DeploymentHistoryTable"), &awscdk.CfnOutputProps{
+		Value:       s.DeploymentHistory.TableName(),
+		Description: jsii.String("Deployment history table"),
+	})
+	if opts.RetentionDays > 0 {
+		awscdk.NewCfnOutput(s.Stack, jsii.String("DeploymentHistoryRetentionDays"), &awscdk.CfnOutputProps{
+			Value:       jsii.String(fmt.Sprintf("%d", opts.RetentionDays)),
+			Description: jsii.String("Days deployment history records are kept"),
+		})
+	}
+}
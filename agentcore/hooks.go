@@ -0,0 +1,46 @@
+package agentcore
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/constructs-go/constructs/v10"
+)
+
+// ResourceHook customizes a CloudFormation resource generated by the stack,
+// e.g. to tag it, rename it, or override a property the options don't
+// expose. resourceType is the CloudFormation type of the resource, such as
+// "AWS::Logs::LogGroup". Cast resource to the L1 type (e.g.
+// awslogs.CfnLogGroup) to set typed properties, or use
+// resource.AddPropertyOverride.
+type ResourceHook func(resource awscdk.CfnResource, resourceType string)
+
+// OnResourceType returns a hook calling hook with the resources of one
+// CloudFormation type.
+func OnResourceType(resourceType string, hook func(resource awscdk.CfnResource)) ResourceHook {
+	return func(resource awscdk.CfnResource, t string) {
+		if t == resourceType {
+			hook(resource)
+		}
+	}
+}
+
+// OnResourceCreated calls hook with each CloudFormation resource of the
+// stack, including those of nested stacks and raw resources, in the order
+// they were created. Resources added to the stack afterwards are not
+// visited. Hooks given with StackBuilder.WithResourceHook run before the
+// removal policies, TLS enforcement, and security and policy checks, so
+// prefer those when the checks should see the changes.
+func (s *AgentCoreStack) OnResourceCreated(hook ResourceHook) {
+	for _, c := range *s.Stack.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		if resource, ok := c.(awscdk.CfnResource); ok {
+			hook(resource, *resource.CfnResourceType())
+		}
+	}
+}
+
+// runResourceHooks runs the hooks of the options over the resources
+// created so far.
+func (s *AgentCoreStack) runResourceHooks() {
+	for _, hook := range s.Options.ResourceHooks {
+		s.OnResourceCreated(hook)
+	}
+}
@@ -0,0 +1,341 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2authorizers"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2integrations"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscertificatemanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53targets"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// HTTP API authorization types.
+const (
+	HTTPAPIAuthIAM  = "AWS_IAM"
+	HTTPAPIAuthNone = "NONE"
+)
+
+var (
+	// domainNamePattern matches fully qualified domain names.
+	domainNamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+	// certificateARNPattern matches ACM certificate ARNs.
+	certificateARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:acm:[a-z0-9-]+:\d{12}:certificate/[a-zA-Z0-9-]+$`)
+)
+
+// httpAPIProxyCode is the integration function of the HTTP API. The agent
+// is taken from the route, and the runtime session ID is passed through in
+// the X-Amzn-Bedrock-AgentCore-Runtime-Session-Id header.
+const httpAPIProxyCode = `import base64
+import json
+import os
+
+import boto3
+from botocore.exceptions import ClientError
+
+client = boto3.client("bedrock-agentcore")
+agents = json.loads(os.environ["AGENTS"])
+session_header = "x-amzn-bedrock-agentcore-runtime-session-id"
+
+
+def handler(event, context):
+    # Route keys look like "POST /agents/{name}/invoke"
+    agent = agents[event["routeKey"].split(" ", 1)[1].split("/")[2]]
+
+    body = event.get("body") or "{}"
+    payload = base64.b64decode(body) if event.get("isBase64Encoded") else body.encode("utf-8")
+    request = {
+        "agentRuntimeArn": agent["runtimeArn"],
+        "qualifier": agent["qualifier"],
+        "contentType": "application/json",
+        "payload": payload,
+    }
+    session = (event.get("headers") or {}).get(session_header)
+    if session:
+        request["runtimeSessionId"] = session
+
+    try:
+        response = client.invoke_agent_runtime(**request)
+    except ClientError as err:
+        return {
+            "statusCode": err.response["ResponseMetadata"]["HTTPStatusCode"],
+            "headers": {"content-type": "application/json"},
+            "body": json.dumps({"message": err.response["Error"]["Message"]}),
+        }
+
+    headers = {"content-type": response.get("contentType", "application/json")}
+    if response.get("runtimeSessionId"):
+        headers[session_header] = response["runtimeSessionId"]
+    return {
+        "statusCode": response.get("statusCode", 200),
+        "headers": headers,
+        "body": response["response"].read().decode("utf-8"),
+    }
+`
+
+// HTTPAPIOptions configures an API Gateway HTTP API in front of the agent
+// runtimes, so clients get a stable HTTPS URL instead of runtime ARNs. Each
+// agent is invoked with POST /agents/{name}/invoke through a proxy Lambda
+// function.
+type HTTPAPIOptions struct {
+	// Name is the API name.
+	// Default: "{stackName}-api"
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Agents are the agents with a route.
+	// Default: all agents
+	Agents []string `json:"agents,omitempty" yaml:"agents,omitempty"`
+
+	// AuthType is the route authorization: "AWS_IAM" (SigV4-signed
+	// requests) or "NONE".
+	// Default: "AWS_IAM"
+	AuthType string `json:"authType,omitempty" yaml:"authType,omitempty"`
+
+	// ThrottlingRateLimit is the steady-state request rate per second.
+	// Default: the account limit
+	ThrottlingRateLimit float64 `json:"throttlingRateLimit,omitempty" yaml:"throttlingRateLimit,omitempty"`
+
+	// ThrottlingBurstLimit is the request burst size.
+	// Default: the account limit
+	ThrottlingBurstLimit int `json:"throttlingBurstLimit,omitempty" yaml:"throttlingBurstLimit,omitempty"`
+
+	// Domain serves the API on a custom domain.
+	Domain *HTTPAPIDomainOptions `json:"domain,omitempty" yaml:"domain,omitempty"`
+}
+
+// HTTPAPIDomainOptions configures the custom domain of the HTTP API. The
+// certificate is imported from CertificateARN, or created and validated in
+// the hosted zone.
+type HTTPAPIDomainOptions struct {
+	// Name is the domain name, e.g. "agents.example.com".
+	Name string `json:"name" yaml:"name"`
+
+	// CertificateARN is an ACM certificate for the domain in the stack
+	// region.
+	// Default: a DNS-validated certificate in the hosted zone
+	CertificateARN string `json:"certificateArn,omitempty" yaml:"certificateArn,omitempty"`
+
+	// HostedZoneID is the Route 53 hosted zone of the domain. When set, an
+	// alias record pointing the domain at the API is created.
+	HostedZoneID string `json:"hostedZoneId,omitempty" yaml:"hostedZoneId,omitempty"`
+
+	// HostedZoneName is the name of the hosted zone, e.g. "example.com".
+	// Default: the record is created with the fully qualified domain name
+	HostedZoneName string `json:"hostedZoneName,omitempty" yaml:"hostedZoneName,omitempty"`
+}
+
+// validate validates the HTTP API options against the stack's agents.
+func (o *HTTPAPIOptions) validate(agents []AgentConfig) error {
+	names := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		names[agent.Name] = true
+	}
+	seen := make(map[string]bool, len(o.Agents))
+	for i, name := range o.Agents {
+		if !names[name] {
+			return fmt.Errorf("httpApi.agents[%d]: unknown agent %q", i, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("httpApi.agents[%d]: duplicate agent %q", i, name)
+		}
+		seen[name] = true
+	}
+
+	switch o.AuthType {
+	case "", HTTPAPIAuthIAM, HTTPAPIAuthNone:
+	default:
+		return fmt.Errorf("httpApi.authType %q must be %s or %s", o.AuthType, HTTPAPIAuthIAM, HTTPAPIAuthNone)
+	}
+	if o.ThrottlingRateLimit < 0 || o.ThrottlingBurstLimit < 0 {
+		return fmt.Errorf("httpApi.throttlingRateLimit and throttlingBurstLimit must not be negative")
+	}
+
+	if d := o.Domain; d != nil {
+		switch {
+		case !*awscdk.Token_IsUnresolved(d.Name) && !domainNamePattern.MatchString(d.Name):
+			return fmt.Errorf("httpApi.domain.name %q must be a lowercase domain name", d.Name)
+		case d.CertificateARN != "" && !*awscdk.Token_IsUnresolved(d.CertificateARN) && !certificateARNPattern.MatchString(d.CertificateARN):
+			return fmt.Errorf("httpApi.domain.certificateArn %q must be an ACM certificate ARN", d.CertificateARN)
+		case d.HostedZoneName != "" && d.HostedZoneID == "":
+			return fmt.Errorf("httpApi.domain.hostedZoneName requires hostedZoneId")
+		case d.CertificateARN == "" && d.HostedZoneID == "":
+			return fmt.Errorf("httpApi.domain requires certificateArn or a hosted zone to validate a new certificate")
+		}
+	}
+	return nil
+}
+
+// createHTTPAPI creates the HTTP API, its proxy function, and the custom
+// domain.
+func (s *AgentCoreStack) createHTTPAPI() {
+	opts := s.httpAPIOptions()
+	if opts == nil {
+		return
+	}
+
+	agentNames := opts.Agents
+	if len(agentNames) == 0 {
+		for _, agent := range s.Config.Agents {
+			agentNames = append(agentNames, agent.Name)
+		}
+	}
+
+	// Agent name -> runtime ARN and qualifier, read by the proxy function
+	agents := make(map[string]interface{}, len(agentNames))
+	var runtimeARNs []*string
+	for _, name := range agentNames {
+		runtime := s.Runtimes[name]
+		agents[name] = map[string]interface{}{
+			"runtimeArn": runtime.AttrAgentRuntimeArn(),
+			"qualifier":  defaultEndpointName(name, s.Options.Agents[name]),
+		}
+		runtimeARNs = append(runtimeARNs, runtime.AttrAgentRuntimeArn(),
+			jsii.String(fmt.Sprintf("%s/*", *runtime.AttrAgentRuntimeArn())))
+	}
+
+	proxy := awslambda.NewFunction(s.Stack, jsii.String("HTTPAPIProxy"), &awslambda.FunctionProps{
+		Description:  jsii.String(fmt.Sprintf("HTTP API proxy to the agents of stack %s", s.Config.StackName)),
+		Code:         awslambda.Code_FromInline(jsii.String(httpAPIProxyCode)),
+		Runtime:      awslambda.Runtime_PYTHON_3_13(),
+		Handler:      jsii.String("index.handler"),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(256),
+		// API Gateway ends integrations after 30 seconds
+		Timeout: awscdk.Duration_Seconds(jsii.Number(30)),
+		Environment: &map[string]*string{
+			"AGENTS": s.Stack.ToJsonString(agents, nil),
+		},
+		EnvironmentEncryption: s.KMSKey,
+	})
+	proxy.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("bedrock-agentcore:InvokeAgentRuntime"),
+		Resources: &runtimeARNs,
+	}))
+	for _, name := range agentNames {
+		// The qualifier names the endpoint without referencing it
+		proxy.Node().AddDependency(s.Endpoints[name])
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-api", s.Config.StackName)
+	}
+	s.HTTPAPI = awsapigatewayv2.NewHttpApi(s.Stack, jsii.String("HTTPAPI"), &awsapigatewayv2.HttpApiProps{
+		ApiName:            jsii.String(name),
+		Description:        jsii.String(fmt.Sprintf("Invokes the agents of stack %s", s.Config.StackName)),
+		CreateDefaultStage: jsii.Bool(false),
+		// Clients use the custom domain only
+		DisableExecuteApiEndpoint: jsii.Bool(opts.Domain != nil),
+	})
+
+	integration := awsapigatewayv2integrations.NewHttpLambdaIntegration(jsii.String("HTTPAPIProxyIntegration"), proxy, nil)
+	var authorizer awsapigatewayv2.IHttpRouteAuthorizer
+	if opts.AuthType == HTTPAPIAuthNone {
+		authorizer = awsapigatewayv2.NewHttpNoneAuthorizer()
+	} else {
+		authorizer = awsapigatewayv2authorizers.NewHttpIamAuthorizer()
+	}
+	for _, agentName := range agentNames {
+		s.HTTPAPI.AddRoutes(&awsapigatewayv2.AddRoutesOptions{
+			Path:        jsii.String(fmt.Sprintf("/agents/%s/invoke", agentName)),
+			Methods:     &[]awsapigatewayv2.HttpMethod{awsapigatewayv2.HttpMethod_POST},
+			Integration: integration,
+			Authorizer:  authorizer,
+		})
+	}
+
+	stageProps := &awsapigatewayv2.HttpStageProps{
+		HttpApi:    s.HTTPAPI,
+		StageName:  jsii.String("$default"),
+		AutoDeploy: jsii.Bool(true),
+	}
+	if opts.ThrottlingRateLimit > 0 || opts.ThrottlingBurstLimit > 0 {
+		stageProps.Throttle = &awsapigatewayv2.ThrottleSettings{}
+		if opts.ThrottlingRateLimit > 0 {
+			stageProps.Throttle.RateLimit = jsii.Number(opts.ThrottlingRateLimit)
+		}
+		if opts.ThrottlingBurstLimit > 0 {
+			stageProps.Throttle.BurstLimit = jsii.Number(float64(opts.ThrottlingBurstLimit))
+		}
+	}
+
+	var url *string
+	if opts.Domain != nil {
+		domain := s.createHTTPAPIDomain(opts.Domain)
+		stageProps.DomainMapping = &awsapigatewayv2.DomainMappingOptions{DomainName: domain}
+		url = jsii.String(fmt.Sprintf("https://%s", opts.Domain.Name))
+	} else {
+		url = s.HTTPAPI.ApiEndpoint()
+	}
+	awsapigatewayv2.NewHttpStage(s.Stack, jsii.String("HTTPAPIStage"), stageProps)
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("HttpApiId"), &awscdk.CfnOutputProps{
+		Value:       s.HTTPAPI.ApiId(),
+		Description: jsii.String("HTTP API ID"),
+	})
+	awscdk.NewCfnOutput(s.Stack, jsii.String("HttpApiUrl"), &awscdk.CfnOutputProps{
+		Value:       url,
+		Description: jsii.String("HTTP API URL; invoke agents with POST /agents/{name}/invoke"),
+	})
+
+	s.createCustomDomainRoutes(agentNames)
+}
+
+// createHTTPAPIDomain creates the custom domain of the HTTP API, with its
+// certificate and alias record as configured.
+func (s *AgentCoreStack) createHTTPAPIDomain(opts *HTTPAPIDomainOptions) awsapigatewayv2.DomainName {
+	var zone awsroute53.IHostedZone
+	recordName := opts.Name
+	switch {
+	case opts.HostedZoneName != "":
+		zone = awsroute53.HostedZone_FromHostedZoneAttributes(s.Stack, jsii.String("HTTPAPIHostedZone"), &awsroute53.HostedZoneAttributes{
+			HostedZoneId: jsii.String(opts.HostedZoneID),
+			ZoneName:     jsii.String(opts.HostedZoneName),
+		})
+	case opts.HostedZoneID != "":
+		zone = awsroute53.HostedZone_FromHostedZoneId(s.Stack, jsii.String("HTTPAPIHostedZone"), jsii.String(opts.HostedZoneID))
+		// Without the zone name, the record name must be fully qualified
+		recordName += "."
+	}
+
+	var certificate awscertificatemanager.ICertificate
+	if opts.CertificateARN != "" {
+		certificate = awscertificatemanager.Certificate_FromCertificateArn(s.Stack,
+			jsii.String("HTTPAPICertificate"), jsii.String(opts.CertificateARN))
+	} else {
+		certificate = awscertificatemanager.NewCertificate(s.Stack, jsii.String("HTTPAPICertificate"), &awscertificatemanager.CertificateProps{
+			DomainName: jsii.String(opts.Name),
+			Validation: awscertificatemanager.CertificateValidation_FromDns(zone),
+		})
+	}
+
+	domain := awsapigatewayv2.NewDomainName(s.Stack, jsii.String("HTTPAPIDomain"), &awsapigatewayv2.DomainNameProps{
+		DomainName:     jsii.String(opts.Name),
+		Certificate:    certificate,
+		SecurityPolicy: awsapigatewayv2.SecurityPolicy_TLS_1_2,
+	})
+
+	// With AWS WAF, clients reach the domain through CloudFront
+	var target awsroute53.IAliasRecordTarget = awsroute53targets.NewApiGatewayv2DomainProperties(
+		domain.RegionalDomainName(), domain.RegionalHostedZoneId())
+	if s.Options.WAF != nil {
+		target = awsroute53targets.NewCloudFrontTarget(s.createWAFDistribution(opts.Name, domain, certificate))
+	}
+
+	if zone != nil {
+		awsroute53.NewARecord(s.Stack, jsii.String("HTTPAPIAliasRecord"), &awsroute53.ARecordProps{
+			Zone:       zone,
+			RecordName: jsii.String(recordName),
+			Target:     awsroute53.RecordTarget_FromAlias(target),
+		})
+	}
+	return domain
+}
@@ -0,0 +1,841 @@
+package agentcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// IAM report formats.
+const (
+	// IAMReportMarkdown renders the report as markdown tables.
+	IAMReportMarkdown = "markdown"
+
+	// IAMReportJSON renders the report as an IAMReport in indented JSON.
+	IAMReportJSON = "json"
+)
+
+// agentResourceKinds are the construct ID prefixes of resources created for
+// a single agent, as in "Runtime-{agent}" or "Schedule-{agent}-{name}".
+var agentResourceKinds = []string{
+	"Agent", "Runtime", "Endpoint", "NamedEndpoint", "Memory", "Contract",
+	"Config", "ConfigDeployment", "Schedule", "Trigger",
+}
+
+// resourcePolicyTypes maps the resource types with a resource policy to the
+// properties holding the policy document and the resources it governs.
+var resourcePolicyTypes = map[string][2]string{
+	"AWS::KMS::Key":                       {"KeyPolicy", ""},
+	"AWS::S3::BucketPolicy":               {"PolicyDocument", "Bucket"},
+	"AWS::SQS::QueuePolicy":               {"PolicyDocument", "Queues"},
+	"AWS::SNS::TopicPolicy":               {"PolicyDocument", "Topics"},
+	"AWS::SecretsManager::ResourcePolicy": {"ResourcePolicy", "SecretId"},
+}
+
+// IAMReport is every IAM statement a stack creates, for review before a
+// deploy. Resources of the stack are shown as ${path} or ${path.Attribute},
+// where path is the construct path relative to the stack.
+type IAMReport struct {
+	// StackName is the name of the stack.
+	StackName string `json:"stackName"`
+
+	// Roles are the roles the stack creates or attaches policies to.
+	Roles []IAMReportRole `json:"roles"`
+
+	// ResourcePolicies are the resource policies the stack creates.
+	ResourcePolicies []IAMReportResourcePolicy `json:"resourcePolicies,omitempty"`
+
+	// Agents are the stack's agents and the roles they run with.
+	Agents []IAMReportAgent `json:"agents"`
+}
+
+// IAMReportRole is a role and the statements of its identity policies.
+type IAMReportRole struct {
+	// Path is the construct path of the role, or the name of an imported
+	// role.
+	Path string `json:"path"`
+
+	// RoleName is the role name, if set by the stack.
+	RoleName string `json:"roleName,omitempty"`
+
+	// Imported is set for roles that exist outside the stack.
+	Imported bool `json:"imported,omitempty"`
+
+	// TrustedPrincipals are the principals allowed to assume the role.
+	TrustedPrincipals []string `json:"trustedPrincipals,omitempty"`
+
+	// ManagedPolicies are the ARNs of the managed policies attached to the
+	// role.
+	ManagedPolicies []string `json:"managedPolicies,omitempty"`
+
+	// PermissionsBoundary is the ARN of the role's permissions boundary.
+	PermissionsBoundary string `json:"permissionsBoundary,omitempty"`
+
+	// Agents are the agents running with the role.
+	Agents []string `json:"agents,omitempty"`
+
+	// Statements are the statements of the role's inline and stack-created
+	// policies.
+	Statements []IAMReportStatement `json:"statements"`
+}
+
+// IAMReportResourcePolicy is a resource policy and its statements.
+type IAMReportResourcePolicy struct {
+	// Path is the construct path of the policy.
+	Path string `json:"path"`
+
+	// Type is the CloudFormation resource type of the policy.
+	Type string `json:"type"`
+
+	// Resources are the resources governed by the policy.
+	Resources []string `json:"resources"`
+
+	// Statements are the statements of the policy.
+	Statements []IAMReportStatement `json:"statements"`
+}
+
+// IAMReportStatement is a policy statement.
+type IAMReportStatement struct {
+	// Policy is the construct path of the policy holding the statement.
+	Policy string `json:"policy"`
+
+	// Effect is "Allow" or "Deny".
+	Effect string `json:"effect"`
+
+	// Principals are the principals of a resource policy statement.
+	Principals []string `json:"principals,omitempty"`
+
+	// Actions are the actions of the statement.
+	Actions []string `json:"actions"`
+
+	// Resources are the resources of the statement.
+	Resources []string `json:"resources,omitempty"`
+
+	// Conditions are the conditions of the statement.
+	Conditions any `json:"conditions,omitempty"`
+
+	// Agents are the agents whose resources the statement grants access
+	// to. Statements without agents apply to every agent of the role.
+	Agents []string `json:"agents,omitempty"`
+}
+
+// IAMReportAgent is an agent and the role it runs with.
+type IAMReportAgent struct {
+	// Name is the agent name.
+	Name string `json:"name"`
+
+	// Role is the path of the role the agent runs with.
+	Role string `json:"role"`
+}
+
+// GenerateIAMReport returns a markdown summary of every IAM statement the
+// stack will create.
+func GenerateIAMReport(config StackConfig) (string, error) {
+	return GenerateIAMReportWithOptions(config, StackOptions{}, IAMReportMarkdown)
+}
+
+// GenerateIAMReportWithOptions returns a summary of every IAM statement the
+// stack will create with CDK-specific options, in IAMReportMarkdown or
+// IAMReportJSON format.
+func GenerateIAMReportWithOptions(config StackConfig, options StackOptions, format string) (string, error) {
+	if format != IAMReportMarkdown && format != IAMReportJSON {
+		return "", fmt.Errorf("IAM report format %q must be %s or %s", format, IAMReportMarkdown, IAMReportJSON)
+	}
+
+	report, err := BuildIAMReport(config, options)
+	if err != nil {
+		return "", err
+	}
+
+	if format == IAMReportJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	}
+	return report.Markdown(), nil
+}
+
+// BuildIAMReport synthesizes the stack and collects the IAM statements of
+// its template.
+func BuildIAMReport(config StackConfig, options StackOptions) (report *IAMReport, err error) {
+	// Stack construction panics on invalid configuration
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("synthesizing stack: %v", r)
+		}
+	}()
+
+	outdir, err := os.MkdirTemp("", "agentcore-iam-report-")
+	if err != nil {
+		return nil, fmt.Errorf("creating synth directory: %w", err)
+	}
+	defer os.RemoveAll(outdir)
+
+	app := awscdk.NewApp(&awscdk.AppProps{
+		Outdir: jsii.String(outdir),
+		Context: &map[string]interface{}{
+			"@aws-cdk/core:newStyleStackSynthesis": true,
+			// Construct paths name the resources in the report
+			"aws:cdk:enable-path-metadata": true,
+			// Asset metadata locates the templates of nested stacks
+			"aws:cdk:enable-asset-metadata": true,
+		},
+	})
+	s := NewAgentCoreStackWithOptions(app, config.StackName, config, options)
+	template, ok := app.Synth(nil).GetStackArtifact(s.ArtifactId()).Template().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("synthesized template of stack %s is not an object", s.Config.StackName)
+	}
+
+	resources, _ := template["Resources"].(map[string]interface{})
+	if err := inlineNestedStacks(outdir, resources); err != nil {
+		return nil, err
+	}
+	return newIAMReportBuilder(s, resources).build(), nil
+}
+
+// inlineNestedStacks merges the resources of the nested stacks of a
+// partitioned stack (Options.Partition) into the resources of its template,
+// so the report covers them. Nested resources are prefixed with the logical
+// ID of their stack, and their references to nested stack parameters and
+// outputs are replaced with the referenced values.
+func inlineNestedStacks(outdir string, resources map[string]interface{}) error {
+	outputs := make(map[string]map[string]interface{})
+	for _, nestedID := range sortedKeys(resources) {
+		resource, _ := resources[nestedID].(map[string]interface{})
+		if resource["Type"] != "AWS::CloudFormation::Stack" {
+			continue
+		}
+		metadata, _ := resource["Metadata"].(map[string]interface{})
+		file, ok := metadata["aws:asset:path"].(string)
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(outdir, file)) //nolint:gosec // G304: file is named by the synthesized template
+		if err != nil {
+			return fmt.Errorf("reading nested stack template: %w", err)
+		}
+		var template map[string]interface{}
+		if err := json.Unmarshal(data, &template); err != nil {
+			return fmt.Errorf("parsing nested stack template %s: %w", file, err)
+		}
+
+		properties, _ := resource["Properties"].(map[string]interface{})
+		parameters, _ := properties["Parameters"].(map[string]interface{})
+		nested, _ := template["Resources"].(map[string]interface{})
+		prefix := nestedID + "."
+		for id, nestedResource := range nested {
+			resources[prefix+id] = rewriteNestedReferences(nestedResource, prefix, nested, parameters)
+		}
+		outputs[nestedID] = make(map[string]interface{})
+		nestedOutputs, _ := template["Outputs"].(map[string]interface{})
+		for name, output := range nestedOutputs {
+			if output, ok := output.(map[string]interface{}); ok {
+				outputs[nestedID][name] = rewriteNestedReferences(output["Value"], prefix, nested, parameters)
+			}
+		}
+	}
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	// The parent references nested resources through nested stack outputs
+	var resolve func(v interface{}) interface{}
+	resolve = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if attr, ok := v["Fn::GetAtt"].([]interface{}); ok && len(v) == 1 && len(attr) == 2 {
+				id, _ := attr[0].(string)
+				name, _ := attr[1].(string)
+				if value, ok := outputs[id][strings.TrimPrefix(name, "Outputs.")]; ok {
+					return value
+				}
+			}
+			resolved := make(map[string]interface{}, len(v))
+			for key, value := range v {
+				resolved[key] = resolve(value)
+			}
+			return resolved
+		case []interface{}:
+			resolved := make([]interface{}, len(v))
+			for i, value := range v {
+				resolved[i] = resolve(value)
+			}
+			return resolved
+		}
+		return v
+	}
+	for id, resource := range resources {
+		resources[id] = resolve(resource)
+	}
+	return nil
+}
+
+// rewriteNestedReferences rewrites a value of a nested stack template for
+// the parent template: references to nested resources get the prefix, and
+// references to nested stack parameters are replaced by their values.
+func rewriteNestedReferences(v interface{}, prefix string, resources, parameters map[string]interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			if ref, ok := v["Ref"].(string); ok {
+				if _, ok := resources[ref]; ok {
+					return map[string]interface{}{"Ref": prefix + ref}
+				}
+				if value, ok := parameters[ref]; ok {
+					return value
+				}
+				return v
+			}
+			if attr, ok := v["Fn::GetAtt"].([]interface{}); ok && len(attr) == 2 {
+				if id, ok := attr[0].(string); ok {
+					if _, ok := resources[id]; ok {
+						return map[string]interface{}{"Fn::GetAtt": []interface{}{prefix + id, attr[1]}}
+					}
+				}
+			}
+		}
+		rewritten := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			rewritten[key] = rewriteNestedReferences(value, prefix, resources, parameters)
+		}
+		return rewritten
+	case []interface{}:
+		rewritten := make([]interface{}, len(v))
+		for i, value := range v {
+			rewritten[i] = rewriteNestedReferences(value, prefix, resources, parameters)
+		}
+		return rewritten
+	}
+	return v
+}
+
+// iamReportBuilder collects the IAM statements of a synthesized template.
+type iamReportBuilder struct {
+	stack     *AgentCoreStack
+	resources map[string]interface{}
+
+	// paths are the construct paths of the resources by logical ID.
+	paths map[string]string
+
+	// owners are the agents of the resources created for a single agent,
+	// by logical ID.
+	owners map[string]string
+
+	// secrets are the agents of the secrets in AgentConfig.SecretsARNs, by
+	// ARN.
+	secrets map[string]string
+}
+
+// newIAMReportBuilder indexes the resources of a synthesized template.
+func newIAMReportBuilder(s *AgentCoreStack, resources map[string]interface{}) *iamReportBuilder {
+	b := &iamReportBuilder{
+		stack:     s,
+		resources: resources,
+		paths:     make(map[string]string),
+		owners:    make(map[string]string),
+		secrets:   make(map[string]string),
+	}
+
+	// Match longer agent names first, so "Runtime-a-b" belongs to agent
+	// "a-b" rather than "a"
+	var agents []string
+	for _, agent := range s.Config.Agents {
+		agents = append(agents, agent.Name)
+		for _, arn := range agent.SecretsARNs {
+			b.secrets[arn] = agent.Name
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return len(agents[i]) > len(agents[j]) })
+
+	stackPath := *s.Stack.Node().Path() + "/"
+	for logicalID, resource := range resources {
+		path := logicalID
+		if resource, ok := resource.(map[string]interface{}); ok {
+			if metadata, ok := resource["Metadata"].(map[string]interface{}); ok {
+				if cdkPath, ok := metadata["aws:cdk:path"].(string); ok {
+					path = strings.TrimSuffix(strings.TrimPrefix(cdkPath, stackPath), "/Resource")
+				}
+			}
+		}
+		b.paths[logicalID] = path
+
+		// Resources of partitioned agents are under their nested stack
+		id, rest, _ := strings.Cut(path, "/")
+		if strings.HasPrefix(id, "AgentStack-") {
+			id, _, _ = strings.Cut(rest, "/")
+		}
+	owner:
+		for _, agent := range agents {
+			for _, kind := range agentResourceKinds {
+				prefix := kind + "-" + agent
+				if id == prefix || strings.HasPrefix(id, prefix+"-") {
+					b.owners[logicalID] = agent
+					break owner
+				}
+			}
+		}
+	}
+	return b
+}
+
+// build builds the report.
+func (b *iamReportBuilder) build() *IAMReport {
+	report := &IAMReport{
+		StackName: b.stack.Config.StackName,
+		Roles:     []IAMReportRole{},
+		Agents:    []IAMReportAgent{},
+	}
+
+	roles := make(map[string]*IAMReportRole)
+	role := func(ref interface{}) *IAMReportRole {
+		key, imported := b.roleKey(ref)
+		if key == "" {
+			return nil
+		}
+		if roles[key] == nil {
+			roles[key] = &IAMReportRole{Path: key, Imported: imported, Statements: []IAMReportStatement{}}
+		}
+		return roles[key]
+	}
+
+	for _, logicalID := range b.logicalIDs() {
+		resource, _ := b.resources[logicalID].(map[string]interface{})
+		properties, _ := resource["Properties"].(map[string]interface{})
+		resourceType, _ := resource["Type"].(string)
+		path := b.paths[logicalID]
+
+		switch resourceType {
+		case "AWS::IAM::Role":
+			r := role(map[string]interface{}{"Ref": logicalID})
+			r.RoleName = b.render(properties["RoleName"])
+			if trust, ok := properties["AssumeRolePolicyDocument"].(map[string]interface{}); ok {
+				for _, statement := range asList(trust["Statement"]) {
+					if statement, ok := statement.(map[string]interface{}); ok {
+						r.TrustedPrincipals = append(r.TrustedPrincipals, b.principals(statement["Principal"])...)
+					}
+				}
+			}
+			for _, arn := range asList(properties["ManagedPolicyArns"]) {
+				r.ManagedPolicies = append(r.ManagedPolicies, b.render(arn))
+			}
+			r.PermissionsBoundary = b.render(properties["PermissionsBoundary"])
+			for _, policy := range asList(properties["Policies"]) {
+				if policy, ok := policy.(map[string]interface{}); ok {
+					name := path + "/" + b.render(policy["PolicyName"])
+					r.Statements = append(r.Statements, b.statements(name, policy["PolicyDocument"])...)
+				}
+			}
+
+		case "AWS::IAM::Policy", "AWS::IAM::ManagedPolicy":
+			statements := b.statements(path, properties["PolicyDocument"])
+			for _, ref := range asList(properties["Roles"]) {
+				if r := role(ref); r != nil {
+					r.Statements = append(r.Statements, statements...)
+				}
+			}
+
+		case "AWS::Lambda::Permission":
+			statement := IAMReportStatement{
+				Policy:     path,
+				Effect:     "Allow",
+				Principals: b.principals(map[string]interface{}{"Service": properties["Principal"]}),
+				Actions:    []string{b.render(properties["Action"])},
+				Resources:  []string{b.render(properties["FunctionName"])},
+				Agents:     b.agents(properties["FunctionName"]),
+			}
+			if sourceARN := properties["SourceArn"]; sourceARN != nil {
+				statement.Conditions = map[string]interface{}{
+					"ArnLike": map[string]interface{}{"aws:SourceArn": b.renderAll(sourceARN)},
+				}
+			}
+			report.ResourcePolicies = append(report.ResourcePolicies, IAMReportResourcePolicy{
+				Path:       path,
+				Type:       resourceType,
+				Resources:  statement.Resources,
+				Statements: []IAMReportStatement{statement},
+			})
+
+		case "AWS::BedrockAgentCore::Runtime":
+			agent := IAMReportAgent{Name: b.render(properties["AgentRuntimeName"])}
+			if r := role(properties["RoleArn"]); r != nil {
+				agent.Role = r.Path
+				r.Agents = append(r.Agents, agent.Name)
+			}
+			report.Agents = append(report.Agents, agent)
+
+		default:
+			props, ok := resourcePolicyTypes[resourceType]
+			if !ok {
+				continue
+			}
+			policy := IAMReportResourcePolicy{
+				Path:       path,
+				Type:       resourceType,
+				Statements: b.statements(path, properties[props[0]]),
+			}
+			if props[1] == "" {
+				policy.Resources = []string{"${" + path + "}"}
+			} else {
+				for _, target := range asList(properties[props[1]]) {
+					policy.Resources = append(policy.Resources, b.render(target))
+				}
+			}
+			report.ResourcePolicies = append(report.ResourcePolicies, policy)
+		}
+	}
+
+	for _, key := range sortedKeys(roles) {
+		sort.Strings(roles[key].Agents)
+		report.Roles = append(report.Roles, *roles[key])
+	}
+	sort.Slice(report.Agents, func(i, j int) bool { return report.Agents[i].Name < report.Agents[j].Name })
+	return report
+}
+
+// roleKey returns the path of the role a Ref, a Fn::GetAtt of the role ARN,
+// or an imported role name or ARN points to.
+func (b *iamReportBuilder) roleKey(ref interface{}) (key string, imported bool) {
+	switch ref := ref.(type) {
+	case string:
+		// Imported roles are referenced by name, or ARN in the runtime
+		if strings.HasPrefix(ref, "arn:") {
+			ref = ref[strings.LastIndex(ref, "/")+1:]
+		}
+		return ref, true
+	case map[string]interface{}:
+		ids := b.references(ref)
+		if len(ids) == 1 {
+			return b.paths[ids[0]], false
+		}
+		return b.render(ref), true
+	}
+	return "", false
+}
+
+// statements returns the statements of a policy document.
+func (b *iamReportBuilder) statements(policy string, document interface{}) []IAMReportStatement {
+	doc, ok := document.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var statements []IAMReportStatement
+	for _, statement := range asList(doc["Statement"]) {
+		statement, ok := statement.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		effect, _ := statement["Effect"].(string)
+		s := IAMReportStatement{
+			Policy:     policy,
+			Effect:     effect,
+			Principals: b.principals(statement["Principal"]),
+			Actions:    b.renderAll(statement["Action"]),
+			Resources:  b.renderAll(statement["Resource"]),
+			Agents:     b.agents(statement["Resource"]),
+		}
+		if conditions := statement["Condition"]; conditions != nil {
+			s.Conditions = b.renderConditions(conditions)
+		}
+		if notActions := b.renderAll(statement["NotAction"]); len(notActions) > 0 {
+			for _, action := range notActions {
+				s.Actions = append(s.Actions, "NOT "+action)
+			}
+		}
+		statements = append(statements, s)
+	}
+	return statements
+}
+
+// principals returns the principals of a statement as "Type: principal".
+func (b *iamReportBuilder) principals(principal interface{}) []string {
+	switch principal := principal.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		var principals []string
+		for _, kind := range sortedKeys(principal) {
+			for _, value := range b.renderAll(principal[kind]) {
+				principals = append(principals, fmt.Sprintf("%s: %s", kind, value))
+			}
+		}
+		return principals
+	default:
+		return b.renderAll(principal)
+	}
+}
+
+// agents returns the agents whose resources are referenced by a value.
+func (b *iamReportBuilder) agents(v interface{}) []string {
+	found := make(map[string]bool)
+	for _, logicalID := range b.references(v) {
+		if agent, ok := b.owners[logicalID]; ok {
+			found[agent] = true
+		}
+	}
+	for _, item := range asList(v) {
+		if arn, ok := item.(string); ok {
+			if agent, ok := b.secrets[arn]; ok {
+				found[agent] = true
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	return sortedKeys(found)
+}
+
+// references returns the logical IDs of the stack's resources referenced by
+// a value.
+func (b *iamReportBuilder) references(v interface{}) []string {
+	var ids []string
+	var walk func(interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			if ref, ok := v["Ref"].(string); ok && len(v) == 1 {
+				if _, ok := b.resources[ref]; ok {
+					ids = append(ids, ref)
+				}
+				return
+			}
+			if attr, ok := v["Fn::GetAtt"].([]interface{}); ok && len(attr) == 2 && len(v) == 1 {
+				if ref, ok := attr[0].(string); ok {
+					ids = append(ids, ref)
+				}
+				return
+			}
+			for _, key := range sortedKeys(v) {
+				walk(v[key])
+			}
+		}
+	}
+	walk(v)
+	return ids
+}
+
+// render renders a template value as a string, showing references to the
+// stack's resources as ${path} or ${path.Attribute} and pseudo parameters
+// as ${AWS::Name}.
+func (b *iamReportBuilder) render(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}:
+		if len(v) != 1 {
+			break
+		}
+		if ref, ok := v["Ref"].(string); ok {
+			if path, ok := b.paths[ref]; ok {
+				return "${" + path + "}"
+			}
+			return "${" + ref + "}"
+		}
+		if attr, ok := v["Fn::GetAtt"].([]interface{}); ok && len(attr) == 2 {
+			ref, _ := attr[0].(string)
+			if path, ok := b.paths[ref]; ok {
+				ref = path
+			}
+			return fmt.Sprintf("${%s.%s}", ref, b.render(attr[1]))
+		}
+		if join, ok := v["Fn::Join"].([]interface{}); ok && len(join) == 2 {
+			separator, _ := join[0].(string)
+			var parts []string
+			for _, part := range asList(join[1]) {
+				parts = append(parts, b.render(part))
+			}
+			return strings.Join(parts, separator)
+		}
+		if sub, ok := v["Fn::Sub"].(string); ok {
+			return sub
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// renderAll renders a value or list of values.
+func (b *iamReportBuilder) renderAll(v interface{}) []string {
+	var values []string
+	for _, item := range asList(v) {
+		values = append(values, b.render(item))
+	}
+	return values
+}
+
+// renderConditions renders the values of a condition block, keeping its
+// operator and key structure.
+func (b *iamReportBuilder) renderConditions(v interface{}) interface{} {
+	conditions, ok := v.(map[string]interface{})
+	if !ok {
+		return b.render(v)
+	}
+	rendered := make(map[string]interface{})
+	for operator, keys := range conditions {
+		keys, ok := keys.(map[string]interface{})
+		if !ok {
+			rendered[operator] = b.render(keys)
+			continue
+		}
+		values := make(map[string]interface{})
+		for key, value := range keys {
+			if list, ok := value.([]interface{}); ok {
+				values[key] = b.renderAll(list)
+			} else {
+				values[key] = b.render(value)
+			}
+		}
+		rendered[operator] = values
+	}
+	return rendered
+}
+
+// logicalIDs returns the logical IDs of the template's resources ordered by
+// construct path.
+func (b *iamReportBuilder) logicalIDs() []string {
+	ids := sortedKeys(b.resources)
+	sort.SliceStable(ids, func(i, j int) bool { return b.paths[ids[i]] < b.paths[ids[j]] })
+	return ids
+}
+
+// Markdown renders the report as markdown.
+func (r *IAMReport) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# IAM Report: %s\n\n", r.StackName)
+	sb.WriteString("Resources of the stack are shown as `${path}` or `${path.Attribute}`, where path is the construct path relative to the stack.\n")
+
+	sb.WriteString("\n## Roles\n")
+	for _, role := range r.Roles {
+		fmt.Fprintf(&sb, "\n### %s\n\n", role.Path)
+		if role.Imported {
+			sb.WriteString("- Imported: the role exists outside the stack; only the statements below are added to it\n")
+		}
+		if role.RoleName != "" {
+			fmt.Fprintf(&sb, "- Role name: `%s`\n", role.RoleName)
+		}
+		if len(role.TrustedPrincipals) > 0 {
+			fmt.Fprintf(&sb, "- Trusted principals: %s\n", markdownCode(role.TrustedPrincipals, ", "))
+		}
+		if len(role.Agents) > 0 {
+			fmt.Fprintf(&sb, "- Agents: %s\n", strings.Join(role.Agents, ", "))
+		}
+		if len(role.ManagedPolicies) > 0 {
+			fmt.Fprintf(&sb, "- Managed policies: %s\n", markdownCode(role.ManagedPolicies, ", "))
+		}
+		if role.PermissionsBoundary != "" {
+			fmt.Fprintf(&sb, "- Permissions boundary: `%s`\n", role.PermissionsBoundary)
+		}
+		sb.WriteString("\n")
+		writeStatementTable(&sb, role.Statements, false)
+	}
+
+	if len(r.ResourcePolicies) > 0 {
+		sb.WriteString("\n## Resource Policies\n")
+		for _, policy := range r.ResourcePolicies {
+			fmt.Fprintf(&sb, "\n### %s\n\n", policy.Path)
+			fmt.Fprintf(&sb, "- Type: `%s`\n", policy.Type)
+			fmt.Fprintf(&sb, "- Resources: %s\n\n", markdownCode(policy.Resources, ", "))
+			writeStatementTable(&sb, policy.Statements, true)
+		}
+	}
+
+	sb.WriteString("\n## Agents\n")
+	for _, agent := range r.Agents {
+		fmt.Fprintf(&sb, "\n### %s\n\n", agent.Name)
+		fmt.Fprintf(&sb, "- Role: %s\n", agent.Role)
+
+		var statements []IAMReportStatement
+		for _, role := range r.Roles {
+			for _, statement := range role.Statements {
+				for _, name := range statement.Agents {
+					if name == agent.Name {
+						statements = append(statements, statement)
+					}
+				}
+			}
+		}
+		if len(statements) == 0 {
+			sb.WriteString("- No statements specific to the agent; it has the shared statements of its role\n")
+			continue
+		}
+		sb.WriteString("- Statements granting access to the agent's resources, in addition to the shared statements of its role:\n\n")
+		writeStatementTable(&sb, statements, false)
+	}
+	return sb.String()
+}
+
+// writeStatementTable writes statements as a markdown table.
+func writeStatementTable(sb *strings.Builder, statements []IAMReportStatement, principals bool) {
+	if len(statements) == 0 {
+		sb.WriteString("No statements.\n")
+		return
+	}
+	if principals {
+		sb.WriteString("| Policy | Effect | Principals | Actions | Resources | Conditions |\n")
+		sb.WriteString("|---|---|---|---|---|---|\n")
+	} else {
+		sb.WriteString("| Policy | Effect | Actions | Resources | Conditions | Agents |\n")
+		sb.WriteString("|---|---|---|---|---|---|\n")
+	}
+	for _, statement := range statements {
+		conditions := ""
+		if statement.Conditions != nil {
+			data, err := json.Marshal(statement.Conditions)
+			if err == nil {
+				conditions = "`" + markdownEscape(string(data)) + "`"
+			}
+		}
+		cells := []string{markdownEscape(statement.Policy), statement.Effect}
+		if principals {
+			cells = append(cells, markdownCode(statement.Principals, "<br>"))
+		}
+		cells = append(cells, markdownCode(statement.Actions, "<br>"), markdownCode(statement.Resources, "<br>"), conditions)
+		if !principals {
+			cells = append(cells, strings.Join(statement.Agents, ", "))
+		}
+		fmt.Fprintf(sb, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+// markdownCode formats values as code spans joined by separator.
+func markdownCode(values []string, separator string) string {
+	code := make([]string, len(values))
+	for i, value := range values {
+		code[i] = "`" + markdownEscape(value) + "`"
+	}
+	return strings.Join(code, separator)
+}
+
+// markdownEscape escapes the pipes of a table cell.
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// sortedKeys returns the keys of a map, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,342 @@
+package agentcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ErrImageNotFound is returned by VerifyImage when the registry reports that
+// the image does not exist.
+var ErrImageNotFound = errors.New("image not found")
+
+// imageCheckTimeout bounds each registry check.
+const imageCheckTimeout = 15 * time.Second
+
+// dockerHubRegistry is the registry host for images without a registry.
+const dockerHubRegistry = "registry-1.docker.io"
+
+var (
+	// imageRepositoryPattern matches a repository path component.
+	imageRepositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+
+	// imageTagPattern matches an image tag.
+	imageTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+	// imageDigestPattern matches an image digest.
+	imageDigestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+	// ecrRegistryPattern matches ECR registry hosts and captures the account and region.
+	ecrRegistryPattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+	// bearerParamPattern matches key="value" pairs in a WWW-Authenticate header.
+	bearerParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// ImageReference is a parsed container image URI.
+type ImageReference struct {
+	// Registry is the registry host, e.g. "ghcr.io".
+	Registry string
+
+	// Repository is the repository path, e.g. "example/research".
+	Repository string
+
+	// Tag is the image tag. Empty if Digest is set.
+	Tag string
+
+	// Digest is the image digest, e.g. "sha256:...".
+	Digest string
+}
+
+// Reference returns the tag or digest used to look up the manifest.
+func (r *ImageReference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String returns the canonical image URI.
+func (r *ImageReference) String() string {
+	if r.Digest != "" {
+		return fmt.Sprintf("%s/%s@%s", r.Registry, r.Repository, r.Digest)
+	}
+	return fmt.Sprintf("%s/%s:%s", r.Registry, r.Repository, r.Tag)
+}
+
+// IsECR reports whether the image is hosted in Amazon ECR.
+func (r *ImageReference) IsECR() bool {
+	return ecrRegistryPattern.MatchString(r.Registry)
+}
+
+// ParseImageReference parses and validates a container image URI such as
+// "123456789012.dkr.ecr.us-east-1.amazonaws.com/research:v1" or
+// "ghcr.io/example/research@sha256:...". Images without a tag or digest
+// use "latest", and images without a registry use Docker Hub.
+func ParseImageReference(image string) (*ImageReference, error) {
+	if image == "" {
+		return nil, fmt.Errorf("image URI is empty")
+	}
+	if strings.ContainsAny(image, " \t\n") {
+		return nil, fmt.Errorf("image URI %q contains whitespace", image)
+	}
+
+	ref := &ImageReference{}
+	name := image
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !imageDigestPattern.MatchString(ref.Digest) {
+			return nil, fmt.Errorf("image URI %q has an invalid digest", image)
+		}
+	}
+
+	// A tag follows the last ':' after the last '/', so registry ports are kept
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !imageTagPattern.MatchString(ref.Tag) {
+			return nil, fmt.Errorf("image URI %q has an invalid tag %q", image, ref.Tag)
+		}
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	parts := strings.Split(name, "/")
+	if len(parts) > 1 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		parts = parts[1:]
+	} else {
+		ref.Registry = dockerHubRegistry
+		if len(parts) == 1 {
+			parts = []string{"library", parts[0]}
+		}
+	}
+
+	for _, part := range parts {
+		if !imageRepositoryPattern.MatchString(part) {
+			return nil, fmt.Errorf("image URI %q has an invalid repository component %q", image, part)
+		}
+	}
+	ref.Repository = strings.Join(parts, "/")
+
+	return ref, nil
+}
+
+// ecrRepositoryARNs returns the ARNs of the ECR repositories the agents'
+// images are pulled from. An image that is a token could be in any
+// repository, which yields "*".
+func (s *AgentCoreStack) ecrRepositoryARNs() []*string {
+	var arns []*string
+	seen := make(map[string]bool)
+	for _, agent := range s.Config.Agents {
+		if *awscdk.Token_IsUnresolved(agent.ContainerImage) {
+			return []*string{jsii.String("*")}
+		}
+		ref, err := ParseImageReference(agent.ContainerImage)
+		if err != nil || !ref.IsECR() {
+			continue
+		}
+		registry := ecrRegistryPattern.FindStringSubmatch(ref.Registry)
+		arn := fmt.Sprintf("arn:%s:ecr:%s:%s:repository/%s", *s.Stack.Partition(), registry[2], registry[1], ref.Repository)
+		if !seen[arn] {
+			seen[arn] = true
+			arns = append(arns, jsii.String(arn))
+		}
+	}
+	return arns
+}
+
+// VerifyImage checks that a container image exists in its registry. ECR
+// images are checked with the default AWS credentials; other registries are
+// checked with the registry v2 API, anonymously or with GITHUB_TOKEN for
+// ghcr.io. It returns an error wrapping ErrImageNotFound if the registry
+// reports the image missing, and nil if it exists or cannot be checked
+// (no credentials, no access, registry unreachable).
+func VerifyImage(ctx context.Context, image string) error {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+	defer cancel()
+
+	if ref.IsECR() {
+		return verifyECRImage(ctx, ref)
+	}
+	return verifyRegistryImage(ctx, ref)
+}
+
+// verifyECRImage checks an ECR image with DescribeImages.
+func verifyECRImage(ctx context.Context, ref *ImageReference) error {
+	matches := ecrRegistryPattern.FindStringSubmatch(ref.Registry)
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(matches[2]))
+	if err != nil {
+		return nil // No AWS configuration; cannot check
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil // No AWS credentials; cannot check
+	}
+
+	imageID := ecrtypes.ImageIdentifier{}
+	if ref.Digest != "" {
+		imageID.ImageDigest = aws.String(ref.Digest)
+	} else {
+		imageID.ImageTag = aws.String(ref.Tag)
+	}
+
+	_, err = ecr.NewFromConfig(cfg).DescribeImages(ctx, &ecr.DescribeImagesInput{
+		RegistryId:     aws.String(matches[1]),
+		RepositoryName: aws.String(ref.Repository),
+		ImageIds:       []ecrtypes.ImageIdentifier{imageID},
+	})
+
+	var imageNotFound *ecrtypes.ImageNotFoundException
+	var repoNotFound *ecrtypes.RepositoryNotFoundException
+	switch {
+	case errors.As(err, &imageNotFound), errors.As(err, &repoNotFound):
+		return fmt.Errorf("%s: %w", ref, ErrImageNotFound)
+	default:
+		return nil // Exists, or no access to check
+	}
+}
+
+// verifyRegistryImage checks an image with a registry v2 manifest HEAD request,
+// following the bearer token challenge if the registry requires one.
+func verifyRegistryImage(ctx context.Context, ref *ImageReference) error {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, ref.Reference())
+
+	resp, err := headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return nil // Registry unreachable; cannot check
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := fetchRegistryToken(ctx, ref, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil // Cannot authenticate; cannot check
+		}
+		resp, err = headManifest(ctx, manifestURL, token)
+		if err != nil {
+			return nil
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", ref, ErrImageNotFound)
+	}
+	return nil // Exists, or private without credentials
+}
+
+// headManifest sends a manifest HEAD request.
+func headManifest(ctx context.Context, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join([]string{
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	}, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// fetchRegistryToken answers a registry bearer token challenge.
+func fetchRegistryToken(ctx context.Context, ref *ImageReference, challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported auth challenge: %s", challenge)
+	}
+	params := make(map[string]string)
+	for _, match := range bearerParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("auth challenge has no realm")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"], nil)
+	if err != nil {
+		return "", err
+	}
+	query := req.URL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+	req.URL.RawQuery = query.Encode()
+	if username, password, ok := registryCredentials(ref.Registry); ok {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// registryCredentials returns credentials for a registry from the environment.
+func registryCredentials(registry string) (username, password string, ok bool) {
+	if registry == "ghcr.io" {
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			return "token", token, true
+		}
+	}
+	return "", "", false
+}
+
+// validateImages checks the syntax of every literal agent image and verifies
+// that it exists in its registry. Token images are skipped.
+func validateImages(ctx context.Context, config StackConfig) error {
+	for i, agent := range config.Agents {
+		if *awscdk.Token_IsUnresolved(agent.ContainerImage) {
+			continue
+		}
+		if err := VerifyImage(ctx, agent.ContainerImage); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+	}
+	return nil
+}
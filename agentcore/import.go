@@ -0,0 +1,70 @@
+package agentcore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ImportMetadataKey is the resource metadata key under which
+// ImportExistingRuntime records the CloudFormation resource identifier of
+// an existing resource, e.g. {"AgentRuntimeId": "research-AbC123"}. The
+// import command reads it to build the resource mapping of `cdk import`.
+const ImportMetadataKey = "agentkit:import"
+
+// ImportExistingRuntime brings a runtime created outside the stack, e.g. in
+// the console, under the management of the stack's agent of the same name
+// instead of creating a new one:
+//
+//	stack := agentcore.NewAgentCoreStack(app, "my-agents", config)
+//	agentcore.ImportExistingRuntime(stack.Stack, "research",
+//		"arn:aws:bedrock-agentcore:us-east-1:123456789012:runtime/research-AbC123")
+//
+// It records the runtime ID for the import command and retains the runtime
+// and its endpoints, since CloudFormation only imports resources with a
+// deletion policy. Run `import` and `cdk import` once, then deploy as usual;
+// the first deploy after the import applies the stack's configuration to
+// the runtime. It panics if the stack has no agent of that name or the ARN
+// is not a runtime ARN.
+func ImportExistingRuntime(scope constructs.Construct, name, runtimeARN string) awsbedrockagentcore.CfnRuntime {
+	if !runtimeARNPattern.MatchString(runtimeARN) {
+		panic(fmt.Sprintf("agentcore: %q is not an agent runtime ARN", runtimeARN))
+	}
+	runtimeID := runtimeARN[strings.LastIndex(runtimeARN, "/")+1:]
+
+	// Runtimes of partitioned stacks are in nested stacks of the top-level
+	// stack
+	stack := awscdk.Stack_Of(scope)
+	for stack.NestedStackParent() != nil {
+		stack = stack.NestedStackParent()
+	}
+
+	var runtime awsbedrockagentcore.CfnRuntime
+	var endpoints []awsbedrockagentcore.CfnRuntimeEndpoint
+	for _, c := range *stack.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		switch resource := c.(type) {
+		case awsbedrockagentcore.CfnRuntime:
+			if *resource.Node().Id() == "Runtime-"+name {
+				runtime = resource
+			}
+		case awsbedrockagentcore.CfnRuntimeEndpoint:
+			endpoints = append(endpoints, resource)
+		}
+	}
+	if runtime == nil {
+		panic(fmt.Sprintf("agentcore: no runtime for agent %q in stack %s", name, *stack.StackName()))
+	}
+
+	runtime.AddMetadata(jsii.String(ImportMetadataKey), map[string]string{"AgentRuntimeId": runtimeID})
+	runtime.ApplyRemovalPolicy(awscdk.RemovalPolicy_RETAIN, nil)
+	for _, endpoint := range endpoints {
+		if *endpoint.AgentRuntimeId() == *runtime.AttrAgentRuntimeId() {
+			endpoint.ApplyRemovalPolicy(awscdk.RemovalPolicy_RETAIN, nil)
+		}
+	}
+	return runtime
+}
@@ -0,0 +1,169 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// lambdaFunctionARNPattern matches Lambda function ARNs, optionally
+// qualified with a version or alias.
+var lambdaFunctionARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:lambda:[a-z0-9-]+:\d{12}:function:[a-zA-Z0-9_-]+(:[a-zA-Z0-9_$-]+)?$`)
+
+// interceptionPoints are the gateway interception points.
+var interceptionPoints = map[string]bool{"REQUEST": true, "RESPONSE": true}
+
+// InterceptorOptions attaches a Lambda function to the gateway that sees
+// every request (and optionally every response), so tenant routing and
+// custom authorization logic can ship with the stack. The function is
+// either deployed from CodePath or referenced by FunctionARN.
+type InterceptorOptions struct {
+	// CodePath is a directory or .zip file, relative to the CDK app
+	// directory, deployed as a new function. Mutually exclusive with
+	// FunctionARN.
+	CodePath string `json:"codePath,omitempty" yaml:"codePath,omitempty"`
+
+	// FunctionARN references an existing function. Mutually exclusive with
+	// CodePath.
+	FunctionARN string `json:"functionArn,omitempty" yaml:"functionArn,omitempty"`
+
+	// Runtime is the Lambda runtime of a deployed function.
+	// Default: "provided.al2023" (a Go "bootstrap" binary)
+	Runtime string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+
+	// Handler is the handler of a deployed function.
+	// Default: "bootstrap"
+	Handler string `json:"handler,omitempty" yaml:"handler,omitempty"`
+
+	// MemoryMB is the memory of a deployed function.
+	// Default: 256
+	MemoryMB int `json:"memoryMB,omitempty" yaml:"memoryMB,omitempty"`
+
+	// TimeoutSeconds is the timeout of a deployed function.
+	// Default: 10
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+
+	// Environment sets environment variables of a deployed function.
+	Environment map[string]string `json:"environment,omitempty" yaml:"environment,omitempty"`
+
+	// InterceptionPoints are REQUEST and/or RESPONSE.
+	// Default: ["REQUEST"]
+	InterceptionPoints []string `json:"interceptionPoints,omitempty" yaml:"interceptionPoints,omitempty"`
+
+	// PassRequestHeaders passes the inbound request headers, including
+	// Authorization, to the interceptor.
+	PassRequestHeaders bool `json:"passRequestHeaders,omitempty" yaml:"passRequestHeaders,omitempty"`
+}
+
+// validate validates the interceptor options.
+func (o *InterceptorOptions) validate() error {
+	const prefix = "gateway.interceptorLambda"
+	switch {
+	case o.CodePath != "" && o.FunctionARN != "":
+		return fmt.Errorf("%s.codePath and %s.functionArn are mutually exclusive", prefix, prefix)
+	case o.CodePath == "" && o.FunctionARN == "":
+		return fmt.Errorf("%s requires codePath or functionArn", prefix)
+	case o.FunctionARN != "" && (o.Runtime != "" || o.Handler != "" || o.MemoryMB != 0 || o.TimeoutSeconds != 0 || len(o.Environment) > 0):
+		return fmt.Errorf("%s.runtime, handler, memoryMB, timeoutSeconds, and environment require %s.codePath", prefix, prefix)
+	}
+
+	if o.FunctionARN != "" && !*awscdk.Token_IsUnresolved(o.FunctionARN) && !lambdaFunctionARNPattern.MatchString(o.FunctionARN) {
+		return fmt.Errorf("%s.functionArn %q must be a Lambda function ARN (arn:aws:lambda:{region}:{account}:function:{name})", prefix, o.FunctionARN)
+	}
+	if o.MemoryMB != 0 && (o.MemoryMB < 128 || o.MemoryMB > 10240) {
+		return fmt.Errorf("%s.memoryMB must be between 128 and 10240, got %d", prefix, o.MemoryMB)
+	}
+	if o.TimeoutSeconds < 0 || o.TimeoutSeconds > 900 {
+		return fmt.Errorf("%s.timeoutSeconds must be between 1 and 900, got %d", prefix, o.TimeoutSeconds)
+	}
+
+	seen := make(map[string]bool)
+	for i, point := range o.InterceptionPoints {
+		if !interceptionPoints[point] {
+			return fmt.Errorf("%s.interceptionPoints[%d] %q must be REQUEST or RESPONSE", prefix, i, point)
+		}
+		if seen[point] {
+			return fmt.Errorf("%s.interceptionPoints[%d]: duplicate %s", prefix, i, point)
+		}
+		seen[point] = true
+	}
+	return nil
+}
+
+// createGatewayInterceptor deploys or imports the interceptor function and
+// grants the gateway role permission to invoke it. It returns the
+// interceptor configuration of the gateway, or nil if none is configured.
+func (s *AgentCoreStack) createGatewayInterceptor() interface{} {
+	if s.Options.Gateway == nil || s.Options.Gateway.InterceptorLambda == nil {
+		return nil
+	}
+	opts := s.Options.Gateway.InterceptorLambda
+
+	if opts.FunctionARN != "" {
+		s.GatewayInterceptor = awslambda.Function_FromFunctionArn(s.Stack,
+			jsii.String("GatewayInterceptor"), jsii.String(opts.FunctionARN))
+	} else {
+		runtime := opts.Runtime
+		if runtime == "" {
+			runtime = "provided.al2023"
+		}
+		handler := opts.Handler
+		if handler == "" {
+			handler = "bootstrap"
+		}
+		memoryMB := opts.MemoryMB
+		if memoryMB == 0 {
+			memoryMB = 256
+		}
+		timeoutSeconds := opts.TimeoutSeconds
+		if timeoutSeconds == 0 {
+			timeoutSeconds = 10
+		}
+		environment := make(map[string]*string, len(opts.Environment))
+		for k, v := range opts.Environment {
+			environment[k] = jsii.String(v)
+		}
+
+		s.GatewayInterceptor = awslambda.NewFunction(s.Stack, jsii.String("GatewayInterceptor"), &awslambda.FunctionProps{
+			Description:  jsii.String(fmt.Sprintf("Gateway interceptor for stack %s", s.Config.StackName)),
+			Code:         awslambda.Code_FromAsset(jsii.String(opts.CodePath), nil),
+			Runtime:      awslambda.NewRuntime(jsii.String(runtime), awslambda.RuntimeFamily_OTHER, nil),
+			Handler:      jsii.String(handler),
+			Architecture: awslambda.Architecture_ARM_64(),
+			MemorySize:   jsii.Number(float64(memoryMB)),
+			Timeout:      awscdk.Duration_Seconds(jsii.Number(float64(timeoutSeconds))),
+			Environment:  &environment,
+		})
+	}
+
+	// The gateway invokes the interceptor with its own role (the execution role).
+	s.GatewayInterceptor.GrantInvoke(s.ExecutionRole)
+
+	points := opts.InterceptionPoints
+	if len(points) == 0 {
+		points = []string{"REQUEST"}
+	}
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("GatewayInterceptorArn"), &awscdk.CfnOutputProps{
+		Value:       s.GatewayInterceptor.FunctionArn(),
+		Description: jsii.String("Gateway interceptor Lambda function ARN"),
+	})
+
+	return &[]interface{}{
+		&awsbedrockagentcore.CfnGateway_GatewayInterceptorConfigurationProperty{
+			InterceptionPoints: jsii.Strings(points...),
+			Interceptor: &awsbedrockagentcore.CfnGateway_InterceptorConfigurationProperty{
+				Lambda: &awsbedrockagentcore.CfnGateway_LambdaInterceptorConfigurationProperty{
+					Arn: s.GatewayInterceptor.FunctionArn(),
+				},
+			},
+			InputConfiguration: &awsbedrockagentcore.CfnGateway_InterceptorInputConfigurationProperty{
+				PassRequestHeaders: jsii.Bool(opts.PassRequestHeaders),
+			},
+		},
+	}
+}
@@ -0,0 +1,195 @@
+package agentcore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// placeholderPattern matches ${env:VAR}, ${aws:accountId}, ${aws:region},
+// and ${ssm:/path} placeholders. A leading "$$" escapes the placeholder.
+var placeholderPattern = regexp.MustCompile(`\$?\$\{(env|aws|ssm):([^}]*)\}`)
+
+// WithAWSConfig sets the AWS configuration used to resolve ${aws:...} and
+// ${ssm:...} placeholders. Default: the default credential chain.
+func WithAWSConfig(cfg aws.Config) LoadOption {
+	return func(s *loadSettings) {
+		s.awsConfig = &cfg
+	}
+}
+
+// hasPlaceholders reports whether a config file contains placeholders.
+func hasPlaceholders(data []byte) bool {
+	return bytes.Contains(data, []byte("${")) && placeholderPattern.Match(data)
+}
+
+// placeholderResolver resolves placeholders in config values. AWS
+// configuration is loaded only when an AWS placeholder is used, and each
+// placeholder is resolved once.
+type placeholderResolver struct {
+	awsConfig *aws.Config
+	useCDKEnv bool // Prefer the account and region set by the cdk CLI
+	resolved  map[string]string
+}
+
+// newPlaceholderResolver returns a resolver using cfg, or the default AWS
+// configuration if cfg is nil.
+func newPlaceholderResolver(cfg *aws.Config) *placeholderResolver {
+	return &placeholderResolver{awsConfig: cfg, useCDKEnv: cfg == nil, resolved: make(map[string]string)}
+}
+
+// resolveValues resolves the placeholders in every string of a decoded
+// config document. Map keys are not resolved.
+func (r *placeholderResolver) resolveValues(ctx context.Context, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return r.resolveString(ctx, v)
+	case map[string]any:
+		for key, item := range v {
+			resolved, err := r.resolveValues(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+		return v, nil
+	case []any:
+		for i, item := range v {
+			resolved, err := r.resolveValues(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// resolveString resolves the placeholders in a string.
+func (r *placeholderResolver) resolveString(ctx context.Context, s string) (string, error) {
+	var resolveErr error
+	result := placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+		if match[1] == '$' {
+			return match[1:] // Escaped
+		}
+		parts := placeholderPattern.FindStringSubmatch(match)
+		value, err := r.resolve(ctx, parts[1], parts[2])
+		if err != nil {
+			resolveErr = fmt.Errorf("resolving %s: %w", match, err)
+			return match
+		}
+		return value
+	})
+	return result, resolveErr
+}
+
+// resolve returns the value of a single placeholder.
+func (r *placeholderResolver) resolve(ctx context.Context, source, name string) (string, error) {
+	key := source + ":" + name
+	if value, ok := r.resolved[key]; ok {
+		return value, nil
+	}
+
+	var value string
+	var err error
+	switch source {
+	case "env":
+		var ok bool
+		if value, ok = os.LookupEnv(name); !ok {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+	case "aws":
+		value, err = r.resolveAWS(ctx, name)
+	case "ssm":
+		value, err = r.resolveSSM(ctx, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	r.resolved[key] = value
+	return value, nil
+}
+
+// resolveAWS returns the account ID or region. Unless WithAWSConfig is
+// given, the values set by the cdk CLI (CDK_DEFAULT_ACCOUNT,
+// CDK_DEFAULT_REGION) are used when present.
+func (r *placeholderResolver) resolveAWS(ctx context.Context, name string) (string, error) {
+	switch name {
+	case "accountId":
+		if account := os.Getenv("CDK_DEFAULT_ACCOUNT"); r.useCDKEnv && account != "" {
+			return account, nil
+		}
+		cfg, err := r.config(ctx)
+		if err != nil {
+			return "", err
+		}
+		identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return "", fmt.Errorf("getting AWS identity: %w", err)
+		}
+		return aws.ToString(identity.Account), nil
+	case "region":
+		if region := os.Getenv("CDK_DEFAULT_REGION"); r.useCDKEnv && region != "" {
+			return region, nil
+		}
+		cfg, err := r.config(ctx)
+		if err != nil {
+			return "", err
+		}
+		if cfg.Region == "" {
+			return "", fmt.Errorf("no AWS region configured")
+		}
+		return cfg.Region, nil
+	default:
+		return "", fmt.Errorf("unknown aws value %q (use accountId or region)", name)
+	}
+}
+
+// resolveSSM returns the value of an SSM String or StringList parameter.
+// SecureString parameters are rejected, because the value would be written
+// into the template in plain text.
+func (r *placeholderResolver) resolveSSM(ctx context.Context, name string) (string, error) {
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("reading SSM parameter: %w", err)
+	}
+	if out.Parameter.Type == ssmtypes.ParameterTypeSecureString {
+		return "", fmt.Errorf("%s is a SecureString parameter; pass secrets to agents with secretsARNs or the secrets backend instead", name)
+	}
+	return aws.ToString(out.Parameter.Value), nil
+}
+
+// config returns the AWS configuration, loading the default configuration
+// on first use.
+func (r *placeholderResolver) config(ctx context.Context) (aws.Config, error) {
+	if r.awsConfig == nil {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("loading AWS config: %w", err)
+		}
+		if cfg.Region == "" {
+			cfg.Region = os.Getenv("CDK_DEFAULT_REGION")
+		}
+		r.awsConfig = &cfg
+	}
+	return *r.awsConfig, nil
+}
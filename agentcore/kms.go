@@ -0,0 +1,122 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// kmsKeyARNPattern matches KMS key ARNs. Aliases are not accepted, because
+// grants and key policies need the key itself.
+var kmsKeyARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:\d{12}:key/[a-zA-Z0-9-]+$`)
+
+// kmsAliasPattern matches KMS alias names without the "alias/" prefix.
+var kmsAliasPattern = regexp.MustCompile(`^[a-zA-Z0-9/_-]+$`)
+
+// KMSOptions configures a customer managed KMS key that encrypts the
+// stack's secret, log group, memory stores, gateway, and config bucket in
+// place of AWS managed keys.
+type KMSOptions struct {
+	// Create creates a customer managed key owned by the stack. Its key
+	// policy lets CloudWatch Logs and AgentCore use it.
+	Create bool `json:"create,omitempty" yaml:"create,omitempty"`
+
+	// KeyARN imports an existing customer managed key. Its key policy must
+	// already allow CloudWatch Logs and AgentCore, and delegate access to
+	// IAM policies in the account. Mutually exclusive with Create.
+	KeyARN string `json:"keyArn,omitempty" yaml:"keyArn,omitempty"`
+
+	// Alias is the alias of a created key, without the "alias/" prefix.
+	// Default: stack name
+	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
+
+	// DisableKeyRotation turns off annual rotation of a created key.
+	DisableKeyRotation bool `json:"disableKeyRotation,omitempty" yaml:"disableKeyRotation,omitempty"`
+}
+
+// validate validates the KMS options.
+func (o *KMSOptions) validate() error {
+	switch {
+	case o.Create && o.KeyARN != "":
+		return fmt.Errorf("kms.create and kms.keyArn are mutually exclusive")
+	case !o.Create && o.KeyARN == "":
+		return fmt.Errorf("kms requires create or keyArn")
+	case !o.Create && (o.Alias != "" || o.DisableKeyRotation):
+		return fmt.Errorf("kms.alias and kms.disableKeyRotation require kms.create")
+	}
+
+	if o.KeyARN != "" && !*awscdk.Token_IsUnresolved(o.KeyARN) && !kmsKeyARNPattern.MatchString(o.KeyARN) {
+		return fmt.Errorf("kms.keyArn %q must be a KMS key ARN (arn:aws:kms:{region}:{account}:key/{id})", o.KeyARN)
+	}
+	if o.Alias != "" && (strings.HasPrefix(o.Alias, "alias/") || strings.HasPrefix(o.Alias, "aws/") || !kmsAliasPattern.MatchString(o.Alias)) {
+		return fmt.Errorf("kms.alias %q must contain only letters, digits, '/', '_', and '-', without the alias/ prefix, and must not start with aws/", o.Alias)
+	}
+	return nil
+}
+
+// createKMSKey creates or imports the customer managed key.
+func (s *AgentCoreStack) createKMSKey() {
+	opts := s.Options.KMS
+	if opts == nil {
+		return
+	}
+
+	if opts.KeyARN != "" {
+		s.KMSKey = awskms.Key_FromKeyArn(s.Stack, jsii.String("KMSKey"), jsii.String(opts.KeyARN))
+		return
+	}
+
+	alias := opts.Alias
+	if alias == "" {
+		alias = s.Config.StackName
+	}
+
+	key := awskms.NewKey(s.Stack, jsii.String("KMSKey"), &awskms.KeyProps{
+		Alias:             jsii.String("alias/" + alias),
+		Description:       jsii.String(fmt.Sprintf("Encrypts %s AgentCore resources", s.Config.StackName)),
+		EnableKeyRotation: jsii.Bool(!opts.DisableKeyRotation),
+		RemovalPolicy:     s.kmsKeyRemovalPolicy(),
+	})
+
+	// CloudWatch Logs uses the key on behalf of the stack's log group
+	region, account := *s.Stack.Region(), *s.Stack.Account()
+	key.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:     awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{awsiam.NewServicePrincipal(jsii.String(fmt.Sprintf("logs.%s.amazonaws.com", region)), nil)},
+		Actions:    jsii.Strings("kms:Encrypt*", "kms:Decrypt*", "kms:ReEncrypt*", "kms:GenerateDataKey*", "kms:Describe*"),
+		Resources:  jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"ArnLike": map[string]interface{}{
+				"kms:EncryptionContext:aws:logs:arn": fmt.Sprintf("arn:%s:logs:%s:%s:log-group:*", *s.Stack.Partition(), region, account),
+			},
+		},
+	}), jsii.Bool(false))
+
+	// AgentCore encrypts memory and gateway data with the key
+	key.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:     awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{awsiam.NewServicePrincipal(jsii.String("bedrock-agentcore.amazonaws.com"), nil)},
+		Actions:    jsii.Strings("kms:Decrypt", "kms:GenerateDataKey*", "kms:DescribeKey", "kms:CreateGrant"),
+		Resources:  jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"aws:SourceAccount": account,
+			},
+		},
+	}), jsii.Bool(false))
+
+	s.KMSKey = key
+}
+
+// kmsKeyARN returns the ARN of the customer managed key, or nil.
+func (s *AgentCoreStack) kmsKeyARN() *string {
+	if s.KMSKey == nil {
+		return nil
+	}
+	return s.KMSKey.KeyArn()
+}
@@ -0,0 +1,552 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrock"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsopensearchserverless"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3vectors"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Vector stores of KnowledgeBaseOptions.VectorStore.
+const (
+	// VectorStoreS3Vectors stores the embeddings in an S3 vector bucket.
+	VectorStoreS3Vectors = "s3vectors"
+
+	// VectorStoreOpenSearchServerless stores the embeddings in an
+	// OpenSearch Serverless vector search collection.
+	VectorStoreOpenSearchServerless = "opensearch-serverless"
+)
+
+// DefaultEmbeddingModel is the embedding model of created knowledge bases.
+const DefaultEmbeddingModel = "amazon.titan-embed-text-v2:0"
+
+const (
+	// defaultEmbeddingDimensions is the vector size of the default
+	// embedding model.
+	defaultEmbeddingDimensions = 1024
+
+	// Field names of the OpenSearch vector index, the defaults of the
+	// Bedrock console
+	knowledgeBaseIndexName   = "bedrock-knowledge-base-default-index"
+	knowledgeBaseVectorField = "bedrock-knowledge-base-default-vector"
+	knowledgeBaseTextField   = "AMAZON_BEDROCK_TEXT_CHUNK"
+	knowledgeBaseMetaField   = "AMAZON_BEDROCK_METADATA"
+)
+
+var (
+	// knowledgeBaseNamePattern is the naming rule for knowledge bases of
+	// the stack, which name their resources and environment variables.
+	knowledgeBaseNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,19}$`)
+
+	// knowledgeBaseIDPattern matches Bedrock knowledge base IDs.
+	knowledgeBaseIDPattern = regexp.MustCompile(`^[0-9a-zA-Z]{10}$`)
+
+	// collectionNamePattern is the naming rule for OpenSearch Serverless
+	// collections, shortened so the policy names derived from it fit.
+	collectionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{2,27}$`)
+)
+
+// KnowledgeBaseOptions attaches a Bedrock knowledge base to the stack for
+// retrieval-augmented generation. It either references an existing
+// knowledge base, or creates one with its vector store, embedding model
+// permissions, and S3 data source. Agents that list its name in
+// AgentOptions.KnowledgeBases get its ID in KNOWLEDGE_BASE_{NAME}_ID and
+// may query it.
+type KnowledgeBaseOptions struct {
+	// Name identifies the knowledge base in the stack, e.g. "docs":
+	// lowercase letters, digits, and '-'.
+	Name string `json:"name" yaml:"name"`
+
+	// KnowledgeBaseID is an existing knowledge base to attach instead of
+	// creating one. The other fields must be empty.
+	KnowledgeBaseID string `json:"knowledgeBaseId,omitempty" yaml:"knowledgeBaseId,omitempty"`
+
+	// Description describes the knowledge base.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// VectorStore is "s3vectors" or "opensearch-serverless". OpenSearch
+	// Serverless bills at least one OCU around the clock.
+	// Default: "s3vectors"
+	VectorStore string `json:"vectorStore,omitempty" yaml:"vectorStore,omitempty"`
+
+	// EmbeddingModel is the Bedrock embedding model ID.
+	// Default: "amazon.titan-embed-text-v2:0"
+	EmbeddingModel string `json:"embeddingModel,omitempty" yaml:"embeddingModel,omitempty"`
+
+	// Dimensions is the vector size the embedding model produces.
+	// Default: 1024
+	Dimensions int `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
+
+	// DataSourceBucketARN is the S3 bucket of the documents to ingest.
+	// Default: no data source
+	DataSourceBucketARN string `json:"dataSourceBucketArn,omitempty" yaml:"dataSourceBucketArn,omitempty"`
+
+	// InclusionPrefixes limits ingestion to objects with these key
+	// prefixes.
+	InclusionPrefixes []string `json:"inclusionPrefixes,omitempty" yaml:"inclusionPrefixes,omitempty"`
+
+	// CollectionName is the OpenSearch Serverless collection name, 3-28
+	// lowercase letters, digits, and '-'.
+	// Default: "{stackName}-{name}", lowercased
+	CollectionName string `json:"collectionName,omitempty" yaml:"collectionName,omitempty"`
+
+	// StandbyReplicas keeps standby replicas of the collection in another
+	// Availability Zone, doubling its minimum OCUs.
+	StandbyReplicas bool `json:"standbyReplicas,omitempty" yaml:"standbyReplicas,omitempty"`
+
+	// AdminPrincipalARNs may manage the collection's indexes, besides the
+	// knowledge base role and the CloudFormation execution role that
+	// creates the vector index: the synthesizer's
+	// CloudFormationExecutionRoleARN, or the CDK bootstrap role. When
+	// CloudFormation runs as another principal, such as the caller of
+	// deploy.Deploy without AssumeBootstrapRoles, list it here or the index
+	// can't be created.
+	AdminPrincipalARNs []string `json:"adminPrincipalArns,omitempty" yaml:"adminPrincipalArns,omitempty"`
+}
+
+// vectorStore returns the vector store, defaulted.
+func (o *KnowledgeBaseOptions) vectorStore() string {
+	if o.VectorStore == "" {
+		return VectorStoreS3Vectors
+	}
+	return o.VectorStore
+}
+
+// collectionName returns the OpenSearch Serverless collection name.
+func (o *KnowledgeBaseOptions) collectionName(stackName string) string {
+	if o.CollectionName != "" {
+		return o.CollectionName
+	}
+	return strings.ToLower(fmt.Sprintf("%s-%s", stackName, o.Name))
+}
+
+// validateKnowledgeBases validates the knowledge bases of the stack and the
+// agents' references to them.
+func validateKnowledgeBases(knowledgeBases []KnowledgeBaseOptions, config StackConfig, agents map[string]*AgentOptions) error {
+	names := make(map[string]bool, len(knowledgeBases))
+	for i, kb := range knowledgeBases {
+		prefix := fmt.Sprintf("knowledgeBases[%d]", i)
+		if err := kb.validate(prefix, config.StackName); err != nil {
+			return err
+		}
+		if names[kb.Name] {
+			return fmt.Errorf("%s.name %q is not unique", prefix, kb.Name)
+		}
+		names[kb.Name] = true
+	}
+
+	for i, agent := range config.Agents {
+		opts := agents[agent.Name]
+		if opts == nil {
+			continue
+		}
+		for _, name := range opts.KnowledgeBases {
+			if !names[name] {
+				return fmt.Errorf("agents[%d] (%s): knowledgeBases: %q is not a knowledge base of the stack", i, agent.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// validate validates a knowledge base.
+func (o *KnowledgeBaseOptions) validate(prefix, stackName string) error {
+	if !knowledgeBaseNamePattern.MatchString(o.Name) {
+		return fmt.Errorf("%s.name %q must be 1-20 lowercase letters, digits, or '-', starting with a letter", prefix, o.Name)
+	}
+
+	if o.KnowledgeBaseID != "" {
+		if !*awscdk.Token_IsUnresolved(o.KnowledgeBaseID) && !knowledgeBaseIDPattern.MatchString(o.KnowledgeBaseID) {
+			return fmt.Errorf("%s.knowledgeBaseId %q must be a knowledge base ID of 10 letters and digits", prefix, o.KnowledgeBaseID)
+		}
+		if o.VectorStore != "" || o.EmbeddingModel != "" || o.Dimensions != 0 || o.DataSourceBucketARN != "" ||
+			len(o.InclusionPrefixes) > 0 || o.CollectionName != "" || o.StandbyReplicas || len(o.AdminPrincipalARNs) > 0 {
+			return fmt.Errorf("%s: knowledgeBaseId attaches an existing knowledge base; remove the settings for creating one", prefix)
+		}
+		return nil
+	}
+
+	switch o.vectorStore() {
+	case VectorStoreS3Vectors:
+		if o.CollectionName != "" || o.StandbyReplicas || len(o.AdminPrincipalARNs) > 0 {
+			return fmt.Errorf("%s: collectionName, standbyReplicas, and adminPrincipalArns require vectorStore %s", prefix, VectorStoreOpenSearchServerless)
+		}
+	case VectorStoreOpenSearchServerless:
+		if name := o.collectionName(stackName); !collectionNamePattern.MatchString(name) {
+			return fmt.Errorf("%s: collection name %q must be 3-28 lowercase letters, digits, or '-', starting with a letter; set collectionName", prefix, name)
+		}
+	default:
+		return fmt.Errorf("%s.vectorStore must be %s or %s, got %q", prefix, VectorStoreS3Vectors, VectorStoreOpenSearchServerless, o.VectorStore)
+	}
+
+	switch {
+	case o.Dimensions < 0:
+		return fmt.Errorf("%s.dimensions must not be negative, got %d", prefix, o.Dimensions)
+	case o.DataSourceBucketARN != "" && !*awscdk.Token_IsUnresolved(o.DataSourceBucketARN) && !bucketARNPattern.MatchString(o.DataSourceBucketARN):
+		return fmt.Errorf("%s.dataSourceBucketArn %q must be an S3 bucket ARN", prefix, o.DataSourceBucketARN)
+	case len(o.InclusionPrefixes) > 0 && o.DataSourceBucketARN == "":
+		return fmt.Errorf("%s.inclusionPrefixes requires dataSourceBucketArn", prefix)
+	}
+	return nil
+}
+
+// KnowledgeBaseEnvName returns the environment variable holding the ID of
+// a knowledge base of the stack, e.g. KNOWLEDGE_BASE_DOCS_ID for "docs".
+func KnowledgeBaseEnvName(name string) string {
+	return "KNOWLEDGE_BASE_" + agentEnvSegment(name) + "_ID"
+}
+
+// knowledgeBaseOptions returns the options of the named knowledge base.
+func (s *AgentCoreStack) knowledgeBaseOptions(name string) *KnowledgeBaseOptions {
+	for i := range s.Options.KnowledgeBases {
+		if s.Options.KnowledgeBases[i].Name == name {
+			return &s.Options.KnowledgeBases[i]
+		}
+	}
+	return nil
+}
+
+// knowledgeBaseID returns the ID of a knowledge base of the stack.
+func (s *AgentCoreStack) knowledgeBaseID(name string) *string {
+	if kb, ok := s.KnowledgeBases[name]; ok {
+		return kb.AttrKnowledgeBaseId()
+	}
+	return jsii.String(s.knowledgeBaseOptions(name).KnowledgeBaseID)
+}
+
+// knowledgeBaseARN returns the ARN of a knowledge base of the stack.
+func (s *AgentCoreStack) knowledgeBaseARN(name string) *string {
+	if kb, ok := s.KnowledgeBases[name]; ok {
+		return kb.AttrKnowledgeBaseArn()
+	}
+	return s.Stack.FormatArn(&awscdk.ArnComponents{
+		Service:      jsii.String("bedrock"),
+		Resource:     jsii.String("knowledge-base"),
+		ResourceName: s.knowledgeBaseID(name),
+	})
+}
+
+// createKnowledgeBases creates the knowledge bases of the stack that don't
+// exist yet.
+func (s *AgentCoreStack) createKnowledgeBases() {
+	s.KnowledgeBases = make(map[string]awsbedrock.CfnKnowledgeBase)
+	s.VectorCollections = make(map[string]awsopensearchserverless.CfnCollection)
+	for i := range s.Options.KnowledgeBases {
+		if opts := &s.Options.KnowledgeBases[i]; opts.KnowledgeBaseID == "" {
+			s.createKnowledgeBase(opts)
+		}
+	}
+}
+
+// createKnowledgeBase creates a knowledge base with its role, vector store,
+// and data source.
+func (s *AgentCoreStack) createKnowledgeBase(opts *KnowledgeBaseOptions) {
+	id := func(kind string) *string { return jsii.String(fmt.Sprintf("KnowledgeBase-%s-%s", opts.Name, kind)) }
+
+	model := opts.EmbeddingModel
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	dimensions := opts.Dimensions
+	if dimensions == 0 {
+		dimensions = defaultEmbeddingDimensions
+	}
+
+	role := awsiam.NewRole(s.Stack, id("Role"), &awsiam.RoleProps{
+		Description: jsii.String(fmt.Sprintf("Knowledge base %s of stack %s", opts.Name, s.Config.StackName)),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("bedrock.amazonaws.com"), &awsiam.ServicePrincipalOpts{
+			Conditions: &map[string]interface{}{
+				"StringEquals": map[string]interface{}{"aws:SourceAccount": s.Stack.Account()},
+			},
+		}),
+	})
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:  awsiam.Effect_ALLOW,
+		Actions: jsii.Strings("bedrock:InvokeModel"),
+		Resources: &[]*string{s.Stack.FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("bedrock"),
+			Account:      jsii.String(""),
+			Resource:     jsii.String("foundation-model"),
+			ResourceName: jsii.String(model),
+		})},
+	}))
+	if opts.DataSourceBucketARN != "" {
+		role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect:    awsiam.Effect_ALLOW,
+			Actions:   jsii.Strings("s3:ListBucket", "s3:GetObject"),
+			Resources: jsii.Strings(opts.DataSourceBucketARN, opts.DataSourceBucketARN+"/*"),
+		}))
+	}
+	if s.KMSKey != nil {
+		// Documents in buckets encrypted with the stack key
+		s.KMSKey.GrantDecrypt(role)
+	}
+
+	var storage *awsbedrock.CfnKnowledgeBase_StorageConfigurationProperty
+	var dependency awscdk.CfnResource
+	if opts.vectorStore() == VectorStoreOpenSearchServerless {
+		storage, dependency = s.createVectorCollection(opts, role, dimensions)
+	} else {
+		storage, dependency = s.createVectorIndex(opts, role, dimensions)
+	}
+
+	description := opts.Description
+	if description == "" {
+		description = fmt.Sprintf("Knowledge base %s of stack %s", opts.Name, s.Config.StackName)
+	}
+	kb := awsbedrock.NewCfnKnowledgeBase(s.Stack, id("KnowledgeBase"), &awsbedrock.CfnKnowledgeBaseProps{
+		Name:        jsii.String(fmt.Sprintf("%s-%s", s.Config.StackName, opts.Name)),
+		Description: jsii.String(description),
+		RoleArn:     role.RoleArn(),
+		KnowledgeBaseConfiguration: &awsbedrock.CfnKnowledgeBase_KnowledgeBaseConfigurationProperty{
+			Type: jsii.String("VECTOR"),
+			VectorKnowledgeBaseConfiguration: &awsbedrock.CfnKnowledgeBase_VectorKnowledgeBaseConfigurationProperty{
+				EmbeddingModelArn: s.Stack.FormatArn(&awscdk.ArnComponents{
+					Service:      jsii.String("bedrock"),
+					Account:      jsii.String(""),
+					Resource:     jsii.String("foundation-model"),
+					ResourceName: jsii.String(model),
+				}),
+				EmbeddingModelConfiguration: &awsbedrock.CfnKnowledgeBase_EmbeddingModelConfigurationProperty{
+					BedrockEmbeddingModelConfiguration: &awsbedrock.CfnKnowledgeBase_BedrockEmbeddingModelConfigurationProperty{
+						Dimensions: jsii.Number(float64(dimensions)),
+					},
+				},
+			},
+		},
+		StorageConfiguration: storage,
+	})
+	// Bedrock checks the role's access to the store and the model
+	kb.Node().AddDependency(role, dependency)
+	s.KnowledgeBases[opts.Name] = kb
+
+	awscdk.NewCfnOutput(s.Stack, id("Id"), &awscdk.CfnOutputProps{
+		Value:       kb.AttrKnowledgeBaseId(),
+		Description: jsii.String(fmt.Sprintf("ID of knowledge base %s", opts.Name)),
+	})
+
+	if opts.DataSourceBucketARN != "" {
+		s3Config := &awsbedrock.CfnDataSource_S3DataSourceConfigurationProperty{
+			BucketArn: jsii.String(opts.DataSourceBucketARN),
+		}
+		if len(opts.InclusionPrefixes) > 0 {
+			s3Config.InclusionPrefixes = jsii.Strings(opts.InclusionPrefixes...)
+		}
+		source := awsbedrock.NewCfnDataSource(s.Stack, id("DataSource"), &awsbedrock.CfnDataSourceProps{
+			KnowledgeBaseId: kb.AttrKnowledgeBaseId(),
+			Name:            jsii.String(fmt.Sprintf("%s-s3", opts.Name)),
+			DataSourceConfiguration: &awsbedrock.CfnDataSource_DataSourceConfigurationProperty{
+				Type:            jsii.String("S3"),
+				S3Configuration: s3Config,
+			},
+		})
+		awscdk.NewCfnOutput(s.Stack, id("DataSourceId"), &awscdk.CfnOutputProps{
+			Value:       source.AttrDataSourceId(),
+			Description: jsii.String(fmt.Sprintf("S3 data source of knowledge base %s; sync it with start-ingestion-job", opts.Name)),
+		})
+	}
+}
+
+// createVectorIndex creates the S3 vector bucket and index of a knowledge
+// base and lets its role use them.
+func (s *AgentCoreStack) createVectorIndex(opts *KnowledgeBaseOptions, role awsiam.Role, dimensions int) (*awsbedrock.CfnKnowledgeBase_StorageConfigurationProperty, awscdk.CfnResource) {
+	bucket := awss3vectors.NewCfnVectorBucket(s.Stack, jsii.String(fmt.Sprintf("KnowledgeBase-%s-VectorBucket", opts.Name)), &awss3vectors.CfnVectorBucketProps{})
+	index := awss3vectors.NewCfnIndex(s.Stack, jsii.String(fmt.Sprintf("KnowledgeBase-%s-VectorIndex", opts.Name)), &awss3vectors.CfnIndexProps{
+		VectorBucketArn: bucket.AttrVectorBucketArn(),
+		DataType:        jsii.String("float32"),
+		Dimension:       jsii.Number(float64(dimensions)),
+		DistanceMetric:  jsii.String("cosine"),
+		// Bedrock stores the chunks and their metadata with the vectors
+		MetadataConfiguration: &awss3vectors.CfnIndex_MetadataConfigurationProperty{
+			NonFilterableMetadataKeys: jsii.Strings("AMAZON_BEDROCK_TEXT", "AMAZON_BEDROCK_METADATA"),
+		},
+	})
+
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: jsii.Strings(
+			"s3vectors:GetIndex",
+			"s3vectors:QueryVectors",
+			"s3vectors:PutVectors",
+			"s3vectors:GetVectors",
+			"s3vectors:DeleteVectors",
+		),
+		Resources: &[]*string{index.AttrIndexArn()},
+	}))
+
+	return &awsbedrock.CfnKnowledgeBase_StorageConfigurationProperty{
+		Type: jsii.String("S3_VECTORS"),
+		S3VectorsConfiguration: &awsbedrock.CfnKnowledgeBase_S3VectorsConfigurationProperty{
+			VectorBucketArn: bucket.AttrVectorBucketArn(),
+			IndexArn:        index.AttrIndexArn(),
+		},
+	}, index
+}
+
+// createVectorCollection creates the OpenSearch Serverless collection of a
+// knowledge base with its security policies and vector index, and lets its
+// role use them.
+func (s *AgentCoreStack) createVectorCollection(opts *KnowledgeBaseOptions, role awsiam.Role, dimensions int) (*awsbedrock.CfnKnowledgeBase_StorageConfigurationProperty, awscdk.CfnResource) {
+	id := func(kind string) *string { return jsii.String(fmt.Sprintf("KnowledgeBase-%s-%s", opts.Name, kind)) }
+	name := opts.collectionName(s.Config.StackName)
+	collectionRule := map[string]interface{}{
+		"ResourceType": "collection",
+		"Resource":     []string{"collection/" + name},
+	}
+
+	encryption := awsopensearchserverless.NewCfnSecurityPolicy(s.Stack, id("EncryptionPolicy"), &awsopensearchserverless.CfnSecurityPolicyProps{
+		Name: jsii.String(name + "-enc"),
+		Type: jsii.String("encryption"),
+		Policy: s.Stack.ToJsonString(map[string]interface{}{
+			"Rules":       []interface{}{collectionRule},
+			"AWSOwnedKey": true,
+		}, nil),
+	})
+	// Bedrock and the CloudFormation deployment reach the collection
+	// endpoint; requests are still authorized by the data access policy
+	network := awsopensearchserverless.NewCfnSecurityPolicy(s.Stack, id("NetworkPolicy"), &awsopensearchserverless.CfnSecurityPolicyProps{
+		Name: jsii.String(name + "-net"),
+		Type: jsii.String("network"),
+		Policy: s.Stack.ToJsonString([]interface{}{map[string]interface{}{
+			"Rules":           []interface{}{collectionRule},
+			"AllowFromPublic": true,
+		}}, nil),
+	})
+
+	// The CloudFormation execution role creates the vector index
+	admins := []*string{role.RoleArn(), s.cloudFormationExecutionRoleARN()}
+	for _, arn := range opts.AdminPrincipalARNs {
+		admins = append(admins, jsii.String(arn))
+	}
+	indexRule := func(permissions ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"ResourceType": "index",
+			"Resource":     []string{fmt.Sprintf("index/%s/*", name)},
+			"Permission":   permissions,
+		}
+	}
+	access := awsopensearchserverless.NewCfnAccessPolicy(s.Stack, id("AccessPolicy"), &awsopensearchserverless.CfnAccessPolicyProps{
+		Name: jsii.String(name + "-acc"),
+		Type: jsii.String("data"),
+		Policy: s.Stack.ToJsonString([]interface{}{
+			map[string]interface{}{
+				"Rules": []interface{}{
+					map[string]interface{}{
+						"ResourceType": "collection",
+						"Resource":     []string{"collection/" + name},
+						"Permission":   []string{"aoss:DescribeCollectionItems", "aoss:CreateCollectionItems", "aoss:UpdateCollectionItems"},
+					},
+					indexRule("aoss:CreateIndex", "aoss:DescribeIndex", "aoss:UpdateIndex", "aoss:DeleteIndex", "aoss:ReadDocument", "aoss:WriteDocument"),
+				},
+				"Principal": admins,
+			},
+			// Agents may search the collection directly
+			map[string]interface{}{
+				"Rules":     []interface{}{indexRule("aoss:DescribeIndex", "aoss:ReadDocument")},
+				"Principal": []*string{s.ExecutionRole.RoleArn()},
+			},
+		}, nil),
+	})
+
+	standby := "DISABLED"
+	if opts.StandbyReplicas {
+		standby = "ENABLED"
+	}
+	collection := awsopensearchserverless.NewCfnCollection(s.Stack, id("Collection"), &awsopensearchserverless.CfnCollectionProps{
+		Name:            jsii.String(name),
+		Type:            jsii.String("VECTORSEARCH"),
+		StandbyReplicas: jsii.String(standby),
+		Description:     jsii.String(fmt.Sprintf("Vectors of knowledge base %s of stack %s", opts.Name, s.Config.StackName)),
+	})
+	collection.AddDependency(encryption)
+	collection.AddDependency(network)
+	s.VectorCollections[opts.Name] = collection
+
+	index := awsopensearchserverless.NewCfnIndex(s.Stack, id("VectorIndex"), &awsopensearchserverless.CfnIndexProps{
+		CollectionEndpoint: collection.AttrCollectionEndpoint(),
+		IndexName:          jsii.String(knowledgeBaseIndexName),
+		Settings: &awsopensearchserverless.CfnIndex_IndexSettingsProperty{
+			Index: &awsopensearchserverless.CfnIndex_IndexProperty{Knn: jsii.Bool(true)},
+		},
+		Mappings: &awsopensearchserverless.CfnIndex_MappingsProperty{
+			Properties: &map[string]interface{}{
+				knowledgeBaseVectorField: &awsopensearchserverless.CfnIndex_PropertyMappingProperty{
+					Type:      jsii.String("knn_vector"),
+					Dimension: jsii.Number(float64(dimensions)),
+					Method: &awsopensearchserverless.CfnIndex_MethodProperty{
+						Name:      jsii.String("hnsw"),
+						Engine:    jsii.String("faiss"),
+						SpaceType: jsii.String("l2"),
+					},
+				},
+				knowledgeBaseTextField: &awsopensearchserverless.CfnIndex_PropertyMappingProperty{Type: jsii.String("text")},
+				knowledgeBaseMetaField: &awsopensearchserverless.CfnIndex_PropertyMappingProperty{Type: jsii.String("text"), Index: jsii.Bool(false)},
+			},
+		},
+	})
+	index.AddDependency(access)
+
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("aoss:APIAccessAll"),
+		Resources: &[]*string{collection.AttrArn()},
+	}))
+
+	awscdk.NewCfnOutput(s.Stack, id("CollectionEndpoint"), &awscdk.CfnOutputProps{
+		Value:       collection.AttrCollectionEndpoint(),
+		Description: jsii.String(fmt.Sprintf("OpenSearch Serverless endpoint of knowledge base %s", opts.Name)),
+	})
+
+	return &awsbedrock.CfnKnowledgeBase_StorageConfigurationProperty{
+		Type: jsii.String("OPENSEARCH_SERVERLESS"),
+		OpensearchServerlessConfiguration: &awsbedrock.CfnKnowledgeBase_OpenSearchServerlessConfigurationProperty{
+			CollectionArn:   collection.AttrArn(),
+			VectorIndexName: jsii.String(knowledgeBaseIndexName),
+			FieldMapping: &awsbedrock.CfnKnowledgeBase_OpenSearchServerlessFieldMappingProperty{
+				VectorField:   jsii.String(knowledgeBaseVectorField),
+				TextField:     jsii.String(knowledgeBaseTextField),
+				MetadataField: jsii.String(knowledgeBaseMetaField),
+			},
+		},
+	}, index
+}
+
+// addKnowledgeBaseEnvironment passes the agent's knowledge bases to it and
+// lets it query them. An agent with a single knowledge base also gets its
+// ID in KNOWLEDGE_BASE_ID.
+func (s *AgentCoreStack) addKnowledgeBaseEnvironment(config *AgentConfig, envVars map[string]string) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || len(opts.KnowledgeBases) == 0 {
+		return
+	}
+
+	resources := make([]*string, 0, len(opts.KnowledgeBases))
+	for _, name := range opts.KnowledgeBases {
+		envVars[KnowledgeBaseEnvName(name)] = *s.knowledgeBaseID(name)
+		resources = append(resources, s.knowledgeBaseARN(name))
+
+		if collection, ok := s.VectorCollections[name]; ok {
+			envVars["KNOWLEDGE_BASE_"+agentEnvSegment(name)+"_ENDPOINT"] = *collection.AttrCollectionEndpoint()
+			s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+				Effect:    awsiam.Effect_ALLOW,
+				Actions:   jsii.Strings("aoss:APIAccessAll"),
+				Resources: &[]*string{collection.AttrArn()},
+			}))
+		}
+	}
+	if len(opts.KnowledgeBases) == 1 {
+		envVars["KNOWLEDGE_BASE_ID"] = *s.knowledgeBaseID(opts.KnowledgeBases[0])
+	}
+
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("bedrock:Retrieve", "bedrock:RetrieveAndGenerate"),
+		Resources: &resources,
+	}))
+}
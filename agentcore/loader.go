@@ -2,17 +2,21 @@
 package agentcore
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/plexusone/agentkit/platforms/agentcore/iac"
+	"gopkg.in/yaml.v3"
 )
 
 // Re-export config loading functions from agentkit for convenience.
 var (
-	// LoadStackConfigFromFile loads a StackConfig from a JSON or YAML file.
-	LoadStackConfigFromFile = iac.LoadStackConfigFromFile
-
 	// LoadStackConfigFromJSON parses a StackConfig from JSON data.
 	LoadStackConfigFromJSON = iac.LoadStackConfigFromJSON
 
@@ -38,20 +42,240 @@ var (
 	GenerateCloudFormationFromFile = iac.GenerateCloudFormationFromFile
 )
 
+// optionsDocument is the shape of a config file as seen by the options loader.
+// CDK-specific fields sit alongside the shared schema fields in the same file;
+// the shared loader ignores them and this one ignores everything else.
+type optionsDocument struct {
+	StackOptions `yaml:",inline"`
+
+	Agents []agentOptionsDocument `json:"agents" yaml:"agents"`
+}
+
+// agentOptionsDocument is a single agent entry as seen by the options loader.
+type agentOptionsDocument struct {
+	Name string `json:"name" yaml:"name"`
+
+	AgentOptions `yaml:",inline"`
+}
+
+// toStackOptions converts the parsed document into StackOptions.
+func (d *optionsDocument) toStackOptions() *StackOptions {
+	options := d.StackOptions
+	options.Agents = make(map[string]*AgentOptions, len(d.Agents))
+	for i := range d.Agents {
+		agentOpts := d.Agents[i].AgentOptions
+		options.Agents[d.Agents[i].Name] = &agentOpts
+	}
+	return &options
+}
+
+// LoadOption configures how a config file is loaded.
+type LoadOption func(*loadSettings)
+
+// loadSettings holds the settings applied by LoadOptions.
+type loadSettings struct {
+	environment string
+	awsConfig   *aws.Config
+	strict      bool
+}
+
+// readConfigFile reads a config file, merges the environment overlay selected
+// by opts over it, checks for unknown fields in strict mode, and resolves
+// ${...} placeholders. When either applies, the
+// result is re-encoded in the format of the file.
+func readConfigFile(path string, opts []LoadOption) ([]byte, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: config path is provided by the caller
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var settings loadSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	if settings.environment == "" && !settings.strict && !hasPlaceholders(data) {
+		return data, nil
+	}
+
+	// YAML is a superset of JSON, so both formats decode the same way
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+
+	if settings.environment != "" {
+		if doc, err = applyOverlay(path, settings.environment, doc); err != nil {
+			return nil, err
+		}
+	}
+	if settings.strict {
+		if err := checkUnknownFields(path, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	resolved, err := newPlaceholderResolver(settings.awsConfig).resolveValues(context.Background(), doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return json.Marshal(resolved)
+	}
+	return yaml.Marshal(resolved)
+}
+
+// checkFileProtocols checks the agent protocols of a config file before
+// the shared loader does, so a protocol in the wrong case gets the valid
+// spelling suggested. Parse errors are left to the loaders.
+func checkFileProtocols(data []byte) error {
+	// YAML is a superset of JSON, so both formats decode the same way
+	var doc struct {
+		Agents []AgentConfig `yaml:"agents"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	if err := validateProtocols(doc.Agents); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// LoadStackConfigFromFile loads a StackConfig from a JSON or YAML file. The
+// file format is auto-detected from the extension. Use WithEnvironment to
+// merge an environment overlay over the file, and Strict to reject unknown
+// fields.
+func LoadStackConfigFromFile(path string, opts ...LoadOption) (*iac.StackConfig, error) {
+	data, err := readConfigFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFileProtocols(data); err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".json":
+		return iac.LoadStackConfigFromJSON(data)
+	case ".yaml", ".yml":
+		return iac.LoadStackConfigFromYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s (use .json, .yaml, or .yml)", ext)
+	}
+}
+
+// LoadStackOptionsFromFile loads CDK-specific StackOptions from a JSON or YAML
+// config file. The file format is auto-detected from the extension. Use
+// WithEnvironment to merge an environment overlay over the file; the
+// environment also becomes the {env} of secrets.prefix unless
+// secrets.environment is set, and the deployment.environment of agent
+// traces unless observability.environment is set.
+func LoadStackOptionsFromFile(path string, opts ...LoadOption) (*StackOptions, error) {
+	data, err := readConfigFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFileProtocols(data); err != nil {
+		return nil, err
+	}
+
+	var options *StackOptions
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".json":
+		options, err = LoadStackOptionsFromJSON(data)
+	case ".yaml", ".yml":
+		options, err = LoadStackOptionsFromYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s (use .json, .yaml, or .yml)", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var settings loadSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	if settings.environment != "" && options.Secrets != nil && options.Secrets.Environment == "" {
+		options.Secrets.Environment = settings.environment
+	}
+	if settings.environment != "" {
+		if options.Observability == nil {
+			options.Observability = &ObservabilityOptions{}
+		}
+		if options.Observability.Environment == "" {
+			options.Observability.Environment = settings.environment
+		}
+	}
+	return options, nil
+}
+
+// LoadStackOptionsFromJSON parses CDK-specific StackOptions from JSON data.
+func LoadStackOptionsFromJSON(data []byte) (*StackOptions, error) {
+	var doc optionsDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON config: %w", err)
+	}
+	return doc.toStackOptions(), nil
+}
+
+// LoadStackOptionsFromYAML parses CDK-specific StackOptions from YAML data.
+func LoadStackOptionsFromYAML(data []byte) (*StackOptions, error) {
+	var doc optionsDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+	}
+	return doc.toStackOptions(), nil
+}
+
 // NewStackFromFile creates an AgentCoreStack from a JSON or YAML config file.
 // This is the simplest way to deploy - just provide a config file.
-func NewStackFromFile(scope constructs.Construct, configPath string) (*AgentCoreStack, error) {
-	config, err := iac.LoadStackConfigFromFile(configPath)
+//
+// Without WithEnvironment, the environment overlay is selected by the
+// agentkit:env CDK context value, if set. Setting the agentkit:strict
+// context value to true loads the file as with Strict.
+func NewStackFromFile(scope constructs.Construct, configPath string, opts ...LoadOption) (*AgentCoreStack, error) {
+	if environment := contextEnvironment(scope); environment != "" {
+		opts = append([]LoadOption{WithEnvironment(environment)}, opts...)
+	}
+	if contextStrict(scope) {
+		opts = append([]LoadOption{Strict()}, opts...)
+	}
+
+	config, err := LoadStackConfigFromFile(configPath, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewAgentCoreStack(scope, config.StackName, *config), nil
+	options, err := LoadStackOptionsFromFile(configPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAgentCoreStackWithOptions(scope, config.StackName, *config, *options), nil
+}
+
+// FindConfigFile returns the path of the config file in the current or
+// parent directory: config.json, config.yaml, or config.yml. It returns ""
+// if there is none.
+func FindConfigFile() string {
+	for _, path := range []string{"config.json", "config.yaml", "config.yml", "../config.json", "../config.yaml", "../config.yml"} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
 }
 
 // MustNewStackFromFile is like NewStackFromFile but panics on error.
-func MustNewStackFromFile(scope constructs.Construct, configPath string) *AgentCoreStack {
-	stack, err := NewStackFromFile(scope, configPath)
+func MustNewStackFromFile(scope constructs.Construct, configPath string, opts ...LoadOption) *AgentCoreStack {
+	stack, err := NewStackFromFile(scope, configPath, opts...)
 	if err != nil {
 		panic(fmt.Sprintf("failed to create stack from %s: %v", configPath, err))
 	}
@@ -65,7 +289,12 @@ func NewStackFromJSON(scope constructs.Construct, jsonData []byte) (*AgentCoreSt
 		return nil, err
 	}
 
-	return NewAgentCoreStack(scope, config.StackName, *config), nil
+	options, err := LoadStackOptionsFromJSON(jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAgentCoreStackWithOptions(scope, config.StackName, *config, *options), nil
 }
 
 // NewStackFromYAML creates an AgentCoreStack from YAML data.
@@ -75,5 +304,10 @@ func NewStackFromYAML(scope constructs.Construct, yamlData []byte) (*AgentCoreSt
 		return nil, err
 	}
 
-	return NewAgentCoreStack(scope, config.StackName, *config), nil
+	options, err := LoadStackOptionsFromYAML(yamlData)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAgentCoreStackWithOptions(scope, config.StackName, *config, *options), nil
 }
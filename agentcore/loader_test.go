@@ -0,0 +1,32 @@
+package agentcore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFromFileProtocols(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "stackName: s\nagents:\n  - name: a\n    containerImage: img\n    protocol: mcp\n",
+		"config.json": `{"stackName": "s", "agents": [{"name": "a", "containerImage": "img", "protocol": "a2a"}]}`,
+	}
+	want := map[string]string{
+		"config.yaml": `protocol "mcp" must be one of HTTP, MCP, A2A (did you mean "MCP"?)`,
+		"config.json": `protocol "a2a" must be one of HTTP, MCP, A2A (did you mean "A2A"?)`,
+	}
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadStackConfigFromFile(path); err == nil || !strings.Contains(err.Error(), want[name]) {
+			t.Errorf("LoadStackConfigFromFile(%s) error = %v, want %q", name, err, want[name])
+		}
+		if _, err := LoadStackOptionsFromFile(path); err == nil || !strings.Contains(err.Error(), want[name]) {
+			t.Errorf("LoadStackOptionsFromFile(%s) error = %v, want %q", name, err, want[name])
+		}
+	}
+}
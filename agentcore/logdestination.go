@@ -0,0 +1,278 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskinesisfirehose"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// logDestinationARNPattern matches the ARNs of the subscription filter
+// destinations: Firehose delivery streams, Kinesis data streams, and
+// CloudWatch Logs destinations of other accounts. The service is captured.
+var logDestinationARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:(firehose|kinesis|logs):[a-z0-9-]+:\d{12}:(deliverystream/|stream/|destination:).+$`)
+
+var (
+	// bucketARNPattern matches S3 bucket ARNs.
+	bucketARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:s3:::[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+	// openSearchDomainARNPattern matches OpenSearch Service domain ARNs.
+	openSearchDomainARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:es:[a-z0-9-]+:\d{12}:domain/[a-z][a-z0-9-]{2,27}$`)
+)
+
+// LogDestinationOptions ships the events of the stack's log group to a SIEM
+// or log archive through a subscription filter. The destination is either
+// an existing stream, or a Firehose delivery stream the stack creates.
+type LogDestinationOptions struct {
+	// DestinationARN is an existing Firehose delivery stream, Kinesis data
+	// stream, or CloudWatch Logs destination (for delivery to another
+	// account). Must be a literal ARN. The stack creates the role CloudWatch
+	// Logs writes to a stream with; a destination of another account
+	// authorizes this account in its access policy instead.
+	DestinationARN string `json:"destinationArn,omitempty" yaml:"destinationArn,omitempty"`
+
+	// Firehose creates a Firehose delivery stream to S3 or OpenSearch as
+	// the destination.
+	Firehose *LogFirehoseOptions `json:"firehose,omitempty" yaml:"firehose,omitempty"`
+
+	// FilterPattern selects the log events to ship, in CloudWatch Logs
+	// filter pattern syntax.
+	// Default: all events
+	FilterPattern string `json:"filterPattern,omitempty" yaml:"filterPattern,omitempty"`
+}
+
+// LogFirehoseOptions configures the Firehose delivery stream created for
+// the log destination. Records are decompressed and split into individual
+// log events before delivery.
+type LogFirehoseOptions struct {
+	// BucketARN is the S3 bucket the logs are delivered to, or that keeps
+	// the events OpenSearch rejected.
+	BucketARN string `json:"bucketArn" yaml:"bucketArn"`
+
+	// Prefix is the S3 key prefix of delivered objects.
+	// Default: "agentcore/{stackName}/"
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+
+	// OpenSearchDomainARN delivers the logs to an OpenSearch Service domain
+	// instead of S3. The domain's access policy must allow the delivery
+	// role, exported as LogDeliveryRoleArn.
+	OpenSearchDomainARN string `json:"openSearchDomainArn,omitempty" yaml:"openSearchDomainArn,omitempty"`
+
+	// IndexName is the OpenSearch index, rotated daily.
+	// Default: "agentcore-{stackName}"
+	IndexName string `json:"indexName,omitempty" yaml:"indexName,omitempty"`
+}
+
+// validate validates the log destination options.
+func (o *LogDestinationOptions) validate(obs *ObservabilityConfig) error {
+	if obs == nil || !obs.EnableCloudWatchLogs {
+		return fmt.Errorf("observability.logDestination requires observability.enableCloudWatchLogs: it ships the stack's log group")
+	}
+	switch {
+	case (o.DestinationARN == "") == (o.Firehose == nil):
+		return fmt.Errorf("observability.logDestination requires one of destinationArn and firehose")
+	case o.DestinationARN != "" && !logDestinationARNPattern.MatchString(o.DestinationARN):
+		return fmt.Errorf("observability.logDestination.destinationArn %q must be a literal Firehose delivery stream, Kinesis data stream, or CloudWatch Logs destination ARN", o.DestinationARN)
+	}
+
+	if f := o.Firehose; f != nil {
+		switch {
+		case f.BucketARN == "":
+			return fmt.Errorf("observability.logDestination.firehose.bucketArn is required")
+		case !*awscdk.Token_IsUnresolved(f.BucketARN) && !bucketARNPattern.MatchString(f.BucketARN):
+			return fmt.Errorf("observability.logDestination.firehose.bucketArn %q must be an S3 bucket ARN", f.BucketARN)
+		case f.OpenSearchDomainARN != "" && !*awscdk.Token_IsUnresolved(f.OpenSearchDomainARN) && !openSearchDomainARNPattern.MatchString(f.OpenSearchDomainARN):
+			return fmt.Errorf("observability.logDestination.firehose.openSearchDomainArn %q must be an OpenSearch Service domain ARN", f.OpenSearchDomainARN)
+		case f.IndexName != "" && f.OpenSearchDomainARN == "":
+			return fmt.Errorf("observability.logDestination.firehose.indexName requires openSearchDomainArn")
+		}
+	}
+	return nil
+}
+
+// logDestinationOptions returns the log destination options, or nil.
+func (s *AgentCoreStack) logDestinationOptions() *LogDestinationOptions {
+	if s.Options.Observability == nil {
+		return nil
+	}
+	return s.Options.Observability.LogDestination
+}
+
+// createLogSubscription creates the subscription filter of the stack's log
+// group, with the destination stream and roles it needs.
+func (s *AgentCoreStack) createLogSubscription() {
+	opts := s.logDestinationOptions()
+	if opts == nil || s.LogGroup == nil {
+		return
+	}
+
+	destinationARN := opts.DestinationARN
+	var putActions *[]*string
+	if opts.Firehose != nil {
+		destinationARN = *s.createLogDeliveryStream(opts.Firehose).AttrArn()
+		putActions = jsii.Strings("firehose:PutRecord", "firehose:PutRecordBatch")
+	} else {
+		switch logDestinationARNPattern.FindStringSubmatch(destinationARN)[1] {
+		case "firehose":
+			putActions = jsii.Strings("firehose:PutRecord", "firehose:PutRecordBatch")
+		case "kinesis":
+			putActions = jsii.Strings("kinesis:PutRecord", "kinesis:PutRecords")
+		}
+	}
+
+	props := &awslogs.CfnSubscriptionFilterProps{
+		LogGroupName:   s.LogGroup.LogGroupName(),
+		DestinationArn: jsii.String(destinationARN),
+		FilterPattern:  jsii.String(opts.FilterPattern),
+	}
+
+	// Streams of this account are written to with a role; destinations of
+	// other accounts authorize the account in their access policy
+	var role awsiam.Role
+	if putActions != nil {
+		role = awsiam.NewRole(s.Stack, jsii.String("LogSubscriptionRole"), &awsiam.RoleProps{
+			Description: jsii.String(fmt.Sprintf("Lets CloudWatch Logs ship the logs of stack %s", s.Config.StackName)),
+			AssumedBy: awsiam.NewServicePrincipal(jsii.String("logs.amazonaws.com"), &awsiam.ServicePrincipalOpts{
+				Conditions: &map[string]interface{}{
+					"StringLike": map[string]interface{}{
+						"aws:SourceArn": s.Stack.FormatArn(&awscdk.ArnComponents{
+							Service:      jsii.String("logs"),
+							Resource:     jsii.String("log-group"),
+							ResourceName: jsii.String("*"),
+							ArnFormat:    awscdk.ArnFormat_COLON_RESOURCE_NAME,
+						}),
+					},
+				},
+			}),
+		})
+		role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect:    awsiam.Effect_ALLOW,
+			Actions:   putActions,
+			Resources: jsii.Strings(destinationARN),
+		}))
+		props.RoleArn = role.RoleArn()
+	}
+
+	filter := awslogs.NewCfnSubscriptionFilter(s.Stack, jsii.String("LogSubscriptionFilter"), props)
+	if role != nil {
+		// The role's policy must exist before CloudWatch Logs tests delivery
+		filter.Node().AddDependency(role)
+	}
+}
+
+// createLogDeliveryStream creates the Firehose delivery stream of the log
+// destination and its delivery role.
+func (s *AgentCoreStack) createLogDeliveryStream(opts *LogFirehoseOptions) awskinesisfirehose.CfnDeliveryStream {
+	role := awsiam.NewRole(s.Stack, jsii.String("LogDeliveryRole"), &awsiam.RoleProps{
+		Description: jsii.String(fmt.Sprintf("Lets Firehose deliver the logs of stack %s", s.Config.StackName)),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("firehose.amazonaws.com"), &awsiam.ServicePrincipalOpts{
+			Conditions: &map[string]interface{}{
+				"StringEquals": map[string]interface{}{"aws:SourceAccount": s.Stack.Account()},
+			},
+		}),
+	})
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: jsii.Strings(
+			"s3:AbortMultipartUpload",
+			"s3:GetBucketLocation",
+			"s3:GetObject",
+			"s3:ListBucket",
+			"s3:ListBucketMultipartUploads",
+			"s3:PutObject",
+		),
+		Resources: jsii.Strings(opts.BucketARN, opts.BucketARN+"/*"),
+	}))
+
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = fmt.Sprintf("agentcore/%s/", s.Config.StackName)
+	}
+	s3Config := &awskinesisfirehose.CfnDeliveryStream_S3DestinationConfigurationProperty{
+		BucketArn:         jsii.String(opts.BucketARN),
+		RoleArn:           role.RoleArn(),
+		Prefix:            jsii.String(prefix),
+		ErrorOutputPrefix: jsii.String(prefix + "errors/"),
+		CompressionFormat: jsii.String("GZIP"),
+	}
+
+	// CloudWatch Logs sends gzipped batches of events; deliver the events
+	processing := &awskinesisfirehose.CfnDeliveryStream_ProcessingConfigurationProperty{
+		Enabled: jsii.Bool(true),
+		Processors: &[]interface{}{
+			&awskinesisfirehose.CfnDeliveryStream_ProcessorProperty{
+				Type: jsii.String("Decompression"),
+				Parameters: &[]interface{}{&awskinesisfirehose.CfnDeliveryStream_ProcessorParameterProperty{
+					ParameterName: jsii.String("CompressionFormat"), ParameterValue: jsii.String("GZIP"),
+				}},
+			},
+			&awskinesisfirehose.CfnDeliveryStream_ProcessorProperty{
+				Type: jsii.String("CloudWatchLogProcessing"),
+				Parameters: &[]interface{}{&awskinesisfirehose.CfnDeliveryStream_ProcessorParameterProperty{
+					ParameterName: jsii.String("DataMessageExtraction"), ParameterValue: jsii.String("true"),
+				}},
+			},
+		},
+	}
+
+	props := &awskinesisfirehose.CfnDeliveryStreamProps{
+		DeliveryStreamType: jsii.String("DirectPut"),
+		DeliveryStreamEncryptionConfigurationInput: &awskinesisfirehose.CfnDeliveryStream_DeliveryStreamEncryptionConfigurationInputProperty{
+			KeyType: jsii.String("AWS_OWNED_CMK"),
+		},
+	}
+	if opts.OpenSearchDomainARN != "" {
+		role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect: awsiam.Effect_ALLOW,
+			Actions: jsii.Strings(
+				"es:DescribeDomain",
+				"es:DescribeDomains",
+				"es:DescribeDomainConfig",
+				"es:ESHttpGet",
+				"es:ESHttpPost",
+				"es:ESHttpPut",
+			),
+			Resources: jsii.Strings(opts.OpenSearchDomainARN, opts.OpenSearchDomainARN+"/*"),
+		}))
+		index := opts.IndexName
+		if index == "" {
+			index = fmt.Sprintf("agentcore-%s", s.Config.StackName)
+		}
+		props.AmazonopensearchserviceDestinationConfiguration = &awskinesisfirehose.CfnDeliveryStream_AmazonopensearchserviceDestinationConfigurationProperty{
+			DomainArn:               jsii.String(opts.OpenSearchDomainARN),
+			IndexName:               jsii.String(index),
+			IndexRotationPeriod:     jsii.String("OneDay"),
+			RoleArn:                 role.RoleArn(),
+			S3BackupMode:            jsii.String("FailedDocumentsOnly"),
+			S3Configuration:         s3Config,
+			ProcessingConfiguration: processing,
+		}
+	} else {
+		props.ExtendedS3DestinationConfiguration = &awskinesisfirehose.CfnDeliveryStream_ExtendedS3DestinationConfigurationProperty{
+			BucketArn:               s3Config.BucketArn,
+			RoleArn:                 s3Config.RoleArn,
+			Prefix:                  s3Config.Prefix,
+			ErrorOutputPrefix:       s3Config.ErrorOutputPrefix,
+			CompressionFormat:       s3Config.CompressionFormat,
+			ProcessingConfiguration: processing,
+		}
+	}
+
+	stream := awskinesisfirehose.NewCfnDeliveryStream(s.Stack, jsii.String("LogDeliveryStream"), props)
+	// The role's policy must exist before Firehose validates the destination
+	stream.Node().AddDependency(role)
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("LogDeliveryStreamArn"), &awscdk.CfnOutputProps{
+		Value:       stream.AttrArn(),
+		Description: jsii.String("Firehose delivery stream of the stack's logs"),
+	})
+	awscdk.NewCfnOutput(s.Stack, jsii.String("LogDeliveryRoleArn"), &awscdk.CfnOutputProps{
+		Value:       role.RoleArn(),
+		Description: jsii.String("Role Firehose delivers the stack's logs with"),
+	})
+	return stream
+}
@@ -0,0 +1,218 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// maxLogMetricDimensions is the number of dimensions a metric filter may
+// publish.
+const maxLogMetricDimensions = 3
+
+var (
+	// logMetricNamePattern is the naming rule for log metrics, which name
+	// their metric filter.
+	logMetricNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,255}$`)
+
+	// logFieldPattern matches a field selector of a JSON or space-delimited
+	// filter pattern, e.g. "$.usage.totalTokens" or "$status".
+	logFieldPattern = regexp.MustCompile(`^\$[a-zA-Z0-9_.\[\]]+$`)
+)
+
+// logMetricUnits maps CloudWatch unit names to metric filter units.
+var logMetricUnits = map[string]awscloudwatch.Unit{
+	"Count":        awscloudwatch.Unit_COUNT,
+	"Percent":      awscloudwatch.Unit_PERCENT,
+	"Seconds":      awscloudwatch.Unit_SECONDS,
+	"Milliseconds": awscloudwatch.Unit_MILLISECONDS,
+	"Microseconds": awscloudwatch.Unit_MICROSECONDS,
+	"Bytes":        awscloudwatch.Unit_BYTES,
+	"Kilobytes":    awscloudwatch.Unit_KILOBYTES,
+	"Megabytes":    awscloudwatch.Unit_MEGABYTES,
+	"Count/Second": awscloudwatch.Unit_COUNT_PER_SECOND,
+	"None":         awscloudwatch.Unit_NONE,
+}
+
+// LogMetricOptions derives a CloudWatch metric from the agent log group
+// with a metric filter, e.g. a count of lines containing "ERROR", or the
+// token usage field of structured JSON logs. The metrics are graphed on the
+// dashboard and available for alarms in AgentCoreStack.LogMetrics.
+type LogMetricOptions struct {
+	// Name is the metric name.
+	Name string `json:"name" yaml:"name"`
+
+	// Pattern selects the log events counted, in CloudWatch Logs filter
+	// pattern syntax, e.g. "ERROR" or '{ $.level = "error" }'.
+	// Default: all events
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	// Namespace is the metric namespace.
+	// Default: "AgentKit/{stackName}"
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+
+	// Value is published for each matching event: a number, or a field of
+	// a JSON or space-delimited pattern, e.g. "$.usage.totalTokens".
+	// Default: "1"
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+
+	// Unit is the CloudWatch unit of the metric, e.g. "Count" or
+	// "Milliseconds".
+	// Default: none
+	Unit string `json:"unit,omitempty" yaml:"unit,omitempty"`
+
+	// Dimensions publishes the metric per value of up to three fields,
+	// e.g. {"Agent": "$.agent"}. A metric with dimensions has no default
+	// value.
+	Dimensions map[string]string `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
+
+	// Statistic is the statistic graphed on the dashboard, e.g. "Sum",
+	// "Average", or "p90".
+	// Default: "Sum"
+	Statistic string `json:"statistic,omitempty" yaml:"statistic,omitempty"`
+}
+
+// validateLogMetrics validates the log metrics.
+func validateLogMetrics(metrics []LogMetricOptions, obs *ObservabilityConfig) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	if obs == nil || !obs.EnableCloudWatchLogs {
+		return fmt.Errorf("observability.logMetrics requires observability.enableCloudWatchLogs: metrics are filtered from the stack's log group")
+	}
+
+	names := make(map[string]bool)
+	for i, metric := range metrics {
+		prefix := fmt.Sprintf("observability.logMetrics[%d]", i)
+		switch {
+		case !logMetricNamePattern.MatchString(metric.Name):
+			return fmt.Errorf("%s.name %q must be 1-255 letters, digits, '_', '.', or '-'", prefix, metric.Name)
+		case names[metric.Name]:
+			return fmt.Errorf("%s.name %q is not unique", prefix, metric.Name)
+		case metric.Value != "" && !logFieldPattern.MatchString(metric.Value) && !isNumber(metric.Value):
+			return fmt.Errorf("%s.value %q must be a number or a field selector such as $.latencyMs", prefix, metric.Value)
+		case metric.Unit != "" && logMetricUnits[metric.Unit] == "":
+			return fmt.Errorf("%s.unit %q must be one of %s", prefix, metric.Unit, strings.Join(logMetricUnitNames(), ", "))
+		case len(metric.Dimensions) > maxLogMetricDimensions:
+			return fmt.Errorf("%s.dimensions has %d entries; metric filters publish at most %d", prefix, len(metric.Dimensions), maxLogMetricDimensions)
+		}
+		for key, field := range metric.Dimensions {
+			if !logFieldPattern.MatchString(field) {
+				return fmt.Errorf("%s.dimensions.%s %q must be a field selector such as $.agent", prefix, key, field)
+			}
+		}
+		names[metric.Name] = true
+	}
+	return nil
+}
+
+// isNumber reports whether s is a decimal number.
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// logMetricUnitNames returns the supported unit names, sorted.
+func logMetricUnitNames() []string {
+	names := make([]string, 0, len(logMetricUnits))
+	for name := range logMetricUnits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// createLogMetrics creates the metric filters of the log metrics.
+func (s *AgentCoreStack) createLogMetrics() {
+	if s.Options.Observability == nil || len(s.Options.Observability.LogMetrics) == 0 || s.LogGroup == nil {
+		return
+	}
+
+	s.LogMetrics = make(map[string]awscloudwatch.Metric)
+	for _, metric := range s.Options.Observability.LogMetrics {
+		namespace := metric.Namespace
+		if namespace == "" {
+			namespace = fmt.Sprintf("AgentKit/%s", s.Config.StackName)
+		}
+		value := metric.Value
+		if value == "" {
+			value = "1"
+		}
+		pattern := awslogs.FilterPattern_AllEvents()
+		if metric.Pattern != "" {
+			pattern = awslogs.FilterPattern_Literal(jsii.String(metric.Pattern))
+		}
+
+		props := &awslogs.MetricFilterProps{
+			LogGroup:        s.LogGroup,
+			FilterPattern:   pattern,
+			MetricNamespace: jsii.String(namespace),
+			MetricName:      jsii.String(metric.Name),
+			MetricValue:     jsii.String(value),
+		}
+		if metric.Unit != "" {
+			props.Unit = logMetricUnits[metric.Unit]
+		}
+		if len(metric.Dimensions) > 0 {
+			dimensions := make(map[string]*string, len(metric.Dimensions))
+			for key, field := range metric.Dimensions {
+				dimensions[key] = jsii.String(field)
+			}
+			props.Dimensions = &dimensions
+		} else {
+			// Periods without matching events read as 0 instead of missing
+			props.DefaultValue = jsii.Number(0)
+		}
+
+		statistic := metric.Statistic
+		if statistic == "" {
+			statistic = "Sum"
+		}
+		filter := awslogs.NewMetricFilter(s.Stack, jsii.String(fmt.Sprintf("LogMetric-%s", metric.Name)), props)
+		s.LogMetrics[metric.Name] = filter.Metric(&awscloudwatch.MetricOptions{
+			Statistic: jsii.String(statistic),
+		})
+	}
+}
+
+// logMetricWidget returns the dashboard graph of the log metrics, or nil.
+// Metrics with dimensions are graphed across all their dimension values.
+func (s *AgentCoreStack) logMetricWidget(period awscdk.Duration) awscloudwatch.IWidget {
+	if len(s.LogMetrics) == 0 {
+		return nil
+	}
+
+	metrics := make([]awscloudwatch.IMetric, 0, len(s.LogMetrics))
+	for _, opts := range s.Options.Observability.LogMetrics {
+		metric := s.LogMetrics[opts.Name]
+		if len(opts.Dimensions) > 0 {
+			keys := make([]string, 0, len(opts.Dimensions))
+			for key := range opts.Dimensions {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			metrics = append(metrics, awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+				Expression: jsii.String(fmt.Sprintf(`SEARCH('{%s,%s} MetricName="%s"', '%s', %d)`,
+					*metric.Namespace(), strings.Join(keys, ","), opts.Name, *metric.Statistic(), int(*period.ToSeconds(nil)))),
+				Label:  jsii.String(opts.Name),
+				Period: period,
+			}))
+			continue
+		}
+		metrics = append(metrics, metric.With(&awscloudwatch.MetricOptions{Period: period}))
+	}
+
+	return awscloudwatch.NewGraphWidget(&awscloudwatch.GraphWidgetProps{
+		Title:  jsii.String("Log metrics"),
+		Left:   &metrics,
+		Width:  jsii.Number(24),
+		Period: period,
+	})
+}
@@ -0,0 +1,101 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// DefaultMemoryEventExpiryDays is the default retention for memory events.
+const DefaultMemoryEventExpiryDays = 30
+
+// memoryNamePattern is the naming rule for AgentCore memory resources.
+var memoryNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,47}$`)
+
+// resolveMemoryStore returns the effective memory store settings for an agent,
+// or nil if the agent has no memory store. An agent gets a memory store when
+// its options declare one or when AgentConfig.EnableMemory is set.
+func resolveMemoryStore(config AgentConfig, opts *AgentOptions) *MemoryStoreConfig {
+	var memory MemoryStoreConfig
+	switch {
+	case opts != nil && opts.MemoryStore != nil:
+		memory = *opts.MemoryStore
+	case config.EnableMemory:
+		// Defaults only
+	default:
+		return nil
+	}
+
+	if memory.Name == "" {
+		memory.Name = strings.ReplaceAll(config.Name, "-", "_") + "_memory"
+	}
+	if memory.EventExpiryDays == 0 {
+		memory.EventExpiryDays = DefaultMemoryEventExpiryDays
+	}
+	return &memory
+}
+
+// validate validates the memory store settings.
+func (c *MemoryStoreConfig) validate() error {
+	if !memoryNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("memoryStore.name %q must match %s", c.Name, memoryNamePattern)
+	}
+	if c.EventExpiryDays < 7 || c.EventExpiryDays > 365 {
+		return fmt.Errorf("memoryStore.eventExpiryDays must be between 7 and 365")
+	}
+	return nil
+}
+
+// createMemoryStore creates the AWS::BedrockAgentCore::Memory resource for an
+// agent, grants the execution role access, and exposes the memory ID to the
+// agent via the AGENTCORE_MEMORY_ID environment variable.
+func (s *AgentCoreStack) createMemoryStore(config *AgentConfig, envVars map[string]string) {
+	memoryConfig := resolveMemoryStore(*config, s.Options.Agents[config.Name])
+	if memoryConfig == nil {
+		return
+	}
+
+	memory := awsbedrockagentcore.NewCfnMemory(s.agentScope(config.Name),
+		jsii.String(fmt.Sprintf("Memory-%s", config.Name)),
+		&awsbedrockagentcore.CfnMemoryProps{
+			Name:                jsii.String(memoryConfig.Name),
+			Description:         jsii.String(fmt.Sprintf("Memory for agent %s", config.Name)),
+			EventExpiryDuration: jsii.Number(float64(memoryConfig.EventExpiryDays)),
+			EncryptionKeyArn:    s.kmsKeyARN(),
+			Tags:                s.getTags(config),
+		},
+	)
+
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: jsii.Strings(
+			"bedrock-agentcore:GetMemory",
+			"bedrock-agentcore:CreateEvent",
+			"bedrock-agentcore:GetEvent",
+			"bedrock-agentcore:ListEvents",
+			"bedrock-agentcore:DeleteEvent",
+			"bedrock-agentcore:ListSessions",
+			"bedrock-agentcore:ListActors",
+			"bedrock-agentcore:GetMemoryRecord",
+			"bedrock-agentcore:ListMemoryRecords",
+			"bedrock-agentcore:RetrieveMemoryRecords",
+		),
+		Resources: &[]*string{memory.AttrMemoryArn()},
+	}))
+
+	envVars["AGENTCORE_MEMORY_ID"] = *memory.AttrMemoryId()
+
+	awscdk.NewCfnOutput(s.Stack,
+		jsii.String(fmt.Sprintf("Agent-%s-MemoryId", config.Name)),
+		&awscdk.CfnOutputProps{
+			Value:       memory.AttrMemoryId(),
+			Description: jsii.String(fmt.Sprintf("Memory ID for agent %s", config.Name)),
+		})
+
+	s.Memories[config.Name] = memory
+}
@@ -0,0 +1,145 @@
+package agentcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+// Model access statuses of ModelAccessResult.
+const (
+	// ModelAccessGranted means the account can invoke the model.
+	ModelAccessGranted = "granted"
+
+	// ModelAccessMissing means the model must be enabled in the Bedrock
+	// console before the agents can invoke it.
+	ModelAccessMissing = "missing"
+
+	// ModelAccessUnchecked means the access could not be checked, e.g.
+	// for wildcard model IDs or without bedrock:GetFoundationModelAvailability.
+	ModelAccessUnchecked = "unchecked"
+)
+
+// inferenceProfilePrefixes are the geography prefixes of cross-region
+// inference profile IDs such as "us.anthropic.claude-sonnet-4-20250514-v1:0".
+var inferenceProfilePrefixes = []string{"us.", "eu.", "apac.", "us-gov.", "jp.", "au.", "global."}
+
+// ModelAccessResult is the model access of a Bedrock model ID of
+// IAMConfig.BedrockModelIDs.
+type ModelAccessResult struct {
+	// ModelID is the model ID as configured.
+	ModelID string `json:"modelId"`
+
+	// FoundationModelID is the foundation model checked: ModelID without
+	// the geography prefix of a cross-region inference profile.
+	FoundationModelID string `json:"foundationModelId"`
+
+	// Status is ModelAccessGranted, ModelAccessMissing, or
+	// ModelAccessUnchecked.
+	Status string `json:"status"`
+
+	// Reason explains a missing or unchecked model, e.g. "access not
+	// granted" or "not offered in the region".
+	Reason string `json:"reason,omitempty"`
+}
+
+// CheckModelAccess checks the account has been granted access to Bedrock
+// models in the region of cfg, one result per model ID in order. Wildcard
+// model IDs are not checked. Errors of individual models are reported as
+// ModelAccessUnchecked results; an error is returned only if the AWS
+// credentials can't be retrieved.
+func CheckModelAccess(ctx context.Context, cfg aws.Config, modelIDs []string) ([]ModelAccessResult, error) {
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("retrieving AWS credentials: %w", err)
+	}
+
+	results := make([]ModelAccessResult, 0, len(modelIDs))
+	for _, modelID := range modelIDs {
+		result := ModelAccessResult{ModelID: modelID, FoundationModelID: FoundationModelID(modelID)}
+		if strings.Contains(modelID, "*") {
+			result.Status, result.Reason = ModelAccessUnchecked, "wildcard model ID"
+			results = append(results, result)
+			continue
+		}
+
+		reason, err := modelAvailability(ctx, cfg, result.FoundationModelID)
+		switch {
+		case err != nil:
+			result.Status, result.Reason = ModelAccessUnchecked, err.Error()
+		case reason != "":
+			result.Status, result.Reason = ModelAccessMissing, reason
+		default:
+			result.Status = ModelAccessGranted
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// FoundationModelID returns the foundation model of a model ID, stripping
+// the geography prefix of a cross-region inference profile.
+func FoundationModelID(modelID string) string {
+	for _, prefix := range inferenceProfilePrefixes {
+		if strings.HasPrefix(modelID, prefix) {
+			return strings.TrimPrefix(modelID, prefix)
+		}
+	}
+	return modelID
+}
+
+// ModelAccessConsoleURL returns the Model access page of the Bedrock
+// console in a region, where missing models are enabled.
+func ModelAccessConsoleURL(region string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/bedrock/home?region=%s#/modelaccess", region, region)
+}
+
+// modelAvailability returns why the account can't invoke a foundation model
+// in the region, or "" if it can, with the Bedrock
+// GetFoundationModelAvailability API.
+func modelAvailability(ctx context.Context, cfg aws.Config, modelID string) (string, error) {
+	data, err := awsapi.Do(ctx, cfg, awsapi.Call{
+		SigningName: "bedrock",
+		Host:        awsapi.Host(cfg.Region, "bedrock"),
+		Method:      http.MethodGet,
+		Path:        "/foundation-model-availability/" + url.PathEscape(modelID),
+	})
+	var apiErr *awsapi.Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return "no such model in the region", nil
+	case err != nil:
+		return "", err
+	}
+
+	var availability struct {
+		AgreementAvailability struct {
+			Status string `json:"status"`
+		} `json:"agreementAvailability"`
+		AuthorizationStatus     string `json:"authorizationStatus"`
+		EntitlementAvailability string `json:"entitlementAvailability"`
+		RegionAvailability      string `json:"regionAvailability"`
+	}
+	if err := json.Unmarshal(data, &availability); err != nil {
+		return "", fmt.Errorf("decoding model availability: %w", err)
+	}
+
+	switch {
+	case availability.RegionAvailability != "" && availability.RegionAvailability != "AVAILABLE":
+		return "not offered in the region", nil
+	case availability.AuthorizationStatus != "" && availability.AuthorizationStatus != "AUTHORIZED":
+		return "not authorized", nil
+	case availability.EntitlementAvailability != "" && availability.EntitlementAvailability != "AVAILABLE":
+		return "access not granted", nil
+	case availability.AgreementAvailability.Status != "" && availability.AgreementAvailability.Status != "AVAILABLE":
+		return "agreement " + strings.ToLower(availability.AgreementAvailability.Status), nil
+	}
+	return "", nil
+}
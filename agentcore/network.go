@@ -0,0 +1,84 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Runtime network modes.
+const (
+	NetworkModeVPC    = "VPC"
+	NetworkModePublic = "PUBLIC"
+)
+
+// ValidNetworkModes returns the list of valid runtime network modes.
+func ValidNetworkModes() []string {
+	return []string{NetworkModeVPC, NetworkModePublic}
+}
+
+// validateNetworkOptions validates an agent's network settings.
+func validateNetworkOptions(opts *AgentOptions) error {
+	if opts == nil {
+		return nil
+	}
+	switch opts.NetworkMode {
+	case "", NetworkModeVPC:
+		return nil
+	case NetworkModePublic:
+		if len(opts.SubnetIDs) > 0 || len(opts.SecurityGroupIDs) > 0 {
+			return fmt.Errorf("subnetIds and securityGroupIds require networkMode %s", NetworkModeVPC)
+		}
+		return nil
+	default:
+		return fmt.Errorf("networkMode must be one of %v", ValidNetworkModes())
+	}
+}
+
+// getNetworkMode returns the runtime network mode for an agent.
+func (s *AgentCoreStack) getNetworkMode(config *AgentConfig) string {
+	if opts := s.Options.Agents[config.Name]; opts != nil && opts.NetworkMode != "" {
+		return opts.NetworkMode
+	}
+	return NetworkModeVPC
+}
+
+// needsVPC reports whether any agent attaches to the VPC. When every agent
+// runs in PUBLIC mode the stack skips the VPC (and its NAT gateway) entirely.
+func (s *AgentCoreStack) needsVPC() bool {
+	for i := range s.Config.Agents {
+		if s.getNetworkMode(&s.Config.Agents[i]) == NetworkModeVPC {
+			return true
+		}
+	}
+	return false
+}
+
+// getNetworkConfiguration builds the runtime network configuration for an agent.
+func (s *AgentCoreStack) getNetworkConfiguration(config *AgentConfig) *awsbedrockagentcore.CfnRuntime_NetworkConfigurationProperty {
+	if s.getNetworkMode(config) == NetworkModePublic {
+		return &awsbedrockagentcore.CfnRuntime_NetworkConfigurationProperty{
+			NetworkMode: jsii.String(NetworkModePublic),
+		}
+	}
+
+	subnets := s.getPrivateSubnetIds()
+	securityGroups := s.getSecurityGroupIds()
+	if opts := s.Options.Agents[config.Name]; opts != nil {
+		if len(opts.SubnetIDs) > 0 {
+			subnets = jsii.Strings(opts.SubnetIDs...)
+		}
+		if len(opts.SecurityGroupIDs) > 0 {
+			securityGroups = jsii.Strings(opts.SecurityGroupIDs...)
+		}
+	}
+
+	return &awsbedrockagentcore.CfnRuntime_NetworkConfigurationProperty{
+		NetworkMode: jsii.String(NetworkModeVPC),
+		NetworkModeConfig: &awsbedrockagentcore.CfnRuntime_VpcConfigProperty{
+			SecurityGroups: securityGroups,
+			Subnets:        subnets,
+		},
+	}
+}
@@ -0,0 +1,213 @@
+package agentcore
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Default provider URLs, used when observability.endpoint is empty.
+const (
+	defaultOpikURL     = "https://www.comet.com/opik/api"
+	defaultLangfuseURL = "https://cloud.langfuse.com"
+)
+
+// secretARNPattern matches Secrets Manager secret ARNs, complete or partial.
+var secretARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:secretsmanager:[a-z0-9-]+:\d+:secret:.+$`)
+
+// validateObservability applies the provider rules the shared config does
+// not check: Phoenix needs a collector endpoint, and API key secrets must
+// be Secrets Manager ARNs.
+func validateObservability(obs *ObservabilityConfig) error {
+	if obs == nil {
+		return nil
+	}
+	if obs.Provider == "phoenix" && obs.Endpoint == "" {
+		return fmt.Errorf("observability.endpoint is required for the phoenix provider (e.g. https://app.phoenix.arize.com)")
+	}
+	if err := validateOTLPEndpoint("observability.endpoint", obs.Endpoint); err != nil {
+		return err
+	}
+	if obs.APIKeySecretARN != "" && !*awscdk.Token_IsUnresolved(obs.APIKeySecretARN) && !secretARNPattern.MatchString(obs.APIKeySecretARN) {
+		return fmt.Errorf("observability.apiKeySecretARN %q must be a Secrets Manager secret ARN", obs.APIKeySecretARN)
+	}
+	return nil
+}
+
+// addProviderEnvironment adds the settings the provider's SDK reads, and the
+// ARN of the API key secret as OBSERVABILITY_API_KEY_SECRET_ARN. Variables
+// set in the agent's environment take precedence.
+func (s *AgentCoreStack) addProviderEnvironment(envVars map[string]string) {
+	obs := s.Config.Observability
+	if obs == nil {
+		return
+	}
+
+	provider := make(map[string]string)
+	switch obs.Provider {
+	case "opik":
+		provider["OPIK_PROJECT_NAME"] = obs.Project
+		provider["OPIK_URL_OVERRIDE"] = obs.Endpoint
+		if s.Options.Observability != nil {
+			provider["OPIK_WORKSPACE"] = s.Options.Observability.OpikWorkspace
+		}
+	case "langfuse":
+		provider["LANGFUSE_HOST"] = obs.Endpoint
+	case "phoenix":
+		provider["PHOENIX_PROJECT_NAME"] = obs.Project
+		provider["PHOENIX_COLLECTOR_ENDPOINT"] = obs.Endpoint
+	}
+	provider["OBSERVABILITY_API_KEY_SECRET_ARN"] = obs.APIKeySecretARN
+
+	for k, v := range provider {
+		if _, ok := envVars[k]; !ok && v != "" {
+			envVars[k] = v
+		}
+	}
+}
+
+// validateOTEL checks the OpenTelemetry settings against the observability
+// configuration.
+func (o *ObservabilityOptions) validateOTEL(obs *ObservabilityConfig) error {
+	if o.Environment != "" && !ssmPathSegmentPattern.MatchString(o.Environment) {
+		return fmt.Errorf("observability.environment %q must contain only letters, digits, '_', '.', and '-'", o.Environment)
+	}
+	if o.APIKeyJSONKey != "" && (obs == nil || obs.APIKeySecretARN == "") {
+		return fmt.Errorf("observability.apiKeyJsonKey requires observability.apiKeySecretARN")
+	}
+	if o.OpikWorkspace != "" && (obs == nil || obs.Provider != "opik") {
+		return fmt.Errorf("observability.opikWorkspace requires the opik provider")
+	}
+	if strings.ContainsAny(o.OpikWorkspace, ",= ") {
+		return fmt.Errorf("observability.opikWorkspace %q must not contain ',', '=', or spaces", o.OpikWorkspace)
+	}
+	return nil
+}
+
+// otelResourceAttributes returns the OTEL_RESOURCE_ATTRIBUTES of an agent:
+// the stack as service.namespace, the agent, and the environment.
+func (s *AgentCoreStack) otelResourceAttributes(config *AgentConfig) string {
+	attrs := []string{
+		"service.namespace=" + s.Config.StackName,
+		"agentcore.agent.name=" + config.Name,
+	}
+	if s.Options.Observability != nil && s.Options.Observability.Environment != "" {
+		attrs = append(attrs, "deployment.environment="+s.Options.Observability.Environment)
+	}
+	return strings.Join(attrs, ",")
+}
+
+// addOTELEnvironment adds the standard OpenTelemetry SDK settings:
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES, and unless X-Ray tracing
+// is enabled, an OTLP exporter for the provider with the API key secret in
+// OTEL_EXPORTER_OTLP_HEADERS. Variables set in the agent's environment take
+// precedence; an agent setting its own OTLP endpoint gets no headers.
+func (s *AgentCoreStack) addOTELEnvironment(config *AgentConfig, envVars map[string]string) {
+	obs := s.Config.Observability
+	if obs == nil {
+		return
+	}
+
+	otel := map[string]string{
+		"OTEL_SERVICE_NAME":        config.Name,
+		"OTEL_RESOURCE_ATTRIBUTES": s.otelResourceAttributes(config),
+	}
+	if endpoint := s.otlpEndpoint(); endpoint != "" && s.xrayOptions() == nil {
+		otel["OTEL_TRACES_EXPORTER"] = "otlp"
+		otel["OTEL_EXPORTER_OTLP_PROTOCOL"] = "http/protobuf"
+		if _, ok := envVars["OTEL_EXPORTER_OTLP_ENDPOINT"]; !ok {
+			otel["OTEL_EXPORTER_OTLP_ENDPOINT"] = endpoint
+			otel["OTEL_EXPORTER_OTLP_HEADERS"] = s.otlpHeaders()
+		}
+	}
+
+	for k, v := range otel {
+		if _, ok := envVars[k]; !ok && v != "" {
+			envVars[k] = v
+		}
+	}
+}
+
+// otlpEndpoint returns the OTLP/HTTP base URL of the provider, to which
+// SDKs append /v1/traces, or "" for providers without one.
+func (s *AgentCoreStack) otlpEndpoint() string {
+	obs := s.Config.Observability
+	endpoint := obs.Endpoint
+	if !*awscdk.Token_IsUnresolved(endpoint) {
+		endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+
+	switch obs.Provider {
+	case "opik":
+		if endpoint == "" {
+			endpoint = defaultOpikURL
+		}
+		return endpoint + "/v1/private/otel"
+	case "langfuse":
+		if endpoint == "" {
+			endpoint = defaultLangfuseURL
+		}
+		return endpoint + "/api/public/otel"
+	case "phoenix":
+		return endpoint
+	}
+	return ""
+}
+
+// otlpHeaders returns the OTEL_EXPORTER_OTLP_HEADERS of the provider, with
+// the API key as a Secrets Manager dynamic reference, or "" without an API
+// key secret. Header values are percent-encoded as the SDKs expect.
+func (s *AgentCoreStack) otlpHeaders() string {
+	obs := s.Config.Observability
+	if obs.APIKeySecretARN == "" {
+		return ""
+	}
+
+	secretOpts := &awscdk.SecretsManagerSecretOptions{}
+	if s.Options.Observability != nil && s.Options.Observability.APIKeyJSONKey != "" {
+		secretOpts.JsonField = jsii.String(s.Options.Observability.APIKeyJSONKey)
+	}
+	apiKey := *awscdk.SecretValue_SecretsManager(jsii.String(obs.APIKeySecretARN), secretOpts).UnsafeUnwrap()
+
+	var headers []string
+	switch obs.Provider {
+	case "opik":
+		headers = append(headers, "Authorization="+apiKey)
+		if obs.Project != "" {
+			project := obs.Project
+			if !*awscdk.Token_IsUnresolved(project) {
+				project = url.PathEscape(project)
+			}
+			headers = append(headers, "projectName="+project)
+		}
+		if s.Options.Observability != nil && s.Options.Observability.OpikWorkspace != "" {
+			headers = append(headers, "Comet-Workspace="+s.Options.Observability.OpikWorkspace)
+		}
+	case "langfuse":
+		// The secret holds base64(publicKey:secretKey)
+		headers = append(headers, "Authorization=Basic%20"+apiKey)
+	case "phoenix":
+		headers = append(headers, "Authorization=Bearer%20"+apiKey)
+	}
+	return strings.Join(headers, ",")
+}
+
+// grantObservabilitySecret allows the role to read the provider API key.
+func (s *AgentCoreStack) grantObservabilitySecret(role awsiam.IRole) {
+	obs := s.Config.Observability
+	if obs == nil || obs.APIKeySecretARN == "" {
+		return
+	}
+	// A partial ARN (without the random suffix) matches the secret through
+	// the wildcard.
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"),
+		Resources: jsii.Strings(obs.APIKeySecretARN, obs.APIKeySecretARN+"-??????"),
+	}))
+}
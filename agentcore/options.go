@@ -0,0 +1,611 @@
+package agentcore
+
+import "fmt"
+
+// StackOptions holds CDK-specific settings that extend the shared
+// iac.StackConfig schema. The shared schema is owned by agentkit and used
+// by every IaC backend; settings that only this module understands live here.
+type StackOptions struct {
+	// Agents contains per-agent options keyed by agent name.
+	Agents map[string]*AgentOptions `json:"-" yaml:"-"`
+
+	// VPC extends the VPC configuration with topology settings for
+	// newly created VPCs.
+	VPC *VPCOptions `json:"vpc,omitempty" yaml:"vpc,omitempty"`
+
+	// Gateway extends the gateway configuration.
+	Gateway *GatewayOptions `json:"gateway,omitempty" yaml:"gateway,omitempty"`
+
+	// ValidateImages checks agent container image URIs and verifies that
+	// the images exist in their registry before synth. Registry checks are
+	// skipped when no credentials are available.
+	ValidateImages bool `json:"validateImages,omitempty" yaml:"validateImages,omitempty"`
+
+	// GlobalEnvironment holds environment variables set on every agent.
+	// An agent's own value of a variable wins.
+	GlobalEnvironment map[string]string `json:"globalEnvironment,omitempty" yaml:"globalEnvironment,omitempty"`
+
+	// RawResources are emitted as-is, for AgentCore resource types the
+	// pinned aws-cdk-go version doesn't model yet.
+	RawResources []RawResource `json:"rawResources,omitempty" yaml:"rawResources,omitempty"`
+
+	// ConfigStore publishes agent environment variables to SSM or S3 and
+	// injects only a CONFIG_URI pointer.
+	ConfigStore *ConfigStoreOptions `json:"configStore,omitempty" yaml:"configStore,omitempty"`
+
+	// Secrets extends the secrets configuration with a choice of backend.
+	Secrets *SecretsOptions `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+
+	// KMS encrypts stack resources with a customer managed key instead of
+	// AWS managed keys.
+	KMS *KMSOptions `json:"kms,omitempty" yaml:"kms,omitempty"`
+
+	// RemovalPolicies sets the removal policy of log groups, secrets, ECR
+	// repositories, the cache, the VPC, and the stack's tables and buckets
+	// independently of StackConfig.RemovalPolicy.
+	RemovalPolicies *RemovalPolicyOptions `json:"removalPolicies,omitempty" yaml:"removalPolicies,omitempty"`
+
+	// TLS enforces encryption in transit on the stack's buckets, queues,
+	// topics, and ingress.
+	TLS *TLSOptions `json:"tls,omitempty" yaml:"tls,omitempty"`
+
+	// Dashboard creates a CloudWatch dashboard for the stack's agents.
+	Dashboard *DashboardOptions `json:"dashboard,omitempty" yaml:"dashboard,omitempty"`
+
+	// DeploymentHistory creates a DynamoDB table the deploy tool records
+	// each deployment in.
+	DeploymentHistory *DeploymentHistoryOptions `json:"deploymentHistory,omitempty" yaml:"deploymentHistory,omitempty"`
+
+	// ArtifactBucket creates an S3 bucket the agents share for inputs and
+	// outputs.
+	ArtifactBucket *ArtifactBucketOptions `json:"artifactBucket,omitempty" yaml:"artifactBucket,omitempty"`
+
+	// StateTable creates a DynamoDB table the agents share for durable
+	// state.
+	StateTable *StateTableOptions `json:"stateTable,omitempty" yaml:"stateTable,omitempty"`
+
+	// KnowledgeBases attaches Bedrock knowledge bases agents may query by
+	// name.
+	KnowledgeBases []KnowledgeBaseOptions `json:"knowledgeBases,omitempty" yaml:"knowledgeBases,omitempty"`
+
+	// Redis creates an ElastiCache cache the agents share in the stack's
+	// VPC.
+	Redis *RedisOptions `json:"redis,omitempty" yaml:"redis,omitempty"`
+
+	// HTTPAPI creates an API Gateway HTTP API invoking the agents.
+	HTTPAPI *HTTPAPIOptions `json:"httpApi,omitempty" yaml:"httpApi,omitempty"`
+
+	// CustomDomain serves the agents and the gateway on a custom domain.
+	CustomDomain *CustomDomainOptions `json:"customDomain,omitempty" yaml:"customDomain,omitempty"`
+
+	// WAF protects the custom domain with an AWS WAF web ACL.
+	WAF *WAFOptions `json:"waf,omitempty" yaml:"waf,omitempty"`
+
+	// SecurityChecks checks the stack's resources against security rules
+	// during synth.
+	SecurityChecks *SecurityChecksOptions `json:"securityChecks,omitempty" yaml:"securityChecks,omitempty"`
+
+	// Observability extends the observability configuration.
+	Observability *ObservabilityOptions `json:"observability,omitempty" yaml:"observability,omitempty"`
+
+	// ExportOutputs exports the agent runtime and endpoint ARNs, the
+	// execution role ARN, and the gateway ARN and URL for other stacks to
+	// import (see AgentExportName and StackExportName).
+	ExportOutputs bool `json:"exportOutputs,omitempty" yaml:"exportOutputs,omitempty"`
+
+	// Partition splits the agents' resources across nested stacks.
+	Partition *PartitionOptions `json:"partition,omitempty" yaml:"partition,omitempty"`
+
+	// Policies are the names of the built-in (see PolicyNoPublicNetwork)
+	// and registered (see RegisterPolicy) policies the configuration must
+	// comply with. Violations fail synth.
+	Policies []string `json:"policies,omitempty" yaml:"policies,omitempty"`
+
+	// SkipRegionCheck synthesizes the stack for regions where the region
+	// matrix (see Regions) says Bedrock AgentCore is not available, e.g.
+	// after AgentCore launched in the region.
+	SkipRegionCheck bool `json:"skipRegionCheck,omitempty" yaml:"skipRegionCheck,omitempty"`
+
+	// Synthesizer configures the stack synthesizer and the permissions
+	// boundary of its roles for a customized CDK bootstrap.
+	Synthesizer *SynthesizerOptions `json:"synthesizer,omitempty" yaml:"synthesizer,omitempty"`
+
+	// ResourceHooks customize the generated CloudFormation resources. They
+	// run after all resources are created, before removal policies, TLS
+	// enforcement, and the checks.
+	ResourceHooks []ResourceHook `json:"-" yaml:"-"`
+}
+
+// AgentOptions holds CDK-specific settings for a single agent.
+type AgentOptions struct {
+	// MemoryStore provisions an AgentCore Memory resource for the agent.
+	MemoryStore *MemoryStoreConfig `json:"memoryStore,omitempty" yaml:"memoryStore,omitempty"`
+
+	// NetworkMode is the runtime network mode: "VPC" or "PUBLIC".
+	// PUBLIC agents are not attached to the VPC.
+	// Default: "VPC"
+	NetworkMode string `json:"networkMode,omitempty" yaml:"networkMode,omitempty"`
+
+	// SubnetIDs overrides the stack's private subnets for this agent.
+	// Only valid in VPC network mode.
+	SubnetIDs []string `json:"subnetIds,omitempty" yaml:"subnetIds,omitempty"`
+
+	// SecurityGroupIDs overrides the stack security group for this agent.
+	// Only valid in VPC network mode.
+	SecurityGroupIDs []string `json:"securityGroupIds,omitempty" yaml:"securityGroupIds,omitempty"`
+
+	// EndpointName renames the agent's default runtime endpoint.
+	// Default: "{agent}-endpoint"
+	EndpointName string `json:"endpointName,omitempty" yaml:"endpointName,omitempty"`
+
+	// Endpoints are additional named runtime endpoints, each with its own
+	// stack output.
+	Endpoints []EndpointOptions `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+
+	// Contract publishes JSON Schemas of the agent's request and response
+	// payloads.
+	Contract *ContractOptions `json:"contract,omitempty" yaml:"contract,omitempty"`
+
+	// Schedules invoke the agent on a schedule.
+	Schedules []ScheduleOptions `json:"schedules,omitempty" yaml:"schedules,omitempty"`
+
+	// Triggers invoke the agent on SQS messages, SNS notifications, or
+	// EventBridge events.
+	Triggers []TriggerOptions `json:"triggers,omitempty" yaml:"triggers,omitempty"`
+
+	// SecretEnvironment injects Secrets Manager secrets into environment
+	// variables. A secret wins over an environment variable of the same name.
+	SecretEnvironment []SecretEnvVar `json:"secretEnvironment,omitempty" yaml:"secretEnvironment,omitempty"`
+
+	// HealthCheck configures the request smoke tests invoke the agent with
+	// after a deploy.
+	HealthCheck *HealthCheckOptions `json:"healthCheck,omitempty" yaml:"healthCheck,omitempty"`
+
+	// BlueGreen pins the default endpoint to a promoted runtime version and
+	// serves the latest version from a candidate endpoint.
+	BlueGreen *BlueGreenOptions `json:"blueGreen,omitempty" yaml:"blueGreen,omitempty"`
+
+	// Versions makes the agent's recent runtime versions available for
+	// rollback.
+	Versions *VersionsOptions `json:"versions,omitempty" yaml:"versions,omitempty"`
+
+	// Scaling sets the idle timeout and maximum lifetime of the agent's
+	// runtime sessions.
+	Scaling *ScalingOptions `json:"scaling,omitempty" yaml:"scaling,omitempty"`
+
+	// JWTAuthorizer makes the runtime accept JWT bearer tokens from an
+	// OpenID Connect identity provider instead of SigV4-signed requests,
+	// for agents invoked directly by external services.
+	JWTAuthorizer *JWTAuthorizerOptions `json:"jwtAuthorizer,omitempty" yaml:"jwtAuthorizer,omitempty"`
+
+	// DependsOn names the agents of the stack this agent calls. They are
+	// created first, their default endpoint and runtime ARNs are set in
+	// AGENT_{NAME}_ENDPOINT_ARN and AGENT_{NAME}_RUNTIME_ARN, and the
+	// execution role may invoke them.
+	DependsOn []string `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+
+	// CanInvoke names the agents of the stack this agent may call without
+	// depending on them. The execution role is granted
+	// bedrock-agentcore:InvokeAgentRuntime on their runtimes; agents in
+	// DependsOn are granted the same.
+	CanInvoke []string `json:"canInvoke,omitempty" yaml:"canInvoke,omitempty"`
+
+	// KnowledgeBases names the knowledge bases of the stack this agent
+	// queries. Their IDs are set in KNOWLEDGE_BASE_{NAME}_ID (and
+	// KNOWLEDGE_BASE_ID for a single one), and the execution role may
+	// retrieve from them.
+	KnowledgeBases []string `json:"knowledgeBases,omitempty" yaml:"knowledgeBases,omitempty"`
+}
+
+// MemoryStoreConfig configures an AWS::BedrockAgentCore::Memory resource.
+type MemoryStoreConfig struct {
+	// Name is the memory resource name.
+	// Pattern: [a-zA-Z][a-zA-Z0-9_]{0,47}
+	// Default: "{agent}_memory"
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// EventExpiryDays is how long short-term memory events are kept.
+	// Range: 7-365
+	// Default: 30
+	EventExpiryDays int `json:"eventExpiryDays,omitempty" yaml:"eventExpiryDays,omitempty"`
+}
+
+// VPCOptions configures the topology of a VPC created by the stack, or how
+// an existing VPC and its subnets are found.
+type VPCOptions struct {
+	// NatGateways is the number of NAT gateways (or NAT instances).
+	// Zero requires IsolatedSubnets.
+	// Default: 1
+	NatGateways *int `json:"natGateways,omitempty" yaml:"natGateways,omitempty"`
+
+	// NatInstanceType uses NAT instances of this EC2 instance type
+	// (e.g. "t4g.nano") instead of managed NAT gateways.
+	NatInstanceType string `json:"natInstanceType,omitempty" yaml:"natInstanceType,omitempty"`
+
+	// SubnetCidrMask is the CIDR mask of each subnet.
+	// Range: 16-28
+	// Default: 24
+	SubnetCidrMask int `json:"subnetCidrMask,omitempty" yaml:"subnetCidrMask,omitempty"`
+
+	// IsolatedSubnets places agents in isolated subnets with no NAT and no
+	// public subnets. Requires EnableVPCEndpoints so agents can still reach
+	// AWS services.
+	IsolatedSubnets bool `json:"isolatedSubnets,omitempty" yaml:"isolatedSubnets,omitempty"`
+
+	// VPCTags finds the existing VPC by its tags instead of vpc.vpcId, so
+	// one configuration works in accounts where the VPC IDs differ.
+	VPCTags map[string]string `json:"vpcTags,omitempty" yaml:"vpcTags,omitempty"`
+
+	// SubnetTags places the agents in the subnets of the VPC found by
+	// VPCTags that carry this tag, e.g. {"tier": "private"}. Only one tag
+	// is supported.
+	SubnetTags map[string]string `json:"subnetTags,omitempty" yaml:"subnetTags,omitempty"`
+
+	// SubnetGroupName places the agents in the subnets of the VPC found by
+	// VPCTags with this aws-cdk:subnet-name tag, as created by CDK VPCs.
+	SubnetGroupName string `json:"subnetGroupName,omitempty" yaml:"subnetGroupName,omitempty"`
+}
+
+// GatewayOptions holds CDK-specific gateway settings.
+type GatewayOptions struct {
+	// RemoteTargets are agent runtimes deployed in other stacks that this
+	// stack's gateway fronts. Requires Gateway.Enabled.
+	RemoteTargets []RemoteRuntimeTarget `json:"remoteTargets,omitempty" yaml:"remoteTargets,omitempty"`
+
+	// ToolTargets expose Lambda functions and OpenAPI described APIs as
+	// MCP tools of the gateway. Requires Gateway.Enabled.
+	ToolTargets []ToolTarget `json:"toolTargets,omitempty" yaml:"toolTargets,omitempty"`
+
+	// InterceptorLambda is a Lambda function that intercepts gateway
+	// requests, e.g. for tenant routing or custom authorization.
+	// Requires Gateway.Enabled.
+	InterceptorLambda *InterceptorOptions `json:"interceptorLambda,omitempty" yaml:"interceptorLambda,omitempty"`
+}
+
+// ObservabilityOptions holds CDK-specific observability settings.
+type ObservabilityOptions struct {
+	// XRay sends agent traces to AWS X-Ray. Setting it enables tracing
+	// without observability.enableXRay.
+	XRay *XRayOptions `json:"xray,omitempty" yaml:"xray,omitempty"`
+
+	// LogDestination ships the stack's log group to Firehose, Kinesis, or
+	// another account. Requires observability.enableCloudWatchLogs.
+	LogDestination *LogDestinationOptions `json:"logDestination,omitempty" yaml:"logDestination,omitempty"`
+
+	// LogMetrics derives metrics from the stack's log group with metric
+	// filters. Requires observability.enableCloudWatchLogs.
+	LogMetrics []LogMetricOptions `json:"logMetrics,omitempty" yaml:"logMetrics,omitempty"`
+
+	// UsageMetrics publishes LLM token and cost metrics from the usage
+	// agents log, and writes a daily cost report. Requires
+	// observability.enableCloudWatchLogs.
+	UsageMetrics *UsageMetricsOptions `json:"usageMetrics,omitempty" yaml:"usageMetrics,omitempty"`
+
+	// Environment is reported to OpenTelemetry as the
+	// deployment.environment resource attribute.
+	// Default: the environment overlay the config file was loaded with
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
+
+	// APIKeyJSONKey is the JSON key of the provider API key in the
+	// observability.apiKeySecretARN secret.
+	// Default: the whole secret string
+	APIKeyJSONKey string `json:"apiKeyJsonKey,omitempty" yaml:"apiKeyJsonKey,omitempty"`
+
+	// OpikWorkspace is the Opik workspace traces are sent to. Required by
+	// Opik Cloud.
+	OpikWorkspace string `json:"opikWorkspace,omitempty" yaml:"opikWorkspace,omitempty"`
+}
+
+// RemoteRuntimeTarget identifies an agent runtime owned by another stack.
+type RemoteRuntimeTarget struct {
+	// Name is the gateway target name.
+	Name string `json:"name" yaml:"name"`
+
+	// RuntimeARN is the ARN of the remote agent runtime. Must be a literal
+	// ARN, not a token, so the invocation URL can be built at synth time.
+	RuntimeARN string `json:"runtimeArn" yaml:"runtimeArn"`
+
+	// Qualifier is the runtime endpoint to invoke.
+	// Default: "DEFAULT"
+	Qualifier string `json:"qualifier,omitempty" yaml:"qualifier,omitempty"`
+}
+
+// RawResource is a CloudFormation resource declared by type and properties.
+type RawResource struct {
+	// ID is the construct ID, which is also the CloudFormation logical ID.
+	// Other raw resources can reference it with Ref and Fn::GetAtt.
+	ID string `json:"id" yaml:"id"`
+
+	// Type is the CloudFormation resource type,
+	// e.g. "AWS::BedrockAgentCore::Policy".
+	Type string `json:"type" yaml:"type"`
+
+	// Properties are the CloudFormation resource properties.
+	Properties map[string]interface{} `json:"properties,omitempty" yaml:"properties,omitempty"`
+
+	// DependsOn lists resources that must be created first: other raw
+	// resource IDs, "agent:{name}", "memory:{agent}", "gatewayTarget:{name}",
+	// or "gateway".
+	DependsOn []string `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+}
+
+// agentOptions returns the options for the named agent, never nil.
+func (o *StackOptions) agentOptions(name string) *AgentOptions {
+	if o.Agents == nil {
+		o.Agents = make(map[string]*AgentOptions)
+	}
+	opts, ok := o.Agents[name]
+	if !ok || opts == nil {
+		opts = &AgentOptions{}
+		o.Agents[name] = opts
+	}
+	return opts
+}
+
+// Validate validates the options against the stack configuration.
+func (o *StackOptions) Validate(config StackConfig) error {
+	agentNames := make(map[string]bool)
+	for _, agent := range config.Agents {
+		agentNames[agent.Name] = true
+	}
+	for name := range o.Agents {
+		if !agentNames[name] {
+			return fmt.Errorf("options given for unknown agent: %s", name)
+		}
+	}
+
+	if o.VPC != nil {
+		if err := o.VPC.validate(config.VPC); err != nil {
+			return err
+		}
+	}
+
+	for i, agent := range config.Agents {
+		opts := o.Agents[agent.Name]
+		if err := validateNetworkOptions(opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateEndpoints(agent.Name, opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateSchedules(agent.Name, opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateTriggers(agent.Name, opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateSecretEnvironment(opts, config); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateBlueGreen(agent.Name, opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateVersions(opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateScaling(agent, opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateHealthCheck(agent.Name, opts); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if err := validateAuthorizer(agent, o, config); err != nil {
+			return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+		}
+		if opts != nil && opts.Contract != nil {
+			if err := opts.Contract.validate(); err != nil {
+				return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+			}
+		}
+		if memory := resolveMemoryStore(agent, opts); memory != nil {
+			if err := memory.validate(); err != nil {
+				return fmt.Errorf("agents[%d] (%s): %w", i, agent.Name, err)
+			}
+		}
+	}
+
+	if err := validateDependencies(config.Agents, o.Agents); err != nil {
+		return err
+	}
+	if err := validateInvokes(config.Agents, o.Agents); err != nil {
+		return err
+	}
+
+	if o.ConfigStore != nil {
+		if err := o.ConfigStore.validate(config.Agents); err != nil {
+			return err
+		}
+	}
+
+	if err := validateSecretValues(config, o.Secrets); err != nil {
+		return err
+	}
+	if o.Secrets != nil {
+		if err := o.Secrets.validate(); err != nil {
+			return err
+		}
+		if len(o.Secrets.ReplicaRegions) > 0 && !createsSecret(config) {
+			return fmt.Errorf("secrets.replicaRegions require the stack to create its secret (secrets.createSecrets); replicate pushed secrets with push-secrets --replicate-to")
+		}
+		if (len(o.Secrets.Tags) > 0 || o.Secrets.RestrictRead) && !createsSecret(config) {
+			return fmt.Errorf("secrets.tags and secrets.restrictRead require the stack to create its secret (secrets.createSecrets); tag pushed secrets with push-secrets --tags and --restrict-read")
+		}
+		if o.Secrets.RestrictRead && len(o.Secrets.ReaderARNs) == 0 && usesStackSecretEnvironment(o) {
+			return fmt.Errorf("secrets.restrictRead blocks the dynamic references of secretEnvironment to the stack's secret; add the CloudFormation execution role to secrets.readerArns")
+		}
+	}
+
+	if o.Synthesizer != nil {
+		if err := o.Synthesizer.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.KMS != nil {
+		if err := o.KMS.validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := validateStackRemovalPolicy(config.RemovalPolicy); err != nil {
+		return err
+	}
+	if o.RemovalPolicies != nil {
+		if err := o.RemovalPolicies.validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := validateObservability(config.Observability); err != nil {
+		return err
+	}
+
+	if o.Partition != nil {
+		if err := o.Partition.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.HTTPAPI != nil {
+		if err := o.HTTPAPI.validate(config.Agents); err != nil {
+			return err
+		}
+	}
+
+	if o.CustomDomain != nil {
+		if err := o.CustomDomain.validate(o.HTTPAPI); err != nil {
+			return err
+		}
+	}
+
+	if o.WAF != nil {
+		if err := o.WAF.validate(o); err != nil {
+			return err
+		}
+	}
+
+	if o.Dashboard != nil {
+		if err := o.Dashboard.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.DeploymentHistory != nil {
+		if err := o.DeploymentHistory.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.ArtifactBucket != nil {
+		if err := o.ArtifactBucket.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.StateTable != nil {
+		if err := o.StateTable.validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := validateKnowledgeBases(o.KnowledgeBases, config, o.Agents); err != nil {
+		return err
+	}
+
+	if o.Redis != nil {
+		if err := o.Redis.validate(config, *o); err != nil {
+			return err
+		}
+	}
+
+	if o.TLS != nil {
+		if err := o.TLS.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.SecurityChecks != nil {
+		if err := o.SecurityChecks.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.Observability != nil && o.Observability.XRay != nil {
+		if err := o.Observability.XRay.validate(); err != nil {
+			return err
+		}
+	}
+	if o.Observability != nil && o.Observability.LogDestination != nil {
+		if err := o.Observability.LogDestination.validate(config.Observability); err != nil {
+			return err
+		}
+	}
+	if o.Observability != nil {
+		if err := validateLogMetrics(o.Observability.LogMetrics, config.Observability); err != nil {
+			return err
+		}
+	}
+	if o.Observability != nil {
+		if err := o.Observability.validateOTEL(config.Observability); err != nil {
+			return err
+		}
+	}
+	if o.Observability != nil && o.Observability.UsageMetrics != nil {
+		if err := o.Observability.UsageMetrics.validate(config.Observability); err != nil {
+			return err
+		}
+	}
+
+	if err := validateRawResources(o.RawResources, config, *o); err != nil {
+		return err
+	}
+
+	if o.Gateway != nil && len(o.Gateway.RemoteTargets) > 0 {
+		if config.Gateway == nil || !config.Gateway.Enabled {
+			return fmt.Errorf("gateway.remoteTargets requires gateway.enabled")
+		}
+		if err := validateRemoteTargets(o.Gateway.RemoteTargets); err != nil {
+			return err
+		}
+		for _, name := range mcpGatewayTargets(config) {
+			for i, target := range o.Gateway.RemoteTargets {
+				if target.Name == name {
+					return fmt.Errorf("gateway.remoteTargets[%d]: name %s is already used by the MCP agent's gateway target", i, name)
+				}
+			}
+		}
+	}
+
+	if o.Gateway != nil && len(o.Gateway.ToolTargets) > 0 {
+		if config.Gateway == nil || !config.Gateway.Enabled {
+			return fmt.Errorf("gateway.toolTargets requires gateway.enabled")
+		}
+		if err := validateToolTargets(o.Gateway.ToolTargets); err != nil {
+			return err
+		}
+		used := make(map[string]string)
+		for _, name := range mcpGatewayTargets(config) {
+			used[name] = "the MCP agent's gateway target"
+		}
+		for _, target := range o.Gateway.RemoteTargets {
+			used[target.Name] = "gateway.remoteTargets"
+		}
+		for i, target := range o.Gateway.ToolTargets {
+			if other, ok := used[target.Name]; ok {
+				return fmt.Errorf("gateway.toolTargets[%d]: name %s is already used by %s", i, target.Name, other)
+			}
+			used[target.Name] = "gateway.toolTargets"
+		}
+	}
+
+	if o.Gateway != nil && o.Gateway.InterceptorLambda != nil {
+		if config.Gateway == nil || !config.Gateway.Enabled {
+			return fmt.Errorf("gateway.interceptorLambda requires gateway.enabled")
+		}
+		if err := o.Gateway.InterceptorLambda.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,403 @@
+package agentcore
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+)
+
+// DescribeStacksAPI is the subset of the CloudFormation client used by
+// FromStackOutputs. *cloudformation.Client satisfies it.
+type DescribeStacksAPI interface {
+	DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error)
+}
+
+// DeployedStack is the typed view of a deployed AgentCoreStack's outputs.
+type DeployedStack struct {
+	// StackName is the CloudFormation stack name.
+	StackName string
+
+	// StackID is the CloudFormation stack ID.
+	StackID string
+
+	// Status is the CloudFormation stack status (e.g. CREATE_COMPLETE).
+	Status string
+
+	// VPCID is the VPC used by the agents.
+	VPCID string
+
+	// SecurityGroupID is the agent security group.
+	SecurityGroupID string
+
+	// ExecutionRoleARN is the agent execution role.
+	ExecutionRoleARN string
+
+	// LogGroupName is the CloudWatch log group for agent logs.
+	LogGroupName string
+
+	// GatewayARN is the gateway ARN (if gateway enabled).
+	GatewayARN string
+
+	// GatewayID is the gateway identifier (if gateway enabled).
+	GatewayID string
+
+	// GatewayURL is the gateway invocation URL (if gateway enabled).
+	GatewayURL string
+
+	// GatewayInterceptorARN is the gateway interceptor function ARN (if
+	// configured).
+	GatewayInterceptorARN string
+
+	// HTTPAPIURL is the HTTP API URL (if configured).
+	HTTPAPIURL string
+
+	// CustomDomainURL is the URL of the custom domain (if configured).
+	CustomDomainURL string
+
+	// GatewayCustomURL is the gateway URL on the custom domain (if
+	// configured with a gateway).
+	GatewayCustomURL string
+
+	// DashboardURL is the CloudWatch dashboard URL (if configured).
+	DashboardURL string
+
+	// DeploymentHistoryTable is the deployment history table (if
+	// configured).
+	DeploymentHistoryTable string
+
+	// ArtifactBucket is the artifact bucket shared by the agents (if
+	// configured).
+	ArtifactBucket string
+
+	// StateTable is the state table shared by the agents (if configured).
+	StateTable string
+
+	// UsageReportBucket is the bucket of the daily LLM cost reports (if
+	// usage metrics are configured).
+	UsageReportBucket string
+
+	// KnowledgeBases contains the knowledge bases created by the stack,
+	// keyed by name with non-alphanumeric characters removed.
+	KnowledgeBases map[string]*DeployedKnowledgeBase
+
+	// RedisURL is the URL of the cache shared by the agents (if
+	// configured).
+	RedisURL string
+
+	// ScheduleDeadLetterQueueURL is the dead-letter queue of failed
+	// scheduled invocations (if any agent has schedules).
+	ScheduleDeadLetterQueueURL string
+
+	// Agents contains the deployed agents keyed by output name. CloudFormation
+	// strips non-alphanumeric characters from output keys, so use Agent to look
+	// up agents by their configured name.
+	Agents map[string]*DeployedAgent
+
+	// Outputs contains all raw stack outputs keyed by output key.
+	Outputs map[string]string
+}
+
+// DeployedKnowledgeBase is the typed view of a knowledge base's stack
+// outputs.
+type DeployedKnowledgeBase struct {
+	// Name is the knowledge base name as it appears in output keys.
+	Name string
+
+	// ID is the Bedrock knowledge base ID.
+	ID string
+
+	// DataSourceID is the ID of the S3 data source (if configured). Sync
+	// it with "aws bedrock-agent start-ingestion-job".
+	DataSourceID string
+
+	// CollectionEndpoint is the OpenSearch Serverless collection endpoint
+	// (opensearch-serverless vector store only).
+	CollectionEndpoint string
+}
+
+// DeployedAgent is the typed view of a single agent's stack outputs.
+type DeployedAgent struct {
+	// Name is the agent name as it appears in output keys.
+	Name string
+
+	// RuntimeARN is the AgentCore runtime ARN.
+	RuntimeARN string
+
+	// RuntimeID is the AgentCore runtime ID.
+	RuntimeID string
+
+	// EndpointARN is the AgentCore runtime endpoint ARN.
+	EndpointARN string
+
+	// Endpoints contains the ARNs of the additional named endpoints, keyed
+	// by endpoint name as it appears in output keys.
+	Endpoints map[string]string
+
+	// Triggers contains the ARNs of the trigger sources (queue, topic, or
+	// event rule), keyed by trigger name.
+	Triggers map[string]string
+
+	// TriggerQueueURLs contains the queue URLs of the sqs triggers, keyed
+	// by trigger name.
+	TriggerQueueURLs map[string]string
+
+	// Image is the deployed container image.
+	Image string
+
+	// MemoryID is the AgentCore memory ID (if memory enabled).
+	MemoryID string
+
+	// ContractParameter is the SSM parameter holding the agent's
+	// request/response contract (if a contract is configured). Load it
+	// with contracts.Load.
+	ContractParameter string
+
+	// AgentCardURL is the agent card URL of an A2A agent.
+	AgentCardURL string
+
+	// RuntimeVersion is the latest runtime version, served by the
+	// candidate endpoint of blue/green agents (blue/green and versioned
+	// agents only).
+	RuntimeVersion string
+
+	// LiveVersion is the runtime version served by the default endpoint
+	// (blue/green and versioned agents only).
+	LiveVersion string
+
+	// RetainVersions is how many runtime versions the agent can be rolled
+	// back to (versioned agents only).
+	RetainVersions string
+
+	// RetainedVersions are the runtime versions the agent can be rolled
+	// back to, newest first, with their version-qualified runtime ARNs
+	// (versioned agents only).
+	RetainedVersions []RetainedVersion
+
+	// InvokeURL is the invoke URL of the agent on the custom domain (if
+	// configured).
+	InvokeURL string
+}
+
+// RetainedVersion is a runtime version in the rollback window of a
+// versioned agent.
+type RetainedVersion struct {
+	// Version is the runtime version number.
+	Version string
+
+	// ARN is the version-qualified runtime ARN.
+	ARN string
+}
+
+// runtimeIDOutputPattern matches the runtime ID output key every agent
+// has, such as AgentresearchRuntimeId. Agent names are read from these, as
+// the other per-agent keys can't be split where the agent name ends: in
+// AgentresearchTriggernightlyArn, "research" and "nightly" may contain
+// "Trigger" themselves.
+var runtimeIDOutputPattern = regexp.MustCompile(`^Agent(.+)RuntimeId$`)
+
+// retainedVersionOutputPattern matches the rest of output keys of the
+// rollback window of versioned agents such as RetainedVersion2Arn.
+var retainedVersionOutputPattern = regexp.MustCompile(`^RetainedVersion([0-9]+)(Arn)?$`)
+
+// triggerOutputPattern matches the rest of output keys of triggers such as
+// TriggernightlyArn and TriggernightlyQueueUrl.
+var triggerOutputPattern = regexp.MustCompile(`^Trigger(.+)(Arn|QueueUrl)$`)
+
+// namedEndpointOutputPattern matches the rest of output keys of additional
+// named endpoints such as EndpointshadowArn.
+var namedEndpointOutputPattern = regexp.MustCompile(`^Endpoint(.+)Arn$`)
+
+// knowledgeBaseOutputPattern matches output keys of knowledge bases such
+// as "KnowledgeBasedocsId".
+var knowledgeBaseOutputPattern = regexp.MustCompile(`^KnowledgeBase(.+?)(Id|DataSourceId|CollectionEndpoint)$`)
+
+// outputKeySanitizer removes the characters CloudFormation strips from output keys.
+var outputKeySanitizer = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// FromStackOutputs reads the outputs of a deployed AgentCoreStack and returns
+// them as a DeployedStack, so operational tooling doesn't re-parse raw
+// DescribeStacks output.
+func FromStackOutputs(ctx context.Context, client DescribeStacksAPI, stackName string) (*DeployedStack, error) {
+	out, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing stack %s: %w", stackName, err)
+	}
+	if len(out.Stacks) == 0 {
+		return nil, fmt.Errorf("stack %s not found", stackName)
+	}
+
+	stack := out.Stacks[0]
+	outputs := make(map[string]string, len(stack.Outputs))
+	for _, output := range stack.Outputs {
+		outputs[aws.ToString(output.OutputKey)] = aws.ToString(output.OutputValue)
+	}
+
+	deployed := ParseStackOutputs(outputs)
+	deployed.StackName = aws.ToString(stack.StackName)
+	deployed.StackID = aws.ToString(stack.StackId)
+	deployed.Status = string(stack.StackStatus)
+	return deployed, nil
+}
+
+// ParseStackOutputs builds a DeployedStack from raw output key/value pairs.
+func ParseStackOutputs(outputs map[string]string) *DeployedStack {
+	deployed := &DeployedStack{
+		VPCID:            outputs["VPCID"],
+		SecurityGroupID:  outputs["SecurityGroupID"],
+		ExecutionRoleARN: outputs["ExecutionRoleARN"],
+		LogGroupName:     outputs["LogGroupName"],
+		GatewayARN:       outputs["GatewayArn"],
+		GatewayID:        outputs["GatewayId"],
+		GatewayURL:       outputs["GatewayUrl"],
+		Agents:           make(map[string]*DeployedAgent),
+		Outputs:          outputs,
+
+		GatewayInterceptorARN: outputs["GatewayInterceptorArn"],
+		DashboardURL:          outputs["DashboardUrl"],
+		HTTPAPIURL:            outputs["HttpApiUrl"],
+		CustomDomainURL:       outputs["CustomDomainUrl"],
+		GatewayCustomURL:      outputs["GatewayCustomUrl"],
+
+		DeploymentHistoryTable: outputs["DeploymentHistoryTable"],
+		ArtifactBucket:         outputs["ArtifactBucketName"],
+		StateTable:             outputs["StateTableName"],
+		UsageReportBucket:      outputs["UsageReportBucketName"],
+		KnowledgeBases:         make(map[string]*DeployedKnowledgeBase),
+		RedisURL:               outputs["RedisUrl"],
+
+		ScheduleDeadLetterQueueURL: outputs["ScheduleDeadLetterQueueUrl"],
+	}
+
+	retained := make(map[string]map[int]*RetainedVersion)
+	for key := range outputs {
+		if matches := runtimeIDOutputPattern.FindStringSubmatch(key); matches != nil {
+			name := matches[1]
+			deployed.Agents[name] = &DeployedAgent{
+				Name:             name,
+				Endpoints:        make(map[string]string),
+				Triggers:         make(map[string]string),
+				TriggerQueueURLs: make(map[string]string),
+			}
+			retained[name] = make(map[int]*RetainedVersion)
+		}
+	}
+
+	// Longest first, so the keys of agent "aTriggerb" aren't read as
+	// trigger outputs of agent "a"
+	names := deployed.AgentNames()
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	for key, value := range outputs {
+		if matches := knowledgeBaseOutputPattern.FindStringSubmatch(key); matches != nil {
+			kb, ok := deployed.KnowledgeBases[matches[1]]
+			if !ok {
+				kb = &DeployedKnowledgeBase{Name: matches[1]}
+				deployed.KnowledgeBases[matches[1]] = kb
+			}
+			switch matches[2] {
+			case "Id":
+				kb.ID = value
+			case "DataSourceId":
+				kb.DataSourceID = value
+			case "CollectionEndpoint":
+				kb.CollectionEndpoint = value
+			}
+			continue
+		}
+		for _, name := range names {
+			rest, ok := strings.CutPrefix(key, "Agent"+name)
+			if ok && setAgentOutput(deployed.Agents[name], retained[name], rest, value) {
+				break
+			}
+		}
+	}
+
+	// Slots before the agent's first version hold "none"
+	for name, slots := range retained {
+		for slot := 1; slots[slot] != nil; slot++ {
+			if slots[slot].Version == "none" {
+				break
+			}
+			deployed.Agents[name].RetainedVersions = append(deployed.Agents[name].RetainedVersions, *slots[slot])
+		}
+	}
+
+	return deployed
+}
+
+// setAgentOutput sets the field of agent read from the output key rest
+// following the agent name, and reports whether rest is an agent output.
+func setAgentOutput(agent *DeployedAgent, retained map[int]*RetainedVersion, rest, value string) bool {
+	switch rest {
+	case "RuntimeArn":
+		agent.RuntimeARN = value
+	case "RuntimeId":
+		agent.RuntimeID = value
+	case "EndpointArn":
+		agent.EndpointARN = value
+	case "Image":
+		agent.Image = value
+	case "MemoryId":
+		agent.MemoryID = value
+	case "ContractParameter":
+		agent.ContractParameter = value
+	case "AgentCardUrl":
+		agent.AgentCardURL = value
+	case "RuntimeVersion":
+		agent.RuntimeVersion = value
+	case "LiveVersion":
+		agent.LiveVersion = value
+	case "RetainVersions":
+		agent.RetainVersions = value
+	case "InvokeUrl":
+		agent.InvokeURL = value
+	default:
+		if matches := retainedVersionOutputPattern.FindStringSubmatch(rest); matches != nil {
+			slot, _ := strconv.Atoi(matches[1])
+			version := retained[slot]
+			if version == nil {
+				version = &RetainedVersion{}
+				retained[slot] = version
+			}
+			if matches[2] == "Arn" {
+				version.ARN = value
+			} else {
+				version.Version = value
+			}
+		} else if matches := triggerOutputPattern.FindStringSubmatch(rest); matches != nil {
+			if matches[2] == "Arn" {
+				agent.Triggers[matches[1]] = value
+			} else {
+				agent.TriggerQueueURLs[matches[1]] = value
+			}
+		} else if matches := namedEndpointOutputPattern.FindStringSubmatch(rest); matches != nil {
+			agent.Endpoints[matches[1]] = value
+		} else {
+			return false
+		}
+	}
+	return true
+}
+
+// Agent returns the deployed agent with the given configured name, or nil.
+func (d *DeployedStack) Agent(name string) *DeployedAgent {
+	return d.Agents[outputKeySanitizer.ReplaceAllString(name, "")]
+}
+
+// AgentNames returns the sorted output names of all deployed agents.
+func (d *DeployedStack) AgentNames() []string {
+	names := make([]string, 0, len(d.Agents))
+	for name := range d.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,72 @@
+package agentcore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStackOutputsAgentNames(t *testing.T) {
+	// Agent, trigger, and endpoint names containing "Trigger" and
+	// "Endpoint" are split at the known agent names
+	outputs := map[string]string{
+		"AgentaRuntimeId":                    "a-id",
+		"AgentaRuntimeArn":                   "a-arn",
+		"AgentaTriggerbArn":                  "a-trigger-b",
+		"AgentaTriggerbQueueUrl":             "a-trigger-b-url",
+		"AgentaTriggernightlyTriggerArn":     "a-trigger-nightly-trigger",
+		"AgentaEndpointArn":                  "a-endpoint",
+		"AgentaEndpointshadowEndpointArn":    "a-endpoint-shadow-endpoint",
+		"AgentaRetainedVersion1":             "3",
+		"AgentaRetainedVersion1Arn":          "a-arn:3",
+		"AgentaRetainedVersion2":             "none",
+		"AgentaTriggerbRuntimeId":            "atb-id",
+		"AgentaTriggerbRuntimeArn":           "atb-arn",
+		"AgentaTriggerbEndpointArn":          "atb-endpoint",
+		"AgentaTriggerbEndpointcanaryArn":    "atb-endpoint-canary",
+		"AgentaTriggerbTriggerRuntimeArn":    "atb-trigger-runtime",
+		"AgentsearchEndpointRuntimeId":       "se-id",
+		"AgentsearchEndpointEndpointArn":     "se-endpoint",
+		"AgentsearchEndpointEndpointblueArn": "se-endpoint-blue",
+		"AgentunknownRuntimeArn":             "no runtime ID",
+	}
+	deployed := ParseStackOutputs(outputs)
+
+	want := map[string]*DeployedAgent{
+		"a": {
+			Name:             "a",
+			RuntimeID:        "a-id",
+			RuntimeARN:       "a-arn",
+			EndpointARN:      "a-endpoint",
+			Endpoints:        map[string]string{"shadowEndpoint": "a-endpoint-shadow-endpoint"},
+			Triggers:         map[string]string{"b": "a-trigger-b", "nightlyTrigger": "a-trigger-nightly-trigger"},
+			TriggerQueueURLs: map[string]string{"b": "a-trigger-b-url"},
+			RetainedVersions: []RetainedVersion{{Version: "3", ARN: "a-arn:3"}},
+		},
+		"aTriggerb": {
+			Name:             "aTriggerb",
+			RuntimeID:        "atb-id",
+			RuntimeARN:       "atb-arn",
+			EndpointARN:      "atb-endpoint",
+			Endpoints:        map[string]string{"canary": "atb-endpoint-canary"},
+			Triggers:         map[string]string{"Runtime": "atb-trigger-runtime"},
+			TriggerQueueURLs: map[string]string{},
+		},
+		"searchEndpoint": {
+			Name:             "searchEndpoint",
+			RuntimeID:        "se-id",
+			EndpointARN:      "se-endpoint",
+			Endpoints:        map[string]string{"blue": "se-endpoint-blue"},
+			Triggers:         map[string]string{},
+			TriggerQueueURLs: map[string]string{},
+		},
+	}
+	if !reflect.DeepEqual(deployed.Agents, want) {
+		for name, agent := range deployed.Agents {
+			t.Logf("%s: %+v", name, *agent)
+		}
+		t.Errorf("agents = %v, want %v", deployed.AgentNames(), []string{"a", "aTriggerb", "searchEndpoint"})
+	}
+	if agent := deployed.Agent("search-Endpoint"); agent == nil || agent.RuntimeID != "se-id" {
+		t.Errorf("Agent(search-Endpoint) = %+v", agent)
+	}
+}
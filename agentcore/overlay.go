@@ -0,0 +1,140 @@
+package agentcore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvironmentContextKey is the CDK context key that selects the config
+// overlay read by NewStackFromFile, e.g. cdk deploy -c agentkit:env=prod.
+const EnvironmentContextKey = "agentkit:env"
+
+// environmentNamePattern matches environment names. They become part of file
+// and stack names.
+var environmentNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// WithEnvironment merges the overlay for the named environment over the
+// config file. The overlay sits next to the file with the environment name
+// before the extension: config.yaml is overlaid by config.prod.yaml.
+//
+// Objects are merged key by key, lists of named entries (such as agents) are
+// merged by name, and all other values in the overlay replace the base. Unless
+// the overlay sets stackName, the environment name is appended to the stack
+// name ("my-agents" becomes "my-agents-prod"), so environments deploy to
+// separate stacks. An empty name loads the file unchanged.
+func WithEnvironment(name string) LoadOption {
+	return func(s *loadSettings) {
+		s.environment = name
+	}
+}
+
+// OverlayPath returns the path of the overlay of a config file for an
+// environment, e.g. config.prod.yaml for config.yaml and prod.
+func OverlayPath(path, environment string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + environment + ext
+}
+
+// applyOverlay merges the overlay of a config file for an environment over
+// the decoded file.
+func applyOverlay(path, environment string, base map[string]any) (map[string]any, error) {
+	if !environmentNamePattern.MatchString(environment) {
+		return nil, fmt.Errorf("environment name %q must start with a letter and contain only letters, digits, and '-'", environment)
+	}
+
+	overlayPath := OverlayPath(path, environment)
+	overlayData, err := os.ReadFile(overlayPath) //nolint:gosec // G304: overlay path is derived from the config path
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s overlay: %w", environment, err)
+	}
+	var overlay map[string]any
+	if err := yaml.Unmarshal(overlayData, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", overlayPath, err)
+	}
+
+	if _, ok := overlay["stackName"]; !ok {
+		if stackName, ok := base["stackName"].(string); ok && stackName != "" {
+			base["stackName"] = stackName + "-" + environment
+		}
+	}
+	return mergeValues(base, overlay).(map[string]any), nil
+}
+
+// mergeValues merges overlay over base. Objects are merged recursively, lists
+// of named entries are merged by name, and anything else is replaced.
+func mergeValues(base, overlay any) any {
+	switch overlayValue := overlay.(type) {
+	case map[string]any:
+		baseMap, ok := base.(map[string]any)
+		if !ok {
+			return overlayValue
+		}
+		merged := make(map[string]any, len(baseMap)+len(overlayValue))
+		for key, value := range baseMap {
+			merged[key] = value
+		}
+		for key, value := range overlayValue {
+			merged[key] = mergeValues(baseMap[key], value)
+		}
+		return merged
+	case []any:
+		baseList, ok := base.([]any)
+		if !ok || !namedEntries(baseList) || !namedEntries(overlayValue) {
+			return overlayValue
+		}
+		merged := append([]any(nil), baseList...)
+		for _, entry := range overlayValue {
+			name := entry.(map[string]any)["name"]
+			i := indexOfName(merged, name)
+			if i < 0 {
+				merged = append(merged, entry)
+				continue
+			}
+			merged[i] = mergeValues(merged[i], entry)
+		}
+		return merged
+	default:
+		return overlay
+	}
+}
+
+// namedEntries reports whether every entry of a list is an object with a
+// name.
+func namedEntries(list []any) bool {
+	for _, entry := range list {
+		object, ok := entry.(map[string]any)
+		if !ok {
+			return false
+		}
+		if _, ok := object["name"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// indexOfName returns the index of the named entry, or -1.
+func indexOfName(list []any, name any) int {
+	for i, entry := range list {
+		if entry.(map[string]any)["name"] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// contextEnvironment returns the environment selected by the CDK context.
+func contextEnvironment(scope constructs.Construct) string {
+	value, ok := scope.Node().TryGetContext(jsii.String(EnvironmentContextKey)).(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
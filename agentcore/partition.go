@@ -0,0 +1,104 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Partition strategies.
+const (
+	// PartitionSingle creates all resources in one stack.
+	PartitionSingle = "single"
+
+	// PartitionPerAgent creates each agent's resources in its own nested
+	// stack.
+	PartitionPerAgent = "per-agent"
+
+	// PartitionGrouped creates the agents' resources in nested stacks of up
+	// to PartitionOptions.GroupSize agents each.
+	PartitionGrouped = "grouped"
+)
+
+// defaultPartitionGroupSize is the number of agents per nested stack of
+// the grouped strategy.
+const defaultPartitionGroupSize = 10
+
+// PartitionOptions splits a large agent fleet across nested stacks, keeping
+// each template under CloudFormation's resource limit. The networking, IAM,
+// secrets, gateway, and outputs stay in the parent (base) stack; each
+// agent's runtime, endpoints, memory, contract, config parameter,
+// schedules, and triggers move to a nested stack. References between the
+// stacks become nested stack parameters and outputs, and the stack is
+// still deployed with a single cdk deploy.
+type PartitionOptions struct {
+	// Strategy is "single", "per-agent", or "grouped".
+	// Default: "single"
+	Strategy string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+
+	// GroupSize is the number of agents per nested stack of the grouped
+	// strategy, in config order.
+	// Range: 1-100
+	// Default: 10
+	GroupSize int `json:"groupSize,omitempty" yaml:"groupSize,omitempty"`
+}
+
+// validate validates the partition options.
+func (o *PartitionOptions) validate() error {
+	switch o.Strategy {
+	case "", PartitionSingle, PartitionPerAgent, PartitionGrouped:
+	default:
+		return fmt.Errorf("partition.strategy must be %s, %s, or %s, got %q",
+			PartitionSingle, PartitionPerAgent, PartitionGrouped, o.Strategy)
+	}
+	if o.GroupSize < 0 || o.GroupSize > 100 {
+		return fmt.Errorf("partition.groupSize must be between 1 and 100, got %d", o.GroupSize)
+	}
+	if o.GroupSize != 0 && o.Strategy != PartitionGrouped {
+		return fmt.Errorf("partition.groupSize requires the %s strategy", PartitionGrouped)
+	}
+	return nil
+}
+
+// groupSize returns the number of agents per nested stack.
+func (o *PartitionOptions) groupSize() int {
+	if o.GroupSize > 0 {
+		return o.GroupSize
+	}
+	return defaultPartitionGroupSize
+}
+
+// agentScope returns the scope an agent's resources are created in: the
+// stack itself, or the nested stack of the agent's partition.
+func (s *AgentCoreStack) agentScope(agentName string) constructs.Construct {
+	opts := s.Options.Partition
+	if opts == nil || opts.Strategy == "" || opts.Strategy == PartitionSingle {
+		return s.Stack
+	}
+	if nested, ok := s.AgentStacks[agentName]; ok {
+		return nested
+	}
+
+	id := fmt.Sprintf("AgentStack-%s", agentName)
+	if opts.Strategy == PartitionGrouped {
+		index := 0
+		for i, agent := range s.Config.Agents {
+			if agent.Name == agentName {
+				index = i
+				break
+			}
+		}
+		id = fmt.Sprintf("AgentStack-%d", index/opts.groupSize()+1)
+	}
+
+	nested, ok := s.Stack.Node().TryFindChild(jsii.String(id)).(awscdk.NestedStack)
+	if !ok {
+		nested = awscdk.NewNestedStack(s.Stack, jsii.String(id), &awscdk.NestedStackProps{
+			Description: jsii.String(fmt.Sprintf("Agents of %s", s.Config.StackName)),
+		})
+	}
+	s.AgentStacks[agentName] = nested
+	return nested
+}
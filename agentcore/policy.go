@@ -0,0 +1,218 @@
+package agentcore
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Built-in policies, selected by name in StackOptions.Policies.
+const (
+	// PolicyNoPublicNetwork rejects agents in PUBLIC network mode.
+	PolicyNoPublicNetwork = "no-public-network"
+
+	// PolicyLogRetention90Days rejects CloudWatch log groups kept for less
+	// than 90 days.
+	PolicyLogRetention90Days = "log-retention-90-days"
+
+	// PolicyNoWildcardIAM rejects permissions on all Bedrock models and
+	// broad AWS managed policies such as AdministratorAccess.
+	PolicyNoWildcardIAM = "no-wildcard-iam"
+)
+
+// minPolicyLogRetentionDays is the retention PolicyLogRetention90Days
+// requires.
+const minPolicyLogRetentionDays = 90
+
+// Violation is a breach of a policy by a stack configuration.
+type Violation struct {
+	// Policy is the name of the violated policy. It is set by the policy
+	// engine.
+	Policy string `json:"policy"`
+
+	// Path locates the offending setting in the config file, e.g.
+	// "agents[0].networkMode".
+	Path string `json:"path,omitempty"`
+
+	// Message describes the violation.
+	Message string `json:"message"`
+}
+
+// String returns the violation as "[policy] path: message".
+func (v Violation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("[%s] %s", v.Policy, v.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", v.Policy, v.Path, v.Message)
+}
+
+// PolicyFunc checks a stack configuration against a rule and returns its
+// violations.
+type PolicyFunc func(config StackConfig, options StackOptions) []Violation
+
+var (
+	policiesMu sync.RWMutex
+	policies   = map[string]PolicyFunc{
+		PolicyNoPublicNetwork:    noPublicNetworkPolicy,
+		PolicyLogRetention90Days: logRetentionPolicy,
+		PolicyNoWildcardIAM:      noWildcardIAMPolicy,
+	}
+)
+
+// RegisterPolicy makes a policy selectable by name in StackOptions.Policies,
+// so platform teams can enforce organization rules from a shared package,
+// typically in its init function. It panics if the name is empty or
+// already registered.
+func RegisterPolicy(name string, policy PolicyFunc) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	if name == "" || policy == nil {
+		panic("agentcore: RegisterPolicy needs a name and a policy")
+	}
+	if _, exists := policies[name]; exists {
+		panic(fmt.Sprintf("agentcore: policy %q is already registered", name))
+	}
+	policies[name] = policy
+}
+
+// RegisteredPolicies returns the names of the built-in and registered
+// policies, sorted.
+func RegisteredPolicies() []string {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	return sortedKeys(policies)
+}
+
+// CheckPolicies runs the policies selected by options.Policies against a
+// stack configuration. It returns an error if a selected policy is not
+// registered.
+func CheckPolicies(config StackConfig, options StackOptions) ([]Violation, error) {
+	policiesMu.RLock()
+	selected := make([]PolicyFunc, len(options.Policies))
+	for i, name := range options.Policies {
+		policy, ok := policies[name]
+		if !ok {
+			registered := sortedKeys(policies)
+			policiesMu.RUnlock()
+			return nil, fmt.Errorf("policies[%d]: unknown policy %q (registered: %s)", i, name, strings.Join(registered, ", "))
+		}
+		selected[i] = policy
+	}
+	policiesMu.RUnlock()
+
+	var violations []Violation
+	for i, policy := range selected {
+		for _, violation := range policy(config, options) {
+			violation.Policy = options.Policies[i]
+			violations = append(violations, violation)
+		}
+	}
+	return violations, nil
+}
+
+// checkPolicies reports the violations of the selected policies as synth
+// errors, which fail `cdk synth` and `cdk deploy`.
+func (s *AgentCoreStack) checkPolicies() {
+	if len(s.Options.Policies) == 0 {
+		return
+	}
+	violations, err := CheckPolicies(s.Config, s.Options)
+	if err != nil {
+		awscdk.Annotations_Of(s.Stack).AddError(jsii.String(err.Error()))
+		return
+	}
+	for _, violation := range violations {
+		awscdk.Annotations_Of(s.Stack).AddError(jsii.String(violation.String()))
+	}
+}
+
+// noPublicNetworkPolicy implements PolicyNoPublicNetwork.
+func noPublicNetworkPolicy(config StackConfig, options StackOptions) []Violation {
+	var violations []Violation
+	for i, agent := range config.Agents {
+		if opts := options.Agents[agent.Name]; opts != nil && opts.NetworkMode == NetworkModePublic {
+			violations = append(violations, Violation{
+				Path:    fmt.Sprintf("agents[%d].networkMode", i),
+				Message: fmt.Sprintf("agent %s runs in %s network mode; use %s", agent.Name, NetworkModePublic, NetworkModeVPC),
+			})
+		}
+	}
+	return violations
+}
+
+// logRetentionPolicy implements PolicyLogRetention90Days.
+func logRetentionPolicy(config StackConfig, _ StackOptions) []Violation {
+	if config.Observability == nil || !config.Observability.EnableCloudWatchLogs {
+		return nil
+	}
+	retentionDays := config.Observability.LogRetentionDays
+	if retentionDays == 0 {
+		retentionDays = 30 // Default of createLogGroup
+	}
+	if retentionDays >= minPolicyLogRetentionDays {
+		return nil
+	}
+	return []Violation{{
+		Path:    "observability.logRetentionDays",
+		Message: fmt.Sprintf("logs are kept for %d days; at least %d are required", retentionDays, minPolicyLogRetentionDays),
+	}}
+}
+
+// broadManagedPolicies are AWS managed policies granting (nearly) all
+// actions.
+var broadManagedPolicies = map[string]bool{
+	"AdministratorAccess": true,
+	"PowerUserAccess":     true,
+}
+
+// noWildcardIAMPolicy implements PolicyNoWildcardIAM.
+func noWildcardIAMPolicy(config StackConfig, _ StackOptions) []Violation {
+	if config.IAM == nil {
+		return nil
+	}
+	var violations []Violation
+	if config.IAM.EnableBedrockAccess && config.IAM.RoleARN == "" {
+		if len(config.IAM.BedrockModelIDs) == 0 {
+			violations = append(violations, Violation{
+				Path:    "iam.bedrockModelIds",
+				Message: "the execution role may invoke every Bedrock model; list the models the agents use",
+			})
+		}
+		for i, modelID := range config.IAM.BedrockModelIDs {
+			if strings.Contains(modelID, "*") {
+				violations = append(violations, Violation{
+					Path:    fmt.Sprintf("iam.bedrockModelIds[%d]", i),
+					Message: fmt.Sprintf("model ID %q is a wildcard", modelID),
+				})
+			}
+		}
+	}
+	for i, arn := range config.IAM.AdditionalPolicies {
+		name, ok := awsManagedPolicyName(arn)
+		if ok && (broadManagedPolicies[name] || strings.HasSuffix(name, "FullAccess")) {
+			violations = append(violations, Violation{
+				Path:    fmt.Sprintf("iam.additionalPolicies[%d]", i),
+				Message: fmt.Sprintf("AWS managed policy %s grants all actions of a service or more", name),
+			})
+		}
+	}
+	return violations
+}
+
+// awsManagedPolicyName returns the name of an AWS managed policy ARN, such
+// as arn:aws:iam::aws:policy/AdministratorAccess.
+func awsManagedPolicyName(arn string) (string, bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "iam" || parts[4] != "aws" {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(parts[5], "policy/")
+	if !ok {
+		return "", false
+	}
+	// Managed policies may sit under a path, e.g. policy/job-function/...
+	return name[strings.LastIndex(name, "/")+1:], true
+}
@@ -0,0 +1,168 @@
+package agentcore
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Agent runtime protocols, set in AgentConfig.Protocol.
+const (
+	// ProtocolHTTP serves the agent's own HTTP API (default).
+	ProtocolHTTP = "HTTP"
+
+	// ProtocolMCP serves an MCP server. MCP agents are registered as
+	// targets of the stack's gateway.
+	ProtocolMCP = "MCP"
+
+	// ProtocolA2A serves the Agent2Agent protocol, with the agent card at
+	// /.well-known/agent-card.json.
+	ProtocolA2A = "A2A"
+)
+
+// protocols are the valid agent runtime protocols.
+var protocols = []string{ProtocolHTTP, ProtocolMCP, ProtocolA2A}
+
+// validateProtocols checks the agents' protocols, suggesting the valid
+// spelling of a protocol in the wrong case.
+func validateProtocols(agents []AgentConfig) error {
+	for i, agent := range agents {
+		if agent.Protocol == "" {
+			continue
+		}
+		valid := false
+		for _, protocol := range protocols {
+			if agent.Protocol == protocol {
+				valid = true
+				break
+			}
+		}
+		if valid {
+			continue
+		}
+		for _, protocol := range protocols {
+			if strings.EqualFold(agent.Protocol, protocol) {
+				return fmt.Errorf("agents[%d] (%s): protocol %q must be one of %s (did you mean %q?)",
+					i, agent.Name, agent.Protocol, strings.Join(protocols, ", "), protocol)
+			}
+		}
+		return fmt.Errorf("agents[%d] (%s): protocol %q must be one of %s",
+			i, agent.Name, agent.Protocol, strings.Join(protocols, ", "))
+	}
+	return nil
+}
+
+// mcpGatewayTargetName returns the gateway target name of an MCP agent.
+// Agent names may contain underscores, which target names may not.
+func mcpGatewayTargetName(agentName string) string {
+	return strings.ReplaceAll(agentName, "_", "-")
+}
+
+// mcpGatewayTargets returns the gateway target names of the stack's MCP
+// agents, which are registered with the gateway when it is enabled.
+func mcpGatewayTargets(config StackConfig) []string {
+	if config.Gateway == nil || !config.Gateway.Enabled {
+		return nil
+	}
+	var names []string
+	for _, agent := range config.Agents {
+		if agent.Protocol == ProtocolMCP {
+			names = append(names, mcpGatewayTargetName(agent.Name))
+		}
+	}
+	return names
+}
+
+// runtimeURL returns the AgentCore data plane URL of a path under the
+// invocations of one of the stack's runtimes.
+func (s *AgentCoreStack) runtimeURL(runtime awsbedrockagentcore.CfnRuntime, path string) string {
+	// The runtime ARN is URL-encoded in the path; build it from its parts
+	// since tokens can't be escaped at synth time
+	escapedARN := fmt.Sprintf("arn%%3A%s%%3Abedrock-agentcore%%3A%s%%3A%s%%3Aruntime%%2F%s",
+		*s.Stack.Partition(), *s.Stack.Region(), *s.Stack.Account(), *runtime.AttrAgentRuntimeId())
+	return fmt.Sprintf("https://bedrock-agentcore.%s.%s/runtimes/%s/invocations%s",
+		*s.Stack.Region(), *s.Stack.UrlSuffix(), escapedARN, path)
+}
+
+// createMCPGatewayTargets registers the stack's MCP agents as targets of its
+// gateway and grants the gateway role permission to invoke them.
+func (s *AgentCoreStack) createMCPGatewayTargets() {
+	if s.Gateway == nil {
+		return
+	}
+
+	var runtimeARNs []*string
+	for i := range s.Config.Agents {
+		config := &s.Config.Agents[i]
+		if s.getProtocol(config) != ProtocolMCP {
+			continue
+		}
+		runtime := s.Runtimes[config.Name]
+		name := mcpGatewayTargetName(config.Name)
+		qualifier := defaultEndpointName(config.Name, s.Options.Agents[config.Name])
+
+		gatewayTarget := awsbedrockagentcore.NewCfnGatewayTarget(s.Stack,
+			jsii.String(fmt.Sprintf("GatewayTarget-%s", name)),
+			&awsbedrockagentcore.CfnGatewayTargetProps{
+				Name:              jsii.String(name),
+				Description:       jsii.String(fmt.Sprintf("MCP agent %s", config.Name)),
+				GatewayIdentifier: s.Gateway.AttrGatewayIdentifier(),
+				TargetConfiguration: &awsbedrockagentcore.CfnGatewayTarget_TargetConfigurationProperty{
+					Mcp: &awsbedrockagentcore.CfnGatewayTarget_McpTargetConfigurationProperty{
+						McpServer: &awsbedrockagentcore.CfnGatewayTarget_McpServerTargetConfigurationProperty{
+							Endpoint: jsii.String(s.runtimeURL(runtime, "?qualifier="+url.QueryEscape(qualifier))),
+						},
+					},
+				},
+				CredentialProviderConfigurations: &[]interface{}{
+					&awsbedrockagentcore.CfnGatewayTarget_CredentialProviderConfigurationProperty{
+						CredentialProviderType: jsii.String("GATEWAY_IAM_ROLE"),
+					},
+				},
+			},
+		)
+		// The qualifier names the endpoint without referencing it
+		gatewayTarget.Node().AddDependency(s.Endpoints[config.Name])
+		s.GatewayTargets[name] = gatewayTarget
+
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("GatewayTarget-%s-Id", name)),
+			&awscdk.CfnOutputProps{
+				Value:       gatewayTarget.AttrTargetId(),
+				Description: jsii.String(fmt.Sprintf("Gateway target ID for MCP agent %s", config.Name)),
+			})
+
+		runtimeARNs = append(runtimeARNs,
+			runtime.AttrAgentRuntimeArn(),
+			jsii.String(fmt.Sprintf("%s/*", *runtime.AttrAgentRuntimeArn())),
+		)
+	}
+	if len(runtimeARNs) == 0 {
+		return
+	}
+
+	// The gateway invokes targets with its own role (the execution role).
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("bedrock-agentcore:InvokeAgentRuntime"),
+		Resources: &runtimeARNs,
+	}))
+}
+
+// addAgentCardOutput exports the agent card URL of an A2A agent.
+func (s *AgentCoreStack) addAgentCardOutput(config *AgentConfig) {
+	if s.getProtocol(config) != ProtocolA2A {
+		return
+	}
+	awscdk.NewCfnOutput(s.Stack,
+		jsii.String(fmt.Sprintf("Agent-%s-AgentCardUrl", config.Name)),
+		&awscdk.CfnOutputProps{
+			Value:       jsii.String(s.runtimeURL(s.Runtimes[config.Name], "/.well-known/agent-card.json")),
+			Description: jsii.String(fmt.Sprintf("A2A agent card URL for agent %s", config.Name)),
+		})
+}
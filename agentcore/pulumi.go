@@ -0,0 +1,619 @@
+package agentcore
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/jsii-runtime-go"
+	"gopkg.in/yaml.v3"
+)
+
+// pulumiProvider is the Pulumi provider the exported program uses. Its
+// resource types mirror CloudFormation's, so every resource of the
+// synthesized template maps to one of its resources.
+const pulumiProvider = "aws-native"
+
+// pseudoParameterVariables maps CloudFormation pseudo parameters to the
+// variables of the exported program holding their values.
+var pseudoParameterVariables = map[string]struct {
+	name, function, result string
+}{
+	"AWS::Region":    {"awsRegion", "getRegion", "region"},
+	"AWS::AccountId": {"awsAccountId", "getAccountId", "accountId"},
+	"AWS::Partition": {"awsPartition", "getPartition", "partition"},
+	"AWS::URLSuffix": {"awsUrlSuffix", "getUrlSuffix", "urlSuffix"},
+}
+
+// freeformProperties are properties whose values are JSON documents or maps
+// with user-defined keys. Their keys are exported as they are instead of in
+// Pulumi casing.
+var freeformProperties = map[string]bool{
+	"AssumeRolePolicyDocument": true,
+	"EnvironmentVariables":     true,
+	"EventPattern":             true,
+	"KeyPolicy":                true,
+	"PolicyDocument":           true,
+	"ResourcePolicy":           true,
+	"Variables":                true,
+}
+
+// assetPattern matches the names of the CDK bootstrap asset bucket and
+// repository, which the exported program can't publish assets to.
+var assetPattern = regexp.MustCompile(`cdk-[a-z0-9]+-(assets|container-assets)-`)
+
+// pulumiProgram is a Pulumi YAML program.
+type pulumiProgram struct {
+	Name        string                    `yaml:"name"`
+	Runtime     string                    `yaml:"runtime"`
+	Description string                    `yaml:"description,omitempty"`
+	Config      map[string]pulumiConfig   `yaml:"config,omitempty"`
+	Variables   map[string]interface{}    `yaml:"variables,omitempty"`
+	Resources   map[string]pulumiResource `yaml:"resources"`
+	Outputs     map[string]interface{}    `yaml:"outputs,omitempty"`
+}
+
+// pulumiConfig is a configuration value of a Pulumi YAML program.
+type pulumiConfig struct {
+	Type    string      `yaml:"type"`
+	Default interface{} `yaml:"default,omitempty"`
+}
+
+// pulumiResource is a resource of a Pulumi YAML program.
+type pulumiResource struct {
+	Type       string                 `yaml:"type"`
+	Properties map[string]interface{} `yaml:"properties,omitempty"`
+	Options    *pulumiResourceOptions `yaml:"options,omitempty"`
+}
+
+// pulumiResourceOptions are the resource options of a Pulumi resource.
+type pulumiResourceOptions struct {
+	DependsOn      []string `yaml:"dependsOn,omitempty"`
+	RetainOnDelete bool     `yaml:"retainOnDelete,omitempty"`
+}
+
+// GeneratePulumiYAML returns a Pulumi YAML program creating the resources
+// of the stack, so teams on Pulumi can deploy agents from the same
+// configuration. See GeneratePulumiYAMLWithOptions.
+func GeneratePulumiYAML(config StackConfig) ([]byte, error) {
+	return GeneratePulumiYAMLWithOptions(config, StackOptions{})
+}
+
+// GeneratePulumiYAMLWithOptions returns a Pulumi YAML program creating the
+// resources of the stack with CDK-specific options.
+//
+// The stack is synthesized and each resource of its template becomes a
+// resource of the aws-native provider, with property names in Pulumi
+// casing. References become ${resource.id} for Ref and
+// ${resource.attribute} for Fn::GetAtt, stack parameters become config
+// values, and AWS::IAM::Policy resources, which aws-native doesn't support,
+// become one aws-native:iam:RolePolicy per role. Stacks using conditions,
+// custom resources, CDK assets, or nested stacks (Options.Partition) can't
+// be exported, and an error lists what stands in the way.
+func GeneratePulumiYAMLWithOptions(config StackConfig, options StackOptions) (program []byte, err error) {
+	// Stack construction panics on invalid configuration
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("synthesizing stack: %v", r)
+		}
+	}()
+
+	outdir, err := os.MkdirTemp("", "agentcore-pulumi-")
+	if err != nil {
+		return nil, fmt.Errorf("creating synth directory: %w", err)
+	}
+	defer os.RemoveAll(outdir)
+
+	app := awscdk.NewApp(&awscdk.AppProps{
+		Outdir: jsii.String(outdir),
+		Context: &map[string]interface{}{
+			"@aws-cdk/core:newStyleStackSynthesis": true,
+			"aws:cdk:version-reporting":            false,
+		},
+	})
+	s := NewAgentCoreStackWithOptions(app, config.StackName, config, options)
+	template, ok := app.Synth(nil).GetStackArtifact(s.ArtifactId()).Template().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("synthesized template of stack %s is not an object", s.Config.StackName)
+	}
+
+	converted, err := newPulumiConverter(template).convert(s.Config.StackName)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(converted); err != nil {
+		return nil, fmt.Errorf("failed to generate YAML: %w", err)
+	}
+
+	header := fmt.Sprintf(`# Pulumi program generated by agentkit-aws-cdk
+# Stack: %s
+#
+# Deploy with:
+#   pulumi stack init dev
+#   pulumi config set aws-native:region us-east-1
+#   pulumi up
+
+`, s.Config.StackName)
+	return append([]byte(header), buf.Bytes()...), nil
+}
+
+// GeneratePulumiYAMLFile generates a Pulumi YAML program and writes it to a
+// file, usually Pulumi.yaml.
+func GeneratePulumiYAMLFile(config StackConfig, options StackOptions, outputPath string) error {
+	program, err := GeneratePulumiYAMLWithOptions(config, options)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, program, 0o600)
+}
+
+// pulumiConverter converts a synthesized CloudFormation template to a
+// Pulumi YAML program.
+type pulumiConverter struct {
+	resources  map[string]interface{}
+	parameters map[string]interface{}
+	mappings   map[string]interface{}
+	outputs    map[string]interface{}
+	variables  map[string]interface{} // Pseudo parameter variables in use
+	problems   []string               // What can't be converted
+	logicalID  string                 // Resource or output being converted
+}
+
+// newPulumiConverter returns a converter of a template.
+func newPulumiConverter(template map[string]interface{}) *pulumiConverter {
+	c := &pulumiConverter{variables: make(map[string]interface{})}
+	c.resources, _ = template["Resources"].(map[string]interface{})
+	c.parameters, _ = template["Parameters"].(map[string]interface{})
+	c.mappings, _ = template["Mappings"].(map[string]interface{})
+	c.outputs, _ = template["Outputs"].(map[string]interface{})
+	return c
+}
+
+// problem records something that can't be converted.
+func (c *pulumiConverter) problem(format string, args ...interface{}) {
+	c.problems = append(c.problems, c.logicalID+": "+fmt.Sprintf(format, args...))
+}
+
+// convert converts the template.
+func (c *pulumiConverter) convert(stackName string) (*pulumiProgram, error) {
+	program := &pulumiProgram{
+		Name:        stackName,
+		Runtime:     "yaml",
+		Description: fmt.Sprintf("AgentCore agents of stack %s, exported by agentkit-aws-cdk", stackName),
+		Config:      make(map[string]pulumiConfig),
+		Resources:   make(map[string]pulumiResource),
+		Outputs:     make(map[string]interface{}),
+	}
+
+	for _, name := range sortedKeys(c.parameters) {
+		// The bootstrap version check of the CDK doesn't apply to Pulumi
+		if name == "BootstrapVersion" {
+			continue
+		}
+		c.logicalID = name
+		parameter, _ := c.parameters[name].(map[string]interface{})
+		cfg := pulumiConfig{Default: parameter["Default"]}
+		switch parameter["Type"] {
+		case "String":
+			cfg.Type = "string"
+		case "Number":
+			cfg.Type = "integer"
+		case "CommaDelimitedList":
+			cfg.Type = "List<String>"
+		default:
+			c.problem("parameter type %v isn't supported", parameter["Type"])
+		}
+		program.Config[name] = cfg
+	}
+
+	for _, id := range sortedKeys(c.resources) {
+		c.logicalID = id
+		resource, _ := c.resources[id].(map[string]interface{})
+		resourceType, _ := resource["Type"].(string)
+		switch {
+		case resourceType == "AWS::CDK::Metadata":
+			continue
+		case resourceType == "AWS::CloudFormation::Stack":
+			c.problem("nested stacks (options.partition) aren't supported")
+			continue
+		case strings.HasPrefix(resourceType, "Custom::"), strings.HasPrefix(resourceType, "AWS::CloudFormation::"):
+			c.problem("resource type %s isn't supported", resourceType)
+			continue
+		}
+		if _, ok := resource["Condition"]; ok {
+			c.problem("conditional resources aren't supported")
+		}
+
+		options := &pulumiResourceOptions{}
+		for _, dependency := range stringList(resource["DependsOn"]) {
+			options.DependsOn = append(options.DependsOn, c.resourceNames(dependency)...)
+		}
+		switch resource["DeletionPolicy"] {
+		case "Retain", "RetainExceptOnCreate", "Snapshot":
+			options.RetainOnDelete = true
+		}
+		if len(options.DependsOn) == 0 && !options.RetainOnDelete {
+			options = nil
+		}
+
+		properties, _ := resource["Properties"].(map[string]interface{})
+		if resourceType == "AWS::IAM::Policy" {
+			c.convertPolicy(program, id, properties, options)
+			continue
+		}
+		program.Resources[id] = pulumiResource{
+			Type:       pulumiType(resourceType),
+			Properties: c.convertProperties(properties),
+			Options:    options,
+		}
+	}
+
+	for _, name := range sortedKeys(c.outputs) {
+		c.logicalID = name
+		output, _ := c.outputs[name].(map[string]interface{})
+		if _, ok := output["Condition"]; ok {
+			c.problem("conditional outputs aren't supported")
+		}
+		program.Outputs[name] = c.convertValue(output["Value"], false)
+	}
+
+	if len(c.problems) > 0 {
+		return nil, fmt.Errorf("stack %s can't be exported to Pulumi:\n  %s", stackName, strings.Join(c.problems, "\n  "))
+	}
+	if len(c.variables) > 0 {
+		program.Variables = c.variables
+	}
+	return program, nil
+}
+
+// convertPolicy converts an AWS::IAM::Policy, which attaches one policy to
+// several principals, to an aws-native:iam:RolePolicy per role.
+func (c *pulumiConverter) convertPolicy(program *pulumiProgram, id string, properties map[string]interface{}, options *pulumiResourceOptions) {
+	if properties["Users"] != nil || properties["Groups"] != nil {
+		c.problem("policies attached to users or groups aren't supported")
+	}
+	roles, _ := properties["Roles"].([]interface{})
+	for i, role := range roles {
+		program.Resources[c.policyName(id, i, len(roles))] = pulumiResource{
+			Type: pulumiProvider + ":iam:RolePolicy",
+			Properties: map[string]interface{}{
+				"policyDocument": c.convertValue(properties["PolicyDocument"], false),
+				"policyName":     c.convertValue(properties["PolicyName"], false),
+				"roleName":       c.convertValue(role, false),
+			},
+			Options: options,
+		}
+	}
+}
+
+// policyName returns the name of the RolePolicy of the i-th of n roles of an
+// AWS::IAM::Policy.
+func (c *pulumiConverter) policyName(id string, i, n int) string {
+	if n == 1 {
+		return id
+	}
+	return fmt.Sprintf("%s%d", id, i+1)
+}
+
+// resourceNames returns the names of the Pulumi resources a logical ID
+// converts to: several for an AWS::IAM::Policy attached to several roles.
+func (c *pulumiConverter) resourceNames(id string) []string {
+	resource, _ := c.resources[id].(map[string]interface{})
+	if resource["Type"] == "AWS::IAM::Policy" {
+		properties, _ := resource["Properties"].(map[string]interface{})
+		roles, _ := properties["Roles"].([]interface{})
+		names := make([]string, 0, len(roles))
+		for i := range roles {
+			names = append(names, "${"+c.policyName(id, i, len(roles))+"}")
+		}
+		return names
+	}
+	return []string{"${" + id + "}"}
+}
+
+// convertProperties converts the properties of a resource to Pulumi casing.
+func (c *pulumiConverter) convertProperties(properties map[string]interface{}) map[string]interface{} {
+	if len(properties) == 0 {
+		return nil
+	}
+	converted, _ := c.convertValue(properties, true).(map[string]interface{})
+	return converted
+}
+
+// convertValue converts a template value, converting the keys of objects to
+// Pulumi casing if pulumiKeys is set and their intrinsic functions to
+// Pulumi YAML expressions. AWS::NoValue converts to nil, and the keys
+// holding it are dropped.
+func (c *pulumiConverter) convertValue(v interface{}, pulumiKeys bool) interface{} {
+	switch v := v.(type) {
+	case string:
+		if assetPattern.MatchString(v) {
+			c.problem("CDK assets aren't supported")
+		}
+		return escapeInterpolation(v)
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if converted := c.convertValue(item, pulumiKeys); converted != nil {
+				items = append(items, converted)
+			}
+		}
+		return items
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for name, args := range v {
+				if name == "Ref" || strings.HasPrefix(name, "Fn::") || name == "Condition" {
+					return c.convertIntrinsic(name, args)
+				}
+			}
+		}
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			keepKeys := pulumiKeys && !freeformProperties[key]
+			// Tags given as a map have user-defined keys
+			if _, ok := value.(map[string]interface{}); ok && key == "Tags" {
+				keepKeys = false
+			}
+			if value = c.convertValue(value, keepKeys); value == nil {
+				continue
+			}
+			if pulumiKeys {
+				key = lowerCamel(key)
+			}
+			converted[key] = value
+		}
+		return converted
+	default:
+		return v
+	}
+}
+
+// convertIntrinsic converts an intrinsic function to a Pulumi YAML
+// expression.
+func (c *pulumiConverter) convertIntrinsic(name string, args interface{}) interface{} {
+	list, _ := args.([]interface{})
+	switch name {
+	case "Ref":
+		ref, _ := args.(string)
+		return c.reference(ref, "")
+	case "Fn::GetAtt":
+		var id, attribute string
+		if s, ok := args.(string); ok {
+			id, attribute, _ = strings.Cut(s, ".")
+		} else if len(list) == 2 {
+			id, _ = list[0].(string)
+			attribute, _ = list[1].(string)
+		}
+		return c.reference(id, attribute)
+	case "Fn::Join":
+		if len(list) != 2 {
+			break
+		}
+		separator, _ := list[0].(string)
+		separator = escapeInterpolation(separator)
+		parts, _ := c.convertValue(list[1], false).([]interface{})
+		if joined, ok := joinStrings(parts, separator); ok {
+			return joined
+		}
+		return map[string]interface{}{"fn::join": []interface{}{separator, parts}}
+	case "Fn::Sub":
+		return c.convertSub(args)
+	case "Fn::Select":
+		if len(list) != 2 {
+			break
+		}
+		return map[string]interface{}{"fn::select": []interface{}{list[0], c.convertValue(list[1], false)}}
+	case "Fn::Split":
+		if len(list) != 2 {
+			break
+		}
+		return map[string]interface{}{"fn::split": []interface{}{list[0], c.convertValue(list[1], false)}}
+	case "Fn::Base64":
+		return map[string]interface{}{"fn::toBase64": c.convertValue(args, false)}
+	case "Fn::ToJsonString":
+		return map[string]interface{}{"fn::toJSON": c.convertValue(args, false)}
+	case "Fn::GetAZs":
+		return c.invoke("getAzs", nil, "azs")
+	case "Fn::ImportValue":
+		return c.invoke("importValue", map[string]interface{}{"name": c.convertValue(args, false)}, "value")
+	case "Fn::FindInMap":
+		if value, ok := c.findInMap(list); ok {
+			return c.convertValue(value, false)
+		}
+		c.problem("Fn::FindInMap with computed keys isn't supported")
+		return nil
+	}
+	if name == "Fn::If" {
+		c.problem("conditions aren't supported (agent versions use them to follow the latest version)")
+		return nil
+	}
+	c.problem("%s isn't supported", name)
+	return nil
+}
+
+// reference returns the expression of a Ref (attribute empty) or
+// Fn::GetAtt: a config value, a pseudo parameter variable, the ID of a
+// resource, or an attribute of one.
+func (c *pulumiConverter) reference(id, attribute string) interface{} {
+	if attribute == "" {
+		if id == "AWS::NoValue" {
+			return nil
+		}
+		if id == "AWS::StackName" {
+			return "${pulumi.stack}"
+		}
+		if pseudo, ok := pseudoParameterVariables[id]; ok {
+			c.variables[pseudo.name] = map[string]interface{}{
+				"fn::invoke": map[string]interface{}{
+					"function": pulumiProvider + ":index:" + pseudo.function,
+					"return":   pseudo.result,
+				},
+			}
+			return "${" + pseudo.name + "}"
+		}
+		if _, ok := c.parameters[id]; ok {
+			return "${" + id + "}"
+		}
+	}
+	if _, ok := c.resources[id]; !ok {
+		c.problem("reference to %s isn't supported", id)
+		return nil
+	}
+	if attribute == "" {
+		return "${" + id + ".id}"
+	}
+	path := strings.Split(attribute, ".")
+	for i, part := range path {
+		path[i] = lowerCamel(part)
+	}
+	return "${" + id + "." + strings.Join(path, ".") + "}"
+}
+
+// subVariablePattern matches the variables of an Fn::Sub string.
+var subVariablePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// convertSub converts an Fn::Sub to a string interpolating its variables,
+// or to an fn::join of its parts if a variable isn't a string.
+func (c *pulumiConverter) convertSub(args interface{}) interface{} {
+	format, _ := args.(string)
+	var values map[string]interface{}
+	if list, ok := args.([]interface{}); ok && len(list) == 2 {
+		format, _ = list[0].(string)
+		values, _ = list[1].(map[string]interface{})
+	}
+
+	var parts []interface{}
+	last := 0
+	for _, match := range subVariablePattern.FindAllStringSubmatchIndex(format, -1) {
+		parts = append(parts, escapeInterpolation(format[last:match[0]]))
+		last = match[1]
+		variable := format[match[2]:match[3]]
+		switch {
+		case strings.HasPrefix(variable, "!"): // ${!Literal}
+			parts = append(parts, escapeInterpolation("${"+variable[1:]+"}"))
+		case values[variable] != nil:
+			parts = append(parts, c.convertValue(values[variable], false))
+		default:
+			id, attribute, _ := strings.Cut(variable, ".")
+			parts = append(parts, c.reference(id, attribute))
+		}
+	}
+	parts = append(parts, escapeInterpolation(format[last:]))
+
+	if joined, ok := joinStrings(parts, ""); ok {
+		return joined
+	}
+	return map[string]interface{}{"fn::join": []interface{}{"", parts}}
+}
+
+// invoke returns the value of a function of the provider, e.g. getAzs.
+func (c *pulumiConverter) invoke(function string, arguments map[string]interface{}, result string) interface{} {
+	invoke := map[string]interface{}{
+		"function": pulumiProvider + ":index:" + function,
+		"return":   result,
+	}
+	if arguments != nil {
+		invoke["arguments"] = arguments
+	}
+	return map[string]interface{}{"fn::invoke": invoke}
+}
+
+// findInMap returns the value of an Fn::FindInMap whose keys are literals.
+func (c *pulumiConverter) findInMap(args []interface{}) (interface{}, bool) {
+	if len(args) != 3 {
+		return nil, false
+	}
+	var value interface{} = c.mappings
+	for _, key := range args {
+		name, ok := key.(string)
+		if !ok {
+			return nil, false
+		}
+		m, _ := value.(map[string]interface{})
+		if value, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// pulumiType converts a CloudFormation resource type to the aws-native type,
+// e.g. AWS::EC2::VPCEndpoint to aws-native:ec2:VpcEndpoint.
+func pulumiType(resourceType string) string {
+	parts := strings.Split(resourceType, "::")
+	if len(parts) != 3 {
+		return resourceType
+	}
+	return pulumiProvider + ":" + strings.ToLower(parts[1]) + ":" + collapseAcronyms(parts[2])
+}
+
+// lowerCamel converts a CloudFormation property name to Pulumi casing, e.g.
+// KMSMasterKeyId to kmsMasterKeyId.
+func lowerCamel(name string) string {
+	name = collapseAcronyms(name)
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// collapseAcronyms lowercases all but the first letter of acronyms, e.g.
+// VPCEndpoint to VpcEndpoint.
+func collapseAcronyms(name string) string {
+	r := []rune(name)
+	out := make([]rune, len(r))
+	for i, ch := range r {
+		if i > 0 && unicode.IsUpper(ch) && unicode.IsUpper(r[i-1]) && (i+1 == len(r) || !unicode.IsLower(r[i+1])) {
+			ch = unicode.ToLower(ch)
+		}
+		out[i] = ch
+	}
+	return string(out)
+}
+
+// escapeInterpolation escapes literal ${ in a string, which Pulumi YAML
+// would otherwise interpolate.
+func escapeInterpolation(s string) string {
+	return strings.ReplaceAll(s, "${", "$${")
+}
+
+// joinStrings joins parts if they are all strings.
+func joinStrings(parts []interface{}, separator string) (string, bool) {
+	strs := make([]string, 0, len(parts))
+	for _, part := range parts {
+		s, ok := part.(string)
+		if !ok {
+			return "", false
+		}
+		strs = append(strs, s)
+	}
+	return strings.Join(strs, separator), true
+}
+
+// stringList returns a string or list of strings as a list.
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		sort.Strings(strs)
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package agentcore
+
+// ResourceCounts are the numbers of quota-limited resources a stack
+// creates, for checking them against the account's service quotas before
+// deploying.
+type ResourceCounts struct {
+	// Runtimes is the number of agent runtimes.
+	Runtimes int `json:"runtimes"`
+
+	// RuntimeEndpoints is the number of runtime endpoints by agent: the
+	// default endpoint, the named endpoints, and a blue/green candidate.
+	RuntimeEndpoints map[string]int `json:"runtimeEndpoints"`
+
+	// InterfaceEndpoints is the number of interface VPC endpoints in the
+	// VPC the stack creates.
+	InterfaceEndpoints int `json:"interfaceEndpoints"`
+
+	// Secrets is the number of Secrets Manager secrets.
+	Secrets int `json:"secrets"`
+}
+
+// CountResources returns the numbers of quota-limited resources the stack
+// of config and options creates.
+func CountResources(config StackConfig, options StackOptions) ResourceCounts {
+	s := &AgentCoreStack{Config: config, Options: options}
+	counts := ResourceCounts{
+		Runtimes:         len(config.Agents),
+		RuntimeEndpoints: make(map[string]int, len(config.Agents)),
+	}
+
+	for _, agent := range config.Agents {
+		endpoints := 1
+		if opts := options.Agents[agent.Name]; opts != nil {
+			endpoints += len(opts.Endpoints)
+			if opts.BlueGreen != nil {
+				endpoints++
+			}
+		}
+		counts.RuntimeEndpoints[agent.Name] = endpoints
+	}
+
+	// Mirrors createVPCEndpoints
+	if vpc := config.VPC; vpc != nil && vpc.CreateVPC && vpc.VPCID == "" && !options.VPC.lookupByTags() &&
+		vpc.EnableVPCEndpoints && s.needsVPC() {
+		counts.InterfaceEndpoints = 6
+		if options.Secrets.usesSSM() {
+			counts.InterfaceEndpoints++
+		}
+	}
+
+	if createsSecret(config) {
+		counts.Secrets = 1
+	}
+	return counts
+}
@@ -0,0 +1,147 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Dependency reference prefixes for raw resources.
+const (
+	rawDependencyAgent   = "agent:"
+	rawDependencyMemory  = "memory:"
+	rawDependencyTarget  = "gatewayTarget:"
+	rawDependencyGateway = "gateway"
+)
+
+// rawResourceIDPattern keeps raw resource IDs alphanumeric so the
+// CloudFormation logical ID equals the ID and can be used in Ref/Fn::GetAtt.
+var rawResourceIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]{0,254}$`)
+
+// rawResourceTypePattern matches CloudFormation resource type names.
+var rawResourceTypePattern = regexp.MustCompile(`^[A-Za-z0-9]+::[A-Za-z0-9]+::[A-Za-z0-9]+$`)
+
+// validateRawResources validates raw resource specs and their dependencies.
+func validateRawResources(resources []RawResource, config StackConfig, options StackOptions) error {
+	ids := make(map[string]bool)
+	for _, resource := range resources {
+		ids[resource.ID] = true
+	}
+
+	seen := make(map[string]bool)
+	for i, resource := range resources {
+		if !rawResourceIDPattern.MatchString(resource.ID) {
+			return fmt.Errorf("rawResources[%d]: id %q must be alphanumeric and start with a letter", i, resource.ID)
+		}
+		if seen[resource.ID] {
+			return fmt.Errorf("rawResources[%d]: duplicate id %s", i, resource.ID)
+		}
+		seen[resource.ID] = true
+
+		if !rawResourceTypePattern.MatchString(resource.Type) {
+			return fmt.Errorf("rawResources[%d] (%s): type %q must look like AWS::Service::Resource", i, resource.ID, resource.Type)
+		}
+
+		for _, ref := range resource.DependsOn {
+			if ref == resource.ID {
+				return fmt.Errorf("rawResources[%d] (%s): resource cannot depend on itself", i, resource.ID)
+			}
+			if !ids[ref] && !isStackDependency(ref, config, options) {
+				return fmt.Errorf("rawResources[%d] (%s): unknown dependency %q", i, resource.ID, ref)
+			}
+		}
+	}
+	return nil
+}
+
+// isStackDependency reports whether ref names a resource created by the stack.
+func isStackDependency(ref string, config StackConfig, options StackOptions) bool {
+	switch {
+	case ref == rawDependencyGateway:
+		return config.Gateway != nil && config.Gateway.Enabled
+	case strings.HasPrefix(ref, rawDependencyAgent):
+		name := strings.TrimPrefix(ref, rawDependencyAgent)
+		for _, agent := range config.Agents {
+			if agent.Name == name {
+				return true
+			}
+		}
+	case strings.HasPrefix(ref, rawDependencyMemory):
+		name := strings.TrimPrefix(ref, rawDependencyMemory)
+		for _, agent := range config.Agents {
+			if agent.Name == name {
+				return resolveMemoryStore(agent, options.Agents[name]) != nil
+			}
+		}
+	case strings.HasPrefix(ref, rawDependencyTarget):
+		name := strings.TrimPrefix(ref, rawDependencyTarget)
+		for _, target := range mcpGatewayTargets(config) {
+			if target == name {
+				return true
+			}
+		}
+		if options.Gateway == nil {
+			return false
+		}
+		for _, target := range options.Gateway.RemoteTargets {
+			if target.Name == name {
+				return true
+			}
+		}
+		for _, target := range options.Gateway.ToolTargets {
+			if target.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// createRawResources emits the raw resource specs as CfnResources, for
+// AgentCore resource types the pinned aws-cdk-go version doesn't model yet.
+// Resources are created after the rest of the stack so they can depend on it.
+func (s *AgentCoreStack) createRawResources() {
+	for _, spec := range s.Options.RawResources {
+		s.RawResources[spec.ID] = awscdk.NewCfnResource(s.Stack, jsii.String(spec.ID), &awscdk.CfnResourceProps{
+			Type:       jsii.String(spec.Type),
+			Properties: &spec.Properties,
+		})
+	}
+
+	for _, spec := range s.Options.RawResources {
+		resource := s.RawResources[spec.ID]
+		for _, ref := range spec.DependsOn {
+			for _, dependency := range s.rawDependency(ref) {
+				resource.Node().AddDependency(dependency)
+			}
+		}
+	}
+}
+
+// rawDependency resolves a raw resource dependency reference to constructs.
+func (s *AgentCoreStack) rawDependency(ref string) []constructs.IConstruct {
+	if resource, ok := s.RawResources[ref]; ok {
+		return []constructs.IConstruct{resource}
+	}
+
+	switch {
+	case ref == rawDependencyGateway:
+		return []constructs.IConstruct{s.Gateway}
+	case strings.HasPrefix(ref, rawDependencyAgent):
+		name := strings.TrimPrefix(ref, rawDependencyAgent)
+		deps := []constructs.IConstruct{s.Runtimes[name], s.Endpoints[name]}
+		for _, endpoint := range s.NamedEndpoints[name] {
+			deps = append(deps, endpoint)
+		}
+		return deps
+	case strings.HasPrefix(ref, rawDependencyMemory):
+		return []constructs.IConstruct{s.Memories[strings.TrimPrefix(ref, rawDependencyMemory)]}
+	case strings.HasPrefix(ref, rawDependencyTarget):
+		return []constructs.IConstruct{s.GatewayTargets[strings.TrimPrefix(ref, rawDependencyTarget)]}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticache"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// RedisEnvVar is the environment variable holding the URL of the cache.
+const RedisEnvVar = "REDIS_URL"
+
+// RedisReaderEnvVar is the environment variable holding the URL of the
+// cache's read replicas.
+const RedisReaderEnvVar = "REDIS_READER_URL"
+
+// Cache engines of RedisOptions.Engine.
+const (
+	RedisEngineRedis  = "redis"
+	RedisEngineValkey = "valkey"
+)
+
+// maxRedisReplicas is the number of read replicas a replication group may
+// have.
+const maxRedisReplicas = 5
+
+var (
+	// cacheNodeTypePattern matches ElastiCache node types, e.g.
+	// cache.t4g.small.
+	cacheNodeTypePattern = regexp.MustCompile(`^cache\.[a-z0-9]+\.[a-z0-9]+$`)
+
+	// cacheNamePattern is the naming rule for serverless caches and
+	// replication groups.
+	cacheNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+)
+
+// RedisOptions creates an ElastiCache cache the agents share, e.g. for rate
+// limits and memoized tool results. It runs in the stack's VPC, accepts
+// connections only from the agent security group, and requires TLS. Every
+// agent in VPC network mode gets its URL in REDIS_URL.
+type RedisOptions struct {
+	// Name is the serverless cache name or replication group ID, 1-40
+	// lowercase letters, digits, and '-'.
+	// Default: "{stackName}-cache" for a serverless cache, generated for
+	// a replication group
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Serverless creates an ElastiCache Serverless cache instead of a
+	// node-based replication group. NodeType and Replicas must be empty.
+	Serverless bool `json:"serverless,omitempty" yaml:"serverless,omitempty"`
+
+	// NodeType is the node type of a node-based cache, e.g.
+	// "cache.t4g.small".
+	NodeType string `json:"nodeType,omitempty" yaml:"nodeType,omitempty"`
+
+	// Replicas is the number of read replicas of a node-based cache, 0-5.
+	// Replicas enable Multi-AZ with automatic failover, and agents get
+	// their reader endpoint in REDIS_READER_URL.
+	Replicas int `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+
+	// Engine is "redis" or "valkey".
+	// Default: "redis"
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty"`
+
+	// EngineVersion is the engine version, e.g. "7.1", or the major
+	// version of a serverless cache, e.g. "7".
+	// Default: the latest version
+	EngineVersion string `json:"engineVersion,omitempty" yaml:"engineVersion,omitempty"`
+
+	// MaxDataGB caps the data stored in a serverless cache.
+	// Default: no limit
+	MaxDataGB int `json:"maxDataGb,omitempty" yaml:"maxDataGb,omitempty"`
+}
+
+// engine returns the cache engine, defaulted.
+func (o *RedisOptions) engine() string {
+	if o.Engine == "" {
+		return RedisEngineRedis
+	}
+	return o.Engine
+}
+
+// name returns the cache name, or "" for a generated one.
+func (o *RedisOptions) name(stackName string) string {
+	if o.Name == "" && o.Serverless {
+		return strings.ToLower(fmt.Sprintf("%s-cache", stackName))
+	}
+	return o.Name
+}
+
+// validate validates the cache options.
+func (o *RedisOptions) validate(config StackConfig, options StackOptions) error {
+	switch o.engine() {
+	case RedisEngineRedis, RedisEngineValkey:
+	default:
+		return fmt.Errorf("redis.engine must be %s or %s, got %q", RedisEngineRedis, RedisEngineValkey, o.Engine)
+	}
+	if name := o.name(config.StackName); name != "" && (len(name) > 40 || !cacheNamePattern.MatchString(name)) {
+		return fmt.Errorf("redis: cache name %q must be 1-40 lowercase letters, digits, or single '-', starting with a letter; set redis.name", name)
+	}
+
+	if o.Serverless {
+		switch {
+		case o.NodeType != "" || o.Replicas != 0:
+			return fmt.Errorf("redis.nodeType and redis.replicas don't apply to a serverless cache")
+		case o.MaxDataGB < 0 || o.MaxDataGB > 5000:
+			return fmt.Errorf("redis.maxDataGb must be 1-5000, got %d", o.MaxDataGB)
+		}
+	} else {
+		switch {
+		case o.NodeType == "":
+			return fmt.Errorf("redis.nodeType is required unless redis.serverless is set")
+		case !*awscdk.Token_IsUnresolved(o.NodeType) && !cacheNodeTypePattern.MatchString(o.NodeType):
+			return fmt.Errorf("redis.nodeType %q must be an ElastiCache node type such as cache.t4g.small", o.NodeType)
+		case o.Replicas < 0 || o.Replicas > maxRedisReplicas:
+			return fmt.Errorf("redis.replicas must be 0-%d, got %d", maxRedisReplicas, o.Replicas)
+		case o.MaxDataGB != 0:
+			return fmt.Errorf("redis.maxDataGb requires redis.serverless")
+		}
+	}
+
+	if !costNeedsVPC(config, options) {
+		return fmt.Errorf("redis requires an agent in networkMode %s: the cache is only reachable from the stack's VPC", NetworkModeVPC)
+	}
+	return nil
+}
+
+// createRedis creates the cache in the private subnets of the VPC with a
+// security group open to the agent security group, and outputs its URL.
+func (s *AgentCoreStack) createRedis() {
+	opts := s.Options.Redis
+	if opts == nil || s.VPC == nil || s.SecurityGroup == nil {
+		return
+	}
+
+	cacheSecurityGroup := awsec2.NewSecurityGroup(s.Stack, jsii.String("RedisSecurityGroup"), &awsec2.SecurityGroupProps{
+		Vpc:              s.VPC,
+		Description:      jsii.String(fmt.Sprintf("Cache of %s AgentCore agents", s.Config.StackName)),
+		AllowAllOutbound: jsii.Bool(false),
+	})
+	cacheSecurityGroup.AddIngressRule(s.SecurityGroup, awsec2.Port_Tcp(jsii.Number(6379)), jsii.String("Allow agents to reach the cache"), jsii.Bool(false))
+	if opts.Serverless {
+		// Serverless caches also serve reads from replicas on port 6380
+		cacheSecurityGroup.AddIngressRule(s.SecurityGroup, awsec2.Port_Tcp(jsii.Number(6380)), jsii.String("Allow agents to read from the cache replicas"), jsii.Bool(false))
+	}
+
+	if opts.Serverless {
+		props := &awselasticache.CfnServerlessCacheProps{
+			ServerlessCacheName: jsii.String(opts.name(s.Config.StackName)),
+			Description:         jsii.String(fmt.Sprintf("Cache of %s AgentCore agents", s.Config.StackName)),
+			Engine:              jsii.String(opts.engine()),
+			SecurityGroupIds:    &[]interface{}{cacheSecurityGroup.SecurityGroupId()},
+			SubnetIds:           stringsToInterfaces(s.getPrivateSubnetIds()),
+		}
+		if opts.EngineVersion != "" {
+			props.MajorEngineVersion = jsii.String(opts.EngineVersion)
+		}
+		if opts.MaxDataGB > 0 {
+			props.CacheUsageLimits = &awselasticache.CfnServerlessCache_CacheUsageLimitsProperty{
+				DataStorage: &awselasticache.CfnServerlessCache_DataStorageProperty{
+					Maximum: jsii.Number(float64(opts.MaxDataGB)),
+					Unit:    jsii.String("GB"),
+				},
+			}
+		}
+		if s.KMSKey != nil {
+			props.KmsKeyId = s.KMSKey.KeyId()
+		}
+		if s.cacheRemovalPolicy() == RemovalPolicySnapshot {
+			props.FinalSnapshotName = jsii.String(opts.name(s.Config.StackName) + "-final")
+		}
+		cache := awselasticache.NewCfnServerlessCache(s.Stack, jsii.String("Redis"), props)
+		if s.cacheRemovalPolicy() == RemovalPolicyRetain {
+			cache.ApplyRemovalPolicy(awscdk.RemovalPolicy_RETAIN, nil)
+		}
+		s.RedisURL = jsii.String(fmt.Sprintf("rediss://%s:%s", *cache.AttrEndpointAddress(), *cache.AttrEndpointPort()))
+	} else {
+		subnetGroup := awselasticache.NewCfnSubnetGroup(s.Stack, jsii.String("RedisSubnetGroup"), &awselasticache.CfnSubnetGroupProps{
+			Description: jsii.String(fmt.Sprintf("Cache subnets of %s AgentCore agents", s.Config.StackName)),
+			SubnetIds:   stringsToInterfaces(s.getPrivateSubnetIds()),
+		})
+		props := &awselasticache.CfnReplicationGroupProps{
+			ReplicationGroupDescription: jsii.String(fmt.Sprintf("Cache of %s AgentCore agents", s.Config.StackName)),
+			Engine:                      jsii.String(opts.engine()),
+			CacheNodeType:               jsii.String(opts.NodeType),
+			NumCacheClusters:            jsii.Number(float64(1 + opts.Replicas)),
+			AutomaticFailoverEnabled:    jsii.Bool(opts.Replicas > 0),
+			MultiAzEnabled:              jsii.Bool(opts.Replicas > 0),
+			CacheSubnetGroupName:        subnetGroup.Ref(),
+			SecurityGroupIds:            &[]*string{cacheSecurityGroup.SecurityGroupId()},
+			AtRestEncryptionEnabled:     jsii.Bool(true),
+			TransitEncryptionEnabled:    jsii.Bool(true),
+		}
+		if opts.Name != "" {
+			props.ReplicationGroupId = jsii.String(opts.Name)
+		}
+		if opts.EngineVersion != "" {
+			props.EngineVersion = jsii.String(opts.EngineVersion)
+		}
+		if s.KMSKey != nil {
+			props.KmsKeyId = s.KMSKey.KeyArn()
+		}
+		cache := awselasticache.NewCfnReplicationGroup(s.Stack, jsii.String("Redis"), props)
+		s.RedisURL = jsii.String(fmt.Sprintf("rediss://%s:%s", *cache.AttrPrimaryEndPointAddress(), *cache.AttrPrimaryEndPointPort()))
+		if opts.Replicas > 0 {
+			s.RedisReaderURL = jsii.String(fmt.Sprintf("rediss://%s:%s", *cache.AttrReaderEndPointAddress(), *cache.AttrReaderEndPointPort()))
+		}
+	}
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("RedisUrl"), &awscdk.CfnOutputProps{
+		Value:       s.RedisURL,
+		Description: jsii.String("URL of the cache shared by the agents"),
+	})
+}
+
+// stringsToInterfaces converts a string slice for properties typed as
+// []interface{}.
+func stringsToInterfaces(values *[]*string) *[]interface{} {
+	result := make([]interface{}, len(*values))
+	for i, v := range *values {
+		result[i] = v
+	}
+	return &result
+}
+
+// addRedisEnvironment passes the cache to an agent in VPC network mode;
+// agents in PUBLIC mode can't reach it.
+func (s *AgentCoreStack) addRedisEnvironment(config *AgentConfig, envVars map[string]string) {
+	if s.RedisURL == nil || s.getNetworkMode(config) != NetworkModeVPC {
+		return
+	}
+	envVars[RedisEnvVar] = *s.RedisURL
+	if s.RedisReaderURL != nil {
+		envVars[RedisReaderEnvVar] = *s.RedisReaderURL
+	}
+}
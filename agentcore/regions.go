@@ -0,0 +1,244 @@
+package agentcore
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// RegionInfo is what the stack knows about an AWS region. The matrix is a
+// snapshot: AgentCore launches in new regions over time, so a region
+// missing here may still work (see StackOptions.SkipRegionCheck).
+type RegionInfo struct {
+	// Name is the region code, e.g. "us-east-1".
+	Name string `json:"name"`
+
+	// Location is the region's display name, e.g. "US East (N. Virginia)".
+	Location string `json:"location"`
+
+	// Partition is the AWS partition: "aws", "aws-us-gov", or "aws-cn".
+	Partition string `json:"partition"`
+
+	// AgentCore is set if Bedrock AgentCore Runtime, Gateway, Memory, and
+	// Identity are available in the region.
+	AgentCore bool `json:"agentCore"`
+
+	// InferenceProfiles are the geography prefixes of the cross-region
+	// inference profiles callable from the region, e.g. "us.".
+	InferenceProfiles []string `json:"inferenceProfiles"`
+
+	latitude, longitude float64
+}
+
+// regions is the region capability matrix.
+var regions = []RegionInfo{
+	{Name: "us-east-1", Location: "US East (N. Virginia)", AgentCore: true, latitude: 38.9, longitude: -77.4},
+	{Name: "us-east-2", Location: "US East (Ohio)", AgentCore: true, latitude: 40.0, longitude: -83.0},
+	{Name: "us-west-1", Location: "US West (N. California)", latitude: 37.4, longitude: -122.0},
+	{Name: "us-west-2", Location: "US West (Oregon)", AgentCore: true, latitude: 45.8, longitude: -119.7},
+	{Name: "ca-central-1", Location: "Canada (Central)", latitude: 45.5, longitude: -73.6},
+	{Name: "ca-west-1", Location: "Canada West (Calgary)", latitude: 51.0, longitude: -114.1},
+	{Name: "mx-central-1", Location: "Mexico (Central)", latitude: 20.6, longitude: -100.4},
+	{Name: "sa-east-1", Location: "South America (São Paulo)", latitude: -23.5, longitude: -46.6},
+	{Name: "eu-west-1", Location: "Europe (Ireland)", AgentCore: true, latitude: 53.3, longitude: -6.3},
+	{Name: "eu-west-2", Location: "Europe (London)", latitude: 51.5, longitude: -0.1},
+	{Name: "eu-west-3", Location: "Europe (Paris)", latitude: 48.9, longitude: 2.4},
+	{Name: "eu-central-1", Location: "Europe (Frankfurt)", AgentCore: true, latitude: 50.1, longitude: 8.7},
+	{Name: "eu-central-2", Location: "Europe (Zurich)", latitude: 47.4, longitude: 8.5},
+	{Name: "eu-north-1", Location: "Europe (Stockholm)", latitude: 59.3, longitude: 18.1},
+	{Name: "eu-south-1", Location: "Europe (Milan)", latitude: 45.5, longitude: 9.2},
+	{Name: "eu-south-2", Location: "Europe (Spain)", latitude: 41.6, longitude: -0.9},
+	{Name: "il-central-1", Location: "Israel (Tel Aviv)", latitude: 32.1, longitude: 34.8},
+	{Name: "me-south-1", Location: "Middle East (Bahrain)", latitude: 26.1, longitude: 50.6},
+	{Name: "me-central-1", Location: "Middle East (UAE)", latitude: 25.2, longitude: 55.3},
+	{Name: "af-south-1", Location: "Africa (Cape Town)", latitude: -33.9, longitude: 18.4},
+	{Name: "ap-south-1", Location: "Asia Pacific (Mumbai)", AgentCore: true, latitude: 19.1, longitude: 72.9},
+	{Name: "ap-south-2", Location: "Asia Pacific (Hyderabad)", latitude: 17.4, longitude: 78.5},
+	{Name: "ap-east-1", Location: "Asia Pacific (Hong Kong)", latitude: 22.3, longitude: 114.2},
+	{Name: "ap-southeast-1", Location: "Asia Pacific (Singapore)", AgentCore: true, latitude: 1.35, longitude: 103.8},
+	{Name: "ap-southeast-2", Location: "Asia Pacific (Sydney)", AgentCore: true, latitude: -33.9, longitude: 151.2},
+	{Name: "ap-southeast-3", Location: "Asia Pacific (Jakarta)", latitude: -6.2, longitude: 106.8},
+	{Name: "ap-southeast-4", Location: "Asia Pacific (Melbourne)", latitude: -37.8, longitude: 145.0},
+	{Name: "ap-southeast-5", Location: "Asia Pacific (Malaysia)", latitude: 3.1, longitude: 101.7},
+	{Name: "ap-southeast-7", Location: "Asia Pacific (Thailand)", latitude: 13.7, longitude: 100.5},
+	{Name: "ap-northeast-1", Location: "Asia Pacific (Tokyo)", AgentCore: true, latitude: 35.7, longitude: 139.7},
+	{Name: "ap-northeast-2", Location: "Asia Pacific (Seoul)", latitude: 37.6, longitude: 127.0},
+	{Name: "ap-northeast-3", Location: "Asia Pacific (Osaka)", latitude: 34.7, longitude: 135.5},
+	{Name: "us-gov-west-1", Location: "AWS GovCloud (US-West)", latitude: 45.8, longitude: -119.7},
+	{Name: "us-gov-east-1", Location: "AWS GovCloud (US-East)", latitude: 40.0, longitude: -83.0},
+	{Name: "cn-north-1", Location: "China (Beijing)", latitude: 39.9, longitude: 116.4},
+	{Name: "cn-northwest-1", Location: "China (Ningxia)", latitude: 37.5, longitude: 105.2},
+}
+
+func init() {
+	for i := range regions {
+		regions[i].Partition = regionPartition(regions[i].Name)
+		regions[i].InferenceProfiles = regionInferenceProfiles(regions[i].Name)
+	}
+}
+
+// regionPartition returns the partition of a region code.
+func regionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	default:
+		return "aws"
+	}
+}
+
+// regionInferenceProfiles returns the geography prefixes of the
+// cross-region inference profiles callable from a region.
+func regionInferenceProfiles(region string) []string {
+	var profiles []string
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return []string{"us-gov."}
+	case strings.HasPrefix(region, "cn-"):
+		return nil
+	case strings.HasPrefix(region, "us-"):
+		profiles = []string{"us."}
+	case strings.HasPrefix(region, "eu-"):
+		profiles = []string{"eu."}
+	case strings.HasPrefix(region, "ap-"):
+		profiles = []string{"apac."}
+		switch region {
+		case "ap-northeast-1", "ap-northeast-3":
+			profiles = append(profiles, "jp.")
+		case "ap-southeast-2", "ap-southeast-4":
+			profiles = append(profiles, "au.")
+		}
+	}
+	return append(profiles, "global.")
+}
+
+// Regions returns the region capability matrix.
+func Regions() []RegionInfo {
+	out := make([]RegionInfo, len(regions))
+	copy(out, regions)
+	return out
+}
+
+// LookupRegion returns what the matrix knows about a region.
+func LookupRegion(name string) (RegionInfo, bool) {
+	for _, region := range regions {
+		if region.Name == name {
+			return region, true
+		}
+	}
+	return RegionInfo{}, false
+}
+
+// AgentCoreRegions returns the regions where Bedrock AgentCore is
+// available.
+func AgentCoreRegions() []string {
+	var names []string
+	for _, region := range regions {
+		if region.AgentCore {
+			names = append(names, region.Name)
+		}
+	}
+	return names
+}
+
+// NearestAgentCoreRegion returns the closest region of the same partition
+// where Bedrock AgentCore is available, or "" if the region is unknown or
+// its partition has none.
+func NearestAgentCoreRegion(name string) string {
+	from, ok := LookupRegion(name)
+	if !ok {
+		return ""
+	}
+	nearest, best := "", math.Inf(1)
+	for _, region := range regions {
+		if !region.AgentCore || region.Partition != from.Partition {
+			continue
+		}
+		if d := greatCircleDistance(from, region); d < best {
+			nearest, best = region.Name, d
+		}
+	}
+	return nearest
+}
+
+// greatCircleDistance returns the distance between two regions in
+// kilometers.
+func greatCircleDistance(a, b RegionInfo) float64 {
+	const earthRadius = 6371
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := rad(b.latitude-a.latitude), rad(b.longitude-a.longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(a.latitude))*math.Cos(rad(b.latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// ValidateRegion checks that Bedrock AgentCore is available in a region,
+// suggesting the nearest region where it is. Regions missing from the
+// matrix pass.
+func ValidateRegion(name string) error {
+	region, ok := LookupRegion(name)
+	if !ok || region.AgentCore {
+		return nil
+	}
+	msg := fmt.Sprintf("Bedrock AgentCore is not available in %s (%s)", region.Name, region.Location)
+	if nearest := NearestAgentCoreRegion(name); nearest != "" {
+		info, _ := LookupRegion(nearest)
+		msg += fmt.Sprintf("; the nearest supported region is %s (%s)", nearest, info.Location)
+	} else {
+		msg += fmt.Sprintf("; no region of the %s partition supports it (supported regions: %s)", region.Partition, strings.Join(AgentCoreRegions(), ", "))
+	}
+	return errors.New(msg)
+}
+
+// ValidateModelRegion checks that a model ID can be invoked from a region:
+// the geography of a cross-region inference profile, e.g. "us.", must be
+// one of the region's. Foundation model IDs and unknown regions pass.
+func ValidateModelRegion(modelID, name string) error {
+	region, ok := LookupRegion(name)
+	if !ok {
+		return nil
+	}
+	prefix := ""
+	for _, p := range inferenceProfilePrefixes {
+		if strings.HasPrefix(modelID, p) {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" {
+		return nil
+	}
+	for _, p := range region.InferenceProfiles {
+		if p == prefix {
+			return nil
+		}
+	}
+	if len(region.InferenceProfiles) == 0 {
+		return fmt.Errorf("%s is a cross-region inference profile, which %s doesn't offer; use the foundation model ID %s", modelID, name, FoundationModelID(modelID))
+	}
+	return fmt.Errorf("%s is an inference profile of the %s geography, which can't be invoked from %s; use %s%s", modelID, strings.TrimSuffix(prefix, "."), name, region.InferenceProfiles[0], FoundationModelID(modelID))
+}
+
+// validateStackRegion checks the region the stack is synthesized for, the
+// CDK_DEFAULT_REGION cdk sets from the AWS profile, against the matrix.
+func validateStackRegion(config StackConfig, options StackOptions) error {
+	region := os.Getenv("CDK_DEFAULT_REGION")
+	if region == "" || options.SkipRegionCheck {
+		return nil
+	}
+	if err := ValidateRegion(region); err != nil {
+		return fmt.Errorf("%w (set skipRegionCheck if it has launched there since)", err)
+	}
+	if config.IAM == nil {
+		return nil
+	}
+	for i, modelID := range config.IAM.BedrockModelIDs {
+		if err := ValidateModelRegion(modelID, region); err != nil {
+			return fmt.Errorf("iam.bedrockModelIds[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Removal policies of StackConfig.RemovalPolicy and RemovalPolicyOptions.
+const (
+	// RemovalPolicyDestroy deletes the resources with the stack.
+	RemovalPolicyDestroy = "destroy"
+
+	// RemovalPolicyRetain keeps the resources when the stack is deleted
+	// or they are removed from it.
+	RemovalPolicyRetain = "retain"
+
+	// RemovalPolicySnapshot deletes the resources after taking a snapshot,
+	// for resource classes that support snapshots.
+	RemovalPolicySnapshot = "snapshot"
+)
+
+// RemovalPolicyOptions sets the removal policy of resource classes
+// independently of the stack-wide StackConfig.RemovalPolicy, e.g. to keep
+// the logs of a production stack after `cdk destroy`. Empty fields use
+// the defaults documented on each field.
+type RemovalPolicyOptions struct {
+	// LogGroups applies to the stack's CloudWatch log groups.
+	// Default: retain, even for stacks that destroy their resources
+	LogGroups string `json:"logGroups,omitempty" yaml:"logGroups,omitempty"`
+
+	// Secrets applies to the Secrets Manager secrets the stack creates.
+	// Default: retain, even for stacks that destroy their resources
+	Secrets string `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+
+	// ECRRepositories applies to ECR repositories created as raw
+	// resources.
+	// Default: retain, even for stacks that destroy their resources
+	ECRRepositories string `json:"ecrRepositories,omitempty" yaml:"ecrRepositories,omitempty"`
+
+	// Cache applies to the ElastiCache cache of RedisOptions and
+	// ElastiCache raw resources. A serverless cache can't be snapshotted by
+	// CloudFormation, so snapshot takes a final snapshot of it on deletion
+	// instead.
+	// Default: snapshot, even for stacks that destroy their resources
+	Cache string `json:"cache,omitempty" yaml:"cache,omitempty"`
+
+	// DeploymentHistory applies to the deployment history table.
+	// Default: retain, even for stacks that destroy their resources, so
+	// the audit log outlives the stack
+	DeploymentHistory string `json:"deploymentHistory,omitempty" yaml:"deploymentHistory,omitempty"`
+
+	// Artifacts applies to the artifact bucket. Destroy empties the bucket
+	// before deleting it.
+	// Default: retain, even for stacks that destroy their resources, so
+	// agent outputs outlive the stack
+	Artifacts string `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+
+	// StateTable applies to the state table.
+	// Default: retain, even for stacks that destroy their resources
+	StateTable string `json:"stateTable,omitempty" yaml:"stateTable,omitempty"`
+
+	// VPC applies to a created VPC and its subnets, gateways, endpoints,
+	// and security groups. Retaining them keeps NAT gateways and endpoints
+	// billing after the stack is gone, so it is only useful if other
+	// resources were attached to the VPC.
+	// Default: destroy, even for stacks that retain their resources
+	VPC string `json:"vpc,omitempty" yaml:"vpc,omitempty"`
+}
+
+// removalPolicyClass is a resource class with its own removal policy.
+type removalPolicyClass struct {
+	field         string
+	resourceTypes []string
+	snapshot      bool // The resource types support the snapshot policy
+	data          bool // The resources hold data the stack's KMS key may encrypt
+	policy        func(o *RemovalPolicyOptions) string
+	defaultPolicy func(stackPolicy string) string
+}
+
+// retainRemovalPolicy is the default of the classes that hold data, which
+// outlives the stack unless a class says otherwise.
+func retainRemovalPolicy(string) string {
+	return RemovalPolicyRetain
+}
+
+// removalPolicyClasses are the resource classes of RemovalPolicyOptions.
+var removalPolicyClasses = []removalPolicyClass{
+	{
+		field:         "logGroups",
+		resourceTypes: []string{"AWS::Logs::LogGroup"},
+		data:          true,
+		policy:        func(o *RemovalPolicyOptions) string { return o.LogGroups },
+		defaultPolicy: retainRemovalPolicy,
+	},
+	{
+		field:         "secrets",
+		resourceTypes: []string{"AWS::SecretsManager::Secret"},
+		data:          true,
+		policy:        func(o *RemovalPolicyOptions) string { return o.Secrets },
+		defaultPolicy: retainRemovalPolicy,
+	},
+	{
+		field:         "ecrRepositories",
+		resourceTypes: []string{"AWS::ECR::Repository"},
+		data:          true,
+		policy:        func(o *RemovalPolicyOptions) string { return o.ECRRepositories },
+		defaultPolicy: retainRemovalPolicy,
+	},
+	{
+		// The serverless cache is handled by createRedis, as CloudFormation
+		// can't snapshot it
+		field:         "cache",
+		resourceTypes: []string{"AWS::ElastiCache::CacheCluster", "AWS::ElastiCache::ReplicationGroup"},
+		snapshot:      true,
+		data:          true,
+		policy:        func(o *RemovalPolicyOptions) string { return o.Cache },
+		defaultPolicy: func(string) string { return RemovalPolicySnapshot },
+	},
+	{
+		field: "vpc",
+		resourceTypes: []string{
+			"AWS::EC2::VPC", "AWS::EC2::Subnet", "AWS::EC2::RouteTable",
+			"AWS::EC2::SubnetRouteTableAssociation", "AWS::EC2::Route",
+			"AWS::EC2::InternetGateway", "AWS::EC2::VPCGatewayAttachment",
+			"AWS::EC2::NatGateway", "AWS::EC2::EIP", "AWS::EC2::VPCEndpoint",
+			"AWS::EC2::SecurityGroup", "AWS::EC2::SecurityGroupIngress",
+			"AWS::EC2::SecurityGroupEgress",
+		},
+		policy:        func(o *RemovalPolicyOptions) string { return o.VPC },
+		defaultPolicy: func(string) string { return RemovalPolicyDestroy },
+	},
+}
+
+// validateStackRemovalPolicy validates the stack-wide removal policy.
+func validateStackRemovalPolicy(policy string) error {
+	switch policy {
+	case "", RemovalPolicyDestroy, RemovalPolicyRetain:
+		return nil
+	}
+	return fmt.Errorf("removalPolicy must be %s or %s, got %q", RemovalPolicyDestroy, RemovalPolicyRetain, policy)
+}
+
+// validate validates the removal policies of the resource classes.
+func (o *RemovalPolicyOptions) validate() error {
+	for _, class := range removalPolicyClasses {
+		switch policy := class.policy(o); policy {
+		case "", RemovalPolicyDestroy, RemovalPolicyRetain:
+		case RemovalPolicySnapshot:
+			if !class.snapshot {
+				return fmt.Errorf("removalPolicies.%s: %s is not supported by %s resources; use %s", class.field, policy, class.field, RemovalPolicyRetain)
+			}
+		default:
+			return fmt.Errorf("removalPolicies.%s must be %s, %s, or %s, got %q", class.field, RemovalPolicyDestroy, RemovalPolicyRetain, RemovalPolicySnapshot, policy)
+		}
+	}
+	switch o.DeploymentHistory {
+	case "", RemovalPolicyDestroy, RemovalPolicyRetain:
+	default:
+		return fmt.Errorf("removalPolicies.deploymentHistory must be %s or %s, got %q", RemovalPolicyDestroy, RemovalPolicyRetain, o.DeploymentHistory)
+	}
+	switch o.Artifacts {
+	case "", RemovalPolicyDestroy, RemovalPolicyRetain:
+	default:
+		return fmt.Errorf("removalPolicies.artifacts must be %s or %s, got %q", RemovalPolicyDestroy, RemovalPolicyRetain, o.Artifacts)
+	}
+	switch o.StateTable {
+	case "", RemovalPolicyDestroy, RemovalPolicyRetain:
+	default:
+		return fmt.Errorf("removalPolicies.stateTable must be %s or %s, got %q", RemovalPolicyDestroy, RemovalPolicyRetain, o.StateTable)
+	}
+	return nil
+}
+
+// cdkRemovalPolicy converts a removal policy to its CDK value.
+func cdkRemovalPolicy(policy string) awscdk.RemovalPolicy {
+	switch policy {
+	case RemovalPolicyRetain:
+		return awscdk.RemovalPolicy_RETAIN
+	case RemovalPolicySnapshot:
+		return awscdk.RemovalPolicy_SNAPSHOT
+	}
+	return awscdk.RemovalPolicy_DESTROY
+}
+
+// removalPolicy returns the stack-wide removal policy, which applies to
+// resources without a resource class.
+func (s *AgentCoreStack) removalPolicy() awscdk.RemovalPolicy {
+	return cdkRemovalPolicy(s.Config.RemovalPolicy)
+}
+
+// classRemovalPolicy returns the removal policy of a resource class, and
+// whether it was set rather than defaulted.
+func (s *AgentCoreStack) classRemovalPolicy(class removalPolicyClass) (string, bool) {
+	if opts := s.Options.RemovalPolicies; opts != nil {
+		if policy := class.policy(opts); policy != "" {
+			return policy, true
+		}
+	}
+	return class.defaultPolicy(s.Config.RemovalPolicy), false
+}
+
+// cacheRemovalPolicy returns the removal policy of the cache.
+func (s *AgentCoreStack) cacheRemovalPolicy() string {
+	for _, class := range removalPolicyClasses {
+		if class.field == "cache" {
+			policy, _ := s.classRemovalPolicy(class)
+			return policy
+		}
+	}
+	return s.Config.RemovalPolicy
+}
+
+// kmsKeyRemovalPolicy returns the removal policy of a created KMS key: the
+// stack-wide policy, but retained while any data it encrypts is retained
+// or snapshotted, so the data stays readable.
+func (s *AgentCoreStack) kmsKeyRemovalPolicy() awscdk.RemovalPolicy {
+	policies := []string{s.artifactBucketRemovalPolicy(), s.deploymentHistoryRemovalPolicy(), s.stateTableRemovalPolicy()}
+	for _, class := range removalPolicyClasses {
+		if class.data {
+			policy, _ := s.classRemovalPolicy(class)
+			policies = append(policies, policy)
+		}
+	}
+	for _, policy := range policies {
+		if policy != RemovalPolicyDestroy {
+			return awscdk.RemovalPolicy_RETAIN
+		}
+	}
+	return s.removalPolicy()
+}
+
+// applyRemovalPolicies applies the removal policy of each resource class to
+// its resources, including those of nested stacks and raw resources.
+// Classes left to a destroy default keep CloudFormation's default deletion,
+// so templates only change where a policy is set.
+func (s *AgentCoreStack) applyRemovalPolicies() {
+	for _, class := range removalPolicyClasses {
+		policy, set := s.classRemovalPolicy(class)
+		if !set && policy == RemovalPolicyDestroy {
+			continue
+		}
+		awscdk.RemovalPolicies_Of(s.Stack).Apply(cdkRemovalPolicy(policy), &awscdk.RemovalPolicyProps{
+			ApplyToResourceTypes: jsii.Strings(class.resourceTypes...),
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package agentcore
+
+import (
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// deletionPolicies synthesizes the stack of b and returns the deletion
+// policy of the resources of each type, and the template.
+func deletionPolicies(t *testing.T, b *StackBuilder, resourceTypes ...string) (map[string]string, assertions.Template) {
+	t.Helper()
+	template := assertions.Template_FromStack(b.Build(awscdk.NewApp(nil)).Stack, nil)
+	policies := make(map[string]string)
+	for _, resourceType := range resourceTypes {
+		for _, resource := range *template.FindResources(jsii.String(resourceType), nil) {
+			policy, _ := (*resource)["DeletionPolicy"].(string)
+			if policy == "" {
+				policy = "Delete"
+			}
+			policies[resourceType] = policy
+		}
+	}
+	return policies, template
+}
+
+func TestRemovalPolicyDefaults(t *testing.T) {
+	vpcAgent := func() *AgentBuilder {
+		return NewAgentBuilder("research", "img").WithNetworkMode(NetworkModeVPC)
+	}
+	types := []string{"AWS::Logs::LogGroup", "AWS::KMS::Key", "AWS::DynamoDB::Table", "AWS::ElastiCache::ReplicationGroup", "AWS::ElastiCache::ServerlessCache"}
+
+	tests := []struct {
+		name  string
+		build func(b *StackBuilder)
+		want  map[string]string
+	}{
+		{
+			// Data outlives the stack even if the stack destroys its
+			// resources, and so does the key that encrypts it
+			name: "defaults",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(vpcAgent()).DestroyOnDelete().
+					WithCustomerManagedKey("removal").WithStateTable("", "").WithRedis("cache.t4g.small", 0)
+			},
+			want: map[string]string{
+				"AWS::Logs::LogGroup":                "Retain",
+				"AWS::KMS::Key":                      "Retain",
+				"AWS::DynamoDB::Table":               "Retain",
+				"AWS::ElastiCache::ReplicationGroup": "Snapshot",
+			},
+		},
+		{
+			name: "destroy everything",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(vpcAgent()).DestroyOnDelete().
+					WithCustomerManagedKey("removal").WithStateTable("", "").WithRedis("cache.t4g.small", 0).
+					WithRemovalPolicies(RemovalPolicyOptions{
+						LogGroups: RemovalPolicyDestroy, Secrets: RemovalPolicyDestroy, ECRRepositories: RemovalPolicyDestroy,
+						Cache: RemovalPolicyDestroy, StateTable: RemovalPolicyDestroy,
+						DeploymentHistory: RemovalPolicyDestroy, Artifacts: RemovalPolicyDestroy,
+					})
+			},
+			want: map[string]string{
+				"AWS::Logs::LogGroup":                "Delete",
+				"AWS::KMS::Key":                      "Delete",
+				"AWS::DynamoDB::Table":               "Delete",
+				"AWS::ElastiCache::ReplicationGroup": "Delete",
+			},
+		},
+		{
+			// CloudFormation can't snapshot a serverless cache
+			name: "serverless cache",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(vpcAgent()).WithRedisOptions(RedisOptions{Serverless: true})
+			},
+			want: map[string]string{
+				"AWS::Logs::LogGroup":               "Retain",
+				"AWS::ElastiCache::ServerlessCache": "Delete",
+			},
+		},
+		{
+			name: "retained serverless cache",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(vpcAgent()).WithRedisOptions(RedisOptions{Serverless: true}).
+					WithRemovalPolicies(RemovalPolicyOptions{Cache: RemovalPolicyRetain})
+			},
+			want: map[string]string{
+				"AWS::Logs::LogGroup":               "Retain",
+				"AWS::ElastiCache::ServerlessCache": "Retain",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewStackBuilder("removal-test")
+			tt.build(b)
+			got, _ := deletionPolicies(t, b, types...)
+			for resourceType, want := range tt.want {
+				if got[resourceType] != want {
+					t.Errorf("%s deletion policy = %q, want %q", resourceType, got[resourceType], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRemovalPolicyServerlessCacheSnapshot(t *testing.T) {
+	b := NewStackBuilder("removal-test").
+		WithAgentBuilder(NewAgentBuilder("research", "img").WithNetworkMode(NetworkModeVPC)).
+		WithRedisOptions(RedisOptions{Serverless: true})
+	_, template := deletionPolicies(t, b)
+	template.HasResourceProperties(jsii.String("AWS::ElastiCache::ServerlessCache"), map[string]interface{}{
+		"FinalSnapshotName": "removal-test-cache-final",
+	})
+}
+
+func TestRemovalPoliciesValidate(t *testing.T) {
+	if err := (&RemovalPolicyOptions{Cache: RemovalPolicySnapshot}).validate(); err != nil {
+		t.Errorf("snapshot cache: %v", err)
+	}
+	if err := (&RemovalPolicyOptions{LogGroups: RemovalPolicySnapshot}).validate(); err == nil {
+		t.Error("snapshot log groups: no error")
+	}
+}
@@ -0,0 +1,117 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Runtime session lifecycle limits of AgentCore, in seconds.
+const (
+	minSessionSeconds = 60
+	maxSessionSeconds = 28800
+
+	// Sessions kept longer than these draw a cost warning during synth
+	idleSessionWarnSeconds     = 3600
+	sessionLifetimeWarnSeconds = 14400
+)
+
+// ScalingOptions configures how long the sessions of an agent's runtime
+// live. AgentCore starts a microVM per session and scales with the number
+// of sessions on its own; it has no provisioned concurrency or session
+// limits per runtime, only the account quota of concurrent sessions. Idle
+// sessions are billed for their memory, so the timeouts bound the cost of a
+// runaway agent.
+type ScalingOptions struct {
+	// IdleSessionTimeoutSeconds ends sessions without requests for this
+	// long, between 60 and 28800.
+	// Default: 900 (the AgentCore default)
+	IdleSessionTimeoutSeconds int `json:"idleSessionTimeoutSeconds,omitempty" yaml:"idleSessionTimeoutSeconds,omitempty"`
+
+	// MaxSessionLifetimeSeconds ends sessions this long after they
+	// started, between 60 and 28800. It overrides the agent's
+	// timeoutSeconds.
+	// Default: timeoutSeconds, or 28800 (the AgentCore default)
+	MaxSessionLifetimeSeconds int `json:"maxSessionLifetimeSeconds,omitempty" yaml:"maxSessionLifetimeSeconds,omitempty"`
+}
+
+// validateScaling validates an agent's scaling options.
+func validateScaling(agent AgentConfig, opts *AgentOptions) error {
+	if opts == nil || opts.Scaling == nil {
+		return nil
+	}
+	scaling := opts.Scaling
+	if scaling.IdleSessionTimeoutSeconds != 0 && (scaling.IdleSessionTimeoutSeconds < minSessionSeconds || scaling.IdleSessionTimeoutSeconds > maxSessionSeconds) {
+		return fmt.Errorf("scaling.idleSessionTimeoutSeconds must be between %d and %d, got %d", minSessionSeconds, maxSessionSeconds, scaling.IdleSessionTimeoutSeconds)
+	}
+	if scaling.MaxSessionLifetimeSeconds != 0 && (scaling.MaxSessionLifetimeSeconds < minSessionSeconds || scaling.MaxSessionLifetimeSeconds > maxSessionSeconds) {
+		return fmt.Errorf("scaling.maxSessionLifetimeSeconds must be between %d and %d, got %d", minSessionSeconds, maxSessionSeconds, scaling.MaxSessionLifetimeSeconds)
+	}
+	lifetime := scaling.MaxSessionLifetimeSeconds
+	if lifetime == 0 {
+		lifetime = agent.TimeoutSeconds
+	}
+	if scaling.IdleSessionTimeoutSeconds != 0 && lifetime != 0 && scaling.IdleSessionTimeoutSeconds > lifetime {
+		return fmt.Errorf("scaling.idleSessionTimeoutSeconds (%d) must not exceed the session lifetime (%d, maxSessionLifetimeSeconds or timeoutSeconds)",
+			scaling.IdleSessionTimeoutSeconds, lifetime)
+	}
+	return nil
+}
+
+// lifecycleConfiguration returns the session lifecycle of an agent's
+// runtime, or nil for the AgentCore defaults.
+func (s *AgentCoreStack) lifecycleConfiguration(config *AgentConfig) *awsbedrockagentcore.CfnRuntime_LifecycleConfigurationProperty {
+	idle, lifetime := 0, config.TimeoutSeconds
+	if opts := s.Options.Agents[config.Name]; opts != nil && opts.Scaling != nil {
+		idle = opts.Scaling.IdleSessionTimeoutSeconds
+		if opts.Scaling.MaxSessionLifetimeSeconds != 0 {
+			lifetime = opts.Scaling.MaxSessionLifetimeSeconds
+		}
+	}
+	if idle == 0 && lifetime == 0 {
+		return nil
+	}
+
+	lifecycle := &awsbedrockagentcore.CfnRuntime_LifecycleConfigurationProperty{}
+	if idle != 0 {
+		lifecycle.IdleRuntimeSessionTimeout = jsii.Number(float64(idle))
+	}
+	if lifetime != 0 {
+		lifecycle.MaxLifetime = jsii.Number(float64(lifetime))
+	}
+	return lifecycle
+}
+
+// warnScalingCost adds cost warnings to an agent's runtime for sessions
+// that may be billed for hours.
+func (s *AgentCoreStack) warnScalingCost(config *AgentConfig) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || opts.Scaling == nil {
+		return
+	}
+	annotations := awscdk.Annotations_Of(s.Runtimes[config.Name])
+	if idle := opts.Scaling.IdleSessionTimeoutSeconds; idle > idleSessionWarnSeconds {
+		annotations.AddWarningV2(jsii.String("agentcore:scaling-idle-timeout"), jsii.String(fmt.Sprintf(
+			"agent %s: idle sessions are billed for memory for up to %s each (scaling.idleSessionTimeoutSeconds)", config.Name, formatSeconds(idle))))
+	}
+	if lifetime := opts.Scaling.MaxSessionLifetimeSeconds; lifetime > sessionLifetimeWarnSeconds {
+		annotations.AddWarningV2(jsii.String("agentcore:scaling-lifetime"), jsii.String(fmt.Sprintf(
+			"agent %s: a runaway session runs for up to %s (scaling.maxSessionLifetimeSeconds)", config.Name, formatSeconds(lifetime))))
+	}
+}
+
+// formatSeconds formats a duration in seconds as hours and minutes, e.g.
+// "2h30m".
+func formatSeconds(seconds int) string {
+	hours, minutes := seconds/3600, seconds%3600/60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
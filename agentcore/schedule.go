@@ -0,0 +1,230 @@
+package agentcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsscheduler"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsschedulertargets"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+var (
+	// scheduleNamePattern is the naming rule for EventBridge Scheduler
+	// schedules.
+	scheduleNamePattern = regexp.MustCompile(`^[0-9a-zA-Z-_.]{1,64}$`)
+
+	// scheduleExpressionPattern matches cron(), rate(), and at() expressions.
+	scheduleExpressionPattern = regexp.MustCompile(`^(cron|rate|at)\(.+\)$`)
+)
+
+// ScheduleOptions invokes the agent on a schedule through EventBridge
+// Scheduler. Invocations that fail after all retries are sent to the
+// stack's schedule dead-letter queue.
+type ScheduleOptions struct {
+	// Name is the schedule name, also used in its construct ID.
+	// Pattern: [0-9a-zA-Z-_.]{1,64}
+	// Default: "{stackName}-{agent}-{index}"
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Expression is a cron(), rate(), or at() expression. A bare cron
+	// expression of six fields (e.g. "0 2 * * ? *") is wrapped in cron().
+	Expression string `json:"expression" yaml:"expression"`
+
+	// Timezone is the IANA time zone of cron() and at() expressions.
+	// Default: "UTC"
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+
+	// Payload is sent to the agent as the JSON request body.
+	// Default: {}
+	Payload map[string]any `json:"payload,omitempty" yaml:"payload,omitempty"`
+
+	// Endpoint is the runtime endpoint to invoke.
+	// Default: the agent's default endpoint
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	// Disabled creates the schedule in the disabled state.
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+
+	// RetryAttempts is the number of retries of a failed invocation.
+	// Range: 0-185
+	// Default: 185
+	RetryAttempts *int `json:"retryAttempts,omitempty" yaml:"retryAttempts,omitempty"`
+
+	// MaxEventAgeSeconds is how long a failed invocation is retried.
+	// Range: 60-86400
+	// Default: 86400
+	MaxEventAgeSeconds int `json:"maxEventAgeSeconds,omitempty" yaml:"maxEventAgeSeconds,omitempty"`
+}
+
+// expression returns the schedule expression, wrapping a bare cron
+// expression in cron().
+func (o *ScheduleOptions) expression() string {
+	expression := strings.TrimSpace(o.Expression)
+	if !scheduleExpressionPattern.MatchString(expression) && len(strings.Fields(expression)) == 6 {
+		return fmt.Sprintf("cron(%s)", expression)
+	}
+	return expression
+}
+
+// validateSchedules validates an agent's schedules.
+func validateSchedules(agentName string, opts *AgentOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	endpoints := map[string]bool{defaultEndpointName(agentName, opts): true}
+	for _, endpoint := range opts.Endpoints {
+		endpoints[endpoint.Name] = true
+	}
+
+	names := make(map[string]bool)
+	for i, schedule := range opts.Schedules {
+		if schedule.Name != "" {
+			if !scheduleNamePattern.MatchString(schedule.Name) {
+				return fmt.Errorf("schedules[%d].name %q must match %s", i, schedule.Name, scheduleNamePattern)
+			}
+			if names[schedule.Name] {
+				return fmt.Errorf("schedules[%d].name %q is already used by another schedule of the agent", i, schedule.Name)
+			}
+			names[schedule.Name] = true
+		}
+		if !scheduleExpressionPattern.MatchString(schedule.expression()) {
+			return fmt.Errorf("schedules[%d].expression %q must be a cron(), rate(), or at() expression", i, schedule.Expression)
+		}
+		if schedule.Endpoint != "" && !endpoints[schedule.Endpoint] {
+			return fmt.Errorf("schedules[%d].endpoint %q is not an endpoint of the agent", i, schedule.Endpoint)
+		}
+		if schedule.RetryAttempts != nil && (*schedule.RetryAttempts < 0 || *schedule.RetryAttempts > 185) {
+			return fmt.Errorf("schedules[%d].retryAttempts must be between 0 and 185, got %d", i, *schedule.RetryAttempts)
+		}
+		if schedule.MaxEventAgeSeconds != 0 && (schedule.MaxEventAgeSeconds < 60 || schedule.MaxEventAgeSeconds > 86400) {
+			return fmt.Errorf("schedules[%d].maxEventAgeSeconds must be between 60 and 86400, got %d", i, schedule.MaxEventAgeSeconds)
+		}
+		if _, err := json.Marshal(schedule.Payload); err != nil {
+			return fmt.Errorf("schedules[%d].payload: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// createSchedules creates the agent's schedules, each invoking the runtime
+// endpoint with InvokeAgentRuntime.
+func (s *AgentCoreStack) createSchedules(config *AgentConfig) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || len(opts.Schedules) == 0 {
+		return
+	}
+	s.createSchedulerResources()
+
+	runtime := s.Runtimes[config.Name]
+	for i, scheduleOpts := range opts.Schedules {
+		name := scheduleOpts.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%s-%d", s.Config.StackName, config.Name, i+1)
+		}
+		endpoint := scheduleOpts.Endpoint
+		if endpoint == "" {
+			endpoint = defaultEndpointName(config.Name, opts)
+		}
+		timezone := scheduleOpts.Timezone
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		payload := scheduleOpts.Payload
+		if payload == nil {
+			payload = map[string]any{}
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			panic(fmt.Sprintf("invalid stack options: agent %s: schedules[%d].payload: %v", config.Name, i, err))
+		}
+
+		targetProps := &awsschedulertargets.UniversalTargetProps{
+			Service: jsii.String("bedrockagentcore"),
+			Action:  jsii.String("invokeAgentRuntime"),
+			Input: awsscheduler.ScheduleTargetInput_FromObject(map[string]interface{}{
+				"AgentRuntimeArn": runtime.AttrAgentRuntimeArn(),
+				"Qualifier":       endpoint,
+				"ContentType":     "application/json",
+				"Payload":         string(body),
+			}),
+			PolicyStatements: &[]awsiam.PolicyStatement{
+				awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+					Effect:  awsiam.Effect_ALLOW,
+					Actions: jsii.Strings("bedrock-agentcore:InvokeAgentRuntime"),
+					Resources: &[]*string{
+						runtime.AttrAgentRuntimeArn(),
+						jsii.String(fmt.Sprintf("%s/*", *runtime.AttrAgentRuntimeArn())),
+					},
+				}),
+			},
+			Role:            s.SchedulerRole,
+			DeadLetterQueue: s.ScheduleDeadLetterQueue,
+		}
+		if scheduleOpts.RetryAttempts != nil {
+			targetProps.RetryAttempts = jsii.Number(float64(*scheduleOpts.RetryAttempts))
+		}
+		if scheduleOpts.MaxEventAgeSeconds != 0 {
+			targetProps.MaxEventAge = awscdk.Duration_Seconds(jsii.Number(float64(scheduleOpts.MaxEventAgeSeconds)))
+		}
+
+		schedule := awsscheduler.NewSchedule(s.agentScope(config.Name),
+			jsii.String(fmt.Sprintf("Schedule-%s-%s", config.Name, name)),
+			&awsscheduler.ScheduleProps{
+				ScheduleName: jsii.String(name),
+				Description:  jsii.String(fmt.Sprintf("Invokes agent %s", config.Name)),
+				Schedule: awsscheduler.ScheduleExpression_Expression(
+					jsii.String(scheduleOpts.expression()), awscdk.TimeZone_Of(jsii.String(timezone))),
+				Target:  awsschedulertargets.NewUniversal(targetProps),
+				Enabled: jsii.Bool(!scheduleOpts.Disabled),
+				Key:     s.KMSKey,
+			},
+		)
+		// The qualifier names the endpoint without referencing it
+		if named, ok := s.NamedEndpoints[config.Name][endpoint]; ok {
+			schedule.Node().AddDependency(named)
+		} else {
+			schedule.Node().AddDependency(s.Endpoints[config.Name])
+		}
+		s.Schedules[config.Name] = append(s.Schedules[config.Name], schedule)
+	}
+}
+
+// createSchedulerResources creates the scheduler execution role and the
+// dead-letter queue shared by all schedules of the stack, once.
+func (s *AgentCoreStack) createSchedulerResources() {
+	if s.SchedulerRole != nil {
+		return
+	}
+
+	s.SchedulerRole = awsiam.NewRole(s.Stack, jsii.String("SchedulerRole"), &awsiam.RoleProps{
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("scheduler.amazonaws.com"), &awsiam.ServicePrincipalOpts{
+			Conditions: &map[string]interface{}{
+				"StringEquals": map[string]interface{}{"aws:SourceAccount": s.Stack.Account()},
+			},
+		}),
+		Description: jsii.String(fmt.Sprintf("EventBridge Scheduler role for stack %s", s.Config.StackName)),
+	})
+
+	encryption := awssqs.QueueEncryption_SQS_MANAGED
+	if s.KMSKey != nil {
+		encryption = awssqs.QueueEncryption_KMS
+	}
+	s.ScheduleDeadLetterQueue = awssqs.NewQueue(s.Stack, jsii.String("ScheduleDeadLetterQueue"), &awssqs.QueueProps{
+		Encryption:          encryption,
+		EncryptionMasterKey: s.KMSKey,
+		EnforceSSL:          jsii.Bool(true),
+		RetentionPeriod:     awscdk.Duration_Days(jsii.Number(14)),
+	})
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("ScheduleDeadLetterQueueUrl"), &awscdk.CfnOutputProps{
+		Value:       s.ScheduleDeadLetterQueue.QueueUrl(),
+		Description: jsii.String("Dead-letter queue of failed scheduled invocations"),
+	})
+}
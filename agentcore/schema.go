@@ -0,0 +1,392 @@
+package agentcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSchemaID is the $id of the config file JSON Schema.
+const ConfigSchemaID = "https://github.com/plexusone/agentkit-aws-cdk/config.schema.json"
+
+// jsonSchema is the subset of JSON Schema (draft 2020-12) the config schema
+// uses.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // false or *jsonSchema
+	Items                *jsonSchema            `json:"items,omitempty"`
+}
+
+// ConfigJSONSchema returns the JSON Schema of config files: the fields of
+// StackConfig and the CDK-specific StackOptions, with AgentConfig and
+// AgentOptions for each agent. It is generated from the Go structs, so
+// it covers every field the loaders read. Editors validate and complete a
+// config file that references the schema with a $schema key.
+func ConfigJSONSchema() []byte {
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("encoding config schema: %v", err))
+	}
+	return append(data, '\n')
+}
+
+// configSchema returns the schema of config files.
+func configSchema() *jsonSchema {
+	root := mergeSchemas(schemaOf(reflect.TypeOf(StackConfig{}), nil), schemaOf(reflect.TypeOf(optionsDocument{}), nil))
+	root.Schema = "https://json-schema.org/draft/2020-12/schema"
+	root.ID = ConfigSchemaID
+	root.Title = "agentkit-aws-cdk stack configuration"
+	// Lets config files reference the schema
+	root.Properties["$schema"] = &jsonSchema{Type: "string"}
+	return root
+}
+
+// schemaOf returns the schema of a Go type as encoded by encoding/json.
+// seen guards against recursive types.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return &jsonSchema{Type: "object"}
+		}
+		if seen == nil {
+			seen = make(map[reflect.Type]bool)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		schema := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema), AdditionalProperties: false}
+		addStructFields(schema, t, seen)
+		return schema
+	}
+	return &jsonSchema{} // Any value
+}
+
+// addStructFields adds the JSON fields of a struct to an object schema,
+// inlining embedded structs without a JSON name as encoding/json does.
+func addStructFields(schema *jsonSchema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addStructFields(schema, embedded, seen)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if existing := schema.Properties[name]; existing != nil {
+			schema.Properties[name] = mergeSchemas(existing, schemaOf(field.Type, seen))
+		} else {
+			schema.Properties[name] = schemaOf(field.Type, seen)
+		}
+		// Fields the loaders always expect are encoded without omitempty
+		if !strings.Contains(flags, "omitempty") && !containsString(schema.Required, name) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	sort.Strings(schema.Required)
+}
+
+// mergeSchemas merges two schemas of the same value, such as the vpc key
+// read by both StackConfig and StackOptions: object properties and array
+// items are merged, and a property required by either is required.
+func mergeSchemas(a, b *jsonSchema) *jsonSchema {
+	if a.Type != b.Type || a.Type == "" {
+		return a
+	}
+	merged := *a
+	switch a.Type {
+	case "object":
+		if a.Properties == nil || b.Properties == nil {
+			return &merged
+		}
+		merged.Properties = make(map[string]*jsonSchema, len(a.Properties)+len(b.Properties))
+		for name, property := range a.Properties {
+			merged.Properties[name] = property
+		}
+		for name, property := range b.Properties {
+			if existing := merged.Properties[name]; existing != nil {
+				merged.Properties[name] = mergeSchemas(existing, property)
+			} else {
+				merged.Properties[name] = property
+			}
+		}
+		merged.Required = append([]string(nil), a.Required...)
+		for _, name := range b.Required {
+			if !containsString(merged.Required, name) {
+				merged.Required = append(merged.Required, name)
+			}
+		}
+		sort.Strings(merged.Required)
+	case "array":
+		if a.Items != nil && b.Items != nil {
+			merged.Items = mergeSchemas(a.Items, b.Items)
+		}
+	}
+	return &merged
+}
+
+// containsString reports whether values contains value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigIssue is a problem found in a config file.
+type ConfigIssue struct {
+	// Path locates the value in the file, e.g. "agents[1].memoryMB", or is
+	// empty for problems of the configuration as a whole.
+	Path string `json:"path,omitempty"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+
+	unknown bool // The field is not in the schema
+}
+
+// String returns the issue as "path: message".
+func (i ConfigIssue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// ValidateConfigFile checks a JSON or YAML config file against the config
+// schema, reporting unknown fields (with the field probably meant), values
+// of the wrong type, and missing required fields. If the file matches the
+// schema, the configuration is then loaded and validated as a stack would,
+// resolving placeholders. Use WithEnvironment to validate the file with an
+// environment overlay merged over it. The error is set only if the file
+// can't be read or parsed.
+func ValidateConfigFile(path string, opts ...LoadOption) ([]ConfigIssue, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+		return nil, fmt.Errorf("unsupported file format: %s (use .json, .yaml, or .yml)", ext)
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // G304: config path is provided by the caller
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+
+	var settings loadSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	if settings.environment != "" {
+		if doc, err = applyOverlay(path, settings.environment, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	var issues []ConfigIssue
+	validateValue(configSchema(), doc, "", &issues)
+	if len(issues) > 0 {
+		return issues, nil
+	}
+
+	config, err := LoadStackConfigFromFile(path, opts...)
+	if err != nil {
+		return []ConfigIssue{{Message: err.Error()}}, nil
+	}
+	options, err := LoadStackOptionsFromFile(path, opts...)
+	if err != nil {
+		return []ConfigIssue{{Message: err.Error()}}, nil
+	}
+	if err := options.Validate(*config); err != nil {
+		return []ConfigIssue{{Message: err.Error()}}, nil
+	}
+	return nil, nil
+}
+
+// validateValue checks a decoded value against a schema, appending the
+// problems found to issues.
+func validateValue(schema *jsonSchema, value any, path string, issues *[]ConfigIssue) {
+	report := func(format string, args ...any) {
+		*issues = append(*issues, ConfigIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if value == nil || schema.Type == "" {
+		return // null is the zero value of every field
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			report("expected an object, got %s", describeValue(value))
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				*issues = append(*issues, ConfigIssue{Path: joinPath(path, name), Message: "required field is missing"})
+			}
+		}
+		for _, name := range sortedKeys(object) {
+			property := schema.Properties[name]
+			if property == nil {
+				if additional, ok := schema.AdditionalProperties.(*jsonSchema); ok {
+					validateValue(additional, object[name], joinPath(path, name), issues)
+					continue
+				}
+				message := "unknown field"
+				if suggestion := suggestField(name, schema.Properties); suggestion != "" {
+					message += fmt.Sprintf("; did you mean %q?", suggestion)
+				}
+				*issues = append(*issues, ConfigIssue{Path: joinPath(path, name), Message: message, unknown: true})
+				continue
+			}
+			validateValue(property, object[name], joinPath(path, name), issues)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			report("expected a list, got %s", describeValue(value))
+			return
+		}
+		for i, item := range array {
+			validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), issues)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			report("expected a string, got %s", describeValue(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			report("expected true or false, got %s", describeValue(value))
+		}
+	case "integer":
+		switch v := value.(type) {
+		case int, int64, uint64:
+		case float64:
+			if v != float64(int64(v)) {
+				report("expected an integer, got %v", v)
+			}
+		default:
+			report("expected an integer, got %s", describeValue(value))
+		}
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+		default:
+			report("expected a number, got %s", describeValue(value))
+		}
+	}
+}
+
+// joinPath appends a field name to a path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// describeValue describes a decoded value for error messages.
+func describeValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case int, int64, uint64, float64:
+		return fmt.Sprintf("number %v", v)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// suggestField returns the known field closest to an unknown one, if it is
+// close enough to be a typo or a case mistake.
+func suggestField(name string, properties map[string]*jsonSchema) string {
+	best, bestDistance := "", len(name)/3+1
+	if bestDistance > 3 {
+		bestDistance = 3
+	}
+	for _, candidate := range sortedKeys(properties) {
+		if strings.EqualFold(candidate, name) {
+			return candidate
+		}
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance <= bestDistance && (best == "" || distance < bestDistance) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
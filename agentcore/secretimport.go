@@ -0,0 +1,140 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/customresources"
+	"github.com/aws/jsii-runtime-go"
+)
+
+var (
+	// secretNamePattern matches Secrets Manager secret names and name
+	// prefixes.
+	secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9/_+=.@-]+$`)
+
+	// envVarUnsafePattern matches the characters replaced by "_" in
+	// environment variable names derived from secret names.
+	envVarUnsafePattern = regexp.MustCompile(`[^A-Za-z0-9]`)
+)
+
+// validateImports validates ImportByPrefix and ImportSecrets.
+func (o *SecretsOptions) validateImports() error {
+	if o.ImportByPrefix == "" {
+		if len(o.ImportSecrets) > 0 {
+			return fmt.Errorf("secrets.importSecrets require secrets.importByPrefix")
+		}
+		return nil
+	}
+
+	// Placeholders are checked by the values they stand for
+	prefix := strings.NewReplacer(secretsPrefixProject, "project", secretsPrefixEnvironment, "env").Replace(o.ImportByPrefix)
+	if !secretNamePattern.MatchString(prefix) {
+		return fmt.Errorf("secrets.importByPrefix %q must contain only letters, digits, /_+=.@-, %s, and %s", o.ImportByPrefix, secretsPrefixProject, secretsPrefixEnvironment)
+	}
+	if strings.Contains(o.ImportByPrefix, secretsPrefixEnvironment) && o.Environment == "" {
+		return fmt.Errorf("secrets.importByPrefix %q uses %s: set secrets.environment or load the config with an environment overlay", o.ImportByPrefix, secretsPrefixEnvironment)
+	}
+
+	envVars := make(map[string]string, len(o.ImportSecrets))
+	for i, name := range o.ImportSecrets {
+		if !secretNamePattern.MatchString(name) {
+			return fmt.Errorf("secrets.importSecrets[%d] %q must contain only letters, digits, and /_+=.@-", i, name)
+		}
+		envVar := importedSecretEnvVar(name)
+		if other, ok := envVars[envVar]; ok {
+			return fmt.Errorf("secrets.importSecrets %q and %q are both injected as %s", other, name, envVar)
+		}
+		envVars[envVar] = name
+	}
+	return nil
+}
+
+// importedSecretEnvVar returns the environment variable holding the ARN of
+// an imported secret, e.g. SECRET_ARN_LLM for "llm".
+func importedSecretEnvVar(name string) string {
+	return "SECRET_ARN_" + strings.ToUpper(envVarUnsafePattern.ReplaceAllString(name, "_"))
+}
+
+// importPrefix returns ImportByPrefix with its placeholders replaced.
+func (s *AgentCoreStack) importPrefix() string {
+	return s.expandSecretsPrefix(s.Options.Secrets.ImportByPrefix)
+}
+
+// importedSecretsARN returns the ARN pattern of the secrets under
+// ImportByPrefix.
+func (s *AgentCoreStack) importedSecretsARN() *string {
+	return s.Stack.FormatArn(&awscdk.ArnComponents{
+		Service:      jsii.String("secretsmanager"),
+		Resource:     jsii.String("secret"),
+		ResourceName: jsii.String(s.importPrefix() + "*"),
+		ArnFormat:    awscdk.ArnFormat_COLON_RESOURCE_NAME,
+	})
+}
+
+// lookupImportedSecrets looks up the ARNs of ImportSecrets with
+// DescribeSecret when the stack is deployed. Secrets Manager appends a
+// random suffix to secret ARNs, so they can't be derived from the names.
+func (s *AgentCoreStack) lookupImportedSecrets() {
+	if s.Options.Secrets == nil || len(s.Options.Secrets.ImportSecrets) == 0 {
+		return
+	}
+
+	s.ImportedSecretARNs = make(map[string]*string, len(s.Options.Secrets.ImportSecrets))
+	for _, name := range s.Options.Secrets.ImportSecrets {
+		secretName := s.importPrefix() + name
+		describe := &customresources.AwsSdkCall{
+			Service:            jsii.String("SecretsManager"),
+			Action:             jsii.String("describeSecret"),
+			Parameters:         map[string]any{"SecretId": secretName},
+			PhysicalResourceId: customresources.PhysicalResourceId_FromResponse(jsii.String("ARN")),
+			OutputPaths:        jsii.Strings("ARN"),
+		}
+		lookup := customresources.NewAwsCustomResource(s.Stack, jsii.String("ImportedSecret-"+strings.ReplaceAll(name, "/", "-")), &customresources.AwsCustomResourceProps{
+			OnCreate: describe,
+			OnUpdate: describe,
+			Policy: customresources.AwsCustomResourcePolicy_FromSdkCalls(&customresources.SdkCallsPolicyOptions{
+				Resources: &[]*string{s.importedSecretsARN()},
+			}),
+			InstallLatestAwsSdk: jsii.Bool(false),
+		})
+		s.ImportedSecretARNs[name] = lookup.GetResponseField(jsii.String("ARN"))
+	}
+}
+
+// grantImportedSecrets grants the role read access to the secrets under
+// ImportByPrefix, and lets it list them. Pushed secrets are encrypted with
+// the aws/secretsmanager key, which needs no grant.
+func (s *AgentCoreStack) grantImportedSecrets(role awsiam.IRole) {
+	if s.Options.Secrets == nil || s.Options.Secrets.ImportByPrefix == "" {
+		return
+	}
+
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"),
+		Resources: &[]*string{s.importedSecretsARN()},
+	}))
+	// ListSecrets supports no resource-level permissions
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("secretsmanager:ListSecrets"),
+		Resources: jsii.Strings("*"),
+	}))
+}
+
+// addImportedSecretsEnvironment injects SECRETS_IMPORT_PREFIX and the
+// SECRET_ARN_{NAME} of each of ImportSecrets.
+func (s *AgentCoreStack) addImportedSecretsEnvironment(envVars map[string]string) {
+	if s.Options.Secrets == nil || s.Options.Secrets.ImportByPrefix == "" {
+		return
+	}
+
+	envVars["SECRETS_IMPORT_PREFIX"] = s.importPrefix()
+	for name, arn := range s.ImportedSecretARNs {
+		envVars[importedSecretEnvVar(name)] = *arn
+	}
+}
@@ -0,0 +1,700 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	"github.com/aws/jsii-runtime-go"
+
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+// Secret backends.
+const (
+	SecretsBackendSecretsManager = "secretsmanager"
+	SecretsBackendSSM            = "ssm"
+)
+
+// DefaultSecretGroups are the groups push-secrets writes.
+var DefaultSecretGroups = []string{"llm", "search", "config"}
+
+// ssmPathSegmentPattern matches a single SSM parameter path segment.
+var ssmPathSegmentPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// SecretsOptions extends the secrets configuration.
+type SecretsOptions struct {
+	// Backend is where secret values are stored: "secretsmanager" or "ssm".
+	// With "ssm", values are SecureString parameters under
+	// /{Prefix}/{group}/{KEY}, as written by push-secrets --backend ssm.
+	// Default: "secretsmanager"
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+
+	// Prefix is the secret name prefix, without a leading slash, with the
+	// {project} and {env} placeholders of push-secrets --prefix, e.g.
+	// "{project}/{env}". Must match push-secrets --prefix. With the ssm
+	// backend it is the parameter path; with the secretsmanager backend,
+	// agents are granted the {prefix}/{group} secrets if it is set.
+	// Default: "{project}", or "{project}/{env}" with Environment
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+
+	// Project is the {project} of Prefix.
+	// Default: the stack name, without the -{env} suffix an environment
+	// overlay appends
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+
+	// Environment is the {env} of Prefix.
+	// Default: the environment overlay the config file was loaded with
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
+
+	// Groups are the secret groups agents read with the ssm backend or a
+	// secretsmanager Prefix.
+	// Default: DefaultSecretGroups
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+
+	// ReplicaRegions replicate the stack's secret (secrets.createSecrets)
+	// to other regions, so agents deployed there for disaster recovery
+	// read the same values. Requires the secretsmanager backend.
+	ReplicaRegions []SecretReplica `json:"replicaRegions,omitempty" yaml:"replicaRegions,omitempty"`
+
+	// Tags are added to the stack's secret (secrets.createSecrets) next to
+	// the stack tags, e.g. {"environment": "prod"}. The secret is always
+	// tagged project={stackName} and managed-by=agentkit-aws-cdk, like the
+	// secrets push-secrets writes; Tags can override both.
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	// RestrictRead attaches a resource policy to the stack's secret that
+	// denies secretsmanager:GetSecretValue to every principal except the
+	// agent execution role and ReaderARNs.
+	RestrictRead bool `json:"restrictRead,omitempty" yaml:"restrictRead,omitempty"`
+
+	// Generate adds a random value to the stack's secret
+	// (secrets.createSecrets), generated by Secrets Manager when the
+	// secret is created and never written to the template.
+	Generate *GeneratedSecretValue `json:"generate,omitempty" yaml:"generate,omitempty"`
+
+	// ValueReferences copy values into the stack's secret from existing
+	// secrets, e.g. those written by push-secrets, instead of writing them
+	// to the template as secrets.secretValues does.
+	ValueReferences []SecretValueReference `json:"valueReferences,omitempty" yaml:"valueReferences,omitempty"`
+
+	// ImportByPrefix grants agents read access to every Secrets Manager
+	// secret whose name starts with the prefix, e.g. "stats-agent/" for
+	// the secrets of push-secrets --prefix stats-agent. It takes the
+	// {project} and {env} placeholders of Prefix. Agents receive it as
+	// SECRETS_IMPORT_PREFIX and may list the secrets.
+	ImportByPrefix string `json:"importByPrefix,omitempty" yaml:"importByPrefix,omitempty"`
+
+	// ImportSecrets are secret names under ImportByPrefix, e.g. "llm",
+	// whose ARNs are looked up when the stack is deployed and injected as
+	// SECRET_ARN_{NAME}. Deployment fails if one is missing.
+	ImportSecrets []string `json:"importSecrets,omitempty" yaml:"importSecrets,omitempty"`
+
+	// ReaderARNs are the IAM roles or users that may still read the
+	// stack's secret with RestrictRead, e.g. administrators or, for
+	// secretEnvironment, the CloudFormation execution role resolving the
+	// dynamic references.
+	ReaderARNs []string `json:"readerArns,omitempty" yaml:"readerArns,omitempty"`
+}
+
+// GeneratedSecretValue is a random key of the stack's secret.
+type GeneratedSecretValue struct {
+	// Key is the key of the generated value, e.g. "WEBHOOK_TOKEN".
+	Key string `json:"key" yaml:"key"`
+
+	// Length is the number of characters, 8-4096.
+	// Default: 32
+	Length int `json:"length,omitempty" yaml:"length,omitempty"`
+
+	// ExcludePunctuation generates only letters and digits.
+	ExcludePunctuation bool `json:"excludePunctuation,omitempty" yaml:"excludePunctuation,omitempty"`
+
+	// ExcludeCharacters are characters the value must not contain.
+	ExcludeCharacters string `json:"excludeCharacters,omitempty" yaml:"excludeCharacters,omitempty"`
+}
+
+// SecretValueReference sets a key of the stack's secret to the value of
+// an existing Secrets Manager secret with a dynamic reference, resolved by
+// CloudFormation when the secret is deployed.
+type SecretValueReference struct {
+	// Key is the key in the stack's secret.
+	Key string `json:"key" yaml:"key"`
+
+	// SecretARN is the secret holding the value.
+	SecretARN string `json:"secretArn" yaml:"secretArn"`
+
+	// JSONKey selects a key of a JSON secret.
+	// Default: the whole secret string
+	JSONKey string `json:"jsonKey,omitempty" yaml:"jsonKey,omitempty"`
+}
+
+// defaultGeneratedSecretLength is the length of generated values.
+const defaultGeneratedSecretLength = 32
+
+// secretPlaintextWarningID is the synth warning ID of secret values
+// written to the template.
+const secretPlaintextWarningID = "agentkit:secret-plaintext"
+
+// Tags applied to the stack's secret.
+const (
+	secretTagProject   = "project"
+	secretTagManagedBy = "managed-by"
+	secretManagedBy    = "agentkit-aws-cdk"
+)
+
+// Placeholders of SecretsOptions.Prefix, as in push-secrets --prefix.
+const (
+	secretsPrefixProject     = "{project}"
+	secretsPrefixEnvironment = "{env}"
+)
+
+// principalARNPattern matches IAM role and user ARNs, optionally with a
+// path and wildcards.
+var principalARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:(role|user)/[\w+=,.@/*-]+$`)
+
+// SecretReplica is a region a secret is replicated to.
+type SecretReplica struct {
+	// Region is the replica region, e.g. us-west-2.
+	Region string `json:"region" yaml:"region"`
+
+	// KMSKeyARN is the key encrypting the replica, in the replica region.
+	// Default: the aws/secretsmanager key of the region
+	KMSKeyARN string `json:"kmsKeyArn,omitempty" yaml:"kmsKeyArn,omitempty"`
+}
+
+// validate validates the secrets options.
+func (o *SecretsOptions) validate() error {
+	switch o.Backend {
+	case "", SecretsBackendSecretsManager:
+		if len(o.Groups) > 0 && o.Prefix == "" {
+			return fmt.Errorf("secrets.groups require secrets.prefix or secrets.backend %s", SecretsBackendSSM)
+		}
+		if err := o.validateAccess(); err != nil {
+			return err
+		}
+		if err := o.validateReplicas(); err != nil {
+			return err
+		}
+	case SecretsBackendSSM:
+		if o.ImportByPrefix != "" {
+			return fmt.Errorf("secrets.importByPrefix requires secrets.backend %s", SecretsBackendSecretsManager)
+		}
+		if len(o.ReplicaRegions) > 0 {
+			return fmt.Errorf("secrets.replicaRegions require secrets.backend %s", SecretsBackendSecretsManager)
+		}
+		if len(o.Tags) > 0 || o.RestrictRead || len(o.ReaderARNs) > 0 {
+			return fmt.Errorf("secrets.tags, secrets.restrictRead, and secrets.readerArns require secrets.backend %s; tag parameters with push-secrets --backend ssm", SecretsBackendSecretsManager)
+		}
+	default:
+		return fmt.Errorf("secrets.backend must be one of [%s %s]", SecretsBackendSecretsManager, SecretsBackendSSM)
+	}
+
+	if o.Prefix != "" {
+		// Placeholders are checked by the values they stand for below
+		prefix := strings.NewReplacer(secretsPrefixProject, "project", secretsPrefixEnvironment, "env").Replace(o.Prefix)
+		for _, segment := range strings.Split(prefix, "/") {
+			if !ssmPathSegmentPattern.MatchString(segment) {
+				return fmt.Errorf("secrets.prefix %q must be slash-separated segments of letters, digits, '_', '.', '-', %s, and %s, without a leading slash", o.Prefix, secretsPrefixProject, secretsPrefixEnvironment)
+			}
+		}
+		if strings.Contains(o.Prefix, secretsPrefixEnvironment) && o.Environment == "" {
+			return fmt.Errorf("secrets.prefix %q uses %s: set secrets.environment or load the config with an environment overlay", o.Prefix, secretsPrefixEnvironment)
+		}
+	}
+	if o.Project != "" && !ssmPathSegmentPattern.MatchString(o.Project) {
+		return fmt.Errorf("secrets.project %q must contain only letters, digits, '_', '.', and '-'", o.Project)
+	}
+	if o.Environment != "" && !ssmPathSegmentPattern.MatchString(o.Environment) {
+		return fmt.Errorf("secrets.environment %q must contain only letters, digits, '_', '.', and '-'", o.Environment)
+	}
+	for i, group := range o.Groups {
+		if !ssmPathSegmentPattern.MatchString(group) {
+			return fmt.Errorf("secrets.groups[%d] %q must contain only letters, digits, '_', '.', and '-'", i, group)
+		}
+	}
+	return o.validateImports()
+}
+
+// validateSecretValues rejects secrets settings that would be ignored, and
+// validates the values of the stack's secret.
+func validateSecretValues(config StackConfig, opts *SecretsOptions) error {
+	var generate *GeneratedSecretValue
+	var references []SecretValueReference
+	if opts != nil {
+		generate, references = opts.Generate, opts.ValueReferences
+	}
+	secrets := config.Secrets
+	if secrets == nil || !secrets.CreateSecrets {
+		switch {
+		case secrets != nil && len(secrets.SecretValues) > 0:
+			return fmt.Errorf("secrets.secretValues require secrets.createSecrets; push them with push-secrets instead")
+		case secrets != nil && secrets.SecretName != "":
+			return fmt.Errorf("secrets.secretName requires secrets.createSecrets")
+		case generate != nil || len(references) > 0:
+			return fmt.Errorf("secrets.generate and secrets.valueReferences require secrets.createSecrets")
+		}
+		return nil
+	}
+	if len(secrets.SecretValues) == 0 && generate == nil && len(references) == 0 {
+		return fmt.Errorf("secrets.createSecrets requires secrets.secretValues, secrets.generate, or secrets.valueReferences")
+	}
+
+	keys := make(map[string]string)
+	for key := range secrets.SecretValues {
+		keys[key] = "secrets.secretValues"
+	}
+	for i, ref := range references {
+		path := fmt.Sprintf("secrets.valueReferences[%d]", i)
+		switch {
+		case ref.Key == "":
+			return fmt.Errorf("%s.key is required", path)
+		case keys[ref.Key] != "":
+			return fmt.Errorf("%s.key %s is already set by %s", path, ref.Key, keys[ref.Key])
+		case ref.SecretARN == "":
+			return fmt.Errorf("%s.secretArn is required", path)
+		case !*awscdk.Token_IsUnresolved(ref.SecretARN) && !secretARNPattern.MatchString(ref.SecretARN):
+			return fmt.Errorf("%s.secretArn %q is not a Secrets Manager secret ARN", path, ref.SecretARN)
+		}
+		keys[ref.Key] = path
+	}
+	if generate != nil {
+		switch {
+		case generate.Key == "":
+			return fmt.Errorf("secrets.generate.key is required")
+		case keys[generate.Key] != "":
+			return fmt.Errorf("secrets.generate.key %s is already set by %s", generate.Key, keys[generate.Key])
+		case generate.Length != 0 && (generate.Length < 8 || generate.Length > 4096):
+			return fmt.Errorf("secrets.generate.length must be 8-4096, got %d", generate.Length)
+		}
+	}
+	return nil
+}
+
+// secretObjectValue returns the values of the stack's secret: the plain
+// secret values and the references. It also returns the keys whose values
+// are written to the template in plaintext.
+func (s *AgentCoreStack) secretObjectValue() (map[string]awscdk.SecretValue, []string) {
+	values := make(map[string]awscdk.SecretValue)
+	var plaintext []string
+	for key, value := range s.Config.Secrets.SecretValues {
+		values[key] = awscdk.SecretValue_UnsafePlainText(jsii.String(value))
+		// Dynamic references and tokens are resolved by CloudFormation
+		if !strings.HasPrefix(value, "{{resolve:") && !*awscdk.Token_IsUnresolved(value) {
+			plaintext = append(plaintext, key)
+		}
+	}
+	if s.Options.Secrets != nil {
+		for _, ref := range s.Options.Secrets.ValueReferences {
+			refOpts := &awscdk.SecretsManagerSecretOptions{}
+			if ref.JSONKey != "" {
+				refOpts.JsonField = jsii.String(ref.JSONKey)
+			}
+			values[ref.Key] = awscdk.SecretValue_SecretsManager(jsii.String(ref.SecretARN), refOpts)
+		}
+	}
+	sort.Strings(plaintext)
+	return values, plaintext
+}
+
+// generateSecretString returns the settings that generate the key of
+// secrets.generate, with the other values of the secret as the template.
+func (s *AgentCoreStack) generateSecretString(values map[string]awscdk.SecretValue) *awssecretsmanager.SecretStringGenerator {
+	generate := s.Options.Secrets.Generate
+	template := make(map[string]string, len(values))
+	for key, value := range values {
+		template[key] = *value.UnsafeUnwrap()
+	}
+	length := generate.Length
+	if length == 0 {
+		length = defaultGeneratedSecretLength
+	}
+	generator := &awssecretsmanager.SecretStringGenerator{
+		SecretStringTemplate: s.Stack.ToJsonString(template, nil),
+		GenerateStringKey:    jsii.String(generate.Key),
+		PasswordLength:       jsii.Number(float64(length)),
+		ExcludePunctuation:   jsii.Bool(generate.ExcludePunctuation),
+	}
+	if generate.ExcludeCharacters != "" {
+		generator.ExcludeCharacters = jsii.String(generate.ExcludeCharacters)
+	}
+	return generator
+}
+
+// validateAccess validates the tags and read restriction of the stack's
+// secret.
+func (o *SecretsOptions) validateAccess() error {
+	for key, value := range o.Tags {
+		switch {
+		case key == "" || len(key) > 128:
+			return fmt.Errorf("secrets.tags: key %q must be 1-128 characters", key)
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			return fmt.Errorf("secrets.tags: key %q uses the reserved aws: prefix", key)
+		case len(value) > 256:
+			return fmt.Errorf("secrets.tags.%s must be at most 256 characters", key)
+		}
+	}
+	if len(o.ReaderARNs) > 0 && !o.RestrictRead {
+		return fmt.Errorf("secrets.readerArns require secrets.restrictRead")
+	}
+	for i, arn := range o.ReaderARNs {
+		if !*awscdk.Token_IsUnresolved(arn) && !principalARNPattern.MatchString(arn) {
+			return fmt.Errorf("secrets.readerArns[%d] %q is not an IAM role or user ARN", i, arn)
+		}
+	}
+	return nil
+}
+
+// validateReplicas validates the replica regions.
+func (o *SecretsOptions) validateReplicas() error {
+	regions := make(map[string]bool, len(o.ReplicaRegions))
+	for i, replica := range o.ReplicaRegions {
+		if !awsapi.RegionPattern.MatchString(replica.Region) {
+			return fmt.Errorf("secrets.replicaRegions[%d].region %q is not an AWS region", i, replica.Region)
+		}
+		if regions[replica.Region] {
+			return fmt.Errorf("secrets.replicaRegions[%d].region %s is listed twice", i, replica.Region)
+		}
+		regions[replica.Region] = true
+
+		if replica.KMSKeyARN == "" {
+			continue
+		}
+		if !kmsKeyARNPattern.MatchString(replica.KMSKeyARN) {
+			return fmt.Errorf("secrets.replicaRegions[%d].kmsKeyArn %q is not a KMS key ARN", i, replica.KMSKeyARN)
+		}
+		// KMS keys are regional: the replica is encrypted in its own region
+		if keyRegion := strings.Split(replica.KMSKeyARN, ":")[3]; keyRegion != replica.Region {
+			return fmt.Errorf("secrets.replicaRegions[%d].kmsKeyArn is in %s, not in the replica region %s", i, keyRegion, replica.Region)
+		}
+	}
+	return nil
+}
+
+// usesSSM reports whether secrets are stored in SSM Parameter Store.
+func (o *SecretsOptions) usesSSM() bool {
+	return o != nil && o.Backend == SecretsBackendSSM
+}
+
+// secretReplicaRegions returns the replica regions of the stack's secret.
+func (s *AgentCoreStack) secretReplicaRegions() *[]*awssecretsmanager.ReplicaRegion {
+	if s.Options.Secrets == nil || len(s.Options.Secrets.ReplicaRegions) == 0 {
+		return nil
+	}
+	regions := make([]*awssecretsmanager.ReplicaRegion, 0, len(s.Options.Secrets.ReplicaRegions))
+	for i, replica := range s.Options.Secrets.ReplicaRegions {
+		region := &awssecretsmanager.ReplicaRegion{Region: jsii.String(replica.Region)}
+		if replica.KMSKeyARN != "" {
+			region.EncryptionKey = awskms.Key_FromKeyArn(s.Stack, jsii.String(fmt.Sprintf("SecretsReplicaKey%d", i)), jsii.String(replica.KMSKeyARN))
+		}
+		regions = append(regions, region)
+	}
+	return &regions
+}
+
+// tagSecret tags the stack's secret with the project, managed-by, and
+// secrets.tags tags.
+func (s *AgentCoreStack) tagSecret() {
+	if s.Secret == nil {
+		return
+	}
+	tags := map[string]string{
+		secretTagProject:   s.Config.StackName,
+		secretTagManagedBy: secretManagedBy,
+	}
+	if s.Options.Secrets != nil {
+		for key, value := range s.Options.Secrets.Tags {
+			tags[key] = value
+		}
+	}
+	for key, value := range tags {
+		awscdk.Tags_Of(s.Secret).Add(jsii.String(key), jsii.String(value), nil)
+	}
+}
+
+// restrictSecretRead denies reading the stack's secret to every principal
+// except the execution role and secrets.readerArns.
+func (s *AgentCoreStack) restrictSecretRead() {
+	if s.Secret == nil || s.Options.Secrets == nil || !s.Options.Secrets.RestrictRead {
+		return
+	}
+	readers := []*string{s.ExecutionRole.RoleArn()}
+	for _, arn := range s.Options.Secrets.ReaderARNs {
+		readers = append(readers, jsii.String(arn))
+	}
+	s.Secret.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Sid:        jsii.String("RestrictGetSecretValue"),
+		Effect:     awsiam.Effect_DENY,
+		Principals: &[]awsiam.IPrincipal{awsiam.NewAnyPrincipal()},
+		Actions:    jsii.Strings("secretsmanager:GetSecretValue"),
+		Resources:  jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"ArnNotLike": map[string]interface{}{
+				"aws:PrincipalArn": readers,
+			},
+		},
+	}))
+}
+
+// secretsPrefix returns the secret name prefix with its placeholders
+// replaced, the same prefix push-secrets expands for the project and
+// environment.
+func (s *AgentCoreStack) secretsPrefix() string {
+	opts := s.Options.Secrets
+	template := opts.Prefix
+	if template == "" {
+		template = secretsPrefixProject
+		if opts.Environment != "" {
+			template += "/" + secretsPrefixEnvironment
+		}
+	}
+	return s.expandSecretsPrefix(template)
+}
+
+// PushedSecretsLocation returns the backend agents read pushed secrets
+// from, SecretsBackendSecretsManager or SecretsBackendSSM, and the expanded
+// prefix they read them under, or "" if agents don't read pushed secrets
+// by prefix.
+func PushedSecretsLocation(config StackConfig, options StackOptions) (backend, prefix string) {
+	s := &AgentCoreStack{Config: config, Options: options}
+	switch {
+	case options.Secrets.usesSSM():
+		return SecretsBackendSSM, s.secretsPrefix()
+	case options.Secrets.usesPrefixedSecrets():
+		return SecretsBackendSecretsManager, s.secretsPrefix()
+	default:
+		return SecretsBackendSecretsManager, ""
+	}
+}
+
+// expandSecretsPrefix replaces the {project} and {env} placeholders of a
+// secret name prefix.
+func (s *AgentCoreStack) expandSecretsPrefix(template string) string {
+	opts := s.Options.Secrets
+	project := opts.Project
+	if project == "" {
+		project = s.Config.StackName
+		if opts.Environment != "" {
+			project = strings.TrimSuffix(project, "-"+opts.Environment)
+		}
+	}
+	return strings.NewReplacer(secretsPrefixProject, project, secretsPrefixEnvironment, opts.Environment).Replace(template)
+}
+
+// usesPrefixedSecrets reports whether agents read the {prefix}/{group}
+// secrets pushed to Secrets Manager.
+func (o *SecretsOptions) usesPrefixedSecrets() bool {
+	return o != nil && !o.usesSSM() && o.Prefix != ""
+}
+
+// ssmSecretsPath returns the parameter path of the ssm secrets backend.
+func (s *AgentCoreStack) ssmSecretsPath() string {
+	return "/" + s.secretsPrefix()
+}
+
+// secretGroups returns the secret groups of the ssm backend or a
+// secretsmanager prefix.
+func (s *AgentCoreStack) secretGroups() []string {
+	if groups := s.Options.Secrets.Groups; len(groups) > 0 {
+		return groups
+	}
+	return DefaultSecretGroups
+}
+
+// grantSSMSecrets grants the role read access to the SecureString parameters
+// of the ssm secrets backend.
+func (s *AgentCoreStack) grantSSMSecrets(role awsiam.IRole) {
+	if !s.Options.Secrets.usesSSM() {
+		return
+	}
+
+	path := s.ssmSecretsPath()
+	parameterARN := func(name string) *string {
+		return s.Stack.FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("ssm"),
+			Resource:     jsii.String("parameter"),
+			ResourceName: jsii.String(strings.TrimPrefix(name, "/")),
+		})
+	}
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("ssm:GetParameter", "ssm:GetParameters", "ssm:GetParametersByPath"),
+		Resources: &[]*string{parameterARN(path), parameterARN(path + "/*")},
+	}))
+
+	// SecureString values are encrypted with the secrets KMS key, or the
+	// AWS managed aws/ssm key
+	if s.Config.Secrets != nil && s.Config.Secrets.KMSKeyARN != "" {
+		role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect:    awsiam.Effect_ALLOW,
+			Actions:   jsii.Strings("kms:Decrypt"),
+			Resources: jsii.Strings(s.Config.Secrets.KMSKeyARN),
+		}))
+		return
+	}
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("kms:Decrypt"),
+		Resources: jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"kms:ViaService": fmt.Sprintf("ssm.%s.amazonaws.com", *s.Stack.Region()),
+			},
+		},
+	}))
+}
+
+// grantPrefixedSecrets grants the role read access to the {prefix}/{group}
+// secrets of a secretsmanager prefix. Pushed secrets are encrypted with the
+// aws/secretsmanager key, which needs no grant.
+func (s *AgentCoreStack) grantPrefixedSecrets(role awsiam.IRole) {
+	if !s.Options.Secrets.usesPrefixedSecrets() {
+		return
+	}
+
+	resources := make([]*string, 0, len(s.secretGroups()))
+	for _, group := range s.secretGroups() {
+		// Secrets Manager appends a random suffix to secret ARNs
+		resources = append(resources, s.Stack.FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("secretsmanager"),
+			Resource:     jsii.String("secret"),
+			ResourceName: jsii.String(s.secretsPrefix() + "/" + group + "-??????"),
+			ArnFormat:    awscdk.ArnFormat_COLON_RESOURCE_NAME,
+		}))
+	}
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"),
+		Resources: &resources,
+	}))
+}
+
+// addSecretsEnvironment injects the secret names agents read: for the ssm
+// backend, SECRETS_BACKEND, SECRETS_SSM_PATH, and SECRETS_SSM_PATH_{GROUP}
+// for each group; for a secretsmanager prefix, SECRETS_BACKEND,
+// SECRETS_PREFIX, and SECRETS_NAME_{GROUP}.
+func (s *AgentCoreStack) addSecretsEnvironment(envVars map[string]string) {
+	if s.Options.Secrets.usesPrefixedSecrets() {
+		prefix := s.secretsPrefix()
+		envVars["SECRETS_BACKEND"] = SecretsBackendSecretsManager
+		envVars["SECRETS_PREFIX"] = prefix
+		for _, group := range s.secretGroups() {
+			name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(group))
+			envVars["SECRETS_NAME_"+name] = prefix + "/" + group
+		}
+		return
+	}
+	if !s.Options.Secrets.usesSSM() {
+		return
+	}
+
+	path := s.ssmSecretsPath()
+	envVars["SECRETS_BACKEND"] = SecretsBackendSSM
+	envVars["SECRETS_SSM_PATH"] = path
+	for _, group := range s.secretGroups() {
+		name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(group))
+		envVars["SECRETS_SSM_PATH_"+name] = path + "/" + group
+	}
+}
+
+// envVarNamePattern matches environment variable names.
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SecretEnvVar injects a Secrets Manager secret into an agent environment
+// variable. The value is resolved by CloudFormation with a dynamic reference
+// when the runtime is deployed, so the agent reads it like any other
+// variable. Rotated values take effect on the next deployment.
+type SecretEnvVar struct {
+	// Name is the environment variable name.
+	Name string `json:"name" yaml:"name"`
+
+	// SecretARN is the secret holding the value.
+	// Default: the stack's secret (secrets.createSecrets)
+	SecretARN string `json:"secretArn,omitempty" yaml:"secretArn,omitempty"`
+
+	// JSONKey selects a key of a JSON secret.
+	// Default: the whole secret string
+	JSONKey string `json:"jsonKey,omitempty" yaml:"jsonKey,omitempty"`
+}
+
+// validateSecretEnvironment validates an agent's secret environment
+// variables.
+func validateSecretEnvironment(opts *AgentOptions, config StackConfig) error {
+	if opts == nil {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for i, secret := range opts.SecretEnvironment {
+		if !envVarNamePattern.MatchString(secret.Name) {
+			return fmt.Errorf("secretEnvironment[%d].name %q must match %s", i, secret.Name, envVarNamePattern)
+		}
+		if names[secret.Name] {
+			return fmt.Errorf("secretEnvironment[%d].name %s is already set by another secret", i, secret.Name)
+		}
+		names[secret.Name] = true
+
+		switch {
+		case secret.SecretARN == "":
+			if !createsSecret(config) {
+				return fmt.Errorf("secretEnvironment[%d] (%s): secretArn is required unless the stack creates its secret (secrets.createSecrets)", i, secret.Name)
+			}
+		case !*awscdk.Token_IsUnresolved(secret.SecretARN) && !secretARNPattern.MatchString(secret.SecretARN):
+			return fmt.Errorf("secretEnvironment[%d] (%s): invalid secret ARN %q", i, secret.Name, secret.SecretARN)
+		}
+	}
+	return nil
+}
+
+// createsSecret reports whether the stack creates its own secret.
+func createsSecret(config StackConfig) bool {
+	return config.Secrets != nil && config.Secrets.CreateSecrets
+}
+
+// usesStackSecretEnvironment reports whether an agent's secretEnvironment
+// reads the stack's secret.
+func usesStackSecretEnvironment(o *StackOptions) bool {
+	for _, opts := range o.Agents {
+		if opts == nil {
+			continue
+		}
+		for _, secret := range opts.SecretEnvironment {
+			if secret.SecretARN == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addSecretEnvironment injects the agent's secret environment variables as
+// Secrets Manager dynamic references. They stay inline when the rest of the
+// environment is moved to the config store, so secret values are never
+// written to it.
+func (s *AgentCoreStack) addSecretEnvironment(config *AgentConfig, envVars map[string]string) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil {
+		return
+	}
+
+	for _, secret := range opts.SecretEnvironment {
+		var value awscdk.SecretValue
+		switch {
+		case secret.SecretARN == "" && secret.JSONKey != "":
+			value = s.Secret.SecretValueFromJson(jsii.String(secret.JSONKey))
+		case secret.SecretARN == "":
+			value = s.Secret.SecretValue()
+		default:
+			secretOpts := &awscdk.SecretsManagerSecretOptions{}
+			if secret.JSONKey != "" {
+				secretOpts.JsonField = jsii.String(secret.JSONKey)
+			}
+			value = awscdk.SecretValue_SecretsManager(jsii.String(secret.SecretARN), secretOpts)
+		}
+		envVars[secret.Name] = *value.UnsafeUnwrap()
+	}
+}
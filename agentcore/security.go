@@ -0,0 +1,353 @@
+package agentcore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Security check rules. They are the stack's own rules, not cdk-nag's,
+// each modeled on a cdk-nag AwsSolutions rule (IAM5, EC23, S10, SQS4, and
+// SNS3).
+const (
+	// SecurityRuleWildcardPermissions flags identity policies allowing all
+	// actions of a service, or actions on all resources.
+	SecurityRuleWildcardPermissions = "agentkit:wildcard-permissions"
+
+	// SecurityRuleOpenIngress flags security groups open to the internet.
+	SecurityRuleOpenIngress = "agentkit:open-ingress"
+
+	// SecurityRuleBucketTLS flags buckets that allow requests without TLS.
+	SecurityRuleBucketTLS = "agentkit:bucket-tls"
+
+	// SecurityRuleQueueTLS flags queues that allow requests without TLS.
+	SecurityRuleQueueTLS = "agentkit:queue-tls"
+
+	// SecurityRuleTopicTLS flags topics that allow publishing without TLS.
+	SecurityRuleTopicTLS = "agentkit:topic-tls"
+)
+
+// securityRules are the rules run by the security checks.
+var securityRules = []string{
+	SecurityRuleWildcardPermissions,
+	SecurityRuleOpenIngress,
+	SecurityRuleBucketTLS,
+	SecurityRuleQueueTLS,
+	SecurityRuleTopicTLS,
+}
+
+// unscopedActions are actions without resource-level permissions, which
+// can only be granted on all resources.
+var unscopedActions = map[string]bool{
+	"ecr:GetAuthorizationToken":          true,
+	"xray:PutTraceSegments":              true,
+	"xray:PutTelemetryRecords":           true,
+	"xray:GetSamplingRules":              true,
+	"xray:GetSamplingTargets":            true,
+	"xray:GetSamplingStatisticSummaries": true,
+	"cloudwatch:PutMetricData":           true,
+}
+
+// SecurityChecksOptions checks the stack's resources against security rules
+// during synth. Findings are reported as synth errors, which fail
+// `cdk synth` and `cdk deploy`, or as warnings.
+type SecurityChecksOptions struct {
+	// WarnOnly reports findings as warnings instead of errors. Warnings
+	// still fail `cdk synth --strict`.
+	WarnOnly bool `json:"warnOnly,omitempty" yaml:"warnOnly,omitempty"`
+
+	// Suppressions accept findings with a documented reason.
+	Suppressions []SecuritySuppression `json:"suppressions,omitempty" yaml:"suppressions,omitempty"`
+}
+
+// SecuritySuppression accepts the findings of a rule.
+type SecuritySuppression struct {
+	// Rule is the rule ID, e.g. "agentkit:wildcard-permissions".
+	Rule string `json:"rule" yaml:"rule"`
+
+	// Path limits the suppression to the constructs under a construct path
+	// relative to the stack, e.g. "ExecutionRole".
+	// Default: every construct in the stack
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// Reason documents why the finding is accepted.
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// validate validates the security check options.
+func (o *SecurityChecksOptions) validate() error {
+	for i, suppression := range o.Suppressions {
+		known := false
+		for _, rule := range securityRules {
+			if suppression.Rule == rule {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("securityChecks.suppressions[%d]: rule %q must be one of %v", i, suppression.Rule, securityRules)
+		}
+		if strings.TrimSpace(suppression.Reason) == "" {
+			return fmt.Errorf("securityChecks.suppressions[%d] (%s): reason is required", i, suppression.Rule)
+		}
+	}
+	return nil
+}
+
+// suppressed reports whether a finding of rule on the construct at path
+// (relative to the stack) is suppressed.
+func (o *SecurityChecksOptions) suppressed(rule, path string) bool {
+	for _, suppression := range o.Suppressions {
+		if suppression.Rule != rule {
+			continue
+		}
+		prefix := strings.Trim(suppression.Path, "/")
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSecurity runs the security rules against the stack's resources. It
+// runs after all resources are created.
+func (s *AgentCoreStack) checkSecurity() {
+	opts := s.Options.SecurityChecks
+	if opts == nil {
+		return
+	}
+
+	stackPath := *s.Stack.Node().Path() + "/"
+	report := func(c constructs.IConstruct, rule, format string, args ...any) {
+		path := strings.TrimPrefix(*c.Node().Path(), stackPath)
+		if opts.suppressed(rule, path) {
+			return
+		}
+		message := fmt.Sprintf("[%s] %s: %s", rule, path, fmt.Sprintf(format, args...))
+		if opts.WarnOnly {
+			awscdk.Annotations_Of(c).AddWarningV2(jsii.String(rule), jsii.String(message))
+		} else {
+			awscdk.Annotations_Of(c).AddError(jsii.String(message))
+		}
+	}
+
+	for _, c := range *s.Stack.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		switch resource := c.(type) {
+		case awsiam.CfnPolicy:
+			s.checkPolicyDocument(c, resource.PolicyDocument(), report)
+		case awsiam.CfnManagedPolicy:
+			s.checkPolicyDocument(c, resource.PolicyDocument(), report)
+		case awsiam.CfnRole:
+			for _, policy := range asList(s.resolve(c, resource.Policies())) {
+				if policy, ok := policy.(map[string]interface{}); ok {
+					s.checkPolicyDocument(c, policy["PolicyDocument"], report)
+				}
+			}
+		case awsec2.CfnSecurityGroup:
+			for _, rule := range asList(s.resolve(c, resource.SecurityGroupIngress())) {
+				if rule, ok := rule.(map[string]interface{}); ok && openToInternet(rule["CidrIp"], rule["CidrIpv6"]) {
+					report(c, SecurityRuleOpenIngress, "allows inbound traffic from the internet")
+				}
+			}
+		case awsec2.CfnSecurityGroupIngress:
+			if openToInternet(s.resolve(c, resource.CidrIp()), s.resolve(c, resource.CidrIpv6())) {
+				report(c, SecurityRuleOpenIngress, "allows inbound traffic from the internet")
+			}
+		}
+	}
+
+	// Resources are TLS-only if a resource policy denies requests without
+	// TLS, whether created with EnforceSSL or by the tls options
+	secure := s.secureTransportResources()
+	for _, c := range *s.Stack.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		resource, ok := c.(awscdk.CfnResource)
+		if !ok || secure[resourceKey(c, s.resolve(c, resource.LogicalId()))] {
+			continue
+		}
+		switch *resource.CfnResourceType() {
+		case "AWS::S3::Bucket":
+			report(c, SecurityRuleBucketTLS, "allows requests without TLS (enable tls)")
+		case "AWS::SQS::Queue":
+			report(c, SecurityRuleQueueTLS, "allows requests without TLS (enable tls)")
+		case "AWS::SNS::Topic":
+			report(c, SecurityRuleTopicTLS, "allows publishing without TLS (enable tls)")
+		}
+	}
+}
+
+// secureTransportResources returns the buckets, queues, and topics whose
+// resource policy denies requests without TLS, by resourceKey.
+func (s *AgentCoreStack) secureTransportResources() map[string]bool {
+	secure := make(map[string]bool)
+	add := func(c constructs.IConstruct, document, targets interface{}) {
+		resolved, ok := s.resolve(c, document).(map[string]interface{})
+		if !ok || !deniesInsecureTransport(resolved) {
+			return
+		}
+		for _, target := range asList(s.resolve(c, targets)) {
+			// Policies reference their resources with Ref
+			if ref, ok := target.(map[string]interface{}); ok {
+				secure[resourceKey(c, ref["Ref"])] = true
+			}
+		}
+	}
+
+	for _, c := range *s.Stack.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		switch policy := c.(type) {
+		case awss3.CfnBucketPolicy:
+			add(c, policy.PolicyDocument(), policy.Bucket())
+		case awssqs.CfnQueuePolicy:
+			add(c, policy.PolicyDocument(), policy.Queues())
+		case awssns.CfnTopicPolicy:
+			add(c, policy.PolicyDocument(), policy.Topics())
+		}
+	}
+
+	// Raw resources are plain CfnResources, whose properties are in the
+	// options
+	targetProperties := map[string]string{
+		"AWS::S3::BucketPolicy": "Bucket",
+		"AWS::SQS::QueuePolicy": "Queues",
+		"AWS::SNS::TopicPolicy": "Topics",
+	}
+	for _, raw := range s.Options.RawResources {
+		if property, ok := targetProperties[raw.Type]; ok {
+			add(s.Stack, raw.Properties["PolicyDocument"], raw.Properties[property])
+		}
+	}
+	return secure
+}
+
+// deniesInsecureTransport reports whether a policy document denies
+// requests made without TLS.
+func deniesInsecureTransport(document map[string]interface{}) bool {
+	for _, statement := range asList(document["Statement"]) {
+		statement, ok := statement.(map[string]interface{})
+		if !ok || statement["Effect"] != "Deny" {
+			continue
+		}
+		condition, _ := statement["Condition"].(map[string]interface{})
+		test, _ := condition["Bool"].(map[string]interface{})
+		if value := test["aws:SecureTransport"]; value == "false" || value == false {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceKey identifies the resource with a logical ID in the (possibly
+// nested) stack of the construct c.
+func resourceKey(c constructs.IConstruct, logicalID interface{}) string {
+	return fmt.Sprintf("%s/%v", *awscdk.Stack_Of(c).Node().Path(), logicalID)
+}
+
+// checkPolicyDocument reports the wildcard permissions of an identity
+// policy document.
+func (s *AgentCoreStack) checkPolicyDocument(c constructs.IConstruct, document interface{}, report func(constructs.IConstruct, string, string, ...any)) {
+	resolved, ok := s.resolve(c, document).(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for _, statement := range asList(resolved["Statement"]) {
+		statement, ok := statement.(map[string]interface{})
+		if !ok || statement["Effect"] != "Allow" {
+			continue
+		}
+		actions := asStrings(statement["Action"])
+
+		for _, action := range actions {
+			if action == "*" || strings.HasSuffix(action, ":*") {
+				report(c, SecurityRuleWildcardPermissions, "allows all actions %q", action)
+			}
+		}
+
+		for _, resource := range asList(statement["Resource"]) {
+			resource, ok := resource.(string)
+			if !ok {
+				// Intrinsic functions reference resources of the stack
+				continue
+			}
+			switch {
+			case resource == "*":
+				if statement["Condition"] != nil || allUnscoped(actions) {
+					continue
+				}
+				report(c, SecurityRuleWildcardPermissions, "allows %s on all resources", strings.Join(actions, ", "))
+			case arnAccountWildcard(resource):
+				report(c, SecurityRuleWildcardPermissions, "allows %s on resources of any account (%s)", strings.Join(actions, ", "), resource)
+			}
+		}
+	}
+}
+
+// allUnscoped reports whether none of the actions support resource-level
+// permissions.
+func allUnscoped(actions []string) bool {
+	for _, action := range actions {
+		if !unscopedActions[action] {
+			return false
+		}
+	}
+	return len(actions) > 0
+}
+
+// arnAccountWildcard reports whether an ARN matches resources of any
+// account.
+func arnAccountWildcard(arn string) bool {
+	parts := strings.SplitN(arn, ":", 6)
+	return len(parts) == 6 && parts[0] == "arn" && strings.Contains(parts[4], "*")
+}
+
+// openToInternet reports whether a CIDR block is the whole internet.
+func openToInternet(cidrs ...interface{}) bool {
+	for _, cidr := range cidrs {
+		if cidr == "0.0.0.0/0" || cidr == "::/0" {
+			return true
+		}
+	}
+	return false
+}
+
+// asList returns a resolved value as a list, wrapping a single value.
+func asList(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// asStrings returns the strings of a resolved string or list of strings,
+// sorted.
+func asStrings(v interface{}) []string {
+	var values []string
+	for _, item := range asList(v) {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// resolve resolves the tokens of an optional property value of the
+// construct c, in the (possibly nested) stack c belongs to.
+func (s *AgentCoreStack) resolve(c constructs.IConstruct, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return awscdk.Stack_Of(c).Resolve(v)
+}
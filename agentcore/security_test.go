@@ -0,0 +1,127 @@
+package agentcore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// tlsFindings synthesizes the stack of b and returns the findings of the
+// TLS rules, as "rule path".
+func tlsFindings(t *testing.T, b *StackBuilder) []string {
+	t.Helper()
+	s := b.Build(awscdk.NewApp(nil))
+	var findings []string
+	for _, m := range *assertions.Annotations_FromStack(s.Stack).FindError(jsii.String("*"), assertions.Match_AnyValue()) {
+		message := fmt.Sprint(m.Entry.Data)
+		for _, rule := range []string{SecurityRuleBucketTLS, SecurityRuleQueueTLS, SecurityRuleTopicTLS} {
+			if strings.HasPrefix(message, "["+rule+"]") {
+				findings = append(findings, rule+" "+strings.TrimPrefix(*m.Id, "/"+*s.Stack.StackName()+"/"))
+			}
+		}
+	}
+	sort.Strings(findings)
+	return findings
+}
+
+func TestSecurityChecksTLS(t *testing.T) {
+	secureTransport := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []interface{}{map[string]interface{}{
+			"Effect":    "Deny",
+			"Principal": "*",
+			"Action":    "sqs:*",
+			"Resource":  map[string]interface{}{"Fn::GetAtt": []interface{}{"RawQueue", "Arn"}},
+			"Condition": map[string]interface{}{"Bool": map[string]interface{}{"aws:SecureTransport": "false"}},
+		}},
+	}
+
+	tests := []struct {
+		name  string
+		build func(b *StackBuilder)
+		want  []string
+	}{
+		{
+			// The dead-letter queue, artifact bucket, and usage bucket are
+			// created with EnforceSSL
+			name: "EnforceSSL resources",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(NewAgentBuilder("research", "img").WithSchedule("rate(1 hour)", nil)).
+					WithUsageMetrics()
+			},
+		},
+		{
+			name: "raw resources without a TLS policy",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(NewAgentBuilder("research", "img")).
+					WithRawResource(RawResource{ID: "RawBucket", Type: "AWS::S3::Bucket"}).
+					WithRawResource(RawResource{ID: "RawQueue", Type: "AWS::SQS::Queue"}).
+					WithRawResource(RawResource{ID: "RawTopic", Type: "AWS::SNS::Topic"})
+			},
+			want: []string{
+				SecurityRuleBucketTLS + " RawBucket",
+				SecurityRuleQueueTLS + " RawQueue",
+				SecurityRuleTopicTLS + " RawTopic",
+			},
+		},
+		{
+			name: "raw queue with a TLS policy",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(NewAgentBuilder("research", "img")).
+					WithRawResource(RawResource{ID: "RawQueue", Type: "AWS::SQS::Queue"}).
+					WithRawResource(RawResource{ID: "RawQueuePolicy", Type: "AWS::SQS::QueuePolicy", Properties: map[string]interface{}{
+						"Queues":         []interface{}{map[string]interface{}{"Ref": "RawQueue"}},
+						"PolicyDocument": secureTransport,
+					}})
+			},
+		},
+		{
+			name: "tls options",
+			build: func(b *StackBuilder) {
+				b.WithAgentBuilder(NewAgentBuilder("research", "img")).
+					WithRawResource(RawResource{ID: "RawQueue", Type: "AWS::SQS::Queue"}).
+					WithTLSEnforcement("1.2")
+			},
+			// Raw resources aren't L2 constructs, so tls can't enforce it
+			want: []string{SecurityRuleQueueTLS + " RawQueue"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewStackBuilder("sec-test").WithSecurityChecks()
+			tt.build(b)
+			got := tlsFindings(t, b)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeniesInsecureTransport(t *testing.T) {
+	statement := func(effect string, condition interface{}) map[string]interface{} {
+		return map[string]interface{}{"Statement": []interface{}{map[string]interface{}{"Effect": effect, "Condition": condition}}}
+	}
+	tests := []struct {
+		name     string
+		document map[string]interface{}
+		want     bool
+	}{
+		{"deny without TLS", statement("Deny", map[string]interface{}{"Bool": map[string]interface{}{"aws:SecureTransport": "false"}}), true},
+		{"boolean condition", statement("Deny", map[string]interface{}{"Bool": map[string]interface{}{"aws:SecureTransport": false}}), true},
+		{"allow", statement("Allow", map[string]interface{}{"Bool": map[string]interface{}{"aws:SecureTransport": "false"}}), false},
+		{"deny with TLS", statement("Deny", map[string]interface{}{"Bool": map[string]interface{}{"aws:SecureTransport": "true"}}), false},
+		{"other condition", statement("Deny", map[string]interface{}{"NumericLessThan": map[string]interface{}{"s3:TlsVersion": "1.2"}}), false},
+		{"no statements", map[string]interface{}{}, false},
+	}
+	for _, tt := range tests {
+		if got := deniesInsecureTransport(tt.document); got != tt.want {
+			t.Errorf("%s: deniesInsecureTransport = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
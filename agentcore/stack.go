@@ -1,14 +1,28 @@
 package agentcore
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrock"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsopensearchserverless"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsscheduler"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
 	"github.com/plexusone/agentkit/platforms/agentcore/iac"
@@ -43,6 +57,9 @@ type AgentCoreStack struct {
 	// Config is the stack configuration.
 	Config StackConfig
 
+	// Options contains CDK-specific settings not covered by Config.
+	Options StackOptions
+
 	// VPC is the VPC used by the agents.
 	VPC awsec2.IVpc
 
@@ -52,9 +69,18 @@ type AgentCoreStack struct {
 	// ExecutionRole is the IAM role used by agents.
 	ExecutionRole awsiam.IRole
 
+	// KMSKey is the customer managed key encrypting stack resources
+	// (Options.KMS only).
+	KMSKey awskms.IKey
+
 	// Secret is the Secrets Manager secret containing API keys.
 	Secret awssecretsmanager.ISecret
 
+	// ImportedSecretARNs contains the ARNs of the imported secrets keyed
+	// by name, resolved when the stack is deployed
+	// (Options.Secrets.ImportSecrets only).
+	ImportedSecretARNs map[string]*string
+
 	// LogGroup is the CloudWatch log group for agent logs.
 	LogGroup awslogs.ILogGroup
 
@@ -64,11 +90,94 @@ type AgentCoreStack struct {
 	// Runtimes contains the AgentCore runtime resources.
 	Runtimes map[string]awsbedrockagentcore.CfnRuntime
 
-	// Endpoints contains the AgentCore runtime endpoint resources.
+	// Endpoints contains the default AgentCore runtime endpoint resources.
 	Endpoints map[string]awsbedrockagentcore.CfnRuntimeEndpoint
 
+	// NamedEndpoints contains the additional runtime endpoints keyed by agent
+	// name, then endpoint name (AgentOptions.Endpoints only).
+	NamedEndpoints map[string]map[string]awsbedrockagentcore.CfnRuntimeEndpoint
+
+	// Schedules contains the agent schedules keyed by agent name
+	// (AgentOptions.Schedules only).
+	Schedules map[string][]awsscheduler.Schedule
+
+	// SchedulerRole is the role EventBridge Scheduler invokes agents with
+	// (agents with schedules only).
+	SchedulerRole awsiam.Role
+
+	// ScheduleDeadLetterQueue receives scheduled invocations that failed
+	// (agents with schedules only).
+	ScheduleDeadLetterQueue awssqs.Queue
+
+	// Triggers contains the agent triggers keyed by agent name, then
+	// trigger name (AgentOptions.Triggers only).
+	Triggers map[string]map[string]*AgentTrigger
+
+	// Memories contains the AgentCore memory resources (agents with a memory store only).
+	Memories map[string]awsbedrockagentcore.CfnMemory
+
+	// ConfigBucket holds offloaded agent configuration (S3 config store only).
+	ConfigBucket awss3.IBucket
+
 	// Gateway is the multi-agent routing gateway (if enabled).
 	Gateway awsbedrockagentcore.CfnGateway
+
+	// GatewayTargets contains the gateway target resources keyed by target name.
+	GatewayTargets map[string]awsbedrockagentcore.CfnGatewayTarget
+
+	// CollectorConfig holds the ADOT collector configuration (X-Ray
+	// collector only).
+	CollectorConfig awsssm.StringParameter
+
+	// LogMetrics contains the metrics derived from the log group keyed by
+	// name (Options.Observability.LogMetrics only).
+	LogMetrics map[string]awscloudwatch.Metric
+
+	// ArtifactBucket is the bucket the agents share for inputs and outputs
+	// (if configured).
+	ArtifactBucket awss3.IBucket
+
+	// StateTable is the table the agents share for durable state (if
+	// configured).
+	StateTable awsdynamodb.Table
+
+	// KnowledgeBases contains the knowledge bases created by the stack
+	// keyed by name. Attached existing knowledge bases are not included.
+	KnowledgeBases map[string]awsbedrock.CfnKnowledgeBase
+
+	// VectorCollections contains the OpenSearch Serverless collections of
+	// the created knowledge bases keyed by knowledge base name.
+	VectorCollections map[string]awsopensearchserverless.CfnCollection
+
+	// RedisURL is the URL of the cache the agents share (if configured).
+	RedisURL *string
+
+	// RedisReaderURL is the URL of the cache's read replicas (node-based
+	// caches with replicas only).
+	RedisReaderURL *string
+
+	// UsageReportBucket holds the daily LLM cost reports
+	// (Options.Observability.UsageMetrics only).
+	UsageReportBucket awss3.IBucket
+
+	// Dashboard is the agent dashboard (if configured).
+	Dashboard awscloudwatch.Dashboard
+
+	// DeploymentHistory is the deployment history table (if configured).
+	DeploymentHistory awsdynamodb.Table
+
+	// HTTPAPI is the HTTP API invoking the agents (if configured).
+	HTTPAPI awsapigatewayv2.HttpApi
+
+	// GatewayInterceptor is the gateway interceptor function (if configured).
+	GatewayInterceptor awslambda.IFunction
+
+	// RawResources contains the resources declared in Options.RawResources keyed by ID.
+	RawResources map[string]awscdk.CfnResource
+
+	// AgentStacks contains the nested stack holding each agent's resources,
+	// keyed by agent name (Options.Partition only).
+	AgentStacks map[string]awscdk.NestedStack
 }
 
 // AgentConstruct represents a single AgentCore agent.
@@ -90,41 +199,125 @@ type AgentConstruct struct {
 
 // NewAgentCoreStack creates a new AgentCore CDK stack.
 func NewAgentCoreStack(scope constructs.Construct, id string, config StackConfig) *AgentCoreStack {
+	return NewAgentCoreStackWithOptions(scope, id, config, StackOptions{})
+}
+
+// NewAgentCoreStackWithOptions creates a new AgentCore CDK stack with
+// CDK-specific options.
+func NewAgentCoreStackWithOptions(scope constructs.Construct, id string, config StackConfig, options StackOptions) *AgentCoreStack {
 	// Validate and apply defaults
 	config.ApplyDefaults()
+	if err := validateTokens(config, options); err != nil {
+		panic(fmt.Sprintf("invalid stack configuration: %v", err))
+	}
+	applyGlobalEnvironment(&config, options.GlobalEnvironment)
+	if err := validateProtocols(config.Agents); err != nil {
+		panic(fmt.Sprintf("invalid stack configuration: %v", err))
+	}
+	if err := validateMemory(config.Agents); err != nil {
+		panic(fmt.Sprintf("invalid stack configuration: %v", err))
+	}
 	if err := config.Validate(); err != nil {
 		panic(fmt.Sprintf("invalid stack configuration: %v", err))
 	}
+	if err := options.Validate(config); err != nil {
+		panic(fmt.Sprintf("invalid stack options: %v", err))
+	}
+	if err := validateStackRegion(config, options); err != nil {
+		panic(fmt.Sprintf("invalid stack region: %v", err))
+	}
+	if options.ValidateImages {
+		if err := validateImages(context.Background(), config); err != nil {
+			panic(fmt.Sprintf("invalid container image: %v", err))
+		}
+	}
 
 	// Create the stack
 	stack := awscdk.NewStack(scope, jsii.String(id), &awscdk.StackProps{
 		StackName:   jsii.String(config.StackName),
 		Description: jsii.String(config.Description),
 		Tags:        convertTags(config.Tags),
+		Env:         lookupEnvironment(config, options),
+
+		Synthesizer:         stackSynthesizer(options),
+		PermissionsBoundary: stackPermissionsBoundary(options),
 	})
 
 	s := &AgentCoreStack{
 		Stack:     stack,
 		Config:    config,
+		Options:   options,
 		Agents:    make(map[string]*AgentConstruct),
 		Runtimes:  make(map[string]awsbedrockagentcore.CfnRuntime),
 		Endpoints: make(map[string]awsbedrockagentcore.CfnRuntimeEndpoint),
+		Memories:  make(map[string]awsbedrockagentcore.CfnMemory),
+
+		NamedEndpoints: make(map[string]map[string]awsbedrockagentcore.CfnRuntimeEndpoint),
+		Schedules:      make(map[string][]awsscheduler.Schedule),
+		Triggers:       make(map[string]map[string]*AgentTrigger),
+		GatewayTargets: make(map[string]awsbedrockagentcore.CfnGatewayTarget),
+		RawResources:   make(map[string]awscdk.CfnResource),
+		AgentStacks:    make(map[string]awscdk.NestedStack),
 	}
 
 	// Create infrastructure
 	s.createVPC()
 	s.createSecurityGroup()
+	s.createKMSKey()
 	s.createSecrets()
+	s.tagSecret()
+	s.lookupImportedSecrets()
 	s.createIAMRole()
+	s.restrictSecretRead()
+	s.createCollectorConfig()
 	s.createLogGroup()
-
-	// Create agents
-	for _, agentConfig := range config.Agents {
+	s.createLogSubscription()
+	s.createLogMetrics()
+	s.createUsageMetrics()
+	s.createArtifactBucket()
+	s.createStateTable()
+	s.createKnowledgeBases()
+	s.createRedis()
+
+	// Create agents, upstream agents first (validated above)
+	agents, _ := dependencyOrder(config.Agents, options.Agents)
+	for _, agentConfig := range agents {
 		s.createAgent(agentConfig)
 	}
+	s.grantAgentInvokes()
 
 	// Create gateway if enabled
 	s.createGateway()
+	s.createMCPGatewayTargets()
+	s.createRemoteGatewayTargets()
+	s.createToolTargets()
+
+	// Create the HTTP API front-end if configured
+	s.createHTTPAPI()
+
+	// Create raw resources not yet modeled by aws-cdk-go
+	s.createRawResources()
+
+	// Create the dashboard if configured
+	s.createDashboard()
+
+	// Create the deployment history table if configured
+	s.createDeploymentHistory()
+
+	// Let the consumer customize the generated resources
+	s.runResourceHooks()
+
+	// Apply the removal policies of the resource classes
+	s.applyRemovalPolicies()
+
+	// Enforce TLS on every resource created above
+	s.enforceTLS()
+
+	// Check the resources against the security rules
+	s.checkSecurity()
+
+	// Check the configuration against the selected policies
+	s.checkPolicies()
 
 	// Add outputs
 	s.addOutputs()
@@ -136,33 +329,16 @@ func NewAgentCoreStack(scope constructs.Construct, id string, config StackConfig
 func (s *AgentCoreStack) createVPC() {
 	vpcConfig := s.Config.VPC
 
-	if vpcConfig.VPCID != "" {
+	if !s.needsVPC() {
+		return // All agents use PUBLIC network mode
+	}
+
+	if vpcConfig.VPCID != "" || s.Options.VPC.lookupByTags() {
 		// Import existing VPC
-		s.VPC = awsec2.Vpc_FromLookup(s.Stack, jsii.String("VPC"), &awsec2.VpcLookupOptions{
-			VpcId: jsii.String(vpcConfig.VPCID),
-		})
+		s.VPC = awsec2.Vpc_FromLookup(s.Stack, jsii.String("VPC"), s.vpcLookupOptions())
 	} else if vpcConfig.CreateVPC {
 		// Create new VPC
-		s.VPC = awsec2.NewVpc(s.Stack, jsii.String("VPC"), &awsec2.VpcProps{
-			VpcName:            jsii.String(fmt.Sprintf("%s-vpc", s.Config.StackName)),
-			IpAddresses:        awsec2.IpAddresses_Cidr(jsii.String(vpcConfig.VPCCidr)),
-			MaxAzs:             jsii.Number(float64(vpcConfig.MaxAZs)),
-			NatGateways:        jsii.Number(1),
-			EnableDnsHostnames: jsii.Bool(true),
-			EnableDnsSupport:   jsii.Bool(true),
-			SubnetConfiguration: &[]*awsec2.SubnetConfiguration{
-				{
-					Name:       jsii.String("Public"),
-					SubnetType: awsec2.SubnetType_PUBLIC,
-					CidrMask:   jsii.Number(24),
-				},
-				{
-					Name:       jsii.String("Private"),
-					SubnetType: awsec2.SubnetType_PRIVATE_WITH_EGRESS,
-					CidrMask:   jsii.Number(24),
-				},
-			},
-		})
+		s.VPC = awsec2.NewVpc(s.Stack, jsii.String("VPC"), s.newVPCProps())
 
 		// Add VPC endpoints if enabled
 		if vpcConfig.EnableVPCEndpoints {
@@ -193,6 +369,13 @@ func (s *AgentCoreStack) createVPCEndpoints() {
 		Service: awsec2.InterfaceVpcEndpointAwsService_SECRETS_MANAGER(),
 	})
 
+	// SSM endpoint for the ssm secrets backend
+	if s.Options.Secrets.usesSSM() {
+		vpc.AddInterfaceEndpoint(jsii.String("SSMEndpoint"), &awsec2.InterfaceVpcEndpointOptions{
+			Service: awsec2.InterfaceVpcEndpointAwsService_SSM(),
+		})
+	}
+
 	// CloudWatch Logs endpoint
 	vpc.AddInterfaceEndpoint(jsii.String("LogsEndpoint"), &awsec2.InterfaceVpcEndpointOptions{
 		Service: awsec2.InterfaceVpcEndpointAwsService_CLOUDWATCH_LOGS(),
@@ -214,6 +397,10 @@ func (s *AgentCoreStack) createVPCEndpoints() {
 
 // createSecurityGroup creates the security group for agent communication.
 func (s *AgentCoreStack) createSecurityGroup() {
+	if s.VPC == nil && len(s.Config.VPC.SecurityGroupIDs) == 0 {
+		return // No VPC to attach a security group to
+	}
+
 	if len(s.Config.VPC.SecurityGroupIDs) > 0 {
 		// Import existing security group
 		s.SecurityGroup = awsec2.SecurityGroup_FromSecurityGroupId(
@@ -241,34 +428,45 @@ func (s *AgentCoreStack) createSecurityGroup() {
 	}
 }
 
-// createSecrets creates or imports secrets.
+// createSecrets creates the stack's secret with secrets.secretValues,
+// secrets.valueReferences, and the secrets.generate key. Plain secret
+// values end up in the template, so they are reported as synth warnings.
 func (s *AgentCoreStack) createSecrets() {
-	if s.Config.Secrets == nil {
+	if !createsSecret(s.Config) {
 		return
 	}
 
 	secretsConfig := s.Config.Secrets
+	secretName := secretsConfig.SecretName
+	if secretName == "" {
+		secretName = fmt.Sprintf("%s-secrets", s.Config.StackName)
+	}
 
-	if secretsConfig.CreateSecrets && len(secretsConfig.SecretValues) > 0 {
-		secretName := secretsConfig.SecretName
-		if secretName == "" {
-			secretName = fmt.Sprintf("%s-secrets", s.Config.StackName)
-		}
+	// Encrypt with the secrets key, or the stack's customer managed key
+	encryptionKey := s.KMSKey
+	if secretsConfig.KMSKeyARN != "" {
+		encryptionKey = awskms.Key_FromKeyArn(s.Stack, jsii.String("SecretsKey"), jsii.String(secretsConfig.KMSKeyARN))
+	}
 
-		// Convert secret values to JSON structure
-		secretJSON := make(map[string]interface{})
-		for k, v := range secretsConfig.SecretValues {
-			secretJSON[k] = v
-		}
+	props := &awssecretsmanager.SecretProps{
+		SecretName:     jsii.String(secretName),
+		EncryptionKey:  encryptionKey,
+		Description:    jsii.String(fmt.Sprintf("Secrets for %s AgentCore agents", s.Config.StackName)),
+		ReplicaRegions: s.secretReplicaRegions(),
+	}
+	values, plaintext := s.secretObjectValue()
+	if s.Options.Secrets != nil && s.Options.Secrets.Generate != nil {
+		props.GenerateSecretString = s.generateSecretString(values)
+	} else {
+		props.SecretObjectValue = &values
+	}
+	s.Secret = awssecretsmanager.NewSecret(s.Stack, jsii.String("Secrets"), props)
 
-		s.Secret = awssecretsmanager.NewSecret(s.Stack, jsii.String("Secrets"), &awssecretsmanager.SecretProps{
-			SecretName:        jsii.String(secretName),
-			Description:       jsii.String(fmt.Sprintf("Secrets for %s AgentCore agents", s.Config.StackName)),
-			SecretObjectValue: &map[string]awscdk.SecretValue{
-				// Note: In production, use SecretValue.unsafePlainText only for initial setup
-				// Prefer external secret management or CDK context for sensitive values
-			},
-		})
+	if len(plaintext) > 0 {
+		awscdk.Annotations_Of(s.Secret).AddWarningV2(jsii.String(secretPlaintextWarningID), jsii.String(fmt.Sprintf(
+			"secrets.secretValues %s are written in plaintext to the CloudFormation template and cdk.out; "+
+				"push them with push-secrets and copy them with secrets.valueReferences, or generate them with secrets.generate",
+			strings.Join(plaintext, ", "))))
 	}
 }
 
@@ -293,6 +491,7 @@ func (s *AgentCoreStack) createIAMRole() {
 		Description: jsii.String(fmt.Sprintf("Execution role for %s AgentCore agents", s.Config.StackName)),
 		AssumedBy: awsiam.NewCompositePrincipal(
 			awsiam.NewServicePrincipal(jsii.String("bedrock.amazonaws.com"), nil),
+			awsiam.NewServicePrincipal(jsii.String("bedrock-agentcore.amazonaws.com"), nil),
 			awsiam.NewServicePrincipal(jsii.String("lambda.amazonaws.com"), nil),
 		),
 	})
@@ -303,7 +502,7 @@ func (s *AgentCoreStack) createIAMRole() {
 			// Specific model access
 			resources := make([]*string, len(iamConfig.BedrockModelIDs))
 			for i, modelID := range iamConfig.BedrockModelIDs {
-				resources[i] = jsii.String(fmt.Sprintf("arn:aws:bedrock:*:*:foundation-model/%s", modelID))
+				resources[i] = jsii.String(fmt.Sprintf("arn:aws:bedrock:*::foundation-model/%s", modelID))
 			}
 			role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 				Effect:    awsiam.Effect_ALLOW,
@@ -315,7 +514,7 @@ func (s *AgentCoreStack) createIAMRole() {
 			role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 				Effect:    awsiam.Effect_ALLOW,
 				Actions:   jsii.Strings("bedrock:InvokeModel", "bedrock:InvokeModelWithResponseStream"),
-				Resources: jsii.Strings("arn:aws:bedrock:*:*:foundation-model/*"),
+				Resources: jsii.Strings("arn:aws:bedrock:*::foundation-model/*"),
 			}))
 		}
 	}
@@ -328,7 +527,7 @@ func (s *AgentCoreStack) createIAMRole() {
 			"logs:CreateLogStream",
 			"logs:PutLogEvents",
 		),
-		Resources: jsii.Strings("arn:aws:logs:*:*:*"),
+		Resources: jsii.Strings(fmt.Sprintf("arn:%s:logs:%s:%s:*", *s.Stack.Partition(), *s.Stack.Region(), *s.Stack.Account())),
 	}))
 
 	// Add Secrets Manager access if secrets exist
@@ -348,17 +547,41 @@ func (s *AgentCoreStack) createIAMRole() {
 		}
 	}
 
-	// Add ECR access for pulling container images
+	// Add Parameter Store access for the ssm secrets backend
+	s.grantSSMSecrets(role)
+	s.grantPrefixedSecrets(role)
+	s.grantImportedSecrets(role)
+
+	// Add access to the customer managed key
+	if s.KMSKey != nil {
+		s.KMSKey.GrantEncryptDecrypt(role)
+	}
+
+	// Add access to the observability provider API key
+	s.grantObservabilitySecret(role)
+
+	// Add X-Ray access if tracing is enabled
+	s.grantXRay(role)
+
+	// Add ECR access for pulling container images. GetAuthorizationToken
+	// has no resource-level permissions; pulls are limited to the agents'
+	// repositories.
 	role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
-		Effect: awsiam.Effect_ALLOW,
-		Actions: jsii.Strings(
-			"ecr:GetAuthorizationToken",
-			"ecr:BatchCheckLayerAvailability",
-			"ecr:GetDownloadUrlForLayer",
-			"ecr:BatchGetImage",
-		),
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("ecr:GetAuthorizationToken"),
 		Resources: jsii.Strings("*"),
 	}))
+	if repositories := s.ecrRepositoryARNs(); len(repositories) > 0 {
+		role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect: awsiam.Effect_ALLOW,
+			Actions: jsii.Strings(
+				"ecr:BatchCheckLayerAvailability",
+				"ecr:GetDownloadUrlForLayer",
+				"ecr:BatchGetImage",
+			),
+			Resources: &repositories,
+		}))
+	}
 
 	// Add additional policies
 	for _, policyARN := range iamConfig.AdditionalPolicies {
@@ -414,15 +637,11 @@ func (s *AgentCoreStack) createLogGroup() {
 		retention = awslogs.RetentionDays_INFINITE
 	}
 
-	removalPolicy := awscdk.RemovalPolicy_DESTROY
-	if s.Config.RemovalPolicy == "retain" {
-		removalPolicy = awscdk.RemovalPolicy_RETAIN
-	}
-
 	s.LogGroup = awslogs.NewLogGroup(s.Stack, jsii.String("LogGroup"), &awslogs.LogGroupProps{
 		LogGroupName:  jsii.String(fmt.Sprintf("/aws/agentcore/%s", s.Config.StackName)),
 		Retention:     retention,
-		RemovalPolicy: removalPolicy,
+		EncryptionKey: s.KMSKey,
+		RemovalPolicy: s.removalPolicy(),
 	})
 }
 
@@ -450,20 +669,71 @@ func (s *AgentCoreStack) createAgent(config AgentConfig) {
 		}
 	}
 
+	// Add provider SDK settings
+	s.addProviderEnvironment(envVars)
+
+	// Add OpenTelemetry exporter settings if X-Ray tracing is enabled
+	s.addTracingEnvironment(&config, envVars)
+
+	// Add the OpenTelemetry SDK settings of the provider
+	s.addOTELEnvironment(&config, envVars)
+
 	// Add AgentCore-specific environment variables
 	envVars["AGENTCORE_AGENT_NAME"] = config.Name
 	if config.IsDefault {
 		envVars["AGENTCORE_DEFAULT_AGENT"] = config.Name
 	}
 
+	// Add the secret names and parameter paths agents read
+	s.addSecretsEnvironment(envVars)
+	s.addImportedSecretsEnvironment(envVars)
+
+	// Pass the shared artifact bucket
+	s.addArtifactEnvironment(envVars)
+
+	// Pass the shared state table
+	s.addStateEnvironment(envVars)
+
+	// Pass the knowledge bases the agent queries
+	s.addKnowledgeBaseEnvironment(&config, envVars)
+
+	// Pass the shared cache
+	s.addRedisEnvironment(&config, envVars)
+
+	// Point the agent at the agents it depends on
+	s.addDependencyEnvironment(&config, envVars)
+
+	// Move user-defined variables to the config store if configured
+	s.offloadEnvironment(&config, envVars)
+
+	// Inject secrets after offloading, so they stay out of the config store
+	s.addSecretEnvironment(&config, envVars)
+
+	// Create AgentCore Memory if requested
+	s.createMemoryStore(&config, envVars)
+
+	// Publish the request/response contract if configured
+	s.publishContract(&config, envVars)
+
 	// Create AgentCore Runtime
 	s.createAgentRuntime(&config, envVars)
+	s.addDependencyOrdering(&config)
 
-	// Create Runtime Endpoint
+	// Create Runtime Endpoints
 	s.createRuntimeEndpoint(&config)
+	s.createNamedEndpoints(&config)
+	s.createCandidateEndpoint(&config)
+	s.createVersionOutputs(&config)
+
+	// Create schedules invoking the agent
+	s.createSchedules(&config)
+
+	// Create event sources invoking the agent
+	s.createTriggers(&config)
 
 	// Add agent-specific outputs
 	s.addAgentOutputs(&config)
+	s.addAgentCardOutput(&config)
 
 	s.Agents[config.Name] = agentConstruct
 }
@@ -476,15 +746,6 @@ func (s *AgentCoreStack) createAgentRuntime(config *AgentConfig, envVars map[str
 		cfnEnvVars[k] = jsii.String(v)
 	}
 
-	// Build network configuration
-	networkConfig := &awsbedrockagentcore.CfnRuntime_NetworkConfigurationProperty{
-		NetworkMode: jsii.String("VPC"),
-		NetworkModeConfig: &awsbedrockagentcore.CfnRuntime_VpcConfigProperty{
-			SecurityGroups: s.getSecurityGroupIds(),
-			Subnets:        s.getPrivateSubnetIds(),
-		},
-	}
-
 	// Build runtime props
 	runtimeProps := &awsbedrockagentcore.CfnRuntimeProps{
 		AgentRuntimeName: jsii.String(config.Name),
@@ -497,40 +758,46 @@ func (s *AgentCoreStack) createAgentRuntime(config *AgentConfig, envVars map[str
 			},
 		},
 
-		NetworkConfiguration:  networkConfig,
+		NetworkConfiguration:  s.getNetworkConfiguration(config),
 		EnvironmentVariables:  &cfnEnvVars,
 		ProtocolConfiguration: jsii.String(s.getProtocol(config)),
 		Tags:                  s.getTags(config),
 	}
 
-	// Add lifecycle configuration if timeout or memory specified
-	if config.TimeoutSeconds > 0 || config.MemoryMB > 0 {
-		runtimeProps.LifecycleConfiguration = &awsbedrockagentcore.CfnRuntime_LifecycleConfigurationProperty{}
-		if config.TimeoutSeconds > 0 {
-			runtimeProps.LifecycleConfiguration.(*awsbedrockagentcore.CfnRuntime_LifecycleConfigurationProperty).MaxLifetime = jsii.Number(float64(config.TimeoutSeconds))
-		}
+	// Add lifecycle configuration if a timeout or scaling is specified
+	if lifecycle := s.lifecycleConfiguration(config); lifecycle != nil {
+		runtimeProps.LifecycleConfiguration = lifecycle
+	}
+
+	// Accept JWTs instead of SigV4-signed requests
+	if authorizer := s.authorizerConfiguration(config); authorizer != nil {
+		runtimeProps.AuthorizerConfiguration = authorizer
 	}
 
 	// Create the runtime
-	runtime := awsbedrockagentcore.NewCfnRuntime(s.Stack,
+	runtime := awsbedrockagentcore.NewCfnRuntime(s.agentScope(config.Name),
 		jsii.String(fmt.Sprintf("Runtime-%s", config.Name)),
 		runtimeProps,
 	)
 
 	s.Runtimes[config.Name] = runtime
+	s.warnScalingCost(config)
 }
 
 // createRuntimeEndpoint creates the AWS::BedrockAgentCore::RuntimeEndpoint resource.
 func (s *AgentCoreStack) createRuntimeEndpoint(config *AgentConfig) {
 	runtime := s.Runtimes[config.Name]
 
-	endpoint := awsbedrockagentcore.NewCfnRuntimeEndpoint(s.Stack,
+	endpoint := awsbedrockagentcore.NewCfnRuntimeEndpoint(s.agentScope(config.Name),
 		jsii.String(fmt.Sprintf("Endpoint-%s", config.Name)),
 		&awsbedrockagentcore.CfnRuntimeEndpointProps{
-			Name:           jsii.String(fmt.Sprintf("%s-endpoint", config.Name)),
+			Name:           jsii.String(defaultEndpointName(config.Name, s.Options.Agents[config.Name])),
 			AgentRuntimeId: runtime.AttrAgentRuntimeId(),
 			Description:    jsii.String(fmt.Sprintf("Endpoint for agent %s", config.Name)),
 			Tags:           s.getTags(config),
+
+			// Blue/green and versioned agents can be pinned to a version
+			AgentRuntimeVersion: s.liveVersion(config),
 		},
 	)
 
@@ -542,7 +809,17 @@ func (s *AgentCoreStack) getPrivateSubnetIds() *[]*string {
 	if s.VPC == nil {
 		return &[]*string{}
 	}
+	if vpcConfig := s.Config.VPC; vpcConfig.VPCID != "" && len(vpcConfig.SubnetIDs) > 0 {
+		return jsii.Strings(vpcConfig.SubnetIDs...)
+	}
+	if name := s.Options.VPC.subnetGroupName(); name != "" {
+		return s.VPC.SelectSubnets(&awsec2.SubnetSelection{SubnetGroupName: jsii.String(name)}).SubnetIds
+	}
 	subnets := s.VPC.PrivateSubnets()
+	if subnets == nil || len(*subnets) == 0 {
+		// Isolated topologies have no private-with-egress subnets
+		subnets = s.VPC.IsolatedSubnets()
+	}
 	if subnets == nil {
 		return &[]*string{}
 	}
@@ -566,7 +843,26 @@ func (s *AgentCoreStack) getProtocol(config *AgentConfig) string {
 	if config.Protocol != "" {
 		return config.Protocol
 	}
-	return "HTTP" // Default protocol
+	return ProtocolHTTP
+}
+
+// defaultMemoryMB is the only memory size accepted for agents. The Runtime
+// resource has no memory setting, so MemoryMB can't be honored.
+const defaultMemoryMB = 512
+
+// validateMemory rejects memory sizes the runtime would silently ignore.
+func validateMemory(agents []AgentConfig) error {
+	for i, agent := range agents {
+		if agent.MemoryMB == 0 || agent.MemoryMB == defaultMemoryMB {
+			continue
+		}
+		if !slices.Contains(ValidMemoryValues(), agent.MemoryMB) {
+			return fmt.Errorf("agents[%d] (%s): memoryMB must be one of %v, got %d", i, agent.Name, ValidMemoryValues(), agent.MemoryMB)
+		}
+		return fmt.Errorf("agents[%d] (%s): memoryMB %d is not supported: AgentCore runtimes have no memory setting, so only %d is accepted (remove memoryMB)",
+			i, agent.Name, agent.MemoryMB, defaultMemoryMB)
+	}
+	return nil
 }
 
 // getTags returns the tags for an agent resource.
@@ -589,6 +885,7 @@ func (s *AgentCoreStack) addAgentOutputs(config *AgentConfig) {
 		&awscdk.CfnOutputProps{
 			Value:       runtime.AttrAgentRuntimeArn(),
 			Description: jsii.String(fmt.Sprintf("Runtime ARN for agent %s", config.Name)),
+			ExportName:  s.agentExportName(config.Name, "RuntimeArn"),
 		})
 
 	awscdk.NewCfnOutput(s.Stack,
@@ -603,6 +900,7 @@ func (s *AgentCoreStack) addAgentOutputs(config *AgentConfig) {
 		&awscdk.CfnOutputProps{
 			Value:       endpoint.AttrAgentRuntimeEndpointArn(),
 			Description: jsii.String(fmt.Sprintf("Endpoint ARN for agent %s", config.Name)),
+			ExportName:  s.agentExportName(config.Name, "EndpointArn"),
 		})
 
 	awscdk.NewCfnOutput(s.Stack,
@@ -619,24 +917,20 @@ func (s *AgentCoreStack) createGateway() {
 		return
 	}
 
-	// Determine protocol type from first agent or default to MCP
-	protocolType := "MCP"
-	if len(s.Config.Agents) > 0 && s.Config.Agents[0].Protocol != "" {
-		protocolType = s.Config.Agents[0].Protocol
-	}
-
 	// Default authorizer type to NONE
 	authorizerType := "NONE"
 
 	gateway := awsbedrockagentcore.NewCfnGateway(s.Stack,
 		jsii.String("Gateway"),
 		&awsbedrockagentcore.CfnGatewayProps{
-			Name:           jsii.String(s.Config.Gateway.Name),
-			Description:    jsii.String(s.Config.Gateway.Description),
-			AuthorizerType: jsii.String(authorizerType),
-			ProtocolType:   jsii.String(protocolType),
-			RoleArn:        s.ExecutionRole.RoleArn(),
-			Tags:           s.getStackTags(),
+			Name:                      jsii.String(s.Config.Gateway.Name),
+			Description:               jsii.String(s.Config.Gateway.Description),
+			AuthorizerType:            jsii.String(authorizerType),
+			ProtocolType:              jsii.String(ProtocolMCP), // The only gateway protocol
+			RoleArn:                   s.ExecutionRole.RoleArn(),
+			KmsKeyArn:                 s.kmsKeyARN(),
+			InterceptorConfigurations: s.createGatewayInterceptor(),
+			Tags:                      s.getStackTags(),
 		},
 	)
 
@@ -672,6 +966,7 @@ func (s *AgentCoreStack) addOutputs() {
 		awscdk.NewCfnOutput(s.Stack, jsii.String("ExecutionRoleARN"), &awscdk.CfnOutputProps{
 			Value:       s.ExecutionRole.RoleArn(),
 			Description: jsii.String("IAM Execution Role ARN"),
+			ExportName:  s.stackExportName("ExecutionRoleArn"),
 		})
 	}
 
@@ -693,6 +988,7 @@ func (s *AgentCoreStack) addOutputs() {
 		awscdk.NewCfnOutput(s.Stack, jsii.String("GatewayArn"), &awscdk.CfnOutputProps{
 			Value:       s.Gateway.AttrGatewayArn(),
 			Description: jsii.String("Gateway ARN"),
+			ExportName:  s.stackExportName("GatewayArn"),
 		})
 
 		awscdk.NewCfnOutput(s.Stack, jsii.String("GatewayId"), &awscdk.CfnOutputProps{
@@ -703,6 +999,7 @@ func (s *AgentCoreStack) addOutputs() {
 		awscdk.NewCfnOutput(s.Stack, jsii.String("GatewayUrl"), &awscdk.CfnOutputProps{
 			Value:       s.Gateway.AttrGatewayUrl(),
 			Description: jsii.String("Gateway URL for invocation"),
+			ExportName:  s.stackExportName("GatewayUrl"),
 		})
 	}
 }
@@ -0,0 +1,165 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// StateTableEnvVar is the environment variable holding the name of the
+// state table.
+const StateTableEnvVar = "STATE_TABLE"
+
+// Default keys of the state table.
+const (
+	defaultStatePartitionKey = "pk"
+	defaultStateSortKey      = "sk"
+)
+
+var (
+	// tableNamePattern is the naming rule for DynamoDB tables.
+	tableNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,255}$`)
+
+	// attributeNamePattern is a conservative naming rule for DynamoDB key
+	// and TTL attributes.
+	attributeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,255}$`)
+)
+
+// StateTableOptions creates an on-demand DynamoDB table the agents share for
+// durable state, such as the tasks of an orchestration. Every agent gets
+// its name in STATE_TABLE and may read and write its items. Keys are
+// strings, so any item layout fits a single table.
+type StateTableOptions struct {
+	// TableName is the table name.
+	// Default: generated by CloudFormation
+	TableName string `json:"tableName,omitempty" yaml:"tableName,omitempty"`
+
+	// PartitionKey is the partition key attribute.
+	// Default: "pk"
+	PartitionKey string `json:"partitionKey,omitempty" yaml:"partitionKey,omitempty"`
+
+	// SortKey is the sort key attribute.
+	// Default: "sk"
+	SortKey string `json:"sortKey,omitempty" yaml:"sortKey,omitempty"`
+
+	// TTLAttribute expires items at the time in epoch seconds it holds.
+	// Default: items don't expire
+	TTLAttribute string `json:"ttlAttribute,omitempty" yaml:"ttlAttribute,omitempty"`
+
+	// PointInTimeRecovery enables continuous backups of the table.
+	PointInTimeRecovery bool `json:"pointInTimeRecovery,omitempty" yaml:"pointInTimeRecovery,omitempty"`
+}
+
+// validate validates the state table options.
+func (o *StateTableOptions) validate() error {
+	switch {
+	case o.TableName != "" && !*awscdk.Token_IsUnresolved(o.TableName) && !tableNamePattern.MatchString(o.TableName):
+		return fmt.Errorf("stateTable.tableName %q must be 3-255 letters, digits, '_', '.', or '-'", o.TableName)
+	case o.PartitionKey != "" && !attributeNamePattern.MatchString(o.PartitionKey):
+		return fmt.Errorf("stateTable.partitionKey %q must be letters, digits, '_', '.', or '-'", o.PartitionKey)
+	case o.SortKey != "" && !attributeNamePattern.MatchString(o.SortKey):
+		return fmt.Errorf("stateTable.sortKey %q must be letters, digits, '_', '.', or '-'", o.SortKey)
+	case o.TTLAttribute != "" && !attributeNamePattern.MatchString(o.TTLAttribute):
+		return fmt.Errorf("stateTable.ttlAttribute %q must be letters, digits, '_', '.', or '-'", o.TTLAttribute)
+	}
+
+	partitionKey, sortKey := o.keys()
+	switch o.TTLAttribute {
+	case "":
+	case partitionKey, sortKey:
+		return fmt.Errorf("stateTable.ttlAttribute %q must not be a key attribute", o.TTLAttribute)
+	}
+	if partitionKey == sortKey {
+		return fmt.Errorf("stateTable.partitionKey and stateTable.sortKey must differ, got %q", partitionKey)
+	}
+	return nil
+}
+
+// keys returns the partition and sort key attributes.
+func (o *StateTableOptions) keys() (partitionKey, sortKey string) {
+	partitionKey, sortKey = o.PartitionKey, o.SortKey
+	if partitionKey == "" {
+		partitionKey = defaultStatePartitionKey
+	}
+	if sortKey == "" {
+		sortKey = defaultStateSortKey
+	}
+	return partitionKey, sortKey
+}
+
+// stateTableRemovalPolicy returns the removal policy of the state table:
+// retain unless removalPolicies.stateTable says otherwise.
+func (s *AgentCoreStack) stateTableRemovalPolicy() string {
+	if opts := s.Options.RemovalPolicies; opts != nil && opts.StateTable != "" {
+		return opts.StateTable
+	}
+	return RemovalPolicyRetain
+}
+
+// createStateTable creates the state table, lets the execution role use its
+// items, and outputs its name.
+func (s *AgentCoreStack) createStateTable() {
+	opts := s.Options.StateTable
+	if opts == nil {
+		return
+	}
+
+	encryption := awsdynamodb.TableEncryption_AWS_MANAGED
+	if s.KMSKey != nil {
+		encryption = awsdynamodb.TableEncryption_CUSTOMER_MANAGED
+	}
+	partitionKey, sortKey := opts.keys()
+	props := &awsdynamodb.TableProps{
+		PartitionKey:  &awsdynamodb.Attribute{Name: jsii.String(partitionKey), Type: awsdynamodb.AttributeType_STRING},
+		SortKey:       &awsdynamodb.Attribute{Name: jsii.String(sortKey), Type: awsdynamodb.AttributeType_STRING},
+		BillingMode:   awsdynamodb.BillingMode_PAY_PER_REQUEST,
+		Encryption:    encryption,
+		EncryptionKey: s.KMSKey,
+		PointInTimeRecoverySpecification: &awsdynamodb.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: jsii.Bool(opts.PointInTimeRecovery),
+		},
+	}
+	if opts.TableName != "" {
+		props.TableName = jsii.String(opts.TableName)
+	}
+	if opts.TTLAttribute != "" {
+		props.TimeToLiveAttribute = jsii.String(opts.TTLAttribute)
+	}
+	s.StateTable = awsdynamodb.NewTable(s.Stack, jsii.String("StateTable"), props)
+	s.StateTable.ApplyRemovalPolicy(cdkRemovalPolicy(s.stateTableRemovalPolicy()))
+
+	// Item access only; agents cannot change or delete the table
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: jsii.Strings(
+			"dynamodb:GetItem",
+			"dynamodb:BatchGetItem",
+			"dynamodb:Query",
+			"dynamodb:Scan",
+			"dynamodb:ConditionCheckItem",
+			"dynamodb:PutItem",
+			"dynamodb:UpdateItem",
+			"dynamodb:DeleteItem",
+			"dynamodb:BatchWriteItem",
+			"dynamodb:DescribeTable",
+		),
+		Resources: &[]*string{s.StateTable.TableArn()},
+	}))
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("StateTableName"), &awscdk.CfnOutputProps{
+		Value:       s.StateTable.TableName(),
+		Description: jsii.String("State table shared by the agents"),
+	})
+}
+
+// addStateEnvironment passes the state table to an agent.
+func (s *AgentCoreStack) addStateEnvironment(envVars map[string]string) {
+	if s.StateTable == nil {
+		return
+	}
+	envVars[StateTableEnvVar] = *s.StateTable.TableName()
+}
@@ -0,0 +1,53 @@
+package agentcore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// StrictContextKey is the CDK context key that makes NewStackFromFile load
+// its config file strictly, e.g. cdk deploy -c agentkit:strict=true.
+const StrictContextKey = "agentkit:strict"
+
+// Strict makes loading fail if the config file (or its environment overlay)
+// contains fields that aren't in the config schema, such as a misspelled
+// memoryMb, instead of silently ignoring them. The error lists every unknown
+// field with the field probably meant.
+func Strict() LoadOption {
+	return func(s *loadSettings) {
+		s.strict = true
+	}
+}
+
+// checkUnknownFields returns an error listing the fields of a decoded config
+// document that aren't in the config schema.
+func checkUnknownFields(path string, doc map[string]any) error {
+	var issues []ConfigIssue
+	validateValue(configSchema(), doc, "", &issues)
+
+	var unknown []string
+	for _, issue := range issues {
+		if issue.unknown {
+			unknown = append(unknown, issue.String())
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s has %d unknown field(s):\n  %s", path, len(unknown), strings.Join(unknown, "\n  "))
+}
+
+// contextStrict reports whether the agentkit:strict CDK context value is
+// set to true.
+func contextStrict(scope constructs.Construct) bool {
+	switch value := scope.Node().TryGetContext(jsii.String(StrictContextKey)).(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// BootstrapQualifierContextKey is the CDK context key of the bootstrap
+// qualifier, e.g. cdk deploy -c @aws-cdk/core:bootstrapQualifier=myorg.
+const BootstrapQualifierContextKey = "@aws-cdk/core:bootstrapQualifier"
+
+// qualifierPattern matches bootstrap qualifiers, as cdk bootstrap accepts
+// them.
+var qualifierPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,10}$`)
+
+// SynthesizerOptions configures the DefaultStackSynthesizer for a
+// customized CDK bootstrap: a non-default qualifier, renamed bootstrap
+// resources, or roles created outside the toolkit stack. Names and ARNs may
+// use the synthesizer's placeholders, e.g. ${Qualifier},
+// ${AWS::AccountId}, and ${AWS::Region}.
+type SynthesizerOptions struct {
+	// Qualifier distinguishes the bootstrap resources of the toolkit stack
+	// to deploy with. It wins over the @aws-cdk/core:bootstrapQualifier
+	// context.
+	// Default: the context, or "hnb659fds"
+	Qualifier string `json:"qualifier,omitempty" yaml:"qualifier,omitempty"`
+
+	// FileAssetsBucketName is the bucket file assets are published to.
+	// Default: "cdk-${Qualifier}-assets-${AWS::AccountId}-${AWS::Region}"
+	FileAssetsBucketName string `json:"fileAssetsBucketName,omitempty" yaml:"fileAssetsBucketName,omitempty"`
+
+	// BucketPrefix prefixes the object keys of file assets.
+	BucketPrefix string `json:"bucketPrefix,omitempty" yaml:"bucketPrefix,omitempty"`
+
+	// ImageAssetsRepositoryName is the ECR repository Docker image assets
+	// are published to.
+	ImageAssetsRepositoryName string `json:"imageAssetsRepositoryName,omitempty" yaml:"imageAssetsRepositoryName,omitempty"`
+
+	// DockerTagPrefix prefixes the tags of Docker image assets.
+	DockerTagPrefix string `json:"dockerTagPrefix,omitempty" yaml:"dockerTagPrefix,omitempty"`
+
+	// DeployRoleARN is the role the deployment assumes to call
+	// CloudFormation.
+	DeployRoleARN string `json:"deployRoleArn,omitempty" yaml:"deployRoleArn,omitempty"`
+
+	// CloudFormationExecutionRoleARN is the role CloudFormation creates the
+	// stack's resources with.
+	CloudFormationExecutionRoleARN string `json:"cloudFormationExecutionRoleArn,omitempty" yaml:"cloudFormationExecutionRoleArn,omitempty"`
+
+	// FileAssetPublishingRoleARN is the role file assets are published
+	// with.
+	FileAssetPublishingRoleARN string `json:"fileAssetPublishingRoleArn,omitempty" yaml:"fileAssetPublishingRoleArn,omitempty"`
+
+	// ImageAssetPublishingRoleARN is the role Docker image assets are
+	// published with.
+	ImageAssetPublishingRoleARN string `json:"imageAssetPublishingRoleArn,omitempty" yaml:"imageAssetPublishingRoleArn,omitempty"`
+
+	// LookupRoleARN is the role context lookups, such as an existing VPC,
+	// run with.
+	LookupRoleARN string `json:"lookupRoleArn,omitempty" yaml:"lookupRoleArn,omitempty"`
+
+	// BootstrapStackVersionSSMParameter is the SSM parameter holding the
+	// bootstrap version.
+	// Default: "/cdk-bootstrap/${Qualifier}/version"
+	BootstrapStackVersionSSMParameter string `json:"bootstrapStackVersionSsmParameter,omitempty" yaml:"bootstrapStackVersionSsmParameter,omitempty"`
+
+	// SkipBootstrapVersionRule omits the template rule checking the
+	// bootstrap version, for deployment roles that may not read the
+	// version parameter.
+	SkipBootstrapVersionRule bool `json:"skipBootstrapVersionRule,omitempty" yaml:"skipBootstrapVersionRule,omitempty"`
+
+	// PermissionsBoundary is the name or ARN of the managed policy set as
+	// the permissions boundary of every IAM role the stack creates, as
+	// bootstraps with a custom permissions boundary require.
+	PermissionsBoundary string `json:"permissionsBoundary,omitempty" yaml:"permissionsBoundary,omitempty"`
+}
+
+// validate validates the synthesizer options.
+func (o *SynthesizerOptions) validate() error {
+	if o.Qualifier != "" {
+		if err := ValidateBootstrapQualifier(o.Qualifier); err != nil {
+			return fmt.Errorf("synthesizer.qualifier: %w", err)
+		}
+	}
+	for _, role := range []struct{ field, arn string }{
+		{"deployRoleArn", o.DeployRoleARN},
+		{"cloudFormationExecutionRoleArn", o.CloudFormationExecutionRoleARN},
+		{"fileAssetPublishingRoleArn", o.FileAssetPublishingRoleARN},
+		{"imageAssetPublishingRoleArn", o.ImageAssetPublishingRoleARN},
+		{"lookupRoleArn", o.LookupRoleARN},
+	} {
+		if role.arn != "" && !strings.HasPrefix(role.arn, "arn:") {
+			return fmt.Errorf("synthesizer.%s %q must be a role ARN", role.field, role.arn)
+		}
+	}
+	return nil
+}
+
+// ValidateBootstrapQualifier checks that qualifier is a valid bootstrap
+// qualifier.
+func ValidateBootstrapQualifier(qualifier string) error {
+	if !qualifierPattern.MatchString(qualifier) {
+		return fmt.Errorf("bootstrap qualifier %q must be 1-10 letters, digits, hyphens, or underscores", qualifier)
+	}
+	return nil
+}
+
+// customizesSynthesizer reports whether the options change the
+// synthesizer, not only the stack's roles.
+func (o *SynthesizerOptions) customizesSynthesizer() bool {
+	return o.Qualifier != "" || o.FileAssetsBucketName != "" || o.BucketPrefix != "" ||
+		o.ImageAssetsRepositoryName != "" || o.DockerTagPrefix != "" ||
+		o.DeployRoleARN != "" || o.CloudFormationExecutionRoleARN != "" ||
+		o.FileAssetPublishingRoleARN != "" || o.ImageAssetPublishingRoleARN != "" ||
+		o.LookupRoleARN != "" || o.BootstrapStackVersionSSMParameter != "" ||
+		o.SkipBootstrapVersionRule
+}
+
+// stackSynthesizer returns the synthesizer of the stack, or nil for the
+// CDK default.
+func stackSynthesizer(options StackOptions) awscdk.IStackSynthesizer {
+	o := options.Synthesizer
+	if o == nil || !o.customizesSynthesizer() {
+		return nil
+	}
+	str := func(v string) *string {
+		if v == "" {
+			return nil
+		}
+		return jsii.String(v)
+	}
+	props := &awscdk.DefaultStackSynthesizerProps{
+		Qualifier:                         str(o.Qualifier),
+		FileAssetsBucketName:              str(o.FileAssetsBucketName),
+		BucketPrefix:                      str(o.BucketPrefix),
+		ImageAssetsRepositoryName:         str(o.ImageAssetsRepositoryName),
+		DockerTagPrefix:                   str(o.DockerTagPrefix),
+		DeployRoleArn:                     str(o.DeployRoleARN),
+		CloudFormationExecutionRole:       str(o.CloudFormationExecutionRoleARN),
+		FileAssetPublishingRoleArn:        str(o.FileAssetPublishingRoleARN),
+		ImageAssetPublishingRoleArn:       str(o.ImageAssetPublishingRoleARN),
+		LookupRoleArn:                     str(o.LookupRoleARN),
+		BootstrapStackVersionSsmParameter: str(o.BootstrapStackVersionSSMParameter),
+	}
+	if o.SkipBootstrapVersionRule {
+		props.GenerateBootstrapVersionRule = jsii.Bool(false)
+	}
+	return awscdk.NewDefaultStackSynthesizer(props)
+}
+
+// stackPermissionsBoundary returns the permissions boundary of the stack's
+// roles, or nil.
+func stackPermissionsBoundary(options StackOptions) awscdk.PermissionsBoundary {
+	if options.Synthesizer == nil || options.Synthesizer.PermissionsBoundary == "" {
+		return nil
+	}
+	boundary := options.Synthesizer.PermissionsBoundary
+	if strings.HasPrefix(boundary, "arn:") {
+		return awscdk.PermissionsBoundary_FromArn(jsii.String(boundary))
+	}
+	return awscdk.PermissionsBoundary_FromName(jsii.String(boundary))
+}
+
+// bootstrapQualifier returns the bootstrap qualifier the stack deploys
+// with.
+func (s *AgentCoreStack) bootstrapQualifier() string {
+	if s.Options.Synthesizer != nil && s.Options.Synthesizer.Qualifier != "" {
+		return s.Options.Synthesizer.Qualifier
+	}
+	if qualifier, ok := s.Stack.Node().TryGetContext(jsii.String(BootstrapQualifierContextKey)).(string); ok && qualifier != "" {
+		return qualifier
+	}
+	return *awscdk.DefaultStackSynthesizer_DEFAULT_QUALIFIER()
+}
+
+// cloudFormationExecutionRoleARN returns the role CloudFormation deploys
+// the stack with: the configured one, or the bootstrap role of the
+// qualifier.
+func (s *AgentCoreStack) cloudFormationExecutionRoleARN() *string {
+	qualifier := s.bootstrapQualifier()
+	if s.Options.Synthesizer != nil && s.Options.Synthesizer.CloudFormationExecutionRoleARN != "" {
+		// Resolve the placeholders the synthesizer accepts
+		arn := strings.ReplaceAll(s.Options.Synthesizer.CloudFormationExecutionRoleARN, "${Qualifier}", qualifier)
+		if strings.Contains(arn, "${AWS::") {
+			return awscdk.Fn_Sub(jsii.String(arn), nil)
+		}
+		return jsii.String(arn)
+	}
+	return jsii.String(fmt.Sprintf("arn:%s:iam::%s:role/cdk-%s-cfn-exec-role-%s-%s",
+		*s.Stack.Partition(), *s.Stack.Account(), qualifier, *s.Stack.Account(), *s.Stack.Region()))
+}
@@ -0,0 +1,139 @@
+package agentcore
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// tlsWarningID identifies the synth warnings of TLS enforcement.
+const tlsWarningID = "agentkit:tls"
+
+// TLSOptions enforces encryption in transit. Every bucket, queue, and topic
+// the stack creates gets a resource policy denying requests without TLS,
+// and API Gateway domain names and CloudFront distributions get a minimum
+// TLS version. Resources that cannot comply are reported as synth warnings
+// (which fail `cdk synth --strict`).
+type TLSOptions struct {
+	// MinimumVersion is the minimum TLS version: "1.2" or "1.3".
+	// Default: "1.2"
+	MinimumVersion string `json:"minimumVersion,omitempty" yaml:"minimumVersion,omitempty"`
+}
+
+// validate validates the TLS options.
+func (o *TLSOptions) validate() error {
+	switch o.MinimumVersion {
+	case "", "1.2", "1.3":
+		return nil
+	default:
+		return fmt.Errorf("tls.minimumVersion %q must be 1.2 or 1.3", o.MinimumVersion)
+	}
+}
+
+// minimumVersion returns the minimum TLS version.
+func (o *TLSOptions) minimumVersion() string {
+	if o.MinimumVersion == "" {
+		return "1.2"
+	}
+	return o.MinimumVersion
+}
+
+// enforceTLS applies TLS-only resource policies and minimum TLS versions to
+// the resources in the stack. It runs after all resources are created.
+func (s *AgentCoreStack) enforceTLS() {
+	if s.Options.TLS == nil {
+		return
+	}
+	version := s.Options.TLS.minimumVersion()
+
+	// Resources enforced through their L2 construct
+	enforced := make(map[string]bool)
+	markEnforced := func(c constructs.IConstruct) {
+		if child := c.Node().DefaultChild(); child != nil {
+			enforced[*child.Node().Path()] = true
+		}
+	}
+	warn := func(c constructs.IConstruct, format string, args ...any) {
+		awscdk.Annotations_Of(c).AddWarningV2(jsii.String(tlsWarningID), jsii.String(fmt.Sprintf(format, args...)))
+	}
+
+	all := *s.Stack.Node().FindAll(constructs.ConstructOrder_PREORDER)
+	for _, c := range all {
+		switch resource := c.(type) {
+		case awss3.Bucket:
+			resources := &[]*string{resource.BucketArn(), resource.ArnForObjects(jsii.String("*"))}
+			resource.AddToResourcePolicy(denyInsecureTransport("s3:*", *resources...))
+			resource.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+				Effect:     awsiam.Effect_DENY,
+				Principals: &[]awsiam.IPrincipal{awsiam.NewAnyPrincipal()},
+				Actions:    jsii.Strings("s3:*"),
+				Resources:  resources,
+				Conditions: &map[string]interface{}{
+					"NumericLessThan": map[string]interface{}{"s3:TlsVersion": version},
+				},
+			}))
+			markEnforced(c)
+		case awssqs.Queue:
+			resource.AddToResourcePolicy(denyInsecureTransport("sqs:*", resource.QueueArn()))
+			markEnforced(c)
+			if version != "1.2" {
+				warn(c, "SQS queue %s cannot require TLS %s; only TLS is enforced", *c.Node().Path(), version)
+			}
+		case awssns.Topic:
+			resource.AddToResourcePolicy(denyInsecureTransport("sns:Publish", resource.TopicArn()))
+			markEnforced(c)
+			if version != "1.2" {
+				warn(c, "SNS topic %s cannot require TLS %s; only TLS is enforced", *c.Node().Path(), version)
+			}
+		}
+	}
+
+	for _, c := range all {
+		resource, ok := c.(awscdk.CfnResource)
+		if !ok || enforced[*c.Node().Path()] {
+			continue
+		}
+		switch *resource.CfnResourceType() {
+		case "AWS::S3::Bucket", "AWS::SQS::Queue", "AWS::SNS::Topic":
+			warn(c, "cannot enforce TLS-only access on %s (%s): add a resource policy denying aws:SecureTransport=false", *c.Node().Path(), *resource.CfnResourceType())
+		case "AWS::ApiGateway::DomainName":
+			if version == "1.2" {
+				resource.AddPropertyOverride(jsii.String("SecurityPolicy"), jsii.String("TLS_1_2"))
+			} else {
+				warn(c, "API Gateway domain name %s cannot require TLS %s", *c.Node().Path(), version)
+			}
+		case "AWS::ApiGatewayV2::DomainName":
+			if version == "1.2" {
+				resource.AddPropertyOverride(jsii.String("DomainNameConfigurations.0.SecurityPolicy"), jsii.String("TLS_1_2"))
+			} else {
+				warn(c, "API Gateway domain name %s cannot require TLS %s", *c.Node().Path(), version)
+			}
+		case "AWS::CloudFront::Distribution":
+			if version == "1.2" {
+				resource.AddPropertyOverride(jsii.String("DistributionConfig.ViewerCertificate.MinimumProtocolVersion"), jsii.String("TLSv1.2_2021"))
+			} else {
+				warn(c, "CloudFront distribution %s cannot require TLS %s", *c.Node().Path(), version)
+			}
+		}
+	}
+}
+
+// denyInsecureTransport returns a statement denying actions on resources
+// for requests made without TLS.
+func denyInsecureTransport(action string, resources ...*string) awsiam.PolicyStatement {
+	return awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:     awsiam.Effect_DENY,
+		Principals: &[]awsiam.IPrincipal{awsiam.NewAnyPrincipal()},
+		Actions:    jsii.Strings(action),
+		Resources:  &resources,
+		Conditions: &map[string]interface{}{
+			"Bool": map[string]interface{}{"aws:SecureTransport": "false"},
+		},
+	})
+}
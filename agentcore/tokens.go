@@ -0,0 +1,364 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+)
+
+// tokenMarkerPattern matches well-formed CDK string and list token markers.
+var tokenMarkerPattern = regexp.MustCompile(`[$#]\{Token\[[^\]]+\]\}`)
+
+// mangledTokenPattern matches token markers that were altered by string
+// functions (case changes, URL escaping) and can no longer be resolved.
+var mangledTokenPattern = regexp.MustCompile(`(?i)(?:[$#]\{|%24%7B|%23%7B)token(?:\[|%5B)`)
+
+// tokenField is a configuration value and its path for error messages.
+type tokenField struct {
+	path  string
+	value string
+}
+
+// validateTokens separates synth-time values from deploy-time values.
+//
+// Some fields are used at synth time: as construct IDs, output keys, lookup
+// arguments, or in derived names and URLs. These must be literal strings; an
+// unresolved token there yields a cryptic jsii error or a malformed template.
+// All other fields may hold tokens, but the tokens must be intact: a token
+// passed through strings.ToLower or url.QueryEscape is no longer recognized
+// by CDK and ends up in the template verbatim.
+func validateTokens(config StackConfig, options StackOptions) error {
+	literals, values := collectTokenFields(config, options)
+
+	for _, f := range literals {
+		if f.value != "" && *awscdk.Token_IsUnresolved(f.value) {
+			return fmt.Errorf("%s must be a literal value known at synth time, got unresolved token %q", f.path, f.value)
+		}
+	}
+
+	for _, f := range append(literals, values...) {
+		if mangledTokenPattern.MatchString(tokenMarkerPattern.ReplaceAllString(f.value, "")) {
+			return fmt.Errorf("%s contains a mangled CDK token %q; pass tokens through unmodified", f.path, f.value)
+		}
+	}
+
+	return nil
+}
+
+// collectTokenFields returns the synth-time (literal) fields and the
+// deploy-time fields of the configuration.
+func collectTokenFields(config StackConfig, options StackOptions) (literals, values []tokenField) {
+	literal := func(path, value string) {
+		literals = append(literals, tokenField{path, value})
+	}
+	value := func(path, v string) {
+		values = append(values, tokenField{path, v})
+	}
+
+	literal("stackName", config.StackName)
+	value("description", config.Description)
+	for k, v := range config.Tags {
+		literal("tags key", k)
+		literal(fmt.Sprintf("tags[%s]", k), v)
+	}
+
+	for i, agent := range config.Agents {
+		prefix := fmt.Sprintf("agents[%d]", i)
+		literal(prefix+".name", agent.Name)
+		literal(prefix+".protocol", agent.Protocol)
+		value(prefix+".containerImage", agent.ContainerImage)
+		value(prefix+".description", agent.Description)
+		for k, v := range agent.Environment {
+			literal(prefix+".environment key", k)
+			value(fmt.Sprintf("%s.environment[%s]", prefix, k), v)
+		}
+		for j, arn := range agent.SecretsARNs {
+			// Used in construct IDs
+			literal(fmt.Sprintf("%s.secretsArns[%d]", prefix, j), arn)
+		}
+		if agent.Authorizer != nil {
+			literal(prefix+".authorizer.type", agent.Authorizer.Type)
+			value(prefix+".authorizer.lambdaArn", agent.Authorizer.LambdaARN)
+		}
+	}
+
+	if vpc := config.VPC; vpc != nil {
+		// Vpc.fromLookup and CIDR planning need concrete values
+		literal("vpc.vpcId", vpc.VPCID)
+		literal("vpc.vpcCidr", vpc.VPCCidr)
+		for j, id := range vpc.SubnetIDs {
+			value(fmt.Sprintf("vpc.subnetIds[%d]", j), id)
+		}
+		for j, id := range vpc.SecurityGroupIDs {
+			value(fmt.Sprintf("vpc.securityGroupIds[%d]", j), id)
+		}
+	}
+
+	if secrets := config.Secrets; secrets != nil {
+		value("secrets.secretName", secrets.SecretName)
+		value("secrets.kmsKeyArn", secrets.KMSKeyARN)
+	}
+
+	if obs := config.Observability; obs != nil {
+		literal("observability.provider", obs.Provider)
+		value("observability.project", obs.Project)
+		value("observability.apiKeySecretArn", obs.APIKeySecretARN)
+		value("observability.endpoint", obs.Endpoint)
+	}
+
+	if iam := config.IAM; iam != nil {
+		value("iam.roleArn", iam.RoleARN)
+		value("iam.permissionsBoundaryArn", iam.PermissionsBoundaryARN)
+		for j, arn := range iam.AdditionalPolicies {
+			// Used in construct IDs
+			literal(fmt.Sprintf("iam.additionalPolicies[%d]", j), arn)
+		}
+		for j, id := range iam.BedrockModelIDs {
+			value(fmt.Sprintf("iam.bedrockModelIds[%d]", j), id)
+		}
+	}
+
+	if gateway := config.Gateway; gateway != nil {
+		value("gateway.name", gateway.Name)
+		value("gateway.description", gateway.Description)
+		for j, target := range gateway.Targets {
+			literal(fmt.Sprintf("gateway.targets[%d]", j), target)
+		}
+	}
+
+	for name, opts := range options.Agents {
+		if opts == nil {
+			continue
+		}
+		prefix := fmt.Sprintf("agents[%s]", name)
+		literal(prefix+".networkMode", opts.NetworkMode)
+		if opts.MemoryStore != nil {
+			literal(prefix+".memoryStore.name", opts.MemoryStore.Name)
+		}
+		for j, id := range opts.SubnetIDs {
+			value(fmt.Sprintf("%s.subnetIds[%d]", prefix, j), id)
+		}
+		for j, id := range opts.SecurityGroupIDs {
+			value(fmt.Sprintf("%s.securityGroupIds[%d]", prefix, j), id)
+		}
+		literal(prefix+".endpointName", opts.EndpointName)
+		if opts.JWTAuthorizer != nil {
+			// Parsed at synth time
+			literal(prefix+".jwtAuthorizer.discoveryUrl", opts.JWTAuthorizer.DiscoveryURL)
+		}
+		if opts.Contract != nil {
+			// Read at synth time
+			literal(prefix+".contract.requestSchemaFile", opts.Contract.RequestSchemaFile)
+			literal(prefix+".contract.responseSchemaFile", opts.Contract.ResponseSchemaFile)
+		}
+		for j, endpoint := range opts.Endpoints {
+			// Used in construct IDs and output keys
+			literal(fmt.Sprintf("%s.endpoints[%d].name", prefix, j), endpoint.Name)
+			value(fmt.Sprintf("%s.endpoints[%d].description", prefix, j), endpoint.Description)
+			value(fmt.Sprintf("%s.endpoints[%d].runtimeVersion", prefix, j), endpoint.RuntimeVersion)
+		}
+		for j, schedule := range opts.Schedules {
+			// Used in construct IDs and validated at synth time
+			literal(fmt.Sprintf("%s.schedules[%d].name", prefix, j), schedule.Name)
+			literal(fmt.Sprintf("%s.schedules[%d].expression", prefix, j), schedule.Expression)
+			literal(fmt.Sprintf("%s.schedules[%d].endpoint", prefix, j), schedule.Endpoint)
+			value(fmt.Sprintf("%s.schedules[%d].timezone", prefix, j), schedule.Timezone)
+		}
+		for j, trigger := range opts.Triggers {
+			// Used in construct IDs and output keys
+			literal(fmt.Sprintf("%s.triggers[%d].type", prefix, j), trigger.Type)
+			literal(fmt.Sprintf("%s.triggers[%d].name", prefix, j), trigger.Name)
+			literal(fmt.Sprintf("%s.triggers[%d].endpoint", prefix, j), trigger.Endpoint)
+			value(fmt.Sprintf("%s.triggers[%d].sourceArn", prefix, j), trigger.SourceARN)
+		}
+		for j, secret := range opts.SecretEnvironment {
+			literal(fmt.Sprintf("%s.secretEnvironment[%d].name", prefix, j), secret.Name)
+			value(fmt.Sprintf("%s.secretEnvironment[%d].secretArn", prefix, j), secret.SecretARN)
+			value(fmt.Sprintf("%s.secretEnvironment[%d].jsonKey", prefix, j), secret.JSONKey)
+		}
+	}
+
+	if options.VPC != nil {
+		literal("vpc.natInstanceType", options.VPC.NatInstanceType)
+	}
+
+	for j, resource := range options.RawResources {
+		prefix := fmt.Sprintf("rawResources[%d]", j)
+		literal(prefix+".id", resource.ID)
+		literal(prefix+".type", resource.Type)
+	}
+
+	for k, v := range options.GlobalEnvironment {
+		literal("globalEnvironment key", k)
+		value(fmt.Sprintf("globalEnvironment[%s]", k), v)
+	}
+
+	if options.ConfigStore != nil {
+		literal("configStore.type", options.ConfigStore.Type)
+	}
+
+	if options.Secrets != nil {
+		literal("secrets.backend", options.Secrets.Backend)
+		value("secrets.prefix", options.Secrets.Prefix)
+		for j, group := range options.Secrets.Groups {
+			// Used in environment variable names
+			literal(fmt.Sprintf("secrets.groups[%d]", j), group)
+		}
+	}
+
+	if api := options.HTTPAPI; api != nil {
+		literal("httpApi.authType", api.AuthType)
+		for j, name := range api.Agents {
+			// Used in route paths
+			literal(fmt.Sprintf("httpApi.agents[%d]", j), name)
+		}
+		if api.Domain != nil {
+			// Used in the API URL output
+			literal("httpApi.domain.name", api.Domain.Name)
+			value("httpApi.domain.certificateArn", api.Domain.CertificateARN)
+			value("httpApi.domain.hostedZoneId", api.Domain.HostedZoneID)
+			value("httpApi.domain.hostedZoneName", api.Domain.HostedZoneName)
+		}
+	}
+
+	if domain := options.CustomDomain; domain != nil {
+		// Used in the URL outputs
+		literal("customDomain.name", domain.Name)
+		value("customDomain.hostedZoneId", domain.HostedZoneID)
+		value("customDomain.hostedZoneName", domain.HostedZoneName)
+		value("customDomain.certificateArn", domain.CertificateARN)
+	}
+
+	if waf := options.WAF; waf != nil {
+		value("waf.webAclArn", waf.WebACLARN)
+		value("waf.certificateArn", waf.CertificateARN)
+		for j, group := range waf.ManagedRuleGroups {
+			// Used in rule and metric names
+			literal(fmt.Sprintf("waf.managedRuleGroups[%d]", j), group)
+		}
+		for j, cidr := range waf.AllowedIPs {
+			// Split into IP sets by IP version
+			literal(fmt.Sprintf("waf.allowedIps[%d]", j), cidr)
+		}
+	}
+
+	if options.Dashboard != nil {
+		literal("dashboard.name", options.Dashboard.Name)
+	}
+
+	if options.Synthesizer != nil {
+		// The qualifier is part of the bootstrap resource names
+		literal("synthesizer.qualifier", options.Synthesizer.Qualifier)
+		value("synthesizer.permissionsBoundary", options.Synthesizer.PermissionsBoundary)
+	}
+
+	if options.TLS != nil {
+		literal("tls.minimumVersion", options.TLS.MinimumVersion)
+	}
+
+	if options.KMS != nil {
+		value("kms.keyArn", options.KMS.KeyARN)
+		value("kms.alias", options.KMS.Alias)
+	}
+
+	if options.ArtifactBucket != nil {
+		value("artifactBucket.bucketName", options.ArtifactBucket.BucketName)
+	}
+	if options.StateTable != nil {
+		value("stateTable.tableName", options.StateTable.TableName)
+	}
+	for i, kb := range options.KnowledgeBases {
+		prefix := fmt.Sprintf("knowledgeBases[%d]", i)
+		// Names the resources and environment variables of the knowledge base
+		literal(prefix+".name", kb.Name)
+		literal(prefix+".collectionName", kb.CollectionName)
+		literal(prefix+".vectorStore", kb.VectorStore)
+		value(prefix+".knowledgeBaseId", kb.KnowledgeBaseID)
+		value(prefix+".embeddingModel", kb.EmbeddingModel)
+		value(prefix+".dataSourceBucketArn", kb.DataSourceBucketARN)
+	}
+	if options.Redis != nil {
+		literal("redis.name", options.Redis.Name)
+		literal("redis.engine", options.Redis.Engine)
+		value("redis.nodeType", options.Redis.NodeType)
+		value("redis.engineVersion", options.Redis.EngineVersion)
+	}
+
+	if options.Observability != nil && options.Observability.XRay != nil {
+		xray := options.Observability.XRay
+		value("observability.xray.endpoint", xray.Endpoint)
+		if xray.Collector != nil {
+			value("observability.xray.collector.endpoint", xray.Collector.Endpoint)
+		}
+	}
+	if options.Observability != nil {
+		// Embedded in OTEL_RESOURCE_ATTRIBUTES and OTEL_EXPORTER_OTLP_HEADERS
+		literal("observability.environment", options.Observability.Environment)
+		literal("observability.opikWorkspace", options.Observability.OpikWorkspace)
+		value("observability.apiKeyJsonKey", options.Observability.APIKeyJSONKey)
+		for i, metric := range options.Observability.LogMetrics {
+			prefix := fmt.Sprintf("observability.logMetrics[%d]", i)
+			// Names the metric filter and is searched for on the dashboard
+			literal(prefix+".name", metric.Name)
+			literal(prefix+".namespace", metric.Namespace)
+			value(prefix+".pattern", metric.Pattern)
+			literal(prefix+".value", metric.Value)
+		}
+	}
+	if options.Observability != nil && options.Observability.UsageMetrics != nil {
+		usage := options.Observability.UsageMetrics
+		value("observability.usageMetrics.namespace", usage.Namespace)
+		value("observability.usageMetrics.filterPattern", usage.FilterPattern)
+		value("observability.usageMetrics.reportBucketArn", usage.ReportBucketARN)
+		// Scopes the report function's bucket permission
+		literal("observability.usageMetrics.reportPrefix", usage.ReportPrefix)
+	}
+	if options.Observability != nil && options.Observability.LogDestination != nil {
+		dest := options.Observability.LogDestination
+		// The destination type is read from the ARN at synth time
+		literal("observability.logDestination.destinationArn", dest.DestinationARN)
+		value("observability.logDestination.filterPattern", dest.FilterPattern)
+		if f := dest.Firehose; f != nil {
+			value("observability.logDestination.firehose.bucketArn", f.BucketARN)
+			value("observability.logDestination.firehose.prefix", f.Prefix)
+			value("observability.logDestination.firehose.openSearchDomainArn", f.OpenSearchDomainARN)
+			value("observability.logDestination.firehose.indexName", f.IndexName)
+		}
+	}
+
+	if options.Gateway != nil {
+		for j, target := range options.Gateway.RemoteTargets {
+			prefix := fmt.Sprintf("gateway.remoteTargets[%d]", j)
+			literal(prefix+".name", target.Name)
+			// The invocation URL is built from the ARN at synth time
+			literal(prefix+".runtimeArn", target.RuntimeARN)
+			literal(prefix+".qualifier", target.Qualifier)
+		}
+		for j, target := range options.Gateway.ToolTargets {
+			prefix := fmt.Sprintf("gateway.toolTargets[%d]", j)
+			literal(prefix+".name", target.Name)
+			value(prefix+".functionArn", target.FunctionARN)
+			// Read or parsed at synth time
+			literal(prefix+".toolSchemaFile", target.ToolSchemaFile)
+			literal(prefix+".toolSchemaS3Uri", target.ToolSchemaS3URI)
+			literal(prefix+".specFile", target.SpecFile)
+			literal(prefix+".specS3Uri", target.SpecS3URI)
+			if target.Credentials != nil {
+				literal(prefix+".credentials.providerArn", target.Credentials.ProviderARN)
+			}
+		}
+		if interceptor := options.Gateway.InterceptorLambda; interceptor != nil {
+			// Read at synth time
+			literal("gateway.interceptorLambda.codePath", interceptor.CodePath)
+			value("gateway.interceptorLambda.functionArn", interceptor.FunctionARN)
+			literal("gateway.interceptorLambda.runtime", interceptor.Runtime)
+			value("gateway.interceptorLambda.handler", interceptor.Handler)
+			for k, v := range interceptor.Environment {
+				value(fmt.Sprintf("gateway.interceptorLambda.environment[%s]", k), v)
+			}
+		}
+	}
+
+	return literals, values
+}
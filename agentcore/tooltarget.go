@@ -0,0 +1,514 @@
+package agentcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsbedrockagentcore"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Gateway tool target types.
+const (
+	// ToolTargetLambda exposes a Lambda function as MCP tools described by
+	// a tool schema.
+	ToolTargetLambda = "lambda"
+
+	// ToolTargetOpenAPI exposes the operations of an OpenAPI spec as MCP
+	// tools.
+	ToolTargetOpenAPI = "openApi"
+)
+
+// Credential types of OpenAPI tool targets.
+const (
+	ToolCredentialAPIKey = "apiKey"
+	ToolCredentialOAuth  = "oauth"
+)
+
+var (
+	// s3URIPattern matches s3://bucket/key URIs and captures the bucket
+	// and key.
+	s3URIPattern = regexp.MustCompile(`^s3://([a-z0-9][a-z0-9.-]{1,61}[a-z0-9])/(.+)$`)
+
+	// credentialProviderARNPattern matches AgentCore Identity credential
+	// provider ARNs and captures the token vault ARN and provider kind.
+	credentialProviderARNPattern = regexp.MustCompile(`^(arn:aws[a-z-]*:bedrock-agentcore:[a-z0-9-]+:\d{12}:token-vault/[a-zA-Z0-9_-]+)/(apikey|oauth2)credentialprovider/[a-zA-Z0-9_-]+$`)
+)
+
+// ToolTarget exposes a Lambda function or an OpenAPI described API as MCP
+// tools of the stack's gateway, so agents can call internal APIs as tools.
+type ToolTarget struct {
+	// Name is the gateway target name.
+	Name string `json:"name" yaml:"name"`
+
+	// Description describes the target.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Type is "lambda" or "openApi".
+	Type string `json:"type" yaml:"type"`
+
+	// FunctionARN is the function of a lambda target. The gateway role is
+	// granted lambda:InvokeFunction on it.
+	FunctionARN string `json:"functionArn,omitempty" yaml:"functionArn,omitempty"`
+
+	// ToolSchemaFile is a JSON file, relative to the CDK app directory,
+	// holding the array of tools of a lambda target, each with a name,
+	// description, and inputSchema. Mutually exclusive with ToolSchemaS3URI.
+	ToolSchemaFile string `json:"toolSchemaFile,omitempty" yaml:"toolSchemaFile,omitempty"`
+
+	// ToolSchemaS3URI is the s3:// URI of the tool schema of a lambda
+	// target. Mutually exclusive with ToolSchemaFile.
+	ToolSchemaS3URI string `json:"toolSchemaS3Uri,omitempty" yaml:"toolSchemaS3Uri,omitempty"`
+
+	// SpecFile is the OpenAPI spec of an openApi target, a JSON or YAML
+	// file relative to the CDK app directory. Mutually exclusive with
+	// SpecS3URI.
+	SpecFile string `json:"specFile,omitempty" yaml:"specFile,omitempty"`
+
+	// SpecS3URI is the s3:// URI of the OpenAPI spec of an openApi target.
+	// Mutually exclusive with SpecFile.
+	SpecS3URI string `json:"specS3Uri,omitempty" yaml:"specS3Uri,omitempty"`
+
+	// Credentials is how the gateway authenticates to the API of an
+	// openApi target. Required for openApi targets; lambda targets are
+	// invoked with the gateway role.
+	Credentials *ToolCredentials `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
+
+// ToolCredentials is an AgentCore Identity credential provider the gateway
+// authenticates to an OpenAPI target's API with.
+type ToolCredentials struct {
+	// Type is "apiKey" or "oauth".
+	Type string `json:"type" yaml:"type"`
+
+	// ProviderARN is the ARN of the API key or OAuth2 credential provider,
+	// e.g. arn:aws:bedrock-agentcore:us-east-1:123456789012:token-vault/default/apikeycredentialprovider/crm.
+	ProviderARN string `json:"providerArn" yaml:"providerArn"`
+
+	// Scopes are the OAuth scopes requested (oauth only).
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+
+	// Location is where the API key is sent: "HEADER" or "QUERY_PARAMETER"
+	// (apiKey only).
+	// Default: "HEADER"
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+
+	// ParameterName is the header or query parameter holding the API key
+	// (apiKey only).
+	// Default: "Authorization"
+	ParameterName string `json:"parameterName,omitempty" yaml:"parameterName,omitempty"`
+
+	// Prefix is prepended to the API key, e.g. "Bearer" (apiKey only).
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+}
+
+// toolDefinition is a tool of a lambda target's tool schema file.
+type toolDefinition struct {
+	Name         string      `json:"name"`
+	Description  string      `json:"description"`
+	InputSchema  *toolSchema `json:"inputSchema"`
+	OutputSchema *toolSchema `json:"outputSchema"`
+}
+
+// toolSchema is the JSON schema subset of gateway tool definitions.
+type toolSchema struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	Items       *toolSchema            `json:"items"`
+	Properties  map[string]*toolSchema `json:"properties"`
+	Required    []string               `json:"required"`
+}
+
+// validate validates a tool schema and its nested schemas.
+func (s *toolSchema) validate(path string) error {
+	if s.Type == "" {
+		return fmt.Errorf("%s.type is required", path)
+	}
+	if s.Items != nil {
+		if err := s.Items.validate(path + ".items"); err != nil {
+			return err
+		}
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s.properties.%s must be an object", path, name)
+		}
+		if err := property.validate(path + ".properties." + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// property returns the schema as a CloudFormation schema definition.
+func (s *toolSchema) property() *awsbedrockagentcore.CfnGatewayTarget_SchemaDefinitionProperty {
+	if s == nil {
+		return nil
+	}
+	schema := &awsbedrockagentcore.CfnGatewayTarget_SchemaDefinitionProperty{
+		Type: jsii.String(s.Type),
+	}
+	if s.Description != "" {
+		schema.Description = jsii.String(s.Description)
+	}
+	if s.Items != nil {
+		schema.Items = s.Items.property()
+	}
+	if len(s.Properties) > 0 {
+		properties := make(map[string]interface{}, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = property.property()
+		}
+		schema.Properties = &properties
+	}
+	if len(s.Required) > 0 {
+		schema.Required = jsii.Strings(s.Required...)
+	}
+	return schema
+}
+
+// loadToolSchema reads and validates the tools of a tool schema file.
+func loadToolSchema(file string) ([]toolDefinition, error) {
+	data, err := os.ReadFile(file) //nolint:gosec // G304: schema path comes from the stack config
+	if err != nil {
+		return nil, fmt.Errorf("reading tool schema: %w", err)
+	}
+	var tools []toolDefinition
+	if err := json.Unmarshal(data, &tools); err != nil {
+		return nil, fmt.Errorf("parsing %s: must be a JSON array of tools: %w", file, err)
+	}
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("%s defines no tools", file)
+	}
+	for i, tool := range tools {
+		path := fmt.Sprintf("%s[%d]", file, i)
+		switch {
+		case tool.Name == "":
+			return nil, fmt.Errorf("%s.name is required", path)
+		case tool.Description == "":
+			return nil, fmt.Errorf("%s (%s).description is required", path, tool.Name)
+		case tool.InputSchema == nil:
+			return nil, fmt.Errorf("%s (%s).inputSchema is required", path, tool.Name)
+		}
+		if err := tool.InputSchema.validate(path + ".inputSchema"); err != nil {
+			return nil, err
+		}
+		if tool.OutputSchema != nil {
+			if err := tool.OutputSchema.validate(path + ".outputSchema"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tools, nil
+}
+
+// loadOpenAPISpec reads an OpenAPI spec file and checks that it is a JSON
+// or YAML OpenAPI document.
+func loadOpenAPISpec(file string) (string, error) {
+	data, err := os.ReadFile(file) //nolint:gosec // G304: spec path comes from the stack config
+	if err != nil {
+		return "", fmt.Errorf("reading OpenAPI spec: %w", err)
+	}
+	var spec map[string]any
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return "", fmt.Errorf("parsing %s: must be a JSON or YAML object: %w", file, err)
+	}
+	if _, ok := spec["openapi"]; !ok {
+		return "", fmt.Errorf("%s is not an OpenAPI 3 spec (no openapi field)", file)
+	}
+	return string(data), nil
+}
+
+// validateToolTargets validates the gateway's tool targets.
+func validateToolTargets(targets []ToolTarget) error {
+	for i, target := range targets {
+		prefix := fmt.Sprintf("gateway.toolTargets[%d]", i)
+		if !gatewayTargetNamePattern.MatchString(target.Name) {
+			return fmt.Errorf("%s: name %q must match %s", prefix, target.Name, gatewayTargetNamePattern)
+		}
+		prefix = fmt.Sprintf("%s (%s)", prefix, target.Name)
+
+		switch target.Type {
+		case ToolTargetLambda:
+			switch {
+			case target.FunctionARN == "":
+				return fmt.Errorf("%s: lambda targets require functionArn", prefix)
+			case !*awscdk.Token_IsUnresolved(target.FunctionARN) && !lambdaFunctionARNPattern.MatchString(target.FunctionARN):
+				return fmt.Errorf("%s: functionArn %q must be a Lambda function ARN", prefix, target.FunctionARN)
+			case (target.ToolSchemaFile == "") == (target.ToolSchemaS3URI == ""):
+				return fmt.Errorf("%s: lambda targets require one of toolSchemaFile and toolSchemaS3Uri", prefix)
+			case target.SpecFile != "" || target.SpecS3URI != "":
+				return fmt.Errorf("%s: specFile and specS3Uri require type %s", prefix, ToolTargetOpenAPI)
+			case target.Credentials != nil:
+				return fmt.Errorf("%s: lambda targets are invoked with the gateway role and take no credentials", prefix)
+			}
+			if target.ToolSchemaFile != "" {
+				if _, err := loadToolSchema(target.ToolSchemaFile); err != nil {
+					return fmt.Errorf("%s: %w", prefix, err)
+				}
+			}
+			if target.ToolSchemaS3URI != "" && !s3URIPattern.MatchString(target.ToolSchemaS3URI) {
+				return fmt.Errorf("%s: toolSchemaS3Uri %q must be an s3://bucket/key URI", prefix, target.ToolSchemaS3URI)
+			}
+		case ToolTargetOpenAPI:
+			switch {
+			case (target.SpecFile == "") == (target.SpecS3URI == ""):
+				return fmt.Errorf("%s: openApi targets require one of specFile and specS3Uri", prefix)
+			case target.FunctionARN != "" || target.ToolSchemaFile != "" || target.ToolSchemaS3URI != "":
+				return fmt.Errorf("%s: functionArn, toolSchemaFile, and toolSchemaS3Uri require type %s", prefix, ToolTargetLambda)
+			case target.Credentials == nil:
+				return fmt.Errorf("%s: openApi targets require credentials", prefix)
+			}
+			if target.SpecFile != "" {
+				if _, err := loadOpenAPISpec(target.SpecFile); err != nil {
+					return fmt.Errorf("%s: %w", prefix, err)
+				}
+			}
+			if target.SpecS3URI != "" && !s3URIPattern.MatchString(target.SpecS3URI) {
+				return fmt.Errorf("%s: specS3Uri %q must be an s3://bucket/key URI", prefix, target.SpecS3URI)
+			}
+			if err := target.Credentials.validate(prefix + ".credentials"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: type must be one of [%s %s], got %q", prefix, ToolTargetLambda, ToolTargetOpenAPI, target.Type)
+		}
+	}
+	return nil
+}
+
+// validate validates the credentials of an OpenAPI target.
+func (c *ToolCredentials) validate(prefix string) error {
+	m := credentialProviderARNPattern.FindStringSubmatch(c.ProviderARN)
+	switch c.Type {
+	case ToolCredentialAPIKey:
+		switch {
+		case m == nil || m[2] != "apikey":
+			return fmt.Errorf("%s.providerArn %q must be an API key credential provider ARN", prefix, c.ProviderARN)
+		case len(c.Scopes) > 0:
+			return fmt.Errorf("%s.scopes require type %s", prefix, ToolCredentialOAuth)
+		case c.Location != "" && c.Location != "HEADER" && c.Location != "QUERY_PARAMETER":
+			return fmt.Errorf("%s.location must be HEADER or QUERY_PARAMETER, got %q", prefix, c.Location)
+		}
+	case ToolCredentialOAuth:
+		switch {
+		case m == nil || m[2] != "oauth2":
+			return fmt.Errorf("%s.providerArn %q must be an OAuth2 credential provider ARN", prefix, c.ProviderARN)
+		case len(c.Scopes) == 0:
+			return fmt.Errorf("%s.scopes are required for type %s", prefix, ToolCredentialOAuth)
+		case c.Location != "" || c.ParameterName != "" || c.Prefix != "":
+			return fmt.Errorf("%s.location, parameterName, and prefix require type %s", prefix, ToolCredentialAPIKey)
+		}
+	default:
+		return fmt.Errorf("%s.type must be one of [%s %s], got %q", prefix, ToolCredentialAPIKey, ToolCredentialOAuth, c.Type)
+	}
+	return nil
+}
+
+// createToolTargets registers the Lambda and OpenAPI tool targets with the
+// stack's gateway and grants the gateway role what it needs to call them:
+// invoking the functions, reading schemas from S3, and fetching
+// credentials from AgentCore Identity.
+func (s *AgentCoreStack) createToolTargets() {
+	if s.Gateway == nil || s.Options.Gateway == nil || len(s.Options.Gateway.ToolTargets) == 0 {
+		return
+	}
+
+	var functionARNs, objectARNs, providerARNs []*string
+	for _, target := range s.Options.Gateway.ToolTargets {
+		mcp := &awsbedrockagentcore.CfnGatewayTarget_McpTargetConfigurationProperty{}
+		var credentials interface{}
+		switch target.Type {
+		case ToolTargetLambda:
+			mcp.Lambda = &awsbedrockagentcore.CfnGatewayTarget_McpLambdaTargetConfigurationProperty{
+				LambdaArn:  jsii.String(target.FunctionARN),
+				ToolSchema: s.toolSchemaProperty(target),
+			}
+			credentials = &awsbedrockagentcore.CfnGatewayTarget_CredentialProviderConfigurationProperty{
+				CredentialProviderType: jsii.String("GATEWAY_IAM_ROLE"),
+			}
+			functionARNs = append(functionARNs, jsii.String(target.FunctionARN))
+			if target.ToolSchemaS3URI != "" {
+				objectARNs = append(objectARNs, s.s3ObjectARN(target.ToolSchemaS3URI))
+			}
+		case ToolTargetOpenAPI:
+			schema := &awsbedrockagentcore.CfnGatewayTarget_ApiSchemaConfigurationProperty{}
+			if target.SpecS3URI != "" {
+				schema.S3 = &awsbedrockagentcore.CfnGatewayTarget_S3ConfigurationProperty{Uri: jsii.String(target.SpecS3URI)}
+				objectARNs = append(objectARNs, s.s3ObjectARN(target.SpecS3URI))
+			} else {
+				spec, err := loadOpenAPISpec(target.SpecFile)
+				if err != nil {
+					panic(fmt.Sprintf("invalid stack options: gateway.toolTargets (%s): %v", target.Name, err))
+				}
+				schema.InlinePayload = jsii.String(spec)
+			}
+			mcp.OpenApiSchema = schema
+			credentials = target.Credentials.property()
+			providerARNs = append(providerARNs, jsii.String(target.Credentials.ProviderARN))
+		}
+
+		description := target.Description
+		if description == "" {
+			description = fmt.Sprintf("%s tool target %s", target.Type, target.Name)
+		}
+		gatewayTarget := awsbedrockagentcore.NewCfnGatewayTarget(s.Stack,
+			jsii.String(fmt.Sprintf("GatewayTarget-%s", target.Name)),
+			&awsbedrockagentcore.CfnGatewayTargetProps{
+				Name:              jsii.String(target.Name),
+				Description:       jsii.String(description),
+				GatewayIdentifier: s.Gateway.AttrGatewayIdentifier(),
+				TargetConfiguration: &awsbedrockagentcore.CfnGatewayTarget_TargetConfigurationProperty{
+					Mcp: mcp,
+				},
+				CredentialProviderConfigurations: &[]interface{}{credentials},
+			},
+		)
+		// Targets are validated against the role's permissions on creation
+		gatewayTarget.Node().AddDependency(s.ExecutionRole)
+		s.GatewayTargets[target.Name] = gatewayTarget
+
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("GatewayTarget-%s-Id", target.Name)),
+			&awscdk.CfnOutputProps{
+				Value:       gatewayTarget.AttrTargetId(),
+				Description: jsii.String(fmt.Sprintf("Gateway target ID for tool target %s", target.Name)),
+			})
+	}
+
+	// The gateway calls targets with its own role (the execution role).
+	if len(functionARNs) > 0 {
+		s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect:    awsiam.Effect_ALLOW,
+			Actions:   jsii.Strings("lambda:InvokeFunction"),
+			Resources: &functionARNs,
+		}))
+	}
+	if len(objectARNs) > 0 {
+		s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect:    awsiam.Effect_ALLOW,
+			Actions:   jsii.Strings("s3:GetObject"),
+			Resources: &objectARNs,
+		}))
+	}
+	if len(providerARNs) > 0 {
+		s.grantCredentialProviders(providerARNs)
+	}
+}
+
+// toolSchemaProperty returns the tool schema of a lambda target: the S3
+// object, or the tools of the schema file inline.
+func (s *AgentCoreStack) toolSchemaProperty(target ToolTarget) *awsbedrockagentcore.CfnGatewayTarget_ToolSchemaProperty {
+	if target.ToolSchemaS3URI != "" {
+		return &awsbedrockagentcore.CfnGatewayTarget_ToolSchemaProperty{
+			S3: &awsbedrockagentcore.CfnGatewayTarget_S3ConfigurationProperty{Uri: jsii.String(target.ToolSchemaS3URI)},
+		}
+	}
+
+	tools, err := loadToolSchema(target.ToolSchemaFile)
+	if err != nil {
+		panic(fmt.Sprintf("invalid stack options: gateway.toolTargets (%s): %v", target.Name, err))
+	}
+	definitions := make([]interface{}, len(tools))
+	for i, tool := range tools {
+		definition := &awsbedrockagentcore.CfnGatewayTarget_ToolDefinitionProperty{
+			Name:        jsii.String(tool.Name),
+			Description: jsii.String(tool.Description),
+			InputSchema: tool.InputSchema.property(),
+		}
+		if tool.OutputSchema != nil {
+			definition.OutputSchema = tool.OutputSchema.property()
+		}
+		definitions[i] = definition
+	}
+	return &awsbedrockagentcore.CfnGatewayTarget_ToolSchemaProperty{InlinePayload: &definitions}
+}
+
+// property returns the credential provider configuration of an OpenAPI
+// target.
+func (c *ToolCredentials) property() *awsbedrockagentcore.CfnGatewayTarget_CredentialProviderConfigurationProperty {
+	if c.Type == ToolCredentialOAuth {
+		return &awsbedrockagentcore.CfnGatewayTarget_CredentialProviderConfigurationProperty{
+			CredentialProviderType: jsii.String("OAUTH"),
+			CredentialProvider: &awsbedrockagentcore.CfnGatewayTarget_CredentialProviderProperty{
+				OauthCredentialProvider: &awsbedrockagentcore.CfnGatewayTarget_OAuthCredentialProviderProperty{
+					ProviderArn: jsii.String(c.ProviderARN),
+					Scopes:      jsii.Strings(c.Scopes...),
+				},
+			},
+		}
+	}
+
+	apiKey := &awsbedrockagentcore.CfnGatewayTarget_ApiKeyCredentialProviderProperty{
+		ProviderArn: jsii.String(c.ProviderARN),
+	}
+	if c.Location != "" {
+		apiKey.CredentialLocation = jsii.String(c.Location)
+	}
+	if c.ParameterName != "" {
+		apiKey.CredentialParameterName = jsii.String(c.ParameterName)
+	}
+	if c.Prefix != "" {
+		apiKey.CredentialPrefix = jsii.String(c.Prefix)
+	}
+	return &awsbedrockagentcore.CfnGatewayTarget_CredentialProviderConfigurationProperty{
+		CredentialProviderType: jsii.String("API_KEY"),
+		CredentialProvider: &awsbedrockagentcore.CfnGatewayTarget_CredentialProviderProperty{
+			ApiKeyCredentialProvider: apiKey,
+		},
+	}
+}
+
+// grantCredentialProviders grants the gateway role access to the
+// credentials of the providers: a workload access token for the gateway,
+// the API keys or OAuth tokens of the providers, and the token vault
+// secrets holding them.
+func (s *AgentCoreStack) grantCredentialProviders(providerARNs []*string) {
+	resources := append([]*string{}, providerARNs...)
+	vaults := make(map[string]bool)
+	for _, arn := range providerARNs {
+		vault := credentialProviderARNPattern.FindStringSubmatch(*arn)[1]
+		if !vaults[vault] {
+			vaults[vault] = true
+			resources = append(resources, jsii.String(vault))
+		}
+	}
+
+	directory := s.Stack.FormatArn(&awscdk.ArnComponents{
+		Service:      jsii.String("bedrock-agentcore"),
+		Resource:     jsii.String("workload-identity-directory"),
+		ResourceName: jsii.String("default"),
+	})
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("bedrock-agentcore:GetWorkloadAccessToken"),
+		Resources: &[]*string{directory, jsii.String(*directory + "/workload-identity/*")},
+	}))
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:    awsiam.Effect_ALLOW,
+		Actions:   jsii.Strings("bedrock-agentcore:GetResourceApiKey", "bedrock-agentcore:GetResourceOauth2Token"),
+		Resources: &resources,
+	}))
+	s.ExecutionRole.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:  awsiam.Effect_ALLOW,
+		Actions: jsii.Strings("secretsmanager:GetSecretValue"),
+		Resources: &[]*string{s.Stack.FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("secretsmanager"),
+			Resource:     jsii.String("secret"),
+			ResourceName: jsii.String("bedrock-agentcore-identity!*"),
+			ArnFormat:    awscdk.ArnFormat_COLON_RESOURCE_NAME,
+		})},
+	}))
+}
+
+// s3ObjectARN returns the ARN of the object of an s3:// URI.
+func (s *AgentCoreStack) s3ObjectARN(uri string) *string {
+	m := s3URIPattern.FindStringSubmatch(uri)
+	return jsii.String(fmt.Sprintf("arn:%s:s3:::%s/%s", *s.Stack.Partition(), m[1], strings.TrimPrefix(m[2], "/")))
+}
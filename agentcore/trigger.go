@@ -0,0 +1,336 @@
+package agentcore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsevents"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambdaeventsources"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssnssubscriptions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Trigger types.
+const (
+	TriggerTypeSQS         = "sqs"
+	TriggerTypeSNS         = "sns"
+	TriggerTypeEventBridge = "eventbridge"
+)
+
+var (
+	// triggerNamePattern is the naming rule for triggers, which are used in
+	// construct IDs and output keys.
+	triggerNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]{0,63}$`)
+
+	// triggerSourceARNPatterns match the source ARN of each trigger type.
+	triggerSourceARNPatterns = map[string]*regexp.Regexp{
+		TriggerTypeSQS:         regexp.MustCompile(`^arn:aws[a-z-]*:sqs:[a-z0-9-]+:\d{12}:[a-zA-Z0-9_-]+(\.fifo)?$`),
+		TriggerTypeSNS:         regexp.MustCompile(`^arn:aws[a-z-]*:sns:[a-z0-9-]+:\d{12}:[a-zA-Z0-9_-]+(\.fifo)?$`),
+		TriggerTypeEventBridge: regexp.MustCompile(`^arn:aws[a-z-]*:events:[a-z0-9-]+:\d{12}:event-bus/[a-zA-Z0-9._/-]+$`),
+	}
+)
+
+// triggerBridgeCode is the bridge function forwarding events to the agent.
+// SQS messages that fail are reported individually so only they are retried.
+const triggerBridgeCode = `import json
+import os
+
+import boto3
+
+client = boto3.client("bedrock-agentcore")
+
+
+def invoke(payload):
+    response = client.invoke_agent_runtime(
+        agentRuntimeArn=os.environ["AGENT_RUNTIME_ARN"],
+        qualifier=os.environ["AGENT_QUALIFIER"],
+        contentType="application/json",
+        payload=payload.encode("utf-8"),
+    )
+    response["response"].read()
+
+
+def handler(event, context):
+    records = event.get("Records")
+    if records is None:
+        invoke(json.dumps(event))
+        return None
+
+    failures = []
+    for record in records:
+        if record.get("EventSource") == "aws:sns":
+            invoke(record["Sns"]["Message"])
+            continue
+        try:
+            invoke(record["body"])
+        except Exception as err:
+            print(f"message {record['messageId']} failed: {err}")
+            failures.append({"itemIdentifier": record["messageId"]})
+    return {"batchItemFailures": failures}
+`
+
+// TriggerOptions invokes the agent when a message arrives on an SQS queue
+// or SNS topic, or an event matches an EventBridge pattern. A bridge Lambda
+// function forwards the SQS message body, the SNS message, or the whole
+// EventBridge event to the agent as the payload.
+type TriggerOptions struct {
+	// Type is the event source: "sqs", "sns", or "eventbridge".
+	Type string `json:"type" yaml:"type"`
+
+	// Name identifies the trigger in construct IDs and outputs.
+	// Pattern: [a-zA-Z][a-zA-Z0-9]{0,63}
+	// Default: "{type}{index}", e.g. "sqs1"
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// SourceARN is an existing queue, topic, or event bus.
+	// Default: a new queue or topic, or the default event bus
+	SourceARN string `json:"sourceArn,omitempty" yaml:"sourceArn,omitempty"`
+
+	// EventPattern selects the EventBridge events that invoke the agent.
+	// Required for eventbridge triggers.
+	EventPattern map[string]any `json:"eventPattern,omitempty" yaml:"eventPattern,omitempty"`
+
+	// BatchSize is the number of SQS messages per bridge invocation.
+	// Range: 1-10
+	// Default: 1
+	BatchSize int `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+
+	// Endpoint is the runtime endpoint to invoke.
+	// Default: the agent's default endpoint
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	// TimeoutSeconds is the timeout of the bridge function, which waits for
+	// the agent's response.
+	// Range: 1-900
+	// Default: 300
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+}
+
+// name returns the trigger name.
+func (o *TriggerOptions) name(index int) string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return fmt.Sprintf("%s%d", o.Type, index+1)
+}
+
+// AgentTrigger is an event source invoking an agent and its bridge function.
+type AgentTrigger struct {
+	// Function is the bridge function invoking the agent.
+	Function awslambda.Function
+
+	// Queue is the source queue (sqs triggers only).
+	Queue awssqs.IQueue
+
+	// Topic is the source topic (sns triggers only).
+	Topic awssns.ITopic
+
+	// Rule is the event rule (eventbridge triggers only).
+	Rule awsevents.CfnRule
+}
+
+// validateTriggers validates an agent's triggers.
+func validateTriggers(agentName string, opts *AgentOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	endpoints := map[string]bool{defaultEndpointName(agentName, opts): true}
+	for _, endpoint := range opts.Endpoints {
+		endpoints[endpoint.Name] = true
+	}
+
+	names := make(map[string]bool)
+	for i, trigger := range opts.Triggers {
+		pattern, ok := triggerSourceARNPatterns[trigger.Type]
+		if !ok {
+			return fmt.Errorf("triggers[%d].type %q must be sqs, sns, or eventbridge", i, trigger.Type)
+		}
+		name := trigger.name(i)
+		if !triggerNamePattern.MatchString(name) {
+			return fmt.Errorf("triggers[%d].name %q must match %s", i, name, triggerNamePattern)
+		}
+		if names[name] {
+			return fmt.Errorf("triggers[%d].name %q is already used by another trigger of the agent", i, name)
+		}
+		names[name] = true
+
+		if trigger.SourceARN != "" && !*awscdk.Token_IsUnresolved(trigger.SourceARN) && !pattern.MatchString(trigger.SourceARN) {
+			return fmt.Errorf("triggers[%d].sourceArn %q is not a valid %s ARN", i, trigger.SourceARN, trigger.Type)
+		}
+		if (trigger.Type == TriggerTypeEventBridge) != (trigger.EventPattern != nil) {
+			return fmt.Errorf("triggers[%d].eventPattern is required for, and only valid for, eventbridge triggers", i)
+		}
+		if trigger.BatchSize != 0 && (trigger.Type != TriggerTypeSQS || trigger.BatchSize < 1 || trigger.BatchSize > 10) {
+			return fmt.Errorf("triggers[%d].batchSize must be between 1 and 10 and is only valid for sqs triggers", i)
+		}
+		if trigger.Endpoint != "" && !endpoints[trigger.Endpoint] {
+			return fmt.Errorf("triggers[%d].endpoint %q is not an endpoint of the agent", i, trigger.Endpoint)
+		}
+		if trigger.TimeoutSeconds < 0 || trigger.TimeoutSeconds > 900 {
+			return fmt.Errorf("triggers[%d].timeoutSeconds must be between 1 and 900, got %d", i, trigger.TimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// createTriggers creates the agent's event sources and bridge functions.
+func (s *AgentCoreStack) createTriggers(config *AgentConfig) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || len(opts.Triggers) == 0 {
+		return
+	}
+
+	runtime := s.Runtimes[config.Name]
+	scope := s.agentScope(config.Name)
+	triggers := make(map[string]*AgentTrigger, len(opts.Triggers))
+	for i, triggerOpts := range opts.Triggers {
+		name := triggerOpts.name(i)
+		id := fmt.Sprintf("Trigger-%s-%s", config.Name, name)
+		endpoint := triggerOpts.Endpoint
+		if endpoint == "" {
+			endpoint = defaultEndpointName(config.Name, opts)
+		}
+		timeoutSeconds := triggerOpts.TimeoutSeconds
+		if timeoutSeconds == 0 {
+			timeoutSeconds = 300
+		}
+
+		fn := awslambda.NewFunction(scope, jsii.String(id), &awslambda.FunctionProps{
+			Description:  jsii.String(fmt.Sprintf("Invokes agent %s from %s trigger %s", config.Name, triggerOpts.Type, name)),
+			Code:         awslambda.Code_FromInline(jsii.String(triggerBridgeCode)),
+			Runtime:      awslambda.Runtime_PYTHON_3_13(),
+			Handler:      jsii.String("index.handler"),
+			Architecture: awslambda.Architecture_ARM_64(),
+			MemorySize:   jsii.Number(128),
+			Timeout:      awscdk.Duration_Seconds(jsii.Number(float64(timeoutSeconds))),
+			Environment: &map[string]*string{
+				"AGENT_RUNTIME_ARN": runtime.AttrAgentRuntimeArn(),
+				"AGENT_QUALIFIER":   jsii.String(endpoint),
+			},
+			EnvironmentEncryption: s.KMSKey,
+		})
+		fn.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Effect:  awsiam.Effect_ALLOW,
+			Actions: jsii.Strings("bedrock-agentcore:InvokeAgentRuntime"),
+			Resources: &[]*string{
+				runtime.AttrAgentRuntimeArn(),
+				jsii.String(fmt.Sprintf("%s/*", *runtime.AttrAgentRuntimeArn())),
+			},
+		}))
+		// The qualifier names the endpoint without referencing it
+		if named, ok := s.NamedEndpoints[config.Name][endpoint]; ok {
+			fn.Node().AddDependency(named)
+		} else {
+			fn.Node().AddDependency(s.Endpoints[config.Name])
+		}
+
+		trigger := &AgentTrigger{Function: fn}
+		var sourceARN *string
+		switch triggerOpts.Type {
+		case TriggerTypeSQS:
+			trigger.Queue = s.triggerQueue(scope, id, triggerOpts, timeoutSeconds)
+			batchSize := triggerOpts.BatchSize
+			if batchSize == 0 {
+				batchSize = 1
+			}
+			fn.AddEventSource(awslambdaeventsources.NewSqsEventSource(trigger.Queue, &awslambdaeventsources.SqsEventSourceProps{
+				BatchSize:               jsii.Number(float64(batchSize)),
+				ReportBatchItemFailures: jsii.Bool(true),
+			}))
+			sourceARN = trigger.Queue.QueueArn()
+
+			awscdk.NewCfnOutput(s.Stack,
+				jsii.String(fmt.Sprintf("Agent-%s-Trigger-%s-QueueUrl", config.Name, name)),
+				&awscdk.CfnOutputProps{
+					Value:       trigger.Queue.QueueUrl(),
+					Description: jsii.String(fmt.Sprintf("URL of the queue of trigger %s of agent %s", name, config.Name)),
+				})
+		case TriggerTypeSNS:
+			if triggerOpts.SourceARN != "" {
+				trigger.Topic = awssns.Topic_FromTopicArn(scope, jsii.String(id+"-Topic"), jsii.String(triggerOpts.SourceARN))
+			} else {
+				trigger.Topic = awssns.NewTopic(scope, jsii.String(id+"-Topic"), &awssns.TopicProps{
+					MasterKey:  s.KMSKey,
+					EnforceSSL: jsii.Bool(true),
+				})
+			}
+			trigger.Topic.AddSubscription(awssnssubscriptions.NewLambdaSubscription(fn, nil))
+			sourceARN = trigger.Topic.TopicArn()
+		case TriggerTypeEventBridge:
+			trigger.Rule = s.triggerRule(scope, id, config.Name, triggerOpts, fn)
+			sourceARN = trigger.Rule.AttrArn()
+		}
+
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("Agent-%s-Trigger-%s-Arn", config.Name, name)),
+			&awscdk.CfnOutputProps{
+				Value:       sourceARN,
+				Description: jsii.String(fmt.Sprintf("ARN of the %s source of trigger %s of agent %s", triggerOpts.Type, name, config.Name)),
+			})
+		triggers[name] = trigger
+	}
+	s.Triggers[config.Name] = triggers
+}
+
+// triggerQueue imports the trigger's queue into scope, or creates one with
+// a dead-letter queue for messages the agent repeatedly fails on.
+func (s *AgentCoreStack) triggerQueue(scope constructs.Construct, id string, opts TriggerOptions, timeoutSeconds int) awssqs.IQueue {
+	if opts.SourceARN != "" {
+		return awssqs.Queue_FromQueueArn(scope, jsii.String(id+"-Queue"), jsii.String(opts.SourceARN))
+	}
+
+	encryption := awssqs.QueueEncryption_SQS_MANAGED
+	if s.KMSKey != nil {
+		encryption = awssqs.QueueEncryption_KMS
+	}
+	deadLetterQueue := awssqs.NewQueue(scope, jsii.String(id+"-DeadLetterQueue"), &awssqs.QueueProps{
+		Encryption:          encryption,
+		EncryptionMasterKey: s.KMSKey,
+		EnforceSSL:          jsii.Bool(true),
+		RetentionPeriod:     awscdk.Duration_Days(jsii.Number(14)),
+	})
+	// Lambda recommends a visibility timeout of six times the function timeout
+	return awssqs.NewQueue(scope, jsii.String(id+"-Queue"), &awssqs.QueueProps{
+		Encryption:          encryption,
+		EncryptionMasterKey: s.KMSKey,
+		EnforceSSL:          jsii.Bool(true),
+		VisibilityTimeout:   awscdk.Duration_Seconds(jsii.Number(float64(6 * timeoutSeconds))),
+		DeadLetterQueue: &awssqs.DeadLetterQueue{
+			Queue:           deadLetterQueue,
+			MaxReceiveCount: jsii.Number(5),
+		},
+	})
+}
+
+// triggerRule creates the event rule of an eventbridge trigger in scope, on
+// the default or the given event bus.
+func (s *AgentCoreStack) triggerRule(scope constructs.Construct, id, agentName string, opts TriggerOptions, fn awslambda.Function) awsevents.CfnRule {
+	props := &awsevents.CfnRuleProps{
+		Description:  jsii.String(fmt.Sprintf("Events invoking agent %s", agentName)),
+		EventPattern: opts.EventPattern,
+		State:        jsii.String("ENABLED"),
+		Targets: &[]interface{}{
+			&awsevents.CfnRule_TargetProperty{
+				Id:  jsii.String("Bridge"),
+				Arn: fn.FunctionArn(),
+			},
+		},
+	}
+	if opts.SourceARN != "" {
+		props.EventBusName = jsii.String(opts.SourceARN)
+	}
+	rule := awsevents.NewCfnRule(scope, jsii.String(id+"-Rule"), props)
+
+	fn.AddPermission(jsii.String("EventBridge"), &awslambda.Permission{
+		Principal: awsiam.NewServicePrincipal(jsii.String("events.amazonaws.com"), nil),
+		SourceArn: rule.AttrArn(),
+	})
+	return rule
+}
@@ -0,0 +1,356 @@
+package agentcore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsevents"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awseventstargets"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogsdestinations"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// defaultUsageFilterPattern selects the log events carrying token usage in
+// any of the field layouts the usage processor reads.
+const defaultUsageFilterPattern = `{ ($.inputTokens = *) || ($.input_tokens = *) || ($.usage.inputTokens = *) || ($.usage.input_tokens = *) }`
+
+// ModelPrice is the on-demand price of a model per million tokens, in USD.
+type ModelPrice struct {
+	// InputPerMillion is the price of a million input tokens.
+	InputPerMillion float64 `json:"inputPerMillion" yaml:"inputPerMillion"`
+
+	// OutputPerMillion is the price of a million output tokens.
+	OutputPerMillion float64 `json:"outputPerMillion" yaml:"outputPerMillion"`
+}
+
+// DefaultModelPrices are the Bedrock on-demand prices in us-east-1 of
+// common models, keyed by model ID prefix. A model is priced by the longest
+// key contained in its ID, so inference profiles such as
+// "us.anthropic.claude-sonnet-4-20250514-v1:0" match too. Prices change;
+// override them with UsageMetricsOptions.ModelPrices.
+var DefaultModelPrices = map[string]ModelPrice{
+	"anthropic.claude-opus-4":     {InputPerMillion: 15, OutputPerMillion: 75},
+	"anthropic.claude-sonnet-4":   {InputPerMillion: 3, OutputPerMillion: 15},
+	"anthropic.claude-3-7-sonnet": {InputPerMillion: 3, OutputPerMillion: 15},
+	"anthropic.claude-haiku-4-5":  {InputPerMillion: 1, OutputPerMillion: 5},
+	"anthropic.claude-3-5-haiku":  {InputPerMillion: 0.8, OutputPerMillion: 4},
+	"amazon.nova-pro":             {InputPerMillion: 0.8, OutputPerMillion: 3.2},
+	"amazon.nova-lite":            {InputPerMillion: 0.06, OutputPerMillion: 0.24},
+	"amazon.nova-micro":           {InputPerMillion: 0.035, OutputPerMillion: 0.14},
+}
+
+// UsageMetricsOptions turns the LLM usage agents log into per-agent,
+// per-model CloudWatch metrics and a daily cost report. Agents write a JSON
+// log line per model call to the stack log group:
+//
+//	{"agent": "research", "model": "anthropic.claude-sonnet-4", "inputTokens": 1200, "outputTokens": 340}
+//
+// Token counts may also be nested under "usage" and use snake_case
+// ("input_tokens", "output_tokens"). Without an "agent" field, the agent is
+// the one whose name starts the log stream name.
+//
+// A function on a subscription filter publishes InputTokens, OutputTokens,
+// and EstimatedCost by Agent and Model, and by Agent, with the embedded
+// metric format. A scheduled function writes the totals of the previous UTC
+// day to {reportPrefix}{yyyy}/{mm}/{dd}.json and .csv in the report bucket.
+type UsageMetricsOptions struct {
+	// Namespace is the metric namespace.
+	// Default: "AgentKit/{stackName}/Usage"
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+
+	// FilterPattern selects the log events carrying usage.
+	// Default: events with an input token count
+	FilterPattern string `json:"filterPattern,omitempty" yaml:"filterPattern,omitempty"`
+
+	// ModelPrices adds to and overrides DefaultModelPrices. Models without
+	// a price get token metrics only.
+	ModelPrices map[string]ModelPrice `json:"modelPrices,omitempty" yaml:"modelPrices,omitempty"`
+
+	// ReportBucketARN is an existing bucket for the cost reports.
+	// Default: a bucket created by the stack
+	ReportBucketARN string `json:"reportBucketArn,omitempty" yaml:"reportBucketArn,omitempty"`
+
+	// ReportPrefix is the key prefix of the cost reports.
+	// Default: "usage-reports/"
+	ReportPrefix string `json:"reportPrefix,omitempty" yaml:"reportPrefix,omitempty"`
+}
+
+// validate validates the usage metrics options.
+func (o *UsageMetricsOptions) validate(obs *ObservabilityConfig) error {
+	if obs == nil || !obs.EnableCloudWatchLogs {
+		return fmt.Errorf("observability.usageMetrics requires observability.enableCloudWatchLogs: usage is read from the stack's log group")
+	}
+	for model, price := range o.ModelPrices {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return fmt.Errorf("observability.usageMetrics.modelPrices.%s must not be negative", model)
+		}
+	}
+	switch {
+	case o.ReportBucketARN != "" && !*awscdk.Token_IsUnresolved(o.ReportBucketARN) && !bucketARNPattern.MatchString(o.ReportBucketARN):
+		return fmt.Errorf("observability.usageMetrics.reportBucketArn %q must be an S3 bucket ARN", o.ReportBucketARN)
+	case strings.HasPrefix(o.ReportPrefix, "/"):
+		return fmt.Errorf("observability.usageMetrics.reportPrefix %q must not start with '/'", o.ReportPrefix)
+	}
+	return nil
+}
+
+// modelPrices returns DefaultModelPrices with the configured prices.
+func (o *UsageMetricsOptions) modelPrices() map[string]ModelPrice {
+	prices := make(map[string]ModelPrice, len(DefaultModelPrices)+len(o.ModelPrices))
+	for model, price := range DefaultModelPrices {
+		prices[model] = price
+	}
+	for model, price := range o.ModelPrices {
+		prices[model] = price
+	}
+	return prices
+}
+
+// createUsageMetrics creates the usage processor on a subscription filter
+// of the stack's log group, and the scheduled cost report.
+func (s *AgentCoreStack) createUsageMetrics() {
+	if s.Options.Observability == nil || s.Options.Observability.UsageMetrics == nil || s.LogGroup == nil {
+		return
+	}
+	opts := s.Options.Observability.UsageMetrics
+
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = fmt.Sprintf("AgentKit/%s/Usage", s.Config.StackName)
+	}
+	filterPattern := opts.FilterPattern
+	if filterPattern == "" {
+		filterPattern = defaultUsageFilterPattern
+	}
+	prefix := opts.ReportPrefix
+	if prefix == "" {
+		prefix = "usage-reports/"
+	}
+	agentNames := make([]string, 0, len(s.Config.Agents))
+	for _, agent := range s.Config.Agents {
+		agentNames = append(agentNames, agent.Name)
+	}
+
+	// The processor publishes through its own log group, so its metrics
+	// are extracted there
+	processorLogs := awslogs.NewLogGroup(s.Stack, jsii.String("UsageProcessorLogs"), &awslogs.LogGroupProps{
+		Retention:     awslogs.RetentionDays_ONE_MONTH,
+		EncryptionKey: s.KMSKey,
+		RemovalPolicy: s.removalPolicy(),
+	})
+	processor := awslambda.NewFunction(s.Stack, jsii.String("UsageProcessor"), &awslambda.FunctionProps{
+		Description:  jsii.String(fmt.Sprintf("Publishes the LLM usage metrics of stack %s", s.Config.StackName)),
+		Code:         awslambda.Code_FromInline(jsii.String(usageProcessorCode)),
+		Runtime:      awslambda.Runtime_PYTHON_3_13(),
+		Handler:      jsii.String("index.handler"),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(128),
+		Timeout:      awscdk.Duration_Seconds(jsii.Number(30)),
+		LogGroup:     processorLogs,
+		Environment: &map[string]*string{
+			"NAMESPACE": jsii.String(namespace),
+			"PRICES":    s.Stack.ToJsonString(opts.modelPrices(), nil),
+			"AGENTS":    s.Stack.ToJsonString(agentNames, nil),
+		},
+		EnvironmentEncryption: s.KMSKey,
+	})
+	awslogs.NewSubscriptionFilter(s.Stack, jsii.String("UsageSubscriptionFilter"), &awslogs.SubscriptionFilterProps{
+		LogGroup:      s.LogGroup,
+		Destination:   awslogsdestinations.NewLambdaDestination(processor, nil),
+		FilterPattern: awslogs.FilterPattern_Literal(jsii.String(filterPattern)),
+	})
+
+	var bucket awss3.IBucket
+	if opts.ReportBucketARN != "" {
+		bucket = awss3.Bucket_FromBucketArn(s.Stack, jsii.String("UsageReportBucket"), jsii.String(opts.ReportBucketARN))
+	} else {
+		encryption := awss3.BucketEncryption_S3_MANAGED
+		if s.KMSKey != nil {
+			encryption = awss3.BucketEncryption_KMS
+		}
+		bucket = awss3.NewBucket(s.Stack, jsii.String("UsageReportBucket"), &awss3.BucketProps{
+			Encryption:        encryption,
+			EncryptionKey:     s.KMSKey,
+			BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+			EnforceSSL:        jsii.Bool(true),
+			RemovalPolicy:     awscdk.RemovalPolicy_RETAIN,
+		})
+	}
+	s.UsageReportBucket = bucket
+
+	report := awslambda.NewFunction(s.Stack, jsii.String("UsageReport"), &awslambda.FunctionProps{
+		Description:  jsii.String(fmt.Sprintf("Writes the daily LLM cost report of stack %s", s.Config.StackName)),
+		Code:         awslambda.Code_FromInline(jsii.String(usageReportCode)),
+		Runtime:      awslambda.Runtime_PYTHON_3_13(),
+		Handler:      jsii.String("index.handler"),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(256),
+		Timeout:      awscdk.Duration_Minutes(jsii.Number(5)),
+		Environment: &map[string]*string{
+			"NAMESPACE": jsii.String(namespace),
+			"BUCKET":    bucket.BucketName(),
+			"PREFIX":    jsii.String(prefix),
+		},
+		EnvironmentEncryption: s.KMSKey,
+	})
+	report.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		// Neither action supports resource-level permissions
+		Actions:   jsii.Strings("cloudwatch:GetMetricData", "cloudwatch:ListMetrics"),
+		Resources: jsii.Strings("*"),
+	}))
+	bucket.GrantPut(report, jsii.String(prefix+"*"))
+
+	// Metrics of the last minutes of the day arrive shortly after midnight
+	awsevents.NewRule(s.Stack, jsii.String("UsageReportSchedule"), &awsevents.RuleProps{
+		Description: jsii.String(fmt.Sprintf("Daily LLM cost report of stack %s", s.Config.StackName)),
+		Schedule: awsevents.Schedule_Cron(&awsevents.CronOptions{
+			Hour:   jsii.String("0"),
+			Minute: jsii.String("30"),
+		}),
+		Targets: &[]awsevents.IRuleTarget{awseventstargets.NewLambdaFunction(report, nil)},
+	})
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("UsageReportBucketName"), &awscdk.CfnOutputProps{
+		Value:       bucket.BucketName(),
+		Description: jsii.String("Bucket of the daily LLM cost reports"),
+	})
+}
+
+// usageProcessorCode is the usage processor function. It reads the usage
+// lines of a subscription batch and prints the sums per agent and model as
+// embedded metric format documents.
+const usageProcessorCode = `import base64
+import gzip
+import json
+import os
+import time
+
+namespace = os.environ["NAMESPACE"]
+prices = json.loads(os.environ["PRICES"])
+agents = json.loads(os.environ["AGENTS"])
+
+
+def first(record, *keys):
+    for key in keys:
+        if isinstance(record.get(key), (int, float)):
+            return record[key]
+    return None
+
+
+def price(model):
+    match = max((key for key in prices if key in model), key=len, default=None)
+    return prices.get(match)
+
+
+def agent_of(record, stream):
+    if record.get("agent"):
+        return str(record["agent"])
+    match = max((name for name in agents if stream.startswith(name)), key=len, default=None)
+    return match or "unknown"
+
+
+def handler(event, context):
+    data = json.loads(gzip.decompress(base64.b64decode(event["awslogs"]["data"])))
+    totals = {}
+    for log_event in data.get("logEvents", []):
+        try:
+            record = json.loads(log_event["message"])
+        except ValueError:
+            continue
+        if not isinstance(record, dict):
+            continue
+        usage = record["usage"] if isinstance(record.get("usage"), dict) else record
+        input_tokens = first(usage, "inputTokens", "input_tokens", "prompt_tokens")
+        if input_tokens is None:
+            continue
+        output_tokens = first(usage, "outputTokens", "output_tokens", "completion_tokens") or 0
+        model = str(record.get("model") or record.get("modelId") or usage.get("model") or "unknown")
+        key = (agent_of(record, data.get("logStream", "")), model)
+        total = totals.setdefault(key, [0, 0])
+        total[0] += input_tokens
+        total[1] += output_tokens
+
+    for (agent, model), (input_tokens, output_tokens) in totals.items():
+        metrics = [{"Name": "InputTokens", "Unit": "Count"}, {"Name": "OutputTokens", "Unit": "Count"}]
+        document = {"Agent": agent, "Model": model, "InputTokens": input_tokens, "OutputTokens": output_tokens}
+        model_price = price(model)
+        if model_price:
+            metrics.append({"Name": "EstimatedCost", "Unit": "None"})
+            document["EstimatedCost"] = (input_tokens * model_price["inputPerMillion"] + output_tokens * model_price["outputPerMillion"]) / 1e6
+        else:
+            print(json.dumps({"warning": "no price for model", "model": model}))
+        document["_aws"] = {
+            "Timestamp": int(time.time() * 1000),
+            "CloudWatchMetrics": [{"Namespace": namespace, "Dimensions": [["Agent", "Model"], ["Agent"]], "Metrics": metrics}],
+        }
+        print(json.dumps(document))
+`
+
+// usageReportCode is the cost report function. It sums the usage metrics
+// by agent and model over the previous UTC day and writes them to S3 as
+// JSON and CSV.
+const usageReportCode = `import csv
+import datetime
+import io
+import json
+import os
+
+import boto3
+
+cloudwatch = boto3.client("cloudwatch")
+s3 = boto3.client("s3")
+namespace = os.environ["NAMESPACE"]
+names = ["InputTokens", "OutputTokens", "EstimatedCost"]
+
+
+def handler(event, context):
+    end = datetime.datetime.now(datetime.timezone.utc).replace(hour=0, minute=0, second=0, microsecond=0)
+    start = end - datetime.timedelta(days=1)
+
+    # Metrics by agent and model, without the by-agent rollups
+    metrics = []
+    for name in names:
+        for page in cloudwatch.get_paginator("list_metrics").paginate(Namespace=namespace, MetricName=name):
+            metrics += [m for m in page["Metrics"] if len(m["Dimensions"]) == 2]
+
+    rows = {}
+    for i in range(0, len(metrics), 500):
+        batch = metrics[i:i + 500]
+        queries = [{
+            "Id": "m%d" % j,
+            "MetricStat": {"Metric": metric, "Period": 86400, "Stat": "Sum"},
+        } for j, metric in enumerate(batch)]
+        for page in cloudwatch.get_paginator("get_metric_data").paginate(MetricDataQueries=queries, StartTime=start, EndTime=end):
+            for result in page["MetricDataResults"]:
+                metric = batch[int(result["Id"][1:])]
+                dims = {d["Name"]: d["Value"] for d in metric["Dimensions"]}
+                row = rows.setdefault((dims.get("Agent"), dims.get("Model")), dict.fromkeys(names, 0))
+                row[metric["MetricName"]] += sum(result["Values"])
+
+    items = [
+        {"agent": agent, "model": model, "inputTokens": int(row["InputTokens"]),
+         "outputTokens": int(row["OutputTokens"]), "estimatedCost": round(row["EstimatedCost"], 6)}
+        for (agent, model), row in sorted(rows.items())
+    ]
+    report = {
+        "date": start.date().isoformat(),
+        "currency": "USD",
+        "items": items,
+        "totalEstimatedCost": round(sum(item["estimatedCost"] for item in items), 6),
+    }
+
+    key = os.environ["PREFIX"] + start.strftime("%Y/%m/%d")
+    s3.put_object(Bucket=os.environ["BUCKET"], Key=key + ".json", Body=json.dumps(report, indent=2), ContentType="application/json")
+    out = io.StringIO()
+    writer = csv.DictWriter(out, fieldnames=["agent", "model", "inputTokens", "outputTokens", "estimatedCost"])
+    writer.writeheader()
+    writer.writerows(items)
+    s3.put_object(Bucket=os.environ["BUCKET"], Key=key + ".csv", Body=out.getvalue(), ContentType="text/csv")
+    return {"key": key, "items": len(items), "totalEstimatedCost": report["totalEstimatedCost"]}
+`
@@ -0,0 +1,209 @@
+package agentcore
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/jsii-runtime-go"
+)
+
+const (
+	// defaultRetainVersions is how many runtime versions an agent can be
+	// rolled back to by default.
+	defaultRetainVersions = 5
+
+	// LatestVersion is the live version parameter value of a versioned agent
+	// whose default endpoint follows the latest runtime version.
+	LatestVersion = "latest"
+)
+
+// VersionsOptions makes an agent's runtime versions available for rollback
+// (deploy --rollback). Every deploy that changes the runtime creates a new,
+// numbered version, which AgentCore keeps; the default endpoint follows the
+// latest version until a rollback pins it to an earlier one.
+type VersionsOptions struct {
+	// Retain is the rollback window: how many of the most recent runtime
+	// versions, including the latest, deploy --rollback accepts and the
+	// stack outputs the ARNs of. It does not delete older versions.
+	// Range: 1-100
+	// Default: 5
+	Retain int `json:"retain,omitempty" yaml:"retain,omitempty"`
+}
+
+// retain returns the number of versions the agent can be rolled back to.
+func (o *VersionsOptions) retain() int {
+	if o.Retain > 0 {
+		return o.Retain
+	}
+	return defaultRetainVersions
+}
+
+// RetainedVersions returns the runtime versions an agent with the given
+// latest version can be rolled back to, newest first. opts may be nil for
+// the default number of versions.
+func RetainedVersions(latest int, opts *VersionsOptions) []int {
+	retain := defaultRetainVersions
+	if opts != nil {
+		retain = opts.retain()
+	}
+	var versions []int
+	for version := latest; version > 0 && len(versions) < retain; version-- {
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// LiveVersionParameterName returns the name of the stack parameter holding
+// the runtime version served by the default endpoint of a blue/green or
+// versioned agent.
+func LiveVersionParameterName(agentName string) string {
+	return outputKeySanitizer.ReplaceAllString(agentName, "") + "LiveVersion"
+}
+
+// validateVersions validates an agent's versions options.
+func validateVersions(opts *AgentOptions) error {
+	if opts == nil || opts.Versions == nil {
+		return nil
+	}
+	if opts.Versions.Retain < 0 || opts.Versions.Retain > 100 {
+		return fmt.Errorf("versions.retain must be between 1 and 100, got %d", opts.Versions.Retain)
+	}
+	return nil
+}
+
+// liveVersion returns the runtime version the default endpoint of a
+// blue/green or versioned agent is pinned to, or nil to follow the latest
+// version. The version is a stack parameter so promotions and rollbacks
+// don't change the template, and cdk deploy keeps its previous value.
+func (s *AgentCoreStack) liveVersion(config *AgentConfig) *string {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || (opts.BlueGreen == nil && opts.Versions == nil) {
+		return nil
+	}
+	name := LiveVersionParameterName(config.Name)
+
+	// Blue/green agents serve the promoted version from their first deploy
+	defaultVersion := LatestVersion
+	if opts.BlueGreen != nil {
+		defaultVersion = "1"
+	}
+	if opts.Versions == nil {
+		parameter := awscdk.NewCfnParameter(s.Stack, jsii.String(name), &awscdk.CfnParameterProps{
+			Type:           jsii.String("String"),
+			Default:        jsii.String(defaultVersion),
+			AllowedPattern: jsii.String(`^[1-9][0-9]*$`),
+			Description:    jsii.String(fmt.Sprintf("Runtime version served by the default endpoint of agent %s", config.Name)),
+		})
+		return parameter.ValueAsString()
+	}
+
+	parameter := awscdk.NewCfnParameter(s.Stack, jsii.String(name), &awscdk.CfnParameterProps{
+		Type:           jsii.String("String"),
+		Default:        jsii.String(defaultVersion),
+		AllowedPattern: jsii.String(`^(latest|[1-9][0-9]*)$`),
+		Description:    jsii.String(fmt.Sprintf("Runtime version served by the default endpoint of agent %s, or latest", config.Name)),
+	})
+	// The condition must be in the template of the endpoint using it
+	latest := awscdk.NewCfnCondition(awscdk.Stack_Of(s.agentScope(config.Name)), jsii.String(name+"IsLatest"), &awscdk.CfnConditionProps{
+		Expression: awscdk.Fn_ConditionEquals(parameter.ValueAsString(), jsii.String(LatestVersion)),
+	})
+	return awscdk.Fn_ConditionIf(latest.LogicalId(), awscdk.Aws_NO_VALUE(), parameter.ValueAsString()).ToString()
+}
+
+// createVersionOutputs creates the runtime version outputs of a blue/green
+// or versioned agent, read by deploy --promote and deploy --rollback.
+func (s *AgentCoreStack) createVersionOutputs(config *AgentConfig) {
+	opts := s.Options.Agents[config.Name]
+	if opts == nil || (opts.BlueGreen == nil && opts.Versions == nil) {
+		return
+	}
+
+	awscdk.NewCfnOutput(s.Stack,
+		jsii.String(fmt.Sprintf("Agent-%s-RuntimeVersion", config.Name)),
+		&awscdk.CfnOutputProps{
+			Value:       s.Runtimes[config.Name].AttrAgentRuntimeVersion(),
+			Description: jsii.String(fmt.Sprintf("Latest runtime version of agent %s", config.Name)),
+		})
+	awscdk.NewCfnOutput(s.Stack,
+		jsii.String(fmt.Sprintf("Agent-%s-LiveVersion", config.Name)),
+		&awscdk.CfnOutputProps{
+			Value:       s.Endpoints[config.Name].AttrLiveVersion(),
+			Description: jsii.String(fmt.Sprintf("Runtime version served by the default endpoint of agent %s", config.Name)),
+		})
+	if opts.Versions != nil {
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("Agent-%s-RetainVersions", config.Name)),
+			&awscdk.CfnOutputProps{
+				Value:       jsii.String(strconv.Itoa(opts.Versions.retain())),
+				Description: jsii.String(fmt.Sprintf("Number of runtime versions agent %s can be rolled back to", config.Name)),
+			})
+		s.createRetainedVersionOutputs(config, opts.Versions.retain())
+	}
+}
+
+// createRetainedVersionOutputs creates an output per version in the
+// rollback window of a versioned agent, with its version-qualified runtime
+// ARN. CloudFormation can't count down from the latest version, so a
+// custom resource computes the versions on every deploy; slots beyond the
+// first version hold "none".
+func (s *AgentCoreStack) createRetainedVersionOutputs(config *AgentConfig, retain int) {
+	scope := s.agentScope(config.Name)
+	function := awslambda.NewSingletonFunction(awscdk.Stack_Of(scope), jsii.String("RetainedVersionsFunction"), &awslambda.SingletonFunctionProps{
+		Uuid:         jsii.String("5d1f3b0e-7c2a-4e8b-9f61-2b7d0c4a9e13"),
+		Description:  jsii.String("Computes the retained runtime versions of versioned agents"),
+		Code:         awslambda.Code_FromInline(jsii.String(retainedVersionsCode)),
+		Runtime:      awslambda.Runtime_PYTHON_3_13(),
+		Handler:      jsii.String("index.handler"),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(128),
+		Timeout:      awscdk.Duration_Seconds(jsii.Number(30)),
+	})
+
+	runtime := s.Runtimes[config.Name]
+	versions := awscdk.NewCustomResource(scope, jsii.String(fmt.Sprintf("RetainedVersions-%s", config.Name)), &awscdk.CustomResourceProps{
+		ServiceToken: function.FunctionArn(),
+		ResourceType: jsii.String("Custom::RetainedVersions"),
+		Properties: &map[string]interface{}{
+			"RuntimeArn":    runtime.AttrAgentRuntimeArn(),
+			"LatestVersion": runtime.AttrAgentRuntimeVersion(),
+			"Retain":        strconv.Itoa(retain),
+		},
+	})
+
+	for i := 1; i <= retain; i++ {
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("Agent-%s-RetainedVersion%d", config.Name, i)),
+			&awscdk.CfnOutputProps{
+				Value:       versions.GetAttString(jsii.String(fmt.Sprintf("Version%d", i))),
+				Description: jsii.String(fmt.Sprintf("Runtime version in slot %d of the rollback window of agent %s (1 is the latest)", i, config.Name)),
+			})
+		awscdk.NewCfnOutput(s.Stack,
+			jsii.String(fmt.Sprintf("Agent-%s-RetainedVersion%dArn", config.Name, i)),
+			&awscdk.CfnOutputProps{
+				Value:       versions.GetAttString(jsii.String(fmt.Sprintf("Version%dArn", i))),
+				Description: jsii.String(fmt.Sprintf("Version-qualified runtime ARN in slot %d of the rollback window of agent %s (1 is the latest)", i, config.Name)),
+			})
+	}
+}
+
+// retainedVersionsCode is the custom resource handler computing the
+// versions of a rollback window. Inline Python code can import cfnresponse.
+const retainedVersionsCode = `import cfnresponse
+
+
+def handler(event, context):
+    props = event["ResourceProperties"]
+    data = {}
+    try:
+        if event["RequestType"] != "Delete":
+            latest = int(props["LatestVersion"])
+            for i in range(1, int(props["Retain"]) + 1):
+                version = latest - i + 1
+                data["Version%d" % i] = str(version) if version > 0 else "none"
+                data["Version%dArn" % i] = "%s:%d" % (props["RuntimeArn"], version) if version > 0 else "none"
+        cfnresponse.send(event, context, cfnresponse.SUCCESS, data, "retained-versions")
+    except Exception as e:
+        cfnresponse.send(event, context, cfnresponse.FAILED, {}, "retained-versions", reason=str(e))
+`
@@ -0,0 +1,196 @@
+package agentcore
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Defaults for created VPC topology.
+const (
+	DefaultNatGateways    = 1
+	DefaultSubnetCidrMask = 24
+)
+
+// validate validates the VPC options against the VPC configuration.
+func (v *VPCOptions) validate(config *VPCConfig) error {
+	if err := v.validateLookup(config); err != nil {
+		return err
+	}
+	if !v.hasTopology() {
+		return nil
+	}
+	if config == nil || config.VPCID != "" || !config.CreateVPC {
+		return fmt.Errorf("vpc topology options require vpc.createVPC")
+	}
+
+	if v.SubnetCidrMask != 0 && (v.SubnetCidrMask < 16 || v.SubnetCidrMask > 28) {
+		return fmt.Errorf("vpc.subnetCidrMask must be between 16 and 28")
+	}
+
+	if v.IsolatedSubnets {
+		if (v.NatGateways != nil && *v.NatGateways > 0) || v.NatInstanceType != "" {
+			return fmt.Errorf("vpc.isolatedSubnets cannot be combined with NAT gateways or NAT instances")
+		}
+		if !config.EnableVPCEndpoints {
+			return fmt.Errorf("vpc.isolatedSubnets requires vpc.enableVPCEndpoints")
+		}
+		return nil
+	}
+
+	if v.NatGateways != nil {
+		if *v.NatGateways < 0 {
+			return fmt.Errorf("vpc.natGateways cannot be negative")
+		}
+		if *v.NatGateways == 0 {
+			return fmt.Errorf("vpc.natGateways 0 requires vpc.isolatedSubnets")
+		}
+		if config.MaxAZs > 0 && *v.NatGateways > config.MaxAZs {
+			return fmt.Errorf("vpc.natGateways (%d) cannot exceed vpc.maxAZs (%d)", *v.NatGateways, config.MaxAZs)
+		}
+	}
+
+	return nil
+}
+
+// hasTopology reports whether any option of a created VPC's topology is
+// set.
+func (v *VPCOptions) hasTopology() bool {
+	return v.NatGateways != nil || v.NatInstanceType != "" || v.SubnetCidrMask != 0 || v.IsolatedSubnets
+}
+
+// lookupByTags reports whether the existing VPC is found by its tags.
+func (v *VPCOptions) lookupByTags() bool {
+	return v != nil && len(v.VPCTags) > 0
+}
+
+// validateLookup validates the options finding an existing VPC and its
+// subnets.
+func (v *VPCOptions) validateLookup(config *VPCConfig) error {
+	if len(v.VPCTags) == 0 {
+		if len(v.SubnetTags) > 0 || v.SubnetGroupName != "" {
+			// The shared schema requires vpc.subnetIds with vpc.vpcId
+			return fmt.Errorf("vpc.subnetTags and vpc.subnetGroupName require vpc.vpcTags")
+		}
+		return nil
+	}
+	if config == nil || config.CreateVPC || config.VPCID != "" {
+		return fmt.Errorf("vpc.vpcTags cannot be combined with vpc.createVPC or vpc.vpcId")
+	}
+	if len(v.SubnetTags) > 1 {
+		return fmt.Errorf("vpc.subnetTags selects subnets by a single tag, got %d", len(v.SubnetTags))
+	}
+	if len(v.SubnetTags) > 0 && v.SubnetGroupName != "" {
+		return fmt.Errorf("vpc.subnetTags and vpc.subnetGroupName are mutually exclusive")
+	}
+	return nil
+}
+
+// vpcLookupOptions returns the options finding the existing VPC. Subnets
+// are grouped by the subnetTags key, so the tag value selects a group.
+func (s *AgentCoreStack) vpcLookupOptions() *awsec2.VpcLookupOptions {
+	lookup := &awsec2.VpcLookupOptions{}
+	if s.Config.VPC.VPCID != "" {
+		lookup.VpcId = jsii.String(s.Config.VPC.VPCID)
+		return lookup
+	}
+	opts := s.Options.VPC
+	tags := make(map[string]*string, len(opts.VPCTags))
+	for k, v := range opts.VPCTags {
+		tags[k] = jsii.String(v)
+	}
+	lookup.Tags = &tags
+	for key := range opts.SubnetTags {
+		lookup.SubnetGroupNameTag = jsii.String(key)
+	}
+	return lookup
+}
+
+// lookupEnvironment returns the account and region set by the cdk CLI for
+// stacks importing an existing VPC, whose lookup needs them. Other stacks
+// stay environment-agnostic.
+func lookupEnvironment(config StackConfig, options StackOptions) *awscdk.Environment {
+	if config.VPC == nil || (config.VPC.VPCID == "" && !options.VPC.lookupByTags()) {
+		return nil
+	}
+	account, region := os.Getenv("CDK_DEFAULT_ACCOUNT"), os.Getenv("CDK_DEFAULT_REGION")
+	if account == "" || region == "" {
+		return nil // The lookup fails with the CDK's explanation
+	}
+	return &awscdk.Environment{Account: jsii.String(account), Region: jsii.String(region)}
+}
+
+// subnetGroupName returns the subnet group the agents are placed in, or
+// "" for the VPC's private subnets.
+func (v *VPCOptions) subnetGroupName() string {
+	if v == nil {
+		return ""
+	}
+	for _, value := range v.SubnetTags {
+		return value
+	}
+	return v.SubnetGroupName
+}
+
+// newVPCProps builds the props for a VPC created by the stack.
+func (s *AgentCoreStack) newVPCProps() *awsec2.VpcProps {
+	vpcConfig := s.Config.VPC
+	opts := s.Options.VPC
+	if opts == nil {
+		opts = &VPCOptions{}
+	}
+
+	cidrMask := DefaultSubnetCidrMask
+	if opts.SubnetCidrMask != 0 {
+		cidrMask = opts.SubnetCidrMask
+	}
+
+	props := &awsec2.VpcProps{
+		VpcName:            jsii.String(fmt.Sprintf("%s-vpc", s.Config.StackName)),
+		IpAddresses:        awsec2.IpAddresses_Cidr(jsii.String(vpcConfig.VPCCidr)),
+		MaxAzs:             jsii.Number(float64(vpcConfig.MaxAZs)),
+		EnableDnsHostnames: jsii.Bool(true),
+		EnableDnsSupport:   jsii.Bool(true),
+	}
+
+	if opts.IsolatedSubnets {
+		props.NatGateways = jsii.Number(0)
+		props.SubnetConfiguration = &[]*awsec2.SubnetConfiguration{
+			{
+				Name:       jsii.String("Isolated"),
+				SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+				CidrMask:   jsii.Number(float64(cidrMask)),
+			},
+		}
+		return props
+	}
+
+	natGateways := DefaultNatGateways
+	if opts.NatGateways != nil {
+		natGateways = *opts.NatGateways
+	}
+	props.NatGateways = jsii.Number(float64(natGateways))
+
+	if opts.NatInstanceType != "" {
+		props.NatGatewayProvider = awsec2.NatProvider_InstanceV2(&awsec2.NatInstanceProps{
+			InstanceType: awsec2.NewInstanceType(jsii.String(opts.NatInstanceType)),
+		})
+	}
+
+	props.SubnetConfiguration = &[]*awsec2.SubnetConfiguration{
+		{
+			Name:       jsii.String("Public"),
+			SubnetType: awsec2.SubnetType_PUBLIC,
+			CidrMask:   jsii.Number(float64(cidrMask)),
+		},
+		{
+			Name:       jsii.String("Private"),
+			SubnetType: awsec2.SubnetType_PRIVATE_WITH_EGRESS,
+			CidrMask:   jsii.Number(float64(cidrMask)),
+		},
+	}
+	return props
+}
@@ -0,0 +1,274 @@
+package agentcore
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscertificatemanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfront"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfrontorigins"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awswafv2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// DefaultWAFRateLimit is the default number of requests a client IP may
+// send per 5 minutes before the web ACL blocks it.
+const DefaultWAFRateLimit = 2000
+
+// DefaultWAFManagedRuleGroups are the AWS managed rule groups of a created
+// web ACL by default.
+var DefaultWAFManagedRuleGroups = []string{
+	"AWSManagedRulesCommonRuleSet",
+	"AWSManagedRulesKnownBadInputsRuleSet",
+	"AWSManagedRulesAmazonIpReputationList",
+}
+
+// cloudFrontWebACLARNPattern matches the ARNs of web ACLs for CloudFront,
+// which live in us-east-1.
+var cloudFrontWebACLARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:wafv2:us-east-1:\d{12}:global/webacl/[A-Za-z0-9_-]+/[a-f0-9-]+$`)
+
+// WAFOptions protects the HTTP entry point of the stack with an AWS WAF web
+// ACL: the agents and the gateway route of the custom domain. API Gateway
+// HTTP APIs can't be associated with a web ACL, so the custom domain is
+// served through a CloudFront distribution the web ACL is attached to.
+// CloudFront only uses web ACLs and certificates in us-east-1.
+type WAFOptions struct {
+	// WebACLARN is an existing web ACL with CLOUDFRONT scope.
+	// Default: a web ACL created in the stack, which must then be deployed
+	// in us-east-1
+	WebACLARN string `json:"webAclArn,omitempty" yaml:"webAclArn,omitempty"`
+
+	// RateLimit is the number of requests a client IP may send per 5
+	// minutes before it is blocked. -1 disables rate limiting.
+	// Default: DefaultWAFRateLimit
+	RateLimit int `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+
+	// ManagedRuleGroups are the AWS managed rule groups applied to the
+	// requests, e.g. "AWSManagedRulesSQLiRuleSet".
+	// Default: DefaultWAFManagedRuleGroups
+	ManagedRuleGroups []string `json:"managedRuleGroups,omitempty" yaml:"managedRuleGroups,omitempty"`
+
+	// AllowedIPs are the CIDR ranges allowed to call the stack, e.g.
+	// "203.0.113.0/24". Requests from other addresses are blocked.
+	// Default: all addresses
+	AllowedIPs []string `json:"allowedIps,omitempty" yaml:"allowedIps,omitempty"`
+
+	// CertificateARN is an ACM certificate for the domain in us-east-1, for
+	// the distribution.
+	// Default: the certificate of the custom domain, which must then be in
+	// us-east-1
+	CertificateARN string `json:"certificateArn,omitempty" yaml:"certificateArn,omitempty"`
+}
+
+// validate validates the WAF options against the stack options.
+func (o *WAFOptions) validate(options *StackOptions) error {
+	if options.CustomDomain == nil && (options.HTTPAPI == nil || options.HTTPAPI.Domain == nil) {
+		return fmt.Errorf("waf requires customDomain or httpApi.domain: the web ACL is attached to the distribution of the domain")
+	}
+
+	if o.WebACLARN != "" {
+		if !*awscdk.Token_IsUnresolved(o.WebACLARN) && !cloudFrontWebACLARNPattern.MatchString(o.WebACLARN) {
+			return fmt.Errorf("waf.webAclArn %q must be the ARN of a web ACL with CLOUDFRONT scope in us-east-1", o.WebACLARN)
+		}
+		if o.RateLimit != 0 || len(o.ManagedRuleGroups) > 0 || len(o.AllowedIPs) > 0 {
+			return fmt.Errorf("waf.rateLimit, managedRuleGroups, and allowedIps configure a created web ACL; remove them or waf.webAclArn")
+		}
+	}
+	if o.RateLimit != -1 && o.RateLimit != 0 && (o.RateLimit < 10 || o.RateLimit > 2000000000) {
+		return fmt.Errorf("waf.rateLimit %d must be between 10 and 2000000000, or -1 to disable rate limiting", o.RateLimit)
+	}
+	seen := make(map[string]bool, len(o.ManagedRuleGroups))
+	for i, name := range o.ManagedRuleGroups {
+		if !strings.HasPrefix(name, "AWSManagedRules") {
+			return fmt.Errorf("waf.managedRuleGroups[%d] %q must be an AWS managed rule group, e.g. AWSManagedRulesCommonRuleSet", i, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("waf.managedRuleGroups[%d]: duplicate rule group %q", i, name)
+		}
+		seen[name] = true
+	}
+	for i, cidr := range o.AllowedIPs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("waf.allowedIps[%d] %q must be a CIDR range, e.g. 203.0.113.0/24", i, cidr)
+		}
+	}
+	if o.CertificateARN != "" && !*awscdk.Token_IsUnresolved(o.CertificateARN) &&
+		(!certificateARNPattern.MatchString(o.CertificateARN) || !strings.Contains(o.CertificateARN, ":acm:us-east-1:")) {
+		return fmt.Errorf("waf.certificateArn %q must be an ACM certificate ARN in us-east-1", o.CertificateARN)
+	}
+	return nil
+}
+
+// createWAFDistribution creates the CloudFront distribution serving a
+// custom domain of the HTTP API, with the web ACL attached. The
+// distribution forwards the Host header, so API Gateway serves the domain
+// and SigV4 signatures for it stay valid.
+func (s *AgentCoreStack) createWAFDistribution(name string, domain awsapigatewayv2.DomainName, certificate awscertificatemanager.ICertificate) awscloudfront.Distribution {
+	opts := s.Options.WAF
+
+	webACLARN := opts.WebACLARN
+	if webACLARN == "" {
+		webACLARN = *s.createWebACL().AttrArn()
+	}
+	if opts.CertificateARN != "" {
+		certificate = awscertificatemanager.Certificate_FromCertificateArn(s.Stack,
+			jsii.String("WAFDistributionCertificate"), jsii.String(opts.CertificateARN))
+	}
+
+	// A created web ACL or certificate is in the stack's region
+	region := s.Stack.Region()
+	needsUSEast1 := opts.WebACLARN == "" || (opts.CertificateARN == "" && !strings.Contains(*certificate.CertificateArn(), ":acm:us-east-1:"))
+	switch {
+	case !needsUSEast1:
+	case !*awscdk.Token_IsUnresolved(region):
+		if *region != "us-east-1" {
+			awscdk.Annotations_Of(s.Stack).AddError(jsii.String(fmt.Sprintf(
+				"waf: CloudFront requires the web ACL and the certificate in us-east-1, the stack is in %s; set waf.webAclArn and waf.certificateArn", *region)))
+		}
+	default:
+		// Environment-agnostic stack: fail before any resource is created
+		awscdk.NewCfnRule(s.Stack, jsii.String("WAFRegionRule"), &awscdk.CfnRuleProps{
+			Assertions: &[]*awscdk.CfnRuleAssertion{{
+				Assert:            awscdk.Fn_ConditionEquals(region, jsii.String("us-east-1")),
+				AssertDescription: jsii.String("CloudFront requires the web ACL and the certificate in us-east-1; deploy the stack in us-east-1 or set waf.webAclArn and waf.certificateArn"),
+			}},
+		})
+	}
+
+	distribution := awscloudfront.NewDistribution(s.Stack, jsii.String("WAFDistribution"), &awscloudfront.DistributionProps{
+		Comment:                jsii.String(fmt.Sprintf("%s with AWS WAF", name)),
+		DomainNames:            jsii.Strings(name),
+		Certificate:            certificate,
+		MinimumProtocolVersion: awscloudfront.SecurityPolicyProtocol_TLS_V1_2_2021,
+		WebAclId:               jsii.String(webACLARN),
+		PriceClass:             awscloudfront.PriceClass_PRICE_CLASS_100,
+		DefaultBehavior: &awscloudfront.BehaviorOptions{
+			Origin: awscloudfrontorigins.NewHttpOrigin(domain.RegionalDomainName(), &awscloudfrontorigins.HttpOriginProps{
+				ProtocolPolicy: awscloudfront.OriginProtocolPolicy_HTTPS_ONLY,
+			}),
+			AllowedMethods:       awscloudfront.AllowedMethods_ALLOW_ALL(),
+			ViewerProtocolPolicy: awscloudfront.ViewerProtocolPolicy_HTTPS_ONLY,
+			CachePolicy:          awscloudfront.CachePolicy_CACHING_DISABLED(),
+			OriginRequestPolicy:  awscloudfront.OriginRequestPolicy_ALL_VIEWER(),
+		},
+	})
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("WebAclArn"), &awscdk.CfnOutputProps{
+		Value:       jsii.String(webACLARN),
+		Description: jsii.String("AWS WAF web ACL protecting the custom domain"),
+	})
+	awscdk.NewCfnOutput(s.Stack, jsii.String("DistributionId"), &awscdk.CfnOutputProps{
+		Value:       distribution.DistributionId(),
+		Description: jsii.String("CloudFront distribution serving the custom domain"),
+	})
+	awscdk.NewCfnOutput(s.Stack, jsii.String("DistributionDomainName"), &awscdk.CfnOutputProps{
+		Value:       distribution.DistributionDomainName(),
+		Description: jsii.String("Domain name to point the custom domain at, without a hosted zone"),
+	})
+	return distribution
+}
+
+// createWebACL creates the web ACL of the distribution: rate limiting and
+// managed rule groups first, then the IP allowlist.
+func (s *AgentCoreStack) createWebACL() awswafv2.CfnWebACL {
+	opts := s.Options.WAF
+	metricPrefix := outputKeySanitizer.ReplaceAllString(s.Config.StackName, "")
+	visibility := func(name string) *awswafv2.CfnWebACL_VisibilityConfigProperty {
+		return &awswafv2.CfnWebACL_VisibilityConfigProperty{
+			CloudWatchMetricsEnabled: jsii.Bool(true),
+			MetricName:               jsii.String(metricPrefix + name),
+			SampledRequestsEnabled:   jsii.Bool(true),
+		}
+	}
+
+	var rules []interface{}
+	addRule := func(rule *awswafv2.CfnWebACL_RuleProperty) {
+		rule.Priority = jsii.Number(float64(len(rules)))
+		rule.VisibilityConfig = visibility(*rule.Name)
+		rules = append(rules, rule)
+	}
+
+	rateLimit := opts.RateLimit
+	if rateLimit == 0 {
+		rateLimit = DefaultWAFRateLimit
+	}
+	if rateLimit > 0 {
+		addRule(&awswafv2.CfnWebACL_RuleProperty{
+			Name:   jsii.String("RateLimit"),
+			Action: &awswafv2.CfnWebACL_RuleActionProperty{Block: map[string]interface{}{}},
+			Statement: &awswafv2.CfnWebACL_StatementProperty{
+				RateBasedStatement: &awswafv2.CfnWebACL_RateBasedStatementProperty{
+					Limit:            jsii.Number(float64(rateLimit)),
+					AggregateKeyType: jsii.String("IP"),
+				},
+			},
+		})
+	}
+
+	groups := opts.ManagedRuleGroups
+	if len(groups) == 0 {
+		groups = DefaultWAFManagedRuleGroups
+	}
+	for _, group := range groups {
+		addRule(&awswafv2.CfnWebACL_RuleProperty{
+			Name:           jsii.String(group),
+			OverrideAction: &awswafv2.CfnWebACL_OverrideActionProperty{None: map[string]interface{}{}},
+			Statement: &awswafv2.CfnWebACL_StatementProperty{
+				ManagedRuleGroupStatement: &awswafv2.CfnWebACL_ManagedRuleGroupStatementProperty{
+					VendorName: jsii.String("AWS"),
+					Name:       jsii.String(group),
+				},
+			},
+		})
+	}
+
+	defaultAction := &awswafv2.CfnWebACL_DefaultActionProperty{Allow: map[string]interface{}{}}
+	if len(opts.AllowedIPs) > 0 {
+		// IP sets hold addresses of one IP version
+		var statements []interface{}
+		for _, version := range []string{"IPV4", "IPV6"} {
+			var addresses []*string
+			for _, cidr := range opts.AllowedIPs {
+				if strings.Contains(cidr, ":") == (version == "IPV6") {
+					addresses = append(addresses, jsii.String(cidr))
+				}
+			}
+			if len(addresses) == 0 {
+				continue
+			}
+			ipSet := awswafv2.NewCfnIPSet(s.Stack, jsii.String("WebACLAllowedIPs"+version), &awswafv2.CfnIPSetProps{
+				Scope:            jsii.String("CLOUDFRONT"),
+				IpAddressVersion: jsii.String(version),
+				Addresses:        &addresses,
+				Description:      jsii.String(fmt.Sprintf("Addresses allowed to call stack %s", s.Config.StackName)),
+			})
+			statements = append(statements, &awswafv2.CfnWebACL_StatementProperty{
+				IpSetReferenceStatement: &awswafv2.CfnWebACL_IPSetReferenceStatementProperty{Arn: ipSet.AttrArn()},
+			})
+		}
+		statement := statements[0].(*awswafv2.CfnWebACL_StatementProperty)
+		if len(statements) > 1 {
+			statement = &awswafv2.CfnWebACL_StatementProperty{
+				OrStatement: &awswafv2.CfnWebACL_OrStatementProperty{Statements: &statements},
+			}
+		}
+		addRule(&awswafv2.CfnWebACL_RuleProperty{
+			Name:      jsii.String("AllowedIPs"),
+			Action:    &awswafv2.CfnWebACL_RuleActionProperty{Allow: map[string]interface{}{}},
+			Statement: statement,
+		})
+		defaultAction = &awswafv2.CfnWebACL_DefaultActionProperty{Block: map[string]interface{}{}}
+	}
+
+	return awswafv2.NewCfnWebACL(s.Stack, jsii.String("WebACL"), &awswafv2.CfnWebACLProps{
+		Scope:            jsii.String("CLOUDFRONT"),
+		Description:      jsii.String(fmt.Sprintf("Protects the HTTP entry point of stack %s", s.Config.StackName)),
+		DefaultAction:    defaultAction,
+		VisibilityConfig: visibility("WebACL"),
+		Rules:            &rules,
+	})
+}
@@ -0,0 +1,180 @@
+package agentcore
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// defaultCollectorEndpoint is the OTLP/HTTP endpoint of an ADOT collector
+// running next to the agent.
+const defaultCollectorEndpoint = "http://localhost:4318"
+
+// xrayHostPrefix starts the host of the X-Ray OTLP endpoints, which require
+// SigV4-signed requests a plain OTLP exporter doesn't send.
+const xrayHostPrefix = "xray."
+
+// XRayOptions configures AWS X-Ray tracing of agents that emit
+// OpenTelemetry spans. Tracing is enabled by observability.enableXRay or by
+// setting these options.
+type XRayOptions struct {
+	// Endpoint is the OTLP endpoint of the collector agents export spans
+	// to, such as an ADOT collector in the agent image. The X-Ray OTLP
+	// endpoints can't be used directly: they require SigV4-signed
+	// requests, which OpenTelemetry exporters don't send, so the collector
+	// signs them.
+	// Default: the collector endpoint, "http://localhost:4318"
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	// Collector provisions an ADOT collector configuration that receives
+	// OTLP spans and exports them to X-Ray.
+	Collector *ADOTCollectorOptions `json:"collector,omitempty" yaml:"collector,omitempty"`
+}
+
+// ADOTCollectorOptions configures the ADOT collector configuration. The
+// configuration is stored in an SSM parameter, whose name is passed to
+// agents as ADOT_CONFIG_PARAMETER, for a collector bundled with the agent
+// image (e.g. started with --config=ssm:{parameter}).
+type ADOTCollectorOptions struct {
+	// Endpoint is the collector's OTLP/HTTP endpoint as seen by the agent.
+	// Default: "http://localhost:4318"
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+}
+
+// validate validates the X-Ray options.
+func (o *XRayOptions) validate() error {
+	if err := validateOTLPEndpoint("observability.xray.endpoint", o.Endpoint); err != nil {
+		return err
+	}
+	if u, err := url.Parse(o.Endpoint); err == nil && strings.HasPrefix(u.Hostname(), xrayHostPrefix) && strings.Contains(u.Hostname(), ".amazonaws.com") {
+		return fmt.Errorf("observability.xray.endpoint %q is an X-Ray endpoint, which requires SigV4-signed requests; export to an ADOT collector instead", o.Endpoint)
+	}
+	if o.Collector != nil {
+		if err := validateOTLPEndpoint("observability.xray.collector.endpoint", o.Collector.Endpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOTLPEndpoint checks that an endpoint is empty or an http(s) URL.
+func validateOTLPEndpoint(field, endpoint string) error {
+	if endpoint == "" || *awscdk.Token_IsUnresolved(endpoint) {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s %q must be an http or https URL", field, endpoint)
+	}
+	return nil
+}
+
+// xrayOptions returns the X-Ray options, or nil if tracing is disabled.
+func (s *AgentCoreStack) xrayOptions() *XRayOptions {
+	if s.Options.Observability != nil && s.Options.Observability.XRay != nil {
+		return s.Options.Observability.XRay
+	}
+	if s.Config.Observability != nil && s.Config.Observability.EnableXRay {
+		return &XRayOptions{}
+	}
+	return nil
+}
+
+// grantXRay allows the role to send traces to X-Ray.
+func (s *AgentCoreStack) grantXRay(role awsiam.IRole) {
+	if s.xrayOptions() == nil {
+		return
+	}
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: jsii.Strings(
+			"xray:PutTraceSegments",
+			"xray:PutTelemetryRecords",
+			"xray:GetSamplingRules",
+			"xray:GetSamplingTargets",
+			"xray:GetSamplingStatisticSummaries",
+		),
+		Resources: jsii.Strings("*"),
+	}))
+}
+
+// createCollectorConfig stores the ADOT collector configuration in SSM and
+// grants the execution role read access.
+func (s *AgentCoreStack) createCollectorConfig() {
+	opts := s.xrayOptions()
+	if opts == nil || opts.Collector == nil {
+		return
+	}
+
+	config := fmt.Sprintf(`receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+      http:
+        endpoint: 0.0.0.0:4318
+processors:
+  batch: {}
+exporters:
+  awsxray:
+    region: %s
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      processors: [batch]
+      exporters: [awsxray]
+`, *s.Stack.Region())
+
+	s.CollectorConfig = awsssm.NewStringParameter(s.Stack, jsii.String("ADOTCollectorConfig"), &awsssm.StringParameterProps{
+		ParameterName: jsii.String(fmt.Sprintf("/%s/adot/collector-config", s.Config.StackName)),
+		Description:   jsii.String(fmt.Sprintf("ADOT collector configuration for %s", s.Config.StackName)),
+		StringValue:   jsii.String(config),
+	})
+	s.CollectorConfig.GrantRead(s.ExecutionRole)
+
+	awscdk.NewCfnOutput(s.Stack, jsii.String("ADOTCollectorConfigParameter"), &awscdk.CfnOutputProps{
+		Value:       s.CollectorConfig.ParameterName(),
+		Description: jsii.String("SSM parameter with the ADOT collector configuration"),
+	})
+}
+
+// addTracingEnvironment adds the OpenTelemetry exporter settings for X-Ray.
+// Variables set in the agent's environment take precedence.
+func (s *AgentCoreStack) addTracingEnvironment(config *AgentConfig, envVars map[string]string) {
+	opts := s.xrayOptions()
+	if opts == nil {
+		return
+	}
+
+	// Spans go through a collector, which signs the requests to X-Ray
+	endpoint := opts.Endpoint
+	if endpoint == "" && opts.Collector != nil {
+		endpoint = opts.Collector.Endpoint
+	}
+	if endpoint == "" {
+		endpoint = defaultCollectorEndpoint
+	}
+
+	tracing := map[string]string{
+		"OTEL_TRACES_EXPORTER":        "otlp",
+		"OTEL_EXPORTER_OTLP_ENDPOINT": endpoint,
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf",
+		"OTEL_PROPAGATORS":            "tracecontext,baggage,xray",
+		"OTEL_SERVICE_NAME":           config.Name,
+		"OTEL_RESOURCE_ATTRIBUTES":    s.otelResourceAttributes(config),
+	}
+	if s.CollectorConfig != nil {
+		tracing["ADOT_CONFIG_PARAMETER"] = *s.CollectorConfig.ParameterName()
+	}
+	for k, v := range tracing {
+		if _, ok := envVars[k]; !ok {
+			envVars[k] = v
+		}
+	}
+}
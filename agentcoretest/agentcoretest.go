@@ -0,0 +1,295 @@
+// Package agentcoretest synthesizes AgentCore stacks in Go tests and checks
+// their templates without the CDK assertions API:
+//
+//	func TestResearchAgent(t *testing.T) {
+//		config, _ := agentcore.LoadStackConfigFromFile("config.yaml")
+//		agentcoretest.Synth(t, *config).
+//			HasRuntime("research").
+//			WithEnv("LOG_LEVEL", "info")
+//	}
+//
+// Failed checks are reported with t.Errorf, so a test reports all of them.
+// A stack that fails to synthesize or a missing runtime stops the test with
+// t.Fatalf.
+//
+// The checks see the resources of the top-level template only; the agents
+// of a partitioned stack (StackOptions.Partition) sit in nested stacks.
+package agentcoretest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/cxapi"
+	"github.com/aws/jsii-runtime-go"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// runtimeType is the CloudFormation type of agent runtimes.
+const runtimeType = "AWS::BedrockAgentCore::Runtime"
+
+// Template is the synthesized template of a stack under test.
+type Template struct {
+	t       testing.TB
+	config  agentcore.StackConfig
+	options agentcore.StackOptions
+
+	// Stack is the synthesized stack.
+	Stack *agentcore.AgentCoreStack
+
+	// Assertions is the template for the CDK assertions API, for checks
+	// this package doesn't cover.
+	Assertions assertions.Template
+
+	// JSON is the template as decoded JSON.
+	JSON map[string]any
+
+	warnings []string
+	iam      *agentcore.IAMReport
+}
+
+// Synth synthesizes the stack of config, stopping the test if it fails.
+func Synth(t testing.TB, config agentcore.StackConfig) *Template {
+	t.Helper()
+	return SynthWithOptions(t, config, agentcore.StackOptions{})
+}
+
+// SynthWithOptions synthesizes the stack of config with CDK-specific
+// options, stopping the test if it fails.
+func SynthWithOptions(t testing.TB, config agentcore.StackConfig, options agentcore.StackOptions) *Template {
+	t.Helper()
+	tpl, err := synth(t.TempDir(), config, options)
+	if err != nil {
+		t.Fatalf("synthesizing stack %s: %v", config.StackName, err)
+	}
+	tpl.t = t
+	return tpl
+}
+
+// synth synthesizes the stack into outdir.
+func synth(outdir string, config agentcore.StackConfig, options agentcore.StackOptions) (tpl *Template, err error) {
+	// Stack construction panics on invalid configuration
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	if err := options.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid stack options: %w", err)
+	}
+
+	app := awscdk.NewApp(&awscdk.AppProps{Outdir: jsii.String(outdir)})
+	stack := agentcore.NewAgentCoreStackWithOptions(app, config.StackName, config, options)
+	artifact := app.Synth(nil).GetStackArtifact(stack.Stack.ArtifactId())
+	template, ok := artifact.Template().(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("synthesized template is not an object")
+	}
+
+	tpl = &Template{
+		config:     config,
+		options:    options,
+		Stack:      stack,
+		Assertions: assertions.Template_FromJSON(&template, nil),
+		JSON:       template,
+	}
+	for _, m := range *artifact.Messages() {
+		if m.Level == cxapi.SynthesisMessageLevel_WARNING {
+			tpl.warnings = append(tpl.warnings, fmt.Sprint(m.Entry.Data))
+		}
+	}
+	return tpl, nil
+}
+
+// resources returns the template resources of a type by logical ID.
+func (tpl *Template) resources(resourceType string) map[string]map[string]any {
+	found := make(map[string]map[string]any)
+	resources, _ := tpl.JSON["Resources"].(map[string]any)
+	for id, r := range resources {
+		resource, _ := r.(map[string]any)
+		if resource["Type"] == resourceType {
+			found[id] = resource
+		}
+	}
+	return found
+}
+
+// HasRuntime returns the runtime of the named agent, stopping the test if
+// the stack has none.
+func (tpl *Template) HasRuntime(name string) *Runtime {
+	tpl.t.Helper()
+	var names []string
+	for id, resource := range tpl.resources(runtimeType) {
+		props, _ := resource["Properties"].(map[string]any)
+		if props["AgentRuntimeName"] == name {
+			return &Runtime{tpl: tpl, Name: name, LogicalID: id, Properties: props}
+		}
+		names = append(names, fmt.Sprint(props["AgentRuntimeName"]))
+	}
+	sort.Strings(names)
+	tpl.t.Fatalf("no runtime for agent %q; runtimes: %s", name, strings.Join(names, ", "))
+	return nil
+}
+
+// HasResource checks that the stack has a resource of a type whose
+// properties include properties. Nested objects match if they include the
+// given keys; arrays must match exactly.
+func (tpl *Template) HasResource(resourceType string, properties map[string]any) *Template {
+	tpl.t.Helper()
+	if err := catch(func() { tpl.Assertions.HasResourceProperties(jsii.String(resourceType), properties) }); err != nil {
+		tpl.t.Errorf("%v", err)
+	}
+	return tpl
+}
+
+// ResourceCount checks that the stack has count resources of a type.
+func (tpl *Template) ResourceCount(resourceType string, count int) *Template {
+	tpl.t.Helper()
+	if n := len(tpl.resources(resourceType)); n != count {
+		tpl.t.Errorf("%d %s resources, want %d", n, resourceType, count)
+	}
+	return tpl
+}
+
+// HasOutput checks that the stack has the named output.
+func (tpl *Template) HasOutput(name string) *Template {
+	tpl.t.Helper()
+	outputs, _ := tpl.JSON["Outputs"].(map[string]any)
+	if _, ok := outputs[name]; !ok {
+		tpl.t.Errorf("no output %s", name)
+	}
+	return tpl
+}
+
+// HasWarning checks that synthesis warned with a message containing text.
+func (tpl *Template) HasWarning(text string) *Template {
+	tpl.t.Helper()
+	for _, warning := range tpl.warnings {
+		if strings.Contains(warning, text) {
+			return tpl
+		}
+	}
+	tpl.t.Errorf("no warning containing %q; warnings: %q", text, tpl.warnings)
+	return tpl
+}
+
+// NoWarnings checks that synthesis didn't warn.
+func (tpl *Template) NoWarnings() *Template {
+	tpl.t.Helper()
+	for _, warning := range tpl.warnings {
+		tpl.t.Errorf("warning: %s", warning)
+	}
+	return tpl
+}
+
+// catch runs an assertion of the CDK assertions API, which panics on
+// failure, and returns the failure.
+func catch(assertion func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	assertion()
+	return nil
+}
+
+// Runtime is the runtime of an agent in a Template.
+type Runtime struct {
+	tpl *Template
+
+	// Name is the agent name.
+	Name string
+
+	// LogicalID is the logical ID of the runtime resource.
+	LogicalID string
+
+	// Properties are the properties of the runtime resource.
+	Properties map[string]any
+}
+
+// Env returns an environment variable of the runtime. Values resolved at
+// deploy time are CloudFormation intrinsic functions, not strings.
+func (r *Runtime) Env(key string) (any, bool) {
+	env, _ := r.Properties["EnvironmentVariables"].(map[string]any)
+	value, ok := env[key]
+	return value, ok
+}
+
+// WithEnv checks that the runtime sets an environment variable to value.
+func (r *Runtime) WithEnv(key, value string) *Runtime {
+	r.tpl.t.Helper()
+	got, ok := r.Env(key)
+	switch {
+	case !ok:
+		r.tpl.t.Errorf("agent %s: environment variable %s is not set", r.Name, key)
+	case got != value:
+		r.tpl.t.Errorf("agent %s: environment variable %s is %s, want %q", r.Name, key, marshal(got), value)
+	}
+	return r
+}
+
+// WithoutEnv checks that the runtime doesn't set an environment variable.
+func (r *Runtime) WithoutEnv(key string) *Runtime {
+	r.tpl.t.Helper()
+	if got, ok := r.Env(key); ok {
+		r.tpl.t.Errorf("agent %s: environment variable %s is set to %s", r.Name, key, marshal(got))
+	}
+	return r
+}
+
+// WithProtocol checks the protocol of the runtime, e.g. "HTTP" or "MCP".
+func (r *Runtime) WithProtocol(protocol string) *Runtime {
+	r.tpl.t.Helper()
+	return r.WithProperty("ProtocolConfiguration", protocol)
+}
+
+// WithNetworkMode checks the network mode of the runtime, "PUBLIC" or
+// "VPC".
+func (r *Runtime) WithNetworkMode(mode string) *Runtime {
+	r.tpl.t.Helper()
+	return r.WithProperty("NetworkConfiguration.NetworkMode", mode)
+}
+
+// WithProperty checks a property of the runtime by its dot-separated path,
+// e.g. "LifecycleConfiguration.MaxLifetime". value is compared with the
+// property as JSON, so numbers of any type match.
+func (r *Runtime) WithProperty(path string, value any) *Runtime {
+	r.tpl.t.Helper()
+	var got any = r.Properties
+	for _, key := range strings.Split(path, ".") {
+		object, _ := got.(map[string]any)
+		var ok bool
+		if got, ok = object[key]; !ok {
+			r.tpl.t.Errorf("agent %s: property %s is not set", r.Name, path)
+			return r
+		}
+	}
+
+	var want any
+	if err := json.Unmarshal([]byte(marshal(value)), &want); err != nil {
+		r.tpl.t.Errorf("agent %s: property %s: %v", r.Name, path, err)
+		return r
+	}
+	if !reflect.DeepEqual(got, want) {
+		r.tpl.t.Errorf("agent %s: property %s is %s, want %s", r.Name, path, marshal(got), marshal(want))
+	}
+	return r
+}
+
+// marshal returns v as compact JSON, for failure messages.
+func marshal(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,160 @@
+package agentcoretest
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// fakeT is a testing.TB that records failures instead of reporting them,
+// for checking the failures the assertions report.
+type fakeT struct {
+	testing.TB
+	tempDir string
+	errors  []string
+	fatal   string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) TempDir() string { return f.tempDir }
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+// Fatalf stops the goroutine like testing.T's Fatalf does.
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.fatal = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// run runs assertions against a fakeT on their own goroutine, so Fatalf
+// can stop them, and returns the fakeT.
+func run(t *testing.T, assertions func(t testing.TB)) *fakeT {
+	t.Helper()
+	f := &fakeT{TB: t, tempDir: t.TempDir()}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assertions(f)
+	}()
+	wg.Wait()
+	return f
+}
+
+// synthOnce shares the synthesized test stack between tests, as synthesis
+// takes seconds.
+var synthOnce = sync.OnceValues(func() (*Template, error) {
+	dir, err := os.MkdirTemp("", "agentcoretest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	b := testStack()
+	return synth(dir, b.Config(), b.Options())
+})
+
+// testStack is a minimal stack of one agent, whose long idle timeout warns.
+func testStack() *agentcore.StackBuilder {
+	return agentcore.NewStackBuilder("agentcoretest").
+		WithAgentBuilder(agentcore.NewAgentBuilder("research", "ghcr.io/example/research:latest").
+			WithEnvVar("LOG_LEVEL", "info").
+			WithScaling(7200, 14400))
+}
+
+// testTemplate returns a copy of the synthesized test stack.
+func testTemplate(t *testing.T) *Template {
+	t.Helper()
+	tpl, err := synthOnce()
+	if err != nil {
+		t.Fatalf("synthesizing test stack: %v", err)
+	}
+	shared := *tpl
+	return &shared
+}
+
+func TestSynthPasses(t *testing.T) {
+	tpl := testTemplate(t)
+	f := run(t, func(tb testing.TB) {
+		tpl.t = tb
+		tpl.
+			ResourceCount(runtimeType, 1).
+			HasWarning("idle sessions are billed").
+			HasRuntime("research").
+			WithEnv("LOG_LEVEL", "info").
+			WithoutEnv("DEBUG").
+			WithProperty("LifecycleConfiguration.IdleRuntimeSessionTimeout", 7200)
+	})
+	if f.fatal != "" || len(f.errors) > 0 {
+		t.Errorf("passing assertions failed: fatal %q, errors %q", f.fatal, f.errors)
+	}
+}
+
+func TestSynthReportsFailures(t *testing.T) {
+	tpl := testTemplate(t)
+	f := run(t, func(tb testing.TB) {
+		tpl.t = tb
+		tpl.
+			ResourceCount(runtimeType, 2).
+			HasOutput("NoSuchOutput").
+			HasWarning("no such warning").
+			NoWarnings().
+			HasRuntime("research").
+			WithEnv("LOG_LEVEL", "debug").
+			WithEnv("MISSING", "x").
+			WithProperty("NoSuchProperty", "x")
+	})
+	if f.fatal != "" {
+		t.Fatalf("unexpected fatal failure: %s", f.fatal)
+	}
+
+	want := []string{
+		"1 " + runtimeType + " resources, want 2",
+		"no output NoSuchOutput",
+		`no warning containing "no such warning"`,
+		"warning: agent research: idle sessions are billed",
+		`environment variable LOG_LEVEL is "info", want "debug"`,
+		"environment variable MISSING is not set",
+		"property NoSuchProperty is not set",
+	}
+	if len(f.errors) != len(want) {
+		t.Fatalf("got %d errors, want %d: %q", len(f.errors), len(want), f.errors)
+	}
+	for i, w := range want {
+		if !strings.Contains(f.errors[i], w) {
+			t.Errorf("error %d is %q, want it to contain %q", i, f.errors[i], w)
+		}
+	}
+}
+
+func TestHasRuntimeStopsTest(t *testing.T) {
+	reached := false
+	tpl := testTemplate(t)
+	f := run(t, func(tb testing.TB) {
+		tpl.t = tb
+		tpl.HasRuntime("missing")
+		reached = true
+	})
+	if reached {
+		t.Error("HasRuntime of a missing agent didn't stop the test")
+	}
+	if !strings.Contains(f.fatal, `no runtime for agent "missing"; runtimes: research`) {
+		t.Errorf("fatal failure is %q", f.fatal)
+	}
+}
+
+func TestSynthStopsTestOnInvalidConfig(t *testing.T) {
+	f := run(t, func(tb testing.TB) {
+		Synth(tb, agentcore.StackConfig{StackName: "invalid"})
+	})
+	if !strings.Contains(f.fatal, "synthesizing stack invalid") {
+		t.Errorf("fatal failure is %q", f.fatal)
+	}
+}
@@ -0,0 +1,81 @@
+package agentcoretest
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// IAM returns the IAM report of the stack (see agentcore.BuildIAMReport),
+// stopping the test if it can't be built.
+func (tpl *Template) IAM() *agentcore.IAMReport {
+	tpl.t.Helper()
+	if tpl.iam == nil {
+		report, err := agentcore.BuildIAMReport(tpl.config, tpl.options)
+		if err != nil {
+			tpl.t.Fatalf("building IAM report: %v", err)
+		}
+		tpl.iam = report
+	}
+	return tpl.iam
+}
+
+// Allows checks that a role of the stack is allowed an action, e.g.
+// "bedrock:InvokeModel". Statement actions with wildcards match.
+func (tpl *Template) Allows(action string) *Template {
+	tpl.t.Helper()
+	if !tpl.allows(action) {
+		tpl.t.Errorf("no role is allowed %s", action)
+	}
+	return tpl
+}
+
+// Denies checks that no role of the stack is allowed an action.
+func (tpl *Template) Denies(action string) *Template {
+	tpl.t.Helper()
+	if tpl.allows(action) {
+		tpl.t.Errorf("a role is allowed %s", action)
+	}
+	return tpl
+}
+
+// allows reports whether an Allow statement of a role matches action.
+func (tpl *Template) allows(action string) bool {
+	tpl.t.Helper()
+	for _, role := range tpl.IAM().Roles {
+		for _, statement := range role.Statements {
+			if statement.Effect != "Allow" {
+				continue
+			}
+			for _, pattern := range statement.Actions {
+				// IAM actions are case-insensitive
+				if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(action)); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// HasSameIAM checks that the stack creates the same roles, statements, and
+// resource policies as other, e.g. the stack of the configuration before a
+// change. Differences are reported as a diff of the JSON IAM reports.
+func (tpl *Template) HasSameIAM(other *Template) *Template {
+	tpl.t.Helper()
+	want, got := iamJSON(other.IAM()), iamJSON(tpl.IAM())
+	if diff := diffLines(want, got); diff != "" {
+		tpl.t.Errorf("IAM changed (-before +after):\n%s", diff)
+	}
+	return tpl
+}
+
+// iamJSON returns an IAM report as indented JSON, without the stack name.
+func iamJSON(report *agentcore.IAMReport) string {
+	r := *report
+	r.StackName = ""
+	data, _ := json.MarshalIndent(r, "", "  ")
+	return string(data)
+}
@@ -0,0 +1,161 @@
+package agentcoretest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// SnapshotDir is the directory of golden templates, relative to the
+// package under test.
+const SnapshotDir = "testdata/snapshots"
+
+// UpdateSnapshotsEnv is the environment variable that rewrites golden
+// templates instead of comparing them: UPDATE_SNAPSHOTS=1 go test ./...
+// It is an environment variable rather than a flag so it can't clash with
+// the flags of the test binaries importing this package.
+const UpdateSnapshotsEnv = "UPDATE_SNAPSHOTS"
+
+var (
+	// assetHashPattern matches the content hashes in asset file names and
+	// S3 keys, which change with every CDK release bundling new handler
+	// code.
+	assetHashPattern = regexp.MustCompile(`\b[0-9a-f]{64}\b`)
+
+	// snapshotNameUnsafe matches the characters of test names that don't
+	// belong in file names.
+	snapshotNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// SnapshotTemplate synthesizes the stack of config and compares its
+// template with the golden file of the test, testdata/snapshots/{test}.json.
+// With UPDATE_SNAPSHOTS=1, the golden file is written instead. Commit the
+// golden files, so template changes show up in code review.
+func SnapshotTemplate(t testing.TB, config agentcore.StackConfig) {
+	t.Helper()
+	SnapshotTemplateWithOptions(t, config, agentcore.StackOptions{})
+}
+
+// SnapshotTemplateWithOptions is SnapshotTemplate with CDK-specific
+// options.
+func SnapshotTemplateWithOptions(t testing.TB, config agentcore.StackConfig, options agentcore.StackOptions) {
+	t.Helper()
+	SynthWithOptions(t, config, options).MatchesSnapshot(snapshotPath(t))
+}
+
+// MatchesSnapshot compares the template with a golden file, or writes the
+// golden file with UPDATE_SNAPSHOTS=1.
+func (tpl *Template) MatchesSnapshot(path string) *Template {
+	tpl.t.Helper()
+	got := tpl.Snapshot()
+
+	if updatingSnapshots() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tpl.t.Fatalf("creating snapshot directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil { //nolint:gosec // G306: golden files are committed
+			tpl.t.Fatalf("writing snapshot: %v", err)
+		}
+		return tpl
+	}
+
+	want, err := os.ReadFile(path) //nolint:gosec // G304: path is chosen by the test
+	if os.IsNotExist(err) {
+		tpl.t.Fatalf("no snapshot %s; run go test with UPDATE_SNAPSHOTS=1 to create it", path)
+	}
+	if err != nil {
+		tpl.t.Fatalf("reading snapshot: %v", err)
+	}
+	if diff := diffLines(string(want), got); diff != "" {
+		tpl.t.Errorf("template differs from %s (-snapshot +template); run go test with UPDATE_SNAPSHOTS=1 if the change is intended:\n%s", path, diff)
+	}
+	return tpl
+}
+
+// Snapshot returns the template as deterministic JSON: indented, with
+// sorted keys, and with asset hashes replaced by numbered placeholders
+// ("[asset-1]", "[asset-2]", ...), so only changes to the stack's own
+// resources show up. A hash gets the same placeholder wherever it
+// appears.
+func (tpl *Template) Snapshot() string {
+	tpl.t.Helper()
+	n := &normalizer{placeholders: make(map[string]string)}
+	data, err := json.MarshalIndent(n.normalize(tpl.JSON), "", "  ")
+	if err != nil {
+		tpl.t.Fatalf("marshaling template: %v", err)
+	}
+	return string(data) + "\n"
+}
+
+// updatingSnapshots reports whether UPDATE_SNAPSHOTS is set to true.
+func updatingSnapshots() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdateSnapshotsEnv))
+	return update
+}
+
+// normalizer replaces the asset hashes of a template with placeholders
+// numbered in the order they are first seen.
+type normalizer struct {
+	placeholders map[string]string
+}
+
+// normalize returns a copy of a template value with asset hashes replaced.
+// Map keys are visited sorted with their hashes masked, so the numbering
+// doesn't depend on the hashes themselves.
+func (n *normalizer) normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			mi, mj := assetHashPattern.ReplaceAllString(keys[i], ""), assetHashPattern.ReplaceAllString(keys[j], "")
+			if mi != mj {
+				return mi < mj
+			}
+			return keys[i] < keys[j]
+		})
+		out := make(map[string]any, len(v))
+		for _, k := range keys {
+			out[n.replace(k)] = n.normalize(v[k])
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = n.normalize(value)
+		}
+		return out
+	case string:
+		return n.replace(v)
+	}
+	return v
+}
+
+// replace replaces the asset hashes of s with their placeholders.
+func (n *normalizer) replace(s string) string {
+	return assetHashPattern.ReplaceAllStringFunc(s, func(hash string) string {
+		placeholder, ok := n.placeholders[hash]
+		if !ok {
+			placeholder = fmt.Sprintf("[asset-%d]", len(n.placeholders)+1)
+			n.placeholders[hash] = placeholder
+		}
+		return placeholder
+	})
+}
+
+// snapshotPath returns the golden file of a test. Subtests are stored as
+// {test}__{subtest}.json.
+func snapshotPath(t testing.TB) string {
+	name := strings.ReplaceAll(t.Name(), "/", "__")
+	return filepath.Join(SnapshotDir, snapshotNameUnsafe.ReplaceAllString(name, "_")+".json")
+}
@@ -0,0 +1,81 @@
+package agentcoretest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotTemplate(t *testing.T) {
+	b := testStack()
+	SnapshotTemplateWithOptions(t, b.Config(), b.Options())
+}
+
+func TestMatchesSnapshotReportsDiff(t *testing.T) {
+	t.Setenv(UpdateSnapshotsEnv, "")
+	tpl := testTemplate(t)
+	tpl.JSON = map[string]any{"Resources": map[string]any{"Extra": map[string]any{"Type": "AWS::SNS::Topic"}}}
+	f := run(t, func(tb testing.TB) {
+		tpl.t = tb
+		tpl.MatchesSnapshot("testdata/snapshots/TestSnapshotTemplate.json")
+	})
+	if f.fatal != "" || len(f.errors) != 1 {
+		t.Fatalf("got fatal %q, errors %q; want one error", f.fatal, f.errors)
+	}
+	if !strings.Contains(f.errors[0], `"Type": "AWS::SNS::Topic"`) {
+		t.Errorf("error doesn't show the added resource:\n%s", f.errors[0])
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	hashA := strings.Repeat("a", 64)
+	hashB := strings.Repeat("b", 64)
+	hashC := strings.Repeat("c", 64)
+
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{
+			name: "value",
+			in:   map[string]any{"Key": hashB + ".zip"},
+			want: map[string]any{"Key": "[asset-1].zip"},
+		},
+		{
+			name: "keys stay distinct",
+			in: map[string]any{
+				"asset." + hashB: "b",
+				"asset." + hashA: "a",
+			},
+			want: map[string]any{
+				"asset.[asset-1]": "a",
+				"asset.[asset-2]": "b",
+			},
+		},
+		{
+			name: "same hash same placeholder",
+			in: map[string]any{
+				"Code":   map[string]any{"S3Key": hashC + ".zip"},
+				"Assets": []any{hashA, hashC},
+			},
+			want: map[string]any{
+				"Assets": []any{"[asset-1]", "[asset-2]"},
+				"Code":   map[string]any{"S3Key": "[asset-2].zip"},
+			},
+		},
+		{
+			name: "other values",
+			in:   map[string]any{"Count": 2.0, "Short": "abc123", "Enabled": true},
+			want: map[string]any{"Count": 2.0, "Short": "abc123", "Enabled": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &normalizer{placeholders: make(map[string]string)}
+			if got := n.normalize(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalize = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// approveDestructiveChanges compares the cloud assembly with the deployed
+// stacks before cdk deploy runs with --require-approval never, and stops
+// the deploy if it replaces or removes agent runtimes, endpoints, gateways,
+// or secrets unless allowed with --allow-destructive or confirmed
+// interactively. The app is synthesized first if there is no cloud
+// assembly yet, and deployed from that assembly.
+func approveDestructiveChanges(ctx context.Context, cfg aws.Config, opts *deployOptions, prompt *prompter) error {
+	if opts.app == "" {
+		dir, err := synthCDK(ctx, cfg, opts.envName)
+		if err != nil {
+			return err
+		}
+		opts.app = dir
+	}
+
+	plans, err := planStacks(ctx, cfg, opts.app)
+	if err != nil {
+		if opts.allowDestructive {
+			logger.Warnf("checking for destructive changes: %v", err)
+			return nil
+		}
+		return fmt.Errorf("checking for destructive changes: %w (rerun with --allow-destructive to skip the check)", err)
+	}
+
+	count := 0
+	for _, plan := range plans {
+		for _, warning := range plan.Warnings {
+			logger.Printf("  DESTRUCTIVE: %s: %s\n", plan.StackName, warning)
+			count++
+		}
+		if len(plan.Warnings) > 0 {
+			logger.Event(eventDestructive, map[string]any{
+				"stack":    plan.StackName,
+				"warnings": plan.Warnings,
+				"allowed":  opts.allowDestructive,
+			})
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	switch {
+	case opts.allowDestructive:
+		logger.Warnf("deploying %d destructive change(s) (--allow-destructive)", count)
+		return nil
+	case prompt != nil:
+		ok, err := prompt.confirm(fmt.Sprintf("Deploy %d destructive change(s)?", count))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+		return nil
+	default:
+		return fmt.Errorf("the deploy makes %d destructive change(s); review them with --dry-run --diff-summary and rerun with --allow-destructive to deploy them", count)
+	}
+}
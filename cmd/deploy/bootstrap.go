@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/plexusone/agentkit-aws-cdk/deploy"
+)
+
+// bootstrapCDK creates the CDK toolkit stack of the account and region, or
+// upgrades it if it is older than the bootstrap template.
+func bootstrapCDK(ctx context.Context, cfg aws.Config, accountID, region string, dryRun bool) error {
+	target := fmt.Sprintf("aws://%s/%s", accountID, region)
+	logger.Printf("Bootstrap target: %s\n", target)
+
+	opts := deploy.BootstrapOptions{AWSConfig: cfg, Qualifier: bootstrapQualifier(), ToolkitStackName: *toolkitStack}
+	info, err := deploy.BootstrapStatus(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if info.StackStatus == "" {
+		logger.Printf("  Toolkit stack %s: not found\n", info.ToolkitStackName)
+	} else {
+		logger.Printf("  Toolkit stack %s: %s, version %d\n", info.ToolkitStackName, info.StackStatus, info.Version)
+	}
+
+	template, err := readBootstrapTemplate(ctx)
+	if err != nil {
+		// A bootstrapped environment can still be deployed to without a
+		// template to upgrade from
+		if info.Version >= minBootstrapVersion {
+			logger.Warnf("not checking for bootstrap upgrades: %v", err)
+			logger.Event(eventBootstrap, map[string]any{"target": target, "status": bootstrapCurrent, "version": info.Version})
+			return nil
+		}
+		return err
+	}
+	templateVersion, err := deploy.TemplateVersion(template)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		switch {
+		case info.StackStatus == "":
+			logger.Printf("[DRY RUN] Would create %s with bootstrap version %d\n", info.ToolkitStackName, templateVersion)
+		case info.Version < templateVersion:
+			logger.Printf("[DRY RUN] Would upgrade %s from bootstrap version %d to %d\n", info.ToolkitStackName, info.Version, templateVersion)
+		default:
+			logger.Println("[DRY RUN] Already bootstrapped")
+		}
+		logger.Event(eventBootstrap, map[string]any{"target": target, "status": bootstrapDryRun, "version": info.Version, "templateVersion": templateVersion})
+		return nil
+	}
+
+	opts.Template = template
+	opts.Progress = logger.Stdout()
+	opts.OnEvent = func(e deploy.StackEvent) {
+		logger.Printf("  %s %s %s %s\n", e.Timestamp.Format("15:04:05"), e.LogicalResourceID, e.Status, e.Reason)
+	}
+	result, err := deploy.Bootstrap(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	status := bootstrapCurrent
+	switch result.Action {
+	case deploy.BootstrapCreated:
+		status = bootstrapCreated
+		logger.Printf("  Created %s with bootstrap version %d\n", result.ToolkitStackName, result.Version)
+	case deploy.BootstrapUpgraded:
+		status = bootstrapUpgraded
+		logger.Printf("  Upgraded %s from bootstrap version %d to %d\n", result.ToolkitStackName, result.PreviousVersion, result.Version)
+	default:
+		logger.Printf("  Already bootstrapped with version %d\n", result.Version)
+	}
+	logger.Event(eventBootstrap, map[string]any{
+		"target":          target,
+		"status":          status,
+		"version":         result.Version,
+		"previousVersion": result.PreviousVersion,
+	})
+	return nil
+}
+
+// readBootstrapTemplate returns the --bootstrap-template file, or the
+// template of the installed cdk CLI.
+func readBootstrapTemplate(ctx context.Context) ([]byte, error) {
+	if *bootstrapTmpl != "" {
+		data, err := os.ReadFile(*bootstrapTmpl)
+		if err != nil {
+			return nil, fmt.Errorf("reading bootstrap template: %w", err)
+		}
+		return data, nil
+	}
+	out, err := exec.CommandContext(ctx, "cdk", "bootstrap", "--show-template").Output()
+	if err != nil {
+		return nil, fmt.Errorf("getting the bootstrap template from cdk bootstrap --show-template (set --bootstrap-template instead): %w", err)
+	}
+	return out, nil
+}
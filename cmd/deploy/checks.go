@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+// Preflight check statuses.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+const (
+	// eventPreflightCheck is the event of a preflight check: name, status,
+	// and message.
+	eventPreflightCheck = "preflight-check"
+
+	// defaultMinCredentialValidity is how long AWS credentials must stay
+	// valid for a deploy by default.
+	defaultMinCredentialValidity = 15 * time.Minute
+
+	// dockerCheckTimeout bounds the docker daemon check.
+	dockerCheckTimeout = 10 * time.Second
+)
+
+// checkResult is the outcome of a preflight check.
+type checkResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// preflightInput is what the preflight checks inspect.
+type preflightInput struct {
+	cfg         aws.Config
+	info        versionInfo
+	steps       map[string]bool
+	config      *agentcore.StackConfig  // nil without a config file
+	options     *agentcore.StackOptions // nil without a config file
+	minValidity time.Duration
+	dryRun      bool
+
+	// increaseCfg files quota increase requests with
+	// --request-quota-increases; nil otherwise.
+	increaseCfg *aws.Config
+}
+
+// runChecks runs the preflight checks and prints their results.
+func runChecks(ctx context.Context, in preflightInput) []checkResult {
+	checks := []func(context.Context, preflightInput) checkResult{
+		checkCDKCLI,
+		checkDocker,
+		checkCredentials,
+		checkRegion,
+		checkModelAccess,
+		checkRuntimeQuota,
+		checkRuntimeEndpointQuota,
+		checkVPCEndpointQuota,
+		checkSecretQuota,
+	}
+
+	results := make([]checkResult, 0, len(checks))
+	for _, check := range checks {
+		result := check(ctx, in)
+		results = append(results, result)
+		status := result.Status
+		if status == checkFail {
+			status = "FAIL"
+		}
+		logger.Printf("  %-5s %s: %s\n", status, result.Name, result.Message)
+		logger.Event(eventPreflightCheck, map[string]any{"name": result.Name, "status": result.Status, "message": result.Message})
+	}
+	return results
+}
+
+// failedChecks returns the names of the failed checks.
+func failedChecks(results []checkResult) []string {
+	var failed []string
+	for _, result := range results {
+		if result.Status == checkFail {
+			failed = append(failed, result.Name)
+		}
+	}
+	return failed
+}
+
+// checkCDKCLI checks the cdk CLI is installed and compatible with the
+// aws-cdk-go version of the app.
+func checkCDKCLI(_ context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "cdk CLI"}
+	needed := in.steps[stepBootstrap] || in.steps[stepSynth] || in.steps[stepDeploy]
+
+	cli, cliOK := parseVersion(in.info.cli)
+	lib, libOK := parseVersion(in.info.cdkLib)
+	switch {
+	case in.info.cli == "" && !needed:
+		result.Status, result.Message = checkSkip, "not installed, not needed by the selected steps"
+	case in.info.cli == "":
+		result.Status, result.Message = checkFail, "not found on PATH; install it with: npm install -g aws-cdk"
+	case cliOK && cli[0] < 2:
+		result.Status, result.Message = checkFail, fmt.Sprintf("%s is v1; aws-cdk-go v2 requires cdk CLI v2 (npm install -g aws-cdk)", in.info.cli)
+	case cliOK && libOK && cli[1] < decoupledCLIMinor && compareVersions(cli, lib) < 0:
+		// Before 2.1000.0 the CLI shipped with the library and must be at
+		// least as new, or it rejects the cloud assembly schema.
+		result.Status, result.Message = checkFail, fmt.Sprintf("%s is older than aws-cdk-go %s; upgrade with: npm install -g aws-cdk", in.info.cli, in.info.cdkLib)
+	default:
+		result.Status, result.Message = checkOK, in.info.cli
+	}
+	return result
+}
+
+// checkDocker checks the Docker daemon is running if the app builds
+// container image assets.
+func checkDocker(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "docker"}
+	if (!in.steps[stepSynth] && !in.steps[stepDeploy]) || !usesImageAssets() {
+		result.Status, result.Message = checkSkip, "no container image assets"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dockerCheckTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "docker", "info", "--format", "{{.ServerVersion}}").Output()
+	if err != nil {
+		result.Status, result.Message = checkFail, "the app builds container image assets, but the Docker daemon isn't running or docker isn't installed"
+		return result
+	}
+	result.Status, result.Message = checkOK, "Docker "+strings.TrimSpace(string(out))
+	return result
+}
+
+// usesImageAssets reports whether the app builds container image assets:
+// a previous cloud assembly has image assets, or the app's Go code
+// references them.
+func usesImageAssets() bool {
+	if dir := existingAssembly(); dir != "" {
+		manifests, _ := filepath.Glob(filepath.Join(dir, "*.assets.json"))
+		for _, path := range manifests {
+			data, err := os.ReadFile(path) //nolint:gosec // G304: path is in the local cloud assembly
+			if err != nil {
+				continue
+			}
+			var manifest struct {
+				DockerImages map[string]any `json:"dockerImages"`
+			}
+			if json.Unmarshal(data, &manifest) == nil && len(manifest.DockerImages) > 0 {
+				return true
+			}
+		}
+	}
+
+	sources, _ := filepath.Glob("*.go")
+	for _, path := range sources {
+		data, err := os.ReadFile(path) //nolint:gosec // G304: path is a Go file of the app
+		if err != nil {
+			continue
+		}
+		for _, marker := range []string{"awsecrassets", "FromDockerImageAsset", "ContainerImage_FromAsset", "DockerImageCode_FromImageAsset"} {
+			if strings.Contains(string(data), marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkCredentials checks the AWS credentials stay valid long enough for a
+// deploy.
+func checkCredentials(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "credentials"}
+	credentials, err := in.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		result.Status, result.Message = checkFail, fmt.Sprintf("retrieving AWS credentials: %v", err)
+		return result
+	}
+	if !credentials.CanExpire {
+		result.Status, result.Message = checkOK, fmt.Sprintf("%s, no expiry", credentials.Source)
+		return result
+	}
+
+	remaining := time.Until(credentials.Expires).Round(time.Minute)
+	if remaining < in.minValidity {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("expire in %s, less than --min-credential-validity %s; refresh them (e.g. aws sso login) before deploying", remaining, in.minValidity)
+		return result
+	}
+	result.Status, result.Message = checkOK, fmt.Sprintf("%s, valid for %s", credentials.Source, remaining)
+	return result
+}
+
+// checkRegion checks Bedrock AgentCore is available in the region by
+// calling its control API: any response, even access denied, means the
+// service exists there. The region matrix suggests the nearest region
+// where it is, and rules out inference profiles of other geographies.
+func checkRegion(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "region"}
+	if in.config != nil && in.config.IAM != nil {
+		for _, modelID := range in.config.IAM.BedrockModelIDs {
+			if err := agentcore.ValidateModelRegion(modelID, in.cfg.Region); err != nil {
+				result.Status, result.Message = checkFail, err.Error()
+				return result
+			}
+		}
+	}
+
+	_, err := controlRequest(ctx, in.cfg, http.MethodPost, "/runtimes/", map[string]any{"maxResults": 1})
+	var apiErr *awsapi.Error
+	switch {
+	case err == nil:
+		result.Status, result.Message = checkOK, fmt.Sprintf("Bedrock AgentCore is available in %s", in.cfg.Region)
+	case errors.As(err, &apiErr) && apiErr.StatusCode != http.StatusNotFound:
+		result.Status, result.Message = checkOK, fmt.Sprintf("Bedrock AgentCore is available in %s (listing runtimes: %s)", in.cfg.Region, apiErr.Status)
+	default:
+		result.Status = checkFail
+		if matrixErr := agentcore.ValidateRegion(in.cfg.Region); matrixErr != nil {
+			result.Message = matrixErr.Error()
+		} else {
+			result.Message = fmt.Sprintf("Bedrock AgentCore is not available in %s (%v); choose one of: %s", in.cfg.Region, err, strings.Join(agentcore.AgentCoreRegions(), ", "))
+		}
+	}
+	return result
+}
+
+// checkModelAccess checks the account has been granted access to the
+// Bedrock models in the config's IAM bedrockModelIds in the region.
+func checkModelAccess(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "model access"}
+	if in.config == nil || len(in.config.IAM.BedrockModelIDs) == 0 {
+		result.Status, result.Message = checkSkip, "no bedrockModelIds configured"
+		return result
+	}
+
+	models, err := agentcore.CheckModelAccess(ctx, in.cfg, in.config.IAM.BedrockModelIDs)
+	if err != nil {
+		result.Status, result.Message = checkWarn, err.Error()
+		return result
+	}
+	var missing, unknown []string
+	granted := 0
+	for _, model := range models {
+		switch {
+		case model.Status == agentcore.ModelAccessGranted:
+			granted++
+		case model.Status == agentcore.ModelAccessMissing:
+			missing = append(missing, fmt.Sprintf("%s (%s)", model.FoundationModelID, model.Reason))
+		case !strings.Contains(model.ModelID, "*"):
+			unknown = append(unknown, fmt.Sprintf("%s (%s)", model.ModelID, model.Reason))
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("no access in %s to %s; enable them at %s", in.cfg.Region, strings.Join(missing, ", "), agentcore.ModelAccessConsoleURL(in.cfg.Region))
+	case len(unknown) > 0:
+		result.Status, result.Message = checkWarn, "could not check "+strings.Join(unknown, ", ")
+	case granted == 0:
+		result.Status, result.Message = checkSkip, "only wildcard model IDs configured"
+	default:
+		result.Status, result.Message = checkOK, fmt.Sprintf("%d models accessible", granted)
+	}
+	return result
+}
+
+// listRuntimeNames returns the names of the account's agent runtimes in the
+// region.
+func listRuntimeNames(ctx context.Context, cfg aws.Config) (map[string]bool, error) {
+	names := make(map[string]bool)
+	token := ""
+	for {
+		body := map[string]any{"maxResults": 100}
+		if token != "" {
+			body["nextToken"] = token
+		}
+		data, err := controlRequest(ctx, cfg, http.MethodPost, "/runtimes/", body)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			AgentRuntimes []struct {
+				Name string `json:"agentRuntimeName"`
+			} `json:"agentRuntimes"`
+			NextToken string `json:"nextToken"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, err
+		}
+		for _, runtime := range page.AgentRuntimes {
+			names[runtime.Name] = true
+		}
+		if page.NextToken == "" {
+			return names, nil
+		}
+		token = page.NextToken
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+// Events written with --json, besides cliout's secrets-pushed, warning, and
+// error events.
+const (
+	eventStart          = "start"           // region, account, project, stack, steps, dryRun
+	eventLock           = "lock"            // stack, action (acquired, released, force-released), owner, host
+	eventStepStart      = "step-start"      // step
+	eventStepSkip       = "step-skip"       // step
+	eventBootstrap      = "bootstrap"       // target, status, version, previousVersion, templateVersion
+	eventSynth          = "synth"           // assembly
+	eventDestructive    = "destructive"     // stack, warnings, allowed
+	eventDeployStart    = "deploy-start"    // stacks, hotswap
+	eventDeployComplete = "deploy-complete" // stacks, seconds
+	eventOutputs        = "outputs"         // file, stacks (stack name to output key to value)
+	eventVerify         = "verify"          // stack, status, agents
+	eventSmokeTest      = "smoke-test"      // stack, agent, endpoint, ok, error
+	eventPromoted       = "promoted"        // stack, agent, version
+	eventRolledBack     = "rolled-back"     // stack, agent, version
+	eventImageUpdated   = "image-updated"   // agent, image, version
+	eventModelAccess    = "model-access"    // modelId, foundationModelId, status, reason
+	eventQuotaIncrease  = "quota-increase"  // service, quota, quotaCode, desiredValue, status, requestId, error
+	eventDeployment     = "deployment"      // stack, deployedAt, actor, host, region, outcome, seconds, gitCommit, ... (--history)
+	eventPlan           = "plan"            // file
+	eventComplete       = "complete"        // dryRun
+)
+
+// Bootstrap statuses of the bootstrap event.
+const (
+	bootstrapCreated  = "created"
+	bootstrapUpgraded = "upgraded"
+	bootstrapCurrent  = "already-bootstrapped"
+	bootstrapDryRun   = "dry-run"
+)
+
+// nonNilNames returns names, or an empty list for nil, so events always hold
+// JSON arrays.
+func nonNilNames(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
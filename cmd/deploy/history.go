@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// Deployment outcomes recorded in the history table.
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+)
+
+// deploymentRecord is an item of the deployment history table
+// (agentcore.DeploymentHistoryOptions).
+type deploymentRecord struct {
+	StackName   string
+	DeployedAt  time.Time
+	Actor       string            // Caller ARN
+	Host        string            // Machine the deploy ran on
+	Region      string            // Deployment region
+	Environment string            // --env-name overlay, if any
+	GitCommit   string            // HEAD of the working directory's repository, if any
+	GitDirty    bool              // The working tree had uncommitted changes
+	ConfigHash  string            // SHA-256 of the loaded config and options
+	Images      map[string]string // Agent name to container image
+	Added       int               // Resources added
+	Modified    int               // Resources modified
+	Removed     int               // Resources removed
+	Changes     bool              // Added, Modified, and Removed are known
+	Outcome     string            // outcomeSucceeded or outcomeFailed
+	Error       string            // Deploy error of a failed deployment
+	Seconds     int               // Deploy duration
+}
+
+// deploymentContext is what a deployment record knows before the deploy
+// runs.
+type deploymentContext struct {
+	actor   string
+	region  string
+	envName string
+	plans   map[string]stackPlan // Resource changes by stack, if planned
+}
+
+// newDeploymentContext collects the planned resource changes of a
+// deployment about to run. dir is the synthesized cloud assembly, or "" if
+// the deploy synthesizes the app itself, in which case no changes are
+// recorded. It returns nil if no stack records its deployments: neither
+// the config file enables deploymentHistory nor a deployed stack has a
+// history table.
+func newDeploymentContext(ctx context.Context, cfg aws.Config, stackNames []string, actor, region, envName, dir string) *deploymentContext {
+	if !historyEnabled(ctx, cfg, stackNames, envName) {
+		return nil
+	}
+	dc := &deploymentContext{actor: actor, region: region, envName: envName}
+	if dir == "" {
+		return dc
+	}
+	plans, err := planStacks(ctx, cfg, dir)
+	if err != nil {
+		logger.Warnf("planning stack changes for the deployment history: %v", err)
+		return dc
+	}
+	dc.plans = make(map[string]stackPlan, len(plans))
+	for _, plan := range plans {
+		dc.plans[plan.StackName] = plan
+	}
+	return dc
+}
+
+// historyEnabled reports whether the config file enables deploymentHistory
+// or one of the deployed stacks has a history table.
+func historyEnabled(ctx context.Context, cfg aws.Config, stackNames []string, envName string) bool {
+	if path := agentcore.FindConfigFile(); path != "" {
+		if _, options, err := loadConfigFile(path, envName); err == nil && options.DeploymentHistory != nil {
+			return true
+		}
+	}
+	client := cloudformation.NewFromConfig(cfg)
+	for _, stackName := range stackNames {
+		if deployed, err := agentcore.FromStackOutputs(ctx, client, stackName); err == nil && deployed.DeploymentHistoryTable != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// record writes a deployment record to the history table of each deployed
+// stack that has one. Stacks without a DeploymentHistoryTable output, such
+// as a stack whose first deploy failed, are skipped. Failures to record
+// are warnings, since the deployment itself is done.
+func (dc *deploymentContext) record(ctx context.Context, cfg aws.Config, stackNames []string, deployErr error, duration time.Duration) {
+	if dc == nil {
+		return
+	}
+	// Record even when the deploy was cancelled
+	ctx = context.WithoutCancel(ctx)
+	cfnClient := cloudformation.NewFromConfig(cfg)
+	ddbClient := dynamodb.NewFromConfig(cfg)
+
+	commit, dirty := gitCommit(ctx)
+	configHash, images := configFingerprint(dc.envName)
+	host, _ := os.Hostname()
+	for _, stackName := range stackNames {
+		deployed, err := agentcore.FromStackOutputs(ctx, cfnClient, stackName)
+		if err != nil || deployed.DeploymentHistoryTable == "" {
+			continue
+		}
+
+		record := deploymentRecord{
+			StackName:   stackName,
+			DeployedAt:  time.Now().UTC(),
+			Actor:       dc.actor,
+			Host:        host,
+			Region:      dc.region,
+			Environment: dc.envName,
+			GitCommit:   commit,
+			GitDirty:    dirty,
+			ConfigHash:  configHash,
+			Images:      images,
+			Outcome:     outcomeSucceeded,
+			Seconds:     int(duration.Seconds()),
+		}
+		if plan, ok := dc.plans[stackName]; ok {
+			record.Changes = true
+			for _, change := range plan.Resources {
+				switch change.Action {
+				case actionAdd:
+					record.Added++
+				case actionModify:
+					record.Modified++
+				case actionRemove:
+					record.Removed++
+				}
+			}
+		}
+		if deployErr != nil {
+			record.Outcome = outcomeFailed
+			record.Error = deployErr.Error()
+		}
+
+		retentionDays, _ := strconv.Atoi(deployed.Outputs["DeploymentHistoryRetentionDays"])
+		_, err = ddbClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(deployed.DeploymentHistoryTable),
+			Item:      record.item(retentionDays),
+		})
+		if err != nil {
+			logger.Warnf("recording deployment of %s in %s: %v", stackName, deployed.DeploymentHistoryTable, err)
+			continue
+		}
+		logger.Printf("Recorded deployment of %s in %s\n", stackName, deployed.DeploymentHistoryTable)
+	}
+}
+
+// item encodes the record as a table item, expiring it after retentionDays
+// if set.
+func (r deploymentRecord) item(retentionDays int) map[string]ddbtypes.AttributeValue {
+	str := func(value string) ddbtypes.AttributeValue { return &ddbtypes.AttributeValueMemberS{Value: value} }
+	num := func(value int64) ddbtypes.AttributeValue {
+		return &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(value, 10)}
+	}
+
+	item := map[string]ddbtypes.AttributeValue{
+		agentcore.HistoryPartitionKey: str(r.StackName),
+		agentcore.HistorySortKey:      str(r.DeployedAt.Format(time.RFC3339Nano)),
+		"Actor":                       str(r.Actor),
+		"Region":                      str(r.Region),
+		"Outcome":                     str(r.Outcome),
+		"Seconds":                     num(int64(r.Seconds)),
+		"GitDirty":                    &ddbtypes.AttributeValueMemberBOOL{Value: r.GitDirty},
+	}
+	optional := map[string]string{
+		"Host":        r.Host,
+		"Environment": r.Environment,
+		"GitCommit":   r.GitCommit,
+		"ConfigHash":  r.ConfigHash,
+		"Error":       r.Error,
+	}
+	for name, value := range optional {
+		if value != "" {
+			item[name] = str(value)
+		}
+	}
+	if len(r.Images) > 0 {
+		images := make(map[string]ddbtypes.AttributeValue, len(r.Images))
+		for agent, image := range r.Images {
+			images[agent] = str(image)
+		}
+		item["Images"] = &ddbtypes.AttributeValueMemberM{Value: images}
+	}
+	if r.Changes {
+		item["ResourcesAdded"] = num(int64(r.Added))
+		item["ResourcesModified"] = num(int64(r.Modified))
+		item["ResourcesRemoved"] = num(int64(r.Removed))
+	}
+	if retentionDays > 0 {
+		item[agentcore.HistoryTTLAttribute] = num(r.DeployedAt.AddDate(0, 0, retentionDays).Unix())
+	}
+	return item
+}
+
+// parseDeploymentRecord decodes a table item.
+func parseDeploymentRecord(item map[string]ddbtypes.AttributeValue) deploymentRecord {
+	str := func(name string) string {
+		if value, ok := item[name].(*ddbtypes.AttributeValueMemberS); ok {
+			return value.Value
+		}
+		return ""
+	}
+	num := func(name string) (int, bool) {
+		if value, ok := item[name].(*ddbtypes.AttributeValueMemberN); ok {
+			n, err := strconv.Atoi(value.Value)
+			return n, err == nil
+		}
+		return 0, false
+	}
+
+	record := deploymentRecord{
+		StackName:   str(agentcore.HistoryPartitionKey),
+		Actor:       str("Actor"),
+		Host:        str("Host"),
+		Region:      str("Region"),
+		Environment: str("Environment"),
+		GitCommit:   str("GitCommit"),
+		ConfigHash:  str("ConfigHash"),
+		Outcome:     str("Outcome"),
+		Error:       str("Error"),
+	}
+	record.DeployedAt, _ = time.Parse(time.RFC3339Nano, str(agentcore.HistorySortKey))
+	record.Seconds, _ = num("Seconds")
+	if dirty, ok := item["GitDirty"].(*ddbtypes.AttributeValueMemberBOOL); ok {
+		record.GitDirty = dirty.Value
+	}
+	if images, ok := item["Images"].(*ddbtypes.AttributeValueMemberM); ok {
+		record.Images = make(map[string]string, len(images.Value))
+		for agent, value := range images.Value {
+			if image, ok := value.(*ddbtypes.AttributeValueMemberS); ok {
+				record.Images[agent] = image.Value
+			}
+		}
+	}
+	record.Added, record.Changes = num("ResourcesAdded")
+	record.Modified, _ = num("ResourcesModified")
+	record.Removed, _ = num("ResourcesRemoved")
+	return record
+}
+
+// gitCommit returns the HEAD commit of the working directory's repository
+// and whether the working tree has uncommitted changes, or "" outside a
+// repository.
+func gitCommit(ctx context.Context) (string, bool) {
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	status, err := exec.CommandContext(ctx, "git", "status", "--porcelain").Output()
+	return strings.TrimSpace(string(out)), err == nil && len(strings.TrimSpace(string(status))) > 0
+}
+
+// configFingerprint returns the SHA-256 of the config file's stack config
+// and options, with the environment overlay merged, and the agents'
+// container images. It returns "" and no images without a config file.
+func configFingerprint(envName string) (string, map[string]string) {
+	path := agentcore.FindConfigFile()
+	if path == "" {
+		return "", nil
+	}
+	config, options, err := loadConfigFile(path, envName)
+	if err != nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(struct {
+		Config  *agentcore.StackConfig  `json:"config"`
+		Options *agentcore.StackOptions `json:"options"`
+	}{config, options})
+	if err != nil {
+		return "", nil
+	}
+	hash := sha256.Sum256(data)
+
+	images := make(map[string]string, len(config.Agents))
+	for _, agent := range config.Agents {
+		images[agent.Name] = agent.ContainerImage
+	}
+	return hex.EncodeToString(hash[:]), images
+}
+
+// printHistory lists the most recent deployments of the deployed stacks
+// from their history tables, newest first.
+func printHistory(ctx context.Context, cfg aws.Config, stackNames []string, limit int) error {
+	cfnClient := cloudformation.NewFromConfig(cfg)
+	ddbClient := dynamodb.NewFromConfig(cfg)
+
+	for _, stackName := range stackNames {
+		deployed, err := agentcore.FromStackOutputs(ctx, cfnClient, stackName)
+		if err != nil {
+			return err
+		}
+		if deployed.DeploymentHistoryTable == "" {
+			return fmt.Errorf("stack %s has no deployment history table (set deploymentHistory in the config and deploy)", stackName)
+		}
+
+		out, err := ddbClient.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(deployed.DeploymentHistoryTable),
+			KeyConditionExpression:    aws.String("#stack = :stack"),
+			ExpressionAttributeNames:  map[string]string{"#stack": agentcore.HistoryPartitionKey},
+			ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{":stack": &ddbtypes.AttributeValueMemberS{Value: stackName}},
+			ScanIndexForward:          aws.Bool(false),
+			Limit:                     aws.Int32(int32(limit)), //nolint:gosec // G115: limit is a validated flag
+		})
+		if err != nil {
+			return fmt.Errorf("reading deployment history of %s: %w", stackName, err)
+		}
+
+		logger.Printf("%s (%s):\n", stackName, deployed.DeploymentHistoryTable)
+		if len(out.Items) == 0 {
+			logger.Println("  No deployments recorded")
+		}
+		for _, item := range out.Items {
+			record := parseDeploymentRecord(item)
+			printDeploymentRecord(record)
+			logger.Event(eventDeployment, record.event())
+		}
+		logger.Println()
+	}
+	return nil
+}
+
+// printDeploymentRecord prints a deployment as one line, with its error
+// and images below.
+func printDeploymentRecord(r deploymentRecord) {
+	commit := "-"
+	if r.GitCommit != "" {
+		commit = r.GitCommit[:min(len(r.GitCommit), 12)]
+		if r.GitDirty {
+			commit += "+dirty"
+		}
+	}
+	changes := "changes unknown"
+	if r.Changes {
+		changes = fmt.Sprintf("+%d ~%d -%d", r.Added, r.Modified, r.Removed)
+	}
+	logger.Printf("  %s  %-9s  %-18s  %-16s  %4ds  %s\n",
+		r.DeployedAt.Local().Format("2006-01-02 15:04:05"), r.Outcome, commit, changes, r.Seconds, r.Actor)
+	if r.Error != "" {
+		logger.Printf("      error: %s\n", r.Error)
+	}
+	for _, agent := range sortedKeys(r.Images) {
+		logger.Printf("      %s: %s\n", agent, r.Images[agent])
+	}
+}
+
+// event returns the fields of the deployment event.
+func (r deploymentRecord) event() map[string]any {
+	fields := map[string]any{
+		"stack":      r.StackName,
+		"deployedAt": r.DeployedAt,
+		"actor":      r.Actor,
+		"host":       r.Host,
+		"region":     r.Region,
+		"outcome":    r.Outcome,
+		"seconds":    r.Seconds,
+		"gitCommit":  r.GitCommit,
+		"gitDirty":   r.GitDirty,
+		"configHash": r.ConfigHash,
+		"images":     r.Images,
+	}
+	if r.Environment != "" {
+		fields["environment"] = r.Environment
+	}
+	if r.Error != "" {
+		fields["error"] = r.Error
+	}
+	if r.Changes {
+		fields["changes"] = map[string]int{"added": r.Added, "modified": r.Modified, "removed": r.Removed}
+	}
+	return fields
+}
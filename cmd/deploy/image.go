@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// parseImages parses the --update-image flag, a comma-separated list of
+// NAME=IMAGE entries, into container images by agent name.
+func parseImages(value string) (map[string]string, error) {
+	images := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, image, ok := strings.Cut(entry, "=")
+		name, image = strings.TrimSpace(name), strings.TrimSpace(image)
+		if !ok || name == "" || image == "" {
+			return nil, fmt.Errorf("--update-image entry %q must be NAME=IMAGE", entry)
+		}
+		if _, err := agentcore.ParseImageReference(image); err != nil {
+			return nil, fmt.Errorf("--update-image %s: %w", name, err)
+		}
+		if _, ok := images[name]; ok {
+			return nil, fmt.Errorf("--update-image names agent %s more than once", name)
+		}
+		images[name] = image
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("--update-image must name at least one agent, e.g. research=ghcr.io/org/research:v2")
+	}
+	return images, nil
+}
+
+// updateAgentImages points the runtimes of deployed agents at new container
+// images with the AgentCore API, bypassing CloudFormation, and waits for
+// each runtime to become ready.
+func updateAgentImages(ctx context.Context, cfg aws.Config, stackNames []string, images map[string]string) error {
+	client := cloudformation.NewFromConfig(cfg)
+	runtimeIDs := make(map[string]string)
+	for _, stackName := range stackNames {
+		deployed, err := agentcore.FromStackOutputs(ctx, client, stackName)
+		if err != nil {
+			return err
+		}
+		for name := range images {
+			if agent := deployed.Agent(name); agent != nil && agent.RuntimeID != "" && runtimeIDs[name] == "" {
+				runtimeIDs[name] = agent.RuntimeID
+			}
+		}
+	}
+
+	names := sortedKeys(images)
+	for _, name := range names {
+		if runtimeIDs[name] == "" {
+			return fmt.Errorf("agent %s is not deployed in %s", name, strings.Join(stackNames, ", "))
+		}
+	}
+
+	for _, name := range names {
+		logger.Printf("  %s: updating image to %s...\n", name, images[name])
+		if err := updateRuntimeImage(ctx, cfg, runtimeIDs[name], images[name]); err != nil {
+			return err
+		}
+		version, err := waitForRuntimeReady(ctx, cfg, runtimeIDs[name])
+		if err != nil {
+			return err
+		}
+		logger.Printf("  %s: ready (runtime version %s)\n", name, version)
+		logger.Event(eventImageUpdated, map[string]any{"agent": name, "image": images[name], "version": version})
+	}
+	return nil
+}
+
+// recordImages writes updated container images to the config file in the
+// current or parent directory, so the next deploy synthesizes the images
+// the runtimes already serve instead of reverting them. Each image is
+// replaced in the environment overlay if it's set there, otherwise in the
+// base file; images that can't be found exactly once are reported instead.
+func recordImages(envName string, images map[string]string) error {
+	path := agentcore.FindConfigFile()
+	if path == "" {
+		logger.Warnf("no config file found; set the agents' containerImage before the next deploy, or it reverts the images")
+		return nil
+	}
+	config, _, err := loadConfigFile(path, envName)
+	if err != nil {
+		return err
+	}
+
+	files := []string{path}
+	if envName != "" {
+		files = append([]string{agentcore.OverlayPath(path, envName)}, files...)
+	}
+
+	for _, name := range sortedKeys(images) {
+		var previous string
+		for _, agent := range config.Agents {
+			if agent.Name == name {
+				previous = agent.ContainerImage
+			}
+		}
+		if previous == images[name] {
+			continue
+		}
+
+		recorded, err := replaceImage(files, previous, images[name])
+		if err != nil {
+			return err
+		}
+		if recorded == "" {
+			logger.Warnf("%s: containerImage %q not found once in %s; update it to %s before the next deploy, or it reverts the image",
+				name, previous, strings.Join(files, " or "), images[name])
+			continue
+		}
+		logger.Printf("  %s: recorded %s in %s\n", name, images[name], recorded)
+	}
+	return nil
+}
+
+// replaceImage replaces the single occurrence of an image in the first of
+// the files containing it, and returns that file, or "" if no file
+// contains the image exactly once.
+func replaceImage(files []string, previous, image string) (string, error) {
+	if previous == "" {
+		return "", nil
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(file) //nolint:gosec // G304: config path is found in the working directory
+		if err != nil {
+			return "", err
+		}
+		switch strings.Count(string(data), previous) {
+		case 0:
+			continue
+		case 1:
+			updated := strings.Replace(string(data), previous, image, 1)
+			if err := os.WriteFile(file, []byte(updated), info.Mode().Perm()); err != nil {
+				return "", fmt.Errorf("writing %s: %w", file, err)
+			}
+			return file, nil
+		default:
+			return "", nil
+		}
+	}
+	return "", nil
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
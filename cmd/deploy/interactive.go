@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+// errCancelled is returned when the user declines a confirmation of
+// --interactive.
+var errCancelled = errors.New("deployment cancelled")
+
+// prompter asks the questions of --interactive on the terminal.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// newPrompter returns a prompter reading answers from stdin, which must be
+// a terminal.
+func newPrompter() (*prompter, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil, fmt.Errorf("--interactive needs a terminal on stdin")
+	}
+	return &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}, nil
+}
+
+// ask prints a question and returns the trimmed answer, or defaultValue if
+// the answer is empty.
+func (p *prompter) ask(question, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return defaultValue, nil
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func (p *prompter) confirm(question string) (bool, error) {
+	for {
+		answer, err := p.ask(question+" (y/N)", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "", "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "Please answer y or n.")
+	}
+}
+
+// choose prints numbered options and returns the index of the one chosen,
+// by number, defaulting to defaultIndex.
+func (p *prompter) choose(question string, options []string, defaultIndex int) (int, error) {
+	fmt.Fprintln(p.out, question)
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		answer, err := p.ask("Choice", strconv.Itoa(defaultIndex+1))
+		if err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		fmt.Fprintf(p.out, "Please enter a number from 1 to %d.\n", len(options))
+	}
+}
+
+// chooseRegion asks for the region to deploy to, offering the AgentCore
+// regions and defaulting to current.
+func (p *prompter) chooseRegion(current string) (string, error) {
+	regions := agentcore.AgentCoreRegions()
+	defaultIndex := -1
+	for i, region := range regions {
+		if region == current {
+			defaultIndex = i
+		}
+	}
+	if defaultIndex < 0 {
+		regions = append([]string{current}, regions...)
+		defaultIndex = 0
+	}
+	options := append(append([]string(nil), regions...), "other")
+
+	index, err := p.choose("Region to deploy to:", options, defaultIndex)
+	if err != nil {
+		return "", err
+	}
+	if index < len(regions) {
+		return regions[index], nil
+	}
+	region, err := p.ask("Region", current)
+	if err != nil {
+		return "", err
+	}
+	return region, nil
+}
+
+// chooseEnvFile asks for the env file to push secrets from, offering
+// current (--env) and the auto-detected files that exist. It returns "" if
+// secrets are to be skipped.
+func (p *prompter) chooseEnvFile(current, projectName string) (string, error) {
+	var files []string
+	if current != "" {
+		files = append(files, current)
+	}
+	for _, path := range envsecrets.EnvFileCandidates(projectName) {
+		if _, err := os.Stat(path); err == nil && path != current {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(p.out, "No env file found; secrets will be skipped.")
+		return "", nil
+	}
+	options := append(append([]string(nil), files...), "skip secrets")
+
+	index, err := p.choose("Env file to push secrets from:", options, 0)
+	if err != nil {
+		return "", err
+	}
+	if index == len(files) {
+		return "", nil
+	}
+	return files[index], nil
+}
+
+// pushSecrets shows the diff of each secret group and writes the created or
+// changed ones the user confirms, with tags. Declined secrets are reported
+// as skipped.
+func (p *prompter) pushSecrets(ctx context.Context, backend envsecrets.Backend, groups []envsecrets.SecretGroup, prefix string, tags map[string]string) ([]envsecrets.PushResult, error) {
+	results := make([]envsecrets.PushResult, 0, len(groups))
+	for _, group := range groups {
+		single := []envsecrets.SecretGroup{group}
+		planned, err := envsecrets.PushGroups(ctx, backend, single, envsecrets.PushOptions{
+			Prefix: prefix,
+			DryRun: true,
+			Tags:   tags,
+			Out:    logger.Stdout(),
+		})
+		if err != nil {
+			return results, err
+		}
+		result := planned[0]
+		if result.Action != envsecrets.ActionCreate && result.Action != envsecrets.ActionUpdate {
+			results = append(results, result)
+			continue
+		}
+
+		ok, err := p.confirm(fmt.Sprintf("%s %s?", actionVerb(result.Action), result.Name))
+		if err != nil {
+			return results, err
+		}
+		if !ok {
+			logger.Println("  Skipped")
+			result.Action = envsecrets.ActionSkip
+			results = append(results, result)
+			continue
+		}
+		pushed, err := envsecrets.PushGroups(ctx, backend, single, envsecrets.PushOptions{Prefix: prefix, Tags: tags})
+		if err != nil {
+			return results, err
+		}
+		logger.Printf("  %sd\n", actionVerb(result.Action))
+		results = append(results, pushed...)
+	}
+	return results, nil
+}
+
+// actionVerb returns the verb of a push action for prompts.
+func actionVerb(action string) string {
+	if action == envsecrets.ActionCreate {
+		return "Create"
+	}
+	return "Update"
+}
+
+// reviewChanges runs cdk diff and asks for approval to deploy.
+func (p *prompter) reviewChanges(ctx context.Context, cfg aws.Config, opts deployOptions, target string) error {
+	if opts.app == "" {
+		goModTidy(ctx)
+	}
+	logger.Println("Running cdk diff...")
+	cmd, err := cdkCommand(ctx, cfg, opts.cdkArgs("diff")...)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = logger.Stdout()
+	cmd.Stderr = logger.Stderr()
+	_ = cmd.Run() // Ignore error, diff returns non-zero if there are differences
+
+	ok, err := p.confirm(fmt.Sprintf("Deploy these changes to %s?", target))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errCancelled
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+const (
+	// lockSSM stores the deploy lock in an SSM parameter.
+	lockSSM = "ssm"
+
+	// lockDynamoDBPrefix selects a DynamoDB table, as dynamodb:TABLE.
+	lockDynamoDBPrefix = "dynamodb:"
+
+	// lockParameterPrefix is the path of the lock parameters of the ssm
+	// lock: /agentkit/deploy-lock/{stack}.
+	lockParameterPrefix = "/agentkit/deploy-lock/"
+
+	// lockTableKey is the partition key (a string) of the lock table.
+	lockTableKey = "LockID"
+)
+
+// lockInfo describes who holds a deploy lock. It is the value of the lock
+// parameter or item.
+type lockInfo struct {
+	ID       string    `json:"id"` // Random, identifies this deploy's lock
+	Stack    string    `json:"stack"`
+	Owner    string    `json:"owner"` // Caller ARN
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// String describes the holder of the lock.
+func (l lockInfo) String() string {
+	return fmt.Sprintf("%s on %s since %s", l.Owner, l.Host, l.Acquired.Format(time.RFC3339))
+}
+
+// lockedError is returned when another deploy holds the lock.
+type lockedError struct {
+	holder lockInfo
+}
+
+func (e *lockedError) Error() string {
+	return fmt.Sprintf("stack %s is locked by %s; wait for that deploy to finish, or release a stale lock with --force-unlock", e.holder.Stack, e.holder)
+}
+
+// deployLock is a mutual exclusion lock on deploying a stack, so concurrent
+// deploys don't race CloudFormation.
+type deployLock interface {
+	// acquire takes the lock, or returns *lockedError if it is held.
+	acquire(ctx context.Context, info lockInfo) error
+
+	// release releases the lock taken with info, unless another deploy
+	// took it after a force unlock (but see ssmLock).
+	release(ctx context.Context, info lockInfo) error
+
+	// forceRelease releases the lock of the stack whoever holds it. It
+	// returns the holder, or nil if the stack was not locked.
+	forceRelease(ctx context.Context, stack string) (*lockInfo, error)
+}
+
+// newDeployLock returns the lock selected by --lock: ssm or dynamodb:TABLE.
+func newDeployLock(cfg aws.Config, spec string) (deployLock, error) {
+	switch {
+	case spec == lockSSM:
+		return &ssmLock{client: ssm.NewFromConfig(cfg)}, nil
+	case strings.HasPrefix(spec, lockDynamoDBPrefix) && len(spec) > len(lockDynamoDBPrefix):
+		return &dynamoDBLock{client: dynamodb.NewFromConfig(cfg), table: strings.TrimPrefix(spec, lockDynamoDBPrefix)}, nil
+	default:
+		return nil, fmt.Errorf("--lock must be %s or %sTABLE, got %q", lockSSM, lockDynamoDBPrefix, spec)
+	}
+}
+
+// newLockInfo describes a lock on stack taken by owner from this host.
+func newLockInfo(stack, owner string) lockInfo {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown host"
+	}
+	return lockInfo{
+		ID:       hex.EncodeToString(id),
+		Stack:    stack,
+		Owner:    owner,
+		Host:     host,
+		Acquired: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// parseLockInfo decodes a lock value. Values not written by deploy are
+// reported as held by an unknown owner.
+func parseLockInfo(stack, value string) lockInfo {
+	var info lockInfo
+	if err := json.Unmarshal([]byte(value), &info); err != nil || info.Owner == "" {
+		return lockInfo{Stack: stack, Owner: "an unknown owner", Host: "an unknown host"}
+	}
+	return info
+}
+
+// acquireLock takes the deploy lock of the stack, printing who holds it if
+// it can't.
+func acquireLock(ctx context.Context, lock deployLock, info lockInfo) error {
+	if err := lock.acquire(ctx, info); err != nil {
+		return err
+	}
+	logger.Printf("Acquired deploy lock for stack %s\n", info.Stack)
+	logger.Event(eventLock, map[string]any{"stack": info.Stack, "action": "acquired", "owner": info.Owner, "host": info.Host})
+	return nil
+}
+
+// releaseLock releases the deploy lock, warning if it can't, since the
+// deploy itself is done.
+func releaseLock(ctx context.Context, lock deployLock, info lockInfo) {
+	// Release even when the deploy was cancelled
+	ctx = context.WithoutCancel(ctx)
+	if err := lock.release(ctx, info); err != nil {
+		logger.Warnf("releasing deploy lock for stack %s: %v (release it with --force-unlock)", info.Stack, err)
+		return
+	}
+	logger.Event(eventLock, map[string]any{"stack": info.Stack, "action": "released", "owner": info.Owner, "host": info.Host})
+}
+
+// forceUnlockStack releases the deploy lock of the stack whoever holds it.
+func forceUnlockStack(ctx context.Context, lock deployLock, stack string) error {
+	holder, err := lock.forceRelease(ctx, stack)
+	if err != nil {
+		return fmt.Errorf("releasing deploy lock: %w", err)
+	}
+	if holder == nil {
+		logger.Printf("Stack %s is not locked\n", stack)
+		return nil
+	}
+	logger.Printf("Released deploy lock for stack %s held by %s\n", stack, holder)
+	logger.Event(eventLock, map[string]any{"stack": stack, "action": "force-released", "owner": holder.Owner, "host": holder.Host})
+	return nil
+}
+
+// ssmLock stores the lock in the String parameter
+// /agentkit/deploy-lock/{stack}, created only if it doesn't exist.
+//
+// SSM can't delete a parameter conditionally, so release reads the holder
+// and then deletes the parameter: a lock force-released and taken by
+// another deploy between those two calls is deleted from under it.
+// Comparing parameter versions wouldn't close the window, since a deleted
+// and recreated parameter starts again at version 1. The window is one
+// API round trip after a --force-unlock; use the dynamodb lock, whose
+// release is conditional, where that matters.
+type ssmLock struct {
+	client *ssm.Client
+}
+
+func (l *ssmLock) name(stack string) string {
+	return lockParameterPrefix + stack
+}
+
+func (l *ssmLock) acquire(ctx context.Context, info lockInfo) error {
+	value, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	_, err = l.client.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:        aws.String(l.name(info.Stack)),
+		Value:       aws.String(string(value)),
+		Type:        ssmtypes.ParameterTypeString,
+		Description: aws.String("Deploy lock held by the deploy tool"),
+		Overwrite:   aws.Bool(false),
+	})
+	var exists *ssmtypes.ParameterAlreadyExists
+	if errors.As(err, &exists) {
+		holder, err := l.holder(ctx, info.Stack)
+		if err != nil {
+			return err
+		}
+		if holder == nil {
+			// Released in the meantime
+			return l.acquire(ctx, info)
+		}
+		return &lockedError{holder: *holder}
+	}
+	if err != nil {
+		return fmt.Errorf("acquiring deploy lock: %w", err)
+	}
+	return nil
+}
+
+// holder returns the holder of the lock, or nil if it is not held.
+func (l *ssmLock) holder(ctx context.Context, stack string) (*lockInfo, error) {
+	out, err := l.client.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(l.name(stack))})
+	var notFound *ssmtypes.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading deploy lock: %w", err)
+	}
+	info := parseLockInfo(stack, aws.ToString(out.Parameter.Value))
+	return &info, nil
+}
+
+// release deletes the lock if info still holds it. See ssmLock for the
+// race this leaves.
+func (l *ssmLock) release(ctx context.Context, info lockInfo) error {
+	holder, err := l.holder(ctx, info.Stack)
+	if err != nil || holder == nil {
+		return err
+	}
+	if holder.ID != info.ID {
+		return fmt.Errorf("the lock is now held by %s", holder)
+	}
+	return l.delete(ctx, info.Stack)
+}
+
+func (l *ssmLock) forceRelease(ctx context.Context, stack string) (*lockInfo, error) {
+	holder, err := l.holder(ctx, stack)
+	if err != nil || holder == nil {
+		return nil, err
+	}
+	return holder, l.delete(ctx, stack)
+}
+
+func (l *ssmLock) delete(ctx context.Context, stack string) error {
+	_, err := l.client.DeleteParameter(ctx, &ssm.DeleteParameterInput{Name: aws.String(l.name(stack))})
+	var notFound *ssmtypes.ParameterNotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return fmt.Errorf("deleting deploy lock: %w", err)
+	}
+	return nil
+}
+
+// dynamoDBLock stores the lock as an item of a table with the string
+// partition key LockID, written only if no item exists. The table can be
+// shared with other tools using the same key, such as Terraform state
+// locking.
+type dynamoDBLock struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func (l *dynamoDBLock) key(stack string) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		lockTableKey: &ddbtypes.AttributeValueMemberS{Value: "agentkit-deploy/" + stack},
+	}
+}
+
+func (l *dynamoDBLock) acquire(ctx context.Context, info lockInfo) error {
+	value, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	item := l.key(info.Stack)
+	item["Info"] = &ddbtypes.AttributeValueMemberS{Value: string(value)}
+	item["LockOwnerID"] = &ddbtypes.AttributeValueMemberS{Value: info.ID}
+	_, err = l.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(l.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(" + lockTableKey + ")"),
+	})
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		holder, err := l.holder(ctx, info.Stack)
+		if err != nil {
+			return err
+		}
+		if holder == nil {
+			// Released in the meantime
+			return l.acquire(ctx, info)
+		}
+		return &lockedError{holder: *holder}
+	}
+	if err != nil {
+		return fmt.Errorf("acquiring deploy lock in table %s: %w", l.table, err)
+	}
+	return nil
+}
+
+// holder returns the holder of the lock, or nil if it is not held.
+func (l *dynamoDBLock) holder(ctx context.Context, stack string) (*lockInfo, error) {
+	out, err := l.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(l.table),
+		Key:            l.key(stack),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading deploy lock from table %s: %w", l.table, err)
+	}
+	if out.Item == nil {
+		return nil, nil
+	}
+	var value string
+	if attr, ok := out.Item["Info"].(*ddbtypes.AttributeValueMemberS); ok {
+		value = attr.Value
+	}
+	info := parseLockInfo(stack, value)
+	return &info, nil
+}
+
+func (l *dynamoDBLock) release(ctx context.Context, info lockInfo) error {
+	_, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(l.table),
+		Key:                       l.key(info.Stack),
+		ConditionExpression:       aws.String("attribute_not_exists(" + lockTableKey + ") OR LockOwnerID = :id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{":id": &ddbtypes.AttributeValueMemberS{Value: info.ID}},
+	})
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		holder, err := l.holder(ctx, info.Stack)
+		if err != nil || holder == nil {
+			return err
+		}
+		return fmt.Errorf("the lock is now held by %s", holder)
+	}
+	if err != nil {
+		return fmt.Errorf("deleting deploy lock from table %s: %w", l.table, err)
+	}
+	return nil
+}
+
+func (l *dynamoDBLock) forceRelease(ctx context.Context, stack string) (*lockInfo, error) {
+	out, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(l.table),
+		Key:          l.key(stack),
+		ReturnValues: ddbtypes.ReturnValueAllOld,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("deleting deploy lock from table %s: %w", l.table, err)
+	}
+	if out.Attributes == nil {
+		return nil, nil
+	}
+	var value string
+	if attr, ok := out.Attributes["Info"].(*ddbtypes.AttributeValueMemberS); ok {
+		value = attr.Value
+	}
+	info := parseLockInfo(stack, value)
+	return &info, nil
+}
@@ -1,9 +1,26 @@
 // deploy orchestrates the full AWS AgentCore deployment process.
 //
-// It handles:
-//  1. Pushing secrets from .env to AWS Secrets Manager
-//  2. Bootstrapping AWS CDK
-//  3. Deploying the CDK stack
+// It runs these steps, which can be selected with --steps and --skip-steps:
+//  0. preflight: checking versions, credentials, region, model access, and service quotas
+//  1. secrets: pushing secrets from .env to AWS Secrets Manager
+//  2. bootstrap: creating or upgrading the CDK toolkit stack
+//  3. synth: synthesizing the cloud assembly
+//  4. deploy: deploying the CDK stack
+//  5. verify: checking the deployed stacks
+//
+// With --smoke-test, each agent is then invoked with its health check
+// payload, and --rollback-on-failure restores the previous templates of
+// stacks with failing agents. --promote then switches the default endpoints
+// of blue/green agents to the runtime version that passed the smoke test.
+// --rollback instead points the default endpoints of agents at an earlier
+// runtime version, and --update-image points agents' runtimes at new
+// container images with the AgentCore API, both without deploying.
+// --check-model-access only checks the account can invoke the configured
+// Bedrock models. --watch keeps running after the deploy and redeploys
+// whenever the config file, Go sources, or Dockerfile change. Stacks with
+// deploymentHistory get a record of each deployment, which --history
+// lists. With --lock, a lock on the stack is held while deploying, so
+// concurrent deploys fail instead of racing CloudFormation.
 //
 // Usage:
 //
@@ -14,8 +31,27 @@
 //	deploy                              # Deploy from current directory
 //	deploy --env ../.env                # Specify env file location
 //	deploy --region us-west-2           # Deploy to specific region
-//	deploy --dry-run                    # Preview without deploying
-//	deploy --skip-secrets               # Skip secrets push (if already created)
+//	deploy --dry-run                    # Preview without deploying, writing plan.json
+//	deploy --skip-steps secrets         # Skip secrets push (if already created)
+//	deploy --steps synth                # Synthesize only, e.g. in a build job
+//	deploy --steps deploy,verify        # Deploy a previously synthesized assembly
+//	deploy --progress events            # Show CloudFormation events instead of cdk output
+//	deploy --concurrency 4              # Deploy independent stacks in parallel
+//	deploy --retries 0                  # Do not retry throttled deploys
+//	deploy --iam-report markdown        # Print the IAM statements of the stack and exit
+//	deploy --estimate-cost table        # Print the estimated monthly cost of the stack and exit
+//	deploy --smoke-test --rollback-on-failure # Invoke the agents after deploying; roll back if one fails
+//	deploy --promote agent=research     # Switch a blue/green agent to the new version once it passes the smoke test
+//	deploy --rollback agent=synthesis --to-version 3 # Point an agent's endpoint back at runtime version 3
+//	deploy --update-image research=ghcr.io/org/research:v42 # Swap an agent's image without a CloudFormation deploy
+//	deploy --check-model-access         # List the configured Bedrock models that need enabling
+//	deploy --request-quota-increases    # File increase requests for quotas the stack would exceed
+//	deploy --watch                      # Redeploy with hotswap on every change during development
+//	deploy --json --quiet > events.jsonl # Write machine-readable events for CI
+//	deploy --interactive                # Pick region and env file, confirm secrets, approve the diff
+//	deploy --lock ssm                   # Fail instead of racing another deploy of the stack
+//	deploy --history                    # List the stack's recent deployments
+//	deploy --lock ssm --force-unlock    # Release the lock left by an interrupted deploy
 //
 // Install:
 //
@@ -23,41 +59,89 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+	"github.com/plexusone/agentkit-aws-cdk/deploy"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+	"github.com/plexusone/agentkit-aws-cdk/internal/cliout"
 )
 
 const (
 	// DefaultConfigDir is the default directory for plexusone configuration
 	DefaultConfigDir = ".plexusone"
+
+	// sdkMaxAttempts is the maximum number of attempts for AWS API calls
+	sdkMaxAttempts = 10
 )
 
 var (
 	region        = flag.String("region", "", "AWS region (default: AWS_REGION or us-east-1)")
 	envFile       = flag.String("env", "", "Path to .env file (default: auto-detect)")
-	prefix        = flag.String("prefix", "stats-agent", "Secret name prefix")
+	prefix        = flag.String("prefix", "", "Secret name prefix, with {project} and {env} placeholders (default: {project}, or {project}/{env} with --env-name)")
 	project       = flag.String("project", "", "Project name for ~/.plexusone/projects/{project}/.env lookup")
 	dryRun        = flag.Bool("dry-run", false, "Preview changes without deploying")
-	skipSecrets   = flag.Bool("skip-secrets", false, "Skip pushing secrets")
-	skipBootstrap = flag.Bool("skip-bootstrap", false, "Skip CDK bootstrap")
+	envName       = flag.String("env-name", "", "Environment overlay to deploy (e.g. prod for config.prod.yaml); suffixes the stack name")
+	steps         = flag.String("steps", "", "Comma-separated steps to run: preflight,secrets,bootstrap,synth,deploy,verify (default: all)")
+	skipSteps     = flag.String("skip-steps", "", "Comma-separated steps to skip")
+	outputsFile   = flag.String("outputs-file", DefaultOutputsFile, "File the deploy step writes stack outputs to and the verify step reads")
+	planFile      = flag.String("plan-file", DefaultPlanFile, "File --dry-run writes the machine-readable plan to")
+	allowDestruct = flag.Bool("allow-destructive", false, "Deploy even if the changes replace or remove agent runtimes, endpoints, gateways, or secrets")
+	diffSummary   = flag.Bool("diff-summary", false, "With --dry-run, print a summary of the stack changes (replacements, IAM changes, destructive changes) instead of the raw cdk diff")
+	skipSecrets   = flag.Bool("skip-secrets", false, "Deprecated: use --skip-steps secrets")
+	qualifier     = flag.String("qualifier", "", "CDK bootstrap qualifier to bootstrap and deploy with (default: synthesizer.qualifier in the config file, the cdk.json context, or hnb659fds)")
+	toolkitStack  = flag.String("toolkit-stack-name", deploy.DefaultToolkitStackName, "Name of the CDK toolkit stack to bootstrap and deploy with")
+	secretsRole   = flag.String("secrets-role-arn", "", "Role the secrets step assumes to push secrets ({account}, {region}, and {qualifier} are replaced)")
+	lookupRole    = flag.String("lookup-role-arn", "", "Role the read-only steps assume: preflight, synth, verify, --check-model-access, and --history")
+	deployRole    = flag.String("deploy-role-arn", "", "Role the steps changing the account assume: bootstrap, deploy, smoke test, promotion, rollback, image updates, --lock, and --request-quota-increases")
+	bootstrapTmpl = flag.String("bootstrap-template", "", "CDK bootstrap template the bootstrap step creates or upgrades the toolkit stack from (default: output of cdk bootstrap --show-template)")
+	skipBootstrap = flag.Bool("skip-bootstrap", false, "Deprecated: use --skip-steps bootstrap")
+	skipPreflight = flag.Bool("skip-preflight", false, "Deprecated: use --skip-steps preflight")
+	progress      = flag.String("progress", progressRaw, "Deploy progress output: raw (cdk output) or events (CloudFormation events)")
+	stack         = flag.String("stack", "", "Stack name for --progress events (default: stackName from config file)")
+	concurrency   = flag.Int("concurrency", 1, "Deploy up to N independent stacks of a multi-stack app in parallel")
+	retries       = flag.Int("retries", 3, "Retry a deploy that failed because of AWS throttling up to N times")
+	groupsFile    = flag.String("groups", "", "Path to secret-groups.yaml (default: auto-detect, then built-in groups)")
+	skipValidate  = flag.Bool("skip-secret-validation", false, "Push secret values even if they look invalid or truncated")
+	smokeTest     = flag.Bool("smoke-test", false, "Invoke each agent with its healthCheck payload after deploying")
+	rollback      = flag.Bool("rollback-on-failure", false, "Redeploy the previous template of stacks whose agents fail the smoke test")
+	rollbackTo    = flag.String("rollback", "", "Comma-separated agent=NAME blue/green or versioned agents to point at --to-version, then exit without deploying")
+	toVersion     = flag.String("to-version", "", "Runtime version --rollback points the agents' default endpoints at, or latest")
+	promote       = flag.String("promote", "", "Comma-separated agent=NAME blue/green agents to switch to their candidate version once the smoke test passes (implies --smoke-test)")
+	updateImage   = flag.String("update-image", "", "Comma-separated NAME=IMAGE agents to point at new container images with the AgentCore API, recording them in the config file, then exit without deploying")
+	watch         = flag.Bool("watch", false, "After deploying, redeploy with cdk deploy --hotswap-fallback whenever config, *.go, or Dockerfile files change")
+	modelAccess   = flag.Bool("check-model-access", false, "Check the account has access to the config's Bedrock models in the region, then exit without deploying")
+	iamReport     = flag.String("iam-report", "", "Print the IAM statements the stack will create as markdown or json, then exit without deploying")
+	estimateCost  = flag.String("estimate-cost", "", "Print the estimated monthly cost of the stack as table or json, then exit without deploying")
+	minValidity   = flag.Duration("min-credential-validity", defaultMinCredentialValidity, "Fail the preflight checks if the AWS credentials expire sooner")
+	requestQuotas = flag.Bool("request-quota-increases", false, "File Service Quotas increase requests for the quotas the preflight checks find exceeded")
+	history       = flag.Bool("history", false, "List the most recent deployments of the stack from its deployment history table, then exit without deploying")
+	historyLimit  = flag.Int("history-limit", 20, "Number of deployments --history lists per stack")
+	lockSpec      = flag.String("lock", "", "Hold a deploy lock on the stack while deploying: ssm (an SSM parameter) or dynamodb:TABLE")
+	forceUnlock   = flag.Bool("force-unlock", false, "Release the --lock deploy lock of the stack whoever holds it, then exit without deploying")
+	interactive   = flag.Bool("interactive", false, "Choose the region and env file, confirm each secret, and approve the cdk diff before deploying")
+	jsonOutput    = flag.Bool("json", false, "Write machine-readable JSON events to stdout, one per line, and progress to stderr")
+	quiet         = flag.Bool("quiet", false, "Print only warnings and errors (and --json events)")
 	verbose       = flag.Bool("verbose", false, "Show verbose output")
 )
 
+// logger prints progress and, with --json, events.
+var logger = cliout.New(false, false)
+
 func main() {
 	flag.Usage = func() {
 		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
@@ -72,19 +156,131 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nSteps:\n")
-		fmt.Fprintf(os.Stderr, "  1. Push secrets from .env to AWS Secrets Manager\n")
-		fmt.Fprintf(os.Stderr, "  2. Bootstrap AWS CDK (if needed)\n")
-		fmt.Fprintf(os.Stderr, "  3. Deploy CDK stack\n")
+		fmt.Fprintf(os.Stderr, "  0. preflight: check versions, Docker, credentials, region, model access, and service quotas\n")
+		fmt.Fprintf(os.Stderr, "  1. secrets:   push secrets from .env to AWS Secrets Manager\n")
+		fmt.Fprintf(os.Stderr, "  2. bootstrap: create or upgrade the CDK toolkit stack (if needed)\n")
+		fmt.Fprintf(os.Stderr, "  3. synth:     synthesize the cloud assembly\n")
+		fmt.Fprintf(os.Stderr, "  4. deploy:    deploy the CDK stack and write --outputs-file\n")
+		fmt.Fprintf(os.Stderr, "  5. verify:    check the stacks in --outputs-file deployed successfully\n")
+		fmt.Fprintf(os.Stderr, "  6. smoke test (--smoke-test): invoke each agent with its healthCheck payload\n")
+		fmt.Fprintf(os.Stderr, "  7. promote (--promote):       switch blue/green agents to their candidate version\n")
+		fmt.Fprintf(os.Stderr, "  8. watch (--watch):           redeploy whenever the app's files change\n")
 	}
 	flag.Parse()
+	logger = cliout.New(*jsonOutput, *quiet)
 
 	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		logger.Errorf("%v", err)
 		os.Exit(1)
 	}
 }
 
 func run() error {
+	if *progress != progressRaw && *progress != progressEvents {
+		return fmt.Errorf("--progress must be %s or %s", progressRaw, progressEvents)
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if *retries < 0 {
+		return fmt.Errorf("--retries must not be negative")
+	}
+	if *qualifier != "" {
+		if err := agentcore.ValidateBootstrapQualifier(*qualifier); err != nil {
+			return fmt.Errorf("--qualifier: %w", err)
+		}
+	}
+	if *toolkitStack == "" {
+		return fmt.Errorf("--toolkit-stack-name must not be empty")
+	}
+	for name, arn := range map[string]string{"secrets-role-arn": *secretsRole, "lookup-role-arn": *lookupRole, "deploy-role-arn": *deployRole} {
+		if err := validateRoleARN(name, arn); err != nil {
+			return err
+		}
+	}
+	var promoted []string
+	if *promote != "" {
+		var err error
+		if promoted, err = parseAgents("promote", *promote); err != nil {
+			return err
+		}
+		*smokeTest = true
+	}
+	var rolledBack []string
+	if *rollbackTo != "" {
+		var err error
+		if rolledBack, err = parseAgents("rollback", *rollbackTo); err != nil {
+			return err
+		}
+		if *toVersion == "" {
+			return fmt.Errorf("--rollback requires --to-version")
+		}
+		if *toVersion, err = parseToVersion(*toVersion); err != nil {
+			return err
+		}
+	} else if *toVersion != "" {
+		return fmt.Errorf("--to-version requires --rollback")
+	}
+	if *rollback && !*smokeTest {
+		return fmt.Errorf("--rollback-on-failure requires --smoke-test")
+	}
+	var images map[string]string
+	if *updateImage != "" {
+		if *rollbackTo != "" || *promote != "" || *watch {
+			return fmt.Errorf("--update-image can't be combined with --rollback, --promote, or --watch")
+		}
+		var err error
+		if images, err = parseImages(*updateImage); err != nil {
+			return err
+		}
+	}
+	if *modelAccess && (*updateImage != "" || *rollbackTo != "" || *promote != "" || *watch) {
+		return fmt.Errorf("--check-model-access can't be combined with --update-image, --rollback, --promote, or --watch")
+	}
+	if *interactive && (*jsonOutput || *quiet) {
+		return fmt.Errorf("--interactive can't be combined with --json or --quiet")
+	}
+	if *watch && (*dryRun || *promote != "" || *rollbackTo != "") {
+		return fmt.Errorf("--watch can't be combined with --dry-run, --promote, or --rollback")
+	}
+	if *historyLimit < 1 {
+		return fmt.Errorf("--history-limit must be at least 1")
+	}
+	if *forceUnlock && *lockSpec == "" {
+		return fmt.Errorf("--force-unlock requires --lock")
+	}
+	if *diffSummary && !*dryRun {
+		return fmt.Errorf("--diff-summary requires --dry-run")
+	}
+
+	// The IAM report and cost estimate need only the config file, not AWS
+	// credentials
+	if *iamReport != "" {
+		return printIAMReport(*iamReport, *envName)
+	}
+	if *estimateCost != "" {
+		return printCostEstimate(*estimateCost, *envName)
+	}
+
+	// The deprecated --skip-* flags add to --skip-steps
+	skip := []string{*skipSteps}
+	if *skipPreflight {
+		skip = append(skip, stepPreflight)
+	}
+	if *skipSecrets {
+		skip = append(skip, stepSecrets)
+	}
+	if *skipBootstrap {
+		skip = append(skip, stepBootstrap)
+	}
+	selected, err := parseSteps(*steps, skip)
+	if err != nil {
+		return err
+	}
+	if *requestQuotas && !selected[stepPreflight] {
+		return fmt.Errorf("--request-quota-increases requires the preflight step")
+	}
+
 	// Determine region
 	awsRegion := *region
 	if awsRegion == "" {
@@ -97,28 +293,73 @@ func run() error {
 		awsRegion = "us-east-1"
 	}
 
-	// Detect project name
+	// Detect project and stack names
 	projectName := *project
 	if projectName == "" {
-		projectName = detectProjectName()
+		projectName = envsecrets.DetectProjectName()
+	}
+	stackName := envsecrets.DetectStackName()
+	if *envName != "" {
+		// Loading the overlay validates it before anything is deployed
+		stackName, err = environmentStackName(*envName)
+		if err != nil {
+			return err
+		}
+	}
+	if *stack != "" {
+		stackName = *stack
+	}
+
+	// Interactive mode asks for the region and env file up front
+	var prompt *prompter
+	if *interactive {
+		if prompt, err = newPrompter(); err != nil {
+			return err
+		}
+		if awsRegion, err = prompt.chooseRegion(awsRegion); err != nil {
+			return err
+		}
+		if selected[stepSecrets] {
+			path, err := prompt.chooseEnvFile(*envFile, projectName)
+			if err != nil {
+				return err
+			}
+			if path == "" {
+				delete(selected, stepSecrets)
+			}
+			*envFile = path
+		}
+		logger.Println()
 	}
 
-	fmt.Println("=== AWS AgentCore Deployment ===")
-	fmt.Println()
-	fmt.Printf("Region: %s\n", awsRegion)
+	logger.Println("=== AWS AgentCore Deployment ===")
+	logger.Println()
+	logger.Printf("Region: %s\n", awsRegion)
 	if projectName != "" {
-		fmt.Printf("Project: %s\n", projectName)
+		logger.Printf("Project: %s\n", projectName)
+	}
+	if *envName != "" {
+		logger.Printf("Environment: %s (stack %s)\n", *envName, stackName)
 	}
-	fmt.Printf("Working directory: %s\n", mustGetwd())
+	logger.Printf("Working directory: %s\n", mustGetwd())
+	logger.Printf("Steps: %s\n", formatSteps(selected))
 	if *dryRun {
-		fmt.Println("Mode: DRY RUN (no changes will be made)")
+		logger.Println("Mode: DRY RUN (no changes will be made)")
 	}
-	fmt.Println()
+	logger.Println()
 
-	ctx := context.Background()
+	// Interrupting the deploy cancels it, so cdk exits and the deferred
+	// steps run, such as releasing the deploy lock
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Load AWS config
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(awsRegion))
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(awsRegion),
+		// Back off on throttling while polling alongside large deploys
+		config.WithRetryMode(aws.RetryModeAdaptive),
+		config.WithRetryMaxAttempts(sdkMaxAttempts),
+	)
 	if err != nil {
 		return fmt.Errorf("loading AWS config: %w", err)
 	}
@@ -130,45 +371,455 @@ func run() error {
 		return fmt.Errorf("getting AWS identity: %w", err)
 	}
 	accountID := *identity.Account
-	fmt.Printf("AWS Account: %s\n", accountID)
-	fmt.Println()
+	logger.Printf("AWS Account: %s\n", accountID)
+
+	// Each phase runs with its own role, if one is given
+	broker := newCredentialBroker(cfg, accountID, map[string]string{
+		phaseSecrets: *secretsRole,
+		phaseLookup:  *lookupRole,
+		phaseDeploy:  *deployRole,
+	})
+	if err := broker.check(ctx, runPhases(selected)...); err != nil {
+		return err
+	}
+	lookupCfg, deployCfg := broker.config(phaseLookup), broker.config(phaseDeploy)
+	logger.Println()
+
+	var lock deployLock
+	if *lockSpec != "" {
+		if lock, err = newDeployLock(deployCfg, *lockSpec); err != nil {
+			return err
+		}
+	}
+	if *forceUnlock {
+		return forceUnlockStack(ctx, lock, stackName)
+	}
+
+	// The history only lists recorded deployments
+	if *history {
+		stackNames, err := deployedStackNames(*outputsFile, stackName)
+		if err != nil {
+			return err
+		}
+		return printHistory(ctx, lookupCfg, stackNames, *historyLimit)
+	}
+	target := fmt.Sprintf("stack %s in account %s (%s)", stackName, accountID, awsRegion)
+	if prompt != nil {
+		ok, err := prompt.confirm(fmt.Sprintf("Continue with %s?", target))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+		logger.Println()
+	}
+	logger.Event(eventStart, map[string]any{
+		"region":  awsRegion,
+		"account": accountID,
+		"project": projectName,
+		"stack":   stackName,
+		"steps":   strings.Split(formatSteps(selected), ","),
+		"dryRun":  *dryRun,
+	})
+
+	// Hold the deploy lock until done, so a concurrent deploy of the stack
+	// fails instead of racing CloudFormation
+	if lock != nil && !*dryRun && !*modelAccess {
+		info := newLockInfo(stackName, aws.ToString(identity.Arn))
+		if err := acquireLock(ctx, lock, info); err != nil {
+			return err
+		}
+		defer releaseLock(ctx, lock, info)
+		logger.Println()
+	}
+
+	// A rollback repoints endpoints of the deployed stacks instead of deploying
+	if len(rolledBack) > 0 {
+		logger.Println("=== Rollback ===")
+		stackNames, err := deployedStackNames(*outputsFile, stackName)
+		if err != nil {
+			return fmt.Errorf("rolling back: %w", err)
+		}
+		if err := rollbackAgents(ctx, deployCfg, stackNames, rolledBack, *toVersion, *dryRun); err != nil {
+			return fmt.Errorf("rolling back: %w", err)
+		}
+		return nil
+	}
+
+	// An image update bypasses CloudFormation instead of deploying
+	if len(images) > 0 {
+		logger.Println("=== Update Images ===")
+		if err := runUpdateImages(ctx, deployCfg, stackName, images); err != nil {
+			return fmt.Errorf("updating images: %w", err)
+		}
+		return nil
+	}
+
+	// The model access check only reports which models need enabling
+	if *modelAccess {
+		logger.Println("=== Model Access ===")
+		return runModelAccessCheck(ctx, lookupCfg, *envName)
+	}
+
+	// A dry run records what would change in a machine-readable plan
+	var plan *deployPlan
+	if *dryRun {
+		plan = &deployPlan{
+			Version:     planFormatVersion,
+			GeneratedAt: time.Now().UTC(),
+			Region:      awsRegion,
+			Account:     accountID,
+			Steps:       strings.Split(formatSteps(selected), ","),
+		}
+	}
+
+	// Step 0: Preflight version skew and environment checks
+	if selected[stepPreflight] {
+		logger.Println("=== Step 0: Preflight Checks ===")
+		logger.Event(eventStepStart, map[string]any{"step": stepPreflight})
+		info, warnings := checkVersions(ctx, ssm.NewFromConfig(lookupCfg), !selected[stepBootstrap])
+		printVersions(info)
+		for _, warning := range warnings {
+			logger.Warnf("%s", warning)
+		}
+		logger.Println()
+		logger.Println("Checks:")
+		var stackConfig *agentcore.StackConfig
+		var stackOptions *agentcore.StackOptions
+		if path := agentcore.FindConfigFile(); path != "" {
+			if stackConfig, stackOptions, err = loadConfigFile(path, *envName); err != nil {
+				return err
+			}
+		}
+		in := preflightInput{
+			cfg:         lookupCfg,
+			info:        info,
+			steps:       selected,
+			config:      stackConfig,
+			options:     stackOptions,
+			minValidity: *minValidity,
+			dryRun:      *dryRun,
+		}
+		if *requestQuotas {
+			in.increaseCfg = &deployCfg
+		}
+		checks := runChecks(ctx, in)
+		if plan != nil {
+			plan.setPreflight(info, warnings, checks)
+		}
+		// A dry run reports failed checks in the plan instead of stopping
+		if failed := failedChecks(checks); len(failed) > 0 && plan == nil {
+			return fmt.Errorf("preflight checks failed: %s (fix them, or skip the checks with --skip-steps preflight)", strings.Join(failed, ", "))
+		}
+		logger.Println()
+	}
 
 	// Step 1: Push secrets
-	if !*skipSecrets {
-		fmt.Println("=== Step 1: Push Secrets ===")
-		if err := pushSecrets(ctx, cfg, *envFile, *prefix, projectName, *dryRun, *verbose); err != nil {
+	if selected[stepSecrets] {
+		logger.Println("=== Step 1: Push Secrets ===")
+		logger.Event(eventStepStart, map[string]any{"step": stepSecrets})
+		if err := pushSecrets(ctx, broker.config(phaseSecrets), *envFile, *groupsFile, *prefix, projectName, *envName, plan, prompt, *skipValidate, *verbose); err != nil {
 			return fmt.Errorf("pushing secrets: %w", err)
 		}
-		fmt.Println()
+		logger.Println()
 	} else {
-		fmt.Println("=== Step 1: Skipping secrets (--skip-secrets) ===")
-		fmt.Println()
+		logger.Println("=== Step 1: Skipping secrets ===")
+		logger.Event(eventStepSkip, map[string]any{"step": stepSecrets})
+		logger.Println()
 	}
 
 	// Step 2: Bootstrap CDK
-	if !*skipBootstrap {
-		fmt.Println("=== Step 2: Bootstrap CDK ===")
-		bootstrapCDK(ctx, accountID, awsRegion, *dryRun)
-		fmt.Println()
+	if selected[stepBootstrap] {
+		logger.Println("=== Step 2: Bootstrap CDK ===")
+		logger.Event(eventStepStart, map[string]any{"step": stepBootstrap})
+		if err := bootstrapCDK(ctx, deployCfg, accountID, awsRegion, *dryRun); err != nil {
+			return fmt.Errorf("bootstrapping: %w", err)
+		}
+		logger.Println()
 	} else {
-		fmt.Println("=== Step 2: Skipping bootstrap (--skip-bootstrap) ===")
-		fmt.Println()
+		logger.Println("=== Step 2: Skipping bootstrap ===")
+		logger.Event(eventStepSkip, map[string]any{"step": stepBootstrap})
+		logger.Println()
+	}
+
+	opts := deployOptions{
+		stackName:        stackName,
+		envName:          *envName,
+		progressMode:     *progress,
+		outputsFile:      *outputsFile,
+		concurrency:      *concurrency,
+		retries:          *retries,
+		dryRun:           *dryRun,
+		diffSummary:      *diffSummary,
+		allowDestructive: *allowDestruct,
+		plan:             plan,
+	}
+
+	// Step 3: Synthesize. A later deploy step, possibly in another pipeline
+	// job, deploys this assembly instead of synthesizing again.
+	if selected[stepSynth] {
+		logger.Println("=== Step 3: Synth ===")
+		logger.Event(eventStepStart, map[string]any{"step": stepSynth})
+		dir, err := synthCDK(ctx, lookupCfg, *envName)
+		if err != nil {
+			return err
+		}
+		opts.app = dir
+		logger.Println()
+	} else if selected[stepDeploy] {
+		logger.Println("=== Step 3: Skipping synth ===")
+		logger.Event(eventStepSkip, map[string]any{"step": stepSynth})
+		if dir := existingAssembly(); dir != "" {
+			logger.Printf("Using existing cloud assembly: %s\n", dir)
+			opts.app = dir
+		}
+		logger.Println()
+	}
+
+	// Step 4: Deploy
+	var snapshots map[string]*stackSnapshot
+	if selected[stepDeploy] {
+		logger.Println("=== Step 4: Deploy ===")
+		logger.Event(eventStepStart, map[string]any{"step": stepDeploy})
+		if *rollback && !*dryRun {
+			logger.Println("Recording current templates for rollback...")
+			snapshots, err = snapshotStacks(ctx, deployCfg, opts.stackNames())
+			if err != nil {
+				return fmt.Errorf("recording current templates: %w", err)
+			}
+		}
+		if !*dryRun {
+			if err := approveDestructiveChanges(ctx, deployCfg, &opts, prompt); err != nil {
+				return err
+			}
+		}
+		if prompt != nil && !*dryRun {
+			if err := prompt.reviewChanges(ctx, deployCfg, opts, target); err != nil {
+				return err
+			}
+		}
+		var deployment *deploymentContext
+		if !*dryRun {
+			deployment = newDeploymentContext(ctx, deployCfg, opts.stackNames(), aws.ToString(identity.Arn), awsRegion, *envName, opts.app)
+		}
+		start := time.Now()
+		deployErr := deployCDK(ctx, deployCfg, opts)
+		deployment.record(ctx, deployCfg, opts.stackNames(), deployErr, time.Since(start))
+		if deployErr != nil {
+			return fmt.Errorf("deploying: %w", deployErr)
+		}
+		if !*dryRun {
+			emitOutputs(*outputsFile)
+		}
+		logger.Println()
+	}
+
+	// Step 5: Verify
+	if selected[stepVerify] {
+		logger.Println("=== Step 5: Verify ===")
+		logger.Event(eventStepStart, map[string]any{"step": stepVerify})
+		if *dryRun {
+			logger.Printf("[DRY RUN] Would verify the stacks in %s\n", *outputsFile)
+		} else if err := verifyDeployment(ctx, lookupCfg, *outputsFile, stackName); err != nil {
+			return fmt.Errorf("verifying: %w", err)
+		}
+		logger.Println()
+	}
+
+	// Step 6: Smoke test
+	if *smokeTest {
+		logger.Println("=== Step 6: Smoke Test ===")
+		if *dryRun {
+			logger.Printf("[DRY RUN] Would invoke the agents of the stacks in %s\n", *outputsFile)
+		} else if err := runSmokeTest(ctx, deployCfg, accountID, stackName, snapshots); err != nil {
+			return err
+		}
+		logger.Println()
+	}
+
+	// Step 7: Promote blue/green agents, only reached if the smoke test passed
+	if len(promoted) > 0 {
+		logger.Println("=== Step 7: Promote ===")
+		if *dryRun {
+			logger.Printf("[DRY RUN] Would promote the candidate versions of agents %s\n", strings.Join(promoted, ", "))
+		} else if err := runPromote(ctx, deployCfg, stackName, promoted); err != nil {
+			return fmt.Errorf("promoting: %w", err)
+		}
+		logger.Println()
+	}
+
+	if plan != nil {
+		if err := plan.write(*planFile); err != nil {
+			return fmt.Errorf("writing plan: %w", err)
+		}
+		logger.Printf("Plan written to: %s\n", *planFile)
+		logger.Event(eventPlan, map[string]any{"file": *planFile})
+		logger.Println()
+	}
+
+	logger.Println("=== Deployment Complete ===")
+	if !*dryRun && selected[stepDeploy] {
+		logger.Println()
+		logger.Printf("Stack outputs: %s\n", *outputsFile)
 	}
+	logger.Event(eventComplete, map[string]any{"dryRun": *dryRun})
 
-	// Step 3: Deploy
-	fmt.Println("=== Step 3: Deploy ===")
-	if err := deployCDK(ctx, *dryRun); err != nil {
-		return fmt.Errorf("deploying: %w", err)
+	// Step 8: Watch for changes until interrupted
+	if *watch {
+		logger.Println()
+		logger.Println("=== Step 8: Watch ===")
+		return watchDeploy(ctx, deployCfg, opts)
 	}
-	fmt.Println()
 
-	fmt.Println("=== Deployment Complete ===")
-	if !*dryRun {
-		fmt.Println()
-		fmt.Println("To get outputs:")
-		fmt.Printf("  aws cloudformation describe-stacks --stack-name stats-agent-team --region %s --query 'Stacks[0].Outputs' --no-cli-pager\n", awsRegion)
+	return nil
+}
+
+// runSmokeTest invokes the agents of the deployed stacks and, with
+// --rollback-on-failure, rolls back the stacks whose agents failed.
+func runSmokeTest(ctx context.Context, cfg aws.Config, accountID, stackName string, snapshots map[string]*stackSnapshot) error {
+	stackNames, err := deployedStackNames(*outputsFile, stackName)
+	if err != nil {
+		return fmt.Errorf("smoke testing: %w", err)
+	}
+	failed, err := smokeTestStacks(ctx, cfg, stackNames, *envName)
+	if err != nil {
+		return fmt.Errorf("smoke testing: %w", err)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	if *rollback {
+		logger.Println()
+		logger.Println("=== Rollback ===")
+		for _, name := range failed {
+			snapshot, ok := snapshots[name]
+			if !ok {
+				logger.Printf("  %s: no previous template recorded, not rolled back\n", name)
+				continue
+			}
+			if err := rollbackStack(ctx, cfg, accountID, snapshot); err != nil {
+				logger.Printf("  %s: rollback failed: %v\n", name, err)
+			}
+		}
+	}
+	return fmt.Errorf("smoke test failed for %d of %d stacks: %s", len(failed), len(stackNames), strings.Join(failed, ", "))
+}
+
+// runPromote promotes the candidate versions of blue/green agents of the
+// deployed stacks.
+func runPromote(ctx context.Context, cfg aws.Config, stackName string, agents []string) error {
+	stackNames, err := deployedStackNames(*outputsFile, stackName)
+	if err != nil {
+		return err
+	}
+	return promoteAgents(ctx, cfg, stackNames, agents)
+}
+
+// runUpdateImages points the runtimes of agents of the deployed stacks at
+// new container images and records the images in the config file.
+func runUpdateImages(ctx context.Context, cfg aws.Config, stackName string, images map[string]string) error {
+	if *dryRun {
+		for _, name := range sortedKeys(images) {
+			logger.Printf("  [DRY RUN] %s: would update image to %s\n", name, images[name])
+		}
+		return nil
+	}
+	stackNames, err := deployedStackNames(*outputsFile, stackName)
+	if err != nil {
+		return err
+	}
+	if err := updateAgentImages(ctx, cfg, stackNames, images); err != nil {
+		return err
+	}
+	return recordImages(*envName, images)
+}
+
+// environmentStackName returns the stack name of the config file in the
+// current or parent directory with the environment overlay merged over it.
+func environmentStackName(envName string) (string, error) {
+	path := agentcore.FindConfigFile()
+	if path == "" {
+		return "", fmt.Errorf("--env-name %s needs a config file in the current or parent directory", envName)
+	}
+	config, err := agentcore.LoadStackConfigFromFile(path, agentcore.WithEnvironment(envName))
+	if err != nil {
+		return "", fmt.Errorf("loading %s with the %s overlay: %w", path, envName, err)
+	}
+	return config.StackName, nil
+}
+
+// loadConfigFile loads the stack config and options of a config file with
+// the environment overlay merged over it.
+func loadConfigFile(path, envName string) (*agentcore.StackConfig, *agentcore.StackOptions, error) {
+	var opts []agentcore.LoadOption
+	if envName != "" {
+		opts = append(opts, agentcore.WithEnvironment(envName))
+	}
+	config, err := agentcore.LoadStackConfigFromFile(path, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	options, err := agentcore.LoadStackOptionsFromFile(path, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return config, options, nil
+}
+
+// printIAMReport prints the IAM statements of the stack of the config file
+// in the current or parent directory, with the environment overlay merged
+// over it if envName is set.
+func printIAMReport(format, envName string) error {
+	if format != agentcore.IAMReportMarkdown && format != agentcore.IAMReportJSON {
+		return fmt.Errorf("--iam-report must be %s or %s", agentcore.IAMReportMarkdown, agentcore.IAMReportJSON)
+	}
+	path := agentcore.FindConfigFile()
+	if path == "" {
+		return fmt.Errorf("--iam-report needs a config file in the current or parent directory")
+	}
+
+	config, options, err := loadConfigFile(path, envName)
+	if err != nil {
+		return err
+	}
+
+	report, err := agentcore.GenerateIAMReportWithOptions(*config, *options, format)
+	if err != nil {
+		return fmt.Errorf("generating IAM report: %w", err)
+	}
+	fmt.Print(report)
+	return nil
+}
+
+// printCostEstimate prints the estimated monthly cost of the stack of the
+// config file in the current or parent directory, with the environment
+// overlay merged over it if envName is set.
+func printCostEstimate(format, envName string) error {
+	if format != agentcore.CostReportTable && format != agentcore.CostReportJSON {
+		return fmt.Errorf("--estimate-cost must be %s or %s", agentcore.CostReportTable, agentcore.CostReportJSON)
+	}
+	path := agentcore.FindConfigFile()
+	if path == "" {
+		return fmt.Errorf("--estimate-cost needs a config file in the current or parent directory")
 	}
 
+	config, options, err := loadConfigFile(path, envName)
+	if err != nil {
+		return err
+	}
+
+	report := agentcore.EstimateCostWithOptions(*config, *options, agentcore.CostAssumptions{})
+	if format == agentcore.CostReportJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(report.Table())
 	return nil
 }
 
@@ -180,8 +831,12 @@ func mustGetwd() string {
 	return wd
 }
 
-// pushSecrets pushes environment variables to AWS Secrets Manager
-func pushSecrets(ctx context.Context, cfg aws.Config, envFile, prefix, projectName string, dryRun, verbose bool) error {
+// pushSecrets pushes environment variables to the secrets backend of the
+// config file, Secrets Manager or SSM Parameter Store. With a
+// plan (dry run), the changes are recorded in it instead. Unless
+// skipValidation is set, obviously invalid values are refused first. The
+// secrets are tagged with the project, environment, and managed-by tags.
+func pushSecrets(ctx context.Context, cfg aws.Config, envFile, groupsFile, prefix, projectName, environment string, plan *deployPlan, prompt *prompter, skipValidation, verbose bool) error {
 	// Find env file
 	var envPath string
 	if envFile != "" {
@@ -196,271 +851,267 @@ func pushSecrets(ctx context.Context, cfg aws.Config, envFile, prefix, projectNa
 			}
 		}
 		if _, err := os.Stat(envPath); os.IsNotExist(err) {
-			fmt.Printf("Warning: %s not found, skipping secrets push\n", envFile)
+			logger.Warnf("%s not found, skipping secrets push", envFile)
 			return nil
 		}
 	} else {
 		// Auto-detect env file
 		var err error
-		envPath, err = findEnvFile(projectName)
+		envPath, err = envsecrets.FindEnvFile(projectName)
 		if err != nil {
-			fmt.Println("No .env file found, skipping secrets push")
-			fmt.Println("  Searched: .env, ../.env, ~/.plexusone/")
+			logger.Println("No .env file found, skipping secrets push")
+			logger.Println("  Searched: .env, ../.env, ~/.plexusone/")
 			return nil
 		}
 	}
 
-	fmt.Printf("Reading from: %s\n", envPath)
-
-	// Define secret groups
-	groups := []secretGroup{
-		{
-			name:        "llm",
-			description: "LLM provider API keys",
-			keys:        make(map[string]string),
-			patterns: []string{
-				"GOOGLE_API_KEY", "GEMINI_API_KEY", "ANTHROPIC_API_KEY",
-				"CLAUDE_API_KEY", "OPENAI_API_KEY", "XAI_API_KEY", "LLM_API_KEY",
-			},
-		},
-		{
-			name:        "search",
-			description: "Search provider API keys",
-			keys:        make(map[string]string),
-			patterns:    []string{"SERPER_API_KEY", "SERPAPI_API_KEY"},
-		},
-		{
-			name:        "config",
-			description: "Configuration and observability settings",
-			keys:        make(map[string]string),
-			patterns: []string{
-				"LLM_PROVIDER", "LLM_MODEL", "LLM_BASE_URL", "SEARCH_PROVIDER",
-				"OBSERVABILITY_ENABLED", "OBSERVABILITY_PROVIDER",
-				"OPIK_API_KEY", "OPIK_WORKSPACE", "OPIK_PROJECT",
-				"LANGFUSE_PUBLIC_KEY", "LANGFUSE_SECRET_KEY", "PHOENIX_API_KEY",
-			},
-		},
-	}
+	logger.Printf("Reading from: %s\n", envPath)
 
-	// Parse env file
-	if err := parseEnvFile(envPath, groups, verbose); err != nil {
+	backendName, prefix, err := secretsLocation(prefix, projectName, environment)
+	if err != nil {
 		return err
 	}
 
-	// Create secrets client
-	var client *secretsmanager.Client
-	if !dryRun {
-		client = secretsmanager.NewFromConfig(cfg)
+	// Load secret groups
+	defs, groupsPath, err := envsecrets.ResolveGroups(groupsFile, projectName)
+	if err != nil {
+		return err
 	}
-
-	// Process each group
-	for _, group := range groups {
-		secretName := fmt.Sprintf("%s/%s", prefix, group.name)
-		if err := createOrUpdateSecret(ctx, client, secretName, group, dryRun); err != nil {
-			return err
-		}
+	if groupsPath != "" {
+		logger.Printf("Secret groups: %s\n", groupsPath)
 	}
 
-	return nil
-}
-
-type secretGroup struct {
-	name        string
-	description string
-	keys        map[string]string
-	patterns    []string
-}
-
-func parseEnvFile(filename string, groups []secretGroup, verbose bool) error {
-	file, err := os.Open(filename)
+	// Parse env file
+	file, err := envsecrets.ParseEnvFile(ctx, envPath, defs)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	envRegex := regexp.MustCompile(`^\s*(export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
+	if file.Format != envsecrets.FormatPlain {
+		logger.Printf("Decrypted %s file in memory\n", file.Format)
+	}
+	if verbose {
+		for _, group := range file.Groups {
+			for _, key := range group.KeyNames() {
+				logger.Printf("  Found %s: %s\n", group.Name, key)
+			}
 		}
-
-		matches := envRegex.FindStringSubmatch(line)
-		if matches == nil {
-			continue
+	}
+	if !skipValidation {
+		problems := envsecrets.ValidateValues(file.Groups)
+		for _, problem := range problems {
+			logger.Warnf("%s", problem)
 		}
-
-		key := matches[2]
-		value := strings.Trim(matches[3], `"'`)
-
-		if value == "" || strings.HasPrefix(value, "your-") {
-			continue
+		if envsecrets.Blocking(problems) {
+			return fmt.Errorf("refusing to push invalid secret values; fix them or use --skip-secret-validation")
 		}
+	}
 
-		for i := range groups {
-			for _, pattern := range groups[i].patterns {
-				if key == pattern {
-					groups[i].keys[key] = value
-					if verbose {
-						fmt.Printf("  Found %s: %s\n", groups[i].name, key)
-					}
-					break
-				}
-			}
-		}
+	// Push each group (a dry run only reads, to diff against current values)
+	backend, err := envsecrets.NewBackend(backendName, cfg)
+	if err != nil {
+		return err
+	}
+	logger.Printf("Secrets backend: %s, prefix %s\n", backendName, prefix)
+	tags := envsecrets.DefaultTags(projectName, environment)
+	var results []envsecrets.PushResult
+	if prompt != nil && plan == nil {
+		results, err = prompt.pushSecrets(ctx, backend, file.Groups, prefix, tags)
+	} else {
+		results, err = envsecrets.PushGroups(ctx, backend, file.Groups, envsecrets.PushOptions{
+			Prefix: prefix,
+			DryRun: plan != nil,
+			Tags:   tags,
+			Out:    logger.Stdout(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	logger.Printf("Secrets: %s\n", envsecrets.Summarize(results))
+	logger.Event(cliout.EventSecretsPushed, map[string]any{
+		"envFile": envPath,
+		"backend": backendName,
+		"prefix":  prefix,
+		"dryRun":  plan != nil,
+		"secrets": cliout.SecretResults(results),
+	})
+	if plan != nil {
+		plan.addSecrets(results)
 	}
 
-	return scanner.Err()
+	return nil
 }
 
-func createOrUpdateSecret(ctx context.Context, client *secretsmanager.Client, secretName string, group secretGroup, dryRun bool) error {
-	if len(group.keys) == 0 {
-		fmt.Printf("  Skipping %s (no keys found)\n", secretName)
-		return nil
+// secretsLocation returns the backend and expanded prefix to push secrets
+// to: those the config file's secrets options make agents read, or
+// Secrets Manager and --prefix without a config file.
+func secretsLocation(prefixFlag, projectName, environment string) (string, string, error) {
+	prefix, err := envsecrets.ExpandPrefix(prefixFlag, projectName, environment)
+	if err != nil {
+		return "", "", fmt.Errorf("--prefix: %w", err)
 	}
-
-	jsonBytes, err := json.Marshal(group.keys)
+	path := agentcore.FindConfigFile()
+	if path == "" {
+		return envsecrets.BackendSecretsManager, prefix, nil
+	}
+	config, options, err := loadConfigFile(path, environment)
 	if err != nil {
-		return err
+		return "", "", err
 	}
-	secretValue := string(jsonBytes)
 
-	var keyNames []string
-	for k := range group.keys {
-		keyNames = append(keyNames, k)
+	backend, configPrefix := agentcore.PushedSecretsLocation(*config, *options)
+	switch {
+	case configPrefix == "":
+	case prefixFlag == "":
+		prefix = configPrefix
+	case prefix != configPrefix:
+		return "", "", fmt.Errorf("--prefix %s does not match the prefix %s agents read secrets from (secrets.prefix in %s)", prefix, configPrefix, path)
 	}
-	fmt.Printf("  %s: %s\n", secretName, strings.Join(keyNames, ", "))
+	return backend, prefix, nil
+}
 
-	if dryRun {
-		fmt.Printf("    [DRY RUN] Would create/update\n")
-		return nil
+// deployOptions controls how the CDK app is deployed.
+type deployOptions struct {
+	stackName        string      // Stack for --progress events
+	envName          string      // Environment overlay selected when synthesizing the app
+	progressMode     string      // progressRaw or progressEvents
+	app              string      // Synthesized cloud assembly to deploy; empty synthesizes the app
+	outputsFile      string      // File to write stack outputs to
+	plan             *deployPlan // Records stack changes on dry runs
+	concurrency      int         // Stacks deployed in parallel; above 1 enables multi-stack orchestration
+	retries          int         // Retries of a deploy that failed because of throttling
+	hotswap          bool        // Hotswap changed Lambda functions and ECS services, falling back to a full deploy
+	diffSummary      bool        // Print a summary of the planned stack changes instead of the cdk diff on dry runs
+	allowDestructive bool        // Deploy changes that replace or remove agent runtimes, endpoints, gateways, or secrets
+	dryRun           bool
+}
+
+// deployCDK runs cdk deploy
+func deployCDK(ctx context.Context, cfg aws.Config, opts deployOptions) error {
+	// cdk synthesizes the app itself unless a cloud assembly is given
+	if opts.app == "" {
+		goModTidy(ctx)
 	}
 
-	_, err = client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
-		SecretId:     aws.String(secretName),
-		SecretString: aws.String(secretValue),
-	})
-	if err != nil {
-		var notFound *types.ResourceNotFoundException
-		if errors.As(err, &notFound) {
-			_, err = client.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
-				Name:         aws.String(secretName),
-				Description:  aws.String(group.description),
-				SecretString: aws.String(secretValue),
-			})
+	if opts.dryRun {
+		// The summary is computed from the cloud assembly, which cdk diff
+		// would otherwise synthesize
+		if opts.diffSummary && opts.app == "" {
+			dir, err := synthCDK(ctx, cfg, opts.envName)
 			if err != nil {
 				return err
 			}
-			fmt.Printf("    Created\n")
-			return nil
+			opts.app = dir
+		}
+		if !opts.diffSummary {
+			logger.Println("Running cdk diff...")
+			cmd, err := cdkCommand(ctx, cfg, opts.cdkArgs("diff")...)
+			if err != nil {
+				return err
+			}
+			cmd.Stdout = logger.Stdout()
+			cmd.Stderr = logger.Stderr()
+			_ = cmd.Run() // Ignore error, diff returns non-zero if there are differences
 		}
-		return err
-	}
-	fmt.Printf("    Updated\n")
-	return nil
-}
-
-// bootstrapCDK runs cdk bootstrap
-func bootstrapCDK(ctx context.Context, accountID, region string, dryRun bool) {
-	target := fmt.Sprintf("aws://%s/%s", accountID, region)
-	fmt.Printf("Bootstrap target: %s\n", target)
 
-	if dryRun {
-		fmt.Println("[DRY RUN] Would run: cdk bootstrap " + target)
-		return
+		if opts.plan != nil {
+			dir := opts.app
+			if dir == "" {
+				dir = assemblyDir()
+			}
+			stacks, err := planStacks(ctx, cfg, dir)
+			if err != nil {
+				return fmt.Errorf("planning stack changes: %w", err)
+			}
+			opts.plan.Stacks = stacks
+			if opts.diffSummary {
+				printDiffSummary(stacks)
+			}
+		}
+		return nil
 	}
 
-	//nolint:gosec // G702: target is built from AWS SDK values (accountID, region), not user input
-	cmd := exec.CommandContext(ctx, "cdk", "bootstrap", target)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		// Bootstrap might fail if already done, that's OK
-		fmt.Println("  Bootstrap completed (or already bootstrapped)")
+	start := time.Now()
+	logger.Event(eventDeployStart, map[string]any{"stacks": nonNilNames(opts.stackNames()), "hotswap": opts.hotswap})
+	if err := deployStacksOf(ctx, cfg, opts); err != nil {
+		return err
 	}
+	logger.Event(eventDeployComplete, map[string]any{
+		"stacks":  nonNilNames(opts.stackNames()),
+		"seconds": int(time.Since(start).Seconds()),
+	})
+	return nil
 }
 
-// findEnvFile searches for .env file in standard locations
-func findEnvFile(projectName string) (string, error) {
-	// Search order:
-	// 1. .env in current directory
-	// 2. ../.env in parent directory
-	// 3. ~/.plexusone/projects/{project}/.env (if project specified)
-	// 4. ~/.plexusone/.env (global fallback)
-
-	candidates := []string{
-		".env",
-		"../.env",
+// deployStacksOf deploys the stacks of the app, in parallel with
+// opts.concurrency above 1 and with CloudFormation events with
+// --progress events.
+func deployStacksOf(ctx context.Context, cfg aws.Config, opts deployOptions) error {
+	if opts.concurrency > 1 {
+		return deployMultiStack(ctx, cfg, opts)
 	}
 
-	// Add project-specific and global paths
-	if home, err := os.UserHomeDir(); err == nil {
-		if projectName != "" {
-			candidates = append(candidates, filepath.Join(home, DefaultConfigDir, "projects", projectName, ".env"))
+	if opts.progressMode == progressEvents {
+		if opts.stackName != "" {
+			return retryThrottled(ctx, "", opts.retries, func() error {
+				return deployWithEvents(ctx, cfg, opts)
+			})
 		}
-		candidates = append(candidates, filepath.Join(home, DefaultConfigDir, ".env"))
+		logger.Warnf("no stack name found for --progress events (set --stack); showing cdk output")
 	}
 
-	for _, path := range candidates {
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
+	return retryThrottled(ctx, "", opts.retries, func() error {
+		logger.Println("Running cdk deploy...")
+		detector := &throttleDetector{}
+		cmd, err := cdkCommand(ctx, cfg, opts.deployArgs()...)
+		if err != nil {
+			return err
 		}
-	}
+		cmd.Stdout = io.MultiWriter(logger.Stdout(), detector)
+		cmd.Stderr = io.MultiWriter(logger.Stderr(), detector)
+		return markThrottled(cmd.Run(), detector.Throttled())
+	})
+}
 
-	return "", fmt.Errorf("no .env file found")
+// cdkArgs returns the arguments of a cdk command, reading the cloud assembly
+// if one is given and otherwise selecting the environment overlay.
+func (o deployOptions) cdkArgs(command string, args ...string) []string {
+	cmdArgs := []string{command}
+	if o.app != "" {
+		cmdArgs = append(cmdArgs, "--app", o.app)
+	} else {
+		cmdArgs = append(cmdArgs, envContextArgs(o.envName)...)
+		cmdArgs = append(cmdArgs, qualifierContextArgs()...)
+	}
+	return append(cmdArgs, args...)
 }
 
-// detectProjectName tries to detect the project name from config.json or directory name
-func detectProjectName() string {
-	// Try to read stackName from config.json
-	configPaths := []string{"config.json", "../config.json"}
-	for _, path := range configPaths {
-		if data, err := os.ReadFile(path); err == nil {
-			var config struct {
-				StackName string `json:"stackName"`
-			}
-			if json.Unmarshal(data, &config) == nil && config.StackName != "" {
-				return config.StackName
+// stackNames returns the names of the stacks about to be deployed: those of
+// the cloud assembly if one is given, otherwise the configured stack.
+func (o deployOptions) stackNames() []string {
+	if o.app != "" {
+		if stacks, err := readStackGraph(o.app); err == nil {
+			names := make([]string, len(stacks))
+			for i, stack := range stacks {
+				names[i] = stack.stackName
 			}
+			return names
 		}
 	}
-
-	// Fall back to current directory name
-	if wd, err := os.Getwd(); err == nil {
-		return filepath.Base(wd)
+	if o.stackName != "" {
+		return []string{o.stackName}
 	}
-
-	return ""
+	return nil
 }
 
-// deployCDK runs cdk deploy
-func deployCDK(ctx context.Context, dryRun bool) error {
-	// Run go mod tidy first
-	fmt.Println("Running go mod tidy...")
-	tidyCmd := exec.CommandContext(ctx, "go", "mod", "tidy")
-	tidyCmd.Stdout = os.Stdout
-	tidyCmd.Stderr = os.Stderr
-	if err := tidyCmd.Run(); err != nil {
-		fmt.Printf("Warning: go mod tidy failed: %v\n", err)
-	}
-
-	if dryRun {
-		fmt.Println("Running cdk diff...")
-		cmd := exec.CommandContext(ctx, "cdk", "diff")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		_ = cmd.Run() // Ignore error, diff returns non-zero if there are differences
-		return nil
+// deployArgs returns the arguments of cdk deploy for the whole app.
+func (o deployOptions) deployArgs() []string {
+	args := []string{"--require-approval", "never", "--outputs-file", o.outputsFile}
+	args = append(args, toolkitStackArgs()...)
+	if o.hotswap {
+		// AgentCore resources can't be hotswapped; plain --hotswap would
+		// silently skip their changes
+		args = append(args, "--hotswap-fallback")
 	}
-
-	fmt.Println("Running cdk deploy...")
-	cmd := exec.CommandContext(ctx, "cdk", "deploy", "--require-approval", "never")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	return cmd.Run()
+	return o.cdkArgs("deploy", args...)
 }
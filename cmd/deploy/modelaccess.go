@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// runModelAccessCheck checks the account has access to the Bedrock models
+// of the config file in the current or parent directory, and prints which
+// models need enabling in the console. It returns an error if any do.
+func runModelAccessCheck(ctx context.Context, cfg aws.Config, envName string) error {
+	path := agentcore.FindConfigFile()
+	if path == "" {
+		return fmt.Errorf("--check-model-access needs a config file in the current or parent directory")
+	}
+	config, _, err := loadConfigFile(path, envName)
+	if err != nil {
+		return err
+	}
+	if len(config.IAM.BedrockModelIDs) == 0 {
+		logger.Printf("No bedrockModelIds in %s; the agents may invoke any model the account has access to.\n", path)
+		return nil
+	}
+
+	results, err := agentcore.CheckModelAccess(ctx, cfg, config.IAM.BedrockModelIDs)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, result := range results {
+		line := fmt.Sprintf("  %-9s %s", result.Status, result.ModelID)
+		if result.Reason != "" {
+			line += " (" + result.Reason + ")"
+		}
+		logger.Println(line)
+		logger.Event(eventModelAccess, map[string]any{
+			"modelId":           result.ModelID,
+			"foundationModelId": result.FoundationModelID,
+			"status":            result.Status,
+			"reason":            result.Reason,
+		})
+		if result.Status == agentcore.ModelAccessMissing {
+			missing = append(missing, result.FoundationModelID)
+		}
+	}
+	logger.Println()
+
+	if len(missing) == 0 {
+		logger.Printf("All checked models are accessible in %s.\n", cfg.Region)
+		return nil
+	}
+	logger.Printf("Enable these models in the Bedrock console (Model access > Modify model access) in %s:\n", cfg.Region)
+	for _, modelID := range missing {
+		logger.Printf("  - %s\n", modelID)
+	}
+	logger.Printf("  %s\n", agentcore.ModelAccessConsoleURL(cfg.Region))
+	return fmt.Errorf("%d of %d models need access in %s: %s", len(missing), len(results), cfg.Region, strings.Join(missing, ", "))
+}
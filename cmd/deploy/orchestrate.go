@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// stackArtifactType is the cloud assembly artifact type of a CloudFormation stack.
+const stackArtifactType = "aws:cloudformation:stack"
+
+// assemblyManifest is the subset of the cloud assembly manifest used to
+// order stack deployments and plan their changes.
+type assemblyManifest struct {
+	Artifacts map[string]struct {
+		Type         string   `json:"type"`
+		Dependencies []string `json:"dependencies"`
+		Properties   struct {
+			StackName    string `json:"stackName"`
+			TemplateFile string `json:"templateFile"`
+		} `json:"properties"`
+	} `json:"artifacts"`
+}
+
+// stackNode is a stack in the deployment graph.
+type stackNode struct {
+	id        string   // Artifact ID, passed to cdk deploy
+	stackName string   // CloudFormation stack name
+	deps      []string // Artifact IDs of stacks that must be deployed first
+}
+
+// stackState is the deployment state of a stack.
+type stackState int
+
+const (
+	statePending stackState = iota
+	stateRunning
+	stateSucceeded
+	stateFailed
+	stateSkipped
+)
+
+// stackResult is the outcome of a single stack deploy.
+type stackResult struct {
+	id       string
+	err      error
+	duration time.Duration
+}
+
+// assemblyDir returns the cloud assembly directory from cdk.json, or cdk.out.
+func assemblyDir() string {
+	data, err := os.ReadFile("cdk.json")
+	if err == nil {
+		var cdkJSON struct {
+			Output string `json:"output"`
+		}
+		if json.Unmarshal(data, &cdkJSON) == nil && cdkJSON.Output != "" {
+			return cdkJSON.Output
+		}
+	}
+	return "cdk.out"
+}
+
+// readStackGraph reads the stacks and their dependencies from a synthesized
+// cloud assembly. CDK records cross-stack references and explicit
+// AddDependency calls as artifact dependencies.
+func readStackGraph(dir string) ([]*stackNode, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json")) //nolint:gosec // G304: path is the local cloud assembly
+	if err != nil {
+		return nil, fmt.Errorf("reading cloud assembly: %w", err)
+	}
+	var manifest assemblyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing cloud assembly manifest: %w", err)
+	}
+
+	var stacks []*stackNode
+	for id, artifact := range manifest.Artifacts {
+		if artifact.Type != stackArtifactType {
+			continue
+		}
+		node := &stackNode{id: id, stackName: artifact.Properties.StackName}
+		if node.stackName == "" {
+			node.stackName = id
+		}
+		for _, dep := range artifact.Dependencies {
+			if manifest.Artifacts[dep].Type == stackArtifactType {
+				node.deps = append(node.deps, dep)
+			}
+		}
+		sort.Strings(node.deps)
+		stacks = append(stacks, node)
+	}
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].id < stacks[j].id })
+	return stacks, nil
+}
+
+// deployMultiStack deploys the stacks of the cloud assembly (synthesizing the
+// app once if none is given) in dependency order, opts.concurrency stacks at
+// a time.
+func deployMultiStack(ctx context.Context, cfg aws.Config, opts deployOptions) error {
+	dir := opts.app
+	if dir == "" {
+		logger.Println("Running cdk synth...")
+		synthCmd, err := cdkCommand(ctx, cfg, synthArgs(opts.envName)...)
+		if err != nil {
+			return err
+		}
+		synthCmd.Stdout = logger.Stdout()
+		synthCmd.Stderr = logger.Stderr()
+		if err := synthCmd.Run(); err != nil {
+			return fmt.Errorf("synthesizing: %w", err)
+		}
+		dir = assemblyDir()
+	}
+
+	stacks, err := readStackGraph(dir)
+	if err != nil {
+		return err
+	}
+	if len(stacks) == 0 {
+		return fmt.Errorf("no stacks found in %s", dir)
+	}
+
+	return deployStacks(ctx, cfg, dir, stacks, opts)
+}
+
+// deployStacks deploys the stacks of a multi-stack app in dependency order,
+// running up to opts.concurrency independent stacks at once. A stack that
+// fails because of throttling is retried on its own. When a stack fails,
+// stacks that depend on it are skipped and independent stacks continue.
+// The outputs of the deployed stacks are merged into opts.outputsFile.
+func deployStacks(ctx context.Context, cfg aws.Config, dir string, stacks []*stackNode, opts deployOptions) error {
+	client := cloudformation.NewFromConfig(cfg)
+	outputsDir, err := os.MkdirTemp("", "cdk-outputs-*")
+	if err != nil {
+		return fmt.Errorf("creating outputs directory: %w", err)
+	}
+	defer os.RemoveAll(outputsDir)
+	state := make(map[string]stackState, len(stacks))
+	results := make(chan stackResult)
+	running := 0
+
+	logger.Printf("Deploying %d stacks (concurrency %d)\n", len(stacks), opts.concurrency)
+	for _, node := range stacks {
+		if len(node.deps) > 0 {
+			logger.Printf("  %s -> depends on %s\n", node.id, strings.Join(node.deps, ", "))
+		} else {
+			logger.Printf("  %s\n", node.id)
+		}
+	}
+	logger.Printf("\n")
+
+	for {
+		// Skip stacks whose dependencies failed, transitively
+		for changed := true; changed; {
+			changed = false
+			for _, node := range stacks {
+				if state[node.id] != statePending {
+					continue
+				}
+				for _, dep := range node.deps {
+					if state[dep] == stateFailed || state[dep] == stateSkipped {
+						state[node.id] = stateSkipped
+						logger.Printf("[%s] skipped: dependency %s did not deploy\n", node.id, dep)
+						changed = true
+						break
+					}
+				}
+			}
+		}
+
+		// Start stacks whose dependencies have all deployed
+		for _, node := range stacks {
+			if running >= opts.concurrency {
+				break
+			}
+			if state[node.id] != statePending || !depsSucceeded(node, state) {
+				continue
+			}
+			state[node.id] = stateRunning
+			running++
+			go func(node *stackNode) {
+				start := time.Now()
+				err := retryThrottled(ctx, fmt.Sprintf("[%s] ", node.id), opts.retries, func() error {
+					return deployStack(ctx, cfg, client, dir, node, opts.progressMode, stackOutputsFile(outputsDir, node))
+				})
+				results <- stackResult{id: node.id, err: err, duration: time.Since(start)}
+			}(node)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		if result.err != nil {
+			state[result.id] = stateFailed
+			logger.Printf("[%s] FAILED after %s: %v\n", result.id, result.duration.Round(time.Second), result.err)
+			continue
+		}
+		state[result.id] = stateSucceeded
+		logger.Printf("[%s] deployed in %s%s\n", result.id, result.duration.Round(time.Second), describeDeployedStack(ctx, client, stackByID(stacks, result.id)))
+	}
+
+	if err := mergeOutputs(outputsDir, stacks, state, opts.outputsFile); err != nil {
+		logger.Warnf("writing %s: %v", opts.outputsFile, err)
+	}
+	return summarizeDeploy(stacks, state)
+}
+
+// stackOutputsFile returns the per-stack outputs file of a stack.
+func stackOutputsFile(dir string, node *stackNode) string {
+	return filepath.Join(dir, sanitizeFileName(node.id)+".json")
+}
+
+// mergeOutputs merges the outputs of the deployed stacks into path.
+func mergeOutputs(dir string, stacks []*stackNode, state map[string]stackState, path string) error {
+	merged := make(map[string]map[string]string)
+	for _, node := range stacks {
+		if state[node.id] != stateSucceeded {
+			continue
+		}
+		outputs, err := readOutputsFile(stackOutputsFile(dir, node))
+		if err != nil {
+			return err
+		}
+		for stackName, values := range outputs {
+			merged[stackName] = values
+		}
+	}
+	return writeOutputsFile(path, merged)
+}
+
+// deployStack deploys a single stack from the synthesized cloud assembly.
+func deployStack(ctx context.Context, cfg aws.Config, client *cloudformation.Client, dir string, node *stackNode, progressMode, outputsFile string) error {
+	logFile, err := os.CreateTemp("", fmt.Sprintf("cdk-deploy-%s-*.log", sanitizeFileName(node.id)))
+	if err != nil {
+		return fmt.Errorf("creating deploy log: %w", err)
+	}
+	defer logFile.Close()
+
+	logger.Printf("[%s] deploying (output: %s)\n", node.id, logFile.Name())
+
+	var renderer *eventRenderer
+	if progressMode == progressEvents {
+		renderer = newEventRenderer(client, node.stackName, time.Now())
+		renderer.prefix = fmt.Sprintf("[%s] ", node.id)
+	}
+
+	args := append([]string{"deploy", "--app", dir, "--exclusively", "--require-approval", "never", "--outputs-file", outputsFile}, toolkitStackArgs()...)
+	cmd, err := cdkCommand(ctx, cfg, append(args, node.id)...)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := runWithEvents(ctx, cmd, renderer); err != nil {
+		if renderer != nil {
+			for _, failure := range renderer.failures {
+				logger.Printf("[%s]   %s\n", node.id, failure)
+			}
+		}
+		logger.Printf("[%s] last lines of cdk output:\n", node.id)
+		printTail(logFile.Name(), logTailLines)
+		return markThrottled(err, fileThrottled(logFile.Name()) || (renderer != nil && anyThrottled(renderer.failures)))
+	}
+	return nil
+}
+
+// describeDeployedStack summarizes the agents and gateway of a deployed
+// AgentCoreStack from its outputs.
+func describeDeployedStack(ctx context.Context, client *cloudformation.Client, node *stackNode) string {
+	deployed, err := agentcore.FromStackOutputs(ctx, client, node.stackName)
+	if err != nil || len(deployed.Agents) == 0 {
+		return ""
+	}
+	summary := fmt.Sprintf(": agents %s", strings.Join(deployed.AgentNames(), ", "))
+	if deployed.GatewayURL != "" {
+		summary += fmt.Sprintf("; gateway %s", deployed.GatewayURL)
+	}
+	return summary
+}
+
+// depsSucceeded reports whether all of a stack's dependencies have deployed.
+func depsSucceeded(node *stackNode, state map[string]stackState) bool {
+	for _, dep := range node.deps {
+		if state[dep] != stateSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// stackByID returns the stack with the given artifact ID.
+func stackByID(stacks []*stackNode, id string) *stackNode {
+	for _, node := range stacks {
+		if node.id == id {
+			return node
+		}
+	}
+	return nil
+}
+
+// summarizeDeploy prints the final state of each stack and returns an error
+// if any stack did not deploy.
+func summarizeDeploy(stacks []*stackNode, state map[string]stackState) error {
+	var failed, skipped, cyclic []string
+	for _, node := range stacks {
+		switch state[node.id] {
+		case stateFailed:
+			failed = append(failed, node.id)
+		case stateSkipped:
+			skipped = append(skipped, node.id)
+		case statePending:
+			cyclic = append(cyclic, node.id)
+		}
+	}
+
+	logger.Printf("\n%d of %d stacks deployed\n", len(stacks)-len(failed)-len(skipped)-len(cyclic), len(stacks))
+	switch {
+	case len(cyclic) > 0:
+		return fmt.Errorf("dependency cycle between stacks: %s", strings.Join(cyclic, ", "))
+	case len(failed) > 0:
+		return fmt.Errorf("stacks failed: %s (skipped: %d)", strings.Join(failed, ", "), len(skipped))
+	}
+	return nil
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+// nestedStackType is the CloudFormation type of nested stacks, such as the
+// partitions of a partitioned stack.
+const nestedStackType = "AWS::CloudFormation::Stack"
+
+const (
+	// DefaultPlanFile is the default file --dry-run writes the plan to.
+	DefaultPlanFile = "plan.json"
+
+	// planFormatVersion is the version of the plan.json format.
+	planFormatVersion = 1
+)
+
+// Plan actions.
+const (
+	actionCreate    = "create"
+	actionUpdate    = "update"
+	actionUnchanged = "unchanged"
+	actionAdd       = "add"
+	actionModify    = "modify"
+	actionRemove    = "remove"
+)
+
+// deployPlan is the machine-readable result of a dry run, for rendering
+// outside the terminal (e.g. as a pull request comment). It never contains
+// secret values.
+type deployPlan struct {
+	Version     int            `json:"version"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Region      string         `json:"region"`
+	Account     string         `json:"account"`
+	Steps       []string       `json:"steps"`
+	Preflight   *preflightPlan `json:"preflight,omitempty"`
+	Secrets     []secretPlan   `json:"secrets,omitempty"`
+	Stacks      []stackPlan    `json:"stacks,omitempty"`
+}
+
+// preflightPlan is the result of the preflight version check and checks.
+type preflightPlan struct {
+	Tool      string        `json:"tool,omitempty"`
+	Library   string        `json:"library,omitempty"`
+	CDKLib    string        `json:"cdkLib,omitempty"`
+	CLI       string        `json:"cli,omitempty"`
+	Bootstrap int           `json:"bootstrap"` // -1 if not bootstrapped, 0 if unknown
+	Warnings  []string      `json:"warnings"`
+	Checks    []checkResult `json:"checks"`
+}
+
+// secretPlan is the planned change of a secret, by key name.
+type secretPlan struct {
+	Name    string   `json:"name"`
+	Action  string   `json:"action"` // create, update, unchanged, or skip
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Stale   []string `json:"stale,omitempty"` // In the secret but not the env file; kept
+}
+
+// stackPlan is the planned change of a stack, by resource.
+type stackPlan struct {
+	StackName string           `json:"stackName"`
+	Action    string           `json:"action"` // create, update, or unchanged
+	Resources []resourceChange `json:"resources,omitempty"`
+	IAM       []iamChange      `json:"iam,omitempty"`
+	Warnings  []string         `json:"warnings,omitempty"` // Replaced or removed agent runtimes, endpoints, gateways, and secrets
+}
+
+// resourceChange is a resource added, modified, or removed by a stack update.
+type resourceChange struct {
+	LogicalID string `json:"logicalId"`
+	Type      string `json:"type"`
+	Action    string `json:"action"` // add, modify, or remove
+	// Properties are the changed properties of a modified resource.
+	Properties []string `json:"properties,omitempty"`
+	// Replacement is set if a changed property makes CloudFormation replace
+	// the resource.
+	Replacement bool `json:"replacement,omitempty"`
+}
+
+// setPreflight records the preflight results.
+func (p *deployPlan) setPreflight(info versionInfo, warnings []string, checks []checkResult) {
+	if warnings == nil {
+		warnings = []string{}
+	}
+	p.Preflight = &preflightPlan{
+		Tool:      info.tool,
+		Library:   info.library,
+		CDKLib:    info.cdkLib,
+		CLI:       info.cli,
+		Bootstrap: info.bootstrap,
+		Warnings:  warnings,
+		Checks:    checks,
+	}
+}
+
+// write writes the plan as indented JSON.
+func (p *deployPlan) write(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// addSecrets records the results of a dry-run secrets push.
+func (p *deployPlan) addSecrets(results []envsecrets.PushResult) {
+	for _, result := range results {
+		p.Secrets = append(p.Secrets, secretPlan{
+			Name:    result.Name,
+			Action:  result.Action,
+			Added:   result.Diff.Added,
+			Changed: result.Diff.Changed,
+			Stale:   result.Diff.Removed,
+		})
+	}
+}
+
+// cfnTemplate is the subset of a CloudFormation template compared by planStacks.
+type cfnTemplate struct {
+	Resources map[string]map[string]any `json:"Resources"`
+}
+
+// planStacks compares the templates in the cloud assembly with the deployed
+// templates of its stacks. Nested stacks are planned as stacks of their
+// own, named {parent}/{logical ID}, since a change to their resources only
+// shows in the parent as a changed TemplateURL.
+func planStacks(ctx context.Context, cfg aws.Config, dir string) ([]stackPlan, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json")) //nolint:gosec // G304: path is the local cloud assembly
+	if err != nil {
+		return nil, fmt.Errorf("reading cloud assembly: %w", err)
+	}
+	var manifest assemblyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing cloud assembly manifest: %w", err)
+	}
+
+	client := cloudformation.NewFromConfig(cfg)
+	var plans []stackPlan
+	for id, artifact := range manifest.Artifacts {
+		if artifact.Type != stackArtifactType {
+			continue
+		}
+		stackName := artifact.Properties.StackName
+		if stackName == "" {
+			stackName = id
+		}
+
+		var desired cfnTemplate
+		templateData, err := os.ReadFile(filepath.Join(dir, artifact.Properties.TemplateFile)) //nolint:gosec // G304: path is from the local cloud assembly
+		if err != nil {
+			return nil, fmt.Errorf("reading template of %s: %w", stackName, err)
+		}
+		if err := json.Unmarshal(templateData, &desired); err != nil {
+			return nil, fmt.Errorf("parsing template of %s: %w", stackName, err)
+		}
+
+		current, exists, err := deployedTemplate(ctx, client, stackName)
+		if err != nil {
+			return nil, fmt.Errorf("reading deployed template of %s: %w", stackName, err)
+		}
+		plans = append(plans, diffTemplates(stackName, current, desired, exists))
+
+		nested, err := planNestedStacks(ctx, client, dir, stackName, stackName, current, desired, exists)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, nested...)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].StackName < plans[j].StackName })
+	return plans, nil
+}
+
+// planNestedStacks compares the nested stacks of a stack's desired
+// template, read from the cloud assembly, with the deployed templates of
+// the nested stacks of its current template. stackID is the name or ARN of
+// the deployed stack.
+func planNestedStacks(ctx context.Context, client *cloudformation.Client, dir, name, stackID string, current, desired cfnTemplate, exists bool) ([]stackPlan, error) {
+	ids := make(map[string]bool)
+	for _, template := range []cfnTemplate{current, desired} {
+		for id, resource := range template.Resources {
+			if resource["Type"] == nestedStackType {
+				ids[id] = true
+			}
+		}
+	}
+
+	var plans []stackPlan
+	for _, id := range sortedKeys(ids) {
+		nestedName := name + "/" + id
+
+		var want cfnTemplate
+		if resource, ok := desired.Resources[id]; ok && resource["Type"] == nestedStackType {
+			var err error
+			if want, err = readNestedTemplate(dir, resource); err != nil {
+				return nil, fmt.Errorf("reading template of nested stack %s: %w", nestedName, err)
+			}
+		}
+
+		var have cfnTemplate
+		nestedID, found := "", false
+		if resource, ok := current.Resources[id]; exists && ok && resource["Type"] == nestedStackType {
+			var err error
+			if nestedID, err = nestedStackID(ctx, client, stackID, id); err != nil {
+				return nil, fmt.Errorf("finding nested stack %s: %w", nestedName, err)
+			}
+			if nestedID != "" {
+				if have, found, err = deployedTemplate(ctx, client, nestedID); err != nil {
+					return nil, fmt.Errorf("reading deployed template of nested stack %s: %w", nestedName, err)
+				}
+			}
+		}
+
+		plans = append(plans, diffTemplates(nestedName, have, want, found))
+		children, err := planNestedStacks(ctx, client, dir, nestedName, nestedID, have, want, found)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, children...)
+	}
+	return plans, nil
+}
+
+// readNestedTemplate reads the template of a nested stack resource from
+// the cloud assembly, where its aws:asset:path metadata locates it.
+func readNestedTemplate(dir string, resource map[string]any) (cfnTemplate, error) {
+	var template cfnTemplate
+	metadata, _ := resource["Metadata"].(map[string]any)
+	file, ok := metadata["aws:asset:path"].(string)
+	if !ok {
+		return template, errors.New("no aws:asset:path metadata; synthesize with asset metadata enabled")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, file)) //nolint:gosec // G304: path is from the local cloud assembly
+	if err != nil {
+		return template, err
+	}
+	if err := json.Unmarshal(data, &template); err != nil {
+		return template, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	return template, nil
+}
+
+// nestedStackID returns the ARN of the nested stack with a logical ID in
+// a deployed stack, or "" if it doesn't exist.
+func nestedStackID(ctx context.Context, client *cloudformation.Client, stackID, logicalID string) (string, error) {
+	out, err := client.DescribeStackResource(ctx, &cloudformation.DescribeStackResourceInput{
+		StackName:         aws.String(stackID),
+		LogicalResourceId: aws.String(logicalID),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "does not exist") {
+			return "", nil
+		}
+		return "", err
+	}
+	return aws.ToString(out.StackResourceDetail.PhysicalResourceId), nil
+}
+
+// deployedTemplate returns the deployed template of a stack, and false if the
+// stack does not exist.
+func deployedTemplate(ctx context.Context, client *cloudformation.Client, stackName string) (cfnTemplate, bool, error) {
+	var template cfnTemplate
+	out, err := client.GetTemplate(ctx, &cloudformation.GetTemplateInput{
+		StackName:     aws.String(stackName),
+		TemplateStage: cfntypes.TemplateStageOriginal,
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "does not exist") {
+			return template, false, nil
+		}
+		return template, false, err
+	}
+	if err := json.Unmarshal([]byte(aws.ToString(out.TemplateBody)), &template); err != nil {
+		return template, false, fmt.Errorf("parsing template: %w", err)
+	}
+	return template, true, nil
+}
+
+// diffTemplates returns the resource changes between two templates.
+func diffTemplates(stackName string, current, desired cfnTemplate, exists bool) stackPlan {
+	plan := stackPlan{StackName: stackName, Action: actionCreate}
+	if exists {
+		plan.Action = actionUnchanged
+	}
+
+	resourceType := func(resource map[string]any) string {
+		t, _ := resource["Type"].(string)
+		return t
+	}
+	for id, resource := range desired.Resources {
+		old, ok := current.Resources[id]
+		switch {
+		case !ok:
+			plan.Resources = append(plan.Resources, resourceChange{LogicalID: id, Type: resourceType(resource), Action: actionAdd})
+			if isIAMType(resourceType(resource)) {
+				plan.IAM = append(plan.IAM, diffIAM(id, resourceType(resource), actionAdd, nil, resource))
+			}
+		case !reflect.DeepEqual(old, resource):
+			changed := changedProperties(old, resource)
+			plan.Resources = append(plan.Resources, resourceChange{
+				LogicalID:   id,
+				Type:        resourceType(resource),
+				Action:      actionModify,
+				Properties:  changed,
+				Replacement: resourceType(old) != resourceType(resource) || requiresReplacement(resourceType(resource), changed),
+			})
+			if isIAMType(resourceType(resource)) {
+				if change := diffIAM(id, resourceType(resource), actionModify, old, resource); len(change.Added)+len(change.Removed) > 0 {
+					plan.IAM = append(plan.IAM, change)
+				}
+			}
+		}
+	}
+	for id, resource := range current.Resources {
+		if _, ok := desired.Resources[id]; !ok {
+			plan.Resources = append(plan.Resources, resourceChange{LogicalID: id, Type: resourceType(resource), Action: actionRemove})
+			if isIAMType(resourceType(resource)) {
+				plan.IAM = append(plan.IAM, diffIAM(id, resourceType(resource), actionRemove, resource, nil))
+			}
+		}
+	}
+	sort.Slice(plan.Resources, func(i, j int) bool { return plan.Resources[i].LogicalID < plan.Resources[j].LogicalID })
+	sort.Slice(plan.IAM, func(i, j int) bool { return plan.IAM[i].LogicalID < plan.IAM[j].LogicalID })
+	plan.Warnings = destructiveWarnings(plan.Resources)
+
+	if exists && len(plan.Resources) > 0 {
+		plan.Action = actionUpdate
+	}
+	return plan
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequiresReplacement(t *testing.T) {
+	tests := []struct {
+		resourceType string
+		changed      []string
+		want         bool
+	}{
+		{agentRuntimeType, []string{"AgentRuntimeName"}, true},
+		{agentRuntimeType, []string{"EnvironmentVariables", "AgentRuntimeName"}, true},
+		{agentRuntimeType, []string{"EnvironmentVariables"}, false},
+		{agentRuntimeEndpointType, []string{"AgentRuntimeId"}, true},
+		{agentRuntimeEndpointType, []string{"AgentRuntimeVersion"}, false},
+		{"AWS::DynamoDB::Table", []string{"KeySchema"}, true},
+		{"AWS::DynamoDB::Table", []string{"BillingMode"}, false},
+		{"AWS::Unknown::Type", []string{"Name"}, false},
+		{agentRuntimeType, nil, false},
+	}
+	for _, tt := range tests {
+		if got := requiresReplacement(tt.resourceType, tt.changed); got != tt.want {
+			t.Errorf("requiresReplacement(%s, %v) = %v, want %v", tt.resourceType, tt.changed, got, tt.want)
+		}
+	}
+}
+
+// resource returns a template resource of a type with properties.
+func resource(resourceType string, properties map[string]any) map[string]any {
+	return map[string]any{"Type": resourceType, "Properties": properties}
+}
+
+func TestDiffTemplates(t *testing.T) {
+	runtime := resource(agentRuntimeType, map[string]any{"AgentRuntimeName": "research", "Description": "v1"})
+	table := resource("AWS::DynamoDB::Table", map[string]any{"BillingMode": "PAY_PER_REQUEST"})
+
+	tests := []struct {
+		name         string
+		current      cfnTemplate
+		desired      cfnTemplate
+		exists       bool
+		wantAction   string
+		wantChanges  []resourceChange
+		wantWarnings []string
+	}{
+		{
+			name:        "new stack",
+			desired:     cfnTemplate{Resources: map[string]map[string]any{"Runtime": runtime}},
+			wantAction:  actionCreate,
+			wantChanges: []resourceChange{{LogicalID: "Runtime", Type: agentRuntimeType, Action: actionAdd}},
+		},
+		{
+			name:       "unchanged",
+			current:    cfnTemplate{Resources: map[string]map[string]any{"Runtime": runtime, "Table": table}},
+			desired:    cfnTemplate{Resources: map[string]map[string]any{"Runtime": runtime, "Table": table}},
+			exists:     true,
+			wantAction: actionUnchanged,
+		},
+		{
+			name:    "in-place update",
+			current: cfnTemplate{Resources: map[string]map[string]any{"Runtime": runtime}},
+			desired: cfnTemplate{Resources: map[string]map[string]any{
+				"Runtime": resource(agentRuntimeType, map[string]any{"AgentRuntimeName": "research", "Description": "v2"}),
+			}},
+			exists:      true,
+			wantAction:  actionUpdate,
+			wantChanges: []resourceChange{{LogicalID: "Runtime", Type: agentRuntimeType, Action: actionModify, Properties: []string{"Description"}}},
+		},
+		{
+			name:    "replacement",
+			current: cfnTemplate{Resources: map[string]map[string]any{"Runtime": runtime}},
+			desired: cfnTemplate{Resources: map[string]map[string]any{
+				"Runtime": resource(agentRuntimeType, map[string]any{"AgentRuntimeName": "renamed", "Description": "v1"}),
+			}},
+			exists:       true,
+			wantAction:   actionUpdate,
+			wantChanges:  []resourceChange{{LogicalID: "Runtime", Type: agentRuntimeType, Action: actionModify, Properties: []string{"AgentRuntimeName"}, Replacement: true}},
+			wantWarnings: []string{"replaces agent runtime Runtime"},
+		},
+		{
+			name:    "type change",
+			current: cfnTemplate{Resources: map[string]map[string]any{"Queue": resource("AWS::SQS::Queue", nil)}},
+			desired: cfnTemplate{Resources: map[string]map[string]any{"Queue": resource("AWS::SNS::Topic", nil)}},
+			exists:  true,
+			wantChanges: []resourceChange{
+				{LogicalID: "Queue", Type: "AWS::SNS::Topic", Action: actionModify, Replacement: true},
+			},
+			wantAction: actionUpdate,
+		},
+		{
+			name:         "removal",
+			current:      cfnTemplate{Resources: map[string]map[string]any{"Runtime": runtime, "Table": table}},
+			desired:      cfnTemplate{Resources: map[string]map[string]any{"Table": table}},
+			exists:       true,
+			wantAction:   actionUpdate,
+			wantChanges:  []resourceChange{{LogicalID: "Runtime", Type: agentRuntimeType, Action: actionRemove}},
+			wantWarnings: []string{"removes agent runtime Runtime"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := diffTemplates("stack", tt.current, tt.desired, tt.exists)
+			if plan.StackName != "stack" || plan.Action != tt.wantAction {
+				t.Errorf("plan %s %s, want stack %s", plan.StackName, plan.Action, tt.wantAction)
+			}
+			if !reflect.DeepEqual(plan.Resources, tt.wantChanges) {
+				t.Errorf("resources = %+v, want %+v", plan.Resources, tt.wantChanges)
+			}
+			if len(plan.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("warnings = %q, want %q", plan.Warnings, tt.wantWarnings)
+			}
+			for i, want := range tt.wantWarnings {
+				if !strings.HasPrefix(plan.Warnings[i], want) {
+					t.Errorf("warning %d = %q, want it to start with %q", i, plan.Warnings[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanNestedStacksNew(t *testing.T) {
+	// A stack that isn't deployed yet is planned from the assembly alone
+	dir := t.TempDir()
+	files := map[string]string{
+		"partition.nested.template.json": `{"Resources": {
+			"Runtime": {"Type": "AWS::BedrockAgentCore::Runtime"},
+			"Inner": {"Type": "AWS::CloudFormation::Stack", "Metadata": {"aws:asset:path": "inner.nested.template.json"}}
+		}}`,
+		"inner.nested.template.json": `{"Resources": {"Endpoint": {"Type": "AWS::BedrockAgentCore::RuntimeEndpoint"}}}`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	desired := cfnTemplate{Resources: map[string]map[string]any{
+		"Partition": {"Type": nestedStackType, "Metadata": map[string]any{"aws:asset:path": "partition.nested.template.json"}},
+	}}
+
+	plans, err := planNestedStacks(context.Background(), nil, dir, "stack", "", cfnTemplate{}, desired, false)
+	if err != nil {
+		t.Fatalf("planNestedStacks: %v", err)
+	}
+	var got []string
+	for _, plan := range plans {
+		for _, r := range plan.Resources {
+			got = append(got, plan.StackName+" "+plan.Action+" "+r.Action+" "+r.LogicalID)
+		}
+	}
+	want := []string{
+		"stack/Partition create add Inner",
+		"stack/Partition create add Runtime",
+		"stack/Partition/Inner create add Endpoint",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plans = %q, want %q", got, want)
+	}
+}
+
+func TestReadNestedTemplateWithoutMetadata(t *testing.T) {
+	_, err := readNestedTemplate(t.TempDir(), map[string]any{"Type": nestedStackType})
+	if err == nil || !strings.Contains(err.Error(), "aws:asset:path") {
+		t.Errorf("readNestedTemplate error = %v, want missing metadata", err)
+	}
+}
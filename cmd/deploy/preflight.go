@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+const (
+	// libraryModule is this module, as required by the CDK app.
+	libraryModule = "github.com/plexusone/agentkit-aws-cdk"
+
+	// cdkLibModule is the aws-cdk-go module used by the CDK app.
+	cdkLibModule = "github.com/aws/aws-cdk-go/awscdk/v2"
+
+	// defaultBootstrapQualifier is the CDK default bootstrap qualifier.
+	defaultBootstrapQualifier = "hnb659fds"
+
+	// minBootstrapVersion is the bootstrap version required by the
+	// DefaultStackSynthesizer.
+	minBootstrapVersion = 6
+
+	// lookupBootstrapVersion is the bootstrap version required for context
+	// lookups such as importing an existing VPC.
+	lookupBootstrapVersion = 8
+
+	// decoupledCLIMinor is the first cdk CLI minor version released
+	// independently of the CDK library (2.1000.0).
+	decoupledCLIMinor = 1000
+)
+
+// versionInfo holds the versions compared by the preflight check.
+// Empty strings and zero mean the version could not be determined.
+type versionInfo struct {
+	tool      string // this deploy tool
+	library   string // agentkit-aws-cdk used by the CDK app
+	cdkLib    string // aws-cdk-go used by the CDK app
+	cli       string // cdk CLI
+	bootstrap int    // bootstrap stack version; -1 if not bootstrapped
+}
+
+// checkVersions gathers component versions and returns warnings for
+// combinations known to produce confusing synth or deploy errors.
+func checkVersions(ctx context.Context, ssmClient *ssm.Client, skipBootstrap bool) (versionInfo, []string) {
+	info := versionInfo{
+		tool:      toolVersion(),
+		library:   goModuleVersion(ctx, libraryModule),
+		cdkLib:    goModuleVersion(ctx, cdkLibModule),
+		cli:       cdkCLIVersion(ctx),
+		bootstrap: bootstrapVersion(ctx, ssmClient, bootstrapQualifier()),
+	}
+
+	// The cdk CLI itself is checked by checkCDKCLI
+	var warnings []string
+	if info.tool != "" && info.library != "" && info.tool != info.library {
+		warnings = append(warnings, fmt.Sprintf("deploy tool %s differs from agentkit-aws-cdk %s used by the CDK app; install the matching tool with: go install %s/cmd/deploy@%s",
+			info.tool, info.library, libraryModule, info.library))
+	}
+
+	switch {
+	case info.bootstrap < 0 && skipBootstrap:
+		warnings = append(warnings, "environment is not bootstrapped; run the bootstrap step")
+	case info.bootstrap > 0 && info.bootstrap < minBootstrapVersion:
+		warnings = append(warnings, fmt.Sprintf("bootstrap version %d is older than the required %d; re-run cdk bootstrap", info.bootstrap, minBootstrapVersion))
+	case info.bootstrap > 0 && info.bootstrap < lookupBootstrapVersion:
+		warnings = append(warnings, fmt.Sprintf("bootstrap version %d does not support context lookups (e.g. an existing VPC), which need %d; re-run cdk bootstrap", info.bootstrap, lookupBootstrapVersion))
+	}
+
+	return info, warnings
+}
+
+// printVersions prints the detected versions.
+func printVersions(info versionInfo) {
+	unknown := func(v string) string {
+		if v == "" {
+			return "unknown"
+		}
+		return v
+	}
+	logger.Printf("deploy tool:      %s\n", unknown(info.tool))
+	logger.Printf("agentkit-aws-cdk: %s\n", unknown(info.library))
+	logger.Printf("aws-cdk-go:       %s\n", unknown(info.cdkLib))
+	logger.Printf("cdk CLI:          %s\n", unknown(info.cli))
+	switch {
+	case info.bootstrap < 0:
+		logger.Println("bootstrap:        not bootstrapped")
+	case info.bootstrap == 0:
+		logger.Println("bootstrap:        unknown")
+	default:
+		logger.Printf("bootstrap:        %d\n", info.bootstrap)
+	}
+}
+
+// toolVersion returns the module version this tool was built from.
+func toolVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok || bi.Main.Version == "" || bi.Main.Version == "(devel)" {
+		return ""
+	}
+	return bi.Main.Version
+}
+
+// cdkCLIVersion returns the installed cdk CLI version, e.g. "2.1029.2".
+func cdkCLIVersion(ctx context.Context) string {
+	out, err := exec.CommandContext(ctx, "cdk", "--version").Output()
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// goModuleVersion returns the version of a module selected by the CDK app's
+// go.mod in the current directory.
+func goModuleVersion(ctx context.Context, module string) string {
+	//nolint:gosec // G204: module is one of the constant module paths above
+	out, err := exec.CommandContext(ctx, "go", "list", "-m", "-f", "{{.Version}}", module).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// bootstrapQualifier returns the --qualifier bootstrap qualifier, or the
+// synthesizer qualifier of the config file, the one from cdk.json context,
+// or the CDK default.
+func bootstrapQualifier() string {
+	if *qualifier != "" {
+		return *qualifier
+	}
+	if path := agentcore.FindConfigFile(); path != "" {
+		if _, options, err := loadConfigFile(path, *envName); err == nil && options.Synthesizer != nil && options.Synthesizer.Qualifier != "" {
+			return options.Synthesizer.Qualifier
+		}
+	}
+	data, err := os.ReadFile("cdk.json")
+	if err != nil {
+		return defaultBootstrapQualifier
+	}
+	var cdkJSON struct {
+		Context map[string]any `json:"context"`
+	}
+	if json.Unmarshal(data, &cdkJSON) != nil {
+		return defaultBootstrapQualifier
+	}
+	if qualifier, ok := cdkJSON.Context[agentcore.BootstrapQualifierContextKey].(string); ok && qualifier != "" {
+		return qualifier
+	}
+	return defaultBootstrapQualifier
+}
+
+// bootstrapVersion reads the bootstrap stack version from SSM. It returns -1
+// if the environment is not bootstrapped and 0 if the version is unknown.
+func bootstrapVersion(ctx context.Context, client *ssm.Client, qualifier string) int {
+	out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name: aws.String(fmt.Sprintf("/cdk-bootstrap/%s/version", qualifier)),
+	})
+	if err != nil {
+		var notFound *ssmtypes.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return -1
+		}
+		return 0
+	}
+	version, err := strconv.Atoi(aws.ToString(out.Parameter.Value))
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// parseVersion parses "v2.240.0" or "2.1029.2 (build x)" into major, minor, patch.
+func parseVersion(v string) ([3]int, bool) {
+	var parsed [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+// compareVersions returns -1, 0, or 1 as a is older than, equal to, or newer than b.
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
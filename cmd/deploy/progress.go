@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// Progress modes for cdk deploy.
+const (
+	progressRaw    = "raw"
+	progressEvents = "events"
+)
+
+const (
+	// eventPollInterval is how often stack events are polled.
+	eventPollInterval = 5 * time.Second
+
+	// logTailLines is how many lines of cdk output are shown on failure.
+	logTailLines = 30
+)
+
+// eventRenderer polls CloudFormation stack events and prints one status
+// line per event, with per-resource durations and failure reasons.
+type eventRenderer struct {
+	client    *cloudformation.Client
+	stackName string
+	since     time.Time
+	prefix    string // Prepended to each line when deploying stacks concurrently
+
+	seen     map[string]bool
+	started  map[string]time.Time
+	failures []string
+}
+
+// newEventRenderer creates a renderer for events after since.
+func newEventRenderer(client *cloudformation.Client, stackName string, since time.Time) *eventRenderer {
+	return &eventRenderer{
+		client:    client,
+		stackName: stackName,
+		since:     since,
+		seen:      make(map[string]bool),
+		started:   make(map[string]time.Time),
+	}
+}
+
+// poll prints events that arrived since the last poll.
+func (r *eventRenderer) poll(ctx context.Context) error {
+	var events []cfntypes.StackEvent
+
+	paginator := cloudformation.NewDescribeStackEventsPaginator(r.client, &cloudformation.DescribeStackEventsInput{
+		StackName: aws.String(r.stackName),
+	})
+	// Events are returned newest first; stop at the first old or seen one
+	for done := false; !done && paginator.HasMorePages(); {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, event := range page.StackEvents {
+			if aws.ToTime(event.Timestamp).Before(r.since) || r.seen[aws.ToString(event.EventId)] {
+				done = true
+				break
+			}
+			events = append(events, event)
+		}
+	}
+
+	for i := len(events) - 1; i >= 0; i-- {
+		r.render(events[i])
+	}
+	return nil
+}
+
+// render prints a single stack event.
+func (r *eventRenderer) render(event cfntypes.StackEvent) {
+	r.seen[aws.ToString(event.EventId)] = true
+
+	logicalID := aws.ToString(event.LogicalResourceId)
+	status := string(event.ResourceStatus)
+	timestamp := aws.ToTime(event.Timestamp)
+
+	var duration string
+	switch {
+	case strings.HasSuffix(status, "_IN_PROGRESS"):
+		if _, ok := r.started[logicalID]; !ok {
+			r.started[logicalID] = timestamp
+		}
+	case strings.HasSuffix(status, "_COMPLETE"), strings.HasSuffix(status, "_FAILED"):
+		if start, ok := r.started[logicalID]; ok {
+			duration = fmt.Sprintf(" (%s)", timestamp.Sub(start).Round(time.Second))
+			delete(r.started, logicalID)
+		}
+	}
+
+	logger.Printf("  %s%s  %-28s %-40s %s%s\n",
+		r.prefix, timestamp.Local().Format("15:04:05"), status, logicalID, aws.ToString(event.ResourceType), duration)
+
+	reason := aws.ToString(event.ResourceStatusReason)
+	if strings.HasSuffix(status, "_FAILED") && reason != "" {
+		logger.Printf("  %s          reason: %s\n", r.prefix, reason)
+		// Cancellations are a consequence of another resource failing
+		if !strings.Contains(reason, "cancelled") {
+			r.failures = append(r.failures, fmt.Sprintf("%s (%s): %s", logicalID, aws.ToString(event.ResourceType), reason))
+		}
+	}
+}
+
+// deployWithEvents runs cdk deploy with its output captured to a log file,
+// and renders the stack's CloudFormation events instead.
+func deployWithEvents(ctx context.Context, cfg aws.Config, opts deployOptions) error {
+	logFile, err := os.CreateTemp("", "cdk-deploy-*.log")
+	if err != nil {
+		return fmt.Errorf("creating deploy log: %w", err)
+	}
+	defer logFile.Close()
+
+	logger.Printf("Running cdk deploy (output: %s)...\n", logFile.Name())
+	logger.Printf("Streaming events for stack %s\n", opts.stackName)
+
+	renderer := newEventRenderer(cloudformation.NewFromConfig(cfg), opts.stackName, time.Now())
+
+	cmd, err := cdkCommand(ctx, cfg, opts.deployArgs()...)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	deployErr := runWithEvents(ctx, cmd, renderer)
+	if deployErr == nil {
+		return nil
+	}
+
+	if len(renderer.failures) > 0 {
+		logger.Println()
+		logger.Println("Failed resources:")
+		for _, failure := range renderer.failures {
+			logger.Printf("  %s\n", failure)
+		}
+	}
+	logger.Println()
+	logger.Printf("Last lines of cdk output (%s):\n", logFile.Name())
+	printTail(logFile.Name(), logTailLines)
+
+	return markThrottled(deployErr, fileThrottled(logFile.Name()) || anyThrottled(renderer.failures))
+}
+
+// runWithEvents runs cmd to completion, polling the renderer (if any) for
+// stack events while it runs.
+func runWithEvents(ctx context.Context, cmd *exec.Cmd, renderer *eventRenderer) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	if renderer == nil {
+		return <-done
+	}
+
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			_ = renderer.poll(ctx)
+			return err
+		case <-ticker.C:
+			// The stack may not exist yet while cdk synthesizes and publishes assets
+			_ = renderer.poll(ctx)
+		}
+	}
+}
+
+// printTail prints the last n lines of a file.
+func printTail(path string, n int) {
+	file, err := os.Open(path) //nolint:gosec // G304: path is our own temp file
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	for _, line := range lines {
+		logger.Printf("  %s\n", line)
+	}
+}
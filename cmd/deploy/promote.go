@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// promoteTimeout bounds the wait for a promotion or rollback to complete.
+const promoteTimeout = 30 * time.Minute
+
+// parseAgents parses a flag holding a comma-separated list of agent=NAME
+// entries, such as --promote, into agent names.
+func parseAgents(flagName, value string) ([]string, error) {
+	var agents []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, name, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(key) != "agent" || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("--%s entry %q must be agent=NAME", flagName, entry)
+		}
+		agents = append(agents, strings.TrimSpace(name))
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("--%s must name at least one agent, e.g. agent=research", flagName)
+	}
+	return agents, nil
+}
+
+// promoteAgents switches the default endpoints of blue/green agents to the
+// runtime version their candidate endpoints serve. The stacks are updated
+// with their deployed templates, changing only the live version parameters.
+func promoteAgents(ctx context.Context, cfg aws.Config, stackNames, agents []string) error {
+	client := cloudformation.NewFromConfig(cfg)
+
+	deployed := make(map[string]*agentcore.DeployedStack)
+	for _, stackName := range stackNames {
+		stack, err := agentcore.FromStackOutputs(ctx, client, stackName)
+		if err != nil {
+			return err
+		}
+		deployed[stackName] = stack
+	}
+
+	// Group the versions to promote by stack, so each stack updates once
+	versions := make(map[string]map[string]string)
+	switched := make(map[string][]map[string]any)
+	var order []string
+	for _, name := range agents {
+		stackName, agent := findVersionedAgent(deployed, stackNames, name)
+		if agent == nil {
+			return fmt.Errorf("agent %s is not a blue/green or versioned agent of the deployed stacks", name)
+		}
+		if agent.RuntimeVersion == agent.LiveVersion {
+			logger.Printf("  %s: version %s is already live\n", name, agent.LiveVersion)
+			continue
+		}
+		logger.Printf("  %s: promoting version %s (live: %s)\n", name, agent.RuntimeVersion, agent.LiveVersion)
+		if versions[stackName] == nil {
+			versions[stackName] = make(map[string]string)
+			order = append(order, stackName)
+		}
+		versions[stackName][agentcore.LiveVersionParameterName(name)] = agent.RuntimeVersion
+		switched[stackName] = append(switched[stackName], map[string]any{"stack": stackName, "agent": name, "version": agent.RuntimeVersion})
+	}
+
+	for _, stackName := range order {
+		if err := updateLiveVersions(ctx, client, stackName, versions[stackName]); err != nil {
+			return err
+		}
+		for _, fields := range switched[stackName] {
+			logger.Event(eventPromoted, fields)
+		}
+	}
+	return nil
+}
+
+// findVersionedAgent returns the deployed blue/green or versioned agent
+// with the given name and the stack it's in, or nil.
+func findVersionedAgent(deployed map[string]*agentcore.DeployedStack, stackNames []string, name string) (string, *agentcore.DeployedAgent) {
+	for _, stackName := range stackNames {
+		agent := deployed[stackName].Agent(name)
+		if agent != nil && agent.RuntimeVersion != "" && agent.LiveVersion != "" {
+			return stackName, agent
+		}
+	}
+	return "", nil
+}
+
+// updateLiveVersions updates a stack with its deployed template and new
+// values for the given parameters, and waits for the update to complete.
+func updateLiveVersions(ctx context.Context, client *cloudformation.Client, stackName string, values map[string]string) error {
+	described, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return fmt.Errorf("describing %s: %w", stackName, err)
+	}
+	if len(described.Stacks) == 0 {
+		return fmt.Errorf("stack %s not found", stackName)
+	}
+	stack := described.Stacks[0]
+
+	input := &cloudformation.UpdateStackInput{
+		StackName:           aws.String(stackName),
+		UsePreviousTemplate: aws.Bool(true),
+		Capabilities:        stack.Capabilities,
+	}
+	for _, parameter := range stack.Parameters {
+		key := aws.ToString(parameter.ParameterKey)
+		if value, ok := values[key]; ok {
+			input.Parameters = append(input.Parameters, cfntypes.Parameter{
+				ParameterKey:   aws.String(key),
+				ParameterValue: aws.String(value),
+			})
+			continue
+		}
+		input.Parameters = append(input.Parameters, cfntypes.Parameter{
+			ParameterKey:     aws.String(key),
+			UsePreviousValue: aws.Bool(true),
+		})
+	}
+
+	if _, err := client.UpdateStack(ctx, input); err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage(), "No updates are to be performed") {
+			logger.Printf("  %s: already up to date\n", stackName)
+			return nil
+		}
+		return fmt.Errorf("updating %s: %w", stackName, err)
+	}
+
+	logger.Printf("  %s: switching endpoints...\n", stackName)
+	waiter := cloudformation.NewStackUpdateCompleteWaiter(client)
+	if err := waiter.Wait(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)}, promoteTimeout); err != nil {
+		return fmt.Errorf("waiting for update of %s: %w", stackName, err)
+	}
+	logger.Printf("  %s: endpoints switched\n", stackName)
+	return nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+const (
+	// quotaWarnRatio is the share of a quota above which a warning is
+	// printed.
+	quotaWarnRatio = 0.8
+
+	// Service Quotas service codes.
+	agentCoreServiceCode      = "bedrock-agentcore"
+	vpcServiceCode            = "vpc"
+	secretsManagerServiceCode = "secretsmanager"
+)
+
+// Quota increase statuses of the quota-increase event.
+const (
+	quotaIncreaseRequested = "requested"
+	quotaIncreasePending   = "already-pending"
+	quotaIncreaseDryRun    = "dry-run"
+	quotaIncreaseFailed    = "failed"
+)
+
+// serviceQuotaValue is a quota of the Service Quotas API.
+type serviceQuotaValue struct {
+	Name       string  `json:"QuotaName"`
+	Code       string  `json:"QuotaCode"`
+	Value      float64 `json:"Value"`
+	Adjustable bool    `json:"Adjustable"`
+}
+
+// checkRuntimeQuota checks the account's agent runtime quota leaves room
+// for the configured agents that don't exist yet.
+func checkRuntimeQuota(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "runtime quota"}
+	if in.config == nil || len(in.config.Agents) == 0 {
+		result.Status, result.Message = checkSkip, "no agents configured"
+		return result
+	}
+
+	existing, err := listRuntimeNames(ctx, in.cfg)
+	if err != nil {
+		result.Status, result.Message = checkWarn, fmt.Sprintf("could not list runtimes: %v", err)
+		return result
+	}
+	created := 0
+	for _, agent := range in.config.Agents {
+		if !existing[agent.Name] {
+			created++
+		}
+	}
+
+	quota, err := serviceQuota(ctx, in.cfg, agentCoreServiceCode, func(name string) bool {
+		name = strings.ToLower(name)
+		return strings.Contains(name, "runtime") && !strings.Contains(name, "endpoint") &&
+			!strings.Contains(name, "version") && !strings.Contains(name, "session") && !strings.Contains(name, "rate")
+	})
+	summary := fmt.Sprintf("%d runtimes exist, %d to create", len(existing), created)
+	return compareQuota(ctx, in, result, agentCoreServiceCode, quota, err, len(existing)+created, summary)
+}
+
+// checkRuntimeEndpointQuota checks the endpoints quota of a runtime leaves
+// room for the agent with the most endpoints.
+func checkRuntimeEndpointQuota(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "runtime endpoint quota"}
+	if in.config == nil || len(in.config.Agents) == 0 {
+		result.Status, result.Message = checkSkip, "no agents configured"
+		return result
+	}
+
+	counts := in.resourceCounts()
+	agent, needed := "", 0
+	for _, a := range in.config.Agents {
+		if n := counts.RuntimeEndpoints[a.Name]; n > needed {
+			agent, needed = a.Name, n
+		}
+	}
+
+	quota, err := serviceQuota(ctx, in.cfg, agentCoreServiceCode, func(name string) bool {
+		name = strings.ToLower(name)
+		return strings.Contains(name, "endpoint") && !strings.Contains(name, "rate") && !strings.Contains(name, "session")
+	})
+	summary := fmt.Sprintf("agent %s has %d endpoints", agent, needed)
+	return compareQuota(ctx, in, result, agentCoreServiceCode, quota, err, needed, summary)
+}
+
+// checkVPCEndpointQuota checks the interface endpoints quota of a VPC
+// leaves room for the endpoints of a created VPC.
+func checkVPCEndpointQuota(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "VPC endpoint quota"}
+	if in.config == nil {
+		result.Status, result.Message = checkSkip, "no config file"
+		return result
+	}
+	needed := in.resourceCounts().InterfaceEndpoints
+	if needed == 0 {
+		result.Status, result.Message = checkSkip, "no VPC endpoints created"
+		return result
+	}
+
+	quota, err := serviceQuota(ctx, in.cfg, vpcServiceCode, func(name string) bool {
+		name = strings.ToLower(name)
+		return strings.Contains(name, "interface") && strings.Contains(name, "endpoint")
+	})
+	summary := fmt.Sprintf("%d interface endpoints in the created VPC", needed)
+	return compareQuota(ctx, in, result, vpcServiceCode, quota, err, needed, summary)
+}
+
+// checkSecretQuota checks the account's secrets quota leaves room for the
+// stack's secret if it doesn't exist yet.
+func checkSecretQuota(ctx context.Context, in preflightInput) checkResult {
+	result := checkResult{Name: "secret quota"}
+	if in.config == nil || in.resourceCounts().Secrets == 0 {
+		result.Status, result.Message = checkSkip, "no secrets created"
+		return result
+	}
+
+	quota, err := serviceQuota(ctx, in.cfg, secretsManagerServiceCode, func(name string) bool {
+		name = strings.ToLower(name)
+		return strings.Contains(name, "secret") && !strings.Contains(name, "rate") &&
+			!strings.Contains(name, "request") && !strings.Contains(name, "size")
+	})
+	if err != nil || quota.Name == "" {
+		// Listing the secrets is only worth it for a quota to compare with
+		return compareQuota(ctx, in, result, secretsManagerServiceCode, quota, err, 0, "")
+	}
+
+	existing, err := listSecretNames(ctx, in.cfg)
+	if err != nil {
+		result.Status, result.Message = checkWarn, fmt.Sprintf("could not list secrets: %v", err)
+		return result
+	}
+	name := in.config.Secrets.SecretName
+	if name == "" {
+		name = in.config.StackName + "-secrets"
+	}
+	created := 0
+	if !existing[name] {
+		created = 1
+	}
+	summary := fmt.Sprintf("%d secrets exist, %d to create", len(existing), created)
+	return compareQuota(ctx, in, result, secretsManagerServiceCode, quota, err, len(existing)+created, summary)
+}
+
+// resourceCounts returns the quota-limited resources the config creates.
+func (in preflightInput) resourceCounts() agentcore.ResourceCounts {
+	var options agentcore.StackOptions
+	if in.options != nil {
+		options = *in.options
+	}
+	return agentcore.CountResources(*in.config, options)
+}
+
+// compareQuota completes a quota check from the quota, or the error
+// reading it, and the number of resources it must allow. An exceeded quota
+// fails the check, and with --request-quota-increases files an increase
+// request.
+func compareQuota(ctx context.Context, in preflightInput, result checkResult, serviceCode string, quota serviceQuotaValue, err error, needed int, summary string) checkResult {
+	switch {
+	case err != nil:
+		result.Status, result.Message = checkWarn, fmt.Sprintf("could not read the %s quotas: %v", serviceCode, err)
+		return result
+	case quota.Name == "" && summary == "":
+		result.Status, result.Message = checkSkip, fmt.Sprintf("no matching %s quota found", serviceCode)
+		return result
+	case quota.Name == "":
+		result.Status, result.Message = checkSkip, fmt.Sprintf("no matching %s quota found; %s", serviceCode, summary)
+		return result
+	}
+
+	summary += fmt.Sprintf(", quota %q is %.0f", quota.Name, quota.Value)
+	switch {
+	case float64(needed) > quota.Value:
+		result.Status, result.Message = checkFail, summary+"; "+increaseQuota(ctx, in, serviceCode, quota, needed)
+	case float64(needed) > quota.Value*quotaWarnRatio:
+		result.Status, result.Message = checkWarn, summary
+	default:
+		result.Status, result.Message = checkOK, summary
+	}
+	return result
+}
+
+// increaseQuota requests an increase of a quota to desired with
+// --request-quota-increases, and returns what to tell the user.
+func increaseQuota(ctx context.Context, in preflightInput, serviceCode string, quota serviceQuotaValue, desired int) string {
+	switch {
+	case !quota.Adjustable:
+		return "the quota is not adjustable; deploy fewer resources or use another account or region"
+	case in.increaseCfg == nil:
+		return "request an increase in the Service Quotas console, or rerun with --request-quota-increases"
+	}
+
+	event := map[string]any{"service": serviceCode, "quota": quota.Name, "quotaCode": quota.Code, "desiredValue": desired}
+	if in.dryRun {
+		event["status"] = quotaIncreaseDryRun
+		logger.Event(eventQuotaIncrease, event)
+		return fmt.Sprintf("[DRY RUN] would request an increase to %d", desired)
+	}
+
+	data, err := awsapi.Do(ctx, *in.increaseCfg, awsapi.Call{
+		SigningName: "servicequotas",
+		Host:        awsapi.Host(in.increaseCfg.Region, "servicequotas"),
+		Method:      http.MethodPost,
+		Path:        "/",
+		Target:      "ServiceQuotasV20190624.RequestServiceQuotaIncrease",
+		Body:        map[string]any{"ServiceCode": serviceCode, "QuotaCode": quota.Code, "DesiredValue": desired},
+	})
+	var apiErr *awsapi.Error
+	switch {
+	case errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "ResourceAlreadyExistsException"):
+		event["status"] = quotaIncreasePending
+		logger.Event(eventQuotaIncrease, event)
+		return "an increase request is already pending; deploy once it is approved"
+	case err != nil:
+		event["status"], event["error"] = quotaIncreaseFailed, err.Error()
+		logger.Event(eventQuotaIncrease, event)
+		return fmt.Sprintf("requesting an increase to %d failed: %v", desired, err)
+	}
+
+	var resp struct {
+		RequestedQuota struct {
+			ID     string `json:"Id"`
+			Status string `json:"Status"`
+		} `json:"RequestedQuota"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Sprintf("requested an increase to %d, but could not read the response: %v", desired, err)
+	}
+	event["status"], event["requestId"] = quotaIncreaseRequested, resp.RequestedQuota.ID
+	logger.Event(eventQuotaIncrease, event)
+	return fmt.Sprintf("requested an increase to %d (request %s, %s); deploy once it is approved", desired, resp.RequestedQuota.ID, resp.RequestedQuota.Status)
+}
+
+// serviceQuota returns the first quota of a service whose name matches,
+// with the Service Quotas API. The name is "" if no quota matches.
+func serviceQuota(ctx context.Context, cfg aws.Config, serviceCode string, match func(name string) bool) (serviceQuotaValue, error) {
+	token := ""
+	for {
+		body := map[string]any{"ServiceCode": serviceCode, "MaxResults": 100}
+		if token != "" {
+			body["NextToken"] = token
+		}
+		data, err := awsapi.Do(ctx, cfg, awsapi.Call{
+			SigningName: "servicequotas",
+			Host:        awsapi.Host(cfg.Region, "servicequotas"),
+			Method:      http.MethodPost,
+			Path:        "/",
+			Target:      "ServiceQuotasV20190624.ListServiceQuotas",
+			Body:        body,
+		})
+		if err != nil {
+			return serviceQuotaValue{}, err
+		}
+		var page struct {
+			Quotas    []serviceQuotaValue `json:"Quotas"`
+			NextToken string              `json:"NextToken"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return serviceQuotaValue{}, err
+		}
+		for _, quota := range page.Quotas {
+			if match(quota.Name) {
+				return quota, nil
+			}
+		}
+		if page.NextToken == "" {
+			return serviceQuotaValue{}, nil
+		}
+		token = page.NextToken
+	}
+}
+
+// listSecretNames returns the names of the account's secrets in the
+// region.
+func listSecretNames(ctx context.Context, cfg aws.Config) (map[string]bool, error) {
+	names := make(map[string]bool)
+	paginator := secretsmanager.NewListSecretsPaginator(secretsmanager.NewFromConfig(cfg), &secretsmanager.ListSecretsInput{
+		MaxResults: aws.Int32(100),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range page.SecretList {
+			names[aws.ToString(secret.Name)] = true
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// retryBaseDelay is the delay before the first retry of a throttled deploy.
+	retryBaseDelay = 30 * time.Second
+
+	// retryMaxDelay caps the delay between retries.
+	retryMaxDelay = 5 * time.Minute
+
+	// maxDetectorLine bounds the partial line buffered by throttleDetector.
+	maxDetectorLine = 64 * 1024
+)
+
+// throttlePattern matches control-plane rate limit errors in cdk output and
+// CloudFormation event reasons.
+var throttlePattern = regexp.MustCompile(`(?i)\b(?:Throttling(?:Exception)?|Rate exceeded|RequestLimitExceeded|TooManyRequestsException|SlowDown)\b`)
+
+// throttledError marks a deploy failure caused by AWS control-plane throttling.
+type throttledError struct {
+	err error
+}
+
+func (e *throttledError) Error() string { return e.err.Error() + " (throttled)" }
+func (e *throttledError) Unwrap() error { return e.err }
+
+// markThrottled wraps a deploy error as a throttledError if throttled is set.
+func markThrottled(err error, throttled bool) error {
+	if err == nil || !throttled {
+		return err
+	}
+	return &throttledError{err: err}
+}
+
+// retryThrottled runs deploy, retrying up to retries times with jittered
+// exponential backoff while it fails because of throttling. Other failures
+// are returned immediately. Each retry re-runs cdk deploy, which only applies
+// what the failed attempt rolled back.
+func retryThrottled(ctx context.Context, label string, retries int, deploy func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := deploy()
+		var throttled *throttledError
+		if err == nil || !errors.As(err, &throttled) || attempt > retries {
+			return err
+		}
+
+		delay := retryDelay(attempt)
+		logger.Printf("%sThrottled by the AWS control plane; retrying in %s (retry %d of %d)\n", label, delay.Round(time.Second), attempt, retries)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryDelay returns the delay before the given retry.
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	// Half fixed, half random, so concurrent deploys spread out
+	return delay/2 + rand.N(delay/2) //nolint:gosec // G404: jitter does not need a secure source
+}
+
+// throttleDetector is an io.Writer that records whether output mentions throttling.
+type throttleDetector struct {
+	mu        sync.Mutex
+	line      []byte
+	throttled bool
+}
+
+func (d *throttleDetector) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, b := range p {
+		if b != '\n' {
+			if len(d.line) < maxDetectorLine {
+				d.line = append(d.line, b)
+			}
+			continue
+		}
+		if throttlePattern.Match(d.line) {
+			d.throttled = true
+		}
+		d.line = d.line[:0]
+	}
+	return len(p), nil
+}
+
+// Throttled reports whether any output so far mentioned throttling.
+func (d *throttleDetector) Throttled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.throttled || throttlePattern.Match(d.line)
+}
+
+// fileThrottled reports whether a log file mentions throttling.
+func fileThrottled(path string) bool {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: path is our own temp file
+	return err == nil && throttlePattern.Match(data)
+}
+
+// anyThrottled reports whether any of the messages mentions throttling.
+func anyThrottled(messages []string) bool {
+	for _, message := range messages {
+		if throttlePattern.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Phases of the deployment with a role of their own.
+const (
+	// phaseSecrets pushes secrets to Secrets Manager or SSM.
+	phaseSecrets = "secrets"
+
+	// phaseLookup reads the account: preflight checks, synth context
+	// lookups, verification, model access, and the deployment history.
+	phaseLookup = "lookup"
+
+	// phaseDeploy changes the stacks: bootstrap, deploy, smoke tests,
+	// promotion, rollback, image updates, the deploy lock, and quota
+	// increase requests.
+	phaseDeploy = "deploy"
+)
+
+// roleSessionDuration is the lifetime of the credentials of a phase role,
+// the longest an assumed role may assume another.
+const roleSessionDuration = time.Hour
+
+// credentialEnvVars are the environment variables that select the
+// credentials of cdk commands.
+var credentialEnvVars = []string{
+	"AWS_PROFILE",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_WEB_IDENTITY_TOKEN_FILE",
+	"AWS_ROLE_ARN",
+	"AWS_ROLE_SESSION_NAME",
+}
+
+// credentialBroker hands out the AWS config of each phase: the caller's
+// credentials, or those of the phase's role. Separate roles keep any one
+// principal from both writing secrets and deploying stacks.
+type credentialBroker struct {
+	base    aws.Config
+	roles   map[string]string // Role ARN by phase
+	configs map[string]aws.Config
+}
+
+// phaseCredentials are the credentials of a phase role.
+type phaseCredentials struct {
+	aws.CredentialsProvider
+	roleARN string
+}
+
+// newCredentialBroker returns a broker assuming the given role ARNs by
+// phase. {account}, {region}, and {qualifier} in the ARNs are replaced, so
+// roles can be named after the environment like the CDK bootstrap roles.
+func newCredentialBroker(base aws.Config, accountID string, roles map[string]string) *credentialBroker {
+	b := &credentialBroker{base: base, roles: make(map[string]string), configs: make(map[string]aws.Config)}
+	for phase, arn := range roles {
+		if arn == "" {
+			continue
+		}
+		arn = strings.NewReplacer("{account}", accountID, "{region}", base.Region).Replace(arn)
+		if strings.Contains(arn, "{qualifier}") {
+			arn = strings.ReplaceAll(arn, "{qualifier}", bootstrapQualifier())
+		}
+		b.roles[phase] = arn
+	}
+	return b
+}
+
+// validateRoleARN checks a --*-role-arn flag.
+func validateRoleARN(flagName, arn string) error {
+	if arn != "" && !strings.HasPrefix(arn, "arn:") {
+		return fmt.Errorf("--%s %q must be a role ARN", flagName, arn)
+	}
+	return nil
+}
+
+// config returns the AWS config of a phase.
+func (b *credentialBroker) config(phase string) aws.Config {
+	arn, ok := b.roles[phase]
+	if !ok {
+		return b.base
+	}
+	if cfg, ok := b.configs[phase]; ok {
+		return cfg
+	}
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(b.base), arn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "agentkit-deploy-" + phase
+		o.Duration = roleSessionDuration
+	})
+	cfg := b.base.Copy()
+	cfg.Credentials = phaseCredentials{CredentialsProvider: aws.NewCredentialsCache(provider), roleARN: arn}
+	b.configs[phase] = cfg
+	return cfg
+}
+
+// check assumes the role of each phase the run needs, so a role that can't
+// be assumed fails the run before anything is changed.
+func (b *credentialBroker) check(ctx context.Context, phases ...string) error {
+	for _, phase := range phases {
+		arn, ok := b.roles[phase]
+		if !ok {
+			continue
+		}
+		if _, err := b.config(phase).Credentials.Retrieve(ctx); err != nil {
+			return fmt.Errorf("assuming the %s role %s: %w", phase, arn, err)
+		}
+		logger.Printf("%s role: %s\n", phase, arn)
+	}
+	return nil
+}
+
+// cdkCommand returns a cdk command running with the credentials of cfg if
+// they are of a phase role, and with the caller's otherwise.
+func cdkCommand(ctx context.Context, cfg aws.Config, args ...string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, "cdk", args...) //nolint:gosec // G204: callers pass validated flags and local paths
+	creds, ok := cfg.Credentials.(phaseCredentials)
+	if !ok {
+		return cmd, nil
+	}
+	value, err := creds.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("assuming role %s: %w", creds.roleARN, err)
+	}
+
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !isCredentialEnvVar(name) {
+			env = append(env, kv)
+		}
+	}
+	cmd.Env = append(env,
+		"AWS_ACCESS_KEY_ID="+value.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY="+value.SecretAccessKey,
+		"AWS_SESSION_TOKEN="+value.SessionToken,
+		"AWS_REGION="+cfg.Region,
+		"AWS_DEFAULT_REGION="+cfg.Region,
+	)
+	return cmd, nil
+}
+
+// isCredentialEnvVar reports whether name selects the credentials of cdk
+// commands.
+func isCredentialEnvVar(name string) bool {
+	for _, v := range credentialEnvVars {
+		if name == v {
+			return true
+		}
+	}
+	return false
+}
+
+// stepPhases are the phases of the steps.
+var stepPhases = map[string]string{
+	stepPreflight: phaseLookup,
+	stepSecrets:   phaseSecrets,
+	stepBootstrap: phaseDeploy,
+	stepSynth:     phaseLookup,
+	stepDeploy:    phaseDeploy,
+	stepVerify:    phaseLookup,
+}
+
+// runPhases returns the phases of the run: those of an action that exits
+// without deploying, or those of the selected steps and flags.
+func runPhases(selected map[string]bool) []string {
+	switch {
+	case *forceUnlock, *rollbackTo != "", *updateImage != "":
+		return []string{phaseDeploy}
+	case *history, *modelAccess:
+		return []string{phaseLookup}
+	}
+
+	needed := make(map[string]bool)
+	for step := range selected {
+		needed[stepPhases[step]] = true
+	}
+	if *smokeTest || *promote != "" || *lockSpec != "" || *watch || (*requestQuotas && selected[stepPreflight]) {
+		needed[phaseDeploy] = true
+	}
+	var phases []string
+	for _, phase := range []string{phaseLookup, phaseSecrets, phaseDeploy} {
+		if needed[phase] {
+			phases = append(phases, phase)
+		}
+	}
+	return phases
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// parseToVersion parses the --to-version flag, a runtime version number or
+// "latest".
+func parseToVersion(value string) (string, error) {
+	if value == agentcore.LatestVersion {
+		return value, nil
+	}
+	if version, err := strconv.Atoi(value); err != nil || version < 1 {
+		return "", fmt.Errorf("--to-version must be a runtime version number or %s, got %q", agentcore.LatestVersion, value)
+	}
+	return value, nil
+}
+
+// rollbackAgents points the default endpoints of blue/green or versioned
+// agents at an earlier runtime version, or back at the latest version. The
+// stacks are updated with their deployed templates, changing only the live
+// version parameters, so no image is rebuilt or redeployed.
+func rollbackAgents(ctx context.Context, cfg aws.Config, stackNames, agents []string, toVersion string, dryRun bool) error {
+	client := cloudformation.NewFromConfig(cfg)
+
+	deployed := make(map[string]*agentcore.DeployedStack)
+	for _, stackName := range stackNames {
+		stack, err := agentcore.FromStackOutputs(ctx, client, stackName)
+		if err != nil {
+			return err
+		}
+		deployed[stackName] = stack
+	}
+
+	// Group the versions by stack, so each stack updates once
+	versions := make(map[string]map[string]string)
+	switched := make(map[string][]map[string]any)
+	var order []string
+	for _, name := range agents {
+		stackName, agent := findVersionedAgent(deployed, stackNames, name)
+		if agent == nil {
+			return fmt.Errorf("agent %s is not a blue/green or versioned agent of the deployed stacks", name)
+		}
+		version, err := rollbackVersion(name, agent, toVersion)
+		if err != nil {
+			return err
+		}
+		if version == agent.LiveVersion {
+			logger.Printf("  %s: version %s is already live\n", name, version)
+			continue
+		}
+		if dryRun {
+			logger.Printf("  [DRY RUN] %s: would point %s at version %s (live: %s)\n", name, agent.EndpointARN, version, agent.LiveVersion)
+			continue
+		}
+		logger.Printf("  %s: pointing %s at version %s (live: %s)\n", name, agent.EndpointARN, version, agent.LiveVersion)
+		if versions[stackName] == nil {
+			versions[stackName] = make(map[string]string)
+			order = append(order, stackName)
+		}
+		versions[stackName][agentcore.LiveVersionParameterName(name)] = version
+		switched[stackName] = append(switched[stackName], map[string]any{"stack": stackName, "agent": name, "version": version})
+	}
+
+	for _, stackName := range order {
+		if err := updateLiveVersions(ctx, client, stackName, versions[stackName]); err != nil {
+			return err
+		}
+		for _, fields := range switched[stackName] {
+			logger.Event(eventRolledBack, fields)
+		}
+	}
+	return nil
+}
+
+// rollbackVersion returns the live version parameter value that rolls an
+// agent back to toVersion, checking the version is one the agent retains.
+func rollbackVersion(name string, agent *agentcore.DeployedAgent, toVersion string) (string, error) {
+	latest, err := strconv.Atoi(agent.RuntimeVersion)
+	if err != nil {
+		return "", fmt.Errorf("agent %s: invalid runtime version output %q", name, agent.RuntimeVersion)
+	}
+
+	// Only versioned agents can follow the latest version; blue/green
+	// agents are pinned to its number instead
+	if toVersion == agentcore.LatestVersion {
+		if agent.RetainVersions != "" {
+			return agentcore.LatestVersion, nil
+		}
+		return agent.RuntimeVersion, nil
+	}
+
+	var opts *agentcore.VersionsOptions
+	if agent.RetainVersions != "" {
+		retain, err := strconv.Atoi(agent.RetainVersions)
+		if err != nil {
+			return "", fmt.Errorf("agent %s: invalid retained versions output %q", name, agent.RetainVersions)
+		}
+		opts = &agentcore.VersionsOptions{Retain: retain}
+	} else {
+		// Blue/green agents without versions options can use any version
+		opts = &agentcore.VersionsOptions{Retain: latest}
+	}
+
+	retained := agentcore.RetainedVersions(latest, opts)
+	var available []string
+	for _, version := range retained {
+		if strconv.Itoa(version) == toVersion {
+			return toVersion, nil
+		}
+		available = append(available, strconv.Itoa(version))
+	}
+	return "", fmt.Errorf("agent %s: version %s is not retained (available: %s)", name, toVersion, strings.Join(available, ", "))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+// runtimeUpdateFields are the fields of a GetAgentRuntime response that
+// UpdateAgentRuntime accepts; an update replaces all of them.
+var runtimeUpdateFields = []string{
+	"agentRuntimeArtifact",
+	"roleArn",
+	"networkConfiguration",
+	"description",
+	"protocolConfiguration",
+	"environmentVariables",
+	"authorizerConfiguration",
+	"requestHeaderConfiguration",
+	"lifecycleConfiguration",
+}
+
+// Runtime statuses reported by GetAgentRuntime.
+const (
+	runtimeStatusReady        = "READY"
+	runtimeStatusCreateFailed = "CREATE_FAILED"
+	runtimeStatusUpdateFailed = "UPDATE_FAILED"
+)
+
+// runtimeReadyTimeout bounds the wait for an updated runtime to become ready.
+const runtimeReadyTimeout = 10 * time.Minute
+
+// runtimePollInterval is how often the runtime status is polled.
+const runtimePollInterval = 5 * time.Second
+
+// updateRuntimeImage points an agent runtime at a new container image
+// with the AgentCore control API, keeping the rest of its configuration.
+// The update creates a new runtime version.
+func updateRuntimeImage(ctx context.Context, cfg aws.Config, runtimeID, image string) error {
+	path := "/runtimes/" + url.PathEscape(runtimeID) + "/"
+	data, err := controlRequest(ctx, cfg, http.MethodGet, path, nil)
+	if err != nil {
+		return fmt.Errorf("reading runtime %s: %w", runtimeID, err)
+	}
+	var runtime map[string]any
+	if err := json.Unmarshal(data, &runtime); err != nil {
+		return fmt.Errorf("parsing runtime %s: %w", runtimeID, err)
+	}
+
+	update := make(map[string]any)
+	for _, field := range runtimeUpdateFields {
+		if value, ok := runtime[field]; ok && value != nil {
+			update[field] = value
+		}
+	}
+	artifact, _ := update["agentRuntimeArtifact"].(map[string]any)
+	container, _ := artifact["containerConfiguration"].(map[string]any)
+	if container == nil {
+		return fmt.Errorf("runtime %s has no container configuration", runtimeID)
+	}
+	container["containerUri"] = image
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	update["clientToken"] = hex.EncodeToString(token)
+
+	if _, err := controlRequest(ctx, cfg, http.MethodPut, path, update); err != nil {
+		return fmt.Errorf("updating runtime %s: %w", runtimeID, err)
+	}
+	return nil
+}
+
+// waitForRuntimeReady polls an agent runtime until its update completes and
+// returns the runtime version it serves.
+func waitForRuntimeReady(ctx context.Context, cfg aws.Config, runtimeID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, runtimeReadyTimeout)
+	defer cancel()
+
+	path := "/runtimes/" + url.PathEscape(runtimeID) + "/"
+	for {
+		data, err := controlRequest(ctx, cfg, http.MethodGet, path, nil)
+		if err != nil {
+			return "", fmt.Errorf("reading runtime %s: %w", runtimeID, err)
+		}
+		var runtime struct {
+			Status        string `json:"status"`
+			FailureReason string `json:"failureReason"`
+			Version       string `json:"agentRuntimeVersion"`
+		}
+		if err := json.Unmarshal(data, &runtime); err != nil {
+			return "", fmt.Errorf("parsing runtime %s: %w", runtimeID, err)
+		}
+
+		switch runtime.Status {
+		case runtimeStatusReady:
+			return runtime.Version, nil
+		case runtimeStatusCreateFailed, runtimeStatusUpdateFailed:
+			if runtime.FailureReason != "" {
+				return "", fmt.Errorf("runtime %s is %s: %s", runtimeID, runtime.Status, runtime.FailureReason)
+			}
+			return "", fmt.Errorf("runtime %s is %s", runtimeID, runtime.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("waiting for runtime %s (status %s): %w", runtimeID, runtime.Status, ctx.Err())
+		case <-time.After(runtimePollInterval):
+		}
+	}
+}
+
+// controlRequest sends a request to the AgentCore control API and returns
+// the response body.
+func controlRequest(ctx context.Context, cfg aws.Config, method, path string, body any) ([]byte, error) {
+	return awsapi.Do(ctx, cfg, awsapi.Call{
+		SigningName: "bedrock-agentcore",
+		Host:        awsapi.Host(cfg.Region, "bedrock-agentcore-control"),
+		Method:      method,
+		Path:        path,
+		Body:        body,
+	})
+}
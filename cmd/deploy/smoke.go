@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+const (
+	// maxTemplateBody is the largest template UpdateStack accepts inline;
+	// larger templates are uploaded to the CDK bootstrap bucket.
+	maxTemplateBody = 51200
+
+	// bootstrapBucketFormat is the name of the CDK bootstrap assets bucket
+	// by qualifier, account, and region.
+	bootstrapBucketFormat = "cdk-%s-assets-%s-%s"
+
+	// rollbackTimeout bounds the wait for a rollback to complete.
+	rollbackTimeout = 60 * time.Minute
+)
+
+// stackSnapshot is the deployed state of a stack before a deploy, which a
+// rollback restores.
+type stackSnapshot struct {
+	stackName    string
+	template     string
+	parameters   []string
+	capabilities []cfntypes.Capability
+}
+
+// snapshotStacks records the deployed templates of the stacks about to be
+// deployed. Stacks that don't exist yet have no snapshot.
+func snapshotStacks(ctx context.Context, cfg aws.Config, stackNames []string) (map[string]*stackSnapshot, error) {
+	client := cloudformation.NewFromConfig(cfg)
+	snapshots := make(map[string]*stackSnapshot)
+	for _, name := range stackNames {
+		described, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(name)})
+		if err != nil {
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "does not exist") {
+				logger.Printf("  %s: new stack, nothing to roll back to\n", name)
+				continue
+			}
+			return nil, fmt.Errorf("describing %s: %w", name, err)
+		}
+		if len(described.Stacks) == 0 {
+			continue
+		}
+		stack := described.Stacks[0]
+
+		template, err := client.GetTemplate(ctx, &cloudformation.GetTemplateInput{
+			StackName:     aws.String(name),
+			TemplateStage: cfntypes.TemplateStageOriginal,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading template of %s: %w", name, err)
+		}
+
+		snapshot := &stackSnapshot{
+			stackName:    name,
+			template:     aws.ToString(template.TemplateBody),
+			capabilities: stack.Capabilities,
+		}
+		for _, parameter := range stack.Parameters {
+			snapshot.parameters = append(snapshot.parameters, aws.ToString(parameter.ParameterKey))
+		}
+		snapshots[name] = snapshot
+		logger.Printf("  %s: recorded current template\n", name)
+	}
+	return snapshots, nil
+}
+
+// smokeTestStacks invokes the agents of the deployed stacks with their health
+// check payloads, and returns the names of the stacks with failing agents.
+// Health checks are read from the config file in the current or parent
+// directory; agents of other stacks get the default check.
+func smokeTestStacks(ctx context.Context, cfg aws.Config, stackNames []string, envName string) ([]string, error) {
+	var config *agentcore.StackConfig
+	var options *agentcore.StackOptions
+	if path := agentcore.FindConfigFile(); path != "" {
+		var err error
+		if config, options, err = loadConfigFile(path, envName); err != nil {
+			return nil, err
+		}
+	}
+
+	client := cloudformation.NewFromConfig(cfg)
+	var failed []string
+	for _, stackName := range stackNames {
+		deployed, err := agentcore.FromStackOutputs(ctx, client, stackName)
+		if err != nil {
+			return nil, err
+		}
+		logger.Printf("  %s:\n", stackName)
+
+		// Prefer configured names, which output keys may have lost
+		agents := make(map[string]*agentcore.AgentOptions)
+		var names []string
+		if config != nil && config.StackName == stackName {
+			for _, agent := range config.Agents {
+				names = append(names, agent.Name)
+				agents[agent.Name] = options.Agents[agent.Name]
+			}
+		} else {
+			names = deployed.AgentNames()
+		}
+
+		healthy := true
+		for _, name := range names {
+			agent := deployed.Agent(name)
+			if agent == nil || agent.RuntimeARN == "" {
+				logger.Printf("    %s: FAILED: no runtime in the stack outputs\n", name)
+				logger.Event(eventSmokeTest, map[string]any{"stack": stackName, "agent": name, "ok": false, "error": "no runtime in the stack outputs"})
+				healthy = false
+				continue
+			}
+			opts := agents[name]
+			if opts != nil && opts.JWTAuthorizer != nil {
+				// Smoke tests sign with SigV4, which JWT runtimes reject
+				logger.Printf("    %s: skipped (JWT authorizer)\n", name)
+				logger.Event(eventSmokeTest, map[string]any{"stack": stackName, "agent": name, "skipped": true})
+				continue
+			}
+			if opts != nil && opts.HealthCheck != nil && opts.HealthCheck.Skip {
+				logger.Printf("    %s: skipped\n", name)
+				logger.Event(eventSmokeTest, map[string]any{"stack": stackName, "agent": name, "skipped": true})
+				continue
+			}
+
+			result, err := agentcore.RunHealthCheck(ctx, cfg, name, agent.RuntimeARN, opts)
+			if err != nil {
+				logger.Printf("    %s (%s): FAILED: %v\n", name, result.Endpoint, err)
+				logger.Event(eventSmokeTest, map[string]any{"stack": stackName, "agent": name, "endpoint": result.Endpoint, "ok": false, "error": err.Error()})
+				healthy = false
+				continue
+			}
+			logger.Printf("    %s (%s): ok, %d in %s\n", name, result.Endpoint, result.StatusCode, result.Duration.Round(time.Millisecond))
+			logger.Event(eventSmokeTest, map[string]any{
+				"stack":        stackName,
+				"agent":        name,
+				"endpoint":     result.Endpoint,
+				"ok":           true,
+				"statusCode":   result.StatusCode,
+				"milliseconds": result.Duration.Milliseconds(),
+			})
+		}
+		if !healthy {
+			failed = append(failed, stackName)
+		}
+	}
+	return failed, nil
+}
+
+// rollbackStack redeploys the template a stack had before the deploy, with
+// the same parameter values, and waits for the update to complete.
+func rollbackStack(ctx context.Context, cfg aws.Config, accountID string, snapshot *stackSnapshot) error {
+	client := cloudformation.NewFromConfig(cfg)
+
+	capabilities := snapshot.capabilities
+	if len(capabilities) == 0 {
+		capabilities = []cfntypes.Capability{cfntypes.CapabilityCapabilityIam, cfntypes.CapabilityCapabilityNamedIam, cfntypes.CapabilityCapabilityAutoExpand}
+	}
+	input := &cloudformation.UpdateStackInput{
+		StackName:    aws.String(snapshot.stackName),
+		Capabilities: capabilities,
+	}
+	for _, key := range snapshot.parameters {
+		input.Parameters = append(input.Parameters, cfntypes.Parameter{
+			ParameterKey:     aws.String(key),
+			UsePreviousValue: aws.Bool(true),
+		})
+	}
+
+	if len(snapshot.template) <= maxTemplateBody {
+		input.TemplateBody = aws.String(snapshot.template)
+	} else {
+		bucket := fmt.Sprintf(bootstrapBucketFormat, bootstrapQualifier(), accountID, cfg.Region)
+		key := fmt.Sprintf("rollback/%s-%d.json", sanitizeFileName(snapshot.stackName), time.Now().Unix())
+		if _, err := s3.NewFromConfig(cfg).PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader([]byte(snapshot.template)),
+		}); err != nil {
+			return fmt.Errorf("uploading previous template to %s: %w", bucket, err)
+		}
+		input.TemplateURL = aws.String(fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, cfg.Region, key))
+	}
+
+	if _, err := client.UpdateStack(ctx, input); err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage(), "No updates are to be performed") {
+			logger.Printf("  %s: template unchanged by the deploy, nothing to roll back\n", snapshot.stackName)
+			return nil
+		}
+		return fmt.Errorf("updating %s: %w", snapshot.stackName, err)
+	}
+
+	logger.Printf("  %s: rolling back to the previous template...\n", snapshot.stackName)
+	waiter := cloudformation.NewStackUpdateCompleteWaiter(client)
+	if err := waiter.Wait(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(snapshot.stackName)}, rollbackTimeout); err != nil {
+		return fmt.Errorf("waiting for rollback of %s: %w", snapshot.stackName, err)
+	}
+	logger.Printf("  %s: rolled back\n", snapshot.stackName)
+	return nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+	"github.com/plexusone/agentkit-aws-cdk/deploy"
+)
+
+// Deployment steps, in the order they run.
+const (
+	stepPreflight = "preflight"
+	stepSecrets   = "secrets"
+	stepBootstrap = "bootstrap"
+	stepSynth     = "synth"
+	stepDeploy    = "deploy"
+	stepVerify    = "verify"
+)
+
+// DefaultOutputsFile is the default file the deploy step writes stack outputs
+// to and the verify step reads them from.
+const DefaultOutputsFile = "cdk-outputs.json"
+
+// allSteps lists every step in run order.
+var allSteps = []string{stepPreflight, stepSecrets, stepBootstrap, stepSynth, stepDeploy, stepVerify}
+
+// parseSteps returns the set of steps to run: the comma-separated steps
+// (default: all), minus the skipped ones.
+func parseSteps(steps string, skip []string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	names, err := splitSteps(steps)
+	if err != nil {
+		return nil, fmt.Errorf("--steps: %w", err)
+	}
+	if len(names) == 0 {
+		names = allSteps
+	}
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	for _, list := range skip {
+		names, err := splitSteps(list)
+		if err != nil {
+			return nil, fmt.Errorf("--skip-steps: %w", err)
+		}
+		for _, name := range names {
+			delete(selected, name)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no steps selected")
+	}
+	return selected, nil
+}
+
+// splitSteps splits and validates a comma-separated list of step names.
+func splitSteps(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !isStep(name) {
+			return nil, fmt.Errorf("unknown step %q (valid: %s)", name, strings.Join(allSteps, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// isStep reports whether name is a known step.
+func isStep(name string) bool {
+	for _, step := range allSteps {
+		if step == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSteps returns the selected steps in run order.
+func formatSteps(steps map[string]bool) string {
+	var names []string
+	for _, step := range allSteps {
+		if steps[step] {
+			names = append(names, step)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// goModTidy runs go mod tidy so the CDK app builds.
+func goModTidy(ctx context.Context) {
+	logger.Println("Running go mod tidy...")
+	cmd := exec.CommandContext(ctx, "go", "mod", "tidy")
+	cmd.Stdout = logger.Stdout()
+	cmd.Stderr = logger.Stderr()
+	if err := cmd.Run(); err != nil {
+		logger.Warnf("go mod tidy failed: %v", err)
+	}
+}
+
+// synthCDK synthesizes the app with the environment overlay selected, and
+// returns the cloud assembly directory.
+func synthCDK(ctx context.Context, cfg aws.Config, envName string) (string, error) {
+	goModTidy(ctx)
+
+	logger.Println("Running cdk synth...")
+	cmd, err := cdkCommand(ctx, cfg, synthArgs(envName)...)
+	if err != nil {
+		return "", err
+	}
+	cmd.Stdout = logger.Stdout()
+	cmd.Stderr = logger.Stderr()
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("synthesizing: %w", err)
+	}
+
+	dir := assemblyDir()
+	logger.Printf("Cloud assembly: %s\n", dir)
+	logger.Event(eventSynth, map[string]any{"assembly": dir})
+	return dir, nil
+}
+
+// envContextArgs returns the cdk arguments that select the environment
+// overlay read by agentcore.NewStackFromFile.
+func envContextArgs(envName string) []string {
+	if envName == "" {
+		return nil
+	}
+	return []string{"--context", agentcore.EnvironmentContextKey + "=" + envName}
+}
+
+// synthArgs returns the arguments of cdk synth.
+func synthArgs(envName string) []string {
+	args := append([]string{"synth", "--quiet"}, envContextArgs(envName)...)
+	return append(args, qualifierContextArgs()...)
+}
+
+// qualifierContextArgs returns the cdk arguments that synthesize the app
+// with the --qualifier bootstrap qualifier, if set.
+func qualifierContextArgs() []string {
+	if *qualifier == "" {
+		return nil
+	}
+	return []string{"--context", agentcore.BootstrapQualifierContextKey + "=" + *qualifier}
+}
+
+// toolkitStackArgs returns the cdk deploy arguments that select the
+// --toolkit-stack-name toolkit stack, if not the default.
+func toolkitStackArgs() []string {
+	if *toolkitStack == deploy.DefaultToolkitStackName {
+		return nil
+	}
+	return []string{"--toolkit-stack-name", *toolkitStack}
+}
+
+// existingAssembly returns the cloud assembly directory if a previous synth
+// step left one behind, or "".
+func existingAssembly() string {
+	dir := assemblyDir()
+	if _, err := os.Stat(filepath.Join(dir, "manifest.json")); err != nil {
+		return ""
+	}
+	return dir
+}
+
+// readOutputsFile reads a cdk --outputs-file: stack name to output key to value.
+func readOutputsFile(path string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: outputs file path is provided by the user
+	if err != nil {
+		return nil, err
+	}
+	var outputs map[string]map[string]string
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return outputs, nil
+}
+
+// writeOutputsFile writes stack outputs in the cdk --outputs-file format.
+func writeOutputsFile(path string, outputs map[string]map[string]string) error {
+	data, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// emitOutputs emits the outputs event with the stack outputs in the
+// outputs file, if it could be read.
+func emitOutputs(outputsFile string) {
+	outputs, err := readOutputsFile(outputsFile)
+	if err != nil {
+		return
+	}
+	logger.Event(eventOutputs, map[string]any{"file": outputsFile, "stacks": outputs})
+}
+
+// deployedStackNames returns the stacks in the outputs file, or stackName if
+// there is no outputs file.
+func deployedStackNames(outputsFile, stackName string) ([]string, error) {
+	var stackNames []string
+	outputs, err := readOutputsFile(outputsFile)
+	switch {
+	case err == nil:
+		logger.Printf("Reading stacks from: %s\n", outputsFile)
+		for name := range outputs {
+			stackNames = append(stackNames, name)
+		}
+		sort.Strings(stackNames)
+	case errors.Is(err, os.ErrNotExist) && stackName != "":
+		stackNames = []string{stackName}
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("%s not found and no stack name set (run the deploy step first or set --stack)", outputsFile)
+	default:
+		return nil, err
+	}
+	if len(stackNames) == 0 {
+		return nil, fmt.Errorf("no stacks in %s", outputsFile)
+	}
+	return stackNames, nil
+}
+
+// verifyDeployment checks that the stacks in the outputs file (or stackName,
+// if there is no outputs file) finished deploying successfully.
+func verifyDeployment(ctx context.Context, cfg aws.Config, outputsFile, stackName string) error {
+	stackNames, err := deployedStackNames(outputsFile, stackName)
+	if err != nil {
+		return err
+	}
+
+	client := cloudformation.NewFromConfig(cfg)
+	var failed []string
+	for _, name := range stackNames {
+		deployed, err := agentcore.FromStackOutputs(ctx, client, name)
+		if err != nil {
+			logger.Printf("  %s: %v\n", name, err)
+			logger.Event(eventVerify, map[string]any{"stack": name, "ok": false, "error": err.Error()})
+			failed = append(failed, name)
+			continue
+		}
+		if !strings.HasSuffix(deployed.Status, "_COMPLETE") || strings.Contains(deployed.Status, "ROLLBACK") {
+			logger.Printf("  %s: %s\n", name, deployed.Status)
+			logger.Event(eventVerify, map[string]any{"stack": name, "ok": false, "status": deployed.Status})
+			failed = append(failed, name)
+			continue
+		}
+
+		logger.Printf("  %s: %s\n", name, deployed.Status)
+		logger.Event(eventVerify, map[string]any{
+			"stack":      name,
+			"ok":         true,
+			"status":     deployed.Status,
+			"agents":     nonNilNames(deployed.AgentNames()),
+			"gatewayUrl": deployed.GatewayURL,
+		})
+		for _, agent := range deployed.AgentNames() {
+			logger.Printf("    Agent %s: %s\n", agent, deployed.Agents[agent].RuntimeID)
+		}
+		if deployed.GatewayURL != "" {
+			logger.Printf("    Gateway: %s\n", deployed.GatewayURL)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d stacks not healthy: %s", len(failed), len(stackNames), strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// replacementProperties lists, by resource type, the properties whose change
+// makes CloudFormation replace the resource instead of updating it in place.
+// It covers the resource types the stack creates; replacements of other types
+// show as plain modifications.
+var replacementProperties = map[string][]string{
+	agentRuntimeType:                       {"AgentRuntimeName"},
+	agentRuntimeEndpointType:               {"AgentRuntimeId", "Name"},
+	"AWS::BedrockAgentCore::Memory":        {"Name"},
+	agentGatewayType:                       {"Name"},
+	"AWS::BedrockAgentCore::GatewayTarget": {"GatewayIdentifier"},
+	"AWS::IAM::Role":                       {"Path", "RoleName"},
+	"AWS::IAM::ManagedPolicy":              {"ManagedPolicyName", "Path"},
+	"AWS::Logs::LogGroup":                  {"LogGroupName"},
+	"AWS::SecretsManager::Secret":          {"Name"},
+	"AWS::SSM::Parameter":                  {"Name"},
+	"AWS::DynamoDB::Table":                 {"KeySchema", "TableName"},
+	"AWS::S3::Bucket":                      {"BucketName"},
+	"AWS::SQS::Queue":                      {"FifoQueue", "QueueName"},
+	"AWS::SNS::Topic":                      {"FifoTopic", "TopicName"},
+	"AWS::Lambda::Function":                {"FunctionName"},
+	"AWS::KMS::Key":                        {"KeySpec", "KeyUsage"},
+	"AWS::EC2::VPC":                        {"CidrBlock"},
+	"AWS::EC2::Subnet":                     {"AvailabilityZone", "CidrBlock", "VpcId"},
+	"AWS::EC2::SecurityGroup":              {"GroupDescription", "GroupName", "VpcId"},
+	"AWS::ECR::Repository":                 {"RepositoryName"},
+	"AWS::ApiGatewayV2::Api":               {"ProtocolType"},
+	"AWS::CloudWatch::Alarm":               {"AlarmName"},
+	"AWS::Events::Rule":                    {"Name"},
+	"AWS::StepFunctions::StateMachine":     {"StateMachineName"},
+}
+
+// Resource types whose replacement or removal is destructive: agents are
+// invoked with the ARNs of runtimes and endpoints, MCP clients use the
+// gateway URL, and secret values are not carried over.
+const (
+	agentRuntimeType         = "AWS::BedrockAgentCore::Runtime"
+	agentRuntimeEndpointType = "AWS::BedrockAgentCore::RuntimeEndpoint"
+	agentGatewayType         = "AWS::BedrockAgentCore::Gateway"
+	secretType               = "AWS::SecretsManager::Secret"
+)
+
+// changedProperties returns the sorted names of the properties that differ
+// between two versions of a resource.
+func changedProperties(old, resource map[string]any) []string {
+	oldProps, _ := old["Properties"].(map[string]any)
+	newProps, _ := resource["Properties"].(map[string]any)
+	seen := make(map[string]bool)
+	var changed []string
+	for name, value := range newProps {
+		seen[name] = true
+		if !reflect.DeepEqual(oldProps[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldProps {
+		if !seen[name] {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// requiresReplacement reports whether changing the given properties of a
+// resource of the given type replaces it.
+func requiresReplacement(resourceType string, changed []string) bool {
+	for _, name := range replacementProperties[resourceType] {
+		for _, property := range changed {
+			if property == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// iamChange is the change of the statements an IAM resource grants.
+type iamChange struct {
+	LogicalID string   `json:"logicalId"`
+	Type      string   `json:"type"`
+	Action    string   `json:"action"` // add, modify, or remove
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+}
+
+// isIAMType reports whether a resource type grants permissions.
+func isIAMType(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "AWS::IAM::") || strings.HasSuffix(resourceType, "::Permission")
+}
+
+// diffIAM returns the statements granted by the desired version of an IAM
+// resource but not the current one, and the other way around. Either version
+// may be nil.
+func diffIAM(id, resourceType, action string, current, desired map[string]any) iamChange {
+	before, after := iamGrants(current), iamGrants(desired)
+	change := iamChange{LogicalID: id, Type: resourceType, Action: action}
+	for grant := range after {
+		if !before[grant] {
+			change.Added = append(change.Added, grant)
+		}
+	}
+	for grant := range before {
+		if !after[grant] {
+			change.Removed = append(change.Removed, grant)
+		}
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	return change
+}
+
+// iamGrants returns the statements of an IAM resource, one line per action,
+// e.g. "Allow s3:GetObject on arn:aws:s3:::bucket/*". Trust policy
+// statements are prefixed with "trust:", managed policies with
+// "managed policy".
+func iamGrants(resource map[string]any) map[string]bool {
+	grants := make(map[string]bool)
+	if resource == nil {
+		return grants
+	}
+	props, _ := resource["Properties"].(map[string]any)
+	addStatements := func(prefix string, document any) {
+		doc, _ := document.(map[string]any)
+		statements, ok := doc["Statement"].([]any)
+		if !ok && doc["Statement"] != nil {
+			statements = []any{doc["Statement"]}
+		}
+		for _, s := range statements {
+			statement, _ := s.(map[string]any)
+			effect, _ := statement["Effect"].(string)
+			target := "on " + renderValues(statement["Resource"])
+			if principal, ok := statement["Principal"]; ok {
+				target = "by " + renderValues(principal)
+			}
+			for _, action := range renderList(statement["Action"]) {
+				grants[fmt.Sprintf("%s%s %s %s", prefix, effect, action, target)] = true
+			}
+		}
+	}
+
+	addStatements("", props["PolicyDocument"])
+	addStatements("trust: ", props["AssumeRolePolicyDocument"])
+	policies, _ := props["Policies"].([]any)
+	for _, p := range policies {
+		policy, _ := p.(map[string]any)
+		addStatements("", policy["PolicyDocument"])
+	}
+	for _, arn := range renderList(props["ManagedPolicyArns"]) {
+		grants["managed policy "+arn] = true
+	}
+	if action, ok := props["Action"]; ok { // Resource-based permissions, e.g. AWS::Lambda::Permission
+		grants[fmt.Sprintf("Allow %s to %s", renderValues(props["Principal"]), renderValues(action))] = true
+	}
+	return grants
+}
+
+// renderList renders a string or list template value as a list of strings.
+func renderList(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, renderValue(item))
+		}
+		return items
+	default:
+		return []string{renderValue(v)}
+	}
+}
+
+// renderValues renders a string or list template value on one line.
+func renderValues(value any) string {
+	if value == nil {
+		return "*"
+	}
+	return strings.Join(renderList(value), ", ")
+}
+
+// renderValue renders a template value: strings as they are, intrinsic
+// functions such as {"Ref": "Bucket"} as compact JSON.
+func renderValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// destructiveWarnings returns warnings for replaced or removed agent
+// runtimes, endpoints, gateways, and secrets: their ARNs or URLs change,
+// breaking callers that hold them, and secret values are lost.
+func destructiveWarnings(resources []resourceChange) []string {
+	var warnings []string
+	for _, r := range resources {
+		var verb string
+		switch {
+		case r.Action == actionRemove:
+			verb = "removes"
+		case r.Replacement:
+			verb = "replaces"
+		default:
+			continue
+		}
+		switch r.Type {
+		case agentRuntimeType:
+			warnings = append(warnings, fmt.Sprintf("%s agent runtime %s: its ARN changes and its endpoints are recreated", verb, r.LogicalID))
+		case agentRuntimeEndpointType:
+			warnings = append(warnings, fmt.Sprintf("%s agent endpoint %s: callers invoking it by ARN fail until updated", verb, r.LogicalID))
+		case agentGatewayType:
+			warnings = append(warnings, fmt.Sprintf("%s gateway %s: its URL changes and MCP clients must be reconfigured", verb, r.LogicalID))
+		case secretType:
+			warnings = append(warnings, fmt.Sprintf("%s secret %s: its value is lost and must be pushed again", verb, r.LogicalID))
+		}
+	}
+	return warnings
+}
+
+// printDiffSummary prints a summary of the stack changes of a dry run for
+// reviewers: counts of added, replaced, modified, and removed resources,
+// destructive changes, and IAM changes.
+func printDiffSummary(stacks []stackPlan) {
+	for _, plan := range stacks {
+		logger.Printf("Stack %s: %s\n", plan.StackName, plan.Action)
+		if plan.Action == actionUnchanged {
+			continue
+		}
+
+		counts := make(map[string]int)
+		for _, r := range plan.Resources {
+			if r.Replacement {
+				counts["replace"]++
+			} else {
+				counts[r.Action]++
+			}
+		}
+		logger.Printf("  %d added, %d replaced, %d modified, %d removed\n",
+			counts[actionAdd], counts["replace"], counts[actionModify], counts[actionRemove])
+
+		for _, warning := range plan.Warnings {
+			logger.Printf("  DESTRUCTIVE: %s\n", warning)
+		}
+
+		if plan.Action == actionUpdate {
+			for _, r := range plan.Resources {
+				symbol := map[string]string{actionAdd: "+", actionModify: "~", actionRemove: "-"}[r.Action]
+				detail := ""
+				if r.Replacement {
+					symbol = "!"
+					detail = " (replace: " + strings.Join(r.Properties, ", ") + ")"
+				} else if len(r.Properties) > 0 {
+					detail = " (" + strings.Join(r.Properties, ", ") + ")"
+				}
+				logger.Printf("  %s %s %s%s\n", symbol, r.LogicalID, r.Type, detail)
+			}
+		}
+
+		if len(plan.IAM) > 0 {
+			logger.Println("  IAM changes:")
+			for _, change := range plan.IAM {
+				logger.Printf("    %s %s (%s)\n", change.LogicalID, change.Type, change.Action)
+				for _, grant := range change.Added {
+					logger.Printf("      + %s\n", grant)
+				}
+				for _, grant := range change.Removed {
+					logger.Printf("      - %s\n", grant)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// watchInterval is how often --watch polls the watched files.
+const watchInterval = time.Second
+
+// watchPatterns are the files --watch redeploys on, in the current
+// directory and the config file's directory.
+var watchPatterns = []string{"config*.json", "config*.yaml", "config*.yml", "*.go", "Dockerfile"}
+
+// fileState is the modification time and size of a watched file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchedFiles returns the state of the files --watch redeploys on.
+func watchedFiles() map[string]fileState {
+	dirs := []string{"."}
+	if path := agentcore.FindConfigFile(); path != "" && filepath.Dir(path) != "." {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+
+	files := make(map[string]fileState)
+	for _, dir := range dirs {
+		for _, pattern := range watchPatterns {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, match := range matches {
+				if strings.HasSuffix(match, "_test.go") {
+					continue
+				}
+				if info, err := os.Stat(match); err == nil && !info.IsDir() {
+					files[match] = fileState{modTime: info.ModTime(), size: info.Size()}
+				}
+			}
+		}
+	}
+	return files
+}
+
+// changedFiles returns the files added, changed, or removed between two
+// states, sorted.
+func changedFiles(before, after map[string]fileState) []string {
+	var changed []string
+	for path, state := range after {
+		if previous, ok := before[path]; !ok || previous != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// watchDeploy redeploys whenever a watched file changes, until interrupted.
+// Changes to container image tags alone are applied with the AgentCore API;
+// other changes run cdk deploy --hotswap-fallback.
+func watchDeploy(ctx context.Context, cfg aws.Config, opts deployOptions) error {
+	// Each change is synthesized anew, one stack at a time
+	opts.app = ""
+	opts.concurrency = 1
+	opts.hotswap = true
+
+	configPath := agentcore.FindConfigFile()
+	var config *agentcore.StackConfig
+	var options *agentcore.StackOptions
+	if configPath != "" {
+		var err error
+		if config, options, err = loadConfigFile(configPath, opts.envName); err != nil {
+			return err
+		}
+	}
+
+	files := watchedFiles()
+	logger.Printf("Watching %d files for changes (Ctrl-C to stop)...\n", len(files))
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Println("Stopped watching")
+			return nil
+		case <-time.After(watchInterval):
+		}
+
+		current := watchedFiles()
+		changed := changedFiles(files, current)
+		if len(changed) == 0 {
+			continue
+		}
+		// Editors save in several writes; wait for the files to settle
+		for {
+			time.Sleep(watchInterval)
+			settled := watchedFiles()
+			if len(changedFiles(current, settled)) == 0 {
+				break
+			}
+			current = settled
+		}
+		changed = changedFiles(files, current)
+		files = current
+
+		logger.Println()
+		logger.Printf("=== %s: %s changed ===\n", time.Now().Format("15:04:05"), strings.Join(changed, ", "))
+
+		var newConfig *agentcore.StackConfig
+		var newOptions *agentcore.StackOptions
+		if configPath != "" {
+			var err error
+			if newConfig, newOptions, err = loadConfigFile(configPath, opts.envName); err != nil {
+				logger.Errorf("%v", err)
+				continue
+			}
+		}
+
+		images := imageOnlyChanges(changed, configPath, config, newConfig, options, newOptions)
+		if len(images) > 0 {
+			err := updateAgentImages(ctx, cfg, []string{newConfig.StackName}, images)
+			if err == nil {
+				config, options = newConfig, newOptions
+				continue
+			}
+			logger.Warnf("direct image update failed: %v", err)
+			logger.Println("Falling back to cdk deploy...")
+		}
+
+		deploy := opts
+		if err := approveDestructiveChanges(ctx, cfg, &deploy, nil); err != nil {
+			logger.Errorf("%v", err)
+			continue
+		}
+		if err := deployCDK(ctx, cfg, deploy); err != nil {
+			logger.Errorf("deploying: %v", err)
+			continue
+		}
+		config, options = newConfig, newOptions
+		logger.Println("Deployed; watching for changes...")
+	}
+}
+
+// imageOnlyChanges returns the new container images by agent name if the
+// only change is to the config file and changes nothing but agent images,
+// otherwise nil.
+func imageOnlyChanges(changed []string, configPath string, before, after *agentcore.StackConfig, beforeOptions, afterOptions *agentcore.StackOptions) map[string]string {
+	if configPath == "" || before == nil || after == nil {
+		return nil
+	}
+	for _, path := range changed {
+		if !strings.HasPrefix(filepath.Base(path), "config") || filepath.Ext(path) == ".go" {
+			return nil
+		}
+	}
+	if !reflect.DeepEqual(beforeOptions, afterOptions) || len(before.Agents) != len(after.Agents) {
+		return nil
+	}
+
+	images := make(map[string]string)
+	stripped := *after
+	stripped.Agents = make([]agentcore.AgentConfig, len(after.Agents))
+	for i, agent := range after.Agents {
+		if agent.Name != before.Agents[i].Name {
+			return nil
+		}
+		if agent.ContainerImage != before.Agents[i].ContainerImage {
+			images[agent.Name] = agent.ContainerImage
+			agent.ContainerImage = before.Agents[i].ContainerImage
+		}
+		stripped.Agents[i] = agent
+	}
+	if len(images) == 0 || !reflect.DeepEqual(*before, stripped) {
+		return nil
+	}
+	return images
+}
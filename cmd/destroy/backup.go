@@ -0,0 +1,500 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// runtimeLogGroupPrefix is the prefix of the log groups AgentCore creates
+// for each runtime, followed by the runtime ID.
+const runtimeLogGroupPrefix = "/aws/bedrock-agentcore/runtimes/"
+
+// backupPlan lists the data of a stack that is exported before it is destroyed.
+type backupPlan struct {
+	stackName string
+	stack     *agentcore.DeployedStack
+	resources []cfntypes.StackResourceSummary
+
+	logGroups []string // Log group names
+	secrets   []string // Secret ARNs or names
+	tables    []string // DynamoDB table names
+	buckets   []string // S3 bucket names
+}
+
+// secretMetadata is the exported metadata of a secret. Secret values are
+// never exported.
+type secretMetadata struct {
+	Name            string              `json:"name"`
+	ARN             string              `json:"arn"`
+	Description     string              `json:"description,omitempty"`
+	KmsKeyID        string              `json:"kmsKeyId,omitempty"`
+	RotationEnabled bool                `json:"rotationEnabled"`
+	CreatedDate     *time.Time          `json:"createdDate,omitempty"`
+	LastChangedDate *time.Time          `json:"lastChangedDate,omitempty"`
+	VersionStages   map[string][]string `json:"versionStages,omitempty"`
+	Tags            map[string]string   `json:"tags,omitempty"`
+}
+
+// planBackup finds the log groups, secrets, DynamoDB tables, and S3 buckets
+// that belong to a stack. Secrets under secretPrefix (created by deploy or
+// push-secrets) are included even though they are not stack resources.
+func planBackup(ctx context.Context, cfg aws.Config, stackName, secretPrefix string) (*backupPlan, error) {
+	cfnClient := cloudformation.NewFromConfig(cfg)
+
+	stack, err := agentcore.FromStackOutputs(ctx, cfnClient, stackName)
+	if err != nil {
+		return nil, err
+	}
+	plan := &backupPlan{stackName: stackName, stack: stack}
+
+	paginator := cloudformation.NewListStackResourcesPaginator(cfnClient, &cloudformation.ListStackResourcesInput{
+		StackName: aws.String(stackName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing stack resources: %w", err)
+		}
+		plan.resources = append(plan.resources, page.StackResourceSummaries...)
+	}
+
+	for _, resource := range plan.resources {
+		physicalID := aws.ToString(resource.PhysicalResourceId)
+		if physicalID == "" || resource.ResourceStatus == cfntypes.ResourceStatusDeleteComplete {
+			continue
+		}
+		switch aws.ToString(resource.ResourceType) {
+		case "AWS::Logs::LogGroup":
+			plan.logGroups = append(plan.logGroups, physicalID)
+		case "AWS::SecretsManager::Secret":
+			plan.secrets = append(plan.secrets, physicalID)
+		case "AWS::DynamoDB::Table":
+			plan.tables = append(plan.tables, physicalID)
+		case "AWS::S3::Bucket":
+			plan.buckets = append(plan.buckets, physicalID)
+		}
+	}
+
+	// Runtime log groups are created by AgentCore, not by the stack
+	logsClient := cloudwatchlogs.NewFromConfig(cfg)
+	for _, agent := range stack.Agents {
+		if agent.RuntimeID == "" {
+			continue
+		}
+		groups := cloudwatchlogs.NewDescribeLogGroupsPaginator(logsClient, &cloudwatchlogs.DescribeLogGroupsInput{
+			LogGroupNamePrefix: aws.String(runtimeLogGroupPrefix + agent.RuntimeID),
+		})
+		for groups.HasMorePages() {
+			page, err := groups.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("listing runtime log groups: %w", err)
+			}
+			for _, group := range page.LogGroups {
+				plan.logGroups = append(plan.logGroups, aws.ToString(group.LogGroupName))
+			}
+		}
+	}
+
+	if secretPrefix != "" {
+		smClient := secretsmanager.NewFromConfig(cfg)
+		secrets := secretsmanager.NewListSecretsPaginator(smClient, &secretsmanager.ListSecretsInput{
+			Filters: []smtypes.Filter{{
+				Key:    smtypes.FilterNameStringTypeName,
+				Values: []string{secretPrefix + "/"},
+			}},
+		})
+		for secrets.HasMorePages() {
+			page, err := secrets.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("listing secrets: %w", err)
+			}
+			for _, secret := range page.SecretList {
+				plan.secrets = append(plan.secrets, aws.ToString(secret.ARN))
+			}
+		}
+	}
+
+	plan.logGroups = uniqueSorted(plan.logGroups)
+	plan.secrets = uniqueSorted(plan.secrets)
+	sort.Strings(plan.tables)
+	sort.Strings(plan.buckets)
+	return plan, nil
+}
+
+// print prints what the backup will export.
+func (p *backupPlan) print() {
+	fmt.Printf("  Stack:      %s (%d resources, %d outputs)\n", p.stackName, len(p.resources), len(p.stack.Outputs))
+	printList("Log groups", p.logGroups)
+	printList("Secrets", p.secrets)
+	printList("Tables", p.tables)
+	printList("Buckets", p.buckets)
+}
+
+// run exports the planned data to dir.
+func (p *backupPlan) run(ctx context.Context, cfg aws.Config, dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating backup directory: %w", err)
+	}
+
+	if err := p.exportStack(ctx, cloudformation.NewFromConfig(cfg), dir); err != nil {
+		return err
+	}
+
+	logsClient := cloudwatchlogs.NewFromConfig(cfg)
+	for _, group := range p.logGroups {
+		count, err := exportLogGroup(ctx, logsClient, group, filepath.Join(dir, "logs", sanitizeFileName(group)+".jsonl"))
+		if err != nil {
+			return fmt.Errorf("exporting log group %s: %w", group, err)
+		}
+		fmt.Printf("  Exported %d log events from %s\n", count, group)
+	}
+
+	if len(p.secrets) > 0 {
+		if err := exportSecretMetadata(ctx, secretsmanager.NewFromConfig(cfg), p.secrets, filepath.Join(dir, "secrets.json")); err != nil {
+			return err
+		}
+		fmt.Printf("  Exported metadata of %d secrets (values are not exported)\n", len(p.secrets))
+	}
+
+	ddbClient := dynamodb.NewFromConfig(cfg)
+	for _, table := range p.tables {
+		count, err := exportTable(ctx, ddbClient, table, filepath.Join(dir, "dynamodb", sanitizeFileName(table)+".jsonl"))
+		if err != nil {
+			return fmt.Errorf("exporting table %s: %w", table, err)
+		}
+		fmt.Printf("  Exported %d items from table %s\n", count, table)
+	}
+
+	s3Client := s3.NewFromConfig(cfg)
+	for _, bucket := range p.buckets {
+		count, err := exportBucket(ctx, s3Client, bucket, filepath.Join(dir, "s3", sanitizeFileName(bucket)))
+		if err != nil {
+			return fmt.Errorf("exporting bucket %s: %w", bucket, err)
+		}
+		fmt.Printf("  Exported %d objects from bucket %s\n", count, bucket)
+	}
+
+	return nil
+}
+
+// exportStack writes the stack outputs, resources, and template.
+func (p *backupPlan) exportStack(ctx context.Context, client *cloudformation.Client, dir string) error {
+	template, err := client.GetTemplate(ctx, &cloudformation.GetTemplateInput{
+		StackName:     aws.String(p.stackName),
+		TemplateStage: cfntypes.TemplateStageOriginal,
+	})
+	if err != nil {
+		return fmt.Errorf("getting stack template: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "template.json"), []byte(aws.ToString(template.TemplateBody)), 0o600); err != nil {
+		return fmt.Errorf("writing template: %w", err)
+	}
+
+	type resource struct {
+		LogicalID  string `json:"logicalId"`
+		PhysicalID string `json:"physicalId"`
+		Type       string `json:"type"`
+		Status     string `json:"status"`
+	}
+	stack := struct {
+		StackName string            `json:"stackName"`
+		StackID   string            `json:"stackId"`
+		Status    string            `json:"status"`
+		Outputs   map[string]string `json:"outputs"`
+		Resources []resource        `json:"resources"`
+		BackedUp  time.Time         `json:"backedUp"`
+	}{
+		StackName: p.stack.StackName,
+		StackID:   p.stack.StackID,
+		Status:    p.stack.Status,
+		Outputs:   p.stack.Outputs,
+		BackedUp:  time.Now().UTC(),
+	}
+	for _, r := range p.resources {
+		stack.Resources = append(stack.Resources, resource{
+			LogicalID:  aws.ToString(r.LogicalResourceId),
+			PhysicalID: aws.ToString(r.PhysicalResourceId),
+			Type:       aws.ToString(r.ResourceType),
+			Status:     string(r.ResourceStatus),
+		})
+	}
+	return writeJSON(filepath.Join(dir, "stack.json"), stack)
+}
+
+// exportLogGroup writes all events of a log group as JSON lines.
+func exportLogGroup(ctx context.Context, client *cloudwatchlogs.Client, group, path string) (int, error) {
+	return writeJSONLines(path, func(emit func(any) error) error {
+		paginator := cloudwatchlogs.NewFilterLogEventsPaginator(client, &cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName: aws.String(group),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			for _, event := range page.Events {
+				if err := emit(map[string]any{
+					"timestamp": time.UnixMilli(aws.ToInt64(event.Timestamp)).UTC(),
+					"logStream": aws.ToString(event.LogStreamName),
+					"message":   aws.ToString(event.Message),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// exportSecretMetadata writes the metadata of each secret.
+func exportSecretMetadata(ctx context.Context, client *secretsmanager.Client, secretIDs []string, path string) error {
+	var secrets []secretMetadata
+	for _, id := range secretIDs {
+		out, err := client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(id),
+		})
+		if err != nil {
+			return fmt.Errorf("describing secret %s: %w", id, err)
+		}
+		secret := secretMetadata{
+			Name:            aws.ToString(out.Name),
+			ARN:             aws.ToString(out.ARN),
+			Description:     aws.ToString(out.Description),
+			KmsKeyID:        aws.ToString(out.KmsKeyId),
+			RotationEnabled: aws.ToBool(out.RotationEnabled),
+			CreatedDate:     out.CreatedDate,
+			LastChangedDate: out.LastChangedDate,
+			VersionStages:   out.VersionIdsToStages,
+			Tags:            make(map[string]string, len(out.Tags)),
+		}
+		for _, tag := range out.Tags {
+			secret.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		secrets = append(secrets, secret)
+	}
+	return writeJSON(path, secrets)
+}
+
+// exportTable writes all items of a DynamoDB table as JSON lines in DynamoDB
+// JSON format, which preserves attribute types for re-import.
+func exportTable(ctx context.Context, client *dynamodb.Client, table, path string) (int, error) {
+	return writeJSONLines(path, func(emit func(any) error) error {
+		paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+			TableName:      aws.String(table),
+			ConsistentRead: aws.Bool(true),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			for _, item := range page.Items {
+				if err := emit(dynamoJSONItem(item)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// dynamoJSONItem converts an item to DynamoDB JSON, e.g. {"id": {"S": "a"}}.
+func dynamoJSONItem(item map[string]ddbtypes.AttributeValue) map[string]any {
+	out := make(map[string]any, len(item))
+	for name, value := range item {
+		out[name] = dynamoJSONValue(value)
+	}
+	return out
+}
+
+// dynamoJSONValue converts an attribute value to DynamoDB JSON.
+func dynamoJSONValue(value ddbtypes.AttributeValue) map[string]any {
+	switch v := value.(type) {
+	case *ddbtypes.AttributeValueMemberS:
+		return map[string]any{"S": v.Value}
+	case *ddbtypes.AttributeValueMemberN:
+		return map[string]any{"N": v.Value}
+	case *ddbtypes.AttributeValueMemberB:
+		return map[string]any{"B": base64.StdEncoding.EncodeToString(v.Value)}
+	case *ddbtypes.AttributeValueMemberBOOL:
+		return map[string]any{"BOOL": v.Value}
+	case *ddbtypes.AttributeValueMemberNULL:
+		return map[string]any{"NULL": v.Value}
+	case *ddbtypes.AttributeValueMemberSS:
+		return map[string]any{"SS": v.Value}
+	case *ddbtypes.AttributeValueMemberNS:
+		return map[string]any{"NS": v.Value}
+	case *ddbtypes.AttributeValueMemberBS:
+		encoded := make([]string, len(v.Value))
+		for i, b := range v.Value {
+			encoded[i] = base64.StdEncoding.EncodeToString(b)
+		}
+		return map[string]any{"BS": encoded}
+	case *ddbtypes.AttributeValueMemberL:
+		list := make([]any, len(v.Value))
+		for i, element := range v.Value {
+			list[i] = dynamoJSONValue(element)
+		}
+		return map[string]any{"L": list}
+	case *ddbtypes.AttributeValueMemberM:
+		return map[string]any{"M": dynamoJSONItem(v.Value)}
+	default:
+		return map[string]any{}
+	}
+}
+
+// exportBucket downloads all objects of a bucket into dir, keeping key paths.
+func exportBucket(ctx context.Context, client *s3.Client, bucket, dir string) (int, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return count, err
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if strings.HasSuffix(key, "/") {
+				continue // Folder placeholder
+			}
+			path := filepath.Join(root, filepath.FromSlash(key))
+			if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+				return count, fmt.Errorf("object key %q escapes the backup directory", key)
+			}
+			if err := downloadObject(ctx, client, bucket, key, path); err != nil {
+				return count, fmt.Errorf("downloading %s: %w", key, err)
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// downloadObject writes a single S3 object to path.
+func downloadObject(ctx context.Context, client *s3.Client, bucket, key, path string) error {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // G304: path is checked to be inside the backup directory
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, out.Body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// writeJSONLines creates path and calls produce with a function that writes
+// one JSON value per line. It returns the number of lines written.
+func writeJSONLines(path string, produce func(emit func(any) error) error) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // G304: path is inside the backup directory
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	count := 0
+	err = produce(func(v any) error {
+		count++
+		return encoder.Encode(v)
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := writer.Flush(); err != nil {
+		return count, err
+	}
+	return count, file.Close()
+}
+
+// printList prints a labeled list, or "none".
+func printList(label string, items []string) {
+	if len(items) == 0 {
+		fmt.Printf("  %-11s none\n", label+":")
+		return
+	}
+	fmt.Printf("  %-11s %s\n", label+":", items[0])
+	for _, item := range items[1:] {
+		fmt.Printf("  %-11s %s\n", "", item)
+	}
+}
+
+// uniqueSorted returns the sorted unique values.
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names.
+func sanitizeFileName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+}
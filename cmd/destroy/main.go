@@ -0,0 +1,199 @@
+// destroy tears down an AWS AgentCore stack, exporting its data first.
+//
+// It handles:
+//  1. Exporting stack outputs, template, logs, secrets metadata, and
+//     DynamoDB/S3 state to a local backup directory
+//  2. Deleting the CloudFormation stack
+//
+// Usage:
+//
+//	destroy [flags]
+//
+// Examples:
+//
+//	destroy                             # Destroy the stack named in config.json
+//	destroy --stack my-agents           # Destroy a specific stack
+//	destroy --backup-dir /mnt/backups   # Write the backup elsewhere
+//	destroy --environment prod          # Back up the {project}/prod secrets
+//	destroy --dry-run                   # Show what would be exported and deleted
+//	destroy --skip-backup --yes         # Delete without a backup or prompt
+//
+// Install:
+//
+//	go install github.com/plexusone/agentkit-aws-cdk/cmd/destroy@latest
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+const (
+	// DefaultBackupDir is the default directory for pre-destroy backups
+	DefaultBackupDir = "backups"
+
+	// deleteTimeout bounds how long to wait for the stack to be deleted
+	deleteTimeout = 60 * time.Minute
+)
+
+var (
+	region     = flag.String("region", "", "AWS region (default: AWS_REGION or us-east-1)")
+	stack      = flag.String("stack", "", "Stack name (default: stackName from config file)")
+	prefix     = flag.String("prefix", "", "Secret name prefix, with {project} and {env} placeholders; metadata of these secrets is backed up (default: {project}, or {project}/{env} with --environment)")
+	project    = flag.String("project", "", "Project name of the {project} placeholder (default: stackName from config file, or the directory name)")
+	envName    = flag.String("environment", "", "Environment of the secrets, e.g. prod: the {env} of --prefix")
+	backupDir  = flag.String("backup-dir", DefaultBackupDir, "Directory for the pre-destroy backup")
+	skipBackup = flag.Bool("skip-backup", false, "Skip the pre-destroy backup")
+	dryRun     = flag.Bool("dry-run", false, "Show what would be exported and deleted")
+	yes        = flag.Bool("yes", false, "Do not prompt for confirmation")
+)
+
+func main() {
+	flag.Usage = func() {
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Destroy an AWS AgentCore stack.\n\n")
+		fmt.Fprintf(os.Stderr, "Stack is auto-detected from config.json stackName if not specified.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nSteps:\n")
+		fmt.Fprintf(os.Stderr, "  1. Back up stack outputs, template, logs, secrets metadata, and DynamoDB/S3 data\n")
+		fmt.Fprintf(os.Stderr, "  2. Delete the CloudFormation stack\n")
+	}
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	stackName := *stack
+	if stackName == "" {
+		stackName = envsecrets.DetectStackName()
+	}
+	if stackName == "" {
+		return fmt.Errorf("no stack name found; set --stack or run from a directory with config.json")
+	}
+
+	projectName := *project
+	if projectName == "" {
+		projectName = envsecrets.DetectProjectName()
+	}
+	secretPrefix, err := envsecrets.ExpandPrefix(*prefix, projectName, *envName)
+	if err != nil {
+		return fmt.Errorf("--prefix: %w", err)
+	}
+
+	// Determine region
+	awsRegion := *region
+	if awsRegion == "" {
+		awsRegion = os.Getenv("AWS_REGION")
+	}
+	if awsRegion == "" {
+		awsRegion = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if awsRegion == "" {
+		awsRegion = "us-east-1"
+	}
+
+	fmt.Println("=== AWS AgentCore Destroy ===")
+	fmt.Println()
+	fmt.Printf("Region: %s\n", awsRegion)
+	fmt.Printf("Stack: %s\n", stackName)
+	fmt.Printf("Secret prefix: %s\n", secretPrefix)
+	if *dryRun {
+		fmt.Println("Mode: DRY RUN (no changes will be made)")
+	}
+	fmt.Println()
+
+	ctx := context.Background()
+
+	// Load AWS config
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(awsRegion))
+	if err != nil {
+		return fmt.Errorf("loading AWS config: %w", err)
+	}
+
+	// Step 1: Back up
+	if !*skipBackup {
+		fmt.Println("=== Step 1: Backup ===")
+		plan, err := planBackup(ctx, cfg, stackName, secretPrefix)
+		if err != nil {
+			return fmt.Errorf("planning backup: %w", err)
+		}
+		plan.print()
+
+		dir := filepath.Join(*backupDir, fmt.Sprintf("%s-%s", stackName, time.Now().UTC().Format("20060102-150405")))
+		if *dryRun {
+			fmt.Printf("[DRY RUN] Would export to %s\n", dir)
+		} else {
+			fmt.Printf("Exporting to %s\n", dir)
+			if err := plan.run(ctx, cfg, dir); err != nil {
+				return fmt.Errorf("backing up (the stack was not deleted; use --skip-backup to delete without a backup): %w", err)
+			}
+		}
+		fmt.Println()
+	} else {
+		fmt.Println("=== Step 1: Skipping backup (--skip-backup) ===")
+		fmt.Println()
+	}
+
+	// Step 2: Delete
+	fmt.Println("=== Step 2: Delete Stack ===")
+	if *dryRun {
+		fmt.Printf("[DRY RUN] Would delete stack %s\n", stackName)
+		return nil
+	}
+	if !*yes && !confirm(stackName) {
+		return fmt.Errorf("aborted")
+	}
+	if err := deleteStack(ctx, cloudformation.NewFromConfig(cfg), stackName); err != nil {
+		return fmt.Errorf("deleting stack: %w", err)
+	}
+	fmt.Println()
+
+	fmt.Println("=== Destroy Complete ===")
+	return nil
+}
+
+// confirm asks the user to type the stack name.
+func confirm(stackName string) bool {
+	fmt.Printf("Type the stack name to confirm deletion of %s: ", stackName)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == stackName
+}
+
+// deleteStack deletes the stack and waits for the deletion to finish.
+func deleteStack(ctx context.Context, client *cloudformation.Client, stackName string) error {
+	if _, err := client.DeleteStack(ctx, &cloudformation.DeleteStackInput{
+		StackName: aws.String(stackName),
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Waiting for %s to be deleted...\n", stackName)
+	waiter := cloudformation.NewStackDeleteCompleteWaiter(client)
+	if err := waiter.Wait(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	}, deleteTimeout); err != nil {
+		return err
+	}
+	fmt.Println("  Deleted")
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+// finder looks up existing AgentCore resources by name with the AgentCore
+// control API, which has no SDK client in this module. Listings are cached.
+type finder struct {
+	cfg      aws.Config
+	runtimes map[string]string // Runtime name to ID
+	gateways map[string]string // Gateway name to ID
+}
+
+func newFinder(cfg aws.Config) *finder {
+	return &finder{cfg: cfg}
+}
+
+// runtime returns the ID of the runtime with the given name, or "".
+func (f *finder) runtime(ctx context.Context, name string) (string, error) {
+	if f.runtimes == nil {
+		runtimes := make(map[string]string)
+		err := f.list(ctx, http.MethodPost, "/runtimes/", "agentRuntimes", func(item map[string]any) {
+			runtimes[fmt.Sprint(item["agentRuntimeName"])] = fmt.Sprint(item["agentRuntimeId"])
+		})
+		if err != nil {
+			return "", fmt.Errorf("listing agent runtimes: %w", err)
+		}
+		f.runtimes = runtimes
+	}
+	return f.runtimes[name], nil
+}
+
+// endpoint returns the ARN of the endpoint of a runtime with the given name,
+// or "".
+func (f *finder) endpoint(ctx context.Context, runtimeID, name string) (string, error) {
+	arn := ""
+	path := "/runtimes/" + url.PathEscape(runtimeID) + "/runtime-endpoints/"
+	err := f.list(ctx, http.MethodPost, path, "runtimeEndpoints", func(item map[string]any) {
+		if item["name"] == name {
+			arn = fmt.Sprint(item["agentRuntimeEndpointArn"])
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("listing endpoints of runtime %s: %w", runtimeID, err)
+	}
+	return arn, nil
+}
+
+// gateway returns the ID of the gateway with the given name, or "".
+func (f *finder) gateway(ctx context.Context, name string) (string, error) {
+	if f.gateways == nil {
+		gateways := make(map[string]string)
+		err := f.list(ctx, http.MethodGet, "/gateways/", "items", func(item map[string]any) {
+			gateways[fmt.Sprint(item["name"])] = fmt.Sprint(item["gatewayId"])
+		})
+		if err != nil {
+			return "", fmt.Errorf("listing gateways: %w", err)
+		}
+		f.gateways = gateways
+	}
+	return f.gateways[name], nil
+}
+
+// list calls a paginated list operation and passes each item of the list
+// field of its responses to fn. POST operations take the pagination
+// parameters in the body, GET operations in the query string.
+func (f *finder) list(ctx context.Context, method, path, field string, fn func(item map[string]any)) error {
+	token := ""
+	for {
+		var body map[string]any
+		requestPath := path
+		if method == http.MethodPost {
+			body = map[string]any{"maxResults": 100}
+			if token != "" {
+				body["nextToken"] = token
+			}
+		} else {
+			query := url.Values{"maxResults": {"100"}}
+			if token != "" {
+				query.Set("nextToken", token)
+			}
+			requestPath += "?" + query.Encode()
+		}
+
+		data, err := awsapi.Do(ctx, f.cfg, awsapi.Call{
+			SigningName: "bedrock-agentcore",
+			Host:        awsapi.Host(f.cfg.Region, "bedrock-agentcore-control"),
+			Method:      method,
+			Path:        requestPath,
+			Body:        body,
+		})
+		if err != nil {
+			return err
+		}
+		var page map[string]any
+		if err := json.Unmarshal(data, &page); err != nil {
+			return err
+		}
+		items, _ := page[field].([]any)
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				fn(m)
+			}
+		}
+		token, _ = page["nextToken"].(string)
+		if token == "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,432 @@
+// import brings AgentCore runtimes, endpoints, and gateways created outside
+// CloudFormation, e.g. in the console, under the management of a stack
+// without recreating them.
+//
+// It handles:
+//  1. Finding the runtimes, endpoints, and gateways of the synthesized stack
+//     that are not deployed yet
+//  2. Matching them with existing resources, by the ID recorded with
+//     agentcore.ImportExistingRuntime or by name
+//  3. Writing the resource mapping for `cdk import`
+//
+// Usage:
+//
+//	import [flags]
+//
+// Examples:
+//
+//	import                                    # Map the stack named in config.json
+//	import --stack my-agents --app cdk.out    # Map a specific stack and assembly
+//	import --format cloudformation            # Write ResourcesToImport for the AWS CLI
+//
+// Install:
+//
+//	go install github.com/plexusone/agentkit-aws-cdk/cmd/import@latest
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/smithy-go"
+	"gopkg.in/yaml.v3"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+const (
+	// DefaultOutput is the default path of the resource mapping
+	DefaultOutput = "import-mapping.json"
+
+	// stackArtifactType is the cloud assembly artifact type of a CloudFormation stack
+	stackArtifactType = "aws:cloudformation:stack"
+)
+
+// Importable resource types and the property that identifies an existing
+// resource of the type in a CloudFormation import.
+const (
+	runtimeType  = "AWS::BedrockAgentCore::Runtime"
+	endpointType = "AWS::BedrockAgentCore::RuntimeEndpoint"
+	gatewayType  = "AWS::BedrockAgentCore::Gateway"
+)
+
+var identifierKeys = map[string]string{
+	runtimeType:  "AgentRuntimeId",
+	endpointType: "AgentRuntimeEndpointArn",
+	gatewayType:  "GatewayIdentifier",
+}
+
+var (
+	region = flag.String("region", "", "AWS region (default: AWS_REGION or us-east-1)")
+	stack  = flag.String("stack", "", "Stack name (default: stackName from config file, or the only stack of the assembly)")
+	app    = flag.String("app", "", "Synthesized cloud assembly directory (default: output from cdk.json or cdk.out)")
+	output = flag.String("output", DefaultOutput, "Path of the resource mapping")
+	format = flag.String("format", "cdk", "Mapping format: cdk (for cdk import) or cloudformation (ResourcesToImport)")
+)
+
+func main() {
+	flag.Usage = func() {
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Map existing AgentCore runtimes, endpoints, and gateways to the resources of a stack for cdk import.\n\n")
+		fmt.Fprintf(os.Stderr, "Run cdk synth first. Stack is auto-detected from config.json stackName if not specified.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nSteps:\n")
+		fmt.Fprintf(os.Stderr, "  1. Find the runtimes, endpoints, and gateways of the stack that are not deployed yet\n")
+		fmt.Fprintf(os.Stderr, "  2. Match them with existing resources by recorded ID or by name\n")
+		fmt.Fprintf(os.Stderr, "  3. Write the resource mapping\n")
+	}
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *format != "cdk" && *format != "cloudformation" {
+		return fmt.Errorf("invalid --format %q: must be cdk or cloudformation", *format)
+	}
+
+	dir := *app
+	if dir == "" {
+		dir = assemblyDir()
+	}
+	stackName := *stack
+	if stackName == "" {
+		stackName = envsecrets.DetectStackName()
+	}
+	artifact, err := readStackArtifact(dir, stackName)
+	if err != nil {
+		return err
+	}
+
+	// Determine region
+	awsRegion := *region
+	if awsRegion == "" {
+		awsRegion = os.Getenv("AWS_REGION")
+	}
+	if awsRegion == "" {
+		awsRegion = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if awsRegion == "" {
+		awsRegion = "us-east-1"
+	}
+
+	fmt.Println("=== AWS AgentCore Import ===")
+	fmt.Println()
+	fmt.Printf("Region: %s\n", awsRegion)
+	fmt.Printf("Stack: %s\n", artifact.stackName)
+	fmt.Printf("Assembly: %s\n", dir)
+	fmt.Println()
+
+	ctx := context.Background()
+
+	// Load AWS config
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(awsRegion))
+	if err != nil {
+		return fmt.Errorf("loading AWS config: %w", err)
+	}
+
+	deployed, err := deployedResources(ctx, cloudformation.NewFromConfig(cfg), artifact.stackName)
+	if err != nil {
+		return fmt.Errorf("reading deployed template: %w", err)
+	}
+
+	imports, problems, err := planImports(ctx, newFinder(cfg), artifact.resources, deployed)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Printf("  ✗ %s\n", problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d resource(s) cannot be imported", len(problems))
+	}
+	if len(imports) == 0 {
+		fmt.Println("No existing runtimes, endpoints, or gateways to import.")
+		return nil
+	}
+
+	if err := writeMapping(*output, *format, imports); err != nil {
+		return fmt.Errorf("writing %s: %w", *output, err)
+	}
+	fmt.Println()
+	fmt.Printf("Wrote %s\n", *output)
+	fmt.Println()
+	fmt.Println("Next steps:")
+	if *format == "cdk" {
+		fmt.Printf("  cdk import --app %s --resource-mapping %s %s\n", dir, *output, artifact.id)
+	} else {
+		fmt.Printf("  aws cloudformation create-change-set --change-set-type IMPORT --stack-name %s \\\n", artifact.stackName)
+		fmt.Printf("    --change-set-name import --template-body file://%s --resources-to-import file://%s\n", artifact.templatePath, *output)
+	}
+	fmt.Println("  cdk deploy    # Apply the stack's configuration to the imported resources")
+	return nil
+}
+
+// stackArtifact is a stack of the cloud assembly.
+type stackArtifact struct {
+	id           string // Artifact ID, passed to cdk import
+	stackName    string // CloudFormation stack name
+	templatePath string
+	resources    map[string]map[string]any // Template resources by logical ID
+}
+
+// assemblyDir returns the cloud assembly directory of the CDK app in the
+// current directory: the output setting of cdk.json, or cdk.out.
+func assemblyDir() string {
+	data, err := os.ReadFile("cdk.json")
+	if err == nil {
+		var cdkJSON struct {
+			Output string `json:"output"`
+		}
+		if json.Unmarshal(data, &cdkJSON) == nil && cdkJSON.Output != "" {
+			return cdkJSON.Output
+		}
+	}
+	return "cdk.out"
+}
+
+// readStackArtifact reads the stack with the given name or artifact ID from
+// a cloud assembly, or its only stack if the name is empty.
+func readStackArtifact(dir, name string) (*stackArtifact, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json")) //nolint:gosec // G304: path is the local cloud assembly
+	if err != nil {
+		return nil, fmt.Errorf("reading cloud assembly (run cdk synth first): %w", err)
+	}
+	var manifest struct {
+		Artifacts map[string]struct {
+			Type       string `json:"type"`
+			Properties struct {
+				StackName    string `json:"stackName"`
+				TemplateFile string `json:"templateFile"`
+			} `json:"properties"`
+		} `json:"artifacts"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing cloud assembly manifest: %w", err)
+	}
+
+	var matches []*stackArtifact
+	var names []string
+	for id, artifact := range manifest.Artifacts {
+		if artifact.Type != stackArtifactType {
+			continue
+		}
+		stackName := artifact.Properties.StackName
+		if stackName == "" {
+			stackName = id
+		}
+		names = append(names, stackName)
+		if name == "" || name == stackName || name == id {
+			matches = append(matches, &stackArtifact{
+				id:           id,
+				stackName:    stackName,
+				templatePath: filepath.Join(dir, artifact.Properties.TemplateFile),
+			})
+		}
+	}
+	sort.Strings(names)
+	switch {
+	case len(matches) == 0 && name != "":
+		return nil, fmt.Errorf("no stack %s in %s (stacks: %s)", name, dir, strings.Join(names, ", "))
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no stacks in %s", dir)
+	case len(matches) > 1:
+		return nil, fmt.Errorf("%s has several stacks (%s); set --stack", dir, strings.Join(names, ", "))
+	}
+
+	artifact := matches[0]
+	data, err = os.ReadFile(artifact.templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	var template struct {
+		Resources map[string]map[string]any `json:"Resources"`
+	}
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", artifact.templatePath, err)
+	}
+	artifact.resources = template.Resources
+	return artifact, nil
+}
+
+// deployedResources returns the logical IDs of the resources of the
+// deployed stack, or none if the stack does not exist.
+func deployedResources(ctx context.Context, client *cloudformation.Client, stackName string) (map[string]bool, error) {
+	out, err := client.GetTemplate(ctx, &cloudformation.GetTemplateInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage(), "does not exist") {
+			return map[string]bool{}, nil
+		}
+		return nil, err
+	}
+
+	// GetTemplate returns the template as it was written, JSON or YAML
+	var template struct {
+		Resources map[string]any `json:"Resources" yaml:"Resources"`
+	}
+	if err := yaml.Unmarshal([]byte(aws.ToString(out.TemplateBody)), &template); err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	deployed := make(map[string]bool, len(template.Resources))
+	for id := range template.Resources {
+		deployed[id] = true
+	}
+	return deployed, nil
+}
+
+// resourceImport is a resource of the stack and the identifier of the
+// existing resource it takes over.
+type resourceImport struct {
+	LogicalID  string
+	Type       string
+	Identifier map[string]string
+}
+
+// planImports matches the runtimes, endpoints, and gateways of a template
+// that are not deployed yet with existing resources. Resources without a
+// match are left to be created. It returns the imports and the reasons why
+// matched resources cannot be imported.
+func planImports(ctx context.Context, find *finder, resources map[string]map[string]any, deployed map[string]bool) ([]resourceImport, []string, error) {
+	// Runtimes first: endpoints are looked up by the ID of their runtime
+	ids := make([]string, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	order := map[string]int{runtimeType: 0, endpointType: 1, gatewayType: 2}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := resources[ids[i]]["Type"].(string), resources[ids[j]]["Type"].(string)
+		if order[ti] != order[tj] {
+			return order[ti] < order[tj]
+		}
+		return ids[i] < ids[j]
+	})
+
+	var imports []resourceImport
+	var problems []string
+	runtimeIDs := make(map[string]string) // Logical ID to runtime ID
+	for _, id := range ids {
+		resource := resources[id]
+		resourceType, _ := resource["Type"].(string)
+		key, ok := identifierKeys[resourceType]
+		if !ok || deployed[id] {
+			continue
+		}
+
+		value, err := identify(ctx, find, resourceType, resource, runtimeIDs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("looking up %s: %w", id, err)
+		}
+		if value == "" {
+			fmt.Printf("  + %s (%s): no existing resource, will be created\n", id, resourceType)
+			continue
+		}
+		if resourceType == runtimeType {
+			runtimeIDs[id] = value
+		}
+		if _, ok := resource["DeletionPolicy"]; !ok {
+			problems = append(problems, fmt.Sprintf("%s (%s) matches %s but has no DeletionPolicy; use agentcore.ImportExistingRuntime or ApplyRemovalPolicy(RETAIN)", id, resourceType, value))
+			continue
+		}
+		fmt.Printf("  ← %s (%s): %s=%s\n", id, resourceType, key, value)
+		imports = append(imports, resourceImport{LogicalID: id, Type: resourceType, Identifier: map[string]string{key: value}})
+	}
+	return imports, problems, nil
+}
+
+// identify returns the identifier of the existing resource a template
+// resource takes over: the one recorded with agentcore.ImportExistingRuntime,
+// or that of the resource of the same name. It returns "" if there is none.
+func identify(ctx context.Context, find *finder, resourceType string, resource map[string]any, runtimeIDs map[string]string) (string, error) {
+	metadata, _ := resource["Metadata"].(map[string]any)
+	if recorded, ok := metadata[agentcore.ImportMetadataKey].(map[string]any); ok {
+		if value, ok := recorded[identifierKeys[resourceType]].(string); ok {
+			return value, nil
+		}
+	}
+
+	props, _ := resource["Properties"].(map[string]any)
+	switch resourceType {
+	case runtimeType:
+		name, ok := props["AgentRuntimeName"].(string)
+		if !ok {
+			return "", nil
+		}
+		return find.runtime(ctx, name)
+	case endpointType:
+		name, ok := props["Name"].(string)
+		if !ok {
+			return "", nil
+		}
+		runtimeID, ok := props["AgentRuntimeId"].(string)
+		if !ok {
+			// {"Fn::GetAtt": [runtime, "AgentRuntimeId"]} of a runtime
+			// imported along with the endpoint
+			getAtt, _ := props["AgentRuntimeId"].(map[string]any)
+			ref, _ := getAtt["Fn::GetAtt"].([]any)
+			if len(ref) == 0 {
+				return "", nil
+			}
+			logicalID, _ := ref[0].(string)
+			if runtimeID = runtimeIDs[logicalID]; runtimeID == "" {
+				return "", nil
+			}
+		}
+		return find.endpoint(ctx, runtimeID, name)
+	case gatewayType:
+		name, ok := props["Name"].(string)
+		if !ok {
+			return "", nil
+		}
+		return find.gateway(ctx, name)
+	}
+	return "", nil
+}
+
+// writeMapping writes the resource mapping of the imports: the
+// --resource-mapping file of cdk import, or the ResourcesToImport of a
+// CloudFormation import change set.
+func writeMapping(path, format string, imports []resourceImport) error {
+	var mapping any
+	if format == "cdk" {
+		m := make(map[string]map[string]string, len(imports))
+		for _, imp := range imports {
+			m[imp.LogicalID] = imp.Identifier
+		}
+		mapping = m
+	} else {
+		type resourceToImport struct {
+			ResourceType       string            `json:"ResourceType"`
+			LogicalResourceID  string            `json:"LogicalResourceId"`
+			ResourceIdentifier map[string]string `json:"ResourceIdentifier"`
+		}
+		var list []resourceToImport
+		for _, imp := range imports {
+			list = append(list, resourceToImport{ResourceType: imp.Type, LogicalResourceID: imp.LogicalID, ResourceIdentifier: imp.Identifier})
+		}
+		mapping = list
+	}
+	data, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
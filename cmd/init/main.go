@@ -0,0 +1,356 @@
+// init scaffolds a new AgentCore CDK project.
+//
+// It writes:
+//   - cdk.json running the Go app
+//   - go.mod requiring agentkit-aws-cdk
+//   - main.go defining the stack with StackBuilder
+//   - config.json defining the same stack for the JSON flow
+//   - .env.example listing the secrets push-secrets uploads
+//   - agents/{name}/Dockerfile for each agent
+//
+// Usage:
+//
+//	init [flags] [dir]
+//
+// Examples:
+//
+//	init my-agents                              # Scaffold ./my-agents with one agent
+//	init --agents research,orchestration .      # Scaffold the current directory
+//	init --module github.com/acme/agents infra  # Set the Go module path
+//	init --registry ghcr.io/acme my-agents      # Use images from another registry
+//	init --dry-run my-agents                    # Show the files that would be written
+//
+// Install:
+//
+//	go install github.com/plexusone/agentkit-aws-cdk/cmd/init@latest
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+const (
+	// modulePath is the module imported by scaffolded projects
+	modulePath = "github.com/plexusone/agentkit-aws-cdk"
+
+	// goVersion is the go directive of scaffolded projects, matching the
+	// minimum Go version of agentkit-aws-cdk
+	goVersion = "1.25.5"
+
+	// ecrRegistry is the registry of the deploy account in config.json,
+	// resolved when the stack is synthesized
+	ecrRegistry = "${aws:accountId}.dkr.ecr.${aws:region}.amazonaws.com"
+)
+
+var (
+	// stackNamePattern is the CloudFormation stack name rule.
+	stackNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]{0,127}$`)
+
+	// agentNamePattern is the AgentCore runtime name rule.
+	agentNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,47}$`)
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	module   = flag.String("module", "", "Go module path (default: the stack name)")
+	stack    = flag.String("stack", "", "Stack name (default: the directory name)")
+	agents   = flag.String("agents", "agent", "Comma-separated agent names; the first is the default agent")
+	registry = flag.String("registry", "", "Container registry of the agent images (default: ECR of the deploy account)")
+	force    = flag.Bool("force", false, "Overwrite existing files")
+	skipMod  = flag.Bool("skip-mod", false, "Skip go get and go mod tidy")
+	dryRun   = flag.Bool("dry-run", false, "Show the files that would be written")
+)
+
+// project holds the values rendered into the templates.
+type project struct {
+	Module         string
+	StackName      string
+	Registry       string
+	ConfigRegistry string
+	GoVersion      string
+	Agents         []agent
+	Groups         []envsecrets.Group
+}
+
+// agent is an agent of the scaffolded project.
+type agent struct {
+	Name       string
+	Var        string
+	Repository string
+	Image      string
+	GoVersion  string
+}
+
+// AgentVars returns the builder variables of the agents, for WithAgents.
+func (p project) AgentVars() string {
+	vars := make([]string, len(p.Agents))
+	for i, a := range p.Agents {
+		vars[i] = a.Var
+	}
+	return strings.Join(vars, ", ")
+}
+
+// file is a scaffolded file.
+type file struct {
+	path     string
+	template string
+	data     any
+}
+
+func main() {
+	flag.Usage = func() {
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [dir]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Scaffold a new AgentCore CDK project in dir (default: current directory).\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nFiles:\n")
+		fmt.Fprintf(os.Stderr, "  cdk.json, go.mod, main.go, config.json, .env.example, .gitignore,\n")
+		fmt.Fprintf(os.Stderr, "  agents/{name}/Dockerfile\n")
+	}
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if flag.NArg() > 1 {
+		return fmt.Errorf("expected at most one directory, got %d arguments", flag.NArg())
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	p, err := newProject(dir)
+	if err != nil {
+		return err
+	}
+
+	files := []file{
+		{path: "cdk.json", template: "cdk.json.tmpl", data: p},
+		{path: "go.mod", template: "go.mod.tmpl", data: p},
+		{path: "main.go", template: "main.go.tmpl", data: p},
+		{path: "config.json", template: "config.json.tmpl", data: p},
+		{path: ".env.example", template: "env.example.tmpl", data: p},
+		{path: ".gitignore", template: "gitignore.tmpl", data: p},
+	}
+	for _, a := range p.Agents {
+		files = append(files, file{
+			path:     filepath.Join("agents", a.Name, "Dockerfile"),
+			template: "Dockerfile.tmpl",
+			data:     a,
+		})
+	}
+
+	if !*force {
+		var existing []string
+		for _, f := range files {
+			if _, err := os.Stat(filepath.Join(dir, f.path)); err == nil {
+				existing = append(existing, f.path)
+			}
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("files already exist in %s: %s (use --force to overwrite)", dir, strings.Join(existing, ", "))
+		}
+	}
+
+	templates, err := template.ParseFS(templateFS, "templates/*.tmpl")
+	if err != nil {
+		return fmt.Errorf("parsing templates: %w", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("[DRY RUN] Would scaffold stack %s (module %s) in %s:\n", p.StackName, p.Module, dir)
+	} else {
+		fmt.Printf("Scaffolding stack %s (module %s) in %s\n", p.StackName, p.Module, dir)
+	}
+	for _, f := range files {
+		content, err := render(templates, f)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, f.path)
+		if *dryRun {
+			fmt.Printf("  %s\n", path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		fmt.Printf("  ✓ %s\n", path)
+	}
+	if *dryRun {
+		return nil
+	}
+
+	if !*skipMod {
+		version := toolVersion()
+		if version == "" {
+			version = "latest"
+		}
+		fmt.Println("\nResolving dependencies...")
+		if err := goCommand(dir, "get", modulePath+"@"+version); err != nil {
+			return err
+		}
+		if err := goCommand(dir, "mod", "tidy"); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\nNext steps:")
+	if dir != "." {
+		fmt.Printf("  cd %s\n", dir)
+	}
+	if *skipMod {
+		fmt.Printf("  go get %s@latest && go mod tidy\n", modulePath)
+	}
+	fmt.Println("  cp .env.example .env            # Fill in secrets, then run push-secrets")
+	fmt.Println("  # Add agent code to agents/{name}, then build and push each image")
+	fmt.Println("  cdk deploy")
+	return nil
+}
+
+// newProject validates the flags and returns the project to scaffold in dir.
+func newProject(dir string) (*project, error) {
+	stackName := *stack
+	if stackName == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", dir, err)
+		}
+		stackName = filepath.Base(abs)
+	}
+	if !stackNamePattern.MatchString(stackName) {
+		return nil, fmt.Errorf("stack name %q must match %s (set --stack)", stackName, stackNamePattern)
+	}
+
+	modPath := *module
+	if modPath == "" {
+		modPath = stackName
+	}
+
+	p := &project{
+		Module:         modPath,
+		StackName:      stackName,
+		Registry:       strings.TrimSuffix(*registry, "/"),
+		ConfigRegistry: strings.TrimSuffix(*registry, "/"),
+		GoVersion:      goVersion,
+		Groups:         envsecrets.DefaultGroups(),
+	}
+	if p.ConfigRegistry == "" {
+		p.ConfigRegistry = ecrRegistry
+	}
+
+	imageRegistry := p.Registry
+	if imageRegistry == "" {
+		imageRegistry = "ACCOUNT.dkr.ecr.REGION.amazonaws.com"
+	}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(*agents, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !agentNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("agent name %q must match %s", name, agentNamePattern)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("agent %q is listed twice", name)
+		}
+		seen[name] = true
+		repository := strings.ToLower(stackName + "-" + name) // Repository names are lowercase
+		p.Agents = append(p.Agents, agent{
+			Name:       name,
+			Var:        agentVar(name),
+			Repository: repository,
+			Image:      fmt.Sprintf("%s/%s:latest", imageRegistry, repository),
+			GoVersion:  strings.Join(strings.Split(goVersion, ".")[:2], "."),
+		})
+	}
+	if len(p.Agents) == 0 {
+		return nil, fmt.Errorf("at least one agent is required")
+	}
+	return p, nil
+}
+
+// agentVar returns the Go variable holding an agent's configuration in
+// main.go, e.g. "web_search" becomes "webSearchAgent".
+func agentVar(name string) string {
+	var b strings.Builder
+	upper := false
+	for i, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case i == 0:
+			b.WriteString(strings.ToLower(string(r)))
+		case upper:
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String() + "Agent"
+}
+
+// render executes a file's template. Go files are gofmt-ed.
+func render(templates *template.Template, f file) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, f.template, f.data); err != nil {
+		return nil, fmt.Errorf("rendering %s: %w", f.path, err)
+	}
+	if filepath.Ext(f.path) != ".go" {
+		return buf.Bytes(), nil
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting %s: %w", f.path, err)
+	}
+	return formatted, nil
+}
+
+// goCommand runs a go command in dir.
+func goCommand(dir string, args ...string) error {
+	cmd := exec.Command("go", args...) //nolint:gosec // G204: fixed go subcommands
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// toolVersion returns the released version of this tool, which is also the
+// agentkit-aws-cdk version scaffolded projects require, or "" for
+// development builds.
+func toolVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok || bi.Main.Version == "" || bi.Main.Version == "(devel)" {
+		return ""
+	}
+	return bi.Main.Version
+}
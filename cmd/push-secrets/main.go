@@ -1,7 +1,17 @@
 // push-secrets pushes environment variables from .env files to AWS Secrets Manager.
 //
 // It reads KEY=VALUE pairs from a file and creates/updates secrets in AWS Secrets Manager,
-// organizing them into logical groups (llm, search, config).
+// organizing them into logical groups (llm, search, config). With --backend ssm,
+// each key is stored as a SecureString parameter in SSM Parameter Store instead. Each secret is
+// compared with its current value first; the key-level diff is printed with
+// masked values and unchanged secrets are not written. SOPS- and
+// age-encrypted env files are decrypted in memory. YAML and JSON secrets
+// manifests, with keys already grouped, are read as well. With --pull, the secrets
+// are read back into a local env file instead. Values are checked before
+// pushing, and obviously invalid or truncated ones (e.g. an OpenAI key not
+// starting with sk-) are refused. Secrets are tagged with the project,
+// environment, and managed-by tags, and --restrict-read attaches a resource
+// policy that lets only the given roles read them.
 //
 // Usage:
 //
@@ -12,7 +22,19 @@
 //	push-secrets .env                          # Push from .env to us-east-1
 //	push-secrets --region us-west-2 .env       # Push to specific region
 //	push-secrets --prefix myapp .env           # Use custom prefix (myapp/llm, myapp/search, etc.)
+//	push-secrets --prefix "{project}/{env}" --environment staging .env
 //	push-secrets --dry-run .env                # Preview without creating
+//	push-secrets --prune .env                  # Remove keys no longer in .env
+//	push-secrets secrets.enc.env               # Push from a SOPS- or age-encrypted file
+//	push-secrets secrets.yaml                  # Push from a grouped YAML or JSON manifest
+//	push-secrets --backend ssm .env            # Push to SSM parameters (/{project}/llm/OPENAI_API_KEY, etc.)
+//	push-secrets --json .env                   # Write a secrets-pushed JSON event for CI
+//	push-secrets --replicate-to us-west-2 .env # Replicate the secrets to a DR region
+//	push-secrets --validate-keys .env          # Verify the LLM provider keys before pushing
+//	push-secrets --environment prod .env       # Push to {project}/prod/llm, etc., tagged environment=prod
+//	push-secrets --restrict-read arn:aws:iam::123456789012:role/stats-agent-execution-role .env
+//	push-secrets --pull                        # Write the secrets to .env.generated, masked
+//	push-secrets --pull --reveal --out .env    # Bootstrap a local .env from the cloud secrets
 //
 // Install:
 //
@@ -20,44 +42,47 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+	"github.com/plexusone/agentkit-aws-cdk/internal/cliout"
 )
 
-const (
-	// DefaultConfigDir is the default directory for plexusone configuration
-	DefaultConfigDir = ".plexusone"
-)
-
-// SecretGroup represents a logical grouping of secrets
-type SecretGroup struct {
-	Name        string
-	Description string
-	Keys        map[string]string
-	Patterns    []string // Key patterns that belong to this group
-}
-
 var (
-	region  = flag.String("region", "", "AWS region (default: AWS_REGION or us-east-1)")
-	prefix  = flag.String("prefix", "stats-agent", "Secret name prefix")
-	project = flag.String("project", "", "Project name for ~/.plexusone/projects/{project}/.env lookup")
-	dryRun  = flag.Bool("dry-run", false, "Preview changes without creating secrets")
-	verbose = flag.Bool("verbose", false, "Show verbose output")
+	region     = flag.String("region", "", "AWS region (default: AWS_REGION or us-east-1)")
+	prefix     = flag.String("prefix", "", "Secret name prefix, with {project} and {env} placeholders (default: {project}, or {project}/{env} with --environment)")
+	project    = flag.String("project", "", "Project name for ~/.plexusone/projects/{project}/.env lookup")
+	dryRun     = flag.Bool("dry-run", false, "Preview changes without creating secrets")
+	prune      = flag.Bool("prune", false, "Remove keys from secrets that are no longer in the env file")
+	backend    = flag.String("backend", envsecrets.BackendSecretsManager, "Secret backend: secretsmanager or ssm (SecureString parameters)")
+	replicate  = flag.String("replicate-to", "", "Comma-separated regions to replicate secrets to, each optionally region=kmsKeyId (secretsmanager backend only)")
+	syncRepl   = flag.Bool("sync-replicas", false, "Also add the missing --replicate-to replicas of unchanged secrets, describing each secret")
+	groupsFile = flag.String("groups", "", "Path to secret-groups.yaml (default: auto-detect, then built-in groups)")
+	jsonOutput = flag.Bool("json", false, "Write machine-readable JSON events to stdout, one per line, and progress to stderr")
+	quiet      = flag.Bool("quiet", false, "Print only warnings and errors (and --json events)")
+	verbose    = flag.Bool("verbose", false, "Show verbose output")
+	pull       = flag.Bool("pull", false, "Read the secrets into a local env file instead of pushing")
+	outFile    = flag.String("out", ".env.generated", "Env file written by --pull (- for stdout)")
+	reveal     = flag.Bool("reveal", false, "Write real values with --pull instead of masked ones")
+	skipCheck  = flag.Bool("skip-validation", false, "Push values even if they look invalid or truncated")
+	checkLive  = flag.Bool("validate-keys", false, "Verify LLM provider keys with a lightweight call to each provider before pushing")
+	envName    = flag.String("environment", "", "Environment of the secrets, e.g. prod: the {env} of --prefix and the environment tag")
+	extraTags  = flag.String("tags", "", "Comma-separated key=value tags added to the secrets, next to project, environment, and managed-by")
+	noTags     = flag.Bool("no-tags", false, "Don't tag the secrets")
+	readers    = flag.String("restrict-read", "", "Comma-separated IAM role ARNs, e.g. the agent execution role, that alone may read the secret values besides the caller (secretsmanager backend only)")
 )
 
+// logger prints progress and, with --json, events.
+var logger = cliout.New(false, false)
+
 func main() {
 	flag.Usage = func() {
 		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
@@ -82,28 +107,65 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s --region us-west-2 .env   # Push to specific region\n", os.Args[0])
 		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
 		fmt.Fprintf(os.Stderr, "  %s --dry-run .env            # Preview without creating\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s --prune .env              # Remove keys no longer in .env\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s secrets.enc.env           # Push from a SOPS- or age-encrypted file\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s secrets.yaml              # Push from a grouped YAML or JSON manifest\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s --backend ssm .env        # Push to SSM SecureString parameters\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s --json .env               # Write a secrets-pushed JSON event for CI\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s --replicate-to us-west-2 .env # Replicate the secrets to a DR region\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s --pull --reveal --out .env # Write the cloud secrets to a local .env\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s --validate-keys .env      # Verify the LLM provider keys before pushing\n", os.Args[0])
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "  %s --environment prod .env   # Push to {project}/prod/llm, etc.\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nSecret Groups:\n")
 		fmt.Fprintf(os.Stderr, "  {prefix}/llm     - LLM provider API keys (GOOGLE_API_KEY, OPENAI_API_KEY, etc.)\n")
 		fmt.Fprintf(os.Stderr, "  {prefix}/search  - Search provider keys (SERPER_API_KEY, SERPAPI_API_KEY)\n")
 		fmt.Fprintf(os.Stderr, "  {prefix}/config  - Configuration and observability settings\n")
+		fmt.Fprintf(os.Stderr, "\nGroups can be redefined in secret-groups.yaml (current or parent directory,\n")
+		fmt.Fprintf(os.Stderr, "~/.plexusone/projects/{project}/, or ~/.plexusone/) or with --groups.\n")
+		fmt.Fprintf(os.Stderr, "\nWith --backend ssm, each key is a parameter: /{prefix}/{group}/{KEY}\n")
+		fmt.Fprintf(os.Stderr, "\nThe prefix defaults to the project name, followed by /{env} with --environment,\n")
+		fmt.Fprintf(os.Stderr, "so the secrets of different projects and environments don't collide.\n")
 	}
 	flag.Parse()
+	logger = cliout.New(*jsonOutput, *quiet)
 
 	// Detect project name
 	projectName := *project
 	if projectName == "" {
-		projectName = detectProjectName()
+		projectName = envsecrets.DetectProjectName()
 	}
 
 	var envFile string
-	if flag.NArg() >= 1 {
+	if *pull {
+		if flag.NArg() > 0 || *prune || *replicate != "" || *dryRun || *checkLive {
+			logger.Errorf("--pull takes no env file and can't be combined with --prune, --replicate-to, --dry-run, or --validate-keys")
+			os.Exit(1)
+		}
+		if *outFile == "-" {
+			if *jsonOutput {
+				logger.Errorf("--out - can't be combined with --json, which writes events to stdout")
+				os.Exit(1)
+			}
+			// stdout carries only the env file
+			logger = cliout.New(false, true)
+		}
+	} else if flag.NArg() >= 1 {
 		envFile = flag.Arg(0)
 	} else {
 		// Auto-detect env file
 		var err error
-		envFile, err = findEnvFile(projectName)
+		envFile, err = envsecrets.FindEnvFile(projectName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			logger.Errorf("%v", err)
 			fmt.Fprintf(os.Stderr, "\nCreate ~/.plexusone/.env or ~/.plexusone/projects/%s/.env\n", projectName)
 			os.Exit(1)
 		}
@@ -121,267 +183,188 @@ func main() {
 		awsRegion = "us-east-1"
 	}
 
-	if err := run(envFile, awsRegion, *prefix, *dryRun, *verbose); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if *backend != envsecrets.BackendSecretsManager && *backend != envsecrets.BackendSSM {
+		logger.Errorf("--backend must be %s or %s", envsecrets.BackendSecretsManager, envsecrets.BackendSSM)
 		os.Exit(1)
 	}
-}
 
-func run(envFile, region, prefix string, dryRun, verbose bool) error {
-	// Define secret groups
-	groups := []SecretGroup{
-		{
-			Name:        "llm",
-			Description: "LLM provider API keys",
-			Keys:        make(map[string]string),
-			Patterns: []string{
-				"GOOGLE_API_KEY",
-				"GEMINI_API_KEY",
-				"ANTHROPIC_API_KEY",
-				"CLAUDE_API_KEY",
-				"OPENAI_API_KEY",
-				"XAI_API_KEY",
-				"LLM_API_KEY",
-			},
-		},
-		{
-			Name:        "search",
-			Description: "Search provider API keys",
-			Keys:        make(map[string]string),
-			Patterns: []string{
-				"SERPER_API_KEY",
-				"SERPAPI_API_KEY",
-			},
-		},
-		{
-			Name:        "config",
-			Description: "Configuration and observability settings",
-			Keys:        make(map[string]string),
-			Patterns: []string{
-				"LLM_PROVIDER",
-				"LLM_MODEL",
-				"LLM_BASE_URL",
-				"SEARCH_PROVIDER",
-				"OBSERVABILITY_ENABLED",
-				"OBSERVABILITY_PROVIDER",
-				"OPIK_API_KEY",
-				"OPIK_WORKSPACE",
-				"OPIK_PROJECT",
-				"LANGFUSE_PUBLIC_KEY",
-				"LANGFUSE_SECRET_KEY",
-				"PHOENIX_API_KEY",
-			},
-		},
+	replicas, err := envsecrets.ParseReplicas(*replicate, awsRegion)
+	if err != nil {
+		logger.Errorf("--replicate-to: %v", err)
+		os.Exit(1)
 	}
-
-	// Parse env file
-	fmt.Printf("Reading from: %s\n", envFile)
-	if err := parseEnvFile(envFile, groups, verbose); err != nil {
-		return fmt.Errorf("parsing env file: %w", err)
+	if len(replicas) > 0 && *backend != envsecrets.BackendSecretsManager {
+		logger.Errorf("--replicate-to requires --backend %s", envsecrets.BackendSecretsManager)
+		os.Exit(1)
+	}
+	if *syncRepl && len(replicas) == 0 {
+		logger.Errorf("--sync-replicas requires --replicate-to")
+		os.Exit(1)
 	}
 
-	fmt.Printf("AWS Region: %s\n", region)
-	fmt.Printf("Secret prefix: %s\n", prefix)
-	if dryRun {
-		fmt.Printf("Mode: DRY RUN (no changes will be made)\n")
+	secretPrefix, err := envsecrets.ExpandPrefix(*prefix, projectName, *envName)
+	if err != nil {
+		logger.Errorf("--prefix: %v", err)
+		os.Exit(1)
 	}
-	fmt.Println()
 
-	// Create AWS client
-	var client *secretsmanager.Client
-	if !dryRun {
-		cfg, err := config.LoadDefaultConfig(context.Background(),
-			config.WithRegion(region),
-		)
-		if err != nil {
-			return fmt.Errorf("loading AWS config: %w", err)
-		}
-		client = secretsmanager.NewFromConfig(cfg)
+	defs, groupsPath, err := envsecrets.ResolveGroups(*groupsFile, projectName)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	if groupsPath != "" {
+		logger.Printf("Secret groups: %s\n", groupsPath)
 	}
 
-	// Process each group
-	ctx := context.Background()
-	for _, group := range groups {
-		secretName := fmt.Sprintf("%s/%s", prefix, group.Name)
-		if err := processGroup(ctx, client, secretName, group, dryRun); err != nil {
-			return fmt.Errorf("processing %s: %w", secretName, err)
+	if *pull {
+		if err := runPull(*outFile, awsRegion, secretPrefix, *backend, defs, *reveal); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
 		}
+		return
 	}
 
-	fmt.Println()
-	fmt.Println("Done!")
-	fmt.Println()
-	fmt.Printf("To verify:\n")
-	fmt.Printf("  aws secretsmanager list-secrets --region %s --filter Key=name,Values=%s/ --no-cli-pager\n", region, prefix)
-
-	return nil
-}
+	if *skipCheck && *checkLive {
+		logger.Errorf("--validate-keys can't be combined with --skip-validation")
+		os.Exit(1)
+	}
 
-func parseEnvFile(filename string, groups []SecretGroup, verbose bool) error {
-	file, err := os.Open(filename)
+	tags, err := pushTags(projectName, *envName, *extraTags, *noTags)
 	if err != nil {
-		return err
+		logger.Errorf("%v", err)
+		os.Exit(1)
 	}
-	defer file.Close()
-
-	// Regex to match: optional "export", KEY, =, VALUE
-	envRegex := regexp.MustCompile(`^\s*(export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Skip empty lines and comments
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-
-		matches := envRegex.FindStringSubmatch(line)
-		if matches == nil {
-			continue
-		}
-
-		key := matches[2]
-		value := matches[3]
-
-		// Remove surrounding quotes
-		value = strings.Trim(value, `"'`)
-
-		// Skip empty or placeholder values
-		if value == "" || strings.HasPrefix(value, "your-") {
-			continue
-		}
-
-		// Categorize into groups
-		for i := range groups {
-			for _, pattern := range groups[i].Patterns {
-				if key == pattern {
-					groups[i].Keys[key] = value
-					if verbose {
-						fmt.Printf("  Found %s key: %s\n", groups[i].Name, key)
-					}
-					break
-				}
-			}
-		}
+	readerARNs, err := envsecrets.ParsePrincipalARNs(*readers)
+	if err != nil {
+		logger.Errorf("--restrict-read: %v", err)
+		os.Exit(1)
+	}
+	if len(readerARNs) > 0 && *backend != envsecrets.BackendSecretsManager {
+		logger.Errorf("--restrict-read requires --backend %s", envsecrets.BackendSecretsManager)
+		os.Exit(1)
 	}
 
-	return scanner.Err()
+	if err := run(envFile, awsRegion, secretPrefix, *backend, defs, replicas, tags, readerARNs, *dryRun, *prune, *syncRepl, *verbose, *skipCheck, *checkLive); err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
 }
 
-func processGroup(ctx context.Context, client *secretsmanager.Client, secretName string, group SecretGroup, dryRun bool) error {
-	if len(group.Keys) == 0 {
-		fmt.Printf("Skipping %s (no keys found)\n", secretName)
-		return nil
+// pushTags returns the tags of the pushed secrets: the project,
+// environment, and managed-by tags, then the --tags tags.
+func pushTags(projectName, environment, extra string, disabled bool) (map[string]string, error) {
+	if disabled {
+		if environment != "" || extra != "" {
+			return nil, fmt.Errorf("--no-tags can't be combined with --environment or --tags")
+		}
+		return nil, nil
 	}
-
-	// Convert to JSON
-	jsonBytes, err := json.Marshal(group.Keys)
+	tags := envsecrets.DefaultTags(projectName, environment)
+	parsed, err := envsecrets.ParseTags(extra)
 	if err != nil {
-		return fmt.Errorf("marshaling JSON: %w", err)
+		return nil, fmt.Errorf("--tags: %w", err)
 	}
-	secretValue := string(jsonBytes)
-
-	fmt.Printf("Creating/updating: %s\n", secretName)
-
-	// Show keys found
-	var keyNames []string
-	for k := range group.Keys {
-		keyNames = append(keyNames, k)
+	for key, value := range parsed {
+		tags[key] = value
 	}
-	fmt.Printf("  Keys: %s\n", strings.Join(keyNames, ", "))
-
-	if dryRun {
-		// Mask sensitive values for display
-		masked := maskSecretValues(secretValue)
-		fmt.Printf("  [DRY RUN] Would create with: %s\n", masked)
-		return nil
+	if err := envsecrets.ValidateTags(tags); err != nil {
+		return nil, err
 	}
+	return tags, nil
+}
 
-	// Try to update existing secret first
-	_, err = client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
-		SecretId:     aws.String(secretName),
-		SecretString: aws.String(secretValue),
-	})
+func run(envFile, region, prefix, backendName string, defs []envsecrets.Group, replicas []envsecrets.Replica, tags map[string]string, readerARNs []string, dryRun, prune, syncReplicas, verbose, skipValidation, validateKeys bool) error {
+	// Parse env file
+	ctx := context.Background()
+	logger.Printf("Reading from: %s\n", envFile)
+	file, err := envsecrets.ParseEnvFile(ctx, envFile, defs)
 	if err != nil {
-		// Check if secret doesn't exist
-		var notFound *types.ResourceNotFoundException
-		if errors.As(err, &notFound) {
-			// Create new secret
-			_, err = client.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
-				Name:         aws.String(secretName),
-				Description:  aws.String(group.Description),
-				SecretString: aws.String(secretValue),
-			})
-			if err != nil {
-				return fmt.Errorf("creating secret: %w", err)
+		return fmt.Errorf("parsing env file: %w", err)
+	}
+	if file.Format != envsecrets.FormatPlain {
+		logger.Printf("Decrypted %s file in memory\n", file.Format)
+	}
+	if verbose {
+		for _, group := range file.Groups {
+			for _, key := range group.KeyNames() {
+				logger.Printf("  Found %s key: %s\n", group.Name, key)
 			}
-			fmt.Printf("  Created new secret\n")
-			return nil
 		}
-		return fmt.Errorf("updating secret: %w", err)
 	}
-
-	fmt.Printf("  Updated existing secret\n")
-	return nil
-}
-
-func maskSecretValues(jsonStr string) string {
-	// Mask API key values, showing only first 8 chars
-	re := regexp.MustCompile(`("(?:[^"]*API_KEY|KEY)[^"]*"\s*:\s*")([^"]{8})([^"]*)"`)
-	return re.ReplaceAllString(jsonStr, `$1$2***"`)
-}
-
-// findEnvFile searches for .env file in standard locations
-func findEnvFile(projectName string) (string, error) {
-	// Search order:
-	// 1. .env in current directory
-	// 2. ../.env in parent directory
-	// 3. ~/.plexusone/projects/{project}/.env (if project specified)
-	// 4. ~/.plexusone/.env (global fallback)
-
-	candidates := []string{
-		".env",
-		"../.env",
+	if !skipValidation {
+		if err := validateSecrets(ctx, file.Groups, validateKeys); err != nil {
+			return err
+		}
 	}
 
-	// Add project-specific and global paths
-	if home, err := os.UserHomeDir(); err == nil {
-		if projectName != "" {
-			candidates = append(candidates, filepath.Join(home, DefaultConfigDir, "projects", projectName, ".env"))
-		}
-		candidates = append(candidates, filepath.Join(home, DefaultConfigDir, ".env"))
+	logger.Printf("AWS Region: %s\n", region)
+	logger.Printf("Secret prefix: %s\n", prefix)
+	logger.Printf("Backend: %s\n", backendName)
+	if len(replicas) > 0 {
+		logger.Printf("Replica regions: %s\n", strings.Join(envsecrets.ReplicaRegionNames(replicas), ", "))
+	}
+	if dryRun {
+		logger.Printf("Mode: DRY RUN (no changes will be made)\n")
+	}
+	logger.Println()
+
+	// Create AWS client (also used in dry-run mode to diff against current values)
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(region),
+		// PutParameter has a low default rate limit
+		config.WithRetryMode(aws.RetryModeAdaptive),
+	)
+	if err != nil {
+		return fmt.Errorf("loading AWS config: %w", err)
+	}
+	var store envsecrets.Backend
+	if len(replicas) > 0 {
+		store = envsecrets.NewReplicatedSecretsManagerBackend(secretsmanager.NewFromConfig(cfg), replicas)
+	} else if store, err = envsecrets.NewBackend(backendName, cfg); err != nil {
+		return err
 	}
 
-	for _, path := range candidates {
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
+	// The caller keeps read access, so the next push can diff the secrets
+	if len(readerARNs) > 0 {
+		identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return fmt.Errorf("getting caller identity for --restrict-read: %w", err)
 		}
+		readerARNs = append(readerARNs, envsecrets.CallerPrincipalARNs(aws.ToString(identity.Arn))...)
 	}
 
-	return "", fmt.Errorf("no .env file found in: .env, ../.env, or ~/.plexusone/")
-}
-
-// detectProjectName tries to detect the project name from config.json or directory name
-func detectProjectName() string {
-	// Try to read stackName from config.json
-	configPaths := []string{"config.json", "../config.json"}
-	for _, path := range configPaths {
-		if data, err := os.ReadFile(path); err == nil {
-			var config struct {
-				StackName string `json:"stackName"`
-			}
-			if json.Unmarshal(data, &config) == nil && config.StackName != "" {
-				return config.StackName
-			}
-		}
+	// Process each group
+	results, err := envsecrets.PushGroups(ctx, store, file.Groups, envsecrets.PushOptions{
+		Prefix:       prefix,
+		DryRun:       dryRun,
+		Prune:        prune,
+		Tags:         tags,
+		ReaderARNs:   readerARNs,
+		SyncReplicas: syncReplicas,
+		Out:          logger.Stdout(),
+	})
+	if err != nil {
+		return err
 	}
 
-	// Fall back to current directory name
-	if wd, err := os.Getwd(); err == nil {
-		return filepath.Base(wd)
+	logger.Event(cliout.EventSecretsPushed, map[string]any{
+		"envFile": envFile,
+		"backend": backendName,
+		"prefix":  prefix,
+		"dryRun":  dryRun,
+		"secrets": cliout.SecretResults(results),
+	})
+
+	logger.Println()
+	if dryRun {
+		logger.Printf("Done! [DRY RUN] Planned: %s\n", envsecrets.Summarize(results))
+	} else {
+		logger.Printf("Done! Secrets: %s\n", envsecrets.Summarize(results))
 	}
+	logger.Println()
+	logger.Printf("To verify:\n")
+	logger.Printf("  %s\n", store.VerifyCommand(region, prefix))
 
-	return ""
+	return nil
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+	"github.com/plexusone/agentkit-aws-cdk/internal/cliout"
+)
+
+// runPull reads the secret of each group and writes its keys to an env
+// file, masked unless reveal is set.
+func runPull(outFile, region, prefix, backendName string, defs []envsecrets.Group, reveal bool) error {
+	ctx := context.Background()
+	logger.Printf("AWS Region: %s\n", region)
+	logger.Printf("Secret prefix: %s\n", prefix)
+	logger.Printf("Backend: %s\n", backendName)
+	logger.Println()
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS config: %w", err)
+	}
+	store, err := envsecrets.NewBackend(backendName, cfg)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := envsecrets.PullGroups(ctx, store, defs, prefix)
+	if err != nil {
+		return err
+	}
+	found := 0
+	events := make([]map[string]any, 0, len(secrets))
+	for _, secret := range secrets {
+		keys := make([]string, 0, len(secret.Keys))
+		for key := range secret.Keys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if secret.Found {
+			found++
+			logger.Printf("Read %s (%d keys)\n", secret.Name, len(keys))
+		} else {
+			logger.Printf("Skipping %s (not found)\n", secret.Name)
+		}
+		events = append(events, map[string]any{"name": secret.Name, "found": secret.Found, "keys": keys})
+	}
+	if found == 0 {
+		return fmt.Errorf("no secrets found; check --prefix, --backend, and --region")
+	}
+
+	data, err := envsecrets.FormatEnv(secrets, reveal)
+	if err != nil {
+		return err
+	}
+	if outFile == "-" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			return fmt.Errorf("writing env file: %w", err)
+		}
+	} else if err := os.WriteFile(outFile, data, 0o600); err != nil {
+		return fmt.Errorf("writing env file: %w", err)
+	}
+
+	logger.Event(cliout.EventSecretsPulled, map[string]any{
+		"outFile":  outFile,
+		"backend":  backendName,
+		"prefix":   prefix,
+		"revealed": reveal,
+		"secrets":  events,
+	})
+
+	logger.Println()
+	if outFile != "-" {
+		logger.Printf("Done! Wrote %s\n", outFile)
+	}
+	if reveal {
+		logger.Printf("The file holds real secret values; keep it out of version control.\n")
+	} else {
+		logger.Printf("Values are masked; use --reveal to write them.\n")
+	}
+	return nil
+}
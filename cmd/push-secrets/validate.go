@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+// liveCheckTimeout bounds each provider call of --validate-keys.
+const liveCheckTimeout = 10 * time.Second
+
+// validateSecrets checks the values about to be pushed and, with live set,
+// verifies the LLM provider keys with their providers. Problems are
+// printed as warnings; an error is returned if any should stop the push.
+func validateSecrets(ctx context.Context, groups []envsecrets.SecretGroup, live bool) error {
+	problems := envsecrets.ValidateValues(groups)
+	if live {
+		logger.Printf("Verifying LLM provider keys...\n")
+		problems = append(problems, envsecrets.CheckKeysLive(ctx, &http.Client{Timeout: liveCheckTimeout}, groups)...)
+	}
+	for _, problem := range problems {
+		logger.Warnf("%s", problem)
+	}
+	if envsecrets.Blocking(problems) {
+		return fmt.Errorf("refusing to push invalid values; fix them or use --skip-validation")
+	}
+	return nil
+}
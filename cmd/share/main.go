@@ -0,0 +1,214 @@
+// share hands out expiring links to AWS AgentCore stack details.
+//
+// share outputs uploads a redacted summary of a stack's status and outputs
+// to S3 and prints a presigned URL, so collaborators without IAM access can
+// read it until the link expires.
+//
+// Usage:
+//
+//	share outputs [flags]
+//
+// Examples:
+//
+//	share outputs --bucket my-share-bucket                 # Link valid for 24 hours
+//	share outputs --bucket my-share-bucket --expires 2h    # Link valid for 2 hours
+//	share outputs --bucket my-share-bucket --format json   # Share JSON instead of text
+//
+// Install:
+//
+//	go install github.com/plexusone/agentkit-aws-cdk/cmd/share@latest
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+const (
+	// DefaultKeyPrefix is the default S3 key prefix of shared objects.
+	DefaultKeyPrefix = "agentkit-share/"
+
+	// maxExpires is the longest validity of a SigV4 presigned URL.
+	maxExpires = 7 * 24 * time.Hour
+
+	// bucketEnvVar names the default bucket.
+	bucketEnvVar = "AGENTKIT_SHARE_BUCKET"
+)
+
+// Summary formats.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+var (
+	region    = flag.String("region", "", "AWS region (default: AWS_REGION or us-east-1)")
+	stack     = flag.String("stack", "", "Stack name (default: stackName from config file)")
+	bucket    = flag.String("bucket", "", "S3 bucket to upload the summary to (default: "+bucketEnvVar+")")
+	keyPrefix = flag.String("key-prefix", DefaultKeyPrefix, "S3 key prefix of the uploaded summary")
+	expires   = flag.Duration("expires", 24*time.Hour, "How long the link is valid (at most 168h)")
+	format    = flag.String("format", formatText, "Summary format: text or json")
+	dryRun    = flag.Bool("dry-run", false, "Print the summary without uploading it")
+)
+
+func main() {
+	flag.Usage = func() {
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "Usage: %s outputs [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Share a redacted summary of an AWS AgentCore stack through an expiring link.\n\n")
+		fmt.Fprintf(os.Stderr, "Stack is auto-detected from config.json stackName if not specified.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nRedaction:\n")
+		fmt.Fprintf(os.Stderr, "  Account IDs are masked, and outputs naming roles, secrets, keys, tokens,\n")
+		fmt.Fprintf(os.Stderr, "  passwords, or credentials are omitted.\n")
+	}
+
+	if len(os.Args) < 2 || os.Args[1] != "outputs" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := flag.CommandLine.Parse(os.Args[2:]); err != nil {
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *expires <= 0 || *expires > maxExpires {
+		return fmt.Errorf("--expires must be between 1s and %s", maxExpires)
+	}
+	if *format != formatText && *format != formatJSON {
+		return fmt.Errorf("--format must be %s or %s", formatText, formatJSON)
+	}
+
+	stackName := *stack
+	if stackName == "" {
+		stackName = envsecrets.DetectStackName()
+	}
+	if stackName == "" {
+		return fmt.Errorf("no stack name found; set --stack or run from a directory with config.json")
+	}
+
+	shareBucket := *bucket
+	if shareBucket == "" {
+		shareBucket = os.Getenv(bucketEnvVar)
+	}
+	if shareBucket == "" && !*dryRun {
+		return fmt.Errorf("no bucket set; use --bucket or %s", bucketEnvVar)
+	}
+
+	// Determine region
+	awsRegion := *region
+	if awsRegion == "" {
+		awsRegion = os.Getenv("AWS_REGION")
+	}
+	if awsRegion == "" {
+		awsRegion = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if awsRegion == "" {
+		awsRegion = "us-east-1"
+	}
+
+	ctx := context.Background()
+
+	// Load AWS config
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(awsRegion))
+	if err != nil {
+		return fmt.Errorf("loading AWS config: %w", err)
+	}
+
+	deployed, err := agentcore.FromStackOutputs(ctx, cloudformation.NewFromConfig(cfg), stackName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	summary := newSummary(deployed, awsRegion, now, now.Add(*expires))
+	body, contentType, err := summary.render(*format)
+	if err != nil {
+		return fmt.Errorf("rendering summary: %w", err)
+	}
+
+	if *dryRun {
+		fmt.Print(string(body))
+		return nil
+	}
+
+	key, err := objectKey(*keyPrefix, stackName, now, *format)
+	if err != nil {
+		return err
+	}
+
+	client := s3.NewFromConfig(cfg)
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(shareBucket),
+		Key:                  aws.String(key),
+		Body:                 strings.NewReader(string(body)),
+		ContentType:          aws.String(contentType),
+		Expires:              aws.Time(summary.ExpiresAt),
+		ServerSideEncryption: s3types.ServerSideEncryptionAes256,
+		Tagging:              aws.String("agentkit-share=true"),
+	}); err != nil {
+		return fmt.Errorf("uploading summary to s3://%s/%s: %w", shareBucket, key, err)
+	}
+
+	presigned, err := s3.NewPresignClient(client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(shareBucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(*expires))
+	if err != nil {
+		return fmt.Errorf("presigning URL: %w", err)
+	}
+
+	fmt.Printf("Uploaded s3://%s/%s\n", shareBucket, key)
+	fmt.Printf("Link expires: %s\n", summary.ExpiresAt.Format(time.RFC3339))
+	if warning := credentialsWarning(ctx, cfg, summary.ExpiresAt); warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	fmt.Println()
+	fmt.Println(presigned.URL)
+	return nil
+}
+
+// objectKey returns an unguessable key for a summary.
+func objectKey(prefix, stackName string, now time.Time, format string) (string, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating object key: %w", err)
+	}
+	ext := ".txt"
+	if format == formatJSON {
+		ext = ".json"
+	}
+	return fmt.Sprintf("%s%s/%s-%s%s", prefix, stackName, now.Format("20060102-150405"), hex.EncodeToString(nonce), ext), nil
+}
+
+// credentialsWarning warns when the signing credentials expire before the
+// link, which invalidates the link early.
+func credentialsWarning(ctx context.Context, cfg aws.Config, linkExpires time.Time) string {
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil || !creds.CanExpire || !creds.Expires.Before(linkExpires) {
+		return ""
+	}
+	return fmt.Sprintf("the link was signed with temporary credentials that expire at %s; it stops working then. Sign with long-term credentials for longer links",
+		creds.Expires.UTC().Format(time.RFC3339))
+}
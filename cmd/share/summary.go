@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+var (
+	// accountIDPattern matches AWS account IDs in ARNs and ECR image URIs.
+	accountIDPattern = regexp.MustCompile(`\b\d{12}\b`)
+
+	// sensitiveOutputPattern matches output keys that are omitted from summaries.
+	sensitiveOutputPattern = regexp.MustCompile(`(?i)role|secret|kms|key|token|password|credential`)
+)
+
+// redactedAccount replaces account IDs.
+const redactedAccount = "************"
+
+// summary is the redacted, shareable view of a deployed stack.
+type summary struct {
+	StackName   string            `json:"stackName"`
+	Region      string            `json:"region"`
+	Status      string            `json:"status"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+	ExpiresAt   time.Time         `json:"expiresAt"`
+	GatewayURL  string            `json:"gatewayUrl,omitempty"`
+	Agents      []agentSummary    `json:"agents,omitempty"`
+	Outputs     map[string]string `json:"outputs,omitempty"`
+}
+
+// agentSummary is the redacted view of a deployed agent.
+type agentSummary struct {
+	Name      string `json:"name"`
+	RuntimeID string `json:"runtimeId,omitempty"`
+	Image     string `json:"image,omitempty"`
+	MemoryID  string `json:"memoryId,omitempty"`
+}
+
+// newSummary builds a redacted summary of a deployed stack.
+func newSummary(deployed *agentcore.DeployedStack, region string, generatedAt, expiresAt time.Time) *summary {
+	s := &summary{
+		StackName:   deployed.StackName,
+		Region:      region,
+		Status:      deployed.Status,
+		GeneratedAt: generatedAt,
+		ExpiresAt:   expiresAt,
+		GatewayURL:  redact(deployed.GatewayURL),
+		Outputs:     make(map[string]string),
+	}
+
+	for _, name := range deployed.AgentNames() {
+		agent := deployed.Agents[name]
+		s.Agents = append(s.Agents, agentSummary{
+			Name:      name,
+			RuntimeID: agent.RuntimeID,
+			Image:     redact(agent.Image),
+			MemoryID:  agent.MemoryID,
+		})
+	}
+
+	// Agent and gateway outputs are listed above
+	for key, value := range deployed.Outputs {
+		if sensitiveOutputPattern.MatchString(key) || strings.HasPrefix(key, "Agent") || key == "GatewayUrl" {
+			continue
+		}
+		s.Outputs[key] = redact(value)
+	}
+	return s
+}
+
+// redact masks account IDs.
+func redact(value string) string {
+	return accountIDPattern.ReplaceAllString(value, redactedAccount)
+}
+
+// render renders the summary and returns it with its content type.
+func (s *summary) render(format string) ([]byte, string, error) {
+	if format == formatJSON {
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return nil, "", err
+		}
+		return append(data, '\n'), "application/json", nil
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Stack:     %s\n", s.StackName)
+	fmt.Fprintf(&b, "Region:    %s\n", s.Region)
+	fmt.Fprintf(&b, "Status:    %s\n", s.Status)
+	fmt.Fprintf(&b, "Generated: %s\n", s.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Expires:   %s\n", s.ExpiresAt.Format(time.RFC3339))
+	if s.GatewayURL != "" {
+		fmt.Fprintf(&b, "\nGateway URL: %s\n", s.GatewayURL)
+	}
+
+	if len(s.Agents) > 0 {
+		fmt.Fprintf(&b, "\nAgents:\n")
+		for _, agent := range s.Agents {
+			fmt.Fprintf(&b, "  %s\n", agent.Name)
+			if agent.RuntimeID != "" {
+				fmt.Fprintf(&b, "    Runtime ID: %s\n", agent.RuntimeID)
+			}
+			if agent.Image != "" {
+				fmt.Fprintf(&b, "    Image:      %s\n", agent.Image)
+			}
+			if agent.MemoryID != "" {
+				fmt.Fprintf(&b, "    Memory ID:  %s\n", agent.MemoryID)
+			}
+		}
+	}
+
+	if len(s.Outputs) > 0 {
+		keys := make([]string, 0, len(s.Outputs))
+		for key := range s.Outputs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(&b, "\nOutputs:\n")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "  %s: %s\n", key, s.Outputs[key])
+		}
+	}
+	return b.Bytes(), "text/plain; charset=utf-8", nil
+}
@@ -0,0 +1,340 @@
+// upgrade rewrites projects for breaking changes of agentkit-aws-cdk.
+//
+// It scans Go files (e.g. the CDK app's main.go) and go.mod files for
+// usages removed between two releases, prints the rewrites as a diff, and
+// with --write applies them. Changes that cannot be rewritten are listed as
+// manual steps.
+//
+// Usage:
+//
+//	upgrade [flags] [path ...]
+//
+// Examples:
+//
+//	upgrade                          # Preview the upgrade of the current directory
+//	upgrade --write                  # Apply it
+//	upgrade --from v0.1.1 --to v0.2.0 stacks/
+//	upgrade --list                   # List the known migrations
+//
+// Install:
+//
+//	go install github.com/plexusone/agentkit-aws-cdk/cmd/upgrade@latest
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// requirePattern matches the agentkit-aws-cdk requirement in go.mod, under
+// the current or the previous module path.
+var requirePattern = regexp.MustCompile(`github\.com/(?:agentplexus|plexusone)/agentkit-aws-cdk\s+(v[0-9]+\.[0-9]+\.[0-9]+\S*)`)
+
+// skipDirs are directories that never hold user code.
+var skipDirs = map[string]bool{
+	".git":         true,
+	"cdk.out":      true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+var (
+	from  = flag.String("from", "", "Version upgraded from (default: the version required in go.mod)")
+	to    = flag.String("to", "", "Version upgraded to (default: the version of this tool, or the latest known release)")
+	write = flag.Bool("write", false, "Rewrite the files instead of printing a diff")
+	list  = flag.Bool("list", false, "List the known migrations and exit")
+)
+
+// change is a rewritten line of a file.
+type change struct {
+	line     int
+	old, new string
+}
+
+func main() {
+	flag.Usage = func() {
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [path ...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Rewrite Go and go.mod files under each path (default: current directory)\n")
+		fmt.Fprintf(os.Stderr, "for breaking changes of agentkit-aws-cdk between two versions.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *list {
+		printMigrations()
+		return nil
+	}
+
+	roots := flag.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	files, err := findFiles(roots)
+	if err != nil {
+		return err
+	}
+
+	fromVersion, toVersion, err := versions(files)
+	if err != nil {
+		return err
+	}
+	if !newer(toVersion, fromVersion) {
+		fmt.Fprintf(os.Stderr, "Already at %s, nothing to upgrade.\n", toVersion)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Upgrading from %s to %s\n", fromVersion, toVersion)
+
+	var pending []migration
+	for _, m := range migrations {
+		if applies(m.version, fromVersion, toVersion) {
+			pending = append(pending, m)
+		}
+	}
+
+	changed := 0
+	used := make(map[int]bool)
+	for _, path := range files {
+		content, err := os.ReadFile(path) //nolint:gosec // G304: user-provided project files
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		upgraded, changes := rewrite(path, content, pending, toVersion, used)
+		if len(changes) == 0 {
+			continue
+		}
+		changed++
+
+		if !*write {
+			printDiff(path, changes)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, upgraded, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		fmt.Printf("  ✓ %s (%d lines)\n", path, len(changes))
+	}
+
+	fmt.Fprintln(os.Stderr)
+	if len(used) > 0 {
+		fmt.Fprintln(os.Stderr, "Migrations:")
+		for i, m := range pending {
+			if used[i] {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", m.version, m.description)
+			}
+		}
+	}
+	switch {
+	case changed == 0:
+		fmt.Fprintln(os.Stderr, "No files need rewriting.")
+	case *write:
+		fmt.Fprintf(os.Stderr, "Rewrote %d files. Run `go mod tidy` and `cdk diff` to check the result.\n", changed)
+	default:
+		fmt.Fprintf(os.Stderr, "%d files need rewriting. Run with --write to apply.\n", changed)
+	}
+
+	var manual []note
+	for _, n := range notes {
+		if applies(n.version, fromVersion, toVersion) {
+			manual = append(manual, n)
+		}
+	}
+	if len(manual) > 0 {
+		fmt.Fprintln(os.Stderr, "\nManual steps:")
+		for _, n := range manual {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", n.version, n.text)
+		}
+	}
+	return nil
+}
+
+// findFiles returns the files under the roots that any migration applies to.
+func findFiles(roots []string) ([]string, error) {
+	var files []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && (skipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			for _, m := range migrations {
+				if m.files(path) {
+					files = append(files, path)
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", root, err)
+		}
+	}
+	return files, nil
+}
+
+// versions returns the versions of the upgrade, detecting the current
+// version from the go.mod files.
+func versions(files []string) (string, string, error) {
+	fromVersion := *from
+	if fromVersion == "" {
+		for _, path := range files {
+			if !goModFiles(path) {
+				continue
+			}
+			content, err := os.ReadFile(path) //nolint:gosec // G304: user-provided project files
+			if err != nil {
+				return "", "", fmt.Errorf("reading %s: %w", path, err)
+			}
+			if m := requirePattern.FindSubmatch(content); m != nil {
+				fromVersion = string(m[1])
+				break
+			}
+		}
+		if fromVersion == "" {
+			return "", "", fmt.Errorf("no go.mod requires agentkit-aws-cdk (set --from)")
+		}
+	}
+
+	toVersion := *to
+	if toVersion == "" {
+		toVersion = toolVersion()
+		if toVersion == "" || newer(latestVersion(), toVersion) {
+			toVersion = latestVersion()
+		}
+	}
+
+	for _, v := range []string{fromVersion, toVersion} {
+		if _, ok := parseVersion(v); !ok {
+			return "", "", fmt.Errorf("invalid version %q (expected vMAJOR.MINOR.PATCH)", v)
+		}
+	}
+	if newer(fromVersion, toVersion) {
+		return "", "", fmt.Errorf("--to %s is older than --from %s", toVersion, fromVersion)
+	}
+	return fromVersion, toVersion, nil
+}
+
+// rewrite applies the migrations that match a file, recording the indexes
+// of the migrations that changed a line in used.
+func rewrite(path string, content []byte, pending []migration, toVersion string, used map[int]bool) ([]byte, []change) {
+	var changes []change
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	for i, line := range lines {
+		old := string(line)
+		upgraded := old
+		for j, m := range pending {
+			if !m.files(path) || !m.pattern.MatchString(upgraded) {
+				continue
+			}
+			upgraded = m.pattern.ReplaceAllString(upgraded, m.replace(toVersion))
+			used[j] = true
+		}
+		if upgraded != old {
+			changes = append(changes, change{line: i + 1, old: old, new: upgraded})
+			lines[i] = []byte(upgraded)
+		}
+	}
+	return bytes.Join(lines, nil), changes
+}
+
+// printDiff prints the changes of a file as a unified diff.
+func printDiff(path string, changes []change) {
+	path = filepath.ToSlash(path)
+	fmt.Printf("--- a/%s\n+++ b/%s\n", path, path)
+	for _, c := range changes {
+		fmt.Printf("@@ -%d +%d @@\n", c.line, c.line)
+		fmt.Printf("-%s", withNewline(c.old))
+		fmt.Printf("+%s", withNewline(c.new))
+	}
+}
+
+// printMigrations prints the known migrations and manual steps.
+func printMigrations() {
+	for _, m := range migrations {
+		fmt.Printf("%s  %s\n", m.version, m.description)
+	}
+	for _, n := range notes {
+		fmt.Printf("%s  manual: %s\n", n.version, n.text)
+	}
+}
+
+// withNewline terminates a line that ends the file without a newline.
+func withNewline(line string) string {
+	if strings.HasSuffix(line, "\n") {
+		return line
+	}
+	return line + "\n\\ No newline at end of file\n"
+}
+
+// toolVersion returns the released version of this tool, or "" for
+// development builds.
+func toolVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok || bi.Main.Version == "" || bi.Main.Version == "(devel)" {
+		return ""
+	}
+	return bi.Main.Version
+}
+
+// newer reports whether version a is newer than version b. Invalid versions
+// are never newer.
+func newer(a, b string) bool {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses "v0.2.0" into major, minor, patch. Pre-release and
+// build suffixes are ignored.
+func parseVersion(v string) ([3]int, bool) {
+	var parsed [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+// migration is a rewrite of a breaking change. It applies to an upgrade
+// that crosses its version, line by line, to the files it matches.
+type migration struct {
+	// version is the release that introduced the change.
+	version string
+
+	// description is shown in the upgrade summary.
+	description string
+
+	// files reports whether the migration applies to a file.
+	files func(path string) bool
+
+	// pattern matches the deprecated usage within a line.
+	pattern *regexp.Regexp
+
+	// replace returns the replacement of a match (with $n expanded) for
+	// an upgrade to version to.
+	replace func(to string) string
+}
+
+// note is a manual step of an upgrade that cannot be rewritten.
+type note struct {
+	version string
+	text    string
+}
+
+// migrations are the rewrites of breaking changes, oldest first. Each
+// deprecated field or builder method gets a migration in the release that
+// removes it.
+var migrations = []migration{
+	{
+		version:     "v0.2.0",
+		description: "module path github.com/agentplexus/agentkit-aws-cdk moved to github.com/plexusone/agentkit-aws-cdk",
+		files:       anyOf(goFiles, goModFiles),
+		pattern:     regexp.MustCompile(`github\.com/agentplexus/agentkit-aws-cdk\b`),
+		replace:     fixed("github.com/plexusone/agentkit-aws-cdk"),
+	},
+	{
+		version:     "v0.2.0",
+		description: "module path github.com/agentplexus/agentkit moved to github.com/plexusone/agentkit",
+		files:       anyOf(goFiles, goModFiles),
+		pattern:     regexp.MustCompile(`github\.com/agentplexus/agentkit([^-]|$)`),
+		replace:     fixed("github.com/plexusone/agentkit$1"),
+	},
+	{
+		// Required after the module path migration, since the new path has
+		// no releases before v0.2.0.
+		version:     "v0.2.0",
+		description: "go.mod requires the target version of agentkit-aws-cdk",
+		files:       goModFiles,
+		pattern:     regexp.MustCompile(`^(\s*(?:require\s+)?github\.com/plexusone/agentkit-aws-cdk\s+)v\S+`),
+		replace: func(to string) string {
+			return "${1}" + to
+		},
+	},
+}
+
+// notes are the manual steps of breaking changes, oldest first.
+var notes = []note{
+	{version: "v0.2.0", text: "move CLI configuration from ~/.agentplexus/ to ~/.plexusone/"},
+	{version: "v0.2.0", text: "run `cdk diff`: AgentCore runtimes, endpoints, and gateways are now created by the stack"},
+}
+
+// latestVersion returns the newest release with a migration or note.
+func latestVersion() string {
+	latest := "v0.0.0"
+	for _, m := range migrations {
+		if newer(m.version, latest) {
+			latest = m.version
+		}
+	}
+	for _, n := range notes {
+		if newer(n.version, latest) {
+			latest = n.version
+		}
+	}
+	return latest
+}
+
+// applies reports whether a change released in version is part of an
+// upgrade from version from to version to.
+func applies(version, from, to string) bool {
+	return newer(version, from) && !newer(version, to)
+}
+
+// goFiles matches Go source files.
+func goFiles(path string) bool {
+	return filepath.Ext(path) == ".go"
+}
+
+// goModFiles matches go.mod files.
+func goModFiles(path string) bool {
+	return filepath.Base(path) == "go.mod"
+}
+
+// anyOf matches files matched by any of the matchers.
+func anyOf(matchers ...func(string) bool) func(string) bool {
+	return func(path string) bool {
+		for _, m := range matchers {
+			if m(path) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// fixed returns a replacement independent of the target version.
+func fixed(replacement string) func(string) string {
+	return func(string) string {
+		return replacement
+	}
+}
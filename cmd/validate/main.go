@@ -0,0 +1,129 @@
+// validate checks AgentCore config files against the config schema.
+//
+// It reports unknown fields (with the field probably meant), values of the
+// wrong type, and missing required fields with the path of each value, then
+// validates the configuration as deploy would. Without it, unknown fields are
+// silently ignored by the loaders.
+//
+// Usage:
+//
+//	validate [flags] [config-file...]
+//
+// Examples:
+//
+//	validate                         # Validate config.json/config.yaml
+//	validate --env-name prod         # Validate with the prod overlay merged
+//	validate agents/*.yaml           # Validate several files
+//	validate --schema > schema.json  # Write the JSON Schema
+//
+// Install:
+//
+//	go install github.com/plexusone/agentkit-aws-cdk/cmd/validate@latest
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+var (
+	envName = flag.String("env-name", "", "Environment overlay to merge over each config file (config.<env>.yaml)")
+	schema  = flag.Bool("schema", false, "Print the JSON Schema of config files and exit")
+	format  = flag.String("format", "text", "Output format: text or json")
+)
+
+// fileResult is the validation result of a config file in JSON output.
+type fileResult struct {
+	Path   string                  `json:"path"`
+	Valid  bool                    `json:"valid"`
+	Error  string                  `json:"error,omitempty"`
+	Issues []agentcore.ConfigIssue `json:"issues,omitempty"`
+}
+
+func main() {
+	flag.Usage = func() {
+		//nolint:gosec // G705: os.Args[0] in CLI usage text is safe
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [config-file...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Validate AgentCore config files against the config schema.\n\n")
+		fmt.Fprintf(os.Stderr, "Config file is auto-detected (config.json, config.yaml) if not specified.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *schema {
+		os.Stdout.Write(agentcore.ConfigJSONSchema()) //nolint:errcheck // Best-effort stdout write
+		return
+	}
+
+	valid, err := run(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !valid {
+		os.Exit(1)
+	}
+}
+
+// run validates the config files and prints the results. It reports
+// whether all files are valid.
+func run(paths []string) (bool, error) {
+	if *format != "text" && *format != "json" {
+		return false, fmt.Errorf("invalid --format %q: use text or json", *format)
+	}
+	if len(paths) == 0 {
+		path := agentcore.FindConfigFile()
+		if path == "" {
+			return false, fmt.Errorf("no config file specified and none found (config.json, config.yaml) in the current or parent directory")
+		}
+		paths = []string{path}
+	}
+
+	var opts []agentcore.LoadOption
+	if *envName != "" {
+		opts = append(opts, agentcore.WithEnvironment(*envName))
+	}
+
+	valid := true
+	results := make([]fileResult, 0, len(paths))
+	for _, path := range paths {
+		result := fileResult{Path: path}
+		issues, err := agentcore.ValidateConfigFile(path, opts...)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Issues = issues
+		result.Valid = err == nil && len(issues) == 0
+		valid = valid && result.Valid
+		results = append(results, result)
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("encoding results: %w", err)
+		}
+		fmt.Println(string(data))
+		return valid, nil
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			fmt.Printf("%s: %s\n", result.Path, result.Error)
+		case result.Valid:
+			fmt.Printf("%s: valid\n", result.Path)
+		default:
+			fmt.Printf("%s: %d problem(s)\n", result.Path, len(result.Issues))
+			for _, issue := range result.Issues {
+				fmt.Printf("  %s\n", issue)
+			}
+		}
+	}
+	return valid, nil
+}
@@ -0,0 +1,88 @@
+package contracts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Contract holds the request and response schemas of an agent.
+type Contract struct {
+	// Request is the schema of payloads sent to the agent, if any.
+	Request *Schema `json:"request,omitempty"`
+
+	// Response is the schema of payloads returned by the agent, if any.
+	Response *Schema `json:"response,omitempty"`
+}
+
+// GetParameterAPI is the subset of the SSM client used by Load.
+// *ssm.Client satisfies it.
+type GetParameterAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// ParameterName returns the SSM parameter holding an agent's contract.
+func ParameterName(stackName, agentName string) string {
+	return fmt.Sprintf("/%s/agents/%s/contract", stackName, agentName)
+}
+
+// Load reads a contract from its SSM parameter.
+func Load(ctx context.Context, client GetParameterAPI, parameterName string) (*Contract, error) {
+	out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name: aws.String(parameterName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading contract %s: %w", parameterName, err)
+	}
+	contract, err := Parse([]byte(aws.ToString(out.Parameter.Value)))
+	if err != nil {
+		return nil, fmt.Errorf("contract %s: %w", parameterName, err)
+	}
+	return contract, nil
+}
+
+// Parse parses and checks a contract document.
+func Parse(data []byte) (*Contract, error) {
+	var contract Contract
+	if err := json.Unmarshal(data, &contract); err != nil {
+		return nil, fmt.Errorf("parsing contract: %w", err)
+	}
+	if contract.Request != nil {
+		if err := contract.Request.compile(""); err != nil {
+			return nil, fmt.Errorf("request schema: %w", err)
+		}
+	}
+	if contract.Response != nil {
+		if err := contract.Response.compile(""); err != nil {
+			return nil, fmt.Errorf("response schema: %w", err)
+		}
+	}
+	return &contract, nil
+}
+
+// ValidateRequest checks a request payload. Without a request schema every
+// payload is valid.
+func (c *Contract) ValidateRequest(payload []byte) error {
+	if c.Request == nil {
+		return nil
+	}
+	if err := c.Request.Validate(payload); err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	return nil
+}
+
+// ValidateResponse checks a response payload. Without a response schema
+// every payload is valid.
+func (c *Contract) ValidateResponse(payload []byte) error {
+	if c.Response == nil {
+		return nil
+	}
+	if err := c.Response.Validate(payload); err != nil {
+		return fmt.Errorf("response: %w", err)
+	}
+	return nil
+}
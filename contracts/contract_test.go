@@ -0,0 +1,65 @@
+package contracts
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// fakeSSM returns a parameter value.
+type fakeSSM struct {
+	value string
+}
+
+func (f fakeSSM) GetParameter(_ context.Context, params *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Name: params.Name, Value: aws.String(f.value)}}, nil
+}
+
+func TestLoad(t *testing.T) {
+	client := fakeSSM{value: `{"request": {"type": "object", "required": ["query"]}}`}
+	contract, err := Load(context.Background(), client, ParameterName("my-agents", "research"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := contract.ValidateRequest([]byte(`{"query": "x"}`)); err != nil {
+		t.Errorf("ValidateRequest: %v", err)
+	}
+	if err := contract.ValidateRequest([]byte(`{}`)); err == nil || !strings.HasPrefix(err.Error(), "request: ") {
+		t.Errorf("ValidateRequest of an invalid payload = %v", err)
+	}
+	// Without a response schema every response is valid
+	if err := contract.ValidateResponse([]byte(`"anything"`)); err != nil {
+		t.Errorf("ValidateResponse: %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		document string
+		wantErr  string
+	}{
+		{document: `{"request": {"type": "object"}, "response": {"type": "string"}}`},
+		{document: `{"response": {"oneOf": [{"type": "string"}]}}`, wantErr: `unsupported schema keywords ["oneOf"]`},
+		{document: `{"request": {"type": "text"}}`, wantErr: `request schema: $: unknown type "text"`},
+		{document: `[]`, wantErr: "parsing contract"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.document))
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Parse(%s): %v", tt.document, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("Parse(%s) error = %v, want %q", tt.document, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParameterName(t *testing.T) {
+	if got, want := ParameterName("my-agents", "research"), "/my-agents/agents/research/contract"; got != want {
+		t.Errorf("ParameterName = %q, want %q", got, want)
+	}
+}
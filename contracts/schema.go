@@ -0,0 +1,333 @@
+// Package contracts validates agent request and response payloads against
+// the JSON Schemas published by an AgentCoreStack.
+//
+// Each agent with a contract has an SSM parameter holding its schemas.
+// Callers (orchestration agents, CLIs, generated clients) load the contract
+// once and validate payloads before sending them and after receiving them:
+//
+//	contract, err := contracts.Load(ctx, ssm.NewFromConfig(cfg), contracts.ParameterName("my-agents", "research"))
+//	if err := contract.ValidateRequest(payload); err != nil {
+//		return err // Contract drift: the payload no longer matches the worker's schema
+//	}
+//
+// Schemas use a subset of JSON Schema (draft 2020-12): type, properties,
+// required, additionalProperties (boolean), items, enum, const, minLength,
+// maxLength, pattern, minimum, maximum, minItems, and maxItems.
+// Annotations such as title and description are ignored. Schemas with other
+// keywords, such as $ref, oneOf, or format, are rejected, since a payload
+// would pass validation without them being checked.
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a JSON Schema for a payload.
+type Schema struct {
+	// Type is the JSON type or list of types: object, array, string,
+	// number, integer, boolean, or null.
+	Type SchemaType `json:"type,omitempty"`
+
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// annotationKeywords are the keywords that don't constrain payloads, which
+// schemas may use.
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// schemaKeywords are the keywords of Schema, by their JSON names.
+var schemaKeywords = func() map[string]bool {
+	keywords := make(map[string]bool)
+	t := reflect.TypeOf(Schema{})
+	for i := range t.NumField() {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" {
+			keywords[name] = true
+		}
+	}
+	return keywords
+}()
+
+// UnmarshalJSON decodes a schema, rejecting keywords it doesn't enforce.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return fmt.Errorf("schema must be an object")
+	}
+	var unsupported []string
+	for keyword := range keywords {
+		if !schemaKeywords[keyword] && !annotationKeywords[keyword] {
+			unsupported = append(unsupported, keyword)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("unsupported schema keywords %q", unsupported)
+	}
+
+	type plain Schema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// SchemaType is a JSON Schema type keyword, which may be a single type or a
+// list of types.
+type SchemaType []string
+
+// validTypes are the JSON Schema type names.
+var validTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// UnmarshalJSON accepts a type name or a list of type names.
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// MarshalJSON writes a single type as a string.
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// ParseSchema parses and checks a JSON Schema.
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	if err := schema.compile(""); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// compile checks the schema and compiles its patterns.
+func (s *Schema) compile(path string) error {
+	for _, t := range s.Type {
+		if !validTypes[t] {
+			return fmt.Errorf("%s: unknown type %q", displayPath(path), t)
+		}
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", displayPath(path), err)
+		}
+		s.pattern = pattern
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s: property %q has no schema", displayPath(path), name)
+		}
+		if err := property.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidationError lists the ways a payload violates a schema.
+type ValidationError struct {
+	// Problems are the individual violations, each prefixed with the JSON
+	// path of the offending value ($ is the payload root).
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "payload does not match schema: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks a JSON payload against the schema. Violations are
+// returned as a *ValidationError.
+func (s *Schema) Validate(payload []byte) error {
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return fmt.Errorf("parsing payload: %w", err)
+	}
+	return s.ValidateValue(value)
+}
+
+// ValidateValue checks a decoded JSON value (as produced by
+// encoding/json) against the schema.
+func (s *Schema) ValidateValue(value any) error {
+	var problems []string
+	s.validate("", value, &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validate appends the violations of value to problems.
+func (s *Schema) validate(path string, value any, problems *[]string) {
+	report := func(format string, args ...any) {
+		*problems = append(*problems, displayPath(path)+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), jsonType(value))
+		return
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		report("value %s is not one of the allowed values", formatValue(value))
+	}
+	if s.Const != nil && !equalValues(s.Const, value) {
+		report("value %s must be %s", formatValue(value), formatValue(s.Const))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				report("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			switch {
+			case ok:
+				property.validate(path+"."+name, v[name], problems)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				report("unexpected property %q", name)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			report("expected at least %d items, got %d", *s.MinItems, len(v))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			report("expected at most %d items, got %d", *s.MaxItems, len(v))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			report("expected at least %d characters, got %d", *s.MinLength, length)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			report("expected at most %d characters, got %d", *s.MaxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("value does not match pattern %s", s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			report("value %v is less than the minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			report("value %v is greater than the maximum %v", v, *s.Maximum)
+		}
+	}
+}
+
+// matchesType reports whether value has one of the schema's types.
+func (s *Schema) matchesType(value any) bool {
+	actual := jsonType(value)
+	for _, t := range s.Type {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// containsValue reports whether values contains value.
+func containsValue(values []any, value any) bool {
+	for _, candidate := range values {
+		if equalValues(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares two JSON values by their encoding.
+func equalValues(a, b any) bool {
+	return formatValue(a) == formatValue(b)
+}
+
+// formatValue returns the JSON encoding of a value.
+func formatValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// displayPath returns a JSON path for messages.
+func displayPath(path string) string {
+	return "$" + path
+}
@@ -0,0 +1,122 @@
+package contracts
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{name: "supported keywords", schema: `{"type": "object", "properties": {"query": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"}}, "required": ["query"], "additionalProperties": false}`},
+		{name: "annotations", schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "Request", "description": "A request", "type": "object", "properties": {"n": {"type": "integer", "default": 1, "examples": [1, 2]}}}`},
+		{name: "type list", schema: `{"type": ["string", "null"]}`},
+		{name: "ref", schema: `{"$ref": "#/$defs/request", "$defs": {"request": {"type": "object"}}}`, wantErr: `unsupported schema keywords ["$defs" "$ref"]`},
+		{name: "combinators", schema: `{"oneOf": [{"type": "string"}], "anyOf": [], "allOf": [], "not": {}}`, wantErr: `unsupported schema keywords ["allOf" "anyOf" "not" "oneOf"]`},
+		{name: "nested property", schema: `{"type": "object", "properties": {"email": {"type": "string", "format": "email"}}}`, wantErr: `unsupported schema keywords ["format"]`},
+		{name: "nested items", schema: `{"type": "array", "items": {"type": "number", "exclusiveMinimum": 0}}`, wantErr: `unsupported schema keywords ["exclusiveMinimum"]`},
+		{name: "boolean schema", schema: `{"type": "object", "properties": {"any": true}}`, wantErr: "schema must be an object"},
+		{name: "unknown type", schema: `{"type": "map"}`, wantErr: `unknown type "map"`},
+		{name: "invalid pattern", schema: `{"properties": {"id": {"pattern": "("}}}`, wantErr: "$.id: invalid pattern"},
+		{name: "invalid type keyword", schema: `{"type": 1}`, wantErr: "type must be a string or a list of strings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.schema))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ParseSchema: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ParseSchema error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["query", "limit"],
+		"additionalProperties": false,
+		"properties": {
+			"query": {"type": "string", "minLength": 1, "maxLength": 10, "pattern": "^[a-z ]+$"},
+			"limit": {"type": "integer", "minimum": 1, "maximum": 100},
+			"mode": {"enum": ["fast", "deep"]},
+			"version": {"const": 2},
+			"tags": {"type": "array", "minItems": 1, "maxItems": 2, "items": {"type": "string"}},
+			"score": {"type": "number"},
+			"note": {"type": ["string", "null"]}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{name: "valid", payload: `{"query": "go agents", "limit": 5, "mode": "deep", "version": 2, "tags": ["a"], "score": 5, "note": null}`},
+		{name: "integer as number", payload: `{"query": "a", "limit": 1, "score": 0.5}`},
+		{name: "wrong root type", payload: `[]`, want: []string{"$: expected object, got array"}},
+		{name: "missing and unexpected", payload: `{"extra": 1}`, want: []string{
+			`$: missing required property "query"`,
+			`$: missing required property "limit"`,
+			`$: unexpected property "extra"`,
+		}},
+		{name: "strings", payload: `{"query": "", "limit": 1}`, want: []string{
+			"$.query: expected at least 1 characters, got 0",
+			"$.query: value does not match pattern ^[a-z ]+$",
+		}},
+		{name: "max length counts runes", payload: `{"query": "ääääääääääa", "limit": 1}`, want: []string{
+			"$.query: expected at most 10 characters, got 11",
+			"$.query: value does not match pattern ^[a-z ]+$",
+		}},
+		{name: "numbers", payload: `{"query": "a", "limit": 1.5}`, want: []string{"$.limit: expected integer, got number"}},
+		{name: "range", payload: `{"query": "a", "limit": 101}`, want: []string{"$.limit: value 101 is greater than the maximum 100"}},
+		{name: "enum and const", payload: `{"query": "a", "limit": 0, "mode": "slow", "version": 1}`, want: []string{
+			"$.limit: value 0 is less than the minimum 1",
+			`$.mode: value "slow" is not one of the allowed values`,
+			"$.version: value 1 must be 2",
+		}},
+		{name: "arrays", payload: `{"query": "a", "limit": 1, "tags": ["a", 2, "c"]}`, want: []string{
+			"$.tags: expected at most 2 items, got 3",
+			"$.tags[1]: expected string, got integer",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.payload))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate error = %v, want a *ValidationError", err)
+			}
+			if !reflect.DeepEqual(validationErr.Problems, tt.want) {
+				t.Errorf("problems = %q, want %q", validationErr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidPayload(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"type": "object"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var validationErr *ValidationError
+	if err := schema.Validate([]byte(`{`)); err == nil || errors.As(err, &validationErr) {
+		t.Errorf("Validate of invalid JSON = %v, want a parse error", err)
+	}
+}
@@ -0,0 +1,169 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Cloud assembly artifact types.
+const (
+	artifactStack         = "aws:cloudformation:stack"
+	artifactAssetManifest = "cdk:asset-manifest"
+)
+
+// assemblyManifest is the manifest.json of a cloud assembly.
+type assemblyManifest struct {
+	Artifacts map[string]assemblyArtifact `json:"artifacts"`
+}
+
+// assemblyArtifact is an artifact of a cloud assembly.
+type assemblyArtifact struct {
+	Type         string             `json:"type"`
+	Environment  string             `json:"environment"`
+	Properties   artifactProperties `json:"properties"`
+	Dependencies []string           `json:"dependencies"`
+}
+
+// artifactProperties are the properties of stack and asset manifest
+// artifacts.
+type artifactProperties struct {
+	// Stack properties
+	TemplateFile                   string            `json:"templateFile"`
+	StackName                      string            `json:"stackName"`
+	Parameters                     map[string]string `json:"parameters"`
+	Tags                           map[string]string `json:"tags"`
+	TerminationProtection          bool              `json:"terminationProtection"`
+	AssumeRoleARN                  string            `json:"assumeRoleArn"`
+	CloudFormationExecutionRoleARN string            `json:"cloudFormationExecutionRoleArn"`
+	StackTemplateAssetObjectURL    string            `json:"stackTemplateAssetObjectUrl"`
+
+	// Asset manifest properties
+	File string `json:"file"`
+
+	RequiresBootstrapStackVersion     int    `json:"requiresBootstrapStackVersion"`
+	BootstrapStackVersionSSMParameter string `json:"bootstrapStackVersionSsmParameter"`
+}
+
+// assetManifest is the {stack}.assets.json of a cloud assembly.
+type assetManifest struct {
+	Files        map[string]fileAsset `json:"files"`
+	DockerImages map[string]any       `json:"dockerImages"`
+}
+
+// fileAsset is a file or directory published to S3.
+type fileAsset struct {
+	DisplayName string `json:"displayName"`
+	Source      struct {
+		Path      string `json:"path"`
+		Packaging string `json:"packaging"`
+	} `json:"source"`
+	Destinations map[string]fileDestination `json:"destinations"`
+}
+
+// fileDestination is an S3 object a file asset is published to.
+type fileDestination struct {
+	BucketName    string `json:"bucketName"`
+	ObjectKey     string `json:"objectKey"`
+	Region        string `json:"region"`
+	AssumeRoleARN string `json:"assumeRoleArn"`
+}
+
+// assemblyStack is a stack of a cloud assembly and its assets.
+type assemblyStack struct {
+	dir      string
+	id       string
+	artifact assemblyArtifact
+	assets   []assetManifest
+}
+
+// readAssemblyStack reads the stack with the given artifact ID or stack
+// name from the cloud assembly in dir. An empty name selects the only
+// stack of the assembly.
+func readAssemblyStack(dir, name string) (*assemblyStack, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json")) //nolint:gosec // G304: assembly directory is provided by the caller
+	if err != nil {
+		return nil, fmt.Errorf("reading cloud assembly: %w", err)
+	}
+	var manifest assemblyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing cloud assembly manifest: %w", err)
+	}
+
+	var ids []string
+	for id, artifact := range manifest.Artifacts {
+		if artifact.Type == artifactStack && (name == "" || id == name || artifact.Properties.StackName == name) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	switch {
+	case len(ids) == 0 && name != "":
+		return nil, fmt.Errorf("cloud assembly %s has no stack %s", dir, name)
+	case len(ids) == 0:
+		return nil, fmt.Errorf("cloud assembly %s has no stacks", dir)
+	case len(ids) > 1:
+		return nil, fmt.Errorf("cloud assembly %s has stacks %s; set StackName", dir, strings.Join(ids, ", "))
+	}
+
+	stack := &assemblyStack{dir: dir, id: ids[0], artifact: manifest.Artifacts[ids[0]]}
+	if stack.artifact.Properties.StackName == "" {
+		stack.artifact.Properties.StackName = stack.id
+	}
+	for _, dep := range stack.artifact.Dependencies {
+		artifact := manifest.Artifacts[dep]
+		if artifact.Type != artifactAssetManifest {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, artifact.Properties.File)) //nolint:gosec // G304: file is named by the assembly manifest
+		if err != nil {
+			return nil, fmt.Errorf("reading asset manifest: %w", err)
+		}
+		var assets assetManifest
+		if err := json.Unmarshal(data, &assets); err != nil {
+			return nil, fmt.Errorf("parsing asset manifest %s: %w", artifact.Properties.File, err)
+		}
+		if len(assets.DockerImages) > 0 {
+			return nil, fmt.Errorf("stack %s has Docker image assets, which Deploy doesn't build; push images to a registry and reference them by URI", stack.id)
+		}
+		stack.assets = append(stack.assets, assets)
+	}
+	return stack, nil
+}
+
+// environment is the account, region, and partition placeholders of the
+// cloud assembly are resolved with.
+type environment struct {
+	account   string
+	region    string
+	partition string
+}
+
+// resolve replaces the ${AWS::AccountId}, ${AWS::Region}, and
+// ${AWS::Partition} placeholders in s.
+func (e environment) resolve(s string) string {
+	return strings.NewReplacer(
+		"${AWS::AccountId}", e.account,
+		"${AWS::Region}", e.region,
+		"${AWS::Partition}", e.partition,
+	).Replace(s)
+}
+
+// checkEnvironment checks that the stack targets the account and region
+// of env, if it names them.
+func (s *assemblyStack) checkEnvironment(env environment) error {
+	account, region, ok := strings.Cut(strings.TrimPrefix(s.artifact.Environment, "aws://"), "/")
+	if !ok {
+		return nil
+	}
+	if account != "unknown-account" && account != env.account {
+		return fmt.Errorf("stack %s targets account %s, but the credentials are for %s", s.id, account, env.account)
+	}
+	if region != "unknown-region" && region != env.region {
+		return fmt.Errorf("stack %s targets region %s, but the configured region is %s", s.id, region, env.region)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files by name into dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const testManifest = `{"artifacts": {
+	"Research": {
+		"type": "aws:cloudformation:stack",
+		"environment": "aws://unknown-account/unknown-region",
+		"properties": {"templateFile": "Research.template.json", "stackName": "research-agents"},
+		"dependencies": ["Research.assets"]
+	},
+	"Research.assets": {"type": "cdk:asset-manifest", "properties": {"file": "Research.assets.json"}},
+	"Orchestration": {"type": "aws:cloudformation:stack", "properties": {"templateFile": "Orchestration.template.json"}},
+	"Tree": {"type": "cdk:tree", "properties": {"file": "tree.json"}}
+}}`
+
+const testAssets = `{"files": {"abc": {
+	"displayName": "Research Template",
+	"source": {"path": "Research.template.json", "packaging": "file"},
+	"destinations": {"current": {"bucketName": "cdk-hnb659fds-assets-${AWS::AccountId}-${AWS::Region}", "objectKey": "abc.json"}}
+}}}`
+
+func TestReadAssemblyStack(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"manifest.json": testManifest, "Research.assets.json": testAssets})
+
+	tests := []struct {
+		name          string
+		wantID        string
+		wantStackName string
+		wantErr       string
+	}{
+		{name: "Research", wantID: "Research", wantStackName: "research-agents"},
+		{name: "research-agents", wantID: "Research", wantStackName: "research-agents"},
+		{name: "Orchestration", wantID: "Orchestration", wantStackName: "Orchestration"},
+		{name: "", wantErr: "has stacks Orchestration, Research; set StackName"},
+		{name: "missing", wantErr: "has no stack missing"},
+		{name: "Tree", wantErr: "has no stack Tree"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack, err := readAssemblyStack(dir, tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readAssemblyStack error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readAssemblyStack: %v", err)
+			}
+			if stack.id != tt.wantID || stack.artifact.Properties.StackName != tt.wantStackName {
+				t.Errorf("stack %s named %s, want %s named %s", stack.id, stack.artifact.Properties.StackName, tt.wantID, tt.wantStackName)
+			}
+		})
+	}
+
+	stack, err := readAssemblyStack(dir, "Research")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stack.assets) != 1 || stack.assets[0].Files["abc"].Destinations["current"].ObjectKey != "abc.json" {
+		t.Errorf("assets = %+v, want the file asset of Research.assets.json", stack.assets)
+	}
+}
+
+func TestReadAssemblyStackErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{name: "no manifest", wantErr: "reading cloud assembly"},
+		{name: "invalid manifest", files: map[string]string{"manifest.json": "{"}, wantErr: "parsing cloud assembly manifest"},
+		{name: "no stacks", files: map[string]string{"manifest.json": `{"artifacts": {}}`}, wantErr: "has no stacks"},
+		{
+			name:    "missing asset manifest",
+			files:   map[string]string{"manifest.json": testManifest},
+			wantErr: "reading asset manifest",
+		},
+		{
+			name: "docker image assets",
+			files: map[string]string{
+				"manifest.json":        testManifest,
+				"Research.assets.json": `{"dockerImages": {"def": {}}}`,
+			},
+			wantErr: "has Docker image assets",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			name := ""
+			if tt.files["manifest.json"] == testManifest {
+				name = "Research"
+			}
+			if _, err := readAssemblyStack(dir, name); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readAssemblyStack error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvironmentResolve(t *testing.T) {
+	env := environment{account: "123456789012", region: "eu-west-1", partition: "aws"}
+	got := env.resolve("arn:${AWS::Partition}:iam::${AWS::AccountId}:role/cdk-hnb659fds-deploy-role-${AWS::AccountId}-${AWS::Region}")
+	want := "arn:aws:iam::123456789012:role/cdk-hnb659fds-deploy-role-123456789012-eu-west-1"
+	if got != want {
+		t.Errorf("resolve = %s, want %s", got, want)
+	}
+	if got := env.resolve("${Qualifier}"); got != "${Qualifier}" {
+		t.Errorf("resolve of an unknown placeholder = %s, want it unchanged", got)
+	}
+}
+
+func TestCheckEnvironment(t *testing.T) {
+	env := environment{account: "123456789012", region: "eu-west-1", partition: "aws"}
+	tests := []struct {
+		environment string
+		wantErr     string
+	}{
+		{environment: ""},
+		{environment: "aws://unknown-account/unknown-region"},
+		{environment: "aws://123456789012/eu-west-1"},
+		{environment: "aws://210987654321/eu-west-1", wantErr: "targets account 210987654321"},
+		{environment: "aws://unknown-account/us-east-1", wantErr: "targets region us-east-1"},
+	}
+	for _, tt := range tests {
+		stack := &assemblyStack{id: "Research", artifact: assemblyArtifact{Environment: tt.environment}}
+		err := stack.checkEnvironment(env)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("checkEnvironment(%q) = %v, want %q", tt.environment, err, tt.wantErr)
+		}
+	}
+}
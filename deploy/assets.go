@@ -0,0 +1,140 @@
+package deploy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// Packaging of file assets.
+const (
+	packagingFile = "file"
+	packagingZip  = "zip"
+)
+
+// publishAssets uploads the stack's file assets, including its template,
+// to the bootstrap bucket. Objects that exist are skipped: asset object
+// keys are content hashes.
+func (d *deployer) publishAssets(ctx context.Context) error {
+	for _, manifest := range d.stack.assets {
+		for _, id := range sortedKeys(manifest.Files) {
+			asset := manifest.Files[id]
+			for _, destID := range sortedKeys(asset.Destinations) {
+				if err := d.publishAsset(ctx, asset, asset.Destinations[destID]); err != nil {
+					name := asset.DisplayName
+					if name == "" {
+						name = id
+					}
+					return fmt.Errorf("publishing asset %s: %w", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// publishAsset uploads a file asset to a destination.
+func (d *deployer) publishAsset(ctx context.Context, asset fileAsset, dest fileDestination) error {
+	cfg := d.cfg
+	if dest.Region != "" {
+		cfg.Region = d.env.resolve(dest.Region)
+	}
+	if d.opts.AssumeBootstrapRoles && dest.AssumeRoleARN != "" {
+		cfg = assumeRole(cfg, d.env.resolve(dest.AssumeRoleARN))
+	}
+	client := s3.NewFromConfig(cfg)
+	bucket, key := d.env.resolve(dest.BucketName), d.env.resolve(dest.ObjectKey)
+
+	_, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err == nil {
+		return nil
+	}
+	var notFound *types.NotFound
+	var apiErr smithy.APIError
+	if !errors.As(err, &notFound) && !(errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotFound") {
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "Forbidden" {
+			return fmt.Errorf("no access to bootstrap bucket %s; is the account bootstrapped, and may the credentials publish assets?", bucket)
+		}
+		return fmt.Errorf("checking s3://%s/%s: %w", bucket, key, err)
+	}
+
+	body, err := readAsset(filepath.Join(d.stack.dir, asset.Source.Path), asset.Source.Packaging)
+	if err != nil {
+		return err
+	}
+	d.progress("Publishing %s to s3://%s/%s", asset.DisplayName, bucket, key)
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	}); err != nil {
+		return fmt.Errorf("uploading s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// readAsset returns the contents of a file asset: the file, or a zip
+// archive of the directory.
+func readAsset(path, packaging string) ([]byte, error) {
+	switch packaging {
+	case packagingFile, "":
+		return os.ReadFile(path) //nolint:gosec // G304: path is named by the asset manifest
+	case packagingZip:
+		return zipDirectory(path)
+	}
+	return nil, fmt.Errorf("unsupported asset packaging %q", packaging)
+}
+
+// zipDirectory returns a zip archive of the files in dir.
+func zipDirectory(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+		out, err := w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path) //nolint:gosec // G304: path is inside the asset directory
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(out, f)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("zipping %s: %w", dir, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("zipping %s: %w", dir, err)
+	}
+	return buf.Bytes(), nil
+}
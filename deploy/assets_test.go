@@ -0,0 +1,69 @@
+package deploy
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestZipDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.py":          "def handler(event, context): pass\n",
+		"lib/util.py":       "VALUE = 1\n",
+		"lib/data/seed.txt": "",
+	}
+	writeFiles(t, dir, files)
+
+	data, err := zipDirectory(dir)
+	if err != nil {
+		t.Fatalf("zipDirectory: %v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	got := make(map[string]string)
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.Name] = string(contents)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("zip contents = %q, want %q", got, files)
+	}
+	// Entries are slash-separated paths of files only
+	want := []string{"index.py", "lib/data/seed.txt", "lib/util.py"}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("zip entries = %q, want %q", names, want)
+	}
+}
+
+func TestReadAsset(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"template.json": "{}"})
+
+	for _, packaging := range []string{packagingFile, ""} {
+		data, err := readAsset(filepath.Join(dir, "template.json"), packaging)
+		if err != nil || string(data) != "{}" {
+			t.Errorf("readAsset(%q) = %q, %v; want the file", packaging, data, err)
+		}
+	}
+	if _, err := readAsset(dir, "tarball"); err == nil {
+		t.Error("readAsset of an unsupported packaging: no error")
+	}
+}
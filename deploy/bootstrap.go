@@ -0,0 +1,373 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"gopkg.in/yaml.v3"
+)
+
+// CDK bootstrap defaults.
+const (
+	DefaultToolkitStackName = "CDKToolkit"
+	DefaultQualifier        = "hnb659fds"
+)
+
+// Actions of BootstrapResult.
+const (
+	BootstrapCreated  = "created"
+	BootstrapUpgraded = "upgraded"
+	BootstrapCurrent  = "current"
+)
+
+// accessDeniedCodes are the error codes of AWS APIs denying a request.
+var accessDeniedCodes = map[string]bool{
+	"AccessDenied":          true,
+	"AccessDeniedException": true,
+	"UnauthorizedOperation": true,
+	"Forbidden":             true,
+}
+
+// BootstrapOptions configures BootstrapStatus and Bootstrap.
+type BootstrapOptions struct {
+	// AWSConfig holds the credentials and region to bootstrap.
+	AWSConfig aws.Config
+
+	// Template is the bootstrap template the toolkit stack is created or
+	// upgraded from, e.g. the output of cdk bootstrap --show-template.
+	// Required by Bootstrap.
+	Template []byte
+
+	// ToolkitStackName is the name of the toolkit stack.
+	// Default: "CDKToolkit"
+	ToolkitStackName string
+
+	// Qualifier distinguishes the bootstrap resources of the toolkit stack.
+	// Default: "hnb659fds"
+	Qualifier string
+
+	// ExecutionPolicies are the managed policy ARNs attached to the
+	// CloudFormation execution role when the stack is created.
+	// Default: AdministratorAccess, as with cdk bootstrap
+	ExecutionPolicies []string
+
+	// Parameters set other parameters of the template, e.g.
+	// TrustedAccounts. Parameters the template doesn't declare are an
+	// error.
+	Parameters map[string]string
+
+	// OnEvent is called with each stack event of the toolkit stack
+	// deployment.
+	OnEvent func(StackEvent)
+
+	// Progress receives progress messages, one per line.
+	Progress io.Writer
+
+	// PollInterval is the interval of stack status checks.
+	// Default: 5s
+	PollInterval time.Duration
+}
+
+// BootstrapInfo is the bootstrap state of an account and region.
+type BootstrapInfo struct {
+	// ToolkitStackName is the name of the toolkit stack.
+	ToolkitStackName string
+
+	// Qualifier is the qualifier of the bootstrap resources.
+	Qualifier string
+
+	// StackStatus is the status of the toolkit stack, or empty if it
+	// doesn't exist.
+	StackStatus string
+
+	// Version is the bootstrap version, or 0 if the account and region are
+	// not bootstrapped.
+	Version int
+
+	// BucketName is the bucket assets are published to.
+	BucketName string
+}
+
+// BootstrapResult is the outcome of Bootstrap.
+type BootstrapResult struct {
+	BootstrapInfo
+
+	// Action is BootstrapCreated, BootstrapUpgraded, or BootstrapCurrent.
+	Action string
+
+	// PreviousVersion is the bootstrap version before an upgrade.
+	PreviousVersion int
+
+	// TemplateVersion is the bootstrap version of the template.
+	TemplateVersion int
+}
+
+// withDefaults returns opts with the defaults applied.
+func (o BootstrapOptions) withDefaults() BootstrapOptions {
+	if o.ToolkitStackName == "" {
+		o.ToolkitStackName = DefaultToolkitStackName
+	}
+	if o.Qualifier == "" {
+		o.Qualifier = DefaultQualifier
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
+	}
+	return o
+}
+
+// VersionParameter returns the name of the SSM parameter holding the
+// bootstrap version of a qualifier.
+func VersionParameter(qualifier string) string {
+	return fmt.Sprintf("/cdk-bootstrap/%s/version", qualifier)
+}
+
+// BootstrapStatus returns the bootstrap state of the account and region
+// of AWSConfig from the toolkit stack and its version parameter.
+func BootstrapStatus(ctx context.Context, opts BootstrapOptions) (*BootstrapInfo, error) {
+	opts = opts.withDefaults()
+	info := &BootstrapInfo{ToolkitStackName: opts.ToolkitStackName, Qualifier: opts.Qualifier}
+
+	stack, err := describeStack(ctx, cloudformation.NewFromConfig(opts.AWSConfig), opts.ToolkitStackName)
+	if err != nil {
+		return nil, err
+	}
+	if stack == nil {
+		return info, nil
+	}
+	info.StackStatus = string(stack.StackStatus)
+	for _, output := range stack.Outputs {
+		switch aws.ToString(output.OutputKey) {
+		case "BucketName":
+			info.BucketName = aws.ToString(output.OutputValue)
+		case "BootstrapVersion":
+			info.Version, _ = strconv.Atoi(aws.ToString(output.OutputValue))
+		}
+	}
+
+	// The parameter is what deployments check
+	version, found, err := readVersionParameter(ctx, opts.AWSConfig, VersionParameter(opts.Qualifier))
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		info.Version = version
+	}
+	return info, nil
+}
+
+// readVersionParameter reads a bootstrap version parameter, and false if
+// it doesn't exist.
+func readVersionParameter(ctx context.Context, cfg aws.Config, name string) (int, bool, error) {
+	out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(name)})
+	var notFound *ssmtypes.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, permissionError(fmt.Errorf("reading bootstrap version parameter %s: %w", name, err), "ssm:GetParameter")
+	}
+	version, err := strconv.Atoi(aws.ToString(out.Parameter.Value))
+	if err != nil {
+		return 0, false, fmt.Errorf("bootstrap version parameter %s is %q, not a number", name, aws.ToString(out.Parameter.Value))
+	}
+	return version, true, nil
+}
+
+// Bootstrap creates the toolkit stack from the template, or upgrades it if
+// its version is older than the template's. A failed bootstrap returns the
+// reason of the first failed resource.
+func Bootstrap(ctx context.Context, opts BootstrapOptions) (*BootstrapResult, error) {
+	opts = opts.withDefaults()
+	if len(opts.Template) == 0 {
+		return nil, fmt.Errorf("no bootstrap template; pass the output of cdk bootstrap --show-template")
+	}
+	if len(opts.Template) > maxTemplateBodySize {
+		return nil, fmt.Errorf("the bootstrap template is %d bytes, over the %d CloudFormation accepts inline", len(opts.Template), maxTemplateBodySize)
+	}
+	template, err := parseBootstrapTemplate(opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := BootstrapStatus(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := &BootstrapResult{BootstrapInfo: *info, PreviousVersion: info.Version, TemplateVersion: template.version}
+	if info.StackStatus != "" && info.Version >= template.version && info.StackStatus != string(types.StackStatusRollbackComplete) {
+		result.Action = BootstrapCurrent
+		return result, nil
+	}
+	result.Action = BootstrapUpgraded
+	if info.StackStatus == "" || info.StackStatus == string(types.StackStatusRollbackComplete) {
+		result.Action = BootstrapCreated
+	}
+
+	env, err := callerEnvironment(ctx, opts.AWSConfig)
+	if err != nil {
+		return nil, err
+	}
+	parameters := map[string]string{"Qualifier": opts.Qualifier}
+	policies := opts.ExecutionPolicies
+	if len(policies) == 0 && result.Action == BootstrapCreated {
+		policies = []string{fmt.Sprintf("arn:%s:iam::aws:policy/AdministratorAccess", env.partition)}
+	}
+	if len(policies) > 0 {
+		parameters["CloudFormationExecutionPolicies"] = strings.Join(policies, ",")
+	}
+	for k, v := range opts.Parameters {
+		parameters[k] = v
+	}
+
+	input := &cloudformation.CreateChangeSetInput{
+		StackName:     aws.String(opts.ToolkitStackName),
+		ChangeSetName: aws.String(fmt.Sprintf("agentkit-bootstrap-%d", time.Now().Unix())),
+		TemplateBody:  aws.String(string(opts.Template)),
+		Capabilities:  []types.Capability{types.CapabilityCapabilityIam, types.CapabilityCapabilityNamedIam},
+		Description:   aws.String("agentkit-aws-cdk bootstrap"),
+	}
+	for _, k := range sortedKeys(parameters) {
+		if !template.parameters[k] {
+			return nil, fmt.Errorf("the bootstrap template has no parameter %s", k)
+		}
+		input.Parameters = append(input.Parameters, types.Parameter{ParameterKey: aws.String(k), ParameterValue: aws.String(parameters[k])})
+	}
+
+	client := cloudformation.NewFromConfig(opts.AWSConfig)
+	d := &deployer{
+		opts: DeployOptions{
+			ChangeSetName: aws.ToString(input.ChangeSetName),
+			OnEvent:       opts.OnEvent,
+			Progress:      opts.Progress,
+			PollInterval:  opts.PollInterval,
+		},
+		cfg: opts.AWSConfig,
+		env: env,
+	}
+	if result.Action == BootstrapUpgraded {
+		// Keep the values of parameters both templates declare, e.g.
+		// TrustedAccounts
+		stack, err := describeStack(ctx, client, opts.ToolkitStackName)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range stack.Parameters {
+			if key := aws.ToString(p.ParameterKey); template.parameters[key] {
+				d.keepParameters = append(d.keepParameters, key)
+			}
+		}
+	}
+	d.progress("Bootstrapping aws://%s/%s (%s, version %d to %d)", env.account, env.region, result.Action, info.Version, template.version)
+	if _, err := d.applyChangeSet(ctx, client, input); err != nil {
+		return nil, fmt.Errorf("bootstrapping: %w", err)
+	}
+
+	if info, err = BootstrapStatus(ctx, opts); err != nil {
+		return nil, err
+	}
+	result.BootstrapInfo = *info
+	return result, nil
+}
+
+// bootstrapTemplate is what Bootstrap reads from a bootstrap template.
+type bootstrapTemplate struct {
+	version    int
+	parameters map[string]bool
+}
+
+// parseBootstrapTemplate reads the declared parameters and the version of
+// a bootstrap template, the value of its CdkBootstrapVersion resource.
+func parseBootstrapTemplate(data []byte) (*bootstrapTemplate, error) {
+	// Decode into nodes: the short form of intrinsic functions, e.g. !Sub,
+	// uses custom tags
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing bootstrap template: %w", err)
+	}
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	template := &bootstrapTemplate{parameters: make(map[string]bool)}
+	if params := mappingValue(root, "Parameters"); params != nil {
+		for i := 0; i+1 < len(params.Content); i += 2 {
+			template.parameters[params.Content[i].Value] = true
+		}
+	}
+	value := mappingValue(mappingValue(mappingValue(mappingValue(root, "Resources"), "CdkBootstrapVersion"), "Properties"), "Value")
+	if value == nil {
+		return nil, fmt.Errorf("the bootstrap template has no CdkBootstrapVersion resource")
+	}
+	version, err := strconv.Atoi(value.Value)
+	if err != nil {
+		return nil, fmt.Errorf("the bootstrap template version %q is not a number", value.Value)
+	}
+	template.version = version
+	return template, nil
+}
+
+// TemplateVersion returns the bootstrap version of a bootstrap template.
+func TemplateVersion(template []byte) (int, error) {
+	t, err := parseBootstrapTemplate(template)
+	if err != nil {
+		return 0, err
+	}
+	return t.version, nil
+}
+
+// mappingValue returns the value of a key of a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// checkBootstrapVersion checks that the account and region are bootstrapped
+// with the version the stack requires, so a missing or outdated bootstrap
+// fails before anything is published.
+func (d *deployer) checkBootstrapVersion(ctx context.Context) error {
+	props := d.stack.artifact.Properties
+	if props.RequiresBootstrapStackVersion == 0 || props.BootstrapStackVersionSSMParameter == "" {
+		return nil
+	}
+	version, found, err := readVersionParameter(ctx, d.cfg, props.BootstrapStackVersionSSMParameter)
+	if err != nil {
+		return err
+	}
+	target := fmt.Sprintf("aws://%s/%s", d.env.account, d.env.region)
+	if !found {
+		return fmt.Errorf("%s is not bootstrapped (no parameter %s); run Bootstrap or cdk bootstrap %s", target, props.BootstrapStackVersionSSMParameter, target)
+	}
+	if version < props.RequiresBootstrapStackVersion {
+		return fmt.Errorf("%s is bootstrapped with version %d, but stack %s requires version %d; upgrade it with Bootstrap or cdk bootstrap %s", target, version, props.StackName, props.RequiresBootstrapStackVersion, target)
+	}
+	return nil
+}
+
+// permissionError adds the missing permission to errors denying access.
+func permissionError(err error, action string) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && accessDeniedCodes[apiErr.ErrorCode()] {
+		return fmt.Errorf("%w; the credentials need %s", err, action)
+	}
+	return err
+}
@@ -0,0 +1,365 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+)
+
+// maxTemplateBodySize is the largest template CloudFormation accepts
+// inline; larger templates are read from S3.
+const maxTemplateBodySize = 51200
+
+// noChangesReasons are the status reasons of change sets failed for having
+// no changes.
+var noChangesReasons = []string{"didn't contain changes", "No updates are to be performed"}
+
+// cloudFormationAPI is the part of the CloudFormation client deployments
+// use, so tests can fake it.
+type cloudFormationAPI interface {
+	cloudformation.DescribeStacksAPIClient
+	cloudformation.DescribeStackEventsAPIClient
+	cloudformation.DescribeChangeSetAPIClient
+	CreateChangeSet(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error)
+	DeleteChangeSet(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error)
+	ExecuteChangeSet(ctx context.Context, params *cloudformation.ExecuteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error)
+	DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error)
+}
+
+// deployStack creates and executes the change set of the stack.
+func (d *deployer) deployStack(ctx context.Context) (*Result, error) {
+	cfg := d.cfg
+	props := d.stack.artifact.Properties
+	if d.opts.AssumeBootstrapRoles && props.AssumeRoleARN != "" {
+		cfg = assumeRole(cfg, d.env.resolve(props.AssumeRoleARN))
+	}
+
+	input, err := d.changeSetInput()
+	if err != nil {
+		return nil, err
+	}
+	return d.applyChangeSet(ctx, cloudformation.NewFromConfig(cfg), input)
+}
+
+// applyChangeSet creates the change set of input, of type CREATE or UPDATE
+// depending on the stack, and executes it unless NoExecute is set.
+func (d *deployer) applyChangeSet(ctx context.Context, client cloudFormationAPI, input *cloudformation.CreateChangeSetInput) (*Result, error) {
+	name := aws.ToString(input.StackName)
+	result := &Result{StackName: name}
+
+	changeSetType, err := d.prepareStack(ctx, client, name)
+	if err != nil {
+		return nil, err
+	}
+	input.ChangeSetType = changeSetType
+	if changeSetType == types.ChangeSetTypeUpdate {
+		input.Parameters = append(input.Parameters, d.previousParameters(input)...)
+	}
+
+	d.progress("Creating change set %s for stack %s", d.opts.ChangeSetName, name)
+	created, err := client.CreateChangeSet(ctx, input)
+	if err != nil {
+		return nil, permissionError(fmt.Errorf("creating change set: %w", err), "cloudformation:CreateChangeSet")
+	}
+	result.StackID = aws.ToString(created.StackId)
+	result.ChangeSetID = aws.ToString(created.Id)
+
+	changeSet, err := d.waitForChangeSet(ctx, client, result.ChangeSetID)
+	if err != nil {
+		return nil, err
+	}
+	if changeSet.Status == types.ChangeSetStatusFailed {
+		reason := aws.ToString(changeSet.StatusReason)
+		if !isNoChanges(reason) {
+			return nil, fmt.Errorf("change set %s failed: %s", d.opts.ChangeSetName, reason)
+		}
+		d.progress("Stack %s is up to date", name)
+		if _, err := client.DeleteChangeSet(ctx, &cloudformation.DeleteChangeSetInput{ChangeSetName: created.Id}); err != nil {
+			return nil, fmt.Errorf("deleting empty change set: %w", err)
+		}
+		result.NoChanges = true
+		result.ChangeSetID = ""
+		return result, d.describeResult(ctx, client, result)
+	}
+
+	if d.opts.NoExecute {
+		d.progress("Created change set %s with %d changes; not executing it", d.opts.ChangeSetName, len(changeSet.Changes))
+		return result, d.describeResult(ctx, client, result)
+	}
+
+	d.progress("Executing change set %s", d.opts.ChangeSetName)
+	if _, err := client.ExecuteChangeSet(ctx, &cloudformation.ExecuteChangeSetInput{ChangeSetName: created.Id}); err != nil {
+		return nil, permissionError(fmt.Errorf("executing change set: %w", err), "cloudformation:ExecuteChangeSet")
+	}
+	result.Executed = true
+	// The change set's creation time is on the CloudFormation clock, which
+	// the local clock may not agree with
+	if err := d.waitForStack(ctx, client, name, result.StackID, aws.ToTime(changeSet.CreationTime)); err != nil {
+		return nil, err
+	}
+	return result, d.describeResult(ctx, client, result)
+}
+
+// previousParameters keeps the current value of the declared parameters
+// of a stack update that input doesn't set.
+func (d *deployer) previousParameters(input *cloudformation.CreateChangeSetInput) []types.Parameter {
+	set := make(map[string]bool, len(input.Parameters))
+	for _, p := range input.Parameters {
+		set[aws.ToString(p.ParameterKey)] = true
+	}
+	var previous []types.Parameter
+	for _, key := range d.keepParameters {
+		if !set[key] {
+			previous = append(previous, types.Parameter{ParameterKey: aws.String(key), UsePreviousValue: aws.Bool(true)})
+		}
+	}
+	return previous
+}
+
+// prepareStack returns the change set type of a stack, deleting it first
+// if a failed creation left it in ROLLBACK_COMPLETE.
+func (d *deployer) prepareStack(ctx context.Context, client cloudFormationAPI, name string) (types.ChangeSetType, error) {
+	stack, err := describeStack(ctx, client, name)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case stack == nil, stack.StackStatus == types.StackStatusReviewInProgress:
+		return types.ChangeSetTypeCreate, nil
+	case stack.StackStatus == types.StackStatusRollbackComplete:
+		d.progress("Deleting stack %s, left in ROLLBACK_COMPLETE by a failed creation", name)
+		if _, err := client.DeleteStack(ctx, &cloudformation.DeleteStackInput{StackName: stack.StackId}); err != nil {
+			return "", permissionError(fmt.Errorf("deleting stack in ROLLBACK_COMPLETE: %w", err), "cloudformation:DeleteStack")
+		}
+		waiter := cloudformation.NewStackDeleteCompleteWaiter(client, func(o *cloudformation.StackDeleteCompleteWaiterOptions) {
+			o.MinDelay = d.opts.PollInterval
+		})
+		if err := waiter.Wait(ctx, &cloudformation.DescribeStacksInput{StackName: stack.StackId}, time.Hour); err != nil {
+			return "", fmt.Errorf("waiting for stack deletion: %w", err)
+		}
+		return types.ChangeSetTypeCreate, nil
+	case strings.HasSuffix(string(stack.StackStatus), "_IN_PROGRESS"):
+		return "", fmt.Errorf("stack %s is busy (%s); wait for the operation to finish", name, stack.StackStatus)
+	}
+	return types.ChangeSetTypeUpdate, nil
+}
+
+// changeSetInput returns the CreateChangeSet request of the stack.
+func (d *deployer) changeSetInput() (*cloudformation.CreateChangeSetInput, error) {
+	props := d.stack.artifact.Properties
+	input := &cloudformation.CreateChangeSetInput{
+		StackName:     aws.String(props.StackName),
+		ChangeSetName: aws.String(d.opts.ChangeSetName),
+		Capabilities: []types.Capability{
+			types.CapabilityCapabilityIam,
+			types.CapabilityCapabilityNamedIam,
+			types.CapabilityCapabilityAutoExpand,
+		},
+		Description: aws.String("agentkit-aws-cdk deploy"),
+	}
+
+	if props.StackTemplateAssetObjectURL != "" {
+		input.TemplateURL = aws.String(d.templateURL(d.env.resolve(props.StackTemplateAssetObjectURL)))
+	} else {
+		body, err := os.ReadFile(filepath.Join(d.stack.dir, props.TemplateFile)) //nolint:gosec // G304: file is named by the assembly manifest
+		if err != nil {
+			return nil, fmt.Errorf("reading template: %w", err)
+		}
+		if len(body) > maxTemplateBodySize {
+			return nil, fmt.Errorf("template of stack %s is %d bytes, over the %d CloudFormation accepts inline; synthesize it with the default stack synthesizer, which publishes it to the bootstrap bucket", props.StackName, len(body), maxTemplateBodySize)
+		}
+		input.TemplateBody = aws.String(string(body))
+	}
+
+	parameters := make(map[string]string, len(props.Parameters)+len(d.opts.Parameters))
+	for k, v := range props.Parameters {
+		parameters[k] = v
+	}
+	for k, v := range d.opts.Parameters {
+		parameters[k] = v
+	}
+	for _, k := range sortedKeys(parameters) {
+		input.Parameters = append(input.Parameters, types.Parameter{ParameterKey: aws.String(k), ParameterValue: aws.String(parameters[k])})
+	}
+	for _, k := range sortedKeys(props.Tags) {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(k), Value: aws.String(props.Tags[k])})
+	}
+
+	switch {
+	case d.opts.ExecutionRoleARN != "":
+		input.RoleARN = aws.String(d.opts.ExecutionRoleARN)
+	case d.opts.AssumeBootstrapRoles && props.CloudFormationExecutionRoleARN != "":
+		input.RoleARN = aws.String(d.env.resolve(props.CloudFormationExecutionRoleARN))
+	}
+	return input, nil
+}
+
+// templateURL returns the HTTPS URL CloudFormation reads a template at
+// from its s3:// URL.
+func (d *deployer) templateURL(s3URL string) string {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(s3URL, "s3://"), "/")
+	domain := "amazonaws.com"
+	if d.env.partition == "aws-cn" {
+		domain = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("https://%s.s3.%s.%s/%s", bucket, d.env.region, domain, key)
+}
+
+// waitForChangeSet polls a change set until it is created or failed.
+func (d *deployer) waitForChangeSet(ctx context.Context, client cloudFormationAPI, id string) (*cloudformation.DescribeChangeSetOutput, error) {
+	for {
+		changeSet, err := client.DescribeChangeSet(ctx, &cloudformation.DescribeChangeSetInput{ChangeSetName: aws.String(id)})
+		if err != nil {
+			return nil, fmt.Errorf("describing change set: %w", err)
+		}
+		switch changeSet.Status {
+		case types.ChangeSetStatusCreateComplete, types.ChangeSetStatusFailed:
+			return changeSet, nil
+		}
+		if err := sleep(ctx, d.opts.PollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForStack polls a stack until its operation completes, passing the
+// events since started to OnEvent. A failed or rolled back operation
+// returns the reason of the first failed resource.
+func (d *deployer) waitForStack(ctx context.Context, client cloudFormationAPI, name, stackID string, started time.Time) error {
+	seen := make(map[string]bool)
+	var failure string
+	for {
+		events, err := newStackEvents(ctx, client, stackID, started, seen)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			seen[aws.ToString(e.EventId)] = true
+			event := StackEvent{
+				Timestamp:         aws.ToTime(e.Timestamp),
+				LogicalResourceID: aws.ToString(e.LogicalResourceId),
+				ResourceType:      aws.ToString(e.ResourceType),
+				Status:            string(e.ResourceStatus),
+				Reason:            aws.ToString(e.ResourceStatusReason),
+			}
+			if failure == "" && strings.HasSuffix(event.Status, "_FAILED") && event.Reason != "" && !strings.Contains(event.Reason, "cancelled") {
+				failure = fmt.Sprintf("%s (%s): %s", event.LogicalResourceID, event.ResourceType, event.Reason)
+			}
+			if d.opts.OnEvent != nil {
+				d.opts.OnEvent(event)
+			}
+		}
+
+		stack, err := describeStack(ctx, client, stackID)
+		if err != nil {
+			return err
+		}
+		if stack == nil {
+			return fmt.Errorf("stack %s was deleted during the deployment", name)
+		}
+		status := stack.StackStatus
+		switch {
+		case status == types.StackStatusCreateComplete || status == types.StackStatusUpdateComplete || status == types.StackStatusImportComplete:
+			return nil
+		case !strings.HasSuffix(string(status), "_IN_PROGRESS"):
+			if failure == "" {
+				failure = aws.ToString(stack.StackStatusReason)
+			}
+			return fmt.Errorf("deploying stack %s failed (%s): %s", name, status, failure)
+		}
+		if err := sleep(ctx, d.opts.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// newStackEvents returns the events of a stack since started that aren't
+// seen yet, oldest first. Events are listed newest first, so it pages
+// until an event is older than started or already seen.
+func newStackEvents(ctx context.Context, client cloudFormationAPI, stackID string, started time.Time, seen map[string]bool) ([]types.StackEvent, error) {
+	var events []types.StackEvent
+	paginator := cloudformation.NewDescribeStackEventsPaginator(client, &cloudformation.DescribeStackEventsInput{StackName: aws.String(stackID)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing stack events: %w", err)
+		}
+		for _, e := range page.StackEvents {
+			if seen[aws.ToString(e.EventId)] || aws.ToTime(e.Timestamp).Before(started) {
+				slices.Reverse(events)
+				return events, nil
+			}
+			events = append(events, e)
+		}
+	}
+	slices.Reverse(events)
+	return events, nil
+}
+
+// describeResult fills in the stack status and outputs of a result.
+func (d *deployer) describeResult(ctx context.Context, client cloudFormationAPI, result *Result) error {
+	name := result.StackID
+	if name == "" {
+		name = result.StackName
+	}
+	stack, err := describeStack(ctx, client, name)
+	if err != nil || stack == nil {
+		return err
+	}
+	result.StackID = aws.ToString(stack.StackId)
+	result.Status = string(stack.StackStatus)
+	result.Outputs = make(map[string]string, len(stack.Outputs))
+	for _, output := range stack.Outputs {
+		result.Outputs[aws.ToString(output.OutputKey)] = aws.ToString(output.OutputValue)
+	}
+	return nil
+}
+
+// describeStack returns a stack, or nil if it doesn't exist.
+func describeStack(ctx context.Context, client cloudFormationAPI, name string) (*types.Stack, error) {
+	out, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(name)})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage(), "does not exist") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, permissionError(fmt.Errorf("describing stack %s: %w", name, err), "cloudformation:DescribeStacks")
+	}
+	if len(out.Stacks) == 0 {
+		return nil, nil
+	}
+	return &out.Stacks[0], nil
+}
+
+// isNoChanges reports whether a change set failed for having no changes.
+func isNoChanges(reason string) bool {
+	for _, r := range noChangesReasons {
+		if strings.Contains(reason, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
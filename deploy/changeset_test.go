@@ -0,0 +1,270 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// fakeCloudFormation is a cloudFormationAPI serving canned responses.
+type fakeCloudFormation struct {
+	cloudFormationAPI
+
+	// events are the stack events, newest first, served pageSize per page.
+	events   []types.StackEvent
+	pageSize int
+
+	// statuses are the stack statuses of successive DescribeStacks calls;
+	// the last one repeats. No statuses means the stack doesn't exist.
+	statuses []types.StackStatus
+
+	// changeSetStatus and changeSetReason describe the created change set.
+	changeSetStatus types.ChangeSetStatus
+	changeSetReason string
+
+	eventPages int
+	calls      []string
+}
+
+func (f *fakeCloudFormation) DescribeStackEvents(_ context.Context, in *cloudformation.DescribeStackEventsInput, _ ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+	f.eventPages++
+	start := 0
+	if in.NextToken != nil {
+		fmt.Sscan(*in.NextToken, &start)
+	}
+	end := min(start+f.pageSize, len(f.events))
+	out := &cloudformation.DescribeStackEventsOutput{StackEvents: f.events[start:end]}
+	if end < len(f.events) {
+		out.NextToken = aws.String(fmt.Sprint(end))
+	}
+	return out, nil
+}
+
+func (f *fakeCloudFormation) DescribeStacks(_ context.Context, in *cloudformation.DescribeStacksInput, _ ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	f.calls = append(f.calls, "DescribeStacks")
+	if len(f.statuses) == 0 {
+		return &cloudformation.DescribeStacksOutput{}, nil
+	}
+	status := f.statuses[0]
+	if len(f.statuses) > 1 {
+		f.statuses = f.statuses[1:]
+	}
+	return &cloudformation.DescribeStacksOutput{Stacks: []types.Stack{{
+		StackId:     aws.String("arn:aws:cloudformation:eu-west-1:123456789012:stack/research/1"),
+		StackName:   in.StackName,
+		StackStatus: status,
+		Outputs:     []types.Output{{OutputKey: aws.String("LogGroupName"), OutputValue: aws.String("/aws/agentcore/research")}},
+	}}}, nil
+}
+
+func (f *fakeCloudFormation) CreateChangeSet(_ context.Context, in *cloudformation.CreateChangeSetInput, _ ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+	f.calls = append(f.calls, "CreateChangeSet "+string(in.ChangeSetType))
+	return &cloudformation.CreateChangeSetOutput{Id: aws.String("changeset-1"), StackId: aws.String("arn:aws:cloudformation:eu-west-1:123456789012:stack/research/1")}, nil
+}
+
+func (f *fakeCloudFormation) DescribeChangeSet(context.Context, *cloudformation.DescribeChangeSetInput, ...func(*cloudformation.Options)) (*cloudformation.DescribeChangeSetOutput, error) {
+	f.calls = append(f.calls, "DescribeChangeSet")
+	return &cloudformation.DescribeChangeSetOutput{Status: f.changeSetStatus, StatusReason: aws.String(f.changeSetReason), CreationTime: aws.Time(time.Unix(100, 0))}, nil
+}
+
+func (f *fakeCloudFormation) DeleteChangeSet(context.Context, *cloudformation.DeleteChangeSetInput, ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error) {
+	f.calls = append(f.calls, "DeleteChangeSet")
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
+
+func (f *fakeCloudFormation) ExecuteChangeSet(context.Context, *cloudformation.ExecuteChangeSetInput, ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error) {
+	f.calls = append(f.calls, "ExecuteChangeSet")
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+// event returns a stack event at a Unix time.
+func event(id string, at int64, status types.ResourceStatus, reason string) types.StackEvent {
+	return types.StackEvent{
+		EventId:              aws.String(id),
+		Timestamp:            aws.Time(time.Unix(at, 0)),
+		LogicalResourceId:    aws.String("Resource" + id),
+		ResourceType:         aws.String("AWS::SQS::Queue"),
+		ResourceStatus:       status,
+		ResourceStatusReason: aws.String(reason),
+	}
+}
+
+// eventIDs returns the IDs of events.
+func eventIDs(events []types.StackEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, aws.ToString(e.EventId))
+	}
+	return ids
+}
+
+func TestNewStackEvents(t *testing.T) {
+	// Newest first, as DescribeStackEvents lists them
+	events := []types.StackEvent{
+		event("5", 150, types.ResourceStatusCreateComplete, ""),
+		event("4", 140, types.ResourceStatusCreateInProgress, ""),
+		event("3", 130, types.ResourceStatusCreateInProgress, ""),
+		event("2", 90, types.ResourceStatusUpdateComplete, ""),
+		event("1", 80, types.ResourceStatusUpdateInProgress, ""),
+	}
+	tests := []struct {
+		name      string
+		seen      map[string]bool
+		wantIDs   []string
+		wantPages int
+	}{
+		{name: "pages until an older event", wantIDs: []string{"3", "4", "5"}, wantPages: 2},
+		{name: "stops at a seen event", seen: map[string]bool{"4": true}, wantIDs: []string{"5"}, wantPages: 1},
+		{name: "all seen", seen: map[string]bool{"5": true}, wantIDs: []string{}, wantPages: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeCloudFormation{events: events, pageSize: 2}
+			got, err := newStackEvents(context.Background(), client, "research", time.Unix(100, 0), tt.seen)
+			if err != nil {
+				t.Fatalf("newStackEvents: %v", err)
+			}
+			if ids := eventIDs(got); !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("events = %q, want %q", ids, tt.wantIDs)
+			}
+			if client.eventPages != tt.wantPages {
+				t.Errorf("read %d pages, want %d", client.eventPages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestWaitForStack(t *testing.T) {
+	tests := []struct {
+		name       string
+		events     []types.StackEvent
+		statuses   []types.StackStatus
+		wantEvents []string
+		wantErr    string
+	}{
+		{
+			name: "complete",
+			events: []types.StackEvent{
+				event("2", 120, types.ResourceStatusCreateComplete, ""),
+				event("1", 110, types.ResourceStatusCreateInProgress, ""),
+			},
+			statuses:   []types.StackStatus{types.StackStatusCreateInProgress, types.StackStatusCreateComplete},
+			wantEvents: []string{"Resource1 CREATE_IN_PROGRESS", "Resource2 CREATE_COMPLETE"},
+		},
+		{
+			name: "rolled back",
+			events: []types.StackEvent{
+				event("3", 130, types.ResourceStatusCreateFailed, "Resource creation cancelled"),
+				event("2", 120, types.ResourceStatusCreateFailed, "Queue already exists"),
+				event("1", 110, types.ResourceStatusCreateInProgress, ""),
+			},
+			statuses:   []types.StackStatus{types.StackStatusRollbackComplete},
+			wantEvents: []string{"Resource1 CREATE_IN_PROGRESS", "Resource2 CREATE_FAILED", "Resource3 CREATE_FAILED"},
+			wantErr:    "deploying stack research failed (ROLLBACK_COMPLETE): Resource2 (AWS::SQS::Queue): Queue already exists",
+		},
+		{
+			name:    "deleted",
+			wantErr: "stack research was deleted during the deployment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			d := &deployer{opts: DeployOptions{
+				PollInterval: time.Millisecond,
+				OnEvent: func(e StackEvent) {
+					got = append(got, e.LogicalResourceID+" "+e.Status)
+				},
+			}}
+			client := &fakeCloudFormation{events: tt.events, pageSize: 10, statuses: tt.statuses}
+			err := d.waitForStack(context.Background(), client, "research", "research", time.Unix(100, 0))
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("waitForStack error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %q, want %q", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestApplyChangeSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    *fakeCloudFormation
+		noExecute bool
+		want      Result
+		wantCalls []string
+	}{
+		{
+			name: "create",
+			client: &fakeCloudFormation{
+				changeSetStatus: types.ChangeSetStatusCreateComplete,
+				statuses:        []types.StackStatus{types.StackStatusReviewInProgress, types.StackStatusCreateComplete},
+			},
+			want: Result{ChangeSetID: "changeset-1", Executed: true, Status: "CREATE_COMPLETE"},
+			wantCalls: []string{
+				"DescribeStacks", "CreateChangeSet CREATE", "DescribeChangeSet", "ExecuteChangeSet",
+				"DescribeStacks", "DescribeStacks",
+			},
+		},
+		{
+			name: "no changes",
+			client: &fakeCloudFormation{
+				changeSetStatus: types.ChangeSetStatusFailed,
+				changeSetReason: "The submitted information didn't contain changes.",
+				statuses:        []types.StackStatus{types.StackStatusUpdateComplete},
+			},
+			want:      Result{NoChanges: true, Status: "UPDATE_COMPLETE"},
+			wantCalls: []string{"DescribeStacks", "CreateChangeSet UPDATE", "DescribeChangeSet", "DeleteChangeSet", "DescribeStacks"},
+		},
+		{
+			name: "no execute",
+			client: &fakeCloudFormation{
+				changeSetStatus: types.ChangeSetStatusCreateComplete,
+				statuses:        []types.StackStatus{types.StackStatusUpdateComplete},
+			},
+			noExecute: true,
+			want:      Result{ChangeSetID: "changeset-1", Status: "UPDATE_COMPLETE"},
+			wantCalls: []string{"DescribeStacks", "CreateChangeSet UPDATE", "DescribeChangeSet", "DescribeStacks"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.client.pageSize = 10
+			d := &deployer{opts: DeployOptions{ChangeSetName: "deploy-1", NoExecute: tt.noExecute, PollInterval: time.Millisecond}}
+			result, err := d.applyChangeSet(context.Background(), tt.client, &cloudformation.CreateChangeSetInput{StackName: aws.String("research")})
+			if err != nil {
+				t.Fatalf("applyChangeSet: %v", err)
+			}
+			tt.want.StackName = "research"
+			tt.want.StackID = "arn:aws:cloudformation:eu-west-1:123456789012:stack/research/1"
+			tt.want.Outputs = map[string]string{"LogGroupName": "/aws/agentcore/research"}
+			if !reflect.DeepEqual(*result, tt.want) {
+				t.Errorf("result = %+v, want %+v", *result, tt.want)
+			}
+			if !reflect.DeepEqual(tt.client.calls, tt.wantCalls) {
+				t.Errorf("calls = %q, want %q", tt.client.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestApplyChangeSetFailed(t *testing.T) {
+	client := &fakeCloudFormation{
+		changeSetStatus: types.ChangeSetStatusFailed,
+		changeSetReason: "Template format error",
+	}
+	d := &deployer{opts: DeployOptions{ChangeSetName: "deploy-1", PollInterval: time.Millisecond}}
+	_, err := d.applyChangeSet(context.Background(), client, &cloudformation.CreateChangeSetInput{StackName: aws.String("research")})
+	if err == nil || !strings.Contains(err.Error(), "change set deploy-1 failed: Template format error") {
+		t.Errorf("applyChangeSet error = %v, want the change set failure", err)
+	}
+}
@@ -0,0 +1,249 @@
+// Package deploy deploys AgentCore stacks with the AWS SDK instead of the
+// cdk CLI, for Go services that embed deployment:
+//
+//	result, err := deploy.Deploy(ctx, deploy.DeployOptions{
+//		Config:    config,
+//		AWSConfig: cfg,
+//		OnEvent: func(e deploy.StackEvent) {
+//			log.Printf("%s %s %s", e.LogicalResourceID, e.Status, e.Reason)
+//		},
+//	})
+//
+// Deploy synthesizes the stack (or reads a cloud assembly synthesized
+// before), publishes its file assets and template to the CDK bootstrap
+// bucket, and creates and executes a CloudFormation change set, streaming
+// stack events until the deployment completes. Bootstrap creates or
+// upgrades the CDK toolkit stack the deployment needs. Docker image assets
+// aren't built or pushed: a stack with image assets fails to deploy rather
+// than referencing images nobody pushed.
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/jsii-runtime-go"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+// defaultPollInterval is the default interval of change set and stack
+// status checks.
+const defaultPollInterval = 5 * time.Second
+
+// DeployOptions configures Deploy. Set Config or AssemblyDir.
+type DeployOptions struct {
+	// Config is the stack to synthesize and deploy. Synthesis runs the CDK
+	// in process, which needs Node.js for the jsii runtime, but not the
+	// cdk CLI.
+	Config *agentcore.StackConfig
+
+	// Options are the CDK-specific options of Config.
+	Options agentcore.StackOptions
+
+	// AssemblyDir is a cloud assembly synthesized before, e.g. by cdk synth
+	// in CI. Deploying it needs no Node.js.
+	AssemblyDir string
+
+	// StackName selects the stack of AssemblyDir by artifact ID or stack
+	// name.
+	// Default: the only stack of the assembly
+	StackName string
+
+	// AWSConfig holds the credentials and region to deploy with.
+	AWSConfig aws.Config
+
+	// AssumeBootstrapRoles publishes assets and deploys with the CDK
+	// bootstrap roles named in the assembly, as cdk deploy does, instead
+	// of the credentials of AWSConfig.
+	AssumeBootstrapRoles bool
+
+	// ExecutionRoleARN is the role CloudFormation deploys the stack's
+	// resources with.
+	// Default: the bootstrap execution role with AssumeBootstrapRoles,
+	// otherwise the credentials of AWSConfig
+	ExecutionRoleARN string
+
+	// Parameters override the stack's parameter values.
+	Parameters map[string]string
+
+	// ChangeSetName names the change set.
+	// Default: "agentkit-deploy-{unix time}"
+	ChangeSetName string
+
+	// NoExecute creates the change set for review without executing it.
+	NoExecute bool
+
+	// OnEvent is called with each stack event of the deployment.
+	OnEvent func(StackEvent)
+
+	// Progress receives progress messages, one per line.
+	Progress io.Writer
+
+	// PollInterval is the interval of change set and stack status checks.
+	// Default: 5s
+	PollInterval time.Duration
+}
+
+// StackEvent is a CloudFormation stack event.
+type StackEvent struct {
+	Timestamp         time.Time
+	LogicalResourceID string
+	ResourceType      string
+	Status            string
+	Reason            string
+}
+
+// Result is the outcome of a deployment.
+type Result struct {
+	// StackName is the CloudFormation stack name.
+	StackName string
+
+	// StackID is the stack ARN.
+	StackID string
+
+	// ChangeSetID is the change set ARN. Empty with NoChanges.
+	ChangeSetID string
+
+	// NoChanges is set if the stack was up to date.
+	NoChanges bool
+
+	// Executed is set if the change set was executed.
+	Executed bool
+
+	// Status is the stack status after the deployment.
+	Status string
+
+	// Outputs are the stack outputs by key.
+	Outputs map[string]string
+}
+
+// deployer holds the state of a deployment.
+type deployer struct {
+	opts  DeployOptions
+	cfg   aws.Config
+	stack *assemblyStack
+	env   environment
+
+	// keepParameters are the parameters whose value is kept on updates
+	// unless set.
+	keepParameters []string
+}
+
+// Deploy deploys a stack and waits for the deployment to complete. A
+// failed deployment returns the reason of the first failed resource.
+func Deploy(ctx context.Context, opts DeployOptions) (*Result, error) {
+	if (opts.Config == nil) == (opts.AssemblyDir == "") {
+		return nil, fmt.Errorf("set exactly one of Config and AssemblyDir")
+	}
+	if opts.AWSConfig.Region == "" {
+		return nil, fmt.Errorf("AWSConfig has no region")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.ChangeSetName == "" {
+		opts.ChangeSetName = fmt.Sprintf("agentkit-deploy-%d", time.Now().Unix())
+	}
+
+	dir, name := opts.AssemblyDir, opts.StackName
+	if opts.Config != nil {
+		outdir, err := os.MkdirTemp("", "agentcore-deploy-")
+		if err != nil {
+			return nil, fmt.Errorf("creating synth directory: %w", err)
+		}
+		defer os.RemoveAll(outdir)
+		if name, err = synth(outdir, *opts.Config, opts.Options); err != nil {
+			return nil, err
+		}
+		dir = outdir
+	}
+
+	stack, err := readAssemblyStack(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	d := &deployer{opts: opts, cfg: opts.AWSConfig, stack: stack}
+	if d.env, err = callerEnvironment(ctx, d.cfg); err != nil {
+		return nil, err
+	}
+	if err := stack.checkEnvironment(d.env); err != nil {
+		return nil, err
+	}
+	if err := d.checkBootstrapVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := d.publishAssets(ctx); err != nil {
+		return nil, err
+	}
+	return d.deployStack(ctx)
+}
+
+// synth synthesizes the stack of config into outdir and returns its
+// artifact ID.
+func synth(outdir string, config agentcore.StackConfig, options agentcore.StackOptions) (id string, err error) {
+	// Stack construction panics on invalid configuration
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("synthesizing stack: %v", r)
+		}
+	}()
+
+	if err := options.Validate(config); err != nil {
+		return "", fmt.Errorf("invalid stack options: %w", err)
+	}
+	app := awscdk.NewApp(&awscdk.AppProps{Outdir: jsii.String(outdir)})
+	s := agentcore.NewAgentCoreStackWithOptions(app, config.StackName, config, options)
+	app.Synth(nil)
+	return *s.Stack.ArtifactId(), nil
+}
+
+// callerEnvironment returns the account and partition of the credentials
+// and the configured region.
+func callerEnvironment(ctx context.Context, cfg aws.Config) (environment, error) {
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return environment{}, fmt.Errorf("getting caller identity: %w", err)
+	}
+	partition := "aws"
+	if parts := strings.SplitN(aws.ToString(identity.Arn), ":", 3); len(parts) == 3 {
+		partition = parts[1]
+	}
+	return environment{account: aws.ToString(identity.Account), region: cfg.Region, partition: partition}, nil
+}
+
+// assumeRole returns cfg with the credentials of a role.
+func assumeRole(cfg aws.Config, roleARN string) aws.Config {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "agentkit-deploy"
+	})
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg
+}
+
+// progress writes a progress message.
+func (d *deployer) progress(format string, args ...any) {
+	if d.opts.Progress != nil {
+		fmt.Fprintf(d.opts.Progress, format+"\n", args...)
+	}
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
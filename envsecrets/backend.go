@@ -0,0 +1,277 @@
+package envsecrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// Secret backends.
+const (
+	BackendSecretsManager = "secretsmanager"
+	BackendSSM            = "ssm"
+)
+
+const (
+	// ssmStandardMaxBytes is the largest value of a standard tier parameter.
+	ssmStandardMaxBytes = 4096
+
+	// ssmDeleteBatchSize is the most parameters DeleteParameters accepts.
+	ssmDeleteBatchSize = 10
+)
+
+// Backend stores the keys of each secret group.
+type Backend interface {
+	// SecretName returns the name of a group's secret.
+	SecretName(prefix, group string) string
+
+	// Get returns the current keys of a secret, and false if it does not exist.
+	Get(ctx context.Context, name string) (map[string]string, bool, error)
+
+	// Create creates a secret with the group's keys.
+	Create(ctx context.Context, name string, group SecretGroup) error
+
+	// Update writes the changes in diff to an existing secret.
+	Update(ctx context.Context, name string, current map[string]string, group SecretGroup, diff Diff, prune bool) error
+
+	// VerifyCommand returns a command that lists the pushed secrets.
+	VerifyCommand(region, prefix string) string
+}
+
+// SecretsManagerAPI is the subset of the Secrets Manager client used by the
+// secretsmanager backend.
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	CreateSecret(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error)
+	PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error)
+	TagResource(ctx context.Context, params *secretsmanager.TagResourceInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.TagResourceOutput, error)
+	PutResourcePolicy(ctx context.Context, params *secretsmanager.PutResourcePolicyInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutResourcePolicyOutput, error)
+}
+
+// SSMAPI is the subset of the SSM client used by the ssm backend.
+type SSMAPI interface {
+	ssm.GetParametersByPathAPIClient
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+	DeleteParameters(ctx context.Context, params *ssm.DeleteParametersInput, optFns ...func(*ssm.Options)) (*ssm.DeleteParametersOutput, error)
+	AddTagsToResource(ctx context.Context, params *ssm.AddTagsToResourceInput, optFns ...func(*ssm.Options)) (*ssm.AddTagsToResourceOutput, error)
+}
+
+// NewBackend returns the backend with the given name, using clients created from cfg.
+func NewBackend(name string, cfg aws.Config) (Backend, error) {
+	switch name {
+	case BackendSecretsManager:
+		return NewSecretsManagerBackend(secretsmanager.NewFromConfig(cfg)), nil
+	case BackendSSM:
+		return NewSSMBackend(ssm.NewFromConfig(cfg)), nil
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %q (valid: %s, %s)", name, BackendSecretsManager, BackendSSM)
+	}
+}
+
+// NewSecretsManagerBackend returns a backend that stores each group as a JSON
+// secret named {prefix}/{group}.
+func NewSecretsManagerBackend(client SecretsManagerAPI) Backend {
+	return &secretsManagerBackend{client: client}
+}
+
+// NewSSMBackend returns a backend that stores each key as a SecureString
+// parameter named /{prefix}/{group}/{KEY}.
+func NewSSMBackend(client SSMAPI) Backend {
+	return &ssmBackend{client: client}
+}
+
+// secretsManagerBackend stores each group as a JSON secret named {prefix}/{group}.
+type secretsManagerBackend struct {
+	client SecretsManagerAPI
+}
+
+func (b *secretsManagerBackend) SecretName(prefix, group string) string {
+	return fmt.Sprintf("%s/%s", prefix, group)
+}
+
+func (b *secretsManagerBackend) Get(ctx context.Context, name string) (map[string]string, bool, error) {
+	return getSecretKeys(ctx, b.client, name)
+}
+
+func (b *secretsManagerBackend) Create(ctx context.Context, name string, group SecretGroup) error {
+	secretValue, err := json.Marshal(group.Keys)
+	if err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	_, err = b.client.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		Description:  aws.String(group.Description),
+		SecretString: aws.String(string(secretValue)),
+	})
+	if err != nil {
+		return fmt.Errorf("creating secret: %w", err)
+	}
+	return nil
+}
+
+func (b *secretsManagerBackend) Update(ctx context.Context, name string, current map[string]string, group SecretGroup, _ Diff, prune bool) error {
+	secretValue, err := json.Marshal(MergeKeys(current, group.Keys, prune))
+	if err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	_, err = b.client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(string(secretValue)),
+	})
+	if err != nil {
+		return fmt.Errorf("updating secret: %w", err)
+	}
+	return nil
+}
+
+func (b *secretsManagerBackend) TagSecret(ctx context.Context, name string, _ []string, tags map[string]string) error {
+	secretTags := make([]smtypes.Tag, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		secretTags = append(secretTags, smtypes.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	_, err := b.client.TagResource(ctx, &secretsmanager.TagResourceInput{
+		SecretId: aws.String(name),
+		Tags:     secretTags,
+	})
+	if err != nil {
+		return fmt.Errorf("tagging secret: %w", err)
+	}
+	return nil
+}
+
+func (b *secretsManagerBackend) RestrictRead(ctx context.Context, name string, readerARNs []string) error {
+	policy, err := ReadPolicy(readerARNs)
+	if err != nil {
+		return err
+	}
+	_, err = b.client.PutResourcePolicy(ctx, &secretsmanager.PutResourcePolicyInput{
+		SecretId:          aws.String(name),
+		ResourcePolicy:    aws.String(policy),
+		BlockPublicPolicy: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("attaching resource policy: %w", err)
+	}
+	return nil
+}
+
+func (b *secretsManagerBackend) VerifyCommand(region, prefix string) string {
+	return fmt.Sprintf("aws secretsmanager list-secrets --region %s --filter Key=name,Values=%s/ --no-cli-pager", region, prefix)
+}
+
+// ssmBackend stores each key as a SecureString parameter named
+// /{prefix}/{group}/{KEY}.
+type ssmBackend struct {
+	client SSMAPI
+}
+
+func (b *ssmBackend) SecretName(prefix, group string) string {
+	return fmt.Sprintf("/%s/%s", strings.Trim(prefix, "/"), group)
+}
+
+func (b *ssmBackend) Get(ctx context.Context, name string) (map[string]string, bool, error) {
+	keys := make(map[string]string)
+	paginator := ssm.NewGetParametersByPathPaginator(b.client, &ssm.GetParametersByPathInput{
+		Path:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("reading parameters: %w", err)
+		}
+		for _, parameter := range page.Parameters {
+			key := strings.TrimPrefix(aws.ToString(parameter.Name), name+"/")
+			keys[key] = aws.ToString(parameter.Value)
+		}
+	}
+	return keys, len(keys) > 0, nil
+}
+
+func (b *ssmBackend) Create(ctx context.Context, name string, group SecretGroup) error {
+	for key, value := range group.Keys {
+		if err := b.put(ctx, name, key, value, group.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *ssmBackend) Update(ctx context.Context, name string, _ map[string]string, group SecretGroup, diff Diff, prune bool) error {
+	for _, key := range append(diff.Added, diff.Changed...) {
+		if err := b.put(ctx, name, key, group.Keys[key], group.Description); err != nil {
+			return err
+		}
+	}
+	if !prune {
+		return nil
+	}
+
+	var names []string
+	for _, key := range diff.Removed {
+		names = append(names, name+"/"+key)
+	}
+	for len(names) > 0 {
+		batch := names[:min(len(names), ssmDeleteBatchSize)]
+		names = names[len(batch):]
+		if _, err := b.client.DeleteParameters(ctx, &ssm.DeleteParametersInput{Names: batch}); err != nil {
+			return fmt.Errorf("deleting parameters: %w", err)
+		}
+	}
+	return nil
+}
+
+// put writes a single SecureString parameter.
+func (b *ssmBackend) put(ctx context.Context, name, key, value, description string) error {
+	tier := ssmtypes.ParameterTierStandard
+	if len(value) > ssmStandardMaxBytes {
+		tier = ssmtypes.ParameterTierAdvanced
+	}
+	_, err := b.client.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:        aws.String(name + "/" + key),
+		Value:       aws.String(value),
+		Description: aws.String(description),
+		Type:        ssmtypes.ParameterTypeSecureString,
+		Tier:        tier,
+		Overwrite:   aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("writing parameter %s/%s: %w", name, key, err)
+	}
+	return nil
+}
+
+func (b *ssmBackend) TagSecret(ctx context.Context, name string, keys []string, tags map[string]string) error {
+	parameterTags := make([]ssmtypes.Tag, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		parameterTags = append(parameterTags, ssmtypes.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	for _, key := range keys {
+		_, err := b.client.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+			ResourceType: ssmtypes.ResourceTypeForTaggingParameter,
+			ResourceId:   aws.String(name + "/" + key),
+			Tags:         parameterTags,
+		})
+		if err != nil {
+			return fmt.Errorf("tagging parameter %s/%s: %w", name, key, err)
+		}
+	}
+	return nil
+}
+
+// RestrictRead fails: SecureString parameters have no resource policy
+// that limits reads.
+func (b *ssmBackend) RestrictRead(_ context.Context, _ string, _ []string) error {
+	return fmt.Errorf("read restrictions require the %s backend", BackendSecretsManager)
+}
+
+func (b *ssmBackend) VerifyCommand(region, prefix string) string {
+	return fmt.Sprintf("aws ssm get-parameters-by-path --region %s --path /%s --recursive --query 'Parameters[].Name' --no-cli-pager", region, strings.Trim(prefix, "/"))
+}
@@ -0,0 +1,162 @@
+package envsecrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Env file encodings.
+const (
+	FormatPlain = "plaintext"
+	FormatSOPS  = "sops"
+	FormatAge   = "age"
+)
+
+const (
+	// ageHeader starts every binary age file.
+	ageHeader = "age-encryption.org/v1"
+
+	// ageArmorHeader starts every ASCII-armored age file.
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+var (
+	// sopsDotenvPattern matches the metadata keys SOPS adds to dotenv files.
+	sopsDotenvPattern = regexp.MustCompile(`(?m)^sops_(?:mac|version|lastmodified)=`)
+
+	// sopsStructuredPattern matches the top-level "sops" metadata key of
+	// SOPS-encrypted YAML and JSON files.
+	sopsStructuredPattern = regexp.MustCompile(`(?m)^(?:sops:|\s*"sops"\s*:)`)
+)
+
+// ReadEnvFile returns the plaintext contents of an env file and its encoding,
+// decrypting SOPS- and age-encrypted files in memory. Plaintext is never
+// written to disk.
+func ReadEnvFile(ctx context.Context, filename string) ([]byte, string, error) {
+	data, err := os.ReadFile(filename) //nolint:gosec // G304: env file path is provided by the user
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format := DetectFormat(data); format {
+	case FormatSOPS:
+		plaintext, err := decryptSOPS(ctx, filename, data)
+		return plaintext, format, err
+	case FormatAge:
+		plaintext, err := decryptAge(data)
+		return plaintext, format, err
+	default:
+		return data, format, nil
+	}
+}
+
+// DetectFormat reports whether env file contents are plaintext,
+// SOPS-encrypted, or age-encrypted.
+func DetectFormat(data []byte) string {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	switch {
+	case bytes.HasPrefix(trimmed, []byte(ageHeader)), bytes.HasPrefix(trimmed, []byte(ageArmorHeader)):
+		return FormatAge
+	case sopsDotenvPattern.Match(data), sopsStructuredPattern.Match(data):
+		return FormatSOPS
+	default:
+		return FormatPlain
+	}
+}
+
+// decryptSOPS decrypts a SOPS file with the sops CLI, which resolves age,
+// PGP, and KMS keys the same way it does for `sops -d`. The decrypted output
+// is captured in memory.
+func decryptSOPS(ctx context.Context, filename string, data []byte) ([]byte, error) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		return nil, fmt.Errorf("%s is SOPS-encrypted but the sops CLI is not installed (https://github.com/getsops/sops)", filename)
+	}
+
+	args := []string{"--decrypt", "--output-type", "dotenv"}
+	if IsManifestFile(filename) {
+		// Keep the groups of a manifest; dotenv output would flatten them
+		args = []string{"--decrypt", "--output-type", "json"}
+	}
+	// sops infers the input type from the extension; secrets.enc.env is dotenv
+	// but e.g. .env.enc is not recognized
+	if sopsDotenvPattern.Match(data) && filepath.Ext(filename) != ".env" {
+		args = append(args, "--input-type", "dotenv")
+	}
+	args = append(args, filename)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sops", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sops decrypt: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// decryptAge decrypts an age file with the identities from ageIdentities.
+func decryptAge(data []byte) ([]byte, error) {
+	identities, err := ageIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(ageArmorHeader)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimLeft(data, " \t\r\n")))
+	}
+
+	reader, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("age decrypt: %w", err)
+	}
+	return io.ReadAll(reader)
+}
+
+// ageIdentities loads age identities from, in order: SOPS_AGE_KEY,
+// SOPS_AGE_KEY_FILE, or the sops default key file
+// ({user config dir}/sops/age/keys.txt).
+func ageIdentities() ([]age.Identity, error) {
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		identities, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("parsing SOPS_AGE_KEY: %w", err)
+		}
+		return identities, nil
+	}
+
+	path := os.Getenv("SOPS_AGE_KEY_FILE")
+	if path == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("no age identity: set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+		}
+		path = filepath.Join(configDir, "sops", "age", "keys.txt")
+	}
+
+	file, err := os.Open(path) //nolint:gosec // G304: key file path comes from the environment or the sops default
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no age identity found at %s: set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE", path)
+		}
+		return nil, fmt.Errorf("reading age identities: %w", err)
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age identities in %s: %w", path, err)
+	}
+	return identities, nil
+}
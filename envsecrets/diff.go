@@ -0,0 +1,114 @@
+package envsecrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// Diff is the key-level difference between a secret and the env file.
+type Diff struct {
+	Added   []string
+	Changed []string
+	Removed []string // In the secret but no longer in the env file
+}
+
+// DiffKeys compares the current secret keys with the desired keys.
+func DiffKeys(current, desired map[string]string) Diff {
+	var diff Diff
+	for key, value := range desired {
+		old, ok := current[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, key)
+		case old != value:
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for key := range current {
+		if _, ok := desired[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// HasChanges reports whether the secret needs to be written.
+func (d Diff) HasChanges(prune bool) bool {
+	return len(d.Added) > 0 || len(d.Changed) > 0 || (prune && len(d.Removed) > 0)
+}
+
+// Print writes the diff with masked values.
+func (d Diff) Print(w io.Writer, current, desired map[string]string, prune bool) {
+	for _, key := range d.Added {
+		fmt.Fprintf(w, "  + %s = %s\n", key, MaskValue(key, desired[key]))
+	}
+	for _, key := range d.Changed {
+		fmt.Fprintf(w, "  ~ %s: %s -> %s\n", key, MaskValue(key, current[key]), MaskValue(key, desired[key]))
+	}
+	for _, key := range d.Removed {
+		if prune {
+			fmt.Fprintf(w, "  - %s\n", key)
+		} else {
+			fmt.Fprintf(w, "  ! %s (not in env file; kept, use --prune to remove)\n", key)
+		}
+	}
+}
+
+// MergeKeys returns the secret keys to write: the desired keys, plus keys
+// only in the current secret unless prune is set.
+func MergeKeys(current, desired map[string]string, prune bool) map[string]string {
+	merged := make(map[string]string, len(current)+len(desired))
+	if !prune {
+		for key, value := range current {
+			merged[key] = value
+		}
+	}
+	for key, value := range desired {
+		merged[key] = value
+	}
+	return merged
+}
+
+// getSecretKeys fetches the current key/value pairs of a secret. It returns
+// false if the secret does not exist.
+func getSecretKeys(ctx context.Context, client SecretsManagerAPI, secretName string) (map[string]string, bool, error) {
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretName),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("reading secret: %w", err)
+	}
+
+	keys := make(map[string]string)
+	if err := json.Unmarshal([]byte(aws.ToString(out.SecretString)), &keys); err != nil {
+		return nil, false, fmt.Errorf("existing secret is not a JSON object of strings: %w", err)
+	}
+	return keys, true, nil
+}
+
+// MaskValue masks a secret value for display. Values of sensitive-looking
+// keys show only their first 8 characters.
+func MaskValue(key, value string) string {
+	if !isSensitiveKey(key) {
+		return value
+	}
+	if len(value) <= 8 {
+		return "***"
+	}
+	return value[:8] + "***"
+}
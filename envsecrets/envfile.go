@@ -0,0 +1,249 @@
+package envsecrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigDir is the directory under the user's home searched for
+// project and global env files and groups manifests.
+const DefaultConfigDir = ".plexusone"
+
+// ErrNoEnvFile is returned by FindEnvFile when no env file exists.
+var ErrNoEnvFile = errors.New("no .env file found in: .env, ../.env, or ~/" + DefaultConfigDir + "/")
+
+// envLinePattern matches: optional "export", KEY, =, VALUE.
+var envLinePattern = regexp.MustCompile(`^\s*(export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// SecretGroup holds the keys found in an env file for one group.
+type SecretGroup struct {
+	Name        string
+	Description string
+	Keys        map[string]string
+}
+
+// KeyNames returns the names of the group's keys in order.
+func (g SecretGroup) KeyNames() []string {
+	names := make([]string, 0, len(g.Keys))
+	for key := range g.Keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnvFile is an env file whose keys have been assigned to secret groups.
+type EnvFile struct {
+	// Path is the file the keys were read from.
+	Path string
+
+	// Format is the encoding of the file: FormatPlain, FormatSOPS, or FormatAge.
+	Format string
+
+	// Groups holds one entry per group, in group order, including groups
+	// with no keys.
+	Groups []SecretGroup
+}
+
+// ParseEnvFile reads an env file, decrypting it if needed (see ReadEnvFile),
+// and assigns its keys to groups. Structured secrets manifests (see
+// IsManifestFile) are parsed with ParseManifest instead.
+func ParseEnvFile(ctx context.Context, path string, groups []Group) (*EnvFile, error) {
+	data, format, err := ReadEnvFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	parse := ParseEnv
+	if IsManifestFile(path) {
+		parse = ParseManifest
+	}
+	parsed, err := parse(data, groups)
+	if err != nil {
+		return nil, err
+	}
+	return &EnvFile{Path: path, Format: format, Groups: parsed}, nil
+}
+
+// ParseEnv assigns the KEY=VALUE lines of plaintext env file contents to
+// groups. A quoted value may span lines, e.g. a PEM key, and keeps its
+// line breaks. Comments, empty values, and placeholder values starting
+// with "your-" are skipped, as are keys that match no group.
+func ParseEnv(data []byte, groups []Group) ([]SecretGroup, error) {
+	parsed := make([]SecretGroup, len(groups))
+	for i, group := range groups {
+		parsed[i] = SecretGroup{Name: group.Name, Description: group.Description, Keys: make(map[string]string)}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+
+		// Skip empty lines and comments
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		matches := envLinePattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		key := matches[2]
+		value := matches[3]
+		if quote := openQuote(value); quote != "" {
+			start := lineNumber
+			for {
+				if !scanner.Scan() {
+					if err := scanner.Err(); err != nil {
+						return nil, err
+					}
+					return nil, fmt.Errorf("line %d: value of %s has no closing %s", start, key, quote)
+				}
+				lineNumber++
+				value += "\n" + scanner.Text()
+				if closesQuote(strings.TrimRight(scanner.Text(), " \t"), quote) {
+					break
+				}
+			}
+		}
+		value = unquoteEnvValue(value)
+
+		// Skip empty or placeholder values
+		if value == "" || strings.HasPrefix(value, "your-") {
+			continue
+		}
+
+		if i := MatchGroup(groups, key); i >= 0 {
+			parsed[i].Keys[key] = value
+		}
+	}
+	return parsed, scanner.Err()
+}
+
+// openQuote returns the quote a value starts with if it doesn't end with
+// it on the same line, or "".
+func openQuote(value string) string {
+	value = strings.TrimRight(value, " \t")
+	for _, quote := range []string{`"`, "'"} {
+		if strings.HasPrefix(value, quote) && !closesQuote(value[1:], quote) {
+			return quote
+		}
+	}
+	return ""
+}
+
+// closesQuote reports whether text ends with the closing quote: a single
+// quote, or a double quote not escaped by a backslash.
+func closesQuote(text, quote string) bool {
+	if !strings.HasSuffix(text, quote) {
+		return false
+	}
+	if quote == "'" {
+		return true
+	}
+	backslashes := len(text) - 1 - len(strings.TrimRight(text[:len(text)-1], `\`))
+	return backslashes%2 == 0
+}
+
+// doubleQuoteUnescaper undoes the backslash escapes shells recognize in
+// double-quoted strings; other backslashes are kept.
+var doubleQuoteUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\$`, `$`, "\\`", "`")
+
+// unquoteEnvValue removes one pair of matching quotes around a value, as a
+// shell does: single-quoted text is literal, and double-quoted text has its
+// escapes undone. Values without matching quotes are returned unchanged.
+func unquoteEnvValue(value string) string {
+	quoted := strings.TrimRight(value, " \t")
+	if len(quoted) < 2 {
+		return value
+	}
+	switch quote := quoted[:1]; {
+	case quote == "'" && closesQuote(quoted[1:], quote):
+		return quoted[1 : len(quoted)-1]
+	case quote == `"` && closesQuote(quoted[1:], quote):
+		return doubleQuoteUnescaper.Replace(quoted[1 : len(quoted)-1])
+	}
+	return value
+}
+
+// FindEnvFile searches for an env file in standard locations:
+//  1. .env in the current directory
+//  2. ../.env in the parent directory
+//  3. ~/.plexusone/projects/{project}/.env (if project specified)
+//  4. ~/.plexusone/.env (global fallback)
+//
+// It returns ErrNoEnvFile if none exists.
+func FindEnvFile(projectName string) (string, error) {
+	for _, path := range EnvFileCandidates(projectName) {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNoEnvFile
+}
+
+// EnvFileCandidates returns the locations FindEnvFile searches, in order,
+// whether or not they exist.
+func EnvFileCandidates(projectName string) []string {
+	candidates := []string{
+		".env",
+		"../.env",
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if projectName != "" {
+			candidates = append(candidates, filepath.Join(home, DefaultConfigDir, "projects", projectName, ".env"))
+		}
+		candidates = append(candidates, filepath.Join(home, DefaultConfigDir, ".env"))
+	}
+	return candidates
+}
+
+// DetectStackName reads stackName from config.json or config.yaml in the
+// current or parent directory.
+func DetectStackName() string {
+	configPaths := []string{"config.json", "config.yaml", "config.yml", "../config.json", "../config.yaml", "../config.yml"}
+	for _, path := range configPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var config struct {
+			StackName string `json:"stackName" yaml:"stackName"`
+		}
+		if strings.HasSuffix(path, ".json") {
+			err = json.Unmarshal(data, &config)
+		} else {
+			err = yaml.Unmarshal(data, &config)
+		}
+		if err == nil && config.StackName != "" {
+			return config.StackName
+		}
+	}
+	return ""
+}
+
+// DetectProjectName returns the project name used to find project env files:
+// the stack name from the config file, or the current directory name.
+func DetectProjectName() string {
+	if stackName := DetectStackName(); stackName != "" {
+		return stackName
+	}
+	if wd, err := os.Getwd(); err == nil {
+		return filepath.Base(wd)
+	}
+	return ""
+}
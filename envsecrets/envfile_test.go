@@ -0,0 +1,120 @@
+package envsecrets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "plain",
+			data: "OPENAI_API_KEY=sk-123\nLLM_MODEL=gpt-4o\n",
+			want: map[string]string{"OPENAI_API_KEY": "sk-123", "LLM_MODEL": "gpt-4o"},
+		},
+		{
+			name: "quoted",
+			data: "LLM_MODEL=\"gpt 4o\"\nLLM_PROVIDER='openai'\n",
+			want: map[string]string{"LLM_MODEL": "gpt 4o", "LLM_PROVIDER": "openai"},
+		},
+		{
+			name: "unmatched quotes",
+			data: "LLM_MODEL=abc'\nLLM_PROVIDER=x\"\nLLM_BASE_URL=it's \"x\"\n",
+			want: map[string]string{"LLM_MODEL": "abc'", "LLM_PROVIDER": "x\"", "LLM_BASE_URL": `it's "x"`},
+		},
+		{
+			name: "one quote pair",
+			data: "LLM_MODEL='\"quoted\"'\nLLM_PROVIDER=\"'single'\"\n",
+			want: map[string]string{"LLM_MODEL": `"quoted"`, "LLM_PROVIDER": "'single'"},
+		},
+		{
+			name: "escapes",
+			data: `LLM_MODEL="it's \"x\" \$HOME \\ \n"` + "\nLLM_PROVIDER='a\\b'\nLLM_BASE_URL=\"ends in \\\\\"\n",
+			want: map[string]string{"LLM_MODEL": `it's "x" $HOME \ \n`, "LLM_PROVIDER": `a\b`, "LLM_BASE_URL": `ends in \`},
+		},
+		{
+			name: "multiline escaped quote",
+			data: "LLM_MODEL=\"a \\\"\nb\"\n",
+			want: map[string]string{"LLM_MODEL": "a \"\nb"},
+		},
+		{
+			name: "export",
+			data: "export OPENAI_API_KEY=sk-123\n  export   LLM_MODEL=gpt-4o\n",
+			want: map[string]string{"OPENAI_API_KEY": "sk-123", "LLM_MODEL": "gpt-4o"},
+		},
+		{
+			name: "comments and blank lines",
+			data: "# OPENAI_API_KEY=sk-old\n\n   # indented comment\nOPENAI_API_KEY=sk-123\n",
+			want: map[string]string{"OPENAI_API_KEY": "sk-123"},
+		},
+		{
+			name: "empty and placeholder values",
+			data: "OPENAI_API_KEY=\nANTHROPIC_API_KEY=your-anthropic-key\nLLM_MODEL=\"\"\n",
+			want: map[string]string{},
+		},
+		{
+			name: "unmatched keys and malformed lines",
+			data: "UNRELATED=1\nnot a line\n1BAD=2\nLLM_MODEL=gpt-4o\n",
+			want: map[string]string{"LLM_MODEL": "gpt-4o"},
+		},
+		{
+			name: "multiline",
+			data: "LLM_PROVIDER=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nLLM_MODEL=gpt-4o\n",
+			want: map[string]string{"LLM_PROVIDER": "-----BEGIN KEY-----\nabc\n-----END KEY-----", "LLM_MODEL": "gpt-4o"},
+		},
+		{
+			name: "multiline single quoted",
+			data: "LLM_PROVIDER='\nline\n'\n",
+			want: map[string]string{"LLM_PROVIDER": "\nline\n"},
+		},
+		{
+			name:    "unterminated quote",
+			data:    "LLM_MODEL=gpt-4o\nLLM_PROVIDER=\"openai\nLLM_BASE_URL=x\n",
+			wantErr: `line 2: value of LLM_PROVIDER has no closing "`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := ParseEnv([]byte(tt.data), DefaultGroups())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseEnv error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEnv: %v", err)
+			}
+			got := make(map[string]string)
+			for _, group := range groups {
+				for key, value := range group.Keys {
+					got[key] = value
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEnv keys = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvGroups(t *testing.T) {
+	groups, err := ParseEnv([]byte("OPENAI_API_KEY=sk-123\nSERPER_API_KEY=abc\n"), DefaultGroups())
+	if err != nil {
+		t.Fatalf("ParseEnv: %v", err)
+	}
+	want := []SecretGroup{
+		{Name: "llm", Description: "LLM provider API keys", Keys: map[string]string{"OPENAI_API_KEY": "sk-123"}},
+		{Name: "search", Description: "Search provider API keys", Keys: map[string]string{"SERPER_API_KEY": "abc"}},
+		{Name: "config", Description: "Configuration and observability settings", Keys: map[string]string{}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("ParseEnv = %+v, want %+v", groups, want)
+	}
+}
@@ -0,0 +1,198 @@
+// Package envsecrets reads env files and pushes their keys to AWS as secret
+// groups, the unit in which cmd/deploy and cmd/push-secrets store secrets.
+//
+// A typical push finds and parses an env file, then writes each group:
+//
+//	groups, _, err := envsecrets.ResolveGroups("", project)
+//	path, err := envsecrets.FindEnvFile(project)
+//	file, err := envsecrets.ParseEnvFile(ctx, path, groups)
+//	backend := envsecrets.NewSecretsManagerBackend(secretsmanager.NewFromConfig(cfg))
+//	results, err := envsecrets.PushGroups(ctx, backend, file.Groups, envsecrets.PushOptions{Prefix: "myapp"})
+package envsecrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GroupsFileName is the name of the groups manifest searched for by FindGroupsFile.
+const GroupsFileName = "secret-groups.yaml"
+
+// groupNamePattern matches group names, which are used in secret names and
+// SSM parameter paths.
+var groupNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Group is a named set of environment variables stored together as one secret.
+type Group struct {
+	// Name is the group name, used as the last segment of the secret name.
+	Name string `json:"name" yaml:"name"`
+
+	// Description is the secret description.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Keys are exact environment variable names that belong to the group.
+	Keys []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+
+	// Patterns are regular expressions matched against the whole
+	// environment variable name, e.g. ".*_API_KEY".
+	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+
+	compiled []*regexp.Regexp
+}
+
+// GroupsManifest is the shape of a secret-groups.yaml file.
+type GroupsManifest struct {
+	Groups []Group `json:"groups" yaml:"groups"`
+}
+
+// DefaultGroups returns the built-in llm, search, and config groups.
+func DefaultGroups() []Group {
+	return []Group{
+		{
+			Name:        "llm",
+			Description: "LLM provider API keys",
+			Keys: []string{
+				"GOOGLE_API_KEY", "GEMINI_API_KEY", "ANTHROPIC_API_KEY",
+				"CLAUDE_API_KEY", "OPENAI_API_KEY", "XAI_API_KEY", "LLM_API_KEY",
+			},
+		},
+		{
+			Name:        "search",
+			Description: "Search provider API keys",
+			Keys:        []string{"SERPER_API_KEY", "SERPAPI_API_KEY"},
+		},
+		{
+			Name:        "config",
+			Description: "Configuration and observability settings",
+			Keys: []string{
+				"LLM_PROVIDER", "LLM_MODEL", "LLM_BASE_URL", "SEARCH_PROVIDER",
+				"OBSERVABILITY_ENABLED", "OBSERVABILITY_PROVIDER",
+				"OPIK_API_KEY", "OPIK_WORKSPACE", "OPIK_PROJECT",
+				"LANGFUSE_PUBLIC_KEY", "LANGFUSE_SECRET_KEY", "PHOENIX_API_KEY",
+			},
+		},
+	}
+}
+
+// LoadGroups loads and validates a groups manifest.
+func LoadGroups(path string) ([]Group, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: manifest path is provided by the user
+	if err != nil {
+		return nil, fmt.Errorf("reading groups manifest: %w", err)
+	}
+
+	var manifest GroupsManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing groups manifest %s: %w", path, err)
+	}
+	if err := ValidateGroups(manifest.Groups); err != nil {
+		return nil, fmt.Errorf("invalid groups manifest %s: %w", path, err)
+	}
+	return manifest.Groups, nil
+}
+
+// ValidateGroups checks group names and compiles key patterns.
+func ValidateGroups(groups []Group) error {
+	if len(groups) == 0 {
+		return errors.New("no groups defined")
+	}
+
+	names := make(map[string]bool)
+	for i := range groups {
+		group := &groups[i]
+		if !groupNamePattern.MatchString(group.Name) {
+			return fmt.Errorf("groups[%d]: name %q must contain only letters, digits, '_', '.', and '-'", i, group.Name)
+		}
+		if names[group.Name] {
+			return fmt.Errorf("groups[%d]: duplicate group name %q", i, group.Name)
+		}
+		names[group.Name] = true
+
+		if len(group.Keys) == 0 && len(group.Patterns) == 0 {
+			return fmt.Errorf("groups[%d] (%s): at least one key or pattern is required", i, group.Name)
+		}
+
+		group.compiled = nil
+		for j, pattern := range group.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("groups[%d] (%s): patterns[%d]: %w", i, group.Name, j, err)
+			}
+			group.compiled = append(group.compiled, regexp.MustCompile("^(?:"+pattern+")$"))
+		}
+	}
+	return nil
+}
+
+// Matches reports whether an environment variable belongs to the group.
+func (g *Group) Matches(key string) bool {
+	for _, k := range g.Keys {
+		if k == key {
+			return true
+		}
+	}
+	for _, re := range g.compiled {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGroup returns the index of the first group the key belongs to, or -1.
+func MatchGroup(groups []Group, key string) int {
+	for i := range groups {
+		if groups[i].Matches(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindGroupsFile searches for a groups manifest in standard locations:
+//  1. secret-groups.yaml in the current directory
+//  2. ../secret-groups.yaml in the parent directory
+//  3. ~/.plexusone/projects/{project}/secret-groups.yaml (if project specified)
+//  4. ~/.plexusone/secret-groups.yaml
+func FindGroupsFile(projectName string) (string, bool) {
+	candidates := []string{
+		GroupsFileName,
+		filepath.Join("..", GroupsFileName),
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if projectName != "" {
+			candidates = append(candidates, filepath.Join(home, DefaultConfigDir, "projects", projectName, GroupsFileName))
+		}
+		candidates = append(candidates, filepath.Join(home, DefaultConfigDir, GroupsFileName))
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// ResolveGroups returns the groups from the manifest at path, or from the
+// manifest found by FindGroupsFile if path is empty, or DefaultGroups if
+// there is none. It also returns the manifest path, empty for the defaults.
+func ResolveGroups(path, projectName string) ([]Group, string, error) {
+	if path == "" {
+		found, ok := FindGroupsFile(projectName)
+		if !ok {
+			return DefaultGroups(), "", nil
+		}
+		path = found
+	}
+
+	groups, err := LoadGroups(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return groups, path, nil
+}
@@ -0,0 +1,33 @@
+package envsecrets
+
+import "testing"
+
+func TestMatchGroup(t *testing.T) {
+	groups := []Group{
+		{Name: "llm", Keys: []string{"OPENAI_API_KEY"}},
+		{Name: "keys", Patterns: []string{".*_API_KEY"}},
+		{Name: "prefixed", Patterns: []string{"APP_.*", "SERVICE_(URL|TOKEN)"}},
+	}
+	if err := ValidateGroups(groups); err != nil {
+		t.Fatalf("ValidateGroups: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"OPENAI_API_KEY", 0},    // exact key before a matching pattern
+		{"SERPER_API_KEY", 1},    // pattern
+		{"SERPER_API_KEY_2", -1}, // patterns match the whole name
+		{"APP_NAME", 2},
+		{"SERVICE_TOKEN", 2},
+		{"SERVICE_TOKENS", -1},
+		{"XAPP_NAME", -1},
+		{"openai_api_key", -1}, // keys are case sensitive
+	}
+	for _, tt := range tests {
+		if got := MatchGroup(groups, tt.key); got != tt.want {
+			t.Errorf("MatchGroup(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package envsecrets
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyNamePattern matches environment variable names, as in env files.
+var keyNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsManifestFile reports whether a file is a structured secrets manifest
+// (.yaml, .yml, or .json, e.g. secrets.yaml or secrets.enc.json) rather
+// than an env file. A trailing .age extension is ignored.
+func IsManifestFile(path string) bool {
+	switch filepath.Ext(strings.TrimSuffix(path, ".age")) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseManifest reads the keys of a structured secrets manifest, a YAML or
+// JSON object of groups, each an object of keys:
+//
+//	llm:
+//	  OPENAI_API_KEY: sk-...
+//	config:
+//	  LLM_MODEL: gpt-4o
+//	  SYSTEM_PROMPT: |
+//	    Multi-line values
+//	    are kept as is.
+//
+// Keys are grouped by the manifest, not by the groups' keys and patterns;
+// groups not in groups are returned after them, sorted by name. A flat
+// object of keys is assigned to groups like an env file. As with ParseEnv,
+// empty and null values and placeholders starting with "your-" are
+// skipped. Scalar values are taken verbatim, so 08 stays "08".
+func ParseManifest(data []byte, groups []Group) ([]SecretGroup, error) {
+	var top map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("secrets manifest must be an object: %w", err)
+	}
+	flat := true
+	for _, node := range top {
+		if node.Kind != yaml.ScalarNode {
+			flat = false
+		}
+	}
+	if flat {
+		return parseFlatManifest(top, groups)
+	}
+
+	var manifest map[string]map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("secrets manifest must be an object of groups, each an object of keys: %w", err)
+	}
+
+	parsed := make([]SecretGroup, 0, len(groups)+len(manifest))
+	known := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		parsed = append(parsed, SecretGroup{Name: group.Name, Description: group.Description, Keys: make(map[string]string)})
+		known[group.Name] = true
+	}
+	var extra []string
+	for name := range manifest {
+		if !known[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		if !groupNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("group %q must contain only letters, digits, '_', '.', and '-'", name)
+		}
+		parsed = append(parsed, SecretGroup{Name: name, Keys: make(map[string]string)})
+	}
+
+	for i := range parsed {
+		for key, node := range manifest[parsed[i].Name] {
+			if !keyNamePattern.MatchString(key) {
+				return nil, fmt.Errorf("%s.%s: key must be a valid environment variable name", parsed[i].Name, key)
+			}
+			value, ok, err := manifestValue(node)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", parsed[i].Name, key, err)
+			}
+			if ok {
+				parsed[i].Keys[key] = value
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// parseFlatManifest assigns the keys of a flat manifest to groups by their
+// keys and patterns, as ParseEnv does.
+func parseFlatManifest(manifest map[string]yaml.Node, groups []Group) ([]SecretGroup, error) {
+	parsed := make([]SecretGroup, len(groups))
+	for i, group := range groups {
+		parsed[i] = SecretGroup{Name: group.Name, Description: group.Description, Keys: make(map[string]string)}
+	}
+	for key, node := range manifest {
+		if !keyNamePattern.MatchString(key) {
+			return nil, fmt.Errorf("%s: key must be a valid environment variable name", key)
+		}
+		value, ok, err := manifestValue(node)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if i := MatchGroup(groups, key); ok && i >= 0 {
+			parsed[i].Keys[key] = value
+		}
+	}
+	return parsed, nil
+}
+
+// manifestValue returns the value of a key, and false for the skipped
+// empty, null, and placeholder values.
+func manifestValue(node yaml.Node) (string, bool, error) {
+	if node.Kind != yaml.ScalarNode {
+		return "", false, fmt.Errorf("value must be a string")
+	}
+	if node.Tag == "!!null" || node.Value == "" || strings.HasPrefix(node.Value, "your-") {
+		return "", false, nil
+	}
+	return node.Value, true, nil
+}
@@ -0,0 +1,52 @@
+package envsecrets
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Placeholders of secret name prefixes.
+const (
+	PrefixProject     = "{project}"
+	PrefixEnvironment = "{env}"
+)
+
+// prefixPattern matches expanded prefixes: slash-separated segments that
+// are valid in both Secrets Manager names and SSM parameter paths.
+var prefixPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// DefaultPrefix returns the default secret name prefix template:
+// "{project}", or "{project}/{env}" for an environment, so the secrets of
+// different projects and environments don't collide. The stack's default
+// secrets.prefix is the same.
+func DefaultPrefix(environment string) string {
+	if environment == "" {
+		return PrefixProject
+	}
+	return PrefixProject + "/" + PrefixEnvironment
+}
+
+// ExpandPrefix replaces the {project} and {env} placeholders of a secret
+// name prefix, e.g. "{project}/{env}" becomes "stats-agent/prod". An empty
+// template is DefaultPrefix(environment).
+func ExpandPrefix(template, project, environment string) (string, error) {
+	if template == "" {
+		template = DefaultPrefix(environment)
+	}
+	if strings.Contains(template, PrefixProject) && project == "" {
+		return "", fmt.Errorf("prefix %q: %s requires a project name", template, PrefixProject)
+	}
+	if strings.Contains(template, PrefixEnvironment) && environment == "" {
+		return "", fmt.Errorf("prefix %q: %s requires an environment", template, PrefixEnvironment)
+	}
+
+	prefix := strings.Trim(strings.NewReplacer(PrefixProject, project, PrefixEnvironment, environment).Replace(template), "/")
+	if strings.ContainsAny(prefix, "{}") {
+		return "", fmt.Errorf("prefix %q: only the %s and %s placeholders are supported", template, PrefixProject, PrefixEnvironment)
+	}
+	if !prefixPattern.MatchString(prefix) {
+		return "", fmt.Errorf("prefix %q must be slash-separated segments of letters, digits, '_', '.', and '-'", prefix)
+	}
+	return prefix, nil
+}
@@ -0,0 +1,96 @@
+package envsecrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// plainValuePattern matches values written to env files without quotes.
+var plainValuePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@,+=%-]*$`)
+
+// PulledSecret is the keys of one group's secret read from a backend.
+type PulledSecret struct {
+	// Group is the group name.
+	Group string
+
+	// Name is the secret name.
+	Name string
+
+	// Found is false if the secret does not exist.
+	Found bool
+
+	// Keys are the keys of the secret.
+	Keys map[string]string
+}
+
+// PullGroups reads the secret of each group from the backend, the reverse
+// of PushGroups. Secrets that don't exist are returned with Found unset.
+func PullGroups(ctx context.Context, backend Backend, groups []Group, prefix string) ([]PulledSecret, error) {
+	secrets := make([]PulledSecret, 0, len(groups))
+	for _, group := range groups {
+		name := backend.SecretName(prefix, group.Name)
+		keys, found, err := backend.Get(ctx, name)
+		if err != nil {
+			return secrets, fmt.Errorf("reading %s: %w", name, err)
+		}
+		secrets = append(secrets, PulledSecret{Group: group.Name, Name: name, Found: found, Keys: keys})
+	}
+	return secrets, nil
+}
+
+// FormatEnv renders pulled secrets as env file contents, one commented
+// section per secret with its keys sorted. Unless reveal is set, values are
+// masked with MaskValue, so the file shows which keys exist without
+// exposing them. Multi-line values are quoted across lines, as ParseEnv
+// reads them; values that contain carriage returns cannot be written.
+func FormatEnv(secrets []PulledSecret, reveal bool) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, secret := range secrets {
+		if !secret.Found || len(secret.Keys) == 0 {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "# %s\n", secret.Name)
+
+		keys := make([]string, 0, len(secret.Keys))
+		for key := range secret.Keys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := secret.Keys[key]
+			if strings.Contains(value, "\r") {
+				return nil, fmt.Errorf("%s in %s: carriage returns cannot be written to an env file", key, secret.Name)
+			}
+			if !reveal {
+				value = MaskValue(key, value)
+			}
+			fmt.Fprintf(&buf, "%s=%s\n", key, quoteEnvValue(value))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// doubleQuoteEscaper escapes the characters shells interpret in
+// double-quoted strings.
+var doubleQuoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+
+// quoteEnvValue quotes a value with spaces or special characters, so
+// ParseEnv and shells read it back unchanged: in single quotes, or in
+// double quotes with escapes if it contains a single quote.
+func quoteEnvValue(value string) string {
+	switch {
+	case plainValuePattern.MatchString(value):
+		return value
+	case !strings.Contains(value, "'"):
+		return "'" + value + "'"
+	default:
+		return `"` + doubleQuoteEscaper.Replace(value) + `"`
+	}
+}
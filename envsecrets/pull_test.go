@@ -0,0 +1,46 @@
+package envsecrets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatEnvRoundTrip(t *testing.T) {
+	keys := map[string]string{
+		"PLAIN":        "sk-123",
+		"SPACES":       "gpt 4o",
+		"TRAILING":     "abc'",
+		"DOUBLE":       `x"`,
+		"QUOTED":       `"quoted"`,
+		"MIXED":        `it's "x"`,
+		"SHELL":        "it's $HOME `date` \\n \\",
+		"PEM":          "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"MULTI_QUOTES": "it's\n\"x\"\n",
+		"HASH":         "# not a comment",
+	}
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	groups := []Group{{Name: "config", Keys: names}}
+
+	data, err := FormatEnv([]PulledSecret{{Group: "config", Name: "app/config", Found: true, Keys: keys}}, true)
+	if err != nil {
+		t.Fatalf("FormatEnv: %v", err)
+	}
+	parsed, err := ParseEnv(data, groups)
+	if err != nil {
+		t.Fatalf("ParseEnv: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(parsed[0].Keys, keys) {
+		t.Errorf("round trip = %q, want %q\n%s", parsed[0].Keys, keys, data)
+	}
+}
+
+func TestFormatEnvCarriageReturn(t *testing.T) {
+	_, err := FormatEnv([]PulledSecret{{Name: "app/config", Found: true, Keys: map[string]string{"KEY": "a\r\nb"}}}, true)
+	if err == nil || !strings.Contains(err.Error(), "carriage returns") {
+		t.Errorf("FormatEnv error = %v, want carriage returns rejected", err)
+	}
+}
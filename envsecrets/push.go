@@ -0,0 +1,208 @@
+package envsecrets
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Push actions.
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionSkip      = "skip"
+)
+
+// PushOptions controls PushGroups.
+type PushOptions struct {
+	// Prefix is the secret name prefix: secrets are named by
+	// Backend.SecretName(Prefix, group).
+	Prefix string
+
+	// DryRun computes the diff without writing.
+	DryRun bool
+
+	// Prune removes keys that are no longer in the env file.
+	Prune bool
+
+	// Tags are added to every secret pushed, e.g. DefaultTags. Requires a
+	// backend that is a Tagger.
+	Tags map[string]string
+
+	// ReaderARNs, if set, are the only principals allowed to read the
+	// secrets' values (see ReadPolicy). Requires the secretsmanager
+	// backend.
+	ReaderARNs []string
+
+	// SyncReplicas adds the missing replicas of unchanged secrets too,
+	// describing each of them. Without it, only secrets that are created
+	// or updated are replicated. Requires a backend that is a Replicator.
+	SyncReplicas bool
+
+	// Out receives the progress and masked diff of each secret. Nil
+	// discards it.
+	Out io.Writer
+}
+
+// PushResult is the outcome for one secret group.
+type PushResult struct {
+	// Name is the secret name.
+	Name string
+
+	// Action is ActionCreate, ActionUpdate, ActionUnchanged, or ActionSkip
+	// (no keys in the env file). In dry-run mode it is the action that
+	// would be taken.
+	Action string
+
+	// Diff is the key-level difference from the current secret.
+	Diff Diff
+
+	// Replicated are the regions an existing secret was replicated to,
+	// when the backend is a Replicator (see PushOptions.SyncReplicas). In
+	// dry-run mode they are the regions it would be replicated to.
+	Replicated []string
+}
+
+// PushGroups writes each group with keys to the backend. Each secret is
+// compared with its current value first and only written if it changed;
+// keys only in the secret are kept unless opts.Prune is set. In dry-run
+// mode, a secret that cannot be read is reported with all keys as new.
+func PushGroups(ctx context.Context, backend Backend, groups []SecretGroup, opts PushOptions) ([]PushResult, error) {
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	results := make([]PushResult, 0, len(groups))
+	for _, group := range groups {
+		name := backend.SecretName(opts.Prefix, group.Name)
+		result, err := pushGroup(ctx, backend, name, group, opts, out)
+		if err != nil {
+			return results, fmt.Errorf("processing %s: %w", name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// pushGroup compares and writes a single group.
+func pushGroup(ctx context.Context, backend Backend, name string, group SecretGroup, opts PushOptions, out io.Writer) (PushResult, error) {
+	result := PushResult{Name: name, Action: ActionSkip}
+	if len(group.Keys) == 0 {
+		fmt.Fprintf(out, "Skipping %s (no keys found)\n", name)
+		return result, nil
+	}
+
+	current, exists, err := backend.Get(ctx, name)
+	if err != nil {
+		if !opts.DryRun {
+			return result, err
+		}
+		fmt.Fprintf(out, "%s: could not read current value (%v); showing all keys as new\n", name, err)
+	}
+
+	if !exists {
+		result.Action = ActionCreate
+		result.Diff = DiffKeys(nil, group.Keys)
+		fmt.Fprintf(out, "Creating: %s\n", name)
+		result.Diff.Print(out, nil, group.Keys, opts.Prune)
+		if opts.DryRun {
+			fmt.Fprintf(out, "  [DRY RUN] Would create\n")
+			return result, applyTags(ctx, backend, name, group.KeyNames(), opts, out)
+		}
+
+		if err := backend.Create(ctx, name, group); err != nil {
+			return result, err
+		}
+		fmt.Fprintf(out, "  Created new secret\n")
+		return result, applyTags(ctx, backend, name, group.KeyNames(), opts, out)
+	}
+
+	keys := SecretGroup{Keys: MergeKeys(current, group.Keys, opts.Prune)}.KeyNames()
+
+	result.Diff = DiffKeys(current, group.Keys)
+	if !result.Diff.HasChanges(opts.Prune) {
+		result.Action = ActionUnchanged
+		fmt.Fprintf(out, "Unchanged: %s (%d keys)\n", name, len(current))
+		result.Diff.Print(out, current, group.Keys, opts.Prune)
+		if err := applyTags(ctx, backend, name, keys, opts, out); err != nil {
+			return result, err
+		}
+		if !opts.SyncReplicas {
+			return result, nil
+		}
+		return result, addMissingReplicas(ctx, backend, name, opts, out, &result)
+	}
+
+	result.Action = ActionUpdate
+	fmt.Fprintf(out, "Updating: %s\n", name)
+	result.Diff.Print(out, current, group.Keys, opts.Prune)
+	if opts.DryRun {
+		fmt.Fprintf(out, "  [DRY RUN] Would update\n")
+		if err := applyTags(ctx, backend, name, keys, opts, out); err != nil {
+			return result, err
+		}
+		return result, addMissingReplicas(ctx, backend, name, opts, out, &result)
+	}
+
+	if err := backend.Update(ctx, name, current, group, result.Diff, opts.Prune); err != nil {
+		return result, err
+	}
+	fmt.Fprintf(out, "  Updated existing secret\n")
+	if err := applyTags(ctx, backend, name, keys, opts, out); err != nil {
+		return result, err
+	}
+	return result, addMissingReplicas(ctx, backend, name, opts, out, &result)
+}
+
+// addMissingReplicas replicates an existing secret to the replica regions
+// it is missing, if the backend is a Replicator.
+func addMissingReplicas(ctx context.Context, backend Backend, name string, opts PushOptions, out io.Writer, result *PushResult) error {
+	replicator, ok := backend.(Replicator)
+	if !ok {
+		return nil
+	}
+	missing, err := replicator.MissingReplicas(ctx, name)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	result.Replicated = ReplicaRegionNames(missing)
+	regions := strings.Join(result.Replicated, ", ")
+	if opts.DryRun {
+		fmt.Fprintf(out, "  [DRY RUN] Would replicate to %s\n", regions)
+		return nil
+	}
+	if err := replicator.AddReplicas(ctx, name, missing); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "  Replicated to %s\n", regions)
+	return nil
+}
+
+// Summarize counts the results by action, e.g. "1 updated, 2 unchanged".
+// Unchanged secrets were not written, so they gained no new version.
+func Summarize(results []PushResult) string {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Action]++
+	}
+
+	var parts []string
+	for _, action := range []struct{ name, label string }{
+		{ActionCreate, "created"},
+		{ActionUpdate, "updated"},
+		{ActionUnchanged, "unchanged"},
+		{ActionSkip, "skipped"},
+	} {
+		if counts[action.name] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[action.name], action.label))
+		}
+	}
+	if len(parts) == 0 {
+		return "no secrets"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,210 @@
+package envsecrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memoryBackend is a Backend that keeps secrets in memory.
+type memoryBackend struct {
+	secrets map[string]map[string]string
+	writes  int
+	getErr  error
+}
+
+func newMemoryBackend(secrets map[string]map[string]string) *memoryBackend {
+	if secrets == nil {
+		secrets = make(map[string]map[string]string)
+	}
+	return &memoryBackend{secrets: secrets}
+}
+
+func (b *memoryBackend) SecretName(prefix, group string) string {
+	return prefix + group
+}
+
+func (b *memoryBackend) Get(_ context.Context, name string) (map[string]string, bool, error) {
+	if b.getErr != nil {
+		return nil, false, b.getErr
+	}
+	keys, ok := b.secrets[name]
+	return maps.Clone(keys), ok, nil
+}
+
+func (b *memoryBackend) Create(_ context.Context, name string, group SecretGroup) error {
+	b.writes++
+	b.secrets[name] = maps.Clone(group.Keys)
+	return nil
+}
+
+func (b *memoryBackend) Update(_ context.Context, name string, current map[string]string, group SecretGroup, _ Diff, prune bool) error {
+	b.writes++
+	b.secrets[name] = MergeKeys(current, group.Keys, prune)
+	return nil
+}
+
+func (b *memoryBackend) VerifyCommand(_, prefix string) string {
+	return "list " + prefix
+}
+
+func TestPushGroups(t *testing.T) {
+	existing := func() map[string]map[string]string {
+		return map[string]map[string]string{
+			"app/llm":    {"OPENAI_API_KEY": "old", "XAI_API_KEY": "xai"},
+			"app/config": {"LLM_MODEL": "gpt-4o"},
+		}
+	}
+	groups := []SecretGroup{
+		{Name: "llm", Keys: map[string]string{"OPENAI_API_KEY": "new", "ANTHROPIC_API_KEY": "ant"}},
+		{Name: "search", Keys: map[string]string{"SERPER_API_KEY": "serper"}},
+		{Name: "config", Keys: map[string]string{"LLM_MODEL": "gpt-4o"}},
+		{Name: "empty", Keys: map[string]string{}},
+	}
+
+	tests := []struct {
+		name        string
+		opts        PushOptions
+		wantActions []string
+		wantDiffs   []Diff
+		wantSecrets map[string]map[string]string
+		wantWrites  int
+	}{
+		{
+			name:        "keeps removed keys",
+			opts:        PushOptions{Prefix: "app/"},
+			wantActions: []string{ActionUpdate, ActionCreate, ActionUnchanged, ActionSkip},
+			wantDiffs: []Diff{
+				{Added: []string{"ANTHROPIC_API_KEY"}, Changed: []string{"OPENAI_API_KEY"}, Removed: []string{"XAI_API_KEY"}},
+				{Added: []string{"SERPER_API_KEY"}},
+				{},
+				{},
+			},
+			wantSecrets: map[string]map[string]string{
+				"app/llm":    {"OPENAI_API_KEY": "new", "ANTHROPIC_API_KEY": "ant", "XAI_API_KEY": "xai"},
+				"app/search": {"SERPER_API_KEY": "serper"},
+				"app/config": {"LLM_MODEL": "gpt-4o"},
+			},
+			wantWrites: 2,
+		},
+		{
+			name:        "prune",
+			opts:        PushOptions{Prefix: "app/", Prune: true},
+			wantActions: []string{ActionUpdate, ActionCreate, ActionUnchanged, ActionSkip},
+			wantDiffs: []Diff{
+				{Added: []string{"ANTHROPIC_API_KEY"}, Changed: []string{"OPENAI_API_KEY"}, Removed: []string{"XAI_API_KEY"}},
+				{Added: []string{"SERPER_API_KEY"}},
+				{},
+				{},
+			},
+			wantSecrets: map[string]map[string]string{
+				"app/llm":    {"OPENAI_API_KEY": "new", "ANTHROPIC_API_KEY": "ant"},
+				"app/search": {"SERPER_API_KEY": "serper"},
+				"app/config": {"LLM_MODEL": "gpt-4o"},
+			},
+			wantWrites: 2,
+		},
+		{
+			name:        "dry run",
+			opts:        PushOptions{Prefix: "app/", Prune: true, DryRun: true},
+			wantActions: []string{ActionUpdate, ActionCreate, ActionUnchanged, ActionSkip},
+			wantDiffs: []Diff{
+				{Added: []string{"ANTHROPIC_API_KEY"}, Changed: []string{"OPENAI_API_KEY"}, Removed: []string{"XAI_API_KEY"}},
+				{Added: []string{"SERPER_API_KEY"}},
+				{},
+				{},
+			},
+			wantSecrets: existing(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMemoryBackend(existing())
+			results, err := PushGroups(context.Background(), backend, groups, tt.opts)
+			if err != nil {
+				t.Fatalf("PushGroups: %v", err)
+			}
+			var actions []string
+			var diffs []Diff
+			for _, result := range results {
+				actions = append(actions, result.Action)
+				diffs = append(diffs, result.Diff)
+			}
+			if !reflect.DeepEqual(actions, tt.wantActions) {
+				t.Errorf("actions = %q, want %q", actions, tt.wantActions)
+			}
+			if !reflect.DeepEqual(diffs, tt.wantDiffs) {
+				t.Errorf("diffs = %+v, want %+v", diffs, tt.wantDiffs)
+			}
+			if !reflect.DeepEqual(backend.secrets, tt.wantSecrets) {
+				t.Errorf("secrets = %q, want %q", backend.secrets, tt.wantSecrets)
+			}
+			if backend.writes != tt.wantWrites {
+				t.Errorf("%d writes, want %d", backend.writes, tt.wantWrites)
+			}
+		})
+	}
+}
+
+func TestPushGroupsPruneOnly(t *testing.T) {
+	// A secret whose only change is a removed key is written with prune
+	// and left alone without it
+	for _, prune := range []bool{false, true} {
+		backend := newMemoryBackend(map[string]map[string]string{"llm": {"OPENAI_API_KEY": "sk", "XAI_API_KEY": "xai"}})
+		groups := []SecretGroup{{Name: "llm", Keys: map[string]string{"OPENAI_API_KEY": "sk"}}}
+		results, err := PushGroups(context.Background(), backend, groups, PushOptions{Prune: prune})
+		if err != nil {
+			t.Fatalf("PushGroups: %v", err)
+		}
+		want, wantKeys := ActionUnchanged, map[string]string{"OPENAI_API_KEY": "sk", "XAI_API_KEY": "xai"}
+		if prune {
+			want, wantKeys = ActionUpdate, map[string]string{"OPENAI_API_KEY": "sk"}
+		}
+		if results[0].Action != want {
+			t.Errorf("prune %v: action %s, want %s", prune, results[0].Action, want)
+		}
+		if !reflect.DeepEqual(backend.secrets["llm"], wantKeys) {
+			t.Errorf("prune %v: secret %q, want %q", prune, backend.secrets["llm"], wantKeys)
+		}
+	}
+}
+
+func TestPushGroupsReadError(t *testing.T) {
+	groups := []SecretGroup{{Name: "llm", Keys: map[string]string{"OPENAI_API_KEY": "sk"}}}
+
+	backend := newMemoryBackend(nil)
+	backend.getErr = errors.New("access denied")
+	if _, err := PushGroups(context.Background(), backend, groups, PushOptions{}); err == nil || !strings.Contains(err.Error(), "processing llm: access denied") {
+		t.Errorf("PushGroups error = %v, want the read error", err)
+	}
+
+	// A dry run shows all keys as new instead
+	var out bytes.Buffer
+	results, err := PushGroups(context.Background(), backend, groups, PushOptions{DryRun: true, Out: &out})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if results[0].Action != ActionCreate || !reflect.DeepEqual(results[0].Diff.Added, []string{"OPENAI_API_KEY"}) {
+		t.Errorf("dry run result = %+v, want a create of all keys", results[0])
+	}
+	if !strings.Contains(out.String(), "could not read current value (access denied)") {
+		t.Errorf("dry run output doesn't report the read error:\n%s", out.String())
+	}
+	if backend.writes != 0 {
+		t.Errorf("dry run wrote %d times", backend.writes)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []PushResult{{Action: ActionUpdate}, {Action: ActionUnchanged}, {Action: ActionUnchanged}, {Action: ActionSkip}}
+	if got, want := Summarize(results), "1 updated, 2 unchanged, 1 skipped"; got != want {
+		t.Errorf("Summarize = %q, want %q", got, want)
+	}
+	if got := Summarize(nil); got != "no secrets" {
+		t.Errorf("Summarize(nil) = %q", got)
+	}
+}
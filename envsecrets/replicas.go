@@ -0,0 +1,165 @@
+package envsecrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+
+	"github.com/plexusone/agentkit-aws-cdk/internal/awsapi"
+)
+
+// Replica is a region a Secrets Manager secret is replicated to.
+type Replica struct {
+	// Region is the replica region, e.g. us-west-2.
+	Region string
+
+	// KMSKeyID is the ARN, ID, or alias of the key encrypting the replica
+	// in its region.
+	// Default: the aws/secretsmanager key of the region
+	KMSKeyID string
+}
+
+// ParseReplicas parses a comma-separated list of replica regions, each
+// optionally followed by "=" and the KMS key of the replica, e.g.
+// "us-west-2,eu-west-1=alias/dr-secrets". The primary region, where the
+// secrets are written, can't be a replica.
+func ParseReplicas(spec, primaryRegion string) ([]Replica, error) {
+	var replicas []Replica
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		region, kmsKeyID, _ := strings.Cut(item, "=")
+		if !awsapi.RegionPattern.MatchString(region) {
+			return nil, fmt.Errorf("invalid replica region %q", region)
+		}
+		if region == primaryRegion {
+			return nil, fmt.Errorf("replica region %s is the primary region", region)
+		}
+		if seen[region] {
+			return nil, fmt.Errorf("replica region %s is listed twice", region)
+		}
+		seen[region] = true
+		replicas = append(replicas, Replica{Region: region, KMSKeyID: kmsKeyID})
+	}
+	return replicas, nil
+}
+
+// Replicator is implemented by backends that replicate secrets to other
+// regions. PushGroups adds the missing replicas of each secret it updates,
+// and of unchanged secrets with PushOptions.SyncReplicas, so secrets pushed
+// before the replicas were configured are replicated too.
+type Replicator interface {
+	// MissingReplicas returns the configured replicas an existing secret
+	// doesn't have yet.
+	MissingReplicas(ctx context.Context, name string) ([]Replica, error)
+
+	// AddReplicas replicates an existing secret to the given regions.
+	AddReplicas(ctx context.Context, name string, replicas []Replica) error
+}
+
+// SecretsManagerReplicationAPI is the subset of the Secrets Manager client
+// used by the secretsmanager backend with replicas.
+type SecretsManagerReplicationAPI interface {
+	SecretsManagerAPI
+	DescribeSecret(ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+	ReplicateSecretToRegions(ctx context.Context, params *secretsmanager.ReplicateSecretToRegionsInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.ReplicateSecretToRegionsOutput, error)
+}
+
+// NewReplicatedSecretsManagerBackend returns a secretsmanager backend that
+// creates each secret with replicas in the given regions, and adds missing
+// replicas to existing secrets. Replicas stay in sync with the primary
+// secret, so only the primary region is ever written.
+func NewReplicatedSecretsManagerBackend(client SecretsManagerReplicationAPI, replicas []Replica) Backend {
+	return &replicatedSecretsManagerBackend{
+		secretsManagerBackend: secretsManagerBackend{client: client},
+		client:                client,
+		replicas:              replicas,
+	}
+}
+
+// replicatedSecretsManagerBackend is a secretsManagerBackend replicating its
+// secrets to other regions.
+type replicatedSecretsManagerBackend struct {
+	secretsManagerBackend
+	client   SecretsManagerReplicationAPI
+	replicas []Replica
+}
+
+func (b *replicatedSecretsManagerBackend) Create(ctx context.Context, name string, group SecretGroup) error {
+	secretValue, err := json.Marshal(group.Keys)
+	if err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	_, err = b.client.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:              aws.String(name),
+		Description:       aws.String(group.Description),
+		SecretString:      aws.String(string(secretValue)),
+		AddReplicaRegions: replicaRegions(b.replicas),
+	})
+	if err != nil {
+		return fmt.Errorf("creating secret: %w", err)
+	}
+	return nil
+}
+
+func (b *replicatedSecretsManagerBackend) MissingReplicas(ctx context.Context, name string) ([]Replica, error) {
+	out, err := b.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing secret: %w", err)
+	}
+	existing := make(map[string]bool, len(out.ReplicationStatus))
+	for _, status := range out.ReplicationStatus {
+		existing[aws.ToString(status.Region)] = true
+	}
+
+	var missing []Replica
+	for _, replica := range b.replicas {
+		if !existing[replica.Region] {
+			missing = append(missing, replica)
+		}
+	}
+	return missing, nil
+}
+
+func (b *replicatedSecretsManagerBackend) AddReplicas(ctx context.Context, name string, replicas []Replica) error {
+	_, err := b.client.ReplicateSecretToRegions(ctx, &secretsmanager.ReplicateSecretToRegionsInput{
+		SecretId:          aws.String(name),
+		AddReplicaRegions: replicaRegions(replicas),
+	})
+	if err != nil {
+		return fmt.Errorf("replicating secret: %w", err)
+	}
+	return nil
+}
+
+// replicaRegions converts replicas to the Secrets Manager API type.
+func replicaRegions(replicas []Replica) []types.ReplicaRegionType {
+	regions := make([]types.ReplicaRegionType, 0, len(replicas))
+	for _, replica := range replicas {
+		region := types.ReplicaRegionType{Region: aws.String(replica.Region)}
+		if replica.KMSKeyID != "" {
+			region.KmsKeyId = aws.String(replica.KMSKeyID)
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// ReplicaRegionNames returns the regions of replicas.
+func ReplicaRegionNames(replicas []Replica) []string {
+	regions := make([]string, 0, len(replicas))
+	for _, replica := range replicas {
+		regions = append(regions, replica.Region)
+	}
+	return regions
+}
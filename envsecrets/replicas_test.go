@@ -0,0 +1,182 @@
+package envsecrets
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+func TestParseReplicas(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Replica
+		wantErr string
+	}{
+		{spec: ""},
+		{spec: "us-west-2", want: []Replica{{Region: "us-west-2"}}},
+		{
+			spec: " us-west-2 , eu-west-1=alias/dr-secrets,",
+			want: []Replica{{Region: "us-west-2"}, {Region: "eu-west-1", KMSKeyID: "alias/dr-secrets"}},
+		},
+		{spec: "us-gov-west-1", want: []Replica{{Region: "us-gov-west-1"}}},
+		{spec: "uswest2", wantErr: `invalid replica region "uswest2"`},
+		{spec: "=alias/key", wantErr: `invalid replica region ""`},
+		{spec: "us-west-2,us-west-2=alias/key", wantErr: "replica region us-west-2 is listed twice"},
+		{spec: "us-west-2,us-east-1", wantErr: "replica region us-east-1 is the primary region"},
+	}
+	for _, tt := range tests {
+		got, err := ParseReplicas(tt.spec, "us-east-1")
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ParseReplicas(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseReplicas(%q): %v", tt.spec, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseReplicas(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+// fakeReplicationAPI is a SecretsManagerReplicationAPI serving the replica
+// regions of secrets.
+type fakeReplicationAPI struct {
+	SecretsManagerReplicationAPI
+	regions    map[string][]string
+	replicated []types.ReplicaRegionType
+}
+
+func (f *fakeReplicationAPI) DescribeSecret(_ context.Context, in *secretsmanager.DescribeSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	out := &secretsmanager.DescribeSecretOutput{Name: in.SecretId}
+	for _, region := range f.regions[aws.ToString(in.SecretId)] {
+		out.ReplicationStatus = append(out.ReplicationStatus, types.ReplicationStatusType{Region: aws.String(region)})
+	}
+	return out, nil
+}
+
+func (f *fakeReplicationAPI) ReplicateSecretToRegions(_ context.Context, in *secretsmanager.ReplicateSecretToRegionsInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.ReplicateSecretToRegionsOutput, error) {
+	f.replicated = append(f.replicated, in.AddReplicaRegions...)
+	return &secretsmanager.ReplicateSecretToRegionsOutput{}, nil
+}
+
+func TestMissingReplicas(t *testing.T) {
+	client := &fakeReplicationAPI{regions: map[string][]string{"app/llm": {"us-west-2"}}}
+	backend := NewReplicatedSecretsManagerBackend(client, []Replica{
+		{Region: "us-west-2"},
+		{Region: "eu-west-1", KMSKeyID: "alias/dr-secrets"},
+	}).(Replicator)
+
+	missing, err := backend.MissingReplicas(context.Background(), "app/llm")
+	if err != nil {
+		t.Fatalf("MissingReplicas: %v", err)
+	}
+	want := []Replica{{Region: "eu-west-1", KMSKeyID: "alias/dr-secrets"}}
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %+v, want %+v", missing, want)
+	}
+
+	if err := backend.AddReplicas(context.Background(), "app/llm", missing); err != nil {
+		t.Fatalf("AddReplicas: %v", err)
+	}
+	wantRegions := []types.ReplicaRegionType{{Region: aws.String("eu-west-1"), KmsKeyId: aws.String("alias/dr-secrets")}}
+	if !reflect.DeepEqual(client.replicated, wantRegions) {
+		t.Errorf("replicated = %+v, want %+v", client.replicated, wantRegions)
+	}
+}
+
+// replicatingBackend is a memoryBackend that is a Replicator.
+type replicatingBackend struct {
+	*memoryBackend
+	regions   map[string][]string
+	replicas  []Replica
+	describes int
+}
+
+func (b *replicatingBackend) MissingReplicas(_ context.Context, name string) ([]Replica, error) {
+	b.describes++
+	var missing []Replica
+	for _, replica := range b.replicas {
+		if !slices.Contains(b.regions[name], replica.Region) {
+			missing = append(missing, replica)
+		}
+	}
+	return missing, nil
+}
+
+func (b *replicatingBackend) AddReplicas(_ context.Context, name string, replicas []Replica) error {
+	b.regions[name] = append(b.regions[name], ReplicaRegionNames(replicas)...)
+	return nil
+}
+
+func TestPushGroupsReplicas(t *testing.T) {
+	groups := []SecretGroup{
+		{Name: "llm", Keys: map[string]string{"OPENAI_API_KEY": "new"}},
+		{Name: "config", Keys: map[string]string{"LLM_MODEL": "gpt-4o"}},
+	}
+
+	tests := []struct {
+		name           string
+		opts           PushOptions
+		wantReplicated [][]string
+		wantRegions    map[string][]string
+		wantDescribes  int
+	}{
+		{
+			// Unchanged secrets aren't described
+			name:           "updated secrets",
+			wantReplicated: [][]string{{"us-west-2"}, nil},
+			wantRegions:    map[string][]string{"llm": {"us-west-2"}},
+			wantDescribes:  1,
+		},
+		{
+			name:           "sync replicas",
+			opts:           PushOptions{SyncReplicas: true},
+			wantReplicated: [][]string{{"us-west-2"}, {"us-west-2"}},
+			wantRegions:    map[string][]string{"llm": {"us-west-2"}, "config": {"us-west-2"}},
+			wantDescribes:  2,
+		},
+		{
+			name:           "dry run",
+			opts:           PushOptions{SyncReplicas: true, DryRun: true},
+			wantReplicated: [][]string{{"us-west-2"}, {"us-west-2"}},
+			wantRegions:    map[string][]string{},
+			wantDescribes:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &replicatingBackend{
+				memoryBackend: newMemoryBackend(map[string]map[string]string{
+					"llm":    {"OPENAI_API_KEY": "old"},
+					"config": {"LLM_MODEL": "gpt-4o"},
+				}),
+				regions:  make(map[string][]string),
+				replicas: []Replica{{Region: "us-west-2"}},
+			}
+			results, err := PushGroups(context.Background(), backend, groups, tt.opts)
+			if err != nil {
+				t.Fatalf("PushGroups: %v", err)
+			}
+			var replicated [][]string
+			for _, result := range results {
+				replicated = append(replicated, result.Replicated)
+			}
+			if !reflect.DeepEqual(replicated, tt.wantReplicated) {
+				t.Errorf("replicated = %q, want %q", replicated, tt.wantReplicated)
+			}
+			if !reflect.DeepEqual(backend.regions, tt.wantRegions) {
+				t.Errorf("replica regions = %q, want %q", backend.regions, tt.wantRegions)
+			}
+			if backend.describes != tt.wantDescribes {
+				t.Errorf("%d secrets described, want %d", backend.describes, tt.wantDescribes)
+			}
+		})
+	}
+}
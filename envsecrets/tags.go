@@ -0,0 +1,206 @@
+package envsecrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Tags applied by DefaultTags.
+const (
+	TagProject     = "project"
+	TagEnvironment = "environment"
+	TagManagedBy   = "managed-by"
+)
+
+// ManagedBy is the managed-by tag value of pushed secrets, the same as on
+// the secrets the stack creates.
+const ManagedBy = "agentkit-aws-cdk"
+
+var (
+	// tagKeyPattern matches the tag keys accepted by both Secrets Manager
+	// and SSM.
+	tagKeyPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{1,128}$`)
+
+	// tagValuePattern matches the tag values accepted by both Secrets
+	// Manager and SSM.
+	tagValuePattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{0,256}$`)
+
+	// principalARNPattern matches IAM user and role ARNs, optionally with
+	// a path and a trailing wildcard.
+	principalARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:(role|user)/[\w+=,.@/*-]+$`)
+
+	// assumedRolePattern matches the ARN of an assumed role session, as
+	// returned by GetCallerIdentity.
+	assumedRolePattern = regexp.MustCompile(`^arn:(aws[a-z-]*):sts::(\d{12}):assumed-role/([\w+=,.@-]+)/.+$`)
+)
+
+// DefaultTags returns the project, environment, and managed-by tags of
+// pushed secrets. An empty project or environment is left out.
+func DefaultTags(project, environment string) map[string]string {
+	tags := map[string]string{TagManagedBy: ManagedBy}
+	if project != "" {
+		tags[TagProject] = project
+	}
+	if environment != "" {
+		tags[TagEnvironment] = environment
+	}
+	return tags
+}
+
+// ParseTags parses a comma-separated list of key=value tags, e.g.
+// "team=search,cost-center=1234".
+func ParseTags(spec string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("tag %q must be key=value", item)
+		}
+		if _, seen := tags[key]; seen {
+			return nil, fmt.Errorf("tag %s is listed twice", key)
+		}
+		tags[key] = value
+	}
+	return tags, ValidateTags(tags)
+}
+
+// ValidateTags checks tag keys and values against the rules of Secrets
+// Manager and SSM. Keys starting with "aws:" are reserved.
+func ValidateTags(tags map[string]string) error {
+	for _, key := range sortedKeys(tags) {
+		switch {
+		case !tagKeyPattern.MatchString(key):
+			return fmt.Errorf("tag key %q must be 1-128 letters, digits, spaces, or _.:/=+-@", key)
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			return fmt.Errorf("tag key %q: the aws: prefix is reserved", key)
+		case !tagValuePattern.MatchString(tags[key]):
+			return fmt.Errorf("tag %s: value must be at most 256 letters, digits, spaces, or _.:/=+-@", key)
+		}
+	}
+	return nil
+}
+
+// ParsePrincipalARNs parses a comma-separated list of IAM role or user
+// ARNs, e.g. the agent execution role.
+func ParsePrincipalARNs(spec string) ([]string, error) {
+	var arns []string
+	for _, arn := range strings.Split(spec, ",") {
+		arn = strings.TrimSpace(arn)
+		if arn == "" {
+			continue
+		}
+		if !principalARNPattern.MatchString(arn) {
+			return nil, fmt.Errorf("%q is not an IAM role or user ARN", arn)
+		}
+		arns = append(arns, arn)
+	}
+	return arns, nil
+}
+
+// CallerPrincipalARNs returns the principal ARNs matching the caller
+// identity ARN returned by GetCallerIdentity. An assumed role session
+// matches its role, with or without a path; other identities match
+// themselves.
+func CallerPrincipalARNs(identityARN string) []string {
+	m := assumedRolePattern.FindStringSubmatch(identityARN)
+	if m == nil {
+		return []string{identityARN}
+	}
+	role := fmt.Sprintf("arn:%s:iam::%s:role/", m[1], m[2])
+	return []string{role + m[3], role + "*/" + m[3]}
+}
+
+// ReadPolicy returns a secret resource policy that denies
+// secretsmanager:GetSecretValue to every principal except readerARNs. It
+// grants nothing: readers still need an identity policy allowing the read.
+func ReadPolicy(readerARNs []string) (string, error) {
+	if len(readerARNs) == 0 {
+		return "", fmt.Errorf("a read policy needs at least one reader")
+	}
+	policy := map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Sid":       "RestrictGetSecretValue",
+			"Effect":    "Deny",
+			"Principal": "*",
+			"Action":    "secretsmanager:GetSecretValue",
+			"Resource":  "*",
+			"Condition": map[string]any{
+				"ArnNotLike": map[string]any{"aws:PrincipalArn": readerARNs},
+			},
+		}},
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("marshaling policy: %w", err)
+	}
+	return string(data), nil
+}
+
+// Tagger is implemented by backends that tag secrets and restrict who can
+// read them. PushGroups applies PushOptions.Tags and PushOptions.ReaderARNs
+// to every secret it creates, updates, or finds unchanged, so secrets
+// pushed before they were configured are brought into compliance too.
+type Tagger interface {
+	// TagSecret adds tags to a secret with the given keys, keeping its
+	// other tags.
+	TagSecret(ctx context.Context, name string, keys []string, tags map[string]string) error
+
+	// RestrictRead replaces the resource policy of a secret with
+	// ReadPolicy(readerARNs).
+	RestrictRead(ctx context.Context, name string, readerARNs []string) error
+}
+
+// applyTags tags and restricts a secret as configured in opts, if the
+// backend is a Tagger.
+func applyTags(ctx context.Context, backend Backend, name string, keys []string, opts PushOptions, out io.Writer) error {
+	if len(opts.Tags) == 0 && len(opts.ReaderARNs) == 0 {
+		return nil
+	}
+	tagger, ok := backend.(Tagger)
+	if !ok {
+		return fmt.Errorf("the backend does not support tags or read restrictions")
+	}
+
+	if len(opts.Tags) > 0 {
+		tags := strings.Join(sortedKeys(opts.Tags), ", ")
+		if opts.DryRun {
+			fmt.Fprintf(out, "  [DRY RUN] Would tag: %s\n", tags)
+		} else {
+			if err := tagger.TagSecret(ctx, name, keys, opts.Tags); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "  Tagged: %s\n", tags)
+		}
+	}
+	if len(opts.ReaderARNs) > 0 {
+		if opts.DryRun {
+			fmt.Fprintf(out, "  [DRY RUN] Would restrict reads to %d principals\n", len(opts.ReaderARNs))
+			return nil
+		}
+		if err := tagger.RestrictRead(ctx, name, opts.ReaderARNs); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "  Restricted reads to %d principals\n", len(opts.ReaderARNs))
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,232 @@
+package envsecrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minSecretLength is the shortest credential accepted for sensitive-looking
+// keys; shorter values are usually truncated or placeholders.
+const minSecretLength = 16
+
+// keyFormat is the expected format of a known provider key.
+type keyFormat struct {
+	pattern *regexp.Regexp
+	hint    string
+}
+
+// keyFormats are the formats of the provider keys of DefaultGroups.
+var keyFormats = map[string]keyFormat{
+	"OPENAI_API_KEY":      {regexp.MustCompile(`^sk-[A-Za-z0-9_-]{20,}$`), "OpenAI keys start with sk-"},
+	"ANTHROPIC_API_KEY":   {regexp.MustCompile(`^sk-ant-[A-Za-z0-9_-]{20,}$`), "Anthropic keys start with sk-ant-"},
+	"CLAUDE_API_KEY":      {regexp.MustCompile(`^sk-ant-[A-Za-z0-9_-]{20,}$`), "Anthropic keys start with sk-ant-"},
+	"GOOGLE_API_KEY":      {regexp.MustCompile(`^AIza[0-9A-Za-z_-]{35}$`), "Google API keys are 39 characters starting with AIza"},
+	"GEMINI_API_KEY":      {regexp.MustCompile(`^AIza[0-9A-Za-z_-]{35}$`), "Google API keys are 39 characters starting with AIza"},
+	"XAI_API_KEY":         {regexp.MustCompile(`^xai-[A-Za-z0-9]{20,}$`), "xAI keys start with xai-"},
+	"SERPER_API_KEY":      {regexp.MustCompile(`^[0-9a-f]{40}$`), "Serper keys are 40 hexadecimal characters"},
+	"SERPAPI_API_KEY":     {regexp.MustCompile(`^[0-9a-f]{64}$`), "SerpApi keys are 64 hexadecimal characters"},
+	"LANGFUSE_PUBLIC_KEY": {regexp.MustCompile(`^pk-lf-[A-Za-z0-9-]+$`), "Langfuse public keys start with pk-lf-"},
+	"LANGFUSE_SECRET_KEY": {regexp.MustCompile(`^sk-lf-[A-Za-z0-9-]+$`), "Langfuse secret keys start with sk-lf-"},
+}
+
+// KeyProblem is a key whose value failed validation.
+type KeyProblem struct {
+	// Group is the group of the key.
+	Group string
+
+	// Key is the environment variable name.
+	Key string
+
+	// Problem describes what is wrong, without the value.
+	Problem string
+
+	// Warning is set for problems that don't block a push, such as a
+	// provider that could not be reached.
+	Warning bool
+}
+
+// String returns the problem as "KEY (group): problem".
+func (p KeyProblem) String() string {
+	return fmt.Sprintf("%s (%s): %s", p.Key, p.Group, p.Problem)
+}
+
+// Blocking reports whether any of the problems should stop a push.
+func Blocking(problems []KeyProblem) bool {
+	for _, problem := range problems {
+		if !problem.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateValues checks the values of the groups' keys for obvious
+// mistakes before they are pushed: surrounding whitespace, truncation
+// marks, control characters, credentials that are too short, invalid
+// LLM_BASE_URL values, and provider keys that don't match their known
+// format (e.g. OpenAI keys start with sk-). It makes no network calls;
+// see CheckKeysLive.
+func ValidateValues(groups []SecretGroup) []KeyProblem {
+	var problems []KeyProblem
+	for _, group := range groups {
+		for _, key := range group.KeyNames() {
+			if problem := validateValue(key, group.Keys[key]); problem != "" {
+				problems = append(problems, KeyProblem{Group: group.Name, Key: key, Problem: problem})
+			}
+		}
+	}
+	return problems
+}
+
+// validateValue returns the problem of a single value, or "".
+func validateValue(key, value string) string {
+	if strings.TrimSpace(value) != value {
+		return "has leading or trailing whitespace"
+	}
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return "contains control characters"
+	}
+	if key == "LLM_BASE_URL" {
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "is not an http or https URL"
+		}
+		return ""
+	}
+	if !isSensitiveKey(key) {
+		return ""
+	}
+
+	switch {
+	case strings.Contains(value, "...") || strings.Contains(value, "…"):
+		return "looks truncated (contains an ellipsis)"
+	case strings.IndexFunc(value, func(r rune) bool { return r > unicode.MaxASCII || unicode.IsSpace(r) }) >= 0:
+		return "contains spaces or non-ASCII characters"
+	case len(value) < minSecretLength:
+		return fmt.Sprintf("is too short for a credential (%d characters)", len(value))
+	}
+	if format, ok := keyFormats[key]; ok && !format.pattern.MatchString(value) {
+		return fmt.Sprintf("does not match the expected format (%s); is it truncated?", format.hint)
+	}
+	return ""
+}
+
+// isSensitiveKey reports whether a key looks like it holds a credential.
+func isSensitiveKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, marker := range []string{"KEY", "SECRET", "TOKEN", "PASSWORD"} {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPDoer is the subset of *http.Client used by CheckKeysLive.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// liveCheck builds a request that lists a provider's models with a key.
+type liveCheck struct {
+	provider string
+	request  func(ctx context.Context, key string) (*http.Request, error)
+
+	// invalidKeyStatus is the status besides 401 and 403 the provider
+	// answers an invalid key with, if any.
+	invalidKeyStatus int
+}
+
+// bearerRequest returns a GET request authorized with a bearer token.
+func bearerRequest(endpoint string) func(context.Context, string) (*http.Request, error) {
+	return func(ctx context.Context, key string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+key)
+		return req, nil
+	}
+}
+
+// headerRequest returns a GET request with the key in a header.
+func headerRequest(endpoint string, headers map[string]string, keyHeader string) func(context.Context, string) (*http.Request, error) {
+	return func(ctx context.Context, key string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		req.Header.Set(keyHeader, key)
+		return req, nil
+	}
+}
+
+// liveChecks are the LLM provider keys CheckKeysLive can verify. Listing
+// models is free and has no side effects.
+var liveChecks = map[string]liveCheck{
+	"OPENAI_API_KEY":    {"OpenAI", bearerRequest("https://api.openai.com/v1/models"), 0},
+	"XAI_API_KEY":       {"xAI", bearerRequest("https://api.x.ai/v1/models"), 0},
+	"ANTHROPIC_API_KEY": {"Anthropic", headerRequest("https://api.anthropic.com/v1/models", map[string]string{"anthropic-version": "2023-06-01"}, "x-api-key"), 0},
+	"CLAUDE_API_KEY":    {"Anthropic", headerRequest("https://api.anthropic.com/v1/models", map[string]string{"anthropic-version": "2023-06-01"}, "x-api-key"), 0},
+	"GOOGLE_API_KEY":    {"Google", headerRequest("https://generativelanguage.googleapis.com/v1beta/models", nil, "x-goog-api-key"), http.StatusBadRequest},
+	"GEMINI_API_KEY":    {"Google", headerRequest("https://generativelanguage.googleapis.com/v1beta/models", nil, "x-goog-api-key"), http.StatusBadRequest},
+}
+
+// LiveCheckKeys returns the keys CheckKeysLive can verify, sorted.
+func LiveCheckKeys() []string {
+	keys := make([]string, 0, len(liveChecks))
+	for key := range liveChecks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CheckKeysLive verifies the LLM provider keys of the groups (see
+// LiveCheckKeys) by listing the provider's models with them. A key the
+// provider rejects (HTTP 401 or 403, or 400 for Google) is a blocking
+// problem; a provider that can't be reached or answers otherwise
+// unexpectedly is a warning.
+func CheckKeysLive(ctx context.Context, client HTTPDoer, groups []SecretGroup) []KeyProblem {
+	var problems []KeyProblem
+	for _, group := range groups {
+		for _, key := range group.KeyNames() {
+			check, ok := liveChecks[key]
+			if !ok {
+				continue
+			}
+			problem := KeyProblem{Group: group.Name, Key: key}
+			req, err := check.request(ctx, group.Keys[key])
+			if err != nil {
+				problem.Problem, problem.Warning = fmt.Sprintf("could not check with %s: %v", check.provider, err), true
+				problems = append(problems, problem)
+				continue
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				problem.Problem, problem.Warning = fmt.Sprintf("could not reach %s: %v", check.provider, err), true
+				problems = append(problems, problem)
+				continue
+			}
+			_ = resp.Body.Close()
+			switch {
+			case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == check.invalidKeyStatus:
+				problem.Problem = fmt.Sprintf("rejected by %s (HTTP %d)", check.provider, resp.StatusCode)
+			case resp.StatusCode >= 300:
+				problem.Problem, problem.Warning = fmt.Sprintf("could not be verified by %s (HTTP %d)", check.provider, resp.StatusCode), true
+			default:
+				continue
+			}
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,57 @@
+// Example 5: Pulumi YAML Export
+//
+// This tool exports the stack described by a JSON/YAML config file as a
+// Pulumi YAML program using the aws-native provider, for teams deploying
+// with Pulumi instead of the CDK.
+//
+// Usage:
+//
+//	go run generate.go                     # Generate from config.yaml
+//	go run generate.go config.json         # Generate from specific file
+//
+// Then deploy with:
+//
+//	pulumi stack init dev
+//	pulumi config set aws-native:region us-east-1
+//	pulumi up
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/plexusone/agentkit-aws-cdk/agentcore"
+)
+
+func main() {
+	// Determine input config file
+	configFile := "config.yaml"
+	if len(os.Args) > 1 {
+		configFile = os.Args[1]
+	}
+
+	// Load configuration, including CDK-specific options
+	config, err := agentcore.LoadStackConfigFromFile(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	options, err := agentcore.LoadStackOptionsFromFile(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading options: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate the Pulumi program
+	outputFile := "Pulumi.yaml"
+	if err := agentcore.GeneratePulumiYAMLFile(*config, *options, outputFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating Pulumi program: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Generated %s from %s\n", outputFile, configFile)
+	fmt.Printf("\nDeploy with:\n")
+	fmt.Printf("  pulumi stack init dev\n")
+	fmt.Printf("  pulumi config set aws-native:region us-east-1\n")
+	fmt.Printf("  pulumi up\n")
+}
@@ -0,0 +1,108 @@
+// Package awsapi sends SigV4-signed JSON requests to the AWS APIs the
+// module has no SDK client for, such as the AgentCore control plane,
+// Bedrock model availability, and Service Quotas.
+package awsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Call is a request to an AWS API.
+type Call struct {
+	SigningName string // SigV4 service name
+	Host        string // Endpoint host, see Host
+	Method      string
+	Path        string // Path and query
+	Target      string // X-Amz-Target of JSON 1.1 protocol APIs; empty for REST APIs
+	Body        any    // Encoded as JSON; nil sends no body
+}
+
+// Error is an error response of an AWS API.
+type Error struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// RegionPattern matches AWS region codes such as us-west-2.
+var RegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// Host returns the endpoint host of a service in a region.
+func Host(region, service string) string {
+	domain := "amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("%s.%s.%s", service, region, domain)
+}
+
+// Sign signs req with SigV4 for a service in a region, with the
+// credentials of cfg. payload is the request body.
+func Sign(ctx context.Context, cfg aws.Config, req *http.Request, payload []byte, signingName, region string) error {
+	credentials, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving AWS credentials: %w", err)
+	}
+	hash := sha256.Sum256(payload)
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, region, time.Now()); err != nil {
+		return fmt.Errorf("signing request: %w", err)
+	}
+	return nil
+}
+
+// Do sends a signed JSON request in the region of cfg and returns the
+// response body. Error responses are returned as *Error.
+func Do(ctx context.Context, cfg aws.Config, call Call) ([]byte, error) {
+	var payload []byte
+	if call.Body != nil {
+		var err error
+		if payload, err = json.Marshal(call.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, call.Method, "https://"+call.Host+call.Path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if call.Target != "" {
+		req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		req.Header.Set("X-Amz-Target", call.Target)
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := Sign(ctx, cfg, req, payload, call.SigningName, cfg.Region); err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &Error{StatusCode: resp.StatusCode, Status: resp.Status, Message: strings.TrimSpace(string(data))}
+	}
+	return data, nil
+}
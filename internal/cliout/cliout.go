@@ -0,0 +1,171 @@
+// Package cliout is the output layer of the command-line tools. It prints
+// human-readable progress by default, nothing but warnings and errors with
+// --quiet, and machine-readable events with --json.
+//
+// With --json, stdout carries only events, one JSON object per line:
+//
+//	{"time":"2026-01-02T15:04:05Z","event":"deploy-start","stacks":["my-agents"]}
+//
+// and the progress text moves to stderr (unless --quiet), so CI systems can
+// parse the results without scraping prose.
+package cliout
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/plexusone/agentkit-aws-cdk/envsecrets"
+)
+
+// EventSecretsPushed is the event of a secrets push: envFile, backend,
+// prefix, dryRun, and secrets, the SecretResults of the push.
+const EventSecretsPushed = "secrets-pushed"
+
+// EventSecretsPulled is the event of a secrets pull: outFile, backend,
+// prefix, revealed, and secrets, each secret's name, whether it was found,
+// and its key names.
+const EventSecretsPulled = "secrets-pulled"
+
+// Logger writes progress text, warnings, and events. Its methods are safe
+// for concurrent use.
+type Logger struct {
+	mu     sync.Mutex
+	text   io.Writer // Progress text
+	warn   io.Writer // Warnings and errors
+	events io.Writer // JSON events; nil discards them
+	now    func() time.Time
+}
+
+// New returns a Logger writing to stdout and stderr. jsonEvents writes events
+// to stdout and progress text to stderr; quiet discards progress text.
+func New(jsonEvents, quiet bool) *Logger {
+	l := &Logger{text: os.Stdout, warn: os.Stderr, now: time.Now}
+	if jsonEvents {
+		l.events = os.Stdout
+		l.text = os.Stderr
+	}
+	if quiet {
+		l.text = io.Discard
+	}
+	// Without --json or --quiet, warnings stay in order with the progress
+	if !jsonEvents && !quiet {
+		l.warn = os.Stdout
+	}
+	return l
+}
+
+// Printf prints progress text.
+func (l *Logger) Printf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.text, format, args...)
+}
+
+// Println prints a line of progress text.
+func (l *Logger) Println(args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintln(l.text, args...)
+}
+
+// Warnf prints a warning, even with --quiet, and emits a warning event.
+func (l *Logger) Warnf(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	fmt.Fprintf(l.warn, "Warning: %s\n", message)
+	l.mu.Unlock()
+	l.Event("warning", map[string]any{"message": message})
+}
+
+// Errorf prints an error, even with --quiet, and emits an error event.
+func (l *Logger) Errorf(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+	l.mu.Unlock()
+	l.Event("error", map[string]any{"message": message})
+}
+
+// Event emits a JSON event with --json, and does nothing otherwise. The
+// fields are merged into the event next to its time and name.
+func (l *Logger) Event(name string, fields map[string]any) {
+	if l.events == nil {
+		return
+	}
+	event := make(map[string]any, len(fields)+2)
+	for key, value := range fields {
+		event[key] = value
+	}
+	event["time"] = l.now().UTC().Format(time.RFC3339)
+	event["event"] = name
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		data, _ = json.Marshal(map[string]any{
+			"time":    event["time"],
+			"event":   "error",
+			"message": fmt.Sprintf("encoding %s event: %v", name, err),
+		})
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.events.Write(append(data, '\n'))
+}
+
+// Stdout returns the writer for the standard output of subprocesses and
+// other progress output.
+func (l *Logger) Stdout() io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.text.Write(p)
+	})
+}
+
+// Stderr returns the writer for the standard error of subprocesses. Tools
+// such as the cdk CLI report progress on stderr, so it's discarded with
+// --quiet like progress text.
+func (l *Logger) Stderr() io.Writer {
+	if l.text == io.Discard {
+		return io.Discard
+	}
+	return os.Stderr
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// SecretResults returns the event fields of secret push results: each
+// secret's name, action, added, changed, and removed keys, and the regions
+// it was replicated to. Values are never included.
+func SecretResults(results []envsecrets.PushResult) []map[string]any {
+	secrets := make([]map[string]any, 0, len(results))
+	for _, result := range results {
+		secrets = append(secrets, map[string]any{
+			"name":       result.Name,
+			"action":     result.Action,
+			"added":      nonNil(result.Diff.Added),
+			"changed":    nonNil(result.Diff.Changed),
+			"removed":    nonNil(result.Diff.Removed),
+			"replicated": nonNil(result.Replicated),
+		})
+	}
+	return secrets
+}
+
+// nonNil returns keys, or an empty list for nil, so events always hold
+// JSON arrays.
+func nonNil(keys []string) []string {
+	if keys == nil {
+		return []string{}
+	}
+	return keys
+}